@@ -4,7 +4,7 @@ Original licensed under Apache 2.0, Copyright Aspect Build Systems Inc
 Source: https://github.com/aspect-build/bazel-examples/blob/64a5066b4be2e85b0a0a0e4a281ee185c75dd67a/protobufjs/defs.bzl
 """
 
-load("//bazel:defs.bzl", "proto_descriptor", "ts_project", "workspace_rel_path")
+load("//bazel:defs.bzl", "proto_descriptor", "ts_project")
 load("@npm//:protobufjs-cli/package_json.bzl", "bin")
 load("@rules_proto//proto:defs.bzl", "ProtoInfo")
 
@@ -20,7 +20,7 @@ _proto_sources = rule(
     attrs = {"proto": attr.label(providers = [ProtoInfo])},
 )
 
-def ts_proto_library(name, proto, deps = [], **kwargs):
+def ts_proto_library(name, proto, deps = [], transport = "grpc-js", **kwargs):
     """Minimal wrapper macro around pbjs/pbts tooling + gRPC client generation.
 
     A single proto_library is used as input into the pbjs executable to generate
@@ -32,7 +32,18 @@ def ts_proto_library(name, proto, deps = [], **kwargs):
     A custom code generator, grpc_gen_ts, outputs ${name}.grpc.ts with gRPC
     service definitions defined in the proto, and respective client classes.
     These can be imported as 'path/to/${name}.grpc' and accept the corresponding
-    .pb types as request/response protos.
+    .pb types as request/response protos. Clients use @grpc/grpc-js and/or
+    grpc-web as a transport, depending on the transport argument; the latter
+    are suffixed WebClient and are suitable for use in browsers. The grpc-js
+    transport additionally results in ${Service}Server interfaces for Node
+    servers, registered with add${Service}Server(); these may throw
+    StatusErrors from //typescript:grpc to return specific gRPC codes.
+
+    The .grpc.ts file also has helpers for messages with oneof or proto3
+    optional fields, in namespaces mirroring the .pb types: a ${Oneof}Case
+    union type and ${oneof}Case() function for each oneof, and a has${Field}()
+    function for each optional field. Imports of other targets use the @proof/*
+    path mapping of ts_project() so don't depend on the package's location.
 
     See https://www.npmjs.com/package/protobufjs-cli re pbjs and pbts.
 
@@ -40,9 +51,26 @@ def ts_proto_library(name, proto, deps = [], **kwargs):
         name: name of generated ts_project target
         proto: label of a single proto_library target to generate for
         deps: additional dependencies of the ts_project
+        transport: one of "grpc-js", "grpc-web", or "both"
         **kwargs: passed through to the ts_project.
     """
 
+    transport_deps = {
+        "grpc-js": [
+            "//:node_modules/@grpc/grpc-js",
+            "//typescript:grpc",
+        ],
+        "grpc-web": [
+            "//:node_modules/grpc-web",
+        ],
+    }
+    if transport == "both":
+        transport_deps = transport_deps["grpc-js"] + transport_deps["grpc-web"]
+    elif transport in transport_deps:
+        transport_deps = transport_deps[transport]
+    else:
+        fail("transport must be one of grpc-js, grpc-web, or both; got %s" % transport)
+
     js_out = name + ".pb.js"
     ts_out = name + ".pb.d.ts"
     grpc_out = name + ".grpc.ts"
@@ -114,9 +142,9 @@ def ts_proto_library(name, proto, deps = [], **kwargs):
         cat $(location %s) | \
         $(location //bazel/ts_proto_library/grpc_gen_ts) \
             --pb_target=%s.pb \
-            --workspace_rel_path=%s \
+            --transport=%s \
             > $@
-        """ % (proto_descriptor_label, name, workspace_rel_path()),
+        """ % (proto_descriptor_label, name, transport),
     )
 
     ts_project(
@@ -128,8 +156,6 @@ def ts_proto_library(name, proto, deps = [], **kwargs):
             "//:node_modules/long",
             "//:node_modules/protobufjs",
             "//:node_modules/@types/node",
-            "//:node_modules/@grpc/grpc-js",
-            "//typescript:grpc",
-        ],
+        ] + transport_deps,
         **kwargs
     )
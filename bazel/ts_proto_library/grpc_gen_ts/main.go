@@ -1,5 +1,6 @@
 // The grpc_gen_ts binary reads a FileDescriptorProto on stdin and writes, to
-// stdout, protobufjs bindings to use @grpc/grpc-js as a transport.
+// stdout, protobufjs bindings to use @grpc/grpc-js and/or grpc-web as a
+// transport.
 package main
 
 import (
@@ -7,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"strings"
 	"text/template"
 
@@ -19,14 +21,38 @@ import (
 
 func main() {
 	pbTarget := flag.String("pb_target", "", "Name of protobuf target being generated")
-	workspaceRelPath := flag.String("workspace_rel_path", "./", "Relative path to workspace root")
+	workspaceModule := flag.String("workspace_module", "@proof", "Module specifier of the workspace root, as mapped by the ts_project() tsconfig paths")
+	transport := flag.String("transport", string(grpcJS), "Transport(s) for which client stubs are generated; one of grpc-js, grpc-web, or both")
 	flag.Parse()
 
-	if err := run(os.Stdin, os.Stdout, *pbTarget, *workspaceRelPath); err != nil {
+	if err := run(os.Stdin, os.Stdout, *pbTarget, *workspaceModule, transportFlag(*transport)); err != nil {
 		glog.Exit(err)
 	}
 }
 
+// A transportFlag is the value of the --transport flag.
+type transportFlag string
+
+const (
+	grpcJS  = transportFlag("grpc-js")
+	grpcWeb = transportFlag("grpc-web")
+	both    = transportFlag("both")
+)
+
+// validate returns an error if t is not a recognised transport.
+func (t transportFlag) validate() error {
+	switch t {
+	case grpcJS, grpcWeb, both:
+		return nil
+	}
+	return fmt.Errorf("unsupported --transport %q; must be one of %q, %q, or %q", t, grpcJS, grpcWeb, both)
+}
+
+// includesGRPCJS and includesGRPCWeb report whether stubs for the respective
+// transport are to be generated.
+func (t transportFlag) includesGRPCJS() bool  { return t == grpcJS || t == both }
+func (t transportFlag) includesGRPCWeb() bool { return t == grpcWeb || t == both }
+
 var (
 	//go:embed servicedef.go.tmpl
 	rawTmpl string
@@ -38,6 +64,15 @@ var (
 		"join": func(delim string, s ...string) string {
 			return strings.Join(s, delim)
 		},
+		"pascal":   pascalCase,
+		"messages": messages,
+		"cases": func(fields []string) string {
+			quoted := make([]string, len(fields))
+			for i, f := range fields {
+				quoted[i] = "'" + f + "'"
+			}
+			return strings.Join(quoted, " | ")
+		},
 		"streaming": func(m *descpb.MethodDescriptorProto) string {
 			switch c, s := m.GetClientStreaming(), m.GetServerStreaming(); {
 			case c && s:
@@ -57,18 +92,30 @@ var (
 	)
 )
 
-// run reads a raw FileDescritproSet from src and uses it as data to the global
+// run reads a raw FileDescriptorSet from src and uses it as data to the global
 // `tmpl` template, which is Execute()d to dest.
-func run(src io.Reader, dest io.Writer, pbTarget, workspaceRelPath string) error {
+func run(src io.Reader, dest io.Writer, pbTarget, workspaceModule string, transport transportFlag) error {
+	if err := transport.validate(); err != nil {
+		return err
+	}
 	files, err := descriptors(src)
 	if err != nil {
 		return err
 	}
 	return tmpl.Execute(dest, struct {
-		PBTarget         string
-		WorkspaceRelPath string
-		DescriptorSet    *descpb.FileDescriptorSet
-	}{pbTarget, workspaceRelPath, files})
+		PBTarget        string
+		GRPCModule      string
+		DescriptorSet   *descpb.FileDescriptorSet
+		GRPCJS, GRPCWeb bool
+	}{
+		pbTarget,
+		// Joining is equivalent to tsc module resolution and keeps the
+		// specifier identical regardless of trailing slashes in the flag.
+		path.Join(workspaceModule, "typescript", "grpc"),
+		files,
+		transport.includesGRPCJS(),
+		transport.includesGRPCWeb(),
+	})
 }
 
 // descriptors expects r to contain a marshalled FileDescriptorSet, which it
@@ -85,3 +132,117 @@ func descriptors(r io.Reader) (*descpb.FileDescriptorSet, error) {
 	}
 	return set, nil
 }
+
+// A message is a protobuf message, possibly nested, that has oneof and/or
+// proto3 optional fields, for which typed helpers are generated.
+type message struct {
+	// FullName is the fully qualified protobuf name, and Path the names of the
+	// message and its parents, outermost first.
+	FullName string
+	Path     []string
+	// Interface is the pbts interface type accepted by helpers, which is also
+	// implemented by the respective message class.
+	Interface string
+	Oneofs    []*oneof
+	// Optional are the protobufjs names of proto3 optional fields.
+	Optional []string
+}
+
+// A oneof is a oneof declared in a .proto file, excluding synthetic ones that
+// protoc creates for each proto3 optional field.
+type oneof struct {
+	// Name and Fields are the protobufjs names of the oneof (i.e. its virtual
+	// property) and its member fields, in declaration order.
+	Name   string
+	Fields []string
+}
+
+// messages returns all messages in f, in declaration order with nested ones
+// after their parent, that have at least one oneof or proto3 optional field.
+func messages(f *descpb.FileDescriptorProto) []*message {
+	var out []*message
+
+	var walk func([]string, []*descpb.DescriptorProto)
+	walk = func(parents []string, msgs []*descpb.DescriptorProto) {
+		for _, m := range msgs {
+			if m.GetOptions().GetMapEntry() {
+				continue
+			}
+			p := append(append([]string{}, parents...), m.GetName())
+			if msg := newMessage(f.GetPackage(), p, m); len(msg.Oneofs) > 0 || len(msg.Optional) > 0 {
+				out = append(out, msg)
+			}
+			walk(p, m.GetNestedType())
+		}
+	}
+	walk(nil, f.GetMessageType())
+
+	return out
+}
+
+func newMessage(pkg string, msgPath []string, m *descpb.DescriptorProto) *message {
+	var scope []string
+	if pkg != "" {
+		scope = strings.Split(pkg, ".")
+	}
+	scope = append(scope, msgPath[:len(msgPath)-1]...)
+	name := msgPath[len(msgPath)-1]
+
+	msg := &message{
+		FullName: strings.Join(append(append([]string{}, scope...), name), "."),
+		Path:     msgPath,
+		// pbts prefixes only the message itself with I, not its parents.
+		Interface: strings.Join(append(append([]string{"pb"}, scope...), "I"+name), "."),
+	}
+
+	oneofs := make([]*oneof, len(m.GetOneofDecl()))
+	for i, o := range m.GetOneofDecl() {
+		oneofs[i] = &oneof{Name: camelCase(o.GetName())}
+	}
+	for _, f := range m.GetField() {
+		name := camelCase(f.GetName())
+		switch {
+		case f.GetProto3Optional():
+			// The field is the sole member of a synthetic oneof, which MUST be
+			// ignored as it isn't a oneof in the .proto file.
+			msg.Optional = append(msg.Optional, name)
+		case f.OneofIndex != nil:
+			o := oneofs[f.GetOneofIndex()]
+			o.Fields = append(o.Fields, name)
+		}
+	}
+	for _, o := range oneofs {
+		if len(o.Fields) > 0 {
+			msg.Oneofs = append(msg.Oneofs, o)
+		}
+	}
+	return msg
+}
+
+// camelCase converts a .proto name to the name used by protobufjs, mirroring
+// its util.camelCase(): underscores followed by a lower-case letter, other than
+// at the start, are removed and the letter upper-cased.
+func camelCase(s string) string {
+	if s == "" {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte(s[0])
+	for i := 1; i < len(s); i++ {
+		if s[i] == '_' && i+1 < len(s) && s[i+1] >= 'a' && s[i+1] <= 'z' {
+			b.WriteByte(s[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// pascalCase upper-cases the first letter of a camelCase name.
+func pascalCase(s string) string {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
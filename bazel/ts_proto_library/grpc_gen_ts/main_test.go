@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	descpb "google.golang.org/protobuf/types/descriptorpb"
+)
+
+var update = flag.Bool("update", false, "Overwrite golden files in testdata/ with current outputs")
+
+// golden compares got to the contents of testdata/name, overwriting the file
+// instead if -update is set.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("os.WriteFile(%q) error %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error %v", path, err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("output diff against golden file %q (-want +got):\n%s\n\nIf the change is intended, run with -update", path, diff)
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"id", "id"},
+		{"token_id", "tokenId"},
+		{"contract_address_v2", "contractAddressV2"},
+		{"_private", "_private"},
+		{"trailing_", "trailing_"},
+		{"double__under", "double_Under"},
+		{"upper_Case", "upper_Case"},
+		{"digit_1", "digit_1"},
+		{"alreadyCamel", "alreadyCamel"},
+	}
+
+	for _, tt := range tests {
+		if got := camelCase(tt.in); got != tt.want {
+			t.Errorf("camelCase(%q) got %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPascalCase(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"a", "A"},
+		{"tokenId", "TokenId"},
+		{"Already", "Already"},
+		{"_private", "_private"},
+		{"1st", "1st"},
+	}
+
+	for _, tt := range tests {
+		if got := pascalCase(tt.in); got != tt.want {
+			t.Errorf("pascalCase(%q) got %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// exampleFile returns the descriptor that protoc would produce for:
+//
+//	syntax = "proto3";
+//	package solgo.example;
+//
+//	message Request {
+//	  oneof target {
+//	    uint64 token_id = 1;
+//	    string contract_address = 2;
+//	  }
+//	  optional string page_cursor = 3;
+//	  uint32 page_size = 4;
+//	  map<string, string> labels = 5;
+//
+//	  message Filter {
+//	    oneof by {
+//	      uint64 min_block = 1;
+//	      bytes block_hash = 2;
+//	    }
+//	  }
+//	}
+//
+//	message Response {
+//	  repeated string uris = 1;
+//	}
+//
+//	service Example {
+//	  rpc Get(Request) returns (Response) {}
+//	  rpc Watch(Request) returns (stream Response) {}
+//	}
+func exampleFile() *descpb.FileDescriptorProto {
+	field := func(name string, num int32, typ descpb.FieldDescriptorProto_Type) *descpb.FieldDescriptorProto {
+		return &descpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(num),
+			Label:    descpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+			JsonName: proto.String(camelCase(name)),
+		}
+	}
+	inOneof := func(f *descpb.FieldDescriptorProto, idx int32) *descpb.FieldDescriptorProto {
+		f.OneofIndex = proto.Int32(idx)
+		return f
+	}
+	oneofs := func(names ...string) []*descpb.OneofDescriptorProto {
+		var decls []*descpb.OneofDescriptorProto
+		for _, n := range names {
+			decls = append(decls, &descpb.OneofDescriptorProto{Name: proto.String(n)})
+		}
+		return decls
+	}
+
+	const (
+		str    = descpb.FieldDescriptorProto_TYPE_STRING
+		u64    = descpb.FieldDescriptorProto_TYPE_UINT64
+		u32    = descpb.FieldDescriptorProto_TYPE_UINT32
+		byt    = descpb.FieldDescriptorProto_TYPE_BYTES
+		msgTyp = descpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+
+	cursor := inOneof(field("page_cursor", 3, str), 1)
+	cursor.Proto3Optional = proto.Bool(true)
+
+	labels := field("labels", 5, msgTyp)
+	labels.Label = descpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	labels.TypeName = proto.String(".solgo.example.Request.LabelsEntry")
+
+	uris := field("uris", 1, str)
+	uris.Label = descpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	return &descpb.FileDescriptorProto{
+		Name:    proto.String("example.proto"),
+		Package: proto.String("solgo.example"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descpb.DescriptorProto{
+			{
+				Name: proto.String("Request"),
+				Field: []*descpb.FieldDescriptorProto{
+					inOneof(field("token_id", 1, u64), 0),
+					inOneof(field("contract_address", 2, str), 0),
+					cursor,
+					field("page_size", 4, u32),
+					labels,
+				},
+				OneofDecl: oneofs("target", "_page_cursor"),
+				NestedType: []*descpb.DescriptorProto{
+					{
+						Name: proto.String("Filter"),
+						Field: []*descpb.FieldDescriptorProto{
+							inOneof(field("min_block", 1, u64), 0),
+							inOneof(field("block_hash", 2, byt), 0),
+						},
+						OneofDecl: oneofs("by"),
+					},
+					{
+						Name: proto.String("LabelsEntry"),
+						Field: []*descpb.FieldDescriptorProto{
+							field("key", 1, str),
+							field("value", 2, str),
+						},
+						Options: &descpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+				},
+			},
+			{
+				Name:  proto.String("Response"),
+				Field: []*descpb.FieldDescriptorProto{uris},
+			},
+		},
+		Service: []*descpb.ServiceDescriptorProto{{
+			Name: proto.String("Example"),
+			Method: []*descpb.MethodDescriptorProto{
+				{
+					Name:       proto.String("Get"),
+					InputType:  proto.String(".solgo.example.Request"),
+					OutputType: proto.String(".solgo.example.Response"),
+				},
+				{
+					Name:            proto.String("Watch"),
+					InputType:       proto.String(".solgo.example.Request"),
+					OutputType:      proto.String(".solgo.example.Response"),
+					ServerStreaming: proto.Bool(true),
+				},
+			},
+		}},
+	}
+}
+
+func TestMessages(t *testing.T) {
+	got := messages(exampleFile())
+
+	want := []*message{
+		{
+			FullName:  "solgo.example.Request",
+			Path:      []string{"Request"},
+			Interface: "pb.solgo.example.IRequest",
+			Oneofs: []*oneof{{
+				Name:   "target",
+				Fields: []string{"tokenId", "contractAddress"},
+			}},
+			Optional: []string{"pageCursor"},
+		},
+		{
+			FullName:  "solgo.example.Request.Filter",
+			Path:      []string{"Request", "Filter"},
+			Interface: "pb.solgo.example.Request.IFilter",
+			Oneofs: []*oneof{{
+				Name:   "by",
+				Fields: []string{"minBlock", "blockHash"},
+			}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("messages(%q) diff (-want +got):\n%s", exampleFile().GetName(), diff)
+	}
+}
+
+func TestRunGolden(t *testing.T) {
+	set := &descpb.FileDescriptorSet{
+		File: []*descpb.FileDescriptorProto{exampleFile()},
+	}
+	buf, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("proto.Marshal(%T) error %v", set, err)
+	}
+
+	for _, transport := range []transportFlag{grpcJS, grpcWeb, both} {
+		t.Run(string(transport), func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(bytes.NewReader(buf), &out, "example_pb", "@proof/", transport); err != nil {
+				t.Fatalf("run(…, %q) error %v", transport, err)
+			}
+			golden(t, "example_"+string(transport)+".ts", out.Bytes())
+		})
+	}
+
+	t.Run("invalid transport", func(t *testing.T) {
+		if err := run(bytes.NewReader(buf), new(bytes.Buffer), "example_pb", "@proof", "grpc"); err == nil {
+			t.Error(`run(…, "grpc") got nil error`)
+		}
+	})
+}
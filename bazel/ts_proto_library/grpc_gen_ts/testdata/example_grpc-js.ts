@@ -0,0 +1,168 @@
+/**
+GENERATED CODE - DO NOT EDIT
+**/
+import * as grpc from '@grpc/grpc-js';
+import * as pb from './example_pb';
+import * as proofgrpc from '@proof/typescript/grpc';
+
+
+
+export namespace solgo {
+
+export namespace example {
+	
+
+export const ExampleDefinition: grpc.ServiceDefinition = {
+		Get: {
+				path: '/solgo.example.Example/Get',
+				requestStream: false,
+				responseStream: false,
+				requestSerialize: (msg: pb.solgo.example.Request) => Buffer.from(pb.solgo.example.Request.encode(msg).finish()),
+				requestDeserialize: (buf: Buffer) => pb.solgo.example.Request.decode(new Uint8Array(buf)),
+				responseSerialize: (msg: pb.solgo.example.Response) => Buffer.from(pb.solgo.example.Response.encode(msg).finish()),
+				responseDeserialize: (buf: Buffer) => pb.solgo.example.Response.decode(new Uint8Array(buf)),
+			},
+		Watch: {
+				path: '/solgo.example.Example/Watch',
+				requestStream: false,
+				responseStream: true,
+				requestSerialize: (msg: pb.solgo.example.Request) => Buffer.from(pb.solgo.example.Request.encode(msg).finish()),
+				requestDeserialize: (buf: Buffer) => pb.solgo.example.Request.decode(new Uint8Array(buf)),
+				responseSerialize: (msg: pb.solgo.example.Response) => Buffer.from(pb.solgo.example.Response.encode(msg).finish()),
+				responseDeserialize: (buf: Buffer) => pb.solgo.example.Response.decode(new Uint8Array(buf)),
+			},
+		};
+
+const ExampleCtor = grpc.makeGenericClientConstructor(
+    ExampleDefinition, "solgo.example.Example"
+);
+
+export class ExampleClient {
+    private client;
+
+    constructor(serverAddr: string, credentials: grpc.ChannelCredentials) {
+        this.client = new ExampleCtor(serverAddr, credentials);
+    }
+
+    
+        
+        
+    public Get(req: pb.solgo.example.Request): Promise<pb.solgo.example.Response> {
+        return new Promise<pb.solgo.example.Response>((resolve, reject) => {
+            this.client.Get(req, (err: Error | null, resp: pb.solgo.example.Response) => {
+                err ? reject(err) : resolve(resp);
+            });
+        });
+    }
+        
+
+        
+
+        
+
+        
+    
+        
+        
+
+        
+
+        
+    public Watch(req: pb.solgo.example.Request): proofgrpc.ClientReadableStream<pb.solgo.example.Response> {
+        return new proofgrpc.ClientReadableStream<pb.solgo.example.Response>(this.client.Watch(req));
+    }
+        
+
+        
+    
+}
+
+/**
+ * ExampleServer is implemented by Node servers of the Example service.
+ * Thrown proofgrpc.StatusErrors end calls with their respective codes; all
+ * other errors result in an INTERNAL status.
+ */
+export interface ExampleServer {
+    Get(req: pb.solgo.example.Request, call: grpc.ServerUnaryCall<pb.solgo.example.Request, pb.solgo.example.Response>): Promise<pb.solgo.example.Response>;
+    Watch(req: pb.solgo.example.Request, call: grpc.ServerWritableStream<pb.solgo.example.Request, pb.solgo.example.Response>): Promise<void>;
+}
+
+/**
+ * addExampleServer registers the implementation with the grpc.Server.
+ */
+export function addExampleServer(server: grpc.Server, impl: ExampleServer): void {
+    server.addService(ExampleDefinition, {
+        Get: proofgrpc.handleUnary(impl.Get.bind(impl)),
+        Watch: proofgrpc.handleServerStreaming(impl.Watch.bind(impl)),
+    });
+}
+
+	
+
+/**
+ * Helpers for oneof and proto3 optional fields of solgo.example.Request.
+ */
+export namespace Request {
+
+    /**
+     * TargetCase is a field of the target oneof, as also returned by the
+     * virtual target property of decoded messages.
+     */
+    export type TargetCase = 'tokenId' | 'contractAddress';
+
+    /**
+     * targetCase returns the populated field of the target oneof, or
+     * undefined if there is none. Unlike the virtual property, it also accepts
+     * plain objects.
+     */
+    export function targetCase(msg: pb.solgo.example.IRequest): TargetCase | undefined {
+        if (msg.tokenId != null) {
+            return 'tokenId';
+        }
+        if (msg.contractAddress != null) {
+            return 'contractAddress';
+        }
+        return undefined;
+    }
+
+    /**
+     * hasPageCursor reports whether the proto3 optional pageCursor field is set,
+     * which is distinct from it having the default value.
+     */
+    export function hasPageCursor(msg: pb.solgo.example.IRequest): boolean {
+        return msg.pageCursor != null;
+    }
+}
+
+/**
+ * Helpers for oneof and proto3 optional fields of solgo.example.Request.Filter.
+ */
+export namespace Request {
+export namespace Filter {
+
+    /**
+     * ByCase is a field of the by oneof, as also returned by the
+     * virtual by property of decoded messages.
+     */
+    export type ByCase = 'minBlock' | 'blockHash';
+
+    /**
+     * byCase returns the populated field of the by oneof, or
+     * undefined if there is none. Unlike the virtual property, it also accepts
+     * plain objects.
+     */
+    export function byCase(msg: pb.solgo.example.Request.IFilter): ByCase | undefined {
+        if (msg.minBlock != null) {
+            return 'minBlock';
+        }
+        if (msg.blockHash != null) {
+            return 'blockHash';
+        }
+        return undefined;
+    }
+}
+}
+}
+}
+
+
@@ -0,0 +1,149 @@
+/**
+GENERATED CODE - DO NOT EDIT
+**/
+import * as pb from './example_pb';
+import * as grpcWeb from 'grpc-web';
+
+
+
+export namespace solgo {
+
+export namespace example {
+	
+
+/**
+ * ExampleWebClient is a client of the Example service, for use in browsers
+ * with grpc-web as a transport. Client and bidirectional streaming are
+ * unsupported by grpc-web.
+ */
+export class ExampleWebClient {
+    private client: grpcWeb.GrpcWebClientBase;
+    private hostname: string;
+
+    constructor(hostname: string, options?: grpcWeb.GrpcWebClientBaseOptions) {
+        this.client = new grpcWeb.GrpcWebClientBase(options ?? {});
+        this.hostname = hostname;
+    }
+
+    
+        
+        
+    private static GetDescriptor = new grpcWeb.MethodDescriptor(
+        '/solgo.example.Example/Get',
+        grpcWeb.MethodType.UNARY,
+        pb.solgo.example.Request,
+        pb.solgo.example.Response,
+        (msg: pb.solgo.example.Request) => pb.solgo.example.Request.encode(msg).finish(),
+        (buf: Uint8Array) => pb.solgo.example.Response.decode(buf),
+    );
+        
+
+        
+    public Get(req: pb.solgo.example.Request, metadata: grpcWeb.Metadata = {}): Promise<pb.solgo.example.Response> {
+        return this.client.thenableCall(
+            this.hostname + '/solgo.example.Example/Get', req, metadata,
+            ExampleWebClient.GetDescriptor,
+        );
+    }
+        
+
+        
+
+        
+    
+        
+        
+    private static WatchDescriptor = new grpcWeb.MethodDescriptor(
+        '/solgo.example.Example/Watch',
+        grpcWeb.MethodType.SERVER_STREAMING,
+        pb.solgo.example.Request,
+        pb.solgo.example.Response,
+        (msg: pb.solgo.example.Request) => pb.solgo.example.Request.encode(msg).finish(),
+        (buf: Uint8Array) => pb.solgo.example.Response.decode(buf),
+    );
+        
+
+        
+
+        
+    public Watch(req: pb.solgo.example.Request, metadata: grpcWeb.Metadata = {}): grpcWeb.ClientReadableStream<pb.solgo.example.Response> {
+        return this.client.serverStreaming(
+            this.hostname + '/solgo.example.Example/Watch', req, metadata,
+            ExampleWebClient.WatchDescriptor,
+        );
+    }
+        
+
+        
+    
+}
+
+	
+
+/**
+ * Helpers for oneof and proto3 optional fields of solgo.example.Request.
+ */
+export namespace Request {
+
+    /**
+     * TargetCase is a field of the target oneof, as also returned by the
+     * virtual target property of decoded messages.
+     */
+    export type TargetCase = 'tokenId' | 'contractAddress';
+
+    /**
+     * targetCase returns the populated field of the target oneof, or
+     * undefined if there is none. Unlike the virtual property, it also accepts
+     * plain objects.
+     */
+    export function targetCase(msg: pb.solgo.example.IRequest): TargetCase | undefined {
+        if (msg.tokenId != null) {
+            return 'tokenId';
+        }
+        if (msg.contractAddress != null) {
+            return 'contractAddress';
+        }
+        return undefined;
+    }
+
+    /**
+     * hasPageCursor reports whether the proto3 optional pageCursor field is set,
+     * which is distinct from it having the default value.
+     */
+    export function hasPageCursor(msg: pb.solgo.example.IRequest): boolean {
+        return msg.pageCursor != null;
+    }
+}
+
+/**
+ * Helpers for oneof and proto3 optional fields of solgo.example.Request.Filter.
+ */
+export namespace Request {
+export namespace Filter {
+
+    /**
+     * ByCase is a field of the by oneof, as also returned by the
+     * virtual by property of decoded messages.
+     */
+    export type ByCase = 'minBlock' | 'blockHash';
+
+    /**
+     * byCase returns the populated field of the by oneof, or
+     * undefined if there is none. Unlike the virtual property, it also accepts
+     * plain objects.
+     */
+    export function byCase(msg: pb.solgo.example.Request.IFilter): ByCase | undefined {
+        if (msg.minBlock != null) {
+            return 'minBlock';
+        }
+        if (msg.blockHash != null) {
+            return 'blockHash';
+        }
+        return undefined;
+    }
+}
+}
+}
+}
+
+
@@ -28,6 +28,16 @@ func Address() common.Address {
 	return common.HexToAddress("0x00000000000076A84feF008CDAbe6409d2FE638B")
 }
 
+// DeploymentName is the name of the delegation registry in eth.Deployments.
+const DeploymentName = "DelegationRegistry"
+
+// NewFromDeployments returns a new IDelegationRegistry binding at the address
+// recorded in the Deployments for the chain, which is only necessary for
+// registries not at Address(); e.g. on local test chains.
+func NewFromDeployments(d *eth.Deployments, chainID uint64, backend bind.ContractBackend) (*IDelegationRegistry, error) {
+	return eth.BindDeployment(d, DeploymentName, chainID, backend, NewIDelegationRegistry)
+}
+
 // A Delegation is a generalised structure capturing all delegation types. When
 // converted to a Delegation, all type-specific delegations include at least the
 // Vault and Delegate fields. A contract delegation also includes the Contract
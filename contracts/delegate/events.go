@@ -0,0 +1,190 @@
+package delegate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// A ChangeKind describes the effect of a single registry event.
+type ChangeKind string
+
+// ChangeKinds, one of which is carried by every Change.
+const (
+	// DelegationAdded and DelegationRemoved are emitted by DelegateForAll,
+	// DelegateForContract, and DelegateForToken events, depending on their
+	// value.
+	DelegationAdded   ChangeKind = "added"
+	DelegationRemoved ChangeKind = "removed"
+	// DelegateRevoked is emitted by RevokeDelegate events, removing all
+	// delegations, of any type, from the vault to the delegate.
+	DelegateRevoked ChangeKind = "delegate_revoked"
+	// AllDelegatesRevoked is emitted by RevokeAllDelegates events, removing all
+	// delegations from the vault. The Change's Delegate is the zero address.
+	AllDelegatesRevoked ChangeKind = "all_revoked"
+)
+
+// A Change is a single modification of the registry's state, derived from an
+// event log. Applying Changes in (Block, LogIndex) order to a snapshot of the
+// state reproduces the state at Block.
+type Change struct {
+	Block    uint64
+	TxHash   common.Hash
+	LogIndex uint
+	Kind     ChangeKind
+
+	Vault    common.Address
+	Delegate common.Address
+	Contract eth.NullableAddress
+	TokenID  eth.NullableUint256
+}
+
+// changeEvents are the names of all events that modify delegations.
+var changeEvents = []string{
+	"DelegateForAll",
+	"DelegateForContract",
+	"DelegateForToken",
+	"RevokeAllDelegates",
+	"RevokeDelegate",
+}
+
+// ChangeQuery returns a query for all logs, emitted by the registry at addr,
+// that can be converted by ParseChange(). The block range is left unset.
+func ChangeQuery(addr common.Address) (ethereum.FilterQuery, error) {
+	parsed, err := IDelegationRegistryMetaData.GetAbi()
+	if err != nil {
+		return ethereum.FilterQuery{}, fmt.Errorf("IDelegationRegistryMetaData.GetAbi(): %v", err)
+	}
+
+	topics := make([]common.Hash, len(changeEvents))
+	for i, name := range changeEvents {
+		ev, ok := parsed.Events[name]
+		if !ok {
+			return ethereum.FilterQuery{}, fmt.Errorf("IDelegationRegistry ABI missing event %q", name)
+		}
+		topics[i] = ev.ID
+	}
+
+	return ethereum.FilterQuery{
+		Addresses: []common.Address{addr},
+		Topics:    [][]common.Hash{topics},
+	}, nil
+}
+
+// ParseChange converts a log matched by ChangeQuery() into a Change.
+func (r *IDelegationRegistry) ParseChange(l types.Log) (*Change, error) {
+	if len(l.Topics) == 0 {
+		return nil, fmt.Errorf("log %d in tx %v has no topics", l.Index, l.TxHash)
+	}
+	parsed, err := IDelegationRegistryMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("IDelegationRegistryMetaData.GetAbi(): %v", err)
+	}
+	ev, err := parsed.EventByID(l.Topics[0])
+	if err != nil {
+		return nil, fmt.Errorf("%T.EventByID(%v): %v", parsed, l.Topics[0], err)
+	}
+
+	c := &Change{
+		Block:    l.BlockNumber,
+		TxHash:   l.TxHash,
+		LogIndex: l.Index,
+	}
+	kind := func(value bool) ChangeKind {
+		if value {
+			return DelegationAdded
+		}
+		return DelegationRemoved
+	}
+
+	switch ev.Name {
+	case "DelegateForAll":
+		e, err := r.ParseDelegateForAll(l)
+		if err != nil {
+			return nil, fmt.Errorf("%T.ParseDelegateForAll(…): %v", r, err)
+		}
+		c.Kind = kind(e.Value)
+		c.Vault = e.Vault
+		c.Delegate = e.Delegate
+
+	case "DelegateForContract":
+		e, err := r.ParseDelegateForContract(l)
+		if err != nil {
+			return nil, fmt.Errorf("%T.ParseDelegateForContract(…): %v", r, err)
+		}
+		c.Kind = kind(e.Value)
+		c.Vault = e.Vault
+		c.Delegate = e.Delegate
+		c.Contract = eth.NullableAddress{Address: e.Contract, Valid: true}
+
+	case "DelegateForToken":
+		e, err := r.ParseDelegateForToken(l)
+		if err != nil {
+			return nil, fmt.Errorf("%T.ParseDelegateForToken(…): %v", r, err)
+		}
+		id, overflow := uint256.FromBig(e.TokenId)
+		if overflow {
+			// See IDelegationRegistryTokenDelegation.Delegation() re panicking.
+			panic(fmt.Sprintf("uint256.FromBig(%v) overflowed", e.TokenId))
+		}
+		c.Kind = kind(e.Value)
+		c.Vault = e.Vault
+		c.Delegate = e.Delegate
+		c.Contract = eth.NullableAddress{Address: e.Contract, Valid: true}
+		c.TokenID = eth.NullableUint256{Int: *id, Valid: true}
+
+	case "RevokeAllDelegates":
+		e, err := r.ParseRevokeAllDelegates(l)
+		if err != nil {
+			return nil, fmt.Errorf("%T.ParseRevokeAllDelegates(…): %v", r, err)
+		}
+		c.Kind = AllDelegatesRevoked
+		c.Vault = e.Vault
+
+	case "RevokeDelegate":
+		e, err := r.ParseRevokeDelegate(l)
+		if err != nil {
+			return nil, fmt.Errorf("%T.ParseRevokeDelegate(…): %v", r, err)
+		}
+		c.Kind = DelegateRevoked
+		c.Vault = e.Vault
+		c.Delegate = e.Delegate
+
+	default:
+		return nil, fmt.Errorf("unsupported event %q", ev.Name)
+	}
+	return c, nil
+}
+
+// Changes returns all Changes to the registry at addr, in blocks [from, to],
+// in the order in which they were emitted. Logs are filtered in chunks of at
+// most maxRange blocks (see eth.Subscriber.MaxBackfillRange for the default if
+// zero).
+func (r *IDelegationRegistry) Changes(ctx context.Context, src eth.LogSource, addr common.Address, from, to, maxRange uint64) ([]*Change, error) {
+	q, err := ChangeQuery(addr)
+	if err != nil {
+		return nil, err
+	}
+	sub := eth.NewSubscriber(src, q)
+	sub.MaxBackfillRange = maxRange
+
+	var changes []*Change
+	err = sub.Range(ctx, from, to, func(l types.Log) error {
+		c, err := r.ParseChange(l)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%T.Range(%d, %d): %v", sub, from, to, err)
+	}
+	return changes, nil
+}
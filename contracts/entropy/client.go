@@ -0,0 +1,304 @@
+// Package entropy provides Go bindings for the EntropyOracle contracts, as well
+// as a high-level Client for requesting and providing entropy.
+package entropy
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/golang/glog"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// A Backend is the union of all interfaces required by a Client.
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	eth.MinedBackend
+	ChainID(context.Context) (*big.Int, error)
+}
+
+// A Client wraps the EntropyOracleV2 bindings with convenience methods for
+// requesting, awaiting, and providing entropy.
+type Client struct {
+	*EntropyOracleV2
+	backend Backend
+	opts    *bind.TransactOpts
+
+	// HTTPClient is used by ProvideFromServer(); if nil, http.DefaultClient is
+	// used.
+	HTTPClient *http.Client
+	// MaxResubscribeBackoff is the maximum delay between attempts to
+	// resubscribe to events after an error. It defaults to 30s if zero.
+	MaxResubscribeBackoff time.Duration
+}
+
+// NewClient returns a Client for the EntropyOracle deployed at addr. All
+// transactions are sent with opts, which MAY be nil if the Client will only be
+// used for watching events.
+func NewClient(addr common.Address, backend Backend, opts *bind.TransactOpts) (*Client, error) {
+	o, err := NewEntropyOracleV2(addr, backend)
+	if err != nil {
+		return nil, fmt.Errorf("NewEntropyOracleV2(%v, %T): %v", addr, backend, err)
+	}
+	return &Client{
+		EntropyOracleV2: o,
+		backend:         backend,
+		opts:            opts,
+	}, nil
+}
+
+// ErrReadOnly is returned by Client methods that send transactions if the
+// Client was constructed without TransactOpts.
+var ErrReadOnly = errors.New("entropy.Client constructed without TransactOpts")
+
+// txOpts returns a copy of c.opts with the Context set to ctx.
+func (c *Client) txOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	if c.opts == nil {
+		return nil, ErrReadOnly
+	}
+	opts := *c.opts
+	opts.Context = ctx
+	return &opts, nil
+}
+
+// waitMined waits for tx to be mined, returning an error if it fails.
+func (c *Client) waitMined(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	rcpt, err := eth.WaitMined(ctx, c.backend, tx, 1)
+	if err != nil {
+		return rcpt, fmt.Errorf("eth.WaitMined(%#x): %w", tx.Hash(), err)
+	}
+	return rcpt, nil
+}
+
+// RequestAndAwait requests entropy for the block and blocks until it has been
+// provided, returning the entropy. If entropy has already been provided then it
+// is returned immediately, without sending a transaction.
+//
+// Entropy is typically provided by an off-chain oracle watching for requests,
+// so RequestAndAwait will block indefinitely if there is no such oracle; ctx
+// SHOULD therefore have a deadline.
+func (c *Client) RequestAndAwait(ctx context.Context, block *big.Int) ([32]byte, error) {
+	if e, err := c.providedEntropy(ctx, block); err != nil || e != ([32]byte{}) {
+		return e, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before requesting so the provision event can't be missed.
+	provided := make(chan *EntropyOracleV2EntropyProvided)
+	sub := c.WatchProvisions(provided, block)
+	defer sub.Unsubscribe()
+
+	opts, err := c.txOpts(ctx)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	tx, err := c.RequestEntropy(opts, block)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("%T.RequestEntropy(%d): %v", c.EntropyOracleV2, block, err)
+	}
+	if _, err := c.waitMined(ctx, tx); err != nil {
+		return [32]byte{}, err
+	}
+
+	// Entropy may have been provided between the initial check and the
+	// subscription.
+	if e, err := c.providedEntropy(ctx, block); err != nil || e != ([32]byte{}) {
+		return e, err
+	}
+
+	select {
+	case ev := <-provided:
+		return ev.Entropy, nil
+	case <-ctx.Done():
+		return [32]byte{}, ctx.Err()
+	}
+}
+
+// providedEntropy returns the entropy for the block, which is zero if it is yet
+// to be provided.
+func (c *Client) providedEntropy(ctx context.Context, block *big.Int) ([32]byte, error) {
+	e, err := c.BlockEntropy(&bind.CallOpts{Context: ctx}, block)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("%T.BlockEntropy(%d): %v", c.EntropyOracleV2, block, err)
+	}
+	return e, nil
+}
+
+// EnvelopeMIMEType is the media type of an Envelope. Signing servers respond
+// with an Envelope, instead of a hex signature, if the request accepts it. A
+// dedicated type is used so that neither generic JSON requests nor JSON error
+// responses are mistaken for Envelopes.
+const EnvelopeMIMEType = "application/vnd.solgo.entropy-envelope+json"
+
+// SignerQueryParam is the URL query parameter with which signing requests
+// select the signer; either "current" (default), "previous", or an address. The
+// previous signer is only available while its key is being rotated out.
+const SignerQueryParam = "signer"
+
+// An Envelope is a signing server's response to a request that accepts
+// EnvelopeMIMEType.
+type Envelope struct {
+	Signature hexutil.Bytes  `json:"signature"`
+	Signer    common.Address `json:"signer"`
+	ChainID   uint64         `json:"chainId"`
+	Block     uint64         `json:"block"`
+	// IssuedAt and ExpiresAt are unix timestamps. The latter is advisory as
+	// the signature itself never expires, but the oracle's signer may be
+	// rotated.
+	IssuedAt  int64 `json:"issuedAt"`
+	ExpiresAt int64 `json:"expiresAt"`
+}
+
+// ErrBlockNotMined is returned by ProvideFromServer() if the signing server
+// refuses to sign a block because it is yet to be mined.
+var ErrBlockNotMined = errors.New("block not yet mined")
+
+// ProvideFromServer fetches signatures for each of the blocks from the signing
+// server at httpURL (see the entropyserver binary) and provides them to the
+// oracle in a single transaction. Signatures are requested from the oracle's
+// current signer so the server MAY be rotating keys.
+func (c *Client) ProvideFromServer(ctx context.Context, httpURL string, blocks ...*big.Int) (*types.Receipt, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no blocks")
+	}
+
+	signer, err := c.Signer(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("%T.Signer(): %v", c.EntropyOracleV2, err)
+	}
+	chainID, err := c.backend.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%T.ChainID(): %v", c.backend, err)
+	}
+
+	fulfil := make([]EntropyOracleEntropyFulfilment, len(blocks))
+	for i, b := range blocks {
+		sig, err := c.fetchSignature(ctx, httpURL, chainID, b, signer)
+		if err != nil {
+			return nil, err
+		}
+		fulfil[i] = EntropyOracleEntropyFulfilment{
+			BlockNumber: b,
+			Signature:   sig,
+		}
+	}
+
+	opts, err := c.txOpts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := c.ProvideEntropy0(opts, fulfil)
+	if err != nil {
+		return nil, fmt.Errorf("%T.ProvideEntropy(%d blocks): %v", c.EntropyOracleV2, len(fulfil), err)
+	}
+	return c.waitMined(ctx, tx)
+}
+
+// fetchSignature returns the signing server's signature for the block on the
+// chain, by the signer. Servers that predate Envelopes ignore the signer, in
+// which case the signature is returned as-is.
+func (c *Client) fetchSignature(ctx context.Context, httpURL string, chainID, block *big.Int, signer common.Address) ([]byte, error) {
+	url := fmt.Sprintf("%s/%s?%s=%s", strings.TrimRight(httpURL, "/"), block, SignerQueryParam, signer.Hex())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext(…, GET, %q): %v", url, err)
+	}
+	req.Header.Set("Accept", EnvelopeMIMEType+", text/plain;q=0.5")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %q: %v", url, err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io.ReadAll(%T.Body): %v", res, err)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("GET %q: %w", url, ErrBlockNotMined)
+	default:
+		return nil, fmt.Errorf("GET %q: status %d: %s", url, res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if t, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); t == EnvelopeMIMEType {
+		env := new(Envelope)
+		if err := json.Unmarshal(body, env); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal(GET %q, %T): %v", url, env, err)
+		}
+		if env.Signer != signer || !block.IsUint64() || env.Block != block.Uint64() {
+			return nil, fmt.Errorf("GET %q: envelope for block %d signed by %v; want block %d signed by %v", url, env.Block, env.Signer, block, signer)
+		}
+		if !chainID.IsUint64() || env.ChainID != chainID.Uint64() {
+			return nil, fmt.Errorf("GET %q: envelope for chain %d; want %d", url, env.ChainID, chainID)
+		}
+		// Catch bad signatures before they're submitted, and revert, on-chain.
+		msg := append(common.BigToHash(block).Bytes(), common.BigToHash(chainID).Bytes()...)
+		if err := eth.VerifyPersonalSign(msg, env.Signature, signer); err != nil {
+			return nil, fmt.Errorf("GET %q: envelope for block %d: %v", url, block, err)
+		}
+		return env.Signature, nil
+	}
+
+	sig, err := hex.DecodeString(strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("hex.DecodeString(GET %q): %v", url, err)
+	}
+	return sig, nil
+}
+
+func (c *Client) maxBackoff() time.Duration {
+	if c.MaxResubscribeBackoff == 0 {
+		return 30 * time.Second
+	}
+	return c.MaxResubscribeBackoff
+}
+
+// WatchRequests is equivalent to WatchEntropyRequested() except that the
+// subscription is automatically re-established after errors, with exponential
+// backoff up to c.MaxResubscribeBackoff. Errors are therefore logged instead of
+// being sent on the returned Subscription's Err() channel, which is only closed
+// by Unsubscribe(). Events emitted while resubscribing are not delivered.
+func (c *Client) WatchRequests(ch chan<- *EntropyOracleV2EntropyRequested, blocks ...*big.Int) event.Subscription {
+	return event.ResubscribeErr(c.maxBackoff(), func(ctx context.Context, lastErr error) (event.Subscription, error) {
+		if lastErr != nil {
+			glog.Warningf("Resubscribing to EntropyRequested events after error: %v", lastErr)
+		}
+		return c.WatchEntropyRequested(&bind.WatchOpts{Context: ctx}, ch, blocks)
+	})
+}
+
+// WatchProvisions is the EntropyProvided equivalent of WatchRequests().
+func (c *Client) WatchProvisions(ch chan<- *EntropyOracleV2EntropyProvided, blocks ...*big.Int) event.Subscription {
+	return event.ResubscribeErr(c.maxBackoff(), func(ctx context.Context, lastErr error) (event.Subscription, error) {
+		if lastErr != nil {
+			glog.Warningf("Resubscribing to EntropyProvided events after error: %v", lastErr)
+		}
+		return c.WatchEntropyProvided(&bind.WatchOpts{Context: ctx}, ch, blocks)
+	})
+}
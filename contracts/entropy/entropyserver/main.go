@@ -5,23 +5,29 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"math/big"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/golang/glog"
 	"golang.org/x/time/rate"
 
+	"github.com/cxkoda/solgo/contracts/entropy"
 	"github.com/cxkoda/solgo/contracts/go/hotsigner"
 	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/httperr"
 	"github.com/cxkoda/solgo/go/secrets"
 
 	_ "embed"
@@ -32,6 +38,12 @@ func main() {
 	flag.IntVar(&cfg.port, "port", 8080, "Port on which to listen for HTTP requests.")
 	flag.Var(&cfg.ethRPCURL, "eth_rpc_url", "Ethereum RPC URL source; e.g. env://INFURA_MAINNET_WITH_KEY.")
 	flag.DurationVar(&cfg.blockInterval, "block_interval", 12*time.Second, "Interval at which blocks are mined, to rate limit calls to fetch latest block number.")
+	flag.UintVar(&cfg.signerIndex, "signer_index", 0, "Index of the hot-signer key used to sign blocks.")
+	flag.IntVar(&cfg.previousSignerIndex, "previous_signer_index", -1, "Index of the hot-signer key being rotated out, which remains available for signing while the oracle's signer is updated; negative to disable.")
+	flag.DurationVar(&cfg.envelopeTTL, "envelope_ttl", time.Hour, "Duration after issuance at which JSON envelopes expire, after which clients SHOULD refetch to account for signer rotation.")
+	flag.Float64Var(&cfg.clientRate, "client_rate_limit", 10, "Sustained requests per second permitted from each client IP; non-positive to disable per-client rate limiting.")
+	flag.IntVar(&cfg.clientBurst, "client_burst", 20, "Maximum burst of requests permitted from each client IP, after which they are limited to -client_rate_limit.")
+	flag.IntVar(&cfg.trustedProxies, "trusted_proxies", 0, "Number of trusted proxies (e.g. load balancers) in front of the server, each of which appends to X-Forwarded-For; used to determine client IPs for rate limiting.")
 	flag.Parse()
 
 	if err := cfg.run(context.Background()); err != nil {
@@ -39,10 +51,19 @@ func main() {
 	}
 }
 
+// logger is used for all logs other than fatal errors in main().
+var logger = ethlog.Default()
+
 type config struct {
-	port          int
-	ethRPCURL     secrets.Secret
-	blockInterval time.Duration
+	port                int
+	ethRPCURL           secrets.Secret
+	blockInterval       time.Duration
+	signerIndex         uint
+	previousSignerIndex int
+	envelopeTTL         time.Duration
+	clientRate          float64
+	clientBurst         int
+	trustedProxies      int
 }
 
 func (cfg *config) run(ctx context.Context) error {
@@ -61,11 +82,20 @@ func (cfg *config) run(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("%T.ChainID(): %v", ethClient, err)
 	}
+	logger.Info("Connected to node", ethlog.URL("rpc", string(nodeURL)), ethlog.ChainID(chainID.Uint64()))
 
 	chain := uint256Bytes(chainID.Uint64())
-	signer, err := hotsigner.New(ctx, chain, 0)
+	signer, err := hotsigner.New(ctx, chain, cfg.signerIndex)
 	if err != nil {
-		return fmt.Errorf("hotsigner.New(ctx, %#x, 0): %v", chain, err)
+		return fmt.Errorf("hotsigner.New(ctx, %#x, %d): %v", chain, cfg.signerIndex, err)
+	}
+	var previous *eth.Signer
+	if i := cfg.previousSignerIndex; i >= 0 {
+		previous, err = hotsigner.New(ctx, chain, uint(i))
+		if err != nil {
+			return fmt.Errorf("hotsigner.New(ctx, %#x, %d): %v", chain, i, err)
+		}
+		logger.Info("Rotating signer", ethlog.Address("from", previous.Address()), ethlog.Address("to", signer.Address()))
 	}
 
 	//
@@ -75,15 +105,26 @@ func (cfg *config) run(ctx context.Context) error {
 		ethClient.BlockNumber,
 		cfg.blockInterval,
 		signer,
+		previous,
 		chainID.Uint64(),
 	)
 	if err != nil {
 		return fmt.Errorf("newSource(): %v", err)
 	}
+	src.envelopeTTL = cfg.envelopeTTL
+
+	var h http.Handler = src
+	if cfg.clientRate > 0 {
+		h = httperr.Chain(h, httperr.RateLimit(httperr.ClientRateLimits{
+			Rate:   rate.Limit(cfg.clientRate),
+			Burst:  cfg.clientBurst,
+			Client: httperr.ForwardedIP(cfg.trustedProxies),
+		}))
+	}
 
 	addr := fmt.Sprintf(":%d", cfg.port)
-	glog.Infof("Listening on %q for chain %d", addr, src.chainID)
-	return http.ListenAndServe(addr, src)
+	logger.Info("Listening", ethlog.String("addr", addr), ethlog.ChainID(src.chainID))
+	return http.ListenAndServe(addr, h)
 }
 
 // A blockSource returns the latest block number mined on a blockchain.
@@ -93,6 +134,14 @@ type blockSource func(context.Context) (uint64, error)
 type source struct {
 	signer  *eth.Signer
 	chainID uint64
+	// previous, if non-nil, is the signer being rotated out. It is only used
+	// when explicitly requested, allowing the oracle's signer to be updated
+	// without downtime.
+	previous *eth.Signer
+	// envelopeTTL is the lifetime of JSON envelopes, from issuance.
+	envelopeTTL time.Duration
+	// now is time.Now, swappable in tests.
+	now func() time.Time
 
 	latestBlock blockSource
 	currBlock   *atomic.Uint64
@@ -105,10 +154,16 @@ type source struct {
 // NOTE that using a prf.PRF offers sufficient security only for these purposes
 // (i.e. short-lived, no assets owned by the address). If a more secure signer
 // is needed, GCP KMS supports secp256k1.
-func newSource(blockSrc blockSource, blockInterval time.Duration, s *eth.Signer, chainID uint64) (*source, error) {
+func newSource(blockSrc blockSource, blockInterval time.Duration, s, previous *eth.Signer, chainID uint64) (*source, error) {
+	if previous != nil && previous.Address() == s.Address() {
+		return nil, fmt.Errorf("previous signer has same address as current (%v)", s.Address())
+	}
 	return &source{
 		signer:      s,
+		previous:    previous,
 		chainID:     chainID,
+		envelopeTTL: time.Hour,
+		now:         time.Now,
 		latestBlock: blockSrc,
 		currBlock:   new(atomic.Uint64),
 		limiter:     rate.NewLimiter(rate.Every(blockInterval), 4),
@@ -126,9 +181,15 @@ var (
 	errNonNumericBlock = errors.New("non-numeric block number")
 	errNegativeBlock   = errors.New("negative block number")
 	errBlockNotMined   = errors.New("block not yet mined")
+	errNoPrevSigner    = errors.New("no previous signer")
+	errUnknownSigner   = errors.New("unknown signer")
 )
 
-const signerAddrEndpoint = "/signer"
+const (
+	signerAddrEndpoint         = "/signer"
+	previousSignerAddrEndpoint = "/signer/previous"
+	signerParam                = entropy.SignerQueryParam
+)
 
 // ServeHTTP implements the http.Handler interface. All requests are handled by
 // s.sign().
@@ -138,17 +199,22 @@ func (s *source) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.Add("Access-Control-Allow-Origin", "*")
 
 	fn := s.sign
-	if r.Method == http.MethodGet && r.URL.Path == signerAddrEndpoint {
-		fn = s.signerAddr
+	if r.Method == http.MethodGet {
+		switch r.URL.Path {
+		case signerAddrEndpoint:
+			fn = s.signerAddr
+		case previousSignerAddrEndpoint:
+			fn = s.previousSignerAddr
+		}
 	}
 
 	switch code, err := fn(w, r); code {
 	case 200:
-	case http.StatusMethodNotAllowed, http.StatusBadRequest, http.StatusForbidden:
+	case http.StatusMethodNotAllowed, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound:
 		http.Error(w, err.Error(), code)
 	default:
 		key := sha256.Sum256([]byte(err.Error()))
-		glog.Errorf("[%x] %v", key[:8], err)
+		logger.Error("Request failed", ethlog.String("id", hex.EncodeToString(key[:8])), ethlog.String("path", r.URL.Path), ethlog.Err(err))
 		http.Error(w, fmt.Sprintf("Uh oh; please report %x", key[:8]), code)
 	}
 }
@@ -161,8 +227,59 @@ func (s *source) signerAddr(w http.ResponseWriter, _ *http.Request) (int, error)
 	return http.StatusOK, nil
 }
 
+// previousSignerAddr writes, to w, the Ethereum address of the signer being
+// rotated out, if any.
+func (s *source) previousSignerAddr(w http.ResponseWriter, _ *http.Request) (int, error) {
+	if s.previous == nil {
+		return http.StatusNotFound, errNoPrevSigner
+	}
+	if _, err := hex.NewEncoder(w).Write(s.previous.Address().Bytes()); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// selectSigner returns the signer requested by the signerParam query parameter.
+func (s *source) selectSigner(r *http.Request) (*eth.Signer, int, error) {
+	switch p := r.URL.Query().Get(signerParam); p {
+	case "", "current":
+		return s.signer, http.StatusOK, nil
+	case "previous":
+		if s.previous == nil {
+			return nil, http.StatusNotFound, errNoPrevSigner
+		}
+		return s.previous, http.StatusOK, nil
+	default:
+		if !common.IsHexAddress(p) {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid %q parameter", signerParam)
+		}
+		addr := common.HexToAddress(p)
+		for _, signer := range []*eth.Signer{s.signer, s.previous} {
+			if signer != nil && signer.Address() == addr {
+				return signer, http.StatusOK, nil
+			}
+		}
+		return nil, http.StatusNotFound, errUnknownSigner
+	}
+}
+
+// acceptsEnvelope returns whether the request's Accept header includes
+// entropy.EnvelopeMIMEType.
+func acceptsEnvelope(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			if t, _, err := mime.ParseMediaType(part); err == nil && t == entropy.EnvelopeMIMEType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // sign reads the block number from the request path, and signs it i.f.f. the
-// block has already been mined.
+// block has already been mined. The signature is written as hex unless the
+// request accepts entropy.EnvelopeMIMEType, in which case it is wrapped in a JSON
+// envelope.
 func (s *source) sign(w http.ResponseWriter, r *http.Request) (int, error) {
 	// NOTE that http.StatusForbidden is reservered for blocks not yet mined so
 	// we can use the code for testing.
@@ -187,11 +304,33 @@ func (s *source) sign(w http.ResponseWriter, r *http.Request) (int, error) {
 		return http.StatusForbidden, errBlockNotMined
 	}
 
+	signer, code, err := s.selectSigner(r)
+	if err != nil {
+		return code, err
+	}
+
 	buf := uint256Bytes(uint64(reqBlock))
 	buf = append(buf, uint256Bytes(s.chainID)...)
-	sig, err := s.signer.PersonalSign(buf)
+	sig, err := signer.PersonalSign(buf)
 	if err != nil {
-		return http.StatusInternalServerError, fmt.Errorf("%T.RawSign(%#x): %v", s.signer, buf, err)
+		return http.StatusInternalServerError, fmt.Errorf("%T.RawSign(%#x): %v", signer, buf, err)
+	}
+
+	if acceptsEnvelope(r) {
+		now := s.now()
+		env := &entropy.Envelope{
+			Signature: sig,
+			Signer:    signer.Address(),
+			ChainID:   s.chainID,
+			Block:     uint64(reqBlock),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.envelopeTTL).Unix(),
+		}
+		w.Header().Set("Content-Type", entropy.EnvelopeMIMEType)
+		if err := json.NewEncoder(w).Encode(env); err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("json.NewEncoder(%T).Encode(%T): %v", w, env, err)
+		}
+		return http.StatusOK, nil
 	}
 
 	if _, err := hex.NewEncoder(w).Write(sig); err != nil {
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -10,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
@@ -38,20 +41,35 @@ const (
 // newTestServer returns an httptest.Server handled by a signing source.
 func newTestServer(t *testing.T, blockSrc blockSource, key entropySrc, chainID uint64) *httptest.Server {
 	t.Helper()
+	server := httptest.NewServer(newTestSource(t, blockSrc, key, nil, chainID))
+	t.Cleanup(server.Close)
+	return server
+}
 
+// newTestSigner returns a signer derived from the key.
+func newTestSigner(t *testing.T, key entropySrc) *eth.Signer {
+	t.Helper()
 	s, err := eth.DefaultHDPathPrefix.SignerFromPRF(key, nil, 0)
 	if err != nil {
 		t.Fatalf("%T(%v).SignerFromPRF(%T, nil, 0) error %v", eth.DefaultHDPathPrefix, eth.DefaultHDPathPrefix, key, err)
 	}
+	return s
+}
+
+// newTestSource returns a signing source, with a previous signer i.f.f.
+// previousKey is non-nil.
+func newTestSource(t *testing.T, blockSrc blockSource, key, previousKey entropySrc, chainID uint64) *source {
+	t.Helper()
 
-	src, err := newSource(blockSrc, 0 /* blockInterval*/, s, chainID)
+	var previous *eth.Signer
+	if previousKey != nil {
+		previous = newTestSigner(t, previousKey)
+	}
+	src, err := newSource(blockSrc, 0 /* blockInterval*/, newTestSigner(t, key), previous, chainID)
 	if err != nil {
 		t.Fatalf("newSource(…) error %v", err)
 	}
-	server := httptest.NewServer(src)
-	t.Cleanup(server.Close)
-
-	return server
+	return src
 }
 
 func httpGet(t *testing.T, s *httptest.Server, path string) []byte {
@@ -280,6 +298,110 @@ func watch[E entropyEvent](t *testing.T, watch func(*bind.WatchOpts, chan<- E, [
 	return ch, sub.Err()
 }
 
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	sim := ethtest.NewSimulatedBackendTB(t, numAccounts)
+	blockSrc := func(context.Context) (uint64, error) { return sim.BlockNumber().Uint64(), nil }
+	srv := newTestServer(t, blockSrc, entropySrc("valid-private-key"), simBackendChainID)
+	signerAddr := common.HexToAddress(string(httpGet(t, srv, signerAddrEndpoint)))
+
+	addr, _, oracle, err := entropy.DeployEntropyOracleV2(sim.Acc(deployer), sim, sim.Addr(admin), sim.Addr(steerer))
+	if err != nil {
+		t.Fatalf("DeployEntropyOracleV2(…): %v", err)
+	}
+	sim.Must(t, "%T.SetSigner([addr from %q])", oracle, signerAddrEndpoint)(oracle.SetSigner(sim.Acc(steerer), signerAddr))
+	role, err := oracle.ENTROPYREQUESTERROLE(nil)
+	if err != nil {
+		t.Fatalf("%T.ENTROPYREQUESTERROLE(nil) error %v", oracle, err)
+	}
+	sim.Must(t, "%T.GrantRole(ENTROPY_REQUESTER_ROLE)", oracle)(oracle.GrantRole(sim.Acc(steerer), role, sim.Addr(requester)))
+
+	newClient := func(account int) *entropy.Client {
+		t.Helper()
+		c, err := entropy.NewClient(addr, sim, sim.Acc(account))
+		if err != nil {
+			t.Fatalf("entropy.NewClient(…) error %v", err)
+		}
+		return c
+	}
+	requesterClient := newClient(requester)
+	providerClient := newClient(public)
+
+	requests := make(chan *entropy.EntropyOracleV2EntropyRequested)
+	sub := providerClient.WatchRequests(requests)
+	defer sub.Unsubscribe()
+
+	provisionErr := make(chan error, 1)
+	go func() {
+		select {
+		case req := <-requests:
+			_, err := providerClient.ProvideFromServer(ctx, srv.URL, req.BlockNumber)
+			provisionErr <- err
+		case <-ctx.Done():
+			provisionErr <- ctx.Err()
+		}
+	}()
+
+	block := sim.BlockNumber()
+	got, err := requesterClient.RequestAndAwait(ctx, block)
+	if err != nil {
+		t.Fatalf("%T.RequestAndAwait(ctx, %d) error %v", requesterClient, block, err)
+	}
+	if err := <-provisionErr; err != nil {
+		t.Fatalf("%T.ProvideFromServer(ctx, %q, %d) error %v", providerClient, srv.URL, block, err)
+	}
+
+	want, err := oracle.BlockEntropy(nil, block)
+	if err != nil {
+		t.Fatalf("%T.BlockEntropy(%d) error %v", oracle, block, err)
+	}
+	if got != want || got == ([32]byte{}) {
+		t.Errorf("%T.RequestAndAwait(ctx, %d) got %#x; want %#x (non-zero)", requesterClient, block, got, want)
+	}
+
+	t.Run("already provided", func(t *testing.T) {
+		got, err := requesterClient.RequestAndAwait(ctx, block)
+		if err != nil || got != want {
+			t.Errorf("%T.RequestAndAwait(ctx, %d) got %#x, err = %v; want %#x, nil err", requesterClient, block, got, err, want)
+		}
+	})
+
+	t.Run("signer rotation", func(t *testing.T) {
+		// The server's current key has been rotated but the oracle is yet to
+		// be updated so still expects signatures from the previous one.
+		rotating := httptest.NewServer(newTestSource(t, blockSrc, entropySrc("rotated-key"), entropySrc("valid-private-key"), simBackendChainID))
+		t.Cleanup(rotating.Close)
+
+		block := new(big.Int).Sub(sim.BlockNumber(), big.NewInt(1))
+		if _, err := providerClient.ProvideFromServer(ctx, rotating.URL, block); err != nil {
+			t.Fatalf("%T.ProvideFromServer(ctx, [rotating server], %d) error %v", providerClient, block, err)
+		}
+		if got, err := oracle.BlockEntropy(nil, block); err != nil || got == ([32]byte{}) {
+			t.Errorf("%T.BlockEntropy(%d) after provision got %#x, err = %v; want non-zero, nil err", oracle, block, got, err)
+		}
+	})
+
+	t.Run("wrong chain", func(t *testing.T) {
+		// The envelope is internally consistent, signed for the chain that it
+		// reports, but would revert on-chain.
+		testnet := newTestServer(t, blockSrc, entropySrc("valid-private-key"), simBackendChainID+1)
+
+		block := new(big.Int).Sub(sim.BlockNumber(), big.NewInt(2))
+		if _, err := providerClient.ProvideFromServer(ctx, testnet.URL, block); err == nil {
+			t.Errorf("%T.ProvideFromServer(ctx, [server for chain %d], %d) got nil error; want error for chain %d", providerClient, simBackendChainID+1, block, simBackendChainID)
+		}
+	})
+
+	t.Run("unmined block", func(t *testing.T) {
+		future := new(big.Int).Add(sim.BlockNumber(), big.NewInt(100))
+		if _, err := providerClient.ProvideFromServer(ctx, srv.URL, future); !errors.Is(err, entropy.ErrBlockNotMined) {
+			t.Errorf("%T.ProvideFromServer(ctx, %q, [future block]) got err %v; want %v", providerClient, srv.URL, err, entropy.ErrBlockNotMined)
+		}
+	})
+}
+
 func TestOnlyMinedBlocks(t *testing.T) {
 	block := new(uint64)
 	server := newTestServer(
@@ -379,3 +501,163 @@ func (e entropySrc) ComputePRF(input []byte, outputLength uint32) ([]byte, error
 	}
 	return buf, nil
 }
+
+func TestEnvelopeAndRotation(t *testing.T) {
+	const (
+		chainID = simBackendChainID
+		block   = 42
+	)
+	blockSrc := func(context.Context) (uint64, error) { return block, nil }
+	current := newTestSigner(t, entropySrc("new-key"))
+	previous := newTestSigner(t, entropySrc("old-key"))
+
+	src := newTestSource(t, blockSrc, entropySrc("new-key"), entropySrc("old-key"), chainID)
+	now := time.Unix(1_700_000_000, 0)
+	src.now = func() time.Time { return now }
+	src.envelopeTTL = time.Minute
+	server := httptest.NewServer(src)
+	t.Cleanup(server.Close)
+
+	if got, want := common.HexToAddress(string(httpGet(t, server, signerAddrEndpoint))), current.Address(); got != want {
+		t.Errorf("GET %s got %v; want %v", signerAddrEndpoint, got, want)
+	}
+	if got, want := common.HexToAddress(string(httpGet(t, server, previousSignerAddrEndpoint))), previous.Address(); got != want {
+		t.Errorf("GET %s got %v; want %v", previousSignerAddrEndpoint, got, want)
+	}
+
+	msg := append(uint256Bytes(block), uint256Bytes(chainID)...)
+	sign := func(t *testing.T, s *eth.Signer) []byte {
+		t.Helper()
+		sig, err := s.PersonalSign(msg)
+		if err != nil {
+			t.Fatalf("%T.PersonalSign(…) error %v", s, err)
+		}
+		return sig
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantCode   int
+		wantSigner *eth.Signer
+	}{
+		{
+			name:       "default",
+			wantCode:   http.StatusOK,
+			wantSigner: current,
+		},
+		{
+			name:       "current",
+			query:      "?signer=current",
+			wantCode:   http.StatusOK,
+			wantSigner: current,
+		},
+		{
+			name:       "previous",
+			query:      "?signer=previous",
+			wantCode:   http.StatusOK,
+			wantSigner: previous,
+		},
+		{
+			name:       "previous by address",
+			query:      "?signer=" + previous.Address().Hex(),
+			wantCode:   http.StatusOK,
+			wantSigner: previous,
+		},
+		{
+			name:     "unknown address",
+			query:    "?signer=" + common.HexToAddress("0xbad").Hex(),
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "invalid signer",
+			query:    "?signer=nope",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := fmt.Sprintf("%s/%d%s", server.URL, block, tt.query)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				t.Fatalf("http.NewRequest(GET, %q, nil) error %v", url, err)
+			}
+			req.Header.Set("Accept", "text/html, "+entropy.EnvelopeMIMEType+";q=0.9")
+
+			res, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatalf("%T.Do(GET %q) error %v", server.Client(), url, err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.wantCode {
+				t.Fatalf("GET %q got status %d; want %d", url, res.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if got, want := res.Header.Get("Content-Type"), entropy.EnvelopeMIMEType; got != want {
+				t.Errorf("GET %q got Content-Type %q; want %q", url, got, want)
+			}
+
+			got := new(entropy.Envelope)
+			if err := json.NewDecoder(res.Body).Decode(got); err != nil {
+				t.Fatalf("json.Decode(GET %q, %T) error %v", url, got, err)
+			}
+			want := &entropy.Envelope{
+				Signature: sign(t, tt.wantSigner),
+				Signer:    tt.wantSigner.Address(),
+				ChainID:   chainID,
+				Block:     block,
+				IssuedAt:  now.Unix(),
+				ExpiresAt: now.Add(time.Minute).Unix(),
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("GET %q envelope diff (-want +got):\n%s", url, diff)
+			}
+		})
+	}
+
+	t.Run("hex without Accept", func(t *testing.T) {
+		got := common.Hex2Bytes(string(httpGet(t, server, fmt.Sprintf("/%d?signer=previous", block))))
+		if want := sign(t, previous); !cmp.Equal(got, want) {
+			t.Errorf("GET /%d?signer=previous got %#x; want %#x", block, got, want)
+		}
+	})
+
+	t.Run("hex with generic JSON Accept", func(t *testing.T) {
+		url := fmt.Sprintf("%s/%d", server.URL, block)
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("http.NewRequest(GET, %q, nil) error %v", url, err)
+		}
+		req.Header.Set("Accept", "application/json")
+
+		res, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%T.Do(GET %q) error %v", server.Client(), url, err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("io.ReadAll(GET %q) error %v", url, err)
+		}
+		if got, want := common.Hex2Bytes(string(body)), sign(t, current); !cmp.Equal(got, want) {
+			t.Errorf("GET %q with Accept: application/json got %q; want hex signature %#x", url, body, want)
+		}
+	})
+
+	t.Run("no previous signer", func(t *testing.T) {
+		server := newTestServer(t, blockSrc, entropySrc("new-key"), chainID)
+		for _, path := range []string{previousSignerAddrEndpoint, fmt.Sprintf("/%d?signer=previous", block)} {
+			res, err := server.Client().Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("%T.Get(%q) error %v", server.Client(), path, err)
+			}
+			res.Body.Close()
+			if got, want := res.StatusCode, http.StatusNotFound; got != want {
+				t.Errorf("GET %q without previous signer got status %d; want %d", path, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import {IERC20} from "openzeppelin-contracts/interfaces/IERC20.sol";
+
+/**
+ * @notice Interface of the Disperse contract (https://disperse.app), which
+ * batches ETH and ERC20 transfers from msg.sender to multiple recipients.
+ * @dev ERC20 transfers are performed with transferFrom() so the Disperse
+ * contract MUST first be approved to spend the total amount.
+ */
+interface IDisperse {
+    function disperseEther(address[] calldata recipients, uint256[] calldata values) external payable;
+
+    function disperseToken(IERC20 token, address[] calldata recipients, uint256[] calldata values) external;
+}
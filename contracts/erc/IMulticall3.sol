@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+/**
+ * @notice Subset of the Multicall3 interface, deployed at the same address on
+ * all major chains; see https://github.com/mds1/multicall.
+ * @dev aggregate3() is payable in the deployed contract but is declared as a
+ * view function here so that abigen generates a caller (i.e. eth_call) binding.
+ */
+interface IMulticall3 {
+    struct Call3 {
+        address target;
+        bool allowFailure;
+        bytes callData;
+    }
+
+    struct Result {
+        bool success;
+        bytes returnData;
+    }
+
+    function aggregate3(Call3[] calldata calls) external view returns (Result[] memory returnData);
+}
@@ -1,6 +1,14 @@
 // SPDX-License-Identifier: MIT
 pragma solidity ^0.8.0;
 
+import {IERC165} from "openzeppelin-contracts/interfaces/IERC165.sol";
+
+import {IERC20} from "openzeppelin-contracts/interfaces/IERC20.sol";
+
 import {IERC721} from "openzeppelin-contracts/interfaces/IERC721.sol";
 
 import {IERC721Enumerable} from "openzeppelin-contracts/interfaces/IERC721Enumerable.sol";
+
+import {IERC721Metadata} from "openzeppelin-contracts/interfaces/IERC721Metadata.sol";
+
+import {IERC2981} from "openzeppelin-contracts/interfaces/IERC2981.sol";
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import {ERC20} from "openzeppelin-contracts/token/ERC20/ERC20.sol";
+import {ERC721} from "openzeppelin-contracts/token/ERC721/ERC721.sol";
+
+/// @notice An ERC721 that implements ERC165, for testing DetectStandards() and
+/// TokenURIBatch().
+contract TestERC721 is ERC721("Test", "TEST") {
+    function mint(address to, uint256 tokenId) external {
+        _mint(to, tokenId);
+    }
+
+    function _baseURI() internal pure override returns (string memory) {
+        return "ipfs://test/";
+    }
+}
+
+/// @notice An ERC20, which doesn't implement ERC165, for testing
+/// DetectStandards().
+contract TestERC20 is ERC20("Test", "TEST") {}
+
+/**
+ * @notice A pre-ERC165 ERC721, for testing DetectStandards(). Only the
+ * functions whose selectors are sniffed are implemented.
+ */
+contract TestLegacyERC721 {
+    function name() external pure returns (string memory) {
+        return "Legacy";
+    }
+
+    function symbol() external pure returns (string memory) {
+        return "LEGACY";
+    }
+
+    function tokenURI(uint256) external pure returns (string memory) {
+        return "";
+    }
+
+    function balanceOf(address) external pure returns (uint256) {
+        return 0;
+    }
+
+    function ownerOf(uint256) external pure returns (address) {
+        return address(0);
+    }
+
+    function safeTransferFrom(address, address, uint256) external pure {
+        revert("unimplemented");
+    }
+}
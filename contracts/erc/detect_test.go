@@ -0,0 +1,65 @@
+package erc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+	"github.com/cxkoda/solgo/go/ethtest"
+)
+
+func TestDetectStandards(t *testing.T) {
+	ctx := context.Background()
+	sim := ethtest.NewSimulatedBackendTB(t, 1)
+
+	erc721, _, _, err := DeployTestERC721(sim.Acc(0), sim)
+	if err != nil {
+		t.Fatalf("DeployTestERC721() error %v", err)
+	}
+	erc20, _, _, err := DeployTestERC20(sim.Acc(0), sim)
+	if err != nil {
+		t.Fatalf("DeployTestERC20() error %v", err)
+	}
+	legacy, _, _, err := DeployTestLegacyERC721(sim.Acc(0), sim)
+	if err != nil {
+		t.Fatalf("DeployTestLegacyERC721() error %v", err)
+	}
+
+	tests := []struct {
+		name string
+		addr common.Address
+		want erc.Standards
+	}{
+		{
+			name: "no code",
+			addr: sim.Addr(0),
+			want: 0,
+		},
+		{
+			name: "ERC165",
+			addr: erc721,
+			want: 1<<erc.ERC165 | 1<<erc.ERC721 | 1<<erc.ERC721Metadata,
+		},
+		{
+			name: "bytecode ERC20",
+			addr: erc20,
+			want: 1 << erc.ERC20,
+		},
+		{
+			name: "bytecode ERC721",
+			addr: legacy,
+			want: 1<<erc.ERC721 | 1<<erc.ERC721Metadata,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := erc.DetectStandards(ctx, sim, tt.addr)
+			if err != nil || got != tt.want {
+				t.Errorf("erc.DetectStandards(ctx, %T, %v) got (%v, %v); want (%v, nil)", sim, tt.addr, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package erc
+
+import "github.com/ethereum/go-ethereum/common"
+
+// DisperseAddress returns the address at which the Disperse contract
+// (https://disperse.app) is deployed on all major chains.
+func DisperseAddress() common.Address {
+	return common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")
+}
@@ -0,0 +1,147 @@
+package erc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Metadata is the collection-level metadata of an ERC721Metadata contract.
+type Metadata struct {
+	Name, Symbol string
+}
+
+// CollectionMetadata returns the name() and symbol() of the ERC721Metadata
+// contract.
+func CollectionMetadata(ctx context.Context, backend bind.ContractCaller, addr common.Address) (*Metadata, error) {
+	c, err := NewIERC721MetadataCaller(addr, backend)
+	if err != nil {
+		return nil, fmt.Errorf("NewIERC721MetadataCaller(%v): %v", addr, err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+
+	name, err := c.Name(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%v.name(): %v", addr, err)
+	}
+	symbol, err := c.Symbol(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%v.symbol(): %v", addr, err)
+	}
+	return &Metadata{Name: name, Symbol: symbol}, nil
+}
+
+// Royalty is the result of an ERC2981 royaltyInfo() call.
+type Royalty struct {
+	Receiver common.Address
+	Amount   *big.Int
+}
+
+// RoyaltyInfo returns the royalty due to the ERC2981 contract for a sale of the
+// token at the price.
+func RoyaltyInfo(ctx context.Context, backend bind.ContractCaller, addr common.Address, tokenID, salePrice *big.Int) (*Royalty, error) {
+	c, err := NewIERC2981Caller(addr, backend)
+	if err != nil {
+		return nil, fmt.Errorf("NewIERC2981Caller(%v): %v", addr, err)
+	}
+	r, err := c.RoyaltyInfo(&bind.CallOpts{Context: ctx}, tokenID, salePrice)
+	if err != nil {
+		return nil, fmt.Errorf("%v.royaltyInfo(%d, %d): %v", addr, tokenID, salePrice, err)
+	}
+	return &Royalty{Receiver: r.Receiver, Amount: r.RoyaltyAmount}, nil
+}
+
+// A TokenURI is the result of calling tokenURI() for a single token. Err is
+// non-nil if the call reverted (e.g. for a non-existent token) or its return
+// data couldn't be decoded.
+type TokenURI struct {
+	TokenID *big.Int
+	URI     string
+	Err     error
+}
+
+// TokenURIBatchSize is the maximum number of tokenURI() calls aggregated into
+// a single Multicall3 call by TokenURIBatch().
+const TokenURIBatchSize = 500
+
+// TokenURIBatch returns the tokenURI() of every token in the ERC721Metadata
+// contract, in the same order as tokenIDs, using Multicall3 to aggregate calls.
+// Per-token failures are reported in the respective TokenURI.Err whereas the
+// returned error is only non-nil if an aggregated call fails in its entirety.
+//
+// The multicall address is typically the Multicall field of the backend's
+// eth.Chain.
+func TokenURIBatch(ctx context.Context, backend bind.ContractCaller, multicall, addr common.Address, tokenIDs []*big.Int) ([]TokenURI, error) {
+	parsed, err := IERC721MetadataMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("IERC721MetadataMetaData.GetAbi(): %v", err)
+	}
+	if multicall == (common.Address{}) {
+		return nil, fmt.Errorf("zero Multicall3 address")
+	}
+	mc, err := NewIMulticall3Caller(multicall, backend)
+	if err != nil {
+		return nil, fmt.Errorf("NewIMulticall3Caller(%v): %v", multicall, err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+
+	uris := make([]TokenURI, 0, len(tokenIDs))
+	for start := 0; start < len(tokenIDs); start += TokenURIBatchSize {
+		end := start + TokenURIBatchSize
+		if end > len(tokenIDs) {
+			end = len(tokenIDs)
+		}
+		batch := tokenIDs[start:end]
+
+		calls := make([]IMulticall3Call3, len(batch))
+		for i, id := range batch {
+			data, err := parsed.Pack("tokenURI", id)
+			if err != nil {
+				return nil, fmt.Errorf("pack tokenURI(%d): %v", id, err)
+			}
+			calls[i] = IMulticall3Call3{
+				Target:       addr,
+				AllowFailure: true,
+				CallData:     data,
+			}
+		}
+
+		results, err := mc.Aggregate3(opts, calls)
+		if err != nil {
+			return nil, fmt.Errorf("Multicall3.aggregate3([%d × %v.tokenURI()]): %v", len(calls), addr, err)
+		}
+		if len(results) != len(batch) {
+			return nil, fmt.Errorf("Multicall3.aggregate3() returned %d results for %d calls", len(results), len(batch))
+		}
+
+		for i, r := range results {
+			uris = append(uris, decodeTokenURI(parsed, batch[i], r))
+		}
+	}
+	return uris, nil
+}
+
+func decodeTokenURI(parsed *abi.ABI, id *big.Int, r IMulticall3Result) TokenURI {
+	u := TokenURI{TokenID: id}
+	if !r.Success {
+		u.Err = fmt.Errorf("tokenURI(%d) reverted", id)
+		return u
+	}
+
+	out, err := parsed.Unpack("tokenURI", r.ReturnData)
+	if err != nil {
+		u.Err = fmt.Errorf("unpack tokenURI(%d) return data: %v", id, err)
+		return u
+	}
+	uri, ok := out[0].(string)
+	if !ok {
+		u.Err = fmt.Errorf("tokenURI(%d) unpacked as %T; want string", id, out[0])
+		return u
+	}
+	u.URI = uri
+	return u
+}
@@ -0,0 +1,64 @@
+package erc_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+	"github.com/cxkoda/solgo/go/ethtest"
+)
+
+func TestTokenURIBatch(t *testing.T) {
+	ctx := context.Background()
+	sim := ethtest.NewSimulatedBackendTB(t, 1)
+
+	multicall, _, _, err := DeployTestMulticall3(sim.Acc(0), sim)
+	if err != nil {
+		t.Fatalf("DeployTestMulticall3() error %v", err)
+	}
+	addr, _, nft, err := DeployTestERC721(sim.Acc(0), sim)
+	if err != nil {
+		t.Fatalf("DeployTestERC721() error %v", err)
+	}
+	for _, id := range []int64{1, 2, 42} {
+		if _, err := nft.Mint(sim.Acc(0), sim.Addr(0), big.NewInt(id)); err != nil {
+			t.Fatalf("%T.Mint(%d) error %v", nft, id, err)
+		}
+	}
+
+	ids := []*big.Int{
+		big.NewInt(42),
+		big.NewInt(1),
+		big.NewInt(3), // not minted
+		big.NewInt(2),
+	}
+	got, err := erc.TokenURIBatch(ctx, sim, multicall, addr, ids)
+	if err != nil {
+		t.Fatalf("erc.TokenURIBatch(ctx, %T, %v, %v, %d) error %v", sim, multicall, addr, ids, err)
+	}
+
+	want := []erc.TokenURI{
+		{TokenID: big.NewInt(42), URI: "ipfs://test/42"},
+		{TokenID: big.NewInt(1), URI: "ipfs://test/1"},
+		{TokenID: big.NewInt(3), Err: cmpopts.AnyError},
+		{TokenID: big.NewInt(2), URI: "ipfs://test/2"},
+	}
+	opts := []cmp.Option{
+		cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 }),
+		cmpopts.EquateErrors(),
+	}
+	if diff := cmp.Diff(want, got, opts...); diff != "" {
+		t.Errorf("erc.TokenURIBatch(…) diff (-want +got):\n%s", diff)
+	}
+
+	t.Run("zero multicall address", func(t *testing.T) {
+		if _, err := erc.TokenURIBatch(ctx, sim, common.Address{}, addr, ids); err == nil {
+			t.Errorf("erc.TokenURIBatch(…, [zero address], …) got nil error")
+		}
+	})
+}
@@ -0,0 +1,477 @@
+package erc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	hosepb "github.com/streamingfast/pbgo/sf/firehose/v2"
+
+	"github.com/cxkoda/solgo/go/dbtx"
+	"github.com/cxkoda/solgo/go/eth"
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+)
+
+// An OwnershipIndex maintains the owner of every token, per (contract, token
+// ID), of ERC721 collections in a PostgreSQL table. It is the persistent
+// sibling of the one-shot cmd/holders tool: the full history of Transfers is
+// recorded so ownership can be queried as of any indexed block, and indexing
+// can be resumed incrementally. All access is performed in transactions, via
+// dbtx.
+//
+// The index is fed either by scanning logs with IndexLogs(), or by Hydrant
+// (Firehose) blocks with ApplyBlock(); both are idempotent so blocks MAY be
+// replayed. Blocks reverted by a chain reorganisation are removed with Undo(),
+// which ApplyBlock() calls for STEP_UNDO responses. Each contract's progress is
+// tracked separately as a contiguous range of blocks, and queries for blocks
+// outside of it return ErrNotIndexed.
+type OwnershipIndex struct {
+	db    dbtx.Transactor
+	table string
+}
+
+// ErrNotIndexed is returned, wrapped, by OwnershipIndex queries for blocks
+// outside of those indexed for the contract.
+var ErrNotIndexed = errors.New("block not indexed")
+
+// NewOwnershipIndex returns an OwnershipIndex using the named table, which MAY
+// be schema-qualified, and a second table with the same name suffixed by
+// "_progress". The tables can be created with CreateTables().
+func NewOwnershipIndex(db dbtx.Beginner, table string) (*OwnershipIndex, error) {
+	if err := dbtx.ValidatePgTableName(table); err != nil {
+		return nil, err
+	}
+	return &OwnershipIndex{
+		db:    dbtx.Transactor{Beginner: db},
+		table: table,
+	}, nil
+}
+
+func (x *OwnershipIndex) progressTable() string {
+	return x.table + "_progress"
+}
+
+// CreateTables creates the index's tables if they don't already exist.
+func (x *OwnershipIndex) CreateTables(ctx context.Context) error {
+	// Token IDs are stored as 32-byte, big-endian words, and every Transfer as
+	// a new row, keyed by its position in the chain; burns have the zero
+	// address as owner.
+	transfers := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	contract bytea NOT NULL,
+	token_id bytea NOT NULL,
+	block_number bigint NOT NULL,
+	log_index integer NOT NULL,
+	owner bytea NOT NULL,
+	PRIMARY KEY(contract, token_id, block_number, log_index)
+)`, x.table)
+
+	// All of the contract's Transfers have been recorded in blocks
+	// [first_block, last_block].
+	progress := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	contract bytea NOT NULL,
+	first_block bigint NOT NULL,
+	last_block bigint NOT NULL,
+	updated_at timestamp with time zone NOT NULL DEFAULT now(),
+	PRIMARY KEY(contract)
+)`, x.progressTable())
+
+	return x.db.Do(ctx, nil, func(tx *sql.Tx) error {
+		for _, qry := range []string{transfers, progress} {
+			if _, err := tx.ExecContext(ctx, qry); err != nil {
+				return fmt.Errorf("creating %T tables: %v", x, err)
+			}
+		}
+		return nil
+	})
+}
+
+// An OwnershipTransfer is a change in ownership of a single token, as recorded
+// by an OwnershipIndex.
+type OwnershipTransfer struct {
+	Contract common.Address
+	TokenID  *big.Int
+	// To is the new owner; the zero address if the token was burnt.
+	To       common.Address
+	Block    uint64
+	LogIndex uint
+}
+
+// OwnershipTransferFromLog parses an ERC721 Transfer log. The returned boolean
+// is false if the log isn't such a Transfer; e.g. if it is an ERC20 Transfer,
+// which has the same topic but a non-indexed value.
+func OwnershipTransferFromLog(l *types.Log) (OwnershipTransfer, bool) {
+	if len(l.Topics) != 4 || l.Topics[0] != eth.ERC721TransferTopic {
+		return OwnershipTransfer{}, false
+	}
+	return OwnershipTransfer{
+		Contract: l.Address,
+		TokenID:  l.Topics[3].Big(),
+		To:       common.BytesToAddress(l.Topics[2].Bytes()),
+		Block:    l.BlockNumber,
+		LogIndex: l.Index,
+	}, true
+}
+
+// OwnershipTransfersFromBlock returns all ERC721 Transfers in the Hydrant
+// block, in the order in which they were emitted. The events MUST have been
+// requested with the signature returned by firehose.ERC721TransferEvent();
+// other events are ignored.
+func OwnershipTransfersFromBlock(b *ethpb.Block) ([]OwnershipTransfer, error) {
+	var transfers []OwnershipTransfer
+	for _, tx := range b.GetTransactions() {
+		for _, ev := range tx.GetLogs() {
+			args := ev.GetArgumentsByName()
+			if ev.GetName() != "Transfer" || len(ev.GetArguments()) != 3 || args["tokenId"] == nil || !args["tokenId"].GetIndexed() {
+				continue
+			}
+
+			to, err := args["to"].AsAddress()
+			if err != nil {
+				return nil, fmt.Errorf("block %d, log %d: Transfer.to: %v", b.GetNumber(), ev.GetLogIndex(), err)
+			}
+			id, err := args["tokenId"].AsUint256()
+			if err != nil {
+				return nil, fmt.Errorf("block %d, log %d: Transfer.tokenId: %v", b.GetNumber(), ev.GetLogIndex(), err)
+			}
+
+			transfers = append(transfers, OwnershipTransfer{
+				Contract: common.BytesToAddress(ev.GetEmitter().GetBytes()),
+				TokenID:  id.ToBig(),
+				To:       to,
+				Block:    b.GetNumber(),
+				LogIndex: uint(ev.GetLogIndex()),
+			})
+		}
+	}
+	return transfers, nil
+}
+
+// Apply records the transfers, which MUST be all of those in blocks [from,
+// through] for each of the contracts, and marks those blocks as indexed. The
+// contracts MUST include those of all transfers.
+//
+// Blocks MAY be replayed but MUST NOT be skipped: once a contract has been
+// indexed, [from, through] MUST overlap or be adjacent to the blocks already
+// indexed for it, otherwise an error is returned and nothing is recorded. The
+// first call for a contract determines the earliest block that can be queried,
+// so from SHOULD be the block in which the contract was deployed.
+func (x *OwnershipIndex) Apply(ctx context.Context, contracts []common.Address, from, through uint64, transfers []OwnershipTransfer) error {
+	return x.db.Do(ctx, nil, func(tx *sql.Tx) error {
+		return x.ApplyTx(ctx, tx, contracts, from, through, transfers)
+	})
+}
+
+// ApplyTx is equivalent to Apply() but uses an existing transaction, allowing
+// consumers to atomically commit a Firehose cursor alongside the transfers;
+// see firehose.PgCursorStore.StoreTx().
+func (x *OwnershipIndex) ApplyTx(ctx context.Context, tx *sql.Tx, contracts []common.Address, from, through uint64, transfers []OwnershipTransfer) error {
+	if from > through {
+		return fmt.Errorf("invalid block range [%d, %d]", from, through)
+	}
+
+	indexed := make(map[common.Address]bool, len(contracts))
+	for _, c := range contracts {
+		indexed[c] = true
+
+		first, last, ok, err := x.indexedRange(ctx, tx, c, true)
+		if err != nil {
+			return err
+		}
+		// Overflow of last+1 is impossible as block numbers are stored as
+		// signed 64-bit integers.
+		if ok && (from > last+1 || through+1 < first) {
+			return fmt.Errorf("blocks [%d, %d] not contiguous with blocks [%d, %d] indexed for %v", from, through, first, last, c)
+		}
+	}
+
+	insert := fmt.Sprintf(`
+INSERT INTO %s (contract, token_id, block_number, log_index, owner) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (contract, token_id, block_number, log_index) DO NOTHING`, x.table)
+
+	for _, t := range transfers {
+		if !indexed[t.Contract] {
+			return fmt.Errorf("transfer in block %d, log %d, from contract %v not in indexed contracts", t.Block, t.LogIndex, t.Contract)
+		}
+		if t.Block < from || t.Block > through {
+			return fmt.Errorf("transfer in block %d, log %d, outside of blocks [%d, %d] being marked as indexed", t.Block, t.LogIndex, from, through)
+		}
+		if !validTokenID(t.TokenID) {
+			return fmt.Errorf("transfer in block %d, log %d, with invalid token ID %v", t.Block, t.LogIndex, t.TokenID)
+		}
+		if _, err := tx.ExecContext(ctx, insert, t.Contract.Bytes(), common.BigToHash(t.TokenID).Bytes(), t.Block, t.LogIndex, t.To.Bytes()); err != nil {
+			return fmt.Errorf("recording transfer of %v token %v in block %d, log %d: %v", t.Contract, t.TokenID, t.Block, t.LogIndex, err)
+		}
+	}
+
+	progress := fmt.Sprintf(`
+INSERT INTO %[1]s (contract, first_block, last_block) VALUES ($1, $2, $3)
+ON CONFLICT (contract) DO UPDATE SET
+	first_block = LEAST(%[1]s.first_block, EXCLUDED.first_block),
+	last_block = GREATEST(%[1]s.last_block, EXCLUDED.last_block),
+	updated_at = now()`, x.progressTable())
+
+	for _, c := range contracts {
+		if _, err := tx.ExecContext(ctx, progress, c.Bytes(), from, through); err != nil {
+			return fmt.Errorf("recording progress of %v in blocks [%d, %d]: %v", c, from, through, err)
+		}
+	}
+	return nil
+}
+
+// validTokenID returns whether id can be stored as a 32-byte word.
+func validTokenID(id *big.Int) bool {
+	return id != nil && id.Sign() >= 0 && id.BitLen() <= 256
+}
+
+// Undo removes all transfers recorded for the contracts in the block and any
+// later ones, and marks the contracts as indexed only through the preceding
+// block; if that is before the first indexed block, the contracts are no longer
+// indexed at all. It is used to revert blocks orphaned by a chain reorganisation, which
+// Firehose signals with STEP_UNDO in descending order of block number.
+func (x *OwnershipIndex) Undo(ctx context.Context, contracts []common.Address, block uint64) error {
+	return x.db.Do(ctx, nil, func(tx *sql.Tx) error {
+		return x.UndoTx(ctx, tx, contracts, block)
+	})
+}
+
+// UndoTx is equivalent to Undo() but uses an existing transaction; see
+// ApplyTx().
+func (x *OwnershipIndex) UndoTx(ctx context.Context, tx *sql.Tx, contracts []common.Address, block uint64) error {
+	del := fmt.Sprintf(`DELETE FROM %s WHERE contract = $1 AND block_number >= $2`, x.table)
+
+	// GREATEST() in ApplyTx() only ever moves progress forwards so it has to be
+	// explicitly rolled back, and removed entirely if the first block is undone.
+	// The UPDATE is a no-op for block 0 as no range remains for it to match.
+	removeProgress := fmt.Sprintf(`DELETE FROM %s WHERE contract = $1 AND first_block >= $2`, x.progressTable())
+	rollback := fmt.Sprintf(`
+UPDATE %s SET last_block = $2::bigint - 1, updated_at = now()
+WHERE contract = $1 AND last_block >= $2`, x.progressTable())
+
+	for _, c := range contracts {
+		if _, err := tx.ExecContext(ctx, del, c.Bytes(), block); err != nil {
+			return fmt.Errorf("deleting transfers of %v from block %d: %v", c, block, err)
+		}
+		for _, qry := range []string{removeProgress, rollback} {
+			if _, err := tx.ExecContext(ctx, qry, c.Bytes(), block); err != nil {
+				return fmt.Errorf("rolling back progress of %v before block %d: %v", c, block, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyBlock is a convenience wrapper for Apply(), using the transfers
+// returned by OwnershipTransfersFromBlock() and marking the block as indexed
+// for all of the contracts. If the response's FirehoseStep is STEP_UNDO, the
+// block is instead reverted with Undo(). The contracts SHOULD be those in the
+// EventsRequest, even if they emitted no Transfers in the block, otherwise
+// progress isn't recorded for them.
+func (x *OwnershipIndex) ApplyBlock(ctx context.Context, contracts []common.Address, resp *svcpb.BlockResponse) error {
+	b := resp.GetBlock()
+	if resp.GetFirehoseStep() == hosepb.ForkStep_STEP_UNDO {
+		return x.Undo(ctx, contracts, b.GetNumber())
+	}
+
+	transfers, err := OwnershipTransfersFromBlock(b)
+	if err != nil {
+		return err
+	}
+	return x.Apply(ctx, contracts, b.GetNumber(), b.GetNumber(), transfers)
+}
+
+// IndexLogs scans the contract's Transfer logs in blocks [from, to] and
+// records them, committing every maxRange blocks (2000 if zero) so that
+// progress is kept if IndexLogs() is interrupted. Blocks that have already
+// been indexed are skipped, so from SHOULD be the block in which the contract
+// was deployed, even when resuming; an error is returned if it is before the
+// first indexed block. Logs are scanned with
+// eth.Subscriber.Range(), which doesn't detect reorganisations, so to SHOULD
+// be a finalised block; ApplyBlock() handles reorganisations for unfinalised
+// blocks.
+func (x *OwnershipIndex) IndexLogs(ctx context.Context, src eth.LogSource, contract common.Address, from, to, maxRange uint64) error {
+	if maxRange == 0 {
+		maxRange = 2000
+	}
+	var (
+		first, last uint64
+		ok          bool
+	)
+	err := x.db.Do(ctx, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+		var err error
+		first, last, ok, err = x.indexedRange(ctx, tx, contract, false)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if ok && from < first {
+		return fmt.Errorf("%v already indexed from block %d; can't index earlier blocks from %d", contract, first, from)
+	}
+	if ok && from <= last {
+		from = last + 1
+	}
+
+	sub := eth.NewSubscriber(src, ethereum.FilterQuery{
+		Addresses: []common.Address{contract},
+		Topics:    [][]common.Hash{{eth.ERC721TransferTopic}},
+	})
+	sub.MaxBackfillRange = maxRange
+
+	for start := from; start <= to; start += maxRange {
+		end := start + maxRange - 1
+		if end > to || end < start { // the latter on overflow
+			end = to
+		}
+
+		var transfers []OwnershipTransfer
+		err := sub.Range(ctx, start, end, func(l types.Log) error {
+			if t, ok := OwnershipTransferFromLog(&l); ok {
+				transfers = append(transfers, t)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("scanning %v Transfers in blocks [%d, %d]: %v", contract, start, end, err)
+		}
+		if err := x.Apply(ctx, []common.Address{contract}, start, end, transfers); err != nil {
+			return err
+		}
+		if end == to {
+			break
+		}
+	}
+	return nil
+}
+
+// IndexedThrough returns the last block for which all of the contract's
+// Transfers have been recorded. The returned boolean is false if no blocks have
+// been indexed.
+func (x *OwnershipIndex) IndexedThrough(ctx context.Context, contract common.Address) (uint64, bool, error) {
+	var (
+		last uint64
+		ok   bool
+	)
+	err := x.db.Do(ctx, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+		var err error
+		_, last, ok, err = x.indexedRange(ctx, tx, contract, false)
+		return err
+	})
+	return last, ok, err
+}
+
+// indexedRange returns the first and last blocks for which all of the
+// contract's Transfers have been recorded, locking the progress row if
+// forUpdate is true. The returned boolean is false if no blocks have been
+// indexed.
+func (x *OwnershipIndex) indexedRange(ctx context.Context, tx *sql.Tx, contract common.Address, forUpdate bool) (uint64, uint64, bool, error) {
+	qry := fmt.Sprintf(`SELECT first_block, last_block FROM %s WHERE contract = $1`, x.progressTable())
+	if forUpdate {
+		qry += ` FOR UPDATE`
+	}
+
+	var first, last uint64
+	switch err := tx.QueryRowContext(ctx, qry, contract.Bytes()).Scan(&first, &last); {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, 0, false, nil
+	case err != nil:
+		return 0, 0, false, fmt.Errorf("loading progress of %v: %v", contract, err)
+	}
+	return first, last, true, nil
+}
+
+// checkIndexed returns an error wrapping ErrNotIndexed if the block is outside
+// of those indexed for the contract.
+func (x *OwnershipIndex) checkIndexed(ctx context.Context, tx *sql.Tx, contract common.Address, block uint64) error {
+	first, last, ok, err := x.indexedRange(ctx, tx, contract, false)
+	if err != nil {
+		return err
+	}
+	if !ok || block < first || block > last {
+		return fmt.Errorf("%w: %v indexed in blocks [%d, %d] (any blocks? %t); requested %d", ErrNotIndexed, contract, first, last, ok, block)
+	}
+	return nil
+}
+
+// OwnerAt returns the owner of the token at the end of the block. The zero
+// address is returned if the token hadn't been minted, or had been burnt, by
+// then.
+func (x *OwnershipIndex) OwnerAt(ctx context.Context, contract common.Address, tokenID *big.Int, block uint64) (common.Address, error) {
+	if !validTokenID(tokenID) {
+		return common.Address{}, fmt.Errorf("invalid token ID %v", tokenID)
+	}
+
+	qry := fmt.Sprintf(`
+SELECT owner FROM %s
+WHERE contract = $1 AND token_id = $2 AND block_number <= $3
+ORDER BY block_number DESC, log_index DESC
+LIMIT 1`, x.table)
+
+	var owner common.Address
+	err := x.db.Do(ctx, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+		if err := x.checkIndexed(ctx, tx, contract, block); err != nil {
+			return err
+		}
+
+		var buf []byte
+		switch err := tx.QueryRowContext(ctx, qry, contract.Bytes(), common.BigToHash(tokenID).Bytes(), block).Scan(&buf); {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil
+		case err != nil:
+			return fmt.Errorf("querying owner of %v token %v at block %d: %v", contract, tokenID, block, err)
+		}
+		owner = common.BytesToAddress(buf)
+		return nil
+	})
+	return owner, err
+}
+
+// HoldersAt returns the number of tokens held by every address at the end of
+// the block, equivalent to a single column of the cmd/holders output.
+func (x *OwnershipIndex) HoldersAt(ctx context.Context, contract common.Address, block uint64) (map[common.Address]uint64, error) {
+	qry := fmt.Sprintf(`
+SELECT owner, count(*) FROM (
+	SELECT DISTINCT ON (token_id) owner FROM %s
+	WHERE contract = $1 AND block_number <= $2
+	ORDER BY token_id, block_number DESC, log_index DESC
+) AS owners
+WHERE owner <> $3
+GROUP BY owner`, x.table)
+
+	holders := make(map[common.Address]uint64)
+	err := x.db.Do(ctx, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+		if err := x.checkIndexed(ctx, tx, contract, block); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, qry, contract.Bytes(), block, common.Address{}.Bytes())
+		if err != nil {
+			return fmt.Errorf("querying holders of %v at block %d: %v", contract, block, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				buf []byte
+				n   uint64
+			)
+			if err := rows.Scan(&buf, &n); err != nil {
+				return fmt.Errorf("scanning holders of %v at block %d: %v", contract, block, err)
+			}
+			holders[common.BytesToAddress(buf)] = n
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return holders, nil
+}
@@ -0,0 +1,288 @@
+package erc_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+	hosepb "github.com/streamingfast/pbgo/sf/firehose/v2"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/spawner"
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+
+	_ "github.com/jackc/pgx/v4/stdlib" // postgres driver
+)
+
+func newOwnershipIndex(ctx context.Context, t *testing.T) *erc.OwnershipIndex {
+	t.Helper()
+
+	conn := spawner.NewPostgresT(ctx, t, "15", time.Minute)
+	db, err := sql.Open("pgx", conn.Dsn)
+	if err != nil {
+		t.Fatalf("sql.Open(pgx, %T.Dsn = %q) error %v", conn, conn.Dsn, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	const table = "erc721_owners"
+	idx, err := erc.NewOwnershipIndex(db, table)
+	if err != nil {
+		t.Fatalf("erc.NewOwnershipIndex(db, %q) error %v", table, err)
+	}
+	for i := 0; i < 2; i++ { // idempotent
+		if err := idx.CreateTables(ctx); err != nil {
+			t.Fatalf("%T.CreateTables() error %v", idx, err)
+		}
+	}
+	return idx
+}
+
+var (
+	collection = common.HexToAddress("0xc011ec7")
+	alice      = common.HexToAddress("0xa11ce")
+	bob        = common.HexToAddress("0xb0b")
+	zero       common.Address
+)
+
+func transfer(block uint64, index uint, tokenID int64, to common.Address) erc.OwnershipTransfer {
+	return erc.OwnershipTransfer{
+		Contract: collection,
+		TokenID:  big.NewInt(tokenID),
+		To:       to,
+		Block:    block,
+		LogIndex: index,
+	}
+}
+
+func TestOwnershipIndex(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	idx := newOwnershipIndex(ctx, t)
+
+	contracts := []common.Address{collection}
+
+	if _, err := idx.OwnerAt(ctx, collection, big.NewInt(1), 0); !errors.Is(err, erc.ErrNotIndexed) {
+		t.Errorf("%T.OwnerAt() before indexing; got err %v; want %v", idx, err, erc.ErrNotIndexed)
+	}
+
+	transfers := []erc.OwnershipTransfer{
+		transfer(10, 0, 1, alice),
+		transfer(10, 1, 2, alice),
+		transfer(12, 0, 2, bob),
+		transfer(14, 3, 1, zero), // burn
+	}
+	for i := 0; i < 2; i++ { // replayed
+		if err := idx.Apply(ctx, contracts, 5, 15, transfers); err != nil {
+			t.Fatalf("%T.Apply(…, 5, 15, …) error %v", idx, err)
+		}
+	}
+	// Replaying an earlier block MUST NOT move progress backwards.
+	if err := idx.Apply(ctx, contracts, 10, 10, transfers[:2]); err != nil {
+		t.Fatalf("%T.Apply(…, 10, 10, …) error %v", idx, err)
+	}
+	if got, ok, err := idx.IndexedThrough(ctx, collection); err != nil || !ok || got != 15 {
+		t.Errorf("%T.IndexedThrough() got (%d, %t, %v); want (15, true, nil)", idx, got, ok, err)
+	}
+
+	applyErrTests := []struct {
+		name      string
+		from, to  uint64
+		transfers []erc.OwnershipTransfer
+	}{
+		{name: "gap after", from: 17, to: 20},
+		{name: "gap before", from: 0, to: 3},
+		{name: "inverted range", from: 16, to: 15},
+		{name: "transfer before range", from: 16, to: 16, transfers: []erc.OwnershipTransfer{transfer(15, 0, 3, alice)}},
+		{name: "transfer after range", from: 16, to: 16, transfers: []erc.OwnershipTransfer{transfer(17, 0, 3, alice)}},
+	}
+	for _, tt := range applyErrTests {
+		if err := idx.Apply(ctx, contracts, tt.from, tt.to, tt.transfers); err == nil {
+			t.Errorf("%T.Apply(…, %d, %d, …) [%s] got nil error", idx, tt.from, tt.to, tt.name)
+		}
+	}
+	if got, ok, err := idx.IndexedThrough(ctx, collection); err != nil || !ok || got != 15 {
+		t.Errorf("%T.IndexedThrough() after rejected Apply() got (%d, %t, %v); want (15, true, nil)", idx, got, ok, err)
+	}
+
+	ownerTests := []struct {
+		tokenID int64
+		block   uint64
+		want    common.Address
+	}{
+		{tokenID: 1, block: 5, want: zero}, // before mint
+		{tokenID: 1, block: 10, want: alice},
+		{tokenID: 1, block: 13, want: alice},
+		{tokenID: 1, block: 14, want: zero}, // after burn
+		{tokenID: 2, block: 11, want: alice},
+		{tokenID: 2, block: 12, want: bob},
+		{tokenID: 3, block: 15, want: zero}, // never minted
+	}
+	for _, tt := range ownerTests {
+		got, err := idx.OwnerAt(ctx, collection, big.NewInt(tt.tokenID), tt.block)
+		if err != nil || got != tt.want {
+			t.Errorf("%T.OwnerAt(token %d, block %d) got (%v, %v); want (%v, nil)", idx, tt.tokenID, tt.block, got, err, tt.want)
+		}
+	}
+
+	if _, err := idx.OwnerAt(ctx, collection, nil, 10); err == nil {
+		t.Errorf("%T.OwnerAt(nil token ID) got nil error", idx)
+	}
+	for _, block := range []uint64{4, 16} {
+		if _, err := idx.OwnerAt(ctx, collection, big.NewInt(1), block); !errors.Is(err, erc.ErrNotIndexed) {
+			t.Errorf("%T.OwnerAt(…, %d [unindexed block]) got err %v; want %v", idx, block, err, erc.ErrNotIndexed)
+		}
+	}
+
+	holderTests := []struct {
+		block uint64
+		want  map[common.Address]uint64
+	}{
+		{block: 9, want: map[common.Address]uint64{}},
+		{block: 10, want: map[common.Address]uint64{alice: 2}},
+		{block: 12, want: map[common.Address]uint64{alice: 1, bob: 1}},
+		{block: 14, want: map[common.Address]uint64{bob: 1}},
+	}
+	for _, tt := range holderTests {
+		got, err := idx.HoldersAt(ctx, collection, tt.block)
+		if err != nil {
+			t.Errorf("%T.HoldersAt(%d) error %v", idx, tt.block, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%T.HoldersAt(%d) diff (-want +got):\n%s", idx, tt.block, diff)
+		}
+	}
+	for _, block := range []uint64{4, 16} {
+		if _, err := idx.HoldersAt(ctx, collection, block); !errors.Is(err, erc.ErrNotIndexed) {
+			t.Errorf("%T.HoldersAt(%d [unindexed block]) got err %v; want %v", idx, block, err, erc.ErrNotIndexed)
+		}
+	}
+
+	t.Run("undo", func(t *testing.T) {
+		undo := &svcpb.BlockResponse{
+			Block:        &ethpb.Block{Number: 14},
+			FirehoseStep: hosepb.ForkStep_STEP_UNDO,
+		}
+		if err := idx.ApplyBlock(ctx, contracts, undo); err != nil {
+			t.Fatalf("%T.ApplyBlock([STEP_UNDO block 14]) error %v", idx, err)
+		}
+		if got, ok, err := idx.IndexedThrough(ctx, collection); err != nil || !ok || got != 13 {
+			t.Errorf("%T.IndexedThrough() after undo got (%d, %t, %v); want (13, true, nil)", idx, got, ok, err)
+		}
+		if _, err := idx.OwnerAt(ctx, collection, big.NewInt(1), 14); !errors.Is(err, erc.ErrNotIndexed) {
+			t.Errorf("%T.OwnerAt(…, [undone block]) got err %v; want %v", idx, err, erc.ErrNotIndexed)
+		}
+
+		// The canonical replacement of block 14 doesn't contain the burn.
+		replacement := &svcpb.BlockResponse{
+			Block:        &ethpb.Block{Number: 14},
+			FirehoseStep: hosepb.ForkStep_STEP_NEW,
+		}
+		if err := idx.ApplyBlock(ctx, contracts, replacement); err != nil {
+			t.Fatalf("%T.ApplyBlock([STEP_NEW block 14]) error %v", idx, err)
+		}
+		if got, err := idx.OwnerAt(ctx, collection, big.NewInt(1), 14); err != nil || got != alice {
+			t.Errorf("%T.OwnerAt(token 1, block 14) after reorg got (%v, %v); want (%v, nil)", idx, got, err, alice)
+		}
+
+		// Undoing the first indexed block removes all progress.
+		if err := idx.Undo(ctx, contracts, 5); err != nil {
+			t.Fatalf("%T.Undo(…, 5) error %v", idx, err)
+		}
+		if _, ok, err := idx.IndexedThrough(ctx, collection); err != nil || ok {
+			t.Errorf("%T.IndexedThrough() after undoing first block got (_, %t, %v); want (_, false, nil)", idx, ok, err)
+		}
+	})
+}
+
+// fakeLogSource is an eth.LogSource that serves FilterLogs() from an in-memory
+// set of logs, recording the requested ranges.
+type fakeLogSource struct {
+	logs []types.Log
+	head uint64
+
+	mu        sync.Mutex
+	requested [][2]uint64
+}
+
+func (f *fakeLogSource) BlockNumber(context.Context) (uint64, error) {
+	return f.head, nil
+}
+
+func (f *fakeLogSource) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+
+	f.mu.Lock()
+	f.requested = append(f.requested, [2]uint64{from, to})
+	f.mu.Unlock()
+
+	var logs []types.Log
+	for _, l := range f.logs {
+		if l.BlockNumber >= from && l.BlockNumber <= to {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func (f *fakeLogSource) SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, errors.New("unsupported")
+}
+
+func TestOwnershipIndexIndexLogs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	idx := newOwnershipIndex(ctx, t)
+
+	transferLog := func(block uint64, index uint, from, to common.Address, tokenID int64) types.Log {
+		return types.Log{
+			Address: collection,
+			Topics: []common.Hash{
+				eth.ERC721TransferTopic,
+				common.BytesToHash(from.Bytes()),
+				common.BytesToHash(to.Bytes()),
+				common.BigToHash(big.NewInt(tokenID)),
+			},
+			BlockNumber: block,
+			Index:       index,
+		}
+	}
+
+	src := &fakeLogSource{
+		logs: []types.Log{
+			transferLog(1, 0, zero, alice, 1),
+			transferLog(3, 0, alice, bob, 1),
+			transferLog(7, 0, bob, alice, 1),
+		},
+		head: 10,
+	}
+
+	const maxRange = 2
+	if err := idx.IndexLogs(ctx, src, collection, 0, 5, maxRange); err != nil {
+		t.Fatalf("%T.IndexLogs(…, 0, 5, %d) error %v", idx, maxRange, err)
+	}
+	if got, err := idx.OwnerAt(ctx, collection, big.NewInt(1), 5); err != nil || got != bob {
+		t.Errorf("%T.OwnerAt(token 1, block 5) got (%v, %v); want (%v, nil)", idx, got, err, bob)
+	}
+
+	src.requested = nil
+	if err := idx.IndexLogs(ctx, src, collection, 0, 8, maxRange); err != nil {
+		t.Fatalf("%T.IndexLogs(…, 0, 8, %d) error %v", idx, maxRange, err)
+	}
+	if diff := cmp.Diff([][2]uint64{{6, 7}, {8, 8}}, src.requested); diff != "" {
+		t.Errorf("%T.IndexLogs() when resuming; FilterLogs() ranges diff (-want +got):\n%s", idx, diff)
+	}
+	if got, err := idx.OwnerAt(ctx, collection, big.NewInt(1), 8); err != nil || got != alice {
+		t.Errorf("%T.OwnerAt(token 1, block 8) got (%v, %v); want (%v, nil)", idx, got, err, alice)
+	}
+}
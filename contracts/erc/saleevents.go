@@ -0,0 +1,53 @@
+package erc
+
+import (
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+)
+
+// WyvernOrdersMatchedEvent returns the protobuf signature of the Wyvern
+// OrdersMatched event, for use in Hydrant EventsRequests. Wyvern sales don't
+// identify the token, which is only available from the ERC721 Transfer in the
+// same transaction, so requests SHOULD also include
+// firehose.ERC721TransferEvent().
+//
+// The Seaport and Blur sale events have tuple arguments, which can't be
+// represented by an ethpb.Value, so aren't available as signatures; use
+// DecodeSales() on transaction receipts instead.
+func WyvernOrdersMatchedEvent() *ethpb.Event {
+	return &ethpb.Event{
+		Name: "OrdersMatched",
+		Arguments: []*ethpb.Argument{
+			ethpb.NewArgument("buyHash", &ethpb.Value_Bytes32{}, false),
+			ethpb.NewArgument("sellHash", &ethpb.Value_Bytes32{}, false),
+			ethpb.NewArgument("maker", &ethpb.Value_Address{}, true),
+			ethpb.NewArgument("taker", &ethpb.Value_Address{}, true),
+			ethpb.NewArgument("price", &ethpb.Value_Uint256{}, false),
+			ethpb.NewArgument("metadata", &ethpb.Value_Bytes32{}, true),
+		},
+	}
+}
+
+// SaleEvents returns the protobuf signatures of all marketplace sale events
+// that can be represented as such, along with any other events required to
+// decode them, for use in Hydrant EventsRequests. See
+// WyvernOrdersMatchedEvent() re limitations.
+func SaleEvents() []*ethpb.Event {
+	return []*ethpb.Event{
+		WyvernOrdersMatchedEvent(),
+		erc721TransferEvent(),
+	}
+}
+
+// erc721TransferEvent returns the protobuf signature of an ERC721 Transfer
+// event. It is equivalent to firehose.ERC721TransferEvent(), which isn't used
+// so that this package doesn't depend on the Hydrant server.
+func erc721TransferEvent() *ethpb.Event {
+	return &ethpb.Event{
+		Name: "Transfer",
+		Arguments: []*ethpb.Argument{
+			ethpb.NewArgument("from", &ethpb.Value_Address{}, true),
+			ethpb.NewArgument("to", &ethpb.Value_Address{}, true),
+			ethpb.NewArgument("tokenId", &ethpb.Value_Uint256{}, true),
+		},
+	}
+}
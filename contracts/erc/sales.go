@@ -0,0 +1,478 @@
+package erc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// A Marketplace is an NFT exchange protocol whose sale events can be decoded
+// by DecodeSales().
+type Marketplace string
+
+// Marketplaces supported by DecodeSales().
+const (
+	Seaport Marketplace = "Seaport"
+	Wyvern  Marketplace = "Wyvern"
+	Blur    Marketplace = "Blur"
+)
+
+// MarketplaceAddresses are the canonical deployments of each Marketplace.
+// Events emitted by other contracts are ignored by DecodeSales() as they could
+// otherwise be spoofed.
+var MarketplaceAddresses = map[Marketplace][]common.Address{
+	Seaport: {
+		common.HexToAddress("0x00000000006c3852cbEf3e08E8dF289169EdE581"), // v1.1
+		common.HexToAddress("0x00000000000001ad428e4906aE43D8F9852d0dD6"), // v1.4
+		common.HexToAddress("0x00000000000000ADc04C56Bf30aC9d3c0aAF14dC"), // v1.5
+		common.HexToAddress("0x0000000000000068F116a894984e2DB1123eB395"), // v1.6
+	},
+	Wyvern: {
+		common.HexToAddress("0x7Be8076f4EA4A4AD08075C2508e481d6C946D12b"), // OpenSea v1
+		common.HexToAddress("0x7f268357A8c2552623316e2562D90e642bB538E5"), // OpenSea v2
+	},
+	Blur: {
+		common.HexToAddress("0x000000000000Ad05Ccc4F10045630fb830B95127"), // BlurExchange
+	},
+}
+
+// A Sale is a marketplace sale of a single token, normalised across
+// Marketplaces.
+type Sale struct {
+	Marketplace Marketplace
+	// Collection and TokenID identify the token sold.
+	Collection common.Address
+	TokenID    *big.Int
+	// Price is the total amount paid by the Buyer, including marketplace and
+	// creator fees, denominated in the Currency.
+	Price *big.Int
+	// Currency is the ERC20 contract in which the Price was paid, or the zero
+	// address for the chain's native token.
+	Currency      common.Address
+	Buyer, Seller common.Address
+	// Log is the marketplace's sale event.
+	Log *types.Log
+}
+
+// DecodeSales returns all single-token sales on supported Marketplaces in the
+// transaction, in log order. Sales of bundles, or in multiple currencies, are
+// ignored as the price of each token can't be determined.
+//
+// Seaport and Blur sales are decoded from their respective events alone. The
+// token of a Wyvern sale is only present in the ERC721 Transfer emitted in the
+// same order, so the receipt's other logs are also used.
+//
+// Seaport's matchOrders() and matchAdvancedOrders() emit an OrderFulfilled
+// event for each of the matched orders, all of which describe the same sale, so
+// at most one Seaport Sale is returned per token in the receipt.
+func DecodeSales(rcpt *types.Receipt) ([]*Sale, error) {
+	var (
+		sales []*Sale
+		// orderStart is the index, in rcpt.Logs, of the first log emitted
+		// after the last sale event.
+		orderStart int
+		// seaport maps tokens to Seaport Sales already in sales.
+		seaport = make(map[seaportToken]*Sale)
+	)
+	for i, l := range rcpt.Logs {
+		var (
+			s   *Sale
+			err error
+		)
+		switch {
+		case isFrom(l, Seaport) && hasTopic0(l, seaportOrderFulfilled.ID):
+			s, err = decodeSeaport(l)
+		case isFrom(l, Blur) && hasTopic0(l, blurOrdersMatched.ID):
+			s, err = decodeBlur(l)
+		case isFrom(l, Wyvern) && hasTopic0(l, wyvernOrdersMatched.ID):
+			s, err = decodeWyvern(l, rcpt.Logs[orderStart:i])
+		default:
+			continue
+		}
+		orderStart = i + 1
+
+		if err != nil {
+			return nil, fmt.Errorf("tx %v log index %d: %v", l.TxHash, l.Index, err)
+		}
+		if s == nil {
+			continue
+		}
+		if s.Marketplace == Seaport {
+			tok := seaportToken{s.Collection, s.TokenID.String()}
+			if prev, ok := seaport[tok]; ok {
+				prev.mergeMatched(s)
+				continue
+			}
+			seaport[tok] = s
+		}
+		s.Log = l
+		sales = append(sales, s)
+	}
+	return sales, nil
+}
+
+// seaportToken identifies a token for deduplication of matched Seaport orders.
+type seaportToken struct {
+	collection common.Address
+	tokenID    string
+}
+
+// mergeMatched merges the counter-order of a Seaport match into s. Matched
+// orders have the zero address as their recipient, so each order only
+// identifies its own offerer as either the buyer or the seller.
+func (s *Sale) mergeMatched(counter *Sale) {
+	if s.Buyer == (common.Address{}) {
+		s.Buyer = counter.Buyer
+	}
+	if s.Seller == (common.Address{}) {
+		s.Seller = counter.Seller
+	}
+}
+
+func isFrom(l *types.Log, m Marketplace) bool {
+	for _, a := range MarketplaceAddresses[m] {
+		if l.Address == a {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTopic0(l *types.Log, id common.Hash) bool {
+	return len(l.Topics) > 0 && l.Topics[0] == id
+}
+
+// mustParseABI parses the ABI JSON, panicking on error.
+func mustParseABI(abiJSON string) *abi.ABI {
+	a, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		panic(fmt.Sprintf("abi.JSON(%q): %v", abiJSON, err))
+	}
+	return &a
+}
+
+// parseLog decodes l, which MUST have been emitted by the named event, using
+// eth.ParseRawLogs().
+func parseLog[T any](a *abi.ABI, eventName string, l *types.Log) (*T, error) {
+	evs, err := eth.ParseRawLogs[T](a, []*types.Log{l}, eventName)
+	if err != nil {
+		return nil, err
+	}
+	if len(evs) != 1 {
+		return nil, fmt.Errorf("%s event with %d topics; incorrect number indexed", eventName, len(l.Topics))
+	}
+	return &evs[0], nil
+}
+
+var (
+	seaportABI            = mustParseABI(seaportABIJSON)
+	seaportOrderFulfilled = seaportABI.Events["OrderFulfilled"]
+)
+
+const seaportABIJSON = `[{
+	"type": "event",
+	"name": "OrderFulfilled",
+	"inputs": [
+		{"name": "orderHash", "type": "bytes32", "indexed": false},
+		{"name": "offerer", "type": "address", "indexed": true},
+		{"name": "zone", "type": "address", "indexed": true},
+		{"name": "recipient", "type": "address", "indexed": false},
+		{"name": "offer", "type": "tuple[]", "indexed": false, "components": [
+			{"name": "itemType", "type": "uint8"},
+			{"name": "token", "type": "address"},
+			{"name": "identifier", "type": "uint256"},
+			{"name": "amount", "type": "uint256"}
+		]},
+		{"name": "consideration", "type": "tuple[]", "indexed": false, "components": [
+			{"name": "itemType", "type": "uint8"},
+			{"name": "token", "type": "address"},
+			{"name": "identifier", "type": "uint256"},
+			{"name": "amount", "type": "uint256"},
+			{"name": "recipient", "type": "address"}
+		]}
+	]
+}]`
+
+// Seaport ItemType values.
+const (
+	seaportNative = iota
+	seaportERC20
+	seaportERC721
+	seaportERC1155
+	seaportERC721WithCriteria
+	seaportERC1155WithCriteria
+)
+
+// seaportItem mirrors Seaport's SpentItem.
+type seaportItem struct {
+	ItemType   uint8
+	Token      common.Address
+	Identifier *big.Int
+	Amount     *big.Int
+}
+
+// seaportReceivedItem mirrors Seaport's ReceivedItem.
+type seaportReceivedItem struct {
+	ItemType   uint8
+	Token      common.Address
+	Identifier *big.Int
+	Amount     *big.Int
+	Recipient  common.Address
+}
+
+type seaportFulfilled struct {
+	OrderHash     [32]byte
+	Offerer       common.Address
+	Zone          common.Address
+	Recipient     common.Address
+	Offer         []seaportItem
+	Consideration []seaportReceivedItem
+}
+
+// decodeSeaport decodes an OrderFulfilled event. The token is offered for an
+// accepted listing and is a consideration item for an accepted bid; in both
+// cases the price is the sum of the other side's payments.
+func decodeSeaport(l *types.Log) (*Sale, error) {
+	ev, err := parseLog[seaportFulfilled](seaportABI, "OrderFulfilled", l)
+	if err != nil {
+		return nil, err
+	}
+
+	consideration := make([]seaportItem, len(ev.Consideration))
+	for i, c := range ev.Consideration {
+		consideration[i] = seaportItem{c.ItemType, c.Token, c.Identifier, c.Amount}
+	}
+
+	var (
+		nft, payment  []seaportItem
+		buyer, seller common.Address
+	)
+	switch {
+	case countSeaportNFTs(ev.Offer) > 0:
+		nft, payment = ev.Offer, consideration
+		buyer, seller = ev.Recipient, ev.Offerer
+	default:
+		nft, payment = consideration, ev.Offer
+		buyer, seller = ev.Offerer, ev.Recipient
+	}
+	if countSeaportNFTs(nft) != 1 || countSeaportNFTs(payment) != 0 {
+		return nil, nil
+	}
+
+	var (
+		currency common.Address
+		price    *big.Int
+	)
+	for _, it := range payment {
+		if it.ItemType != seaportNative && it.ItemType != seaportERC20 {
+			continue
+		}
+		if price == nil {
+			currency, price = it.Token, new(big.Int)
+		}
+		if it.Token != currency {
+			return nil, nil
+		}
+		price.Add(price, it.Amount)
+	}
+	if price == nil {
+		return nil, nil
+	}
+
+	var token seaportItem
+	for _, it := range nft {
+		if isSeaportNFT(it) {
+			token = it
+		}
+	}
+	return &Sale{
+		Marketplace: Seaport,
+		Collection:  token.Token,
+		TokenID:     token.Identifier,
+		Price:       price,
+		Currency:    currency,
+		Buyer:       buyer,
+		Seller:      seller,
+	}, nil
+}
+
+func isSeaportNFT(it seaportItem) bool {
+	switch it.ItemType {
+	case seaportERC721, seaportERC1155, seaportERC721WithCriteria, seaportERC1155WithCriteria:
+		return true
+	}
+	return false
+}
+
+func countSeaportNFTs(items []seaportItem) int {
+	var n int
+	for _, it := range items {
+		if isSeaportNFT(it) {
+			n++
+		}
+	}
+	return n
+}
+
+var (
+	blurABI           = mustParseABI(blurABIJSON)
+	blurOrdersMatched = blurABI.Events["OrdersMatched"]
+)
+
+const blurABIJSON = `[{
+	"type": "event",
+	"name": "OrdersMatched",
+	"inputs": [
+		{"name": "maker", "type": "address", "indexed": true},
+		{"name": "taker", "type": "address", "indexed": true},
+		{"name": "sell", "type": "tuple", "indexed": false, "components": ` + blurOrderComponents + `},
+		{"name": "sellHash", "type": "bytes32", "indexed": false},
+		{"name": "buy", "type": "tuple", "indexed": false, "components": ` + blurOrderComponents + `},
+		{"name": "buyHash", "type": "bytes32", "indexed": false}
+	]
+}]`
+
+const blurOrderComponents = `[
+	{"name": "trader", "type": "address"},
+	{"name": "side", "type": "uint8"},
+	{"name": "matchingPolicy", "type": "address"},
+	{"name": "collection", "type": "address"},
+	{"name": "tokenId", "type": "uint256"},
+	{"name": "amount", "type": "uint256"},
+	{"name": "paymentToken", "type": "address"},
+	{"name": "price", "type": "uint256"},
+	{"name": "listingTime", "type": "uint256"},
+	{"name": "expirationTime", "type": "uint256"},
+	{"name": "fees", "type": "tuple[]", "components": [
+		{"name": "rate", "type": "uint16"},
+		{"name": "recipient", "type": "address"}
+	]},
+	{"name": "salt", "type": "uint256"},
+	{"name": "extraParams", "type": "bytes"}
+]`
+
+// blurOrder mirrors BlurExchange's Order.
+type blurOrder struct {
+	Trader         common.Address
+	Side           uint8
+	MatchingPolicy common.Address
+	Collection     common.Address
+	TokenId        *big.Int
+	Amount         *big.Int
+	PaymentToken   common.Address
+	Price          *big.Int
+	ListingTime    *big.Int
+	ExpirationTime *big.Int
+	Fees           []struct {
+		Rate      uint16
+		Recipient common.Address
+	}
+	Salt        *big.Int
+	ExtraParams []byte
+}
+
+type blurMatched struct {
+	Maker    common.Address
+	Taker    common.Address
+	Sell     blurOrder
+	SellHash [32]byte
+	Buy      blurOrder
+	BuyHash  [32]byte
+}
+
+// decodeBlur decodes an OrdersMatched event. Blur fees are paid by the seller
+// so the sell-side price is that paid by the buyer. Note that the Currency of
+// bids is typically the Blur Pool contract, an ETH wrapper.
+func decodeBlur(l *types.Log) (*Sale, error) {
+	ev, err := parseLog[blurMatched](blurABI, "OrdersMatched", l)
+	if err != nil {
+		return nil, err
+	}
+	if ev.Sell.Amount == nil || ev.Sell.Amount.Cmp(big.NewInt(1)) != 0 {
+		return nil, nil
+	}
+	return &Sale{
+		Marketplace: Blur,
+		Collection:  ev.Sell.Collection,
+		TokenID:     ev.Sell.TokenId,
+		Price:       ev.Sell.Price,
+		Currency:    ev.Sell.PaymentToken,
+		Buyer:       ev.Buy.Trader,
+		Seller:      ev.Sell.Trader,
+	}, nil
+}
+
+var (
+	wyvernABI           = mustParseABI(wyvernABIJSON)
+	wyvernOrdersMatched = wyvernABI.Events["OrdersMatched"]
+)
+
+const wyvernABIJSON = `[{
+	"type": "event",
+	"name": "OrdersMatched",
+	"inputs": [
+		{"name": "buyHash", "type": "bytes32", "indexed": false},
+		{"name": "sellHash", "type": "bytes32", "indexed": false},
+		{"name": "maker", "type": "address", "indexed": true},
+		{"name": "taker", "type": "address", "indexed": true},
+		{"name": "price", "type": "uint256", "indexed": false},
+		{"name": "metadata", "type": "bytes32", "indexed": true}
+	]
+}]`
+
+type wyvernMatched struct {
+	BuyHash  [32]byte
+	SellHash [32]byte
+	Maker    common.Address
+	Taker    common.Address
+	Price    *big.Int
+	Metadata [32]byte
+}
+
+// decodeWyvern decodes an OrdersMatched event, which doesn't identify the token
+// nor the currency. These are instead derived from the logs emitted while
+// executing the order, which MUST be those since the previous sale event. The
+// token is that of the sole ERC721 Transfer, and the currency is that of any
+// ERC20 Transfer from the buyer, defaulting to the native token.
+func decodeWyvern(l *types.Log, orderLogs []*types.Log) (*Sale, error) {
+	ev, err := parseLog[wyvernMatched](wyvernABI, "OrdersMatched", l)
+	if err != nil {
+		return nil, err
+	}
+
+	var nft *types.Log
+	for _, o := range orderLogs {
+		if !hasTopic0(o, eth.ERC721TransferTopic) || len(o.Topics) != 4 {
+			continue
+		}
+		if nft != nil {
+			return nil, nil // bundle
+		}
+		nft = o
+	}
+	if nft == nil {
+		return nil, nil
+	}
+
+	s := &Sale{
+		Marketplace: Wyvern,
+		Collection:  nft.Address,
+		TokenID:     nft.Topics[3].Big(),
+		Price:       ev.Price,
+		Seller:      common.BytesToAddress(nft.Topics[1].Bytes()),
+		Buyer:       common.BytesToAddress(nft.Topics[2].Bytes()),
+	}
+	for _, o := range orderLogs {
+		if hasTopic0(o, eth.ERC721TransferTopic) && len(o.Topics) == 3 && common.BytesToAddress(o.Topics[1].Bytes()) == s.Buyer {
+			s.Currency = o.Address
+			break
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,369 @@
+package erc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// saleReceipt builds a receipt with logs as they are emitted by marketplace
+// contracts, with log indices reflecting their order.
+type saleReceipt struct {
+	t    *testing.T
+	logs []*types.Log
+}
+
+// event appends a log of the named event, with args in the order of the
+// event's inputs.
+func (r *saleReceipt) event(addr common.Address, a *abi.ABI, name string, args ...interface{}) *types.Log {
+	r.t.Helper()
+
+	ev := a.Events[name]
+	var (
+		topics [][]interface{}
+		data   []interface{}
+	)
+	for i, in := range ev.Inputs {
+		if in.Indexed {
+			topics = append(topics, []interface{}{args[i]})
+		} else {
+			data = append(data, args[i])
+		}
+	}
+	packed, err := ev.Inputs.NonIndexed().Pack(data...)
+	if err != nil {
+		r.t.Fatalf("packing %s data: %v", name, err)
+	}
+	idx, err := abi.MakeTopics(topics...)
+	if err != nil {
+		r.t.Fatalf("abi.MakeTopics(%s args): %v", name, err)
+	}
+
+	l := &types.Log{
+		Address: addr,
+		Topics:  []common.Hash{ev.ID},
+		Data:    packed,
+	}
+	for _, tp := range idx {
+		l.Topics = append(l.Topics, tp[0])
+	}
+	return r.append(l)
+}
+
+func (r *saleReceipt) append(l *types.Log) *types.Log {
+	l.Index = uint(len(r.logs))
+	r.logs = append(r.logs, l)
+	return l
+}
+
+// erc721Transfer appends an ERC721 Transfer log.
+func (r *saleReceipt) erc721Transfer(collection, from, to common.Address, tokenID int64) *types.Log {
+	return r.append(&types.Log{
+		Address: collection,
+		Topics: []common.Hash{
+			eth.ERC721TransferTopic,
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+			common.BigToHash(big.NewInt(tokenID)),
+		},
+	})
+}
+
+// erc20Transfer appends an ERC20 Transfer log, which has the same topic as an
+// ERC721 Transfer but the value isn't indexed.
+func (r *saleReceipt) erc20Transfer(token, from, to common.Address, amount *big.Int) *types.Log {
+	return r.append(&types.Log{
+		Address: token,
+		Topics: []common.Hash{
+			eth.ERC721TransferTopic,
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data: common.BigToHash(amount).Bytes(),
+	})
+}
+
+func (r *saleReceipt) receipt() *types.Receipt {
+	return &types.Receipt{Logs: r.logs}
+}
+
+func ether(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e16)) // hundredths
+}
+
+func TestDecodeSales(t *testing.T) {
+	var (
+		seaport = MarketplaceAddresses[Seaport][3]
+		blur    = MarketplaceAddresses[Blur][0]
+		wyvern  = MarketplaceAddresses[Wyvern][1]
+
+		collection = common.HexToAddress("0xc011ec7")
+		weth       = common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+		blurPool   = common.HexToAddress("0x0000000000A39bb272e79075ade125fd351887Ac")
+		seller     = common.HexToAddress("0x5e11e4")
+		buyer      = common.HexToAddress("0xb0e4")
+		feeTo      = common.HexToAddress("0xfee")
+		zone       = common.HexToAddress("0x20e")
+		zero       common.Address
+	)
+
+	nft := func(id int64) seaportItem {
+		return seaportItem{seaportERC721, collection, big.NewInt(id), big.NewInt(1)}
+	}
+	received := func(typ uint8, token common.Address, amount *big.Int, to common.Address) seaportReceivedItem {
+		return seaportReceivedItem{typ, token, new(big.Int), amount, to}
+	}
+	nftTo := func(id int64, to common.Address) seaportReceivedItem {
+		return seaportReceivedItem{seaportERC721, collection, big.NewInt(id), big.NewInt(1), to}
+	}
+	orderFulfilled := func(r *saleReceipt, addr, offerer, recipient common.Address, offer []seaportItem, consideration []seaportReceivedItem) *types.Log {
+		return r.event(addr, seaportABI, "OrderFulfilled", [32]byte{1}, offerer, zone, recipient, offer, consideration)
+	}
+
+	blurOrderFor := func(trader common.Address, side uint8, amount int64) blurOrder {
+		return blurOrder{
+			Trader:         trader,
+			Side:           side,
+			Collection:     collection,
+			TokenId:        big.NewInt(42),
+			Amount:         big.NewInt(amount),
+			PaymentToken:   blurPool,
+			Price:          ether(150),
+			ListingTime:    big.NewInt(0),
+			ExpirationTime: big.NewInt(0),
+			Fees: []struct {
+				Rate      uint16
+				Recipient common.Address
+			}{{50, feeTo}},
+			Salt:        big.NewInt(0),
+			ExtraParams: []byte{},
+		}
+	}
+
+	tests := []struct {
+		name string
+		// build returns the expected sales, with Log set to that of the
+		// respective marketplace event.
+		build func(*saleReceipt) []*Sale
+	}{
+		{
+			name: "Seaport listing",
+			build: func(r *saleReceipt) []*Sale {
+				l := orderFulfilled(r, seaport, seller, buyer,
+					[]seaportItem{nft(7)},
+					[]seaportReceivedItem{
+						received(seaportNative, zero, ether(975), seller),
+						received(seaportNative, zero, ether(25), feeTo),
+					},
+				)
+				return []*Sale{{
+					Marketplace: Seaport,
+					Collection:  collection,
+					TokenID:     big.NewInt(7),
+					Price:       ether(1000),
+					Currency:    zero,
+					Buyer:       buyer,
+					Seller:      seller,
+					Log:         l,
+				}}
+			},
+		},
+		{
+			name: "Seaport bid",
+			build: func(r *saleReceipt) []*Sale {
+				l := orderFulfilled(r, seaport, buyer, seller,
+					[]seaportItem{{seaportERC20, weth, new(big.Int), ether(200)}},
+					[]seaportReceivedItem{
+						nftTo(8, buyer),
+						received(seaportERC20, weth, ether(5), feeTo),
+					},
+				)
+				return []*Sale{{
+					Marketplace: Seaport,
+					Collection:  collection,
+					TokenID:     big.NewInt(8),
+					Price:       ether(200),
+					Currency:    weth,
+					Buyer:       buyer,
+					Seller:      seller,
+					Log:         l,
+				}}
+			},
+		},
+		{
+			name: "Seaport matchOrders counter-order merged",
+			build: func(r *saleReceipt) []*Sale {
+				// Matched orders have the zero address as recipient.
+				l := orderFulfilled(r, seaport, seller, zero,
+					[]seaportItem{nft(9)},
+					[]seaportReceivedItem{received(seaportNative, zero, ether(300), seller)},
+				)
+				orderFulfilled(r, seaport, buyer, zero,
+					[]seaportItem{{seaportNative, zero, new(big.Int), ether(300)}},
+					[]seaportReceivedItem{nftTo(9, buyer)},
+				)
+				return []*Sale{{
+					Marketplace: Seaport,
+					Collection:  collection,
+					TokenID:     big.NewInt(9),
+					Price:       ether(300),
+					Currency:    zero,
+					Buyer:       buyer,
+					Seller:      seller,
+					Log:         l,
+				}}
+			},
+		},
+		{
+			name: "Seaport from non-canonical address",
+			build: func(r *saleReceipt) []*Sale {
+				orderFulfilled(r, common.HexToAddress("0xbad"), seller, buyer,
+					[]seaportItem{nft(7)},
+					[]seaportReceivedItem{received(seaportNative, zero, ether(1), seller)},
+				)
+				return nil
+			},
+		},
+		{
+			name: "Blur OrdersMatched",
+			build: func(r *saleReceipt) []*Sale {
+				l := r.event(blur, blurABI, "OrdersMatched",
+					seller, buyer,
+					blurOrderFor(seller, 1, 1), [32]byte{2},
+					blurOrderFor(buyer, 0, 1), [32]byte{3},
+				)
+				return []*Sale{{
+					Marketplace: Blur,
+					Collection:  collection,
+					TokenID:     big.NewInt(42),
+					Price:       ether(150),
+					Currency:    blurPool,
+					Buyer:       buyer,
+					Seller:      seller,
+					Log:         l,
+				}}
+			},
+		},
+		{
+			name: "Wyvern with ETH",
+			build: func(r *saleReceipt) []*Sale {
+				r.erc721Transfer(collection, seller, buyer, 3)
+				l := r.event(wyvern, wyvernABI, "OrdersMatched", [32]byte{4}, [32]byte{5}, seller, buyer, ether(80), [32]byte{})
+				return []*Sale{{
+					Marketplace: Wyvern,
+					Collection:  collection,
+					TokenID:     big.NewInt(3),
+					Price:       ether(80),
+					Currency:    zero,
+					Buyer:       buyer,
+					Seller:      seller,
+					Log:         l,
+				}}
+			},
+		},
+		{
+			name: "Wyvern with WETH",
+			build: func(r *saleReceipt) []*Sale {
+				r.erc20Transfer(weth, buyer, feeTo, ether(2))
+				r.erc20Transfer(weth, buyer, seller, ether(78))
+				r.erc721Transfer(collection, seller, buyer, 4)
+				l := r.event(wyvern, wyvernABI, "OrdersMatched", [32]byte{4}, [32]byte{5}, buyer, seller, ether(80), [32]byte{})
+				return []*Sale{{
+					Marketplace: Wyvern,
+					Collection:  collection,
+					TokenID:     big.NewInt(4),
+					Price:       ether(80),
+					Currency:    weth,
+					Buyer:       buyer,
+					Seller:      seller,
+					Log:         l,
+				}}
+			},
+		},
+		{
+			name: "Wyvern orders delimited by sale events",
+			build: func(r *saleReceipt) []*Sale {
+				r.erc721Transfer(collection, seller, buyer, 5)
+				l0 := r.event(wyvern, wyvernABI, "OrdersMatched", [32]byte{4}, [32]byte{5}, seller, buyer, ether(10), [32]byte{})
+				r.erc721Transfer(collection, seller, buyer, 6)
+				l1 := r.event(wyvern, wyvernABI, "OrdersMatched", [32]byte{6}, [32]byte{7}, seller, buyer, ether(20), [32]byte{})
+
+				sale := func(id, price int64, l *types.Log) *Sale {
+					return &Sale{
+						Marketplace: Wyvern,
+						Collection:  collection,
+						TokenID:     big.NewInt(id),
+						Price:       ether(price),
+						Buyer:       buyer,
+						Seller:      seller,
+						Log:         l,
+					}
+				}
+				return []*Sale{sale(5, 10, l0), sale(6, 20, l1)}
+			},
+		},
+		{
+			name: "skipped bundles",
+			build: func(r *saleReceipt) []*Sale {
+				orderFulfilled(r, seaport, seller, buyer,
+					[]seaportItem{nft(1), nft(2)},
+					[]seaportReceivedItem{received(seaportNative, zero, ether(100), seller)},
+				)
+				r.erc721Transfer(collection, seller, buyer, 1)
+				r.erc721Transfer(collection, seller, buyer, 2)
+				r.event(wyvern, wyvernABI, "OrdersMatched", [32]byte{4}, [32]byte{5}, seller, buyer, ether(100), [32]byte{})
+				r.event(blur, blurABI, "OrdersMatched",
+					seller, buyer,
+					blurOrderFor(seller, 1, 2), [32]byte{2},
+					blurOrderFor(buyer, 0, 2), [32]byte{3},
+				)
+				return nil
+			},
+		},
+		{
+			name: "Seaport in multiple currencies skipped",
+			build: func(r *saleReceipt) []*Sale {
+				orderFulfilled(r, seaport, seller, buyer,
+					[]seaportItem{nft(1)},
+					[]seaportReceivedItem{
+						received(seaportNative, zero, ether(100), seller),
+						received(seaportERC20, weth, ether(1), feeTo),
+					},
+				)
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &saleReceipt{t: t}
+			want := tt.build(r)
+
+			got, err := DecodeSales(r.receipt())
+			if err != nil {
+				t.Fatalf("DecodeSales() error %v", err)
+			}
+			opts := []cmp.Option{
+				cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 }),
+				cmpopts.EquateEmpty(),
+			}
+			if diff := cmp.Diff(want, got, opts...); diff != "" {
+				t.Errorf("DecodeSales() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestERC721TransferEvent(t *testing.T) {
+	if got, want := erc721TransferEvent().EVMHash(), eth.ERC721TransferTopic; got != want {
+		t.Errorf("erc721TransferEvent().EVMHash() got %v; want eth.ERC721TransferTopic %v", got, want)
+	}
+}
@@ -0,0 +1,219 @@
+// Package erc provides bindings for standard ERC interfaces, and helpers for
+// working with contracts that implement them.
+package erc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// A Standard is an ERC standard that a contract may implement.
+type Standard uint
+
+// Standards detectable by DetectStandards().
+const (
+	ERC165 Standard = iota
+	ERC20
+	ERC721
+	ERC721Metadata
+	ERC721Enumerable
+	ERC1155
+	ERC1155MetadataURI
+	ERC2981
+	ERC4906
+	numStandards
+)
+
+var standardNames = [...]string{
+	ERC165:             "ERC165",
+	ERC20:              "ERC20",
+	ERC721:             "ERC721",
+	ERC721Metadata:     "ERC721Metadata",
+	ERC721Enumerable:   "ERC721Enumerable",
+	ERC1155:            "ERC1155",
+	ERC1155MetadataURI: "ERC1155MetadataURI",
+	ERC2981:            "ERC2981",
+	ERC4906:            "ERC4906",
+}
+
+// String returns the name of the Standard; e.g. "ERC721".
+func (s Standard) String() string {
+	if s < numStandards {
+		return standardNames[s]
+	}
+	return fmt.Sprintf("Standard(%d)", uint(s))
+}
+
+// InterfaceID returns the ERC165 interface ID of the Standard, and a boolean
+// indicating whether it has one. ERC20 pre-dates ERC165 so doesn't.
+func (s Standard) InterfaceID() ([4]byte, bool) {
+	id, ok := interfaceIDs[s]
+	return id, ok
+}
+
+var interfaceIDs = map[Standard][4]byte{
+	ERC165:             {0x01, 0xff, 0xc9, 0xa7},
+	ERC721:             {0x80, 0xac, 0x58, 0xcd},
+	ERC721Metadata:     {0x5b, 0x5e, 0x13, 0x9f},
+	ERC721Enumerable:   {0x78, 0x0e, 0x9d, 0x63},
+	ERC1155:            {0xd9, 0xb6, 0x7a, 0x26},
+	ERC1155MetadataURI: {0x0e, 0x89, 0x34, 0x1c},
+	ERC2981:            {0x2a, 0x55, 0x20, 0x5a},
+	ERC4906:            {0x49, 0x06, 0x49, 0x06},
+}
+
+// Standards is a set of Standards.
+type Standards uint64
+
+// Has reports whether s includes the Standard.
+func (s Standards) Has(std Standard) bool {
+	return s&(1<<std) != 0
+}
+
+// with returns s with std added.
+func (s Standards) with(std Standard) Standards {
+	return s | 1<<std
+}
+
+// String returns a comma-separated list of the Standards in s.
+func (s Standards) String() string {
+	var names []string
+	for std := Standard(0); std < numStandards; std++ {
+		if s.Has(std) {
+			names = append(names, std.String())
+		}
+	}
+	return "{" + strings.Join(names, ", ") + "}"
+}
+
+// selectors are function selectors that, if all present in a contract's
+// bytecode, are used as a fallback indicator that the contract implements the
+// Standard. This is only used for contracts that don't implement ERC165.
+var selectors = map[Standard][][4]byte{
+	ERC20: {
+		{0x18, 0x16, 0x0d, 0xdd}, // totalSupply()
+		{0x70, 0xa0, 0x82, 0x31}, // balanceOf(address)
+		{0xa9, 0x05, 0x9c, 0xbb}, // transfer(address,uint256)
+		{0xdd, 0x62, 0xed, 0x3e}, // allowance(address,address)
+	},
+	ERC721: {
+		{0x70, 0xa0, 0x82, 0x31}, // balanceOf(address)
+		{0x63, 0x52, 0x21, 0x1e}, // ownerOf(uint256)
+		{0x42, 0x84, 0x2e, 0x0e}, // safeTransferFrom(address,address,uint256)
+	},
+	ERC721Metadata: {
+		{0x06, 0xfd, 0xde, 0x03}, // name()
+		{0x95, 0xd8, 0x9b, 0x41}, // symbol()
+		{0xc8, 0x7b, 0x56, 0xdd}, // tokenURI(uint256)
+	},
+	ERC1155: {
+		{0x00, 0xfd, 0xd5, 0x8e}, // balanceOf(address,uint256)
+		{0xf2, 0x42, 0x43, 0x2a}, // safeTransferFrom(address,address,uint256,uint256,bytes)
+	},
+	ERC2981: {
+		{0x2a, 0x55, 0x20, 0x5a}, // royaltyInfo(uint256,uint256)
+	},
+}
+
+// DetectStandards returns the set of Standards implemented by the contract at
+// the address. If the contract implements ERC165, only supportsInterface() is
+// used. Otherwise the contract's bytecode is searched for the function
+// selectors of each Standard; this is a heuristic that doesn't work for
+// proxies and may have false positives for ERC721 vs ERC20, which share a
+// balanceOf() selector, so ERC20 is only reported if ownerOf() is absent.
+//
+// A nil error and empty set are returned if there is no code at the address.
+func DetectStandards(ctx context.Context, backend bind.ContractCaller, addr common.Address) (Standards, error) {
+	code, err := backend.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%T.CodeAt(%v): %v", backend, addr, err)
+	}
+	if len(code) == 0 {
+		return 0, nil
+	}
+
+	c, err := NewIERC165Caller(addr, backend)
+	if err != nil {
+		return 0, fmt.Errorf("NewIERC165Caller(%v): %v", addr, err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+
+	if supportsERC165(opts, c) {
+		found := Standards(0).with(ERC165)
+		for std, id := range interfaceIDs {
+			if std == ERC165 {
+				continue
+			}
+			ok, err := c.SupportsInterface(opts, id)
+			if err != nil {
+				// As supportsERC165() returned true, errors are unexpected
+				// and may indicate a transient node failure.
+				return 0, fmt.Errorf("%v.supportsInterface(%#x) [%v]: %v", addr, id, std, err)
+			}
+			if ok {
+				found = found.with(std)
+			}
+		}
+		return found, nil
+	}
+
+	return sniffBytecode(code), nil
+}
+
+// supportsERC165 performs the ERC165 detection algorithm, which requires that
+// supportsInterface(0x01ffc9a7) is true and supportsInterface(0xffffffff) is
+// false. Any error, including reverts, is treated as lack of support.
+func supportsERC165(opts *bind.CallOpts, c *IERC165Caller) bool {
+	if ok, err := c.SupportsInterface(opts, interfaceIDs[ERC165]); err != nil || !ok {
+		return false
+	}
+	ok, err := c.SupportsInterface(opts, [4]byte{0xff, 0xff, 0xff, 0xff})
+	return err == nil && !ok
+}
+
+// sniffBytecode returns the Standards for which all selectors are pushed onto
+// the stack by the bytecode, which is how the Solidity dispatcher matches
+// them.
+func sniffBytecode(code []byte) Standards {
+	var found Standards
+	for std, sels := range selectors {
+		all := true
+		for _, sel := range sels {
+			if !pushesSelector(code, sel) {
+				all = false
+				break
+			}
+		}
+		if all {
+			found = found.with(std)
+		}
+	}
+
+	if found.Has(ERC721) {
+		found &^= 1 << ERC20
+	} else {
+		found &^= 1 << ERC721Metadata
+	}
+	return found
+}
+
+// EVM opcodes used to push selectors onto the stack.
+const (
+	push3 = 0x62
+	push4 = 0x63
+)
+
+// pushesSelector reports whether the bytecode pushes the selector onto the
+// stack. Selectors with a leading zero byte are pushed with PUSH3 by the
+// optimiser.
+func pushesSelector(code []byte, sel [4]byte) bool {
+	if bytes.Contains(code, append([]byte{push4}, sel[:]...)) {
+		return true
+	}
+	return sel[0] == 0 && bytes.Contains(code, append([]byte{push3}, sel[1:]...))
+}
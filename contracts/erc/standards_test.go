@@ -0,0 +1,142 @@
+package erc
+
+import (
+	"testing"
+)
+
+// pushed returns bytecode that pushes each selector with the opcode, padded
+// with unrelated bytes to ensure that only exact sequences are matched.
+func pushed(op byte, sels ...[4]byte) []byte {
+	code := []byte{0x60, 0x80, 0x60, 0x40} // PUSH1 0x80 PUSH1 0x40
+	for _, sel := range sels {
+		code = append(code, op)
+		if op == push3 {
+			code = append(code, sel[1:]...)
+		} else {
+			code = append(code, sel[:]...)
+		}
+		code = append(code, 0x14, 0x61) // EQ PUSH2
+	}
+	return code
+}
+
+func TestPushesSelector(t *testing.T) {
+	ownerOf := [4]byte{0x63, 0x52, 0x21, 0x1e}
+	balanceOf1155 := [4]byte{0x00, 0xfd, 0xd5, 0x8e}
+
+	tests := []struct {
+		name string
+		code []byte
+		sel  [4]byte
+		want bool
+	}{
+		{
+			name: "empty",
+			sel:  ownerOf,
+			want: false,
+		},
+		{
+			name: "PUSH4",
+			code: pushed(push4, ownerOf),
+			sel:  ownerOf,
+			want: true,
+		},
+		{
+			name: "without PUSH",
+			code: append([]byte{0x00}, ownerOf[:]...),
+			sel:  ownerOf,
+			want: false,
+		},
+		{
+			name: "PUSH3 with leading zero",
+			code: pushed(push3, balanceOf1155),
+			sel:  balanceOf1155,
+			want: true,
+		},
+		{
+			name: "PUSH4 with leading zero",
+			code: pushed(push4, balanceOf1155),
+			sel:  balanceOf1155,
+			want: true,
+		},
+		{
+			name: "PUSH3 without leading zero",
+			code: pushed(push3, ownerOf),
+			sel:  ownerOf,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pushesSelector(tt.code, tt.sel); got != tt.want {
+				t.Errorf("pushesSelector(%#x, %#x) got %t; want %t", tt.code, tt.sel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffBytecode(t *testing.T) {
+	var (
+		erc20    = selectors[ERC20]
+		erc721   = selectors[ERC721]
+		metadata = selectors[ERC721Metadata]
+		erc1155  = selectors[ERC1155]
+	)
+	concat := func(sels ...[][4]byte) [][4]byte {
+		var all [][4]byte
+		for _, s := range sels {
+			all = append(all, s...)
+		}
+		return all
+	}
+
+	tests := []struct {
+		name string
+		code []byte
+		want Standards
+	}{
+		{
+			name: "empty",
+			want: 0,
+		},
+		{
+			name: "ERC20",
+			code: pushed(push4, erc20...),
+			want: Standards(0).with(ERC20),
+		},
+		{
+			name: "incomplete ERC20",
+			code: pushed(push4, erc20[1:]...),
+			want: 0,
+		},
+		{
+			name: "ERC721 shares balanceOf with ERC20",
+			code: pushed(push4, concat(erc20, erc721)...),
+			want: Standards(0).with(ERC721),
+		},
+		{
+			name: "ERC721 with metadata",
+			code: pushed(push4, concat(erc721, metadata)...),
+			want: Standards(0).with(ERC721).with(ERC721Metadata),
+		},
+		{
+			name: "ERC20 metadata is not ERC721Metadata",
+			code: pushed(push4, concat(erc20, metadata)...),
+			want: Standards(0).with(ERC20),
+		},
+		{
+			name: "ERC1155 with PUSH3",
+			code: append(pushed(push3, erc1155[0]), pushed(push4, erc1155[1])...),
+			want: Standards(0).with(ERC1155),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffBytecode(tt.code); got != tt.want {
+				t.Errorf("sniffBytecode(%#x) got %v; want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
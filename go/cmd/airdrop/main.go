@@ -0,0 +1,255 @@
+// Binary airdrop distributes ETH, ERC20, or ERC721 tokens to many recipients.
+// It reads a CSV from stdin, with a header row of either "recipient,amount"
+// (ETH and ERC20; amounts in the smallest unit, e.g. wei) or
+// "recipient,token_id" (ERC721). ETH and ERC20 transfers are batched into calls
+// to the Disperse contract whereas each ERC721 is sent in its own transaction.
+//
+// Total gas and cost are estimated before any transactions are sent; use
+// -dry_run to stop there. Transactions are signed by a hardware wallet or a
+// KMS-backed key and are sent sequentially, each awaiting confirmation before
+// the next.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/ethkms"
+	"github.com/cxkoda/solgo/go/proof"
+	"github.com/cxkoda/solgo/go/usbwallet"
+)
+
+var (
+	assetKind     = flag.String("kind", "", "Type of asset to airdrop; eth, erc20, or erc721")
+	token         = flag.String("token", "", "Address of the ERC20 or ERC721 contract; ignored for ETH")
+	batchSize     = flag.Int("batch_size", 200, "Maximum number of recipients per Disperse transaction; ignored for ERC721")
+	dryRun        = flag.Bool("dry_run", false, "Estimate gas and cost without sending any transactions")
+	approve       = flag.Bool("approve", false, "If the ERC20 allowance of the Disperse contract is insufficient, send an approve() transaction for the total amount")
+	confirmations = flag.Uint64("confirmations", 1, "Number of confirmations to await for each transaction before sending the next")
+
+	signerType = flag.String("signer", "usb", "Transaction signer; usb (hardware wallet) or kms (GCP KMS)")
+	usbIndex   = flag.Uint("usb_index", 0, "0-based account index on the hardware wallet")
+	usbAddress = flag.String("usb_address", "", "Expected address of the hardware-wallet account; required if more than one device is connected")
+	kmsKey     = flag.String("kms_key", "", "Resource name of the GCP KMS key version used to sign; e.g. projects/…/cryptoKeyVersions/1")
+
+	logger = ethlog.NewFromFlag(flag.CommandLine, os.Stderr)
+)
+
+func main() {
+	d := eth.MustNewDialerFromFlag(flag.CommandLine, proof.InfuraMainnetURL())
+	flag.Parse()
+	if err := run(context.Background(), d, os.Stdin); err != nil {
+		exit(err)
+	}
+}
+
+// exit prints err to stderr and exits with code 1.
+func exit(err error) {
+	fmt.Fprint(os.Stderr, err)
+	os.Exit(1)
+}
+
+// run reads the CSV from src and airdrops the assets.
+func run(ctx context.Context, d *eth.Dialer, src io.Reader) error {
+	k := kind(*assetKind)
+	var tokenAddr common.Address
+	switch k {
+	case ethKind:
+	case erc20Kind, erc721Kind:
+		if !common.IsHexAddress(*token) {
+			return fmt.Errorf("invalid -token %q", *token)
+		}
+		tokenAddr = common.HexToAddress(*token)
+	default:
+		return fmt.Errorf("unsupported -kind %q", *assetKind)
+	}
+
+	transfers, err := readTransfers(src, k)
+	if err != nil {
+		return err
+	}
+	if len(transfers) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+
+	client, err := d.Dial(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.Dial(): %v", d, err)
+	}
+	defer client.Close()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.ChainID(): %v", client, err)
+	}
+
+	opts, closeSigner, err := transactOpts(ctx, chainID)
+	if err != nil {
+		return err
+	}
+	defer closeSigner()
+	from := opts.From
+	logger.Info("Sending", ethlog.Address("from", from), ethlog.ChainID(chainID.Uint64()))
+
+	if k == erc20Kind {
+		if err := checkAllowance(ctx, client, opts, tokenAddr, total(transfers)); err != nil {
+			return err
+		}
+	}
+
+	calls, err := planCalls(k, from, tokenAddr, transfers, *batchSize)
+	if err != nil {
+		return err
+	}
+	gas, err := estimateGas(ctx, client, from, calls)
+	if err != nil {
+		return err
+	}
+
+	fees, err := eth.NewFeeEstimator(client).Estimate(ctx)
+	if err != nil {
+		return err
+	}
+	value := new(big.Int)
+	for _, c := range calls {
+		value.Add(value, c.value)
+	}
+	g := new(big.Int).SetUint64(gas)
+	expected := new(big.Int).Mul(g, new(big.Int).Add(fees.BaseFee, fees.GasTipCap))
+	maxCost := new(big.Int).Mul(g, fees.GasFeeCap)
+	logger.Info("Planned", ethlog.Int("recipients", int64(len(transfers))), ethlog.Int("txs", int64(len(calls))), ethlog.Uint("gas", gas))
+	logger.Info("Expected cost", ethlog.String("eth", ether(expected)), ethlog.String("max_eth", ether(maxCost)), ethlog.String("value_eth", ether(value)))
+
+	if *dryRun {
+		return nil
+	}
+
+	fees.Apply(opts)
+	for i, c := range calls {
+		opts.Value = c.value
+		// The estimate doesn't account for state changes by earlier calls.
+		opts.GasLimit = c.gas * 6 / 5
+
+		tx, err := bind.NewBoundContract(c.to, abi.ABI{}, client, client, client).RawTransact(opts, c.data)
+		if err != nil {
+			return fmt.Errorf("sending transaction %d/%d (%s): %v", i+1, len(calls), c.desc, err)
+		}
+		logger.Info("Sent", ethlog.Int("n", int64(i+1)), ethlog.Int("total", int64(len(calls))), ethlog.String("desc", c.desc), ethlog.TxHash(tx.Hash()))
+
+		if _, err := eth.WaitMined(ctx, client, tx, *confirmations); err != nil {
+			return fmt.Errorf("transaction %d/%d (%s): %w", i+1, len(calls), c.desc, err)
+		}
+	}
+	logger.Info("Done")
+	return nil
+}
+
+// transactOpts returns TransactOpts signing with the signer specified by flags.
+// The returned function MUST be called to free resources.
+func transactOpts(ctx context.Context, chainID *big.Int) (*bind.TransactOpts, func(), error) {
+	switch *signerType {
+	case "usb":
+		w, err := usbwallet.NewAny()
+		if err != nil {
+			return nil, nil, fmt.Errorf("usbwallet.NewAny(): %v", err)
+		}
+		logger.Info("Waiting for hardware wallet")
+		if err := w.Wait(ctx); err != nil {
+			w.Close()
+			return nil, nil, fmt.Errorf("%T.Wait(): %v", w, err)
+		}
+
+		var expected *common.Address
+		if *usbAddress != "" {
+			a := common.HexToAddress(*usbAddress)
+			expected = &a
+		}
+		prompt := usbwallet.WithPrompt(func(p usbwallet.Prompt) {
+			logger.Info("Confirm transaction on device", ethlog.TxHash(p.Tx.Hash()))
+		})
+		fn, addr, err := w.SignerFn(uint32(*usbIndex), expected, chainID, prompt, usbwallet.WithSigningContext(ctx))
+		if err != nil {
+			w.Close()
+			return nil, nil, fmt.Errorf("%T.SignerFn(%d, %v, %d): %v", w, *usbIndex, expected, chainID, err)
+		}
+		return &bind.TransactOpts{From: addr, Signer: fn, Context: ctx}, func() { w.Close() }, nil
+
+	case "kms":
+		g, err := ethkms.NewGCP(ctx, *kmsKey, chainID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ethkms.NewGCP(%q): %v", *kmsKey, err)
+		}
+		addr := g.Address()
+		fn := func(signAddr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if signAddr != addr {
+				return nil, fmt.Errorf("signing for %v with KMS key for %v", signAddr, addr)
+			}
+			return g.SignTx(ctx, tx)
+		}
+		return &bind.TransactOpts{From: addr, Signer: fn, Context: ctx}, func() { g.Close() }, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported -signer %q", *signerType)
+	}
+}
+
+// checkAllowance confirms that the Disperse contract is approved to spend at
+// least the amount of the sender's ERC20 tokens, sending an approval if
+// -approve is set and -dry_run isn't.
+func checkAllowance(ctx context.Context, client *ethclient.Client, opts *bind.TransactOpts, tokenAddr common.Address, amount *big.Int) error {
+	t, err := erc.NewIERC20(tokenAddr, client)
+	if err != nil {
+		return fmt.Errorf("erc.NewIERC20(%v): %v", tokenAddr, err)
+	}
+	callOpts := &bind.CallOpts{Context: ctx}
+	spender := erc.DisperseAddress()
+
+	bal, err := t.BalanceOf(callOpts, opts.From)
+	if err != nil {
+		return fmt.Errorf("%v.balanceOf(%v): %v", tokenAddr, opts.From, err)
+	}
+	if bal.Cmp(amount) < 0 {
+		return fmt.Errorf("balance %d of %v is less than total %d", bal, opts.From, amount)
+	}
+
+	allowance, err := t.Allowance(callOpts, opts.From, spender)
+	if err != nil {
+		return fmt.Errorf("%v.allowance(%v, %v): %v", tokenAddr, opts.From, spender, err)
+	}
+	if allowance.Cmp(amount) >= 0 {
+		return nil
+	}
+	if !*approve || *dryRun {
+		return fmt.Errorf("allowance %d for Disperse contract (%v) is less than total %d; use -approve without -dry_run", allowance, spender, amount)
+	}
+
+	tx, err := t.Approve(opts, spender, amount)
+	if err != nil {
+		return fmt.Errorf("%v.approve(%v, %d): %v", tokenAddr, spender, amount, err)
+	}
+	logger.Info("Sent approval", ethlog.TxHash(tx.Hash()))
+	if _, err := eth.WaitMined(ctx, client, tx, *confirmations); err != nil {
+		return fmt.Errorf("approval: %w", err)
+	}
+	return nil
+}
+
+// ether returns wei as a decimal string of ETH.
+func ether(wei *big.Int) string {
+	f := new(big.Float).SetInt(wei)
+	return f.Quo(f, big.NewFloat(params.Ether)).Text('f', 6)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+)
+
+func TestReadTransfers(t *testing.T) {
+	alice := common.HexToAddress("0xa11ce")
+	bob := common.HexToAddress("0xb0b")
+
+	tests := []struct {
+		name    string
+		kind    kind
+		csv     string
+		want    []transfer
+		wantErr bool
+	}{
+		{
+			name: "amounts",
+			kind: erc20Kind,
+			csv:  "recipient,amount\n" + alice.Hex() + ",100\n" + bob.Hex() + ",0x10\n",
+			want: []transfer{
+				{to: alice, amount: big.NewInt(100)},
+				{to: bob, amount: big.NewInt(16)},
+			},
+		},
+		{
+			name: "token IDs",
+			kind: erc721Kind,
+			csv:  "recipient,token_id\n" + alice.Hex() + ",0\n" + bob.Hex() + ",42\n",
+			want: []transfer{
+				{to: alice, amount: big.NewInt(0)},
+				{to: bob, amount: big.NewInt(42)},
+			},
+		},
+		{
+			name:    "invalid recipient",
+			kind:    ethKind,
+			csv:     "recipient,amount\n0xnope,1\n",
+			wantErr: true,
+		},
+		{
+			name:    "zero amount",
+			kind:    ethKind,
+			csv:     "recipient,amount\n" + alice.Hex() + ",0\n",
+			wantErr: true,
+		},
+		{
+			name:    "negative amount",
+			kind:    erc20Kind,
+			csv:     "recipient,amount\n" + alice.Hex() + ",-1\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing token ID",
+			kind:    erc721Kind,
+			csv:     "recipient,amount\n" + alice.Hex() + ",1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTransfers(strings.NewReader(tt.csv), tt.kind)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("readTransfers(%q, %q) got err %v; want err? %t", tt.csv, tt.kind, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got, transferCmp()); diff != "" {
+				t.Errorf("readTransfers(%q, %q) diff (-want +got):\n%s", tt.csv, tt.kind, diff)
+			}
+		})
+	}
+}
+
+func transferCmp() cmp.Option {
+	return cmp.Comparer(func(a, b transfer) bool {
+		return a.to == b.to && a.amount.Cmp(b.amount) == 0
+	})
+}
+
+func TestPlanCalls(t *testing.T) {
+	from := common.HexToAddress("0xf")
+	token := common.HexToAddress("0x20")
+
+	var ts []transfer
+	for i := int64(1); i <= 5; i++ {
+		ts = append(ts, transfer{to: common.BigToAddress(big.NewInt(i)), amount: big.NewInt(i * 10)})
+	}
+
+	disperse, err := erc.IDisperseMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("IDisperseMetaData.GetAbi() error %v", err)
+	}
+	erc721, err := erc.IERC721MetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("IERC721MetaData.GetAbi() error %v", err)
+	}
+
+	// unpack returns the arguments of the call, failing if it isn't to the
+	// method.
+	unpack := func(t *testing.T, parsed *abi.ABI, c *call) (string, []interface{}) {
+		t.Helper()
+		m, err := parsed.MethodById(c.data[:4])
+		if err != nil {
+			t.Fatalf("%T.MethodById(%#x) error %v", parsed, c.data[:4], err)
+		}
+		args, err := m.Inputs.Unpack(c.data[4:])
+		if err != nil {
+			t.Fatalf("%T.Inputs.Unpack(…) error %v", m, err)
+		}
+		return m.Sig, args
+	}
+
+	t.Run("eth", func(t *testing.T) {
+		calls, err := planCalls(ethKind, from, common.Address{}, ts, 2)
+		if err != nil {
+			t.Fatalf("planCalls(eth, …, batchSize=2) error %v", err)
+		}
+		if got, want := len(calls), 3; got != want {
+			t.Fatalf("planCalls(eth, [5 transfers], batchSize=2) got %d calls; want %d", got, want)
+		}
+
+		var gotRecipients []common.Address
+		wantValues := []int64{30, 70, 50}
+		for i, c := range calls {
+			if c.to != erc.DisperseAddress() {
+				t.Errorf("call[%d] to %v; want Disperse %v", i, c.to, erc.DisperseAddress())
+			}
+			if c.value.Int64() != wantValues[i] {
+				t.Errorf("call[%d] value %d; want %d", i, c.value, wantValues[i])
+			}
+			sig, args := unpack(t, disperse, c)
+			if sig != "disperseEther(address[],uint256[])" {
+				t.Errorf("call[%d] to %s; want disperseEther()", i, sig)
+			}
+			gotRecipients = append(gotRecipients, args[0].([]common.Address)...)
+		}
+
+		var wantRecipients []common.Address
+		for _, t := range ts {
+			wantRecipients = append(wantRecipients, t.to)
+		}
+		if diff := cmp.Diff(wantRecipients, gotRecipients); diff != "" {
+			t.Errorf("planCalls(eth, …) recipients across all calls diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("erc20", func(t *testing.T) {
+		calls, err := planCalls(erc20Kind, from, token, ts, 10)
+		if err != nil {
+			t.Fatalf("planCalls(erc20, …) error %v", err)
+		}
+		if got, want := len(calls), 1; got != want {
+			t.Fatalf("planCalls(erc20, [5 transfers], batchSize=10) got %d calls; want %d", got, want)
+		}
+		c := calls[0]
+		if c.value.Sign() != 0 {
+			t.Errorf("planCalls(erc20, …) got call with value %d; want 0", c.value)
+		}
+		sig, args := unpack(t, disperse, c)
+		if sig != "disperseToken(address,address[],uint256[])" || args[0].(common.Address) != token {
+			t.Errorf("planCalls(erc20, …) got %s with token %v; want disperseToken() with %v", sig, args[0], token)
+		}
+	})
+
+	t.Run("erc721", func(t *testing.T) {
+		calls, err := planCalls(erc721Kind, from, token, ts, 2)
+		if err != nil {
+			t.Fatalf("planCalls(erc721, …) error %v", err)
+		}
+		if got, want := len(calls), len(ts); got != want {
+			t.Fatalf("planCalls(erc721, [%d transfers]) got %d calls; want one per token", len(ts), got)
+		}
+		for i, c := range calls {
+			if c.to != token {
+				t.Errorf("call[%d] to %v; want token %v", i, c.to, token)
+			}
+			sig, args := unpack(t, erc721, c)
+			if sig != safeTransferFromSig {
+				t.Errorf("call[%d] to %s; want %s", i, sig, safeTransferFromSig)
+			}
+			if got := []interface{}{args[0], args[1], args[2].(*big.Int).Int64()}; !cmp.Equal(got, []interface{}{from, ts[i].to, ts[i].amount.Int64()}) {
+				t.Errorf("call[%d] args %v; want [%v %v %d]", i, got, from, ts[i].to, ts[i].amount)
+			}
+		}
+	})
+
+	t.Run("invalid batch size", func(t *testing.T) {
+		if _, err := planCalls(ethKind, from, token, ts, 0); err == nil {
+			t.Errorf("planCalls(…, batchSize=0) got nil error; want non-nil")
+		}
+	})
+}
+
+type fakeEstimator map[common.Address]uint64
+
+func (f fakeEstimator) EstimateGas(_ context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return f[*msg.To], nil
+}
+
+func TestEstimateGas(t *testing.T) {
+	a := common.HexToAddress("0xa")
+	b := common.HexToAddress("0xb")
+	calls := []*call{{to: a}, {to: b}, {to: a}}
+
+	got, err := estimateGas(context.Background(), fakeEstimator{a: 100, b: 7}, common.Address{}, calls)
+	if err != nil {
+		t.Fatalf("estimateGas(…) error %v", err)
+	}
+	if want := uint64(207); got != want {
+		t.Errorf("estimateGas(…) got %d; want %d", got, want)
+	}
+	for i, want := range []uint64{100, 7, 100} {
+		if calls[i].gas != want {
+			t.Errorf("estimateGas(…) set call[%d].gas = %d; want %d", i, calls[i].gas, want)
+		}
+	}
+}
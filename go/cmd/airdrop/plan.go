@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gocarina/gocsv"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+)
+
+// A kind is the type of asset being airdropped.
+type kind string
+
+const (
+	ethKind    kind = "eth"
+	erc20Kind  kind = "erc20"
+	erc721Kind kind = "erc721"
+)
+
+// A row is a single line of the input CSV.
+type row struct {
+	Recipient string `csv:"recipient"`
+	// Amount is in the asset's smallest unit (e.g. wei) and is required for ETH
+	// and ERC20 airdrops.
+	Amount string `csv:"amount"`
+	// TokenID is required for ERC721 airdrops.
+	TokenID string `csv:"token_id"`
+}
+
+// A transfer is a parsed row. For ERC721 airdrops, amount is the token ID.
+type transfer struct {
+	to     common.Address
+	amount *big.Int
+}
+
+// readTransfers parses a CSV with a header row, returning one transfer per row.
+func readTransfers(r io.Reader, k kind) ([]transfer, error) {
+	var rows []*row
+	if err := gocsv.Unmarshal(r, &rows); err != nil {
+		return nil, fmt.Errorf("gocsv.Unmarshal(…): %v", err)
+	}
+
+	ts := make([]transfer, len(rows))
+	for i, r := range rows {
+		// 1-indexed, after the header
+		line := i + 2
+
+		if !common.IsHexAddress(r.Recipient) {
+			return nil, fmt.Errorf("line %d: invalid recipient %q", line, r.Recipient)
+		}
+		ts[i].to = common.HexToAddress(r.Recipient)
+
+		col, val := "amount", r.Amount
+		if k == erc721Kind {
+			col, val = "token_id", r.TokenID
+		}
+		n, ok := new(big.Int).SetString(strings.TrimSpace(val), 0)
+		if !ok || n.Sign() < 0 {
+			return nil, fmt.Errorf("line %d: invalid %s %q", line, col, val)
+		}
+		if k != erc721Kind && n.Sign() == 0 {
+			return nil, fmt.Errorf("line %d: zero %s", line, col)
+		}
+		ts[i].amount = n
+	}
+	return ts, nil
+}
+
+// total returns the sum of all transfer amounts; it is meaningless for ERC721
+// airdrops.
+func total(ts []transfer) *big.Int {
+	sum := new(big.Int)
+	for _, t := range ts {
+		sum.Add(sum, t.amount)
+	}
+	return sum
+}
+
+// A call is a single transaction to be sent.
+type call struct {
+	desc  string
+	to    common.Address
+	value *big.Int
+	data  []byte
+	// gas is populated by estimateGas().
+	gas uint64
+}
+
+// planCalls returns the transactions required to airdrop the transfers, from
+// the sender. ETH and ERC20 transfers are batched into calls to the Disperse
+// contract, with up to batchSize recipients each. ERC721 transfers require a
+// call per token.
+func planCalls(k kind, from, token common.Address, ts []transfer, batchSize int) ([]*call, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size %d must be positive", batchSize)
+	}
+
+	if k == erc721Kind {
+		return planERC721(from, token, ts)
+	}
+
+	parsed, err := erc.IDisperseMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("IDisperseMetaData.GetAbi(): %v", err)
+	}
+
+	var calls []*call
+	for start := 0; start < len(ts); start += batchSize {
+		end := start + batchSize
+		if end > len(ts) {
+			end = len(ts)
+		}
+		batch := ts[start:end]
+
+		recipients := make([]common.Address, len(batch))
+		values := make([]*big.Int, len(batch))
+		for i, t := range batch {
+			recipients[i] = t.to
+			values[i] = t.amount
+		}
+
+		c := &call{
+			desc:  fmt.Sprintf("rows [%d,%d)", start, end),
+			to:    erc.DisperseAddress(),
+			value: new(big.Int),
+		}
+		switch k {
+		case ethKind:
+			c.value = total(batch)
+			c.data, err = parsed.Pack("disperseEther", recipients, values)
+		case erc20Kind:
+			c.data, err = parsed.Pack("disperseToken", token, recipients, values)
+		default:
+			return nil, fmt.Errorf("unsupported kind %q", k)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pack %s call for %s: %v", k, c.desc, err)
+		}
+		calls = append(calls, c)
+	}
+	return calls, nil
+}
+
+// safeTransferFromSig is used to disambiguate the overloaded ERC721 function.
+const safeTransferFromSig = "safeTransferFrom(address,address,uint256)"
+
+func planERC721(from, token common.Address, ts []transfer) ([]*call, error) {
+	parsed, err := erc.IERC721MetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("IERC721MetaData.GetAbi(): %v", err)
+	}
+	var method *abi.Method
+	for _, m := range parsed.Methods {
+		if m.Sig == safeTransferFromSig {
+			m := m
+			method = &m
+			break
+		}
+	}
+	if method == nil {
+		return nil, fmt.Errorf("IERC721 ABI missing %s", safeTransferFromSig)
+	}
+
+	calls := make([]*call, len(ts))
+	for i, t := range ts {
+		args, err := method.Inputs.Pack(from, t.to, t.amount)
+		if err != nil {
+			return nil, fmt.Errorf("pack %s for row %d: %v", safeTransferFromSig, i, err)
+		}
+		calls[i] = &call{
+			desc:  fmt.Sprintf("row %d: token %d", i, t.amount),
+			to:    token,
+			value: new(big.Int),
+			data:  append(append([]byte{}, method.ID...), args...),
+		}
+	}
+	return calls, nil
+}
+
+// A gasEstimator is satisfied by *ethclient.Client.
+type gasEstimator interface {
+	EstimateGas(context.Context, ethereum.CallMsg) (uint64, error)
+}
+
+// estimateGas populates the gas field of every call, returning the total.
+func estimateGas(ctx context.Context, client gasEstimator, from common.Address, calls []*call) (uint64, error) {
+	var sum uint64
+	for _, c := range calls {
+		to := c.to
+		gas, err := client.EstimateGas(ctx, ethereum.CallMsg{
+			From:  from,
+			To:    &to,
+			Value: c.value,
+			Data:  c.data,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("estimating gas for %s: %v", c.desc, err)
+		}
+		c.gas = gas
+		sum += gas
+	}
+	return sum, nil
+}
@@ -1,6 +1,10 @@
 // Package delegations finds all delegate.cash delegations for a set of input
 // addresses. It reads new-line delimited addresses from stdin and writes a CSV
 // of delegations (of all types) to stdout.
+//
+// With -events, stdin is ignored and it instead writes a change log of all
+// delegation events in [-from_block, -to_block], in the order in which they
+// were emitted, allowing a previous snapshot to be updated incrementally.
 package main
 
 import (
@@ -8,17 +12,29 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"sync"
-	"sync/atomic"
 
-	"github.com/gocarina/gocsv"
-	"golang.org/x/sync/errgroup"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/cxkoda/solgo/contracts/delegate"
 	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/eth/export"
 	"github.com/cxkoda/solgo/go/proof"
+	proofsync "github.com/cxkoda/solgo/go/sync"
+)
+
+var (
+	concurrency = flag.Int("concurrency", 32, "Maximum number of vaults for which delegations are fetched concurrently; negative for no limit")
+	events      = flag.Bool("events", false, "Write a change log of delegation events in [-from_block, -to_block] instead of the current delegations of vaults read from stdin")
+	fromBlock   = flag.Uint64("from_block", 0, "First block, inclusive, scanned for events with -events")
+	toBlock     = flag.Int64("to_block", -1, "Last block, inclusive, scanned for events with -events; negative for latest")
+	maxRange    = flag.Uint64("max_range", 2000, "Maximum number of blocks per eth_getLogs request with -events")
+	deployments = flag.String("deployments", "", "Optional JSON eth.Deployments file from which the address of the "+delegate.DeploymentName+" is read; if empty, the canonical delegate.cash address is used")
+
+	logger = ethlog.NewFromFlag(flag.CommandLine, os.Stderr)
 )
 
 func main() {
@@ -44,11 +60,58 @@ func run(ctx context.Context, d *eth.Dialer, addrSrc io.Reader, out io.Writer) e
 	}
 	defer client.Close()
 
-	reg, err := delegate.New(client)
+	addr, err := registryAddress(ctx, client)
+	if err != nil {
+		return err
+	}
+	reg, err := delegate.NewIDelegationRegistry(addr, client)
+	if err != nil {
+		return fmt.Errorf("delegate.NewIDelegationRegistry(%v, …): %v", addr, err)
+	}
+
+	if !*events {
+		return fetchDelegationsAndExportCSV(ctx, reg, addrSrc, out)
+	}
+
+	to := uint64(*toBlock)
+	if *toBlock < 0 {
+		to, err = client.BlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("%T.BlockNumber(): %v", client, err)
+		}
+	}
+	logger.Info("Scanning delegation events", ethlog.Uint("from_block", *fromBlock), ethlog.Uint("to_block", to))
+	return fetchChangesAndExportCSV(ctx, client, reg, addr, *fromBlock, to, out)
+}
+
+// registryAddress returns the address of the delegation registry on the
+// client's chain, as recorded in the -deployments file if one is specified.
+func registryAddress(ctx context.Context, client *ethclient.Client) (common.Address, error) {
+	if *deployments == "" {
+		return delegate.Address(), nil
+	}
+	d, err := eth.LoadDeployments(*deployments)
+	if err != nil {
+		return common.Address{}, err
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%T.ChainID(): %v", client, err)
+	}
+	return d.Address(delegate.DeploymentName, chainID.Uint64())
+}
+
+// fetchChangesAndExportCSV writes a CSV of all delegate.Changes to the registry
+// at addr in blocks [from, to].
+func fetchChangesAndExportCSV(ctx context.Context, src eth.LogSource, reg *delegate.IDelegationRegistry, addr common.Address, from, to uint64, out io.Writer) error {
+	if from > to {
+		return fmt.Errorf("-from_block %d after -to_block %d", from, to)
+	}
+	changes, err := reg.Changes(ctx, src, addr, from, to, *maxRange)
 	if err != nil {
-		return fmt.Errorf("delegate.New(…): %v", err)
+		return fmt.Errorf("%T.Changes(%v, %d, %d): %v", reg, addr, from, to, err)
 	}
-	return fetchDelegationsAndExportCSV(ctx, reg, addrSrc, out)
+	return export.Write(export.NewCSV(out), changeColumns, changes)
 }
 
 func fetchDelegationsAndExportCSV(ctx context.Context, reg *delegate.IDelegationRegistry, addrSrc io.Reader, out io.Writer) error {
@@ -59,14 +122,16 @@ func fetchDelegationsAndExportCSV(ctx context.Context, reg *delegate.IDelegation
 
 	var vaultDelegates []*delegate.Delegation
 	var mu sync.Mutex
-	// For progress logging.
-	n := len(vaults)
-	done := new(uint64)
 
-	g, ctx := errgroup.WithContext(ctx)
+	pool := proofsync.NewPool(ctx)
+	pool.SetLimit(*concurrency)
+	pool.OnProgress(func(done, _ uint64) {
+		logger.Info("Progress", ethlog.Uint("done", done), ethlog.Int("total", int64(len(vaults))))
+	})
+
 	for _, v := range vaults {
 		vault := v
-		g.Go(func() error {
+		pool.Go(func(ctx context.Context) error {
 			var delegations []*delegate.Delegation
 
 			delegations, err := delegate.AppendDelegations(ctx, delegations, reg.GetAppendableDelegatesForAll, vault)
@@ -84,19 +149,36 @@ func fetchDelegationsAndExportCSV(ctx context.Context, reg *delegate.IDelegation
 				return err
 			}
 
-			atomic.AddUint64(done, 1)
-			log.Printf("%d/%d (at least)", atomic.LoadUint64(done), n)
-
 			mu.Lock()
 			defer mu.Unlock()
 			vaultDelegates = append(vaultDelegates, delegations...)
 			return nil
 		})
 	}
-	if err := g.Wait(); err != nil {
+	if err := pool.Wait(); err != nil {
 		return err
 	}
-	log.Printf("%d/%d", *done, n)
 
-	return gocsv.Marshal(vaultDelegates, out)
+	return export.Write(export.NewCSV(out), delegationColumns, vaultDelegates)
+}
+
+// delegationColumns are the CSV columns written for each delegate.Delegation,
+// named after its fields so the output can be parsed with gocsv.
+var delegationColumns = []export.Column[*delegate.Delegation]{
+	export.Address("Vault", func(d *delegate.Delegation) common.Address { return d.Vault }),
+	export.Address("Delegate", func(d *delegate.Delegation) common.Address { return d.Delegate }),
+	export.Any("Contract", func(d *delegate.Delegation) any { return d.Contract }),
+	export.Any("TokenID", func(d *delegate.Delegation) any { return d.TokenID }),
+}
+
+// changeColumns are the equivalent of delegationColumns for delegate.Changes.
+var changeColumns = []export.Column[*delegate.Change]{
+	export.Uint("Block", func(c *delegate.Change) uint64 { return c.Block }),
+	export.Hash("TxHash", func(c *delegate.Change) common.Hash { return c.TxHash }),
+	export.Uint("LogIndex", func(c *delegate.Change) uint64 { return uint64(c.LogIndex) }),
+	export.String("Kind", func(c *delegate.Change) string { return string(c.Kind) }),
+	export.Address("Vault", func(c *delegate.Change) common.Address { return c.Vault }),
+	export.Address("Delegate", func(c *delegate.Change) common.Address { return c.Delegate }),
+	export.Any("Contract", func(c *delegate.Change) any { return c.Contract }),
+	export.Any("TokenID", func(c *delegate.Change) any { return c.TokenID }),
 }
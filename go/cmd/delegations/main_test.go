@@ -9,6 +9,7 @@ import (
 	"testing"
 	"text/template"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gocarina/gocsv"
 	"github.com/google/go-cmp/cmp"
 
@@ -102,3 +103,94 @@ func TestCSVRoundTrip(t *testing.T) {
 		}
 	})
 }
+
+// simLogSource adds the BlockNumber() method required of an eth.LogSource.
+type simLogSource struct {
+	*ethtest.SimulatedBackend
+}
+
+func (s simLogSource) BlockNumber(context.Context) (uint64, error) {
+	return s.Blockchain().CurrentBlock().Number.Uint64(), nil
+}
+
+func TestChangeLog(t *testing.T) {
+	ctx := context.Background()
+	sim := ethtest.NewSimulatedBackendTB(t, numAccounts)
+
+	addr, _, _, err := impl.DeployDelegationRegistry(sim.Acc(deployer), sim)
+	if err != nil {
+		t.Fatalf("DeployDelegationRegistry(…) error %v", err)
+	}
+	reg, err := delegate.NewIDelegationRegistry(addr, sim)
+	if err != nil {
+		t.Fatalf("NewIDelegationRegistry([address just deployed to %T]) error %v", sim, err)
+	}
+	src := simLogSource{sim}
+	from, err := src.BlockNumber(ctx)
+	if err != nil {
+		t.Fatalf("BlockNumber() error %v", err)
+	}
+	from++
+
+	const tokenID = 42
+	sim.Must(t, "%T.DelegateForAll()", reg)(reg.DelegateForAll(sim.Acc(vault), sim.Addr(delegatedAll), true))
+	sim.Must(t, "%T.DelegateForContract()", reg)(reg.DelegateForContract(sim.Acc(vault), sim.Addr(delegatedContract), sim.Addr(contract), true))
+	sim.Must(t, "%T.DelegateForToken()", reg)(reg.DelegateForToken(sim.Acc(vault), sim.Addr(delegatedToken), sim.Addr(tokenContract), big.NewInt(tokenID), true))
+	sim.Must(t, "%T.DelegateForAll(false)", reg)(reg.DelegateForAll(sim.Acc(vault), sim.Addr(delegatedAll), false))
+	sim.Must(t, "%T.RevokeDelegate()", reg)(reg.RevokeDelegate(sim.Acc(vault), sim.Addr(delegatedContract)))
+	sim.Must(t, "%T.RevokeAllDelegates()", reg)(reg.RevokeAllDelegates(sim.Acc(vault)))
+
+	to, err := src.BlockNumber(ctx)
+	if err != nil {
+		t.Fatalf("BlockNumber() error %v", err)
+	}
+
+	gotCSV := new(bytes.Buffer)
+	if err := fetchChangesAndExportCSV(ctx, src, reg, addr, from, to, gotCSV); err != nil {
+		t.Fatalf("fetchChangesAndExportCSV(…) error %v", err)
+	}
+
+	var got []*delegate.Change
+	if err := gocsv.Unmarshal(bytes.NewReader(gotCSV.Bytes()), &got); err != nil {
+		t.Fatalf("gocsv.Unmarshal([CSV written by fetchChangesAndExportCSV()], %T) error %v", &got, err)
+	}
+
+	type change struct {
+		Kind                      delegate.ChangeKind
+		Vault, Delegate, Contract string
+		TokenID                   string
+	}
+	var gotChanges []change
+	for i, c := range got {
+		if c.Block < from || c.Block > to {
+			t.Errorf("Change[%d].Block = %d; want in [%d, %d]", i, c.Block, from, to)
+		}
+		if i > 0 && c.Block < got[i-1].Block {
+			t.Errorf("Change[%d].Block = %d before previous Change in block %d", i, c.Block, got[i-1].Block)
+		}
+		gotChanges = append(gotChanges, change{
+			Kind:     c.Kind,
+			Vault:    c.Vault.Hex(),
+			Delegate: c.Delegate.Hex(),
+			Contract: c.Contract.Address.Hex(),
+			TokenID:  c.TokenID.Int.Dec(),
+		})
+	}
+
+	zero := (common.Address{}).Hex()
+	want := []change{
+		{delegate.DelegationAdded, sim.Addr(vault).Hex(), sim.Addr(delegatedAll).Hex(), zero, "0"},
+		{delegate.DelegationAdded, sim.Addr(vault).Hex(), sim.Addr(delegatedContract).Hex(), sim.Addr(contract).Hex(), "0"},
+		{delegate.DelegationAdded, sim.Addr(vault).Hex(), sim.Addr(delegatedToken).Hex(), sim.Addr(tokenContract).Hex(), fmt.Sprint(tokenID)},
+		{delegate.DelegationRemoved, sim.Addr(vault).Hex(), sim.Addr(delegatedAll).Hex(), zero, "0"},
+		{delegate.DelegateRevoked, sim.Addr(vault).Hex(), sim.Addr(delegatedContract).Hex(), zero, "0"},
+		{delegate.AllDelegatesRevoked, sim.Addr(vault).Hex(), zero, zero, "0"},
+	}
+	if diff := cmp.Diff(want, gotChanges); diff != "" {
+		t.Errorf("fetchChangesAndExportCSV(…) round-tripped changes diff (-want +got):\n%s", diff)
+	}
+
+	if err := fetchChangesAndExportCSV(ctx, src, reg, addr, to+1, to, new(bytes.Buffer)); err == nil {
+		t.Errorf("fetchChangesAndExportCSV(…, from = to+1, to) got nil error; want non-nil")
+	}
+}
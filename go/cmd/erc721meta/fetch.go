@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ipfs/go-libipfs/files"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+	"github.com/cxkoda/solgo/go/ipfs"
+)
+
+// Status classifies a Problem.
+type Status string
+
+const (
+	// Missing metadata couldn't be fetched at all; e.g. tokenURI() reverted or
+	// the URI returned a non-200 response.
+	Missing Status = "missing"
+	// Malformed metadata was fetched but isn't valid JSON or is missing
+	// required fields.
+	Malformed Status = "malformed"
+)
+
+// A Problem is a single row in the report, describing a token with missing or
+// malformed metadata.
+type Problem struct {
+	tokenID *big.Int
+
+	TokenID string `csv:"token_id" json:"token_id"`
+	URI     string `csv:"uri" json:"uri"`
+	Status  Status `csv:"status" json:"status"`
+	// Details is a semicolon-separated list of reasons.
+	Details string `csv:"details" json:"details"`
+}
+
+// A fetcher fetches token metadata from URIs returned by tokenURI().
+type fetcher struct {
+	client *http.Client
+	// gateway is the base URL of an HTTP IPFS gateway, used if ipfs is nil.
+	gateway string
+	ipfs    *ipfs.IPFS
+}
+
+// check fetches and validates the token's metadata, returning nil if there are
+// no problems.
+func (f *fetcher) check(ctx context.Context, u erc.TokenURI) *Problem {
+	p := &Problem{
+		tokenID: u.TokenID,
+		TokenID: u.TokenID.String(),
+		URI:     u.URI,
+	}
+	missing := func(err error) *Problem {
+		p.Status = Missing
+		p.Details = err.Error()
+		return p
+	}
+
+	if u.Err != nil {
+		return missing(u.Err)
+	}
+	if u.URI == "" {
+		return missing(fmt.Errorf("empty tokenURI(%d)", u.TokenID))
+	}
+
+	buf, err := f.fetch(ctx, u.URI)
+	if err != nil {
+		return missing(err)
+	}
+	if reasons := validate(buf); len(reasons) > 0 {
+		p.Status = Malformed
+		p.Details = strings.Join(reasons, "; ")
+		return p
+	}
+	return nil
+}
+
+// fetch returns the content addressed by the URI.
+func (f *fetcher) fetch(ctx context.Context, uri string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(uri, "data:"):
+		return parseDataURI(uri)
+	case strings.HasPrefix(uri, "ipfs://"):
+		return f.fetchIPFS(ctx, strings.TrimPrefix(uri, "ipfs://"))
+	case strings.HasPrefix(uri, "http://"), strings.HasPrefix(uri, "https://"):
+		return f.fetchHTTP(ctx, uri)
+	default:
+		return nil, fmt.Errorf("unsupported URI scheme in %q", uri)
+	}
+}
+
+// fetchIPFS returns the content at the IPFS path, which MUST NOT include the
+// ipfs:// prefix.
+func (f *fetcher) fetchIPFS(ctx context.Context, p string) ([]byte, error) {
+	// Some collections incorrectly use ipfs://ipfs/<cid>.
+	p = strings.TrimPrefix(p, "ipfs/")
+
+	if f.ipfs == nil {
+		return f.fetchHTTP(ctx, strings.TrimSuffix(f.gateway, "/")+"/ipfs/"+p)
+	}
+
+	node, err := f.ipfs.Unixfs().Get(ctx, path.New("/ipfs/"+p))
+	if err != nil {
+		return nil, fmt.Errorf("%T.Unixfs().Get(%q): %v", f.ipfs, p, err)
+	}
+	defer node.Close()
+
+	file, ok := node.(files.File)
+	if !ok {
+		return nil, fmt.Errorf("IPFS path %q is a %T, not a file", p, node)
+	}
+	return io.ReadAll(file)
+}
+
+func (f *fetcher) fetchHTTP(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest(GET, %q): %v", url, err)
+	}
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %q: %v", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %q: %s", url, res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// parseDataURI returns the data encoded in an RFC 2397 data: URI.
+func parseDataURI(uri string) ([]byte, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("data: URI missing comma")
+	}
+
+	if strings.HasSuffix(header, ";base64") {
+		buf, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("base64 decoding data: URI: %v", err)
+		}
+		return buf, nil
+	}
+
+	// On-chain metadata commonly embeds raw JSON without percent-encoding, which
+	// may include literal % characters, so fall back to the raw data.
+	if s, err := url.PathUnescape(data); err == nil {
+		return []byte(s), nil
+	}
+	return []byte(data), nil
+}
+
+// validate returns the reasons for which buf isn't valid ERC721 metadata, or
+// nil if it is valid.
+func validate(buf []byte) []string {
+	var md map[string]json.RawMessage
+	if err := json.Unmarshal(bytes.TrimSpace(buf), &md); err != nil {
+		return []string{fmt.Sprintf("invalid JSON object: %v", err)}
+	}
+
+	var reasons []string
+	for _, field := range []string{"name", "image"} {
+		raw, ok := md[field]
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("missing %q", field))
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			reasons = append(reasons, fmt.Sprintf("%q not a string", field))
+			continue
+		}
+		if s == "" {
+			reasons = append(reasons, fmt.Sprintf("empty %q", field))
+		}
+	}
+
+	raw, ok := md["attributes"]
+	if !ok {
+		return append(reasons, `missing "attributes"`)
+	}
+	var attrs []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &attrs); err != nil {
+		return append(reasons, `"attributes" not an array of objects`)
+	}
+	for i, a := range attrs {
+		if _, ok := a["value"]; !ok {
+			reasons = append(reasons, fmt.Sprintf(`attributes[%d] missing "value"`, i))
+		}
+		if t, ok := a["trait_type"]; ok {
+			var s string
+			if err := json.Unmarshal(t, &s); err != nil {
+				reasons = append(reasons, fmt.Sprintf(`attributes[%d] "trait_type" not a string`, i))
+			}
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,233 @@
+// Binary erc721meta fetches and validates the metadata of tokens in an ERC721
+// collection. It calls tokenURI() for each token in a range, fetches the
+// metadata from the returned URI (http(s)://, ipfs://, or data:), and writes a
+// report of tokens with missing or malformed metadata to stdout.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gocarina/gocsv"
+	"github.com/ipfs/kubo/core"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/flagtype"
+	"github.com/cxkoda/solgo/go/ipfs"
+	"github.com/cxkoda/solgo/go/proof"
+	proofsync "github.com/cxkoda/solgo/go/sync"
+)
+
+var (
+	contract    = flag.String("contract", "", "Address of the ERC721 contract")
+	first       = flag.Int64("first", 0, "First token ID to check")
+	last        = flag.Int64("last", -1, "Last token ID to check, inclusive; if negative, derived from totalSupply() assuming sequential IDs from -first")
+	concurrency = flag.Int("concurrency", 32, "Maximum number of concurrent metadata fetches; negative for no limit")
+	format      = flag.String("format", "csv", "Report format; csv or json")
+	gateway     = flagtype.MustNewURL("https://ipfs.io", "http", "https") // -ipfs_gateway, registered in main()
+	ipfsRepo    = flag.String("ipfs_repo", "", "Path to an initialised IPFS repository; if non-empty, ipfs:// URIs are fetched by a local node instead of -ipfs_gateway")
+
+	logger = ethlog.NewFromFlag(flag.CommandLine, os.Stderr)
+)
+
+func main() {
+	flag.Var(gateway, "ipfs_gateway", "HTTP gateway used to fetch ipfs:// URIs if -ipfs_repo is empty; either a literal URL or a secrets.Secret, for gateways that require an access token; e.g. env://IPFS_GATEWAY_URL")
+	d := eth.MustNewDialerFromFlag(flag.CommandLine, proof.InfuraMainnetURL())
+	flag.Parse()
+	if err := run(context.Background(), d, os.Stdout); err != nil {
+		exit(err)
+	}
+}
+
+// exit prints err to stderr and exits with code 1.
+func exit(err error) {
+	fmt.Fprint(os.Stderr, err)
+	os.Exit(1)
+}
+
+// run checks the metadata of every token in the range specified by flags and
+// writes a report to out.
+func run(ctx context.Context, d *eth.Dialer, out io.Writer) error {
+	if !common.IsHexAddress(*contract) {
+		return fmt.Errorf("invalid -contract %q", *contract)
+	}
+	addr := common.HexToAddress(*contract)
+
+	var write func([]*Problem, io.Writer) error
+	switch *format {
+	case "csv":
+		write = writeCSV
+	case "json":
+		write = writeJSON
+	default:
+		return fmt.Errorf("unsupported -format %q", *format)
+	}
+
+	client, err := d.Dial(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.Dial(): %v", d, err)
+	}
+	defer client.Close()
+
+	ids, err := tokenIDs(ctx, client, addr)
+	if err != nil {
+		return err
+	}
+
+	f := &fetcher{
+		client: http.DefaultClient,
+	}
+	if *ipfsRepo != "" {
+		node, err := ipfsNode(ctx, *ipfsRepo)
+		if err != nil {
+			return err
+		}
+		f.ipfs = node
+	} else {
+		u, err := gateway.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("-ipfs_gateway: %v", err)
+		}
+		f.gateway = u.String()
+	}
+
+	multicall, err := multicallAddress(ctx, client)
+	if err != nil {
+		return err
+	}
+	uris, err := erc.TokenURIBatch(ctx, client, multicall, addr, ids)
+	if err != nil {
+		return fmt.Errorf("erc.TokenURIBatch(…, %v, %v, [%d token IDs]): %v", multicall, addr, len(ids), err)
+	}
+
+	problems, err := check(ctx, f, uris)
+	if err != nil {
+		return err
+	}
+	logger.Info("Tokens with missing or malformed metadata", ethlog.Int("problems", int64(len(problems))), ethlog.Int("total", int64(len(uris))))
+	return write(problems, out)
+}
+
+// multicallAddress returns the Multicall3 deployment of the client's chain,
+// defaulting to eth.Multicall3Address for chains absent from the registry.
+func multicallAddress(ctx context.Context, client *ethclient.Client) (common.Address, error) {
+	id, err := client.ChainID(ctx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%T.ChainID(): %v", client, err)
+	}
+	chain, ok := eth.ChainByID(id.Uint64())
+	if !ok {
+		return eth.Multicall3Address, nil
+	}
+	if chain.Multicall == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("no Multicall3 deployment on %v", chain)
+	}
+	return chain.Multicall, nil
+}
+
+// tokenIDs returns the token IDs in the range specified by flags.
+func tokenIDs(ctx context.Context, backend bind.ContractCaller, addr common.Address) ([]*big.Int, error) {
+	end := *last
+	if end < 0 {
+		token, err := erc.NewIERC721EnumerableCaller(addr, backend)
+		if err != nil {
+			return nil, fmt.Errorf("erc.NewIERC721EnumerableCaller(…): %v", err)
+		}
+		supply, err := token.TotalSupply(&bind.CallOpts{Context: ctx})
+		if err != nil {
+			return nil, fmt.Errorf("%T.TotalSupply(): %v", token, err)
+		}
+		n, err := eth.ToInt64(supply)
+		if err != nil {
+			return nil, fmt.Errorf("%T.TotalSupply(): %v", token, err)
+		}
+		end = *first + n - 1
+	}
+
+	var ids []*big.Int
+	for i := *first; i <= end; i++ {
+		ids = append(ids, big.NewInt(i))
+	}
+	return ids, nil
+}
+
+// ipfsNode returns an online IPFS node using the repository at repoPath.
+func ipfsNode(ctx context.Context, repoPath string) (*ipfs.IPFS, error) {
+	if _, err := ipfs.LoadPlugins(repoPath); err != nil {
+		return nil, err
+	}
+	node, err := ipfs.FromFS(ctx, repoPath, &core.BuildCfg{Online: true})
+	if err != nil {
+		return nil, err
+	}
+	if n := node.TryConnect(ctx, ipfs.DefaultPeers); n == 0 {
+		return nil, fmt.Errorf("failed to connect to any of %d default IPFS peers", len(ipfs.DefaultPeers))
+	}
+	return node, nil
+}
+
+// check fetches and validates the metadata of every token, returning the
+// Problems sorted by token ID.
+func check(ctx context.Context, f *fetcher, uris []erc.TokenURI) ([]*Problem, error) {
+	var (
+		problems []*Problem
+		mu       sync.Mutex
+	)
+
+	pool := proofsync.NewPool(ctx)
+	pool.SetLimit(*concurrency)
+	pool.OnProgress(func(done, _ uint64) {
+		if done%100 == 0 || done == uint64(len(uris)) {
+			logger.Info("Progress", ethlog.Uint("done", done), ethlog.Int("total", int64(len(uris))))
+		}
+	})
+
+	for _, u := range uris {
+		u := u
+		pool.Go(func(ctx context.Context) error {
+			p := f.check(ctx, u)
+			if p == nil {
+				return nil
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			problems = append(problems, p)
+			return nil
+		})
+	}
+	if err := pool.Wait(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		return problems[i].tokenID.Cmp(problems[j].tokenID) == -1
+	})
+	return problems, nil
+}
+
+func writeCSV(problems []*Problem, w io.Writer) error {
+	return gocsv.Marshal(problems, w)
+}
+
+func writeJSON(problems []*Problem, w io.Writer) error {
+	// Ensure an empty array instead of null.
+	if problems == nil {
+		problems = []*Problem{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(problems)
+}
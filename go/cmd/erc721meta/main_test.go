@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/cxkoda/solgo/contracts/erc"
+)
+
+const validJSON = `{"name":"Token","image":"ipfs://img","attributes":[{"trait_type":"colour","value":"red"}]}`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		json string
+		want []string
+	}{
+		{
+			json: validJSON,
+			want: nil,
+		},
+		{
+			json: `{"name":"Token","image":"x","attributes":[]}`,
+			want: nil,
+		},
+		{
+			json: `not json`,
+			want: []string{`invalid JSON object: invalid character 'o' in literal null (expecting 'u')`},
+		},
+		{
+			json: `{}`,
+			want: []string{`missing "name"`, `missing "image"`, `missing "attributes"`},
+		},
+		{
+			json: `{"name":"","image":42,"attributes":{}}`,
+			want: []string{`empty "name"`, `"image" not a string`, `"attributes" not an array of objects`},
+		},
+		{
+			json: `{"name":"Token","image":"x","attributes":[{"trait_type":1,"value":0},{"trait_type":"t"}]}`,
+			want: []string{`attributes[0] "trait_type" not a string`, `attributes[1] missing "value"`},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, validate([]byte(tt.json))); diff != "" {
+			t.Errorf("validate(%s) diff (-want +got):\n%s", tt.json, diff)
+		}
+	}
+}
+
+func TestParseDataURI(t *testing.T) {
+	tests := []struct {
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		{
+			uri:  "data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(validJSON)),
+			want: validJSON,
+		},
+		{
+			uri:  "data:application/json;utf8," + validJSON,
+			want: validJSON,
+		},
+		{
+			uri:  "data:,hello%20world",
+			want: "hello world",
+		},
+		{
+			uri:  "data:,100%",
+			want: "100%",
+		},
+		{
+			uri:     "data:application/json;base64,!!!",
+			wantErr: true,
+		},
+		{
+			uri:     "data:no-comma",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDataURI(tt.uri)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("parseDataURI(%q) got err %v; want err? %t", tt.uri, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("parseDataURI(%q) got %q; want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/meta/1", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, validJSON)
+	})
+	mux.HandleFunc("/meta/2", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"name":"Token"}`)
+	})
+	mux.HandleFunc("/ipfs/cid/3", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, validJSON)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	f := &fetcher{
+		client:  srv.Client(),
+		gateway: srv.URL,
+	}
+
+	uris := []erc.TokenURI{
+		{URI: srv.URL + "/meta/1"},
+		{URI: srv.URL + "/meta/2"},
+		{URI: "ipfs://cid/3"},
+		{URI: "ipfs://cid/4"},
+		{URI: "data:application/json;base64," + base64.StdEncoding.EncodeToString([]byte(validJSON))},
+		{URI: "data:application/json,{}"},
+		{Err: errors.New("tokenURI(6) reverted")},
+		{URI: ""},
+		{URI: "ar://unsupported"},
+	}
+	for i := range uris {
+		uris[i].TokenID = big.NewInt(int64(len(uris) - i))
+	}
+	// Reverse so as to demonstrate sorting.
+	for i, j := 0, len(uris)-1; i < j; i, j = i+1, j-1 {
+		uris[i], uris[j] = uris[j], uris[i]
+	}
+
+	got, err := check(ctx, f, uris)
+	if err != nil {
+		t.Fatalf("check(…) error %v", err)
+	}
+
+	want := []*Problem{
+		{TokenID: "1", URI: "ar://unsupported", Status: Missing},
+		{TokenID: "2", URI: "", Status: Missing},
+		{TokenID: "3", Status: Missing},
+		{TokenID: "4", URI: "data:application/json,{}", Status: Malformed},
+		{TokenID: "6", URI: "ipfs://cid/4", Status: Missing},
+		{TokenID: "8", URI: srv.URL + "/meta/2", Status: Malformed},
+	}
+
+	opts := []cmp.Option{
+		cmpopts.IgnoreUnexported(Problem{}),
+		cmpopts.IgnoreFields(Problem{}, "Details"),
+	}
+	if diff := cmp.Diff(want, got, opts...); diff != "" {
+		t.Errorf("check(…) diff (-want +got):\n%s", diff)
+	}
+
+	for _, p := range got {
+		if p.Details == "" {
+			t.Errorf("check(…) got empty Details for token %s", p.TokenID)
+		}
+	}
+}
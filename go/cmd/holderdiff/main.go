@@ -0,0 +1,154 @@
+// Binary holderdiff reports changes in the holders of an ERC721 collection
+// between two blocks, each specified either by number or by timestamp. It
+// writes a CSV to stdout with one row per holder whose balance changed, with
+// columns holder, before, after, delta, and status (gained, lost, or changed).
+//
+// Balances are computed from Transfer logs so -deployed_block SHOULD be set to
+// the block in which the collection was deployed; it is otherwise scanned from
+// genesis.
+//
+// If -labels is set, a holder_label column is added after the holder; see
+// eth.LoadLabels() re the file format.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/cxkoda/solgo/go/erc721"
+	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/eth/export"
+	"github.com/cxkoda/solgo/go/proof"
+)
+
+var (
+	collection    = flag.String("collection", "", "Address of the ERC721 contract")
+	deployedBlock = flag.Uint64("deployed_block", 0, "Block in which the collection was deployed; logs are scanned from here")
+	beforeBlock   = flag.Int64("before_block", -1, "Block at the end of which the first snapshot is taken; mutually exclusive with -before_time")
+	afterBlock    = flag.Int64("after_block", -1, "Block at the end of which the second snapshot is taken; mutually exclusive with -after_time; defaults to latest")
+	beforeTime    = flag.String("before_time", "", "RFC3339 time; the first snapshot is taken at the last block mined by this time")
+	afterTime     = flag.String("after_time", "", "RFC3339 time; the second snapshot is taken at the last block mined by this time")
+	maxRange      = flag.Uint64("max_range", 10_000, "Maximum number of blocks per eth_getLogs request")
+	labelsFile    = flag.String("labels", "", "Optional JSON or CSV file of address labels to include in the output")
+
+	logger = ethlog.NewFromFlag(flag.CommandLine, os.Stderr)
+)
+
+func main() {
+	d := eth.MustNewDialerFromFlag(flag.CommandLine, proof.InfuraMainnetURL())
+	flag.Parse()
+	if err := run(context.Background(), d, os.Stdout); err != nil {
+		exit(err)
+	}
+}
+
+// exit prints err to stderr and exits with code 1.
+func exit(err error) {
+	fmt.Fprint(os.Stderr, err)
+	os.Exit(1)
+}
+
+// run computes the holder diff and writes it as CSV to out.
+func run(ctx context.Context, d *eth.Dialer, out io.Writer) error {
+	if !common.IsHexAddress(*collection) {
+		return fmt.Errorf("invalid -collection %q", *collection)
+	}
+	addr := common.HexToAddress(*collection)
+
+	var labels *eth.Labels
+	if *labelsFile != "" {
+		l, err := eth.LoadLabels(*labelsFile)
+		if err != nil {
+			return err
+		}
+		labels = l
+	}
+
+	client, err := d.Dial(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.Dial(): %v", d, err)
+	}
+	defer client.Close()
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.ChainID(): %v", client, err)
+	}
+
+	// The two binary searches of resolveBlock() share many of their blocks.
+	blocks := eth.DefaultBlockCache.Fetcher(eth.BlockFetcherFor(client, chainID.Uint64()))
+	before, err := resolveBlock(ctx, blocks, "before", *beforeBlock, *beforeTime)
+	if err != nil {
+		return err
+	}
+	after, err := resolveBlock(ctx, blocks, "after", *afterBlock, *afterTime)
+	if err != nil {
+		return err
+	}
+	logger.Info("Diffing holders", ethlog.Address("contract", addr), ethlog.Uint("before_block", before), ethlog.Uint("after_block", after))
+
+	changes, err := erc721.HolderDiff(ctx, client, addr, *deployedBlock, before, after, erc721.WithMaxBlockRange(*maxRange))
+	if err != nil {
+		return fmt.Errorf("erc721.HolderDiff(%v, %d, %d, %d): %v", addr, *deployedBlock, before, after, err)
+	}
+
+	return writeCSV(out, changes, labels, chainID.Uint64())
+}
+
+// resolveBlock returns the block number specified by exactly one of num and
+// ts, where num < 0 is unset. If neither is set, the latest block is returned
+// for the "after" snapshot and an error for "before".
+func resolveBlock(ctx context.Context, client eth.BlockFetcher, which string, num int64, ts string) (uint64, error) {
+	switch {
+	case num >= 0 && ts != "":
+		return 0, fmt.Errorf("-%s_block and -%s_time are mutually exclusive", which, which)
+	case num >= 0:
+		return uint64(num), nil
+	case ts != "":
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return 0, fmt.Errorf("parsing -%s_time: %v", which, err)
+		}
+		b, err := eth.LastBlockBy(ctx, client, uint64(t.Unix()), nil)
+		if err != nil {
+			return 0, fmt.Errorf("eth.LastBlockBy(%v): %v", t, err)
+		}
+		return b.NumberU64(), nil
+	case which == "after":
+		n, err := client.BlockNumber(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("%T.BlockNumber(): %v", client, err)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("one of -%s_block or -%s_time required", which, which)
+	}
+}
+
+// writeCSV writes the changes, with a header row, to w. Holders are annotated
+// with their labels on the chain, if any.
+func writeCSV(w io.Writer, changes []erc721.HolderChange, labels *eth.Labels, chainID uint64) error {
+	cols := export.LabeledAddress("holder", func(c erc721.HolderChange) common.Address { return c.Holder }, labels, chainID)
+	cols = append(cols,
+		export.Uint("before", func(c erc721.HolderChange) uint64 { return c.Before }),
+		export.Uint("after", func(c erc721.HolderChange) uint64 { return c.After }),
+		export.Int("delta", erc721.HolderChange.Delta),
+		export.String("status", func(c erc721.HolderChange) string {
+			switch {
+			case c.Gained():
+				return "gained"
+			case c.Lost():
+				return "lost"
+			}
+			return "changed"
+		}),
+	)
+	return export.Write(export.NewCSV(w), cols, changes)
+}
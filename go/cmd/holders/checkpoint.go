@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// A checkpoint records per-contract scanning progress so that an interrupted
+// run can be resumed. Balances are kept per contract, and only updated along
+// with the next token ID to be scanned, so resuming never double counts; a
+// completed checkpoint therefore always results in the same output.
+//
+// A checkpoint is only valid for the chain and ordered list of contracts with
+// which it was created, both of which are recorded and checked when loading.
+type checkpoint struct {
+	// path is the file to which the checkpoint is saved; if empty, save() is
+	// a no-op.
+	path      string
+	ChainID   uint64                               `json:"chain_id"`
+	Addresses []common.Address                     `json:"addresses"`
+	Contracts map[common.Address]*contractProgress `json:"contracts"`
+}
+
+// contractProgress is the progress of scanning a single contract.
+type contractProgress struct {
+	// Supply is the total supply when scanning began. It is fixed for the
+	// lifetime of the checkpoint, even if the supply changes, so that
+	// balances are from a consistent set of tokens.
+	Supply uint64 `json:"supply"`
+	// NextTokenID is the lowest token ID yet to be scanned; owners of all
+	// lower IDs are reflected in Balances.
+	NextTokenID uint64                    `json:"next_token_id"`
+	Balances    map[common.Address]uint64 `json:"balances"`
+}
+
+// loadCheckpoint reads the checkpoint from the file at path. A new, empty
+// checkpoint is returned if path is empty or the file doesn't exist. An error
+// is returned if an existing checkpoint was created for a different chain ID
+// or list of contracts.
+func loadCheckpoint(path string, chainID uint64, contracts []common.Address) (*checkpoint, error) {
+	c := &checkpoint{
+		path:      path,
+		ChainID:   chainID,
+		Addresses: contracts,
+		Contracts: make(map[common.Address]*contractProgress),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Addresses = nil
+	if err := json.Unmarshal(buf, c); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(checkpoint %q): %v", path, err)
+	}
+	if c.ChainID != chainID {
+		return nil, fmt.Errorf("checkpoint %q is for chain ID %d; connected to chain %d", path, c.ChainID, chainID)
+	}
+	if !slices.Equal(c.Addresses, contracts) {
+		return nil, fmt.Errorf("checkpoint %q is for contracts %v; got %v", path, c.Addresses, contracts)
+	}
+	for addr, p := range c.Contracts {
+		if p.NextTokenID > p.Supply {
+			return nil, fmt.Errorf("checkpoint %q: contract %v with next token ID %d > supply %d", path, addr, p.NextTokenID, p.Supply)
+		}
+		if p.Balances == nil {
+			p.Balances = make(map[common.Address]uint64)
+		}
+	}
+	return c, nil
+}
+
+// progress returns the contract's progress, beginning a new scan with the
+// supply returned by getSupply if there is none.
+func (c *checkpoint) progress(contract common.Address, getSupply func() (uint64, error)) (*contractProgress, error) {
+	if p, ok := c.Contracts[contract]; ok {
+		return p, nil
+	}
+	n, err := getSupply()
+	if err != nil {
+		return nil, err
+	}
+	p := &contractProgress{
+		Supply:   n,
+		Balances: make(map[common.Address]uint64),
+	}
+	c.Contracts[contract] = p
+	return p, nil
+}
+
+// done returns whether all tokens have been scanned.
+func (p *contractProgress) done() bool {
+	return p.NextTokenID >= p.Supply
+}
+
+// advance adds the balances of a scanned range of tokens, which MUST begin at
+// p.NextTokenID and end immediately before next.
+func (p *contractProgress) advance(balances map[common.Address]uint64, next uint64) {
+	for owner, n := range balances {
+		p.Balances[owner] += n
+	}
+	p.NextTokenID = next
+}
+
+// save atomically writes the checkpoint to its file, via a temporary file in
+// the same directory, so that it is never left partially written.
+func (c *checkpoint) save() error {
+	if c.path == "" {
+		return nil
+	}
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(%T): %v", c, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("os.CreateTemp(): %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after successful rename
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing checkpoint: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("os.Rename(%q, %q): %v", tmp.Name(), c.path, err)
+	}
+	return nil
+}
+
+// balances merges the per-contract balances of the contracts into a map keyed
+// by holder and then contract, as expected by writeCSV().
+func (c *checkpoint) balances(contracts []common.Address) map[common.Address]map[common.Address]uint64 {
+	merged := make(map[common.Address]map[common.Address]uint64)
+	for _, contract := range contracts {
+		p, ok := c.Contracts[contract]
+		if !ok {
+			continue
+		}
+		for holder, n := range p.Balances {
+			if merged[holder] == nil {
+				merged[holder] = make(map[common.Address]uint64)
+			}
+			merged[holder][contract] = n
+		}
+	}
+	return merged
+}
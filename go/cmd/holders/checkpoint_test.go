@@ -0,0 +1,197 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+)
+
+const chainID = 1
+
+var (
+	contractA = common.HexToAddress("0xa")
+	contractB = common.HexToAddress("0xb")
+	alice     = common.HexToAddress("0xa11ce")
+	bob       = common.HexToAddress("0xb0b")
+)
+
+func supplyOf(n uint64) func() (uint64, error) {
+	return func() (uint64, error) { return n, nil }
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	contracts := []common.Address{contractA, contractB}
+
+	cp, err := loadCheckpoint(path, chainID, contracts)
+	if err != nil {
+		t.Fatalf("loadCheckpoint(%q) [non-existent] error %v", path, err)
+	}
+	if len(cp.Contracts) != 0 {
+		t.Fatalf("loadCheckpoint(%q) [non-existent] got %d contracts; want 0", path, len(cp.Contracts))
+	}
+
+	a, err := cp.progress(contractA, supplyOf(10))
+	if err != nil {
+		t.Fatalf("%T.progress(%v) error %v", cp, contractA, err)
+	}
+	a.advance(map[common.Address]uint64{alice: 2, bob: 3}, 5)
+	a.advance(map[common.Address]uint64{alice: 5}, 10)
+	b, err := cp.progress(contractB, supplyOf(4))
+	if err != nil {
+		t.Fatalf("%T.progress(%v) error %v", cp, contractB, err)
+	}
+	b.advance(map[common.Address]uint64{bob: 1}, 1)
+
+	if err := cp.save(); err != nil {
+		t.Fatalf("%T.save() error %v", cp, err)
+	}
+
+	got, err := loadCheckpoint(path, chainID, contracts)
+	if err != nil {
+		t.Fatalf("loadCheckpoint(%q) error %v", path, err)
+	}
+	if diff := cmp.Diff(cp, got, cmp.AllowUnexported(checkpoint{})); diff != "" {
+		t.Errorf("loadCheckpoint(%q) after save() diff (-want +got):\n%s", path, diff)
+	}
+
+	wantBalances := map[common.Address]map[common.Address]uint64{
+		alice: {contractA: 7},
+		bob:   {contractA: 3, contractB: 1},
+	}
+	if diff := cmp.Diff(wantBalances, got.balances(contracts)); diff != "" {
+		t.Errorf("%T.balances() diff (-want +got):\n%s", got, diff)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("os.ReadDir() error %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%T.save() left %d files in directory; want only the checkpoint", cp, len(entries))
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	contracts := []common.Address{contractA, contractB}
+
+	cp, err := loadCheckpoint(path, chainID, contracts)
+	if err != nil {
+		t.Fatalf("loadCheckpoint(%q) error %v", path, err)
+	}
+	a, err := cp.progress(contractA, supplyOf(10))
+	if err != nil {
+		t.Fatalf("%T.progress(%v) error %v", cp, contractA, err)
+	}
+	a.advance(map[common.Address]uint64{alice: 4}, 4)
+	if err := cp.save(); err != nil {
+		t.Fatalf("%T.save() error %v", cp, err)
+	}
+
+	resumed, err := loadCheckpoint(path, chainID, contracts)
+	if err != nil {
+		t.Fatalf("loadCheckpoint(%q) error %v", path, err)
+	}
+	// The supply is fixed when scanning begins, so MUST NOT be fetched again.
+	a, err = resumed.progress(contractA, supplyOf(1000))
+	if err != nil {
+		t.Fatalf("%T.progress(%v) after resuming error %v", resumed, contractA, err)
+	}
+	if a.Supply != 10 || a.NextTokenID != 4 || a.done() {
+		t.Errorf("%T.progress(%v) after resuming got {Supply: %d, NextTokenID: %d, done: %t}; want {10, 4, false}", resumed, contractA, a.Supply, a.NextTokenID, a.done())
+	}
+	a.advance(map[common.Address]uint64{alice: 1, bob: 5}, 10)
+	if !a.done() {
+		t.Errorf("%T.done() after scanning all tokens got false; want true", a)
+	}
+
+	want := map[common.Address]map[common.Address]uint64{
+		alice: {contractA: 5},
+		bob:   {contractA: 5},
+	}
+	if diff := cmp.Diff(want, resumed.balances(contracts)); diff != "" {
+		t.Errorf("%T.balances() after resuming diff (-want +got):\n%s", resumed, diff)
+	}
+}
+
+func TestLoadCheckpointErrors(t *testing.T) {
+	contracts := []common.Address{contractA, contractB}
+
+	valid, err := loadCheckpoint(filepath.Join(t.TempDir(), "valid.json"), chainID, contracts)
+	if err != nil {
+		t.Fatalf("loadCheckpoint() error %v", err)
+	}
+	if _, err := valid.progress(contractA, supplyOf(10)); err != nil {
+		t.Fatalf("%T.progress() error %v", valid, err)
+	}
+	if err := valid.save(); err != nil {
+		t.Fatalf("%T.save() error %v", valid, err)
+	}
+
+	tests := []struct {
+		name      string
+		contents  string // if empty, valid is used
+		chainID   uint64
+		contracts []common.Address
+	}{
+		{
+			name:      "corrupt",
+			contents:  `{"contracts": {`,
+			chainID:   chainID,
+			contracts: contracts,
+		},
+		{
+			name:      "next token ID beyond supply",
+			contents:  `{"chain_id": 1, "addresses": ["0x000000000000000000000000000000000000000a"], "contracts": {"0x000000000000000000000000000000000000000a": {"supply": 5, "next_token_id": 6}}}`,
+			chainID:   chainID,
+			contracts: []common.Address{contractA},
+		},
+		{
+			name:      "different chain",
+			chainID:   chainID + 1,
+			contracts: contracts,
+		},
+		{
+			name:      "different contracts",
+			chainID:   chainID,
+			contracts: []common.Address{contractA},
+		},
+		{
+			name:      "reordered contracts",
+			chainID:   chainID,
+			contracts: []common.Address{contractB, contractA},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := valid.path
+			if tt.contents != "" {
+				path = filepath.Join(t.TempDir(), "checkpoint.json")
+				if err := os.WriteFile(path, []byte(tt.contents), 0600); err != nil {
+					t.Fatalf("os.WriteFile(%q) error %v", path, err)
+				}
+			}
+			if _, err := loadCheckpoint(path, tt.chainID, tt.contracts); err == nil {
+				t.Errorf("loadCheckpoint(%q, %d, %v) got nil error", path, tt.chainID, tt.contracts)
+			}
+		})
+	}
+}
+
+func TestCheckpointWithoutPath(t *testing.T) {
+	cp, err := loadCheckpoint("", chainID, nil)
+	if err != nil {
+		t.Fatalf(`loadCheckpoint("") error %v`, err)
+	}
+	if _, err := cp.progress(contractA, supplyOf(1)); err != nil {
+		t.Fatalf("%T.progress() error %v", cp, err)
+	}
+	if err := cp.save(); err != nil {
+		t.Errorf("%T.save() without path; error %v", cp, err)
+	}
+}
@@ -1,33 +1,51 @@
 // Binary holder fetches holders balances for a set of input ERC721 collections.
 // It reads new-line delimited contract addresses from stdin and writes
-// token balances as CSV to stdout (row = holder, column = collection).
+// token balances as CSV to stdout (row = holder, column = collection). If
+// -labels is set, an address_label column is added after the holder; see
+// eth.LoadLabels() re the file format.
+//
+// If -checkpoint is set, per-contract progress is periodically written to the
+// file, and a subsequent run with the same file resumes from it, skipping
+// completed contracts. Rerunning with a completed checkpoint writes the same
+// CSV without rescanning; delete the file to start afresh. A checkpoint can't be
+// used with a different chain or list of contracts.
 package main
 
 import (
 	"context"
-	"encoding/csv"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"math"
 	"math/big"
 	"os"
 	"sync"
-	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"golang.org/x/sync/errgroup"
 
 	"github.com/cxkoda/solgo/contracts/erc"
 	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/eth/export"
 	"github.com/cxkoda/solgo/go/proof"
+	proofsync "github.com/cxkoda/solgo/go/sync"
+)
+
+var (
+	concurrency     = flag.Int("concurrency", 32, "Maximum number of concurrent ownerOf() calls; negative for no limit")
+	labelsFile      = flag.String("labels", "", "Optional JSON or CSV file of address labels to include in the output")
+	checkpointFile  = flag.String("checkpoint", "", "Optional file in which to record progress, and from which to resume if it exists")
+	checkpointEvery = flag.Uint64("checkpoint_every", 1000, "Number of tokens to scan between writes to the -checkpoint file")
+
+	logger = ethlog.NewFromFlag(flag.CommandLine, os.Stderr)
 )
 
 func main() {
 	d := eth.MustNewDialerFromFlag(flag.CommandLine, proof.InfuraMainnetURL())
+	rl := eth.MustNewRateLimiterFromFlags(flag.CommandLine, nil, 0)
 	flag.Parse()
-	if err := run(context.Background(), d, os.Stdin, os.Stdout); err != nil {
+	if err := run(context.Background(), d, rl, os.Stdin, os.Stdout); err != nil {
 		exit(err)
 	}
 }
@@ -39,84 +57,135 @@ func exit(err error) {
 }
 
 // run reads new-line delimeted address from addrSrc, finds all of their
-// delegations, and writes a CSV to out.
-func run(ctx context.Context, d *eth.Dialer, addrSrc io.Reader, out io.Writer) error {
+// delegations, and writes a CSV to out. All calls to the node are throttled by
+// rl.
+func run(ctx context.Context, d *eth.Dialer, rl *eth.RateLimiter, addrSrc io.Reader, out io.Writer) error {
+	var labels *eth.Labels
+	if *labelsFile != "" {
+		l, err := eth.LoadLabels(*labelsFile)
+		if err != nil {
+			return err
+		}
+		labels = l
+	}
+
 	client, err := d.Dial(ctx)
 	if err != nil {
 		return fmt.Errorf("%T.Dial(): %v", d, err)
 	}
 	defer client.Close()
+	backend := rl.ContractBackend(client)
 
 	addrs, err := eth.AddressPerLine(addrSrc)
 	if err != nil {
 		return fmt.Errorf("eth.AddressPerLine((…): %v", err)
 	}
 
-	balances := make(map[common.Address]map[common.Address]uint64)
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.ChainID(): %v", client, err)
+	}
+	cp, err := loadCheckpoint(*checkpointFile, chainID.Uint64(), addrs)
+	if err != nil {
+		return err
+	}
+	chunk := *checkpointEvery
+	if *checkpointFile == "" || chunk == 0 {
+		// Without a checkpoint there is no need to pause between chunks.
+		chunk = math.MaxUint64
+	}
 
 	for _, tokenAddress := range addrs {
-		token, err := erc.NewIERC721Enumerable(tokenAddress, client)
+		token, err := erc.NewIERC721Enumerable(tokenAddress, backend)
 		if err != nil {
 			return fmt.Errorf("erc.NewIERC721Enumerable(…): %v", err)
 		}
 
-		g, ctx := errgroup.WithContext(ctx)
-
-		var mu sync.Mutex
-		done := new(uint64)
-		supply, err := token.TotalSupply(nil)
+		prog, err := cp.progress(tokenAddress, func() (uint64, error) {
+			supply, err := token.TotalSupply(nil)
+			if err != nil {
+				return 0, fmt.Errorf("%T.TotalSupply(): %v", token, err)
+			}
+			n, err := eth.ToUint64(supply)
+			if err != nil {
+				return 0, fmt.Errorf("%T.TotalSupply() of %v: %v", token, tokenAddress, err)
+			}
+			return n, nil
+		})
 		if err != nil {
-			return fmt.Errorf("%T.TotalSupply(): %v", token, err)
+			return err
+		}
+		if prog.done() {
+			logger.Info("Already scanned according to checkpoint", ethlog.Address("contract", tokenAddress))
+			continue
+		}
+		if prog.NextTokenID > 0 {
+			logger.Info("Resuming from checkpoint", ethlog.Address("contract", tokenAddress), ethlog.Uint("token_id", prog.NextTokenID))
 		}
 
-		n := supply.Int64()
-		for i := int64(0); i < n; i++ {
-			tokenId := new(big.Int).SetInt64(i)
-			g.Go(func() error {
-				owner, err := token.OwnerOf(&bind.CallOpts{Context: ctx}, tokenId)
-				if err != nil {
-					return err
-				}
-
-				mu.Lock()
-				defer mu.Unlock()
-
-				if balances[owner] == nil {
-					balances[owner] = make(map[common.Address]uint64)
-				}
-				balances[owner][tokenAddress]++
-
-				atomic.AddUint64(done, 1)
-				log.Printf("%d/%d (at least)", *done, n)
-				return nil
+		n := prog.Supply
+		for !prog.done() {
+			start := prog.NextTokenID
+			end := n
+			if n-start > chunk {
+				end = start + chunk
+			}
+
+			pool := proofsync.NewPool(ctx)
+			pool.SetLimit(*concurrency)
+			pool.OnProgress(func(done, _ uint64) {
+				logger.Info("Progress", ethlog.Address("contract", tokenAddress), ethlog.Uint("done", start+done), ethlog.Uint("total", n))
 			})
-		}
 
-		if err := g.Wait(); err != nil {
-			return err
+			var mu sync.Mutex
+			balances := make(map[common.Address]uint64)
+			for i := start; i < end; i++ {
+				tokenId := new(big.Int).SetUint64(i)
+				pool.Go(func(ctx context.Context) error {
+					owner, err := token.OwnerOf(&bind.CallOpts{Context: ctx}, tokenId)
+					if err != nil {
+						return err
+					}
+
+					mu.Lock()
+					defer mu.Unlock()
+					balances[owner]++
+					return nil
+				})
+			}
+
+			if err := pool.Wait(); err != nil {
+				return err
+			}
+			prog.advance(balances, end)
+			if err := cp.save(); err != nil {
+				return fmt.Errorf("saving checkpoint: %v", err)
+			}
 		}
-		log.Printf("%d/%d", *done, n)
 	}
 
-	return writeCSV(out, addrs, balances)
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.ChainID(): %v", client, err)
+	}
+	return writeCSV(out, addrs, cp.balances(addrs), labels, chainID.Uint64())
 }
 
 // writeCSV writes a CSV containing holder addresses and token balances as rows and collections as columns.
-func writeCSV(w io.Writer, tokenAddrs []common.Address, balances map[common.Address]map[common.Address]uint64) error {
-	var rows [][]string
-	row := []string{"address"}
+// Holders are annotated with their labels on the chain, if any.
+func writeCSV(w io.Writer, tokenAddrs []common.Address, balances map[common.Address]map[common.Address]uint64, labels *eth.Labels, chainID uint64) error {
+	holder := func(h common.Address) common.Address { return h }
+	cols := export.LabeledAddress("address", holder, labels, chainID)
 	for _, a := range tokenAddrs {
-		row = append(row, a.String())
+		a := a
+		cols = append(cols, export.Uint(a.String(), func(h common.Address) uint64 {
+			return balances[h][a]
+		}))
 	}
-	rows = append(rows, row)
 
-	for h, bs := range balances {
-		row := []string{h.String()}
-		for _, a := range tokenAddrs {
-			row = append(row, fmt.Sprintf("%d", bs[a]))
-		}
-		rows = append(rows, row)
+	holders := make([]common.Address, 0, len(balances))
+	for h := range balances {
+		holders = append(holders, h)
 	}
-
-	return csv.NewWriter(w).WriteAll(rows)
+	return export.Write(export.NewCSV(w), cols, holders)
 }
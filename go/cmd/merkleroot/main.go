@@ -0,0 +1,81 @@
+// Binary merkleroot computes an OpenZeppelin-compatible Merkle tree over a set
+// of addresses, for use as an allowlist. It reads new-line delimited addresses
+// from stdin and writes the root and per-address proofs to stdout as JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/merkle"
+)
+
+var dumpPath = flag.String("dump", "", "If non-empty, path to which the full tree is written, loadable by OpenZeppelin's StandardMerkleTree.load()")
+
+func main() {
+	flag.Parse()
+	if err := run(os.Stdin, os.Stdout); err != nil {
+		exit(err)
+	}
+}
+
+// exit prints err to stderr and exits with code 1.
+func exit(err error) {
+	fmt.Fprint(os.Stderr, err)
+	os.Exit(1)
+}
+
+// output is the JSON written to stdout.
+type output struct {
+	Root   common.Hash                      `json:"root"`
+	Proofs map[common.Address][]common.Hash `json:"proofs"`
+}
+
+// run reads new-line delimited addresses from addrSrc and writes the tree's
+// root and proofs to out.
+func run(addrSrc io.Reader, out io.Writer) error {
+	addrs, err := eth.AddressPerLine(addrSrc)
+	if err != nil {
+		return fmt.Errorf("eth.AddressPerLine(…): %v", err)
+	}
+
+	tree, err := merkle.NewAddresses(addrs)
+	if err != nil {
+		return fmt.Errorf("merkle.NewAddresses([%d addresses]): %v", len(addrs), err)
+	}
+
+	o := output{
+		Root:   tree.Root(),
+		Proofs: make(map[common.Address][]common.Hash),
+	}
+	for i, a := range addrs {
+		p, err := tree.Proof(i)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			p = []common.Hash{}
+		}
+		o.Proofs[a] = p
+	}
+
+	if *dumpPath != "" {
+		buf, err := json.Marshal(tree)
+		if err != nil {
+			return fmt.Errorf("json.Marshal(%T): %v", tree, err)
+		}
+		if err := os.WriteFile(*dumpPath, buf, 0644); err != nil {
+			return fmt.Errorf("os.WriteFile(%q, …): %v", *dumpPath, err)
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(o)
+}
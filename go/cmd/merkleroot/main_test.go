@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/cxkoda/solgo/go/merkle"
+)
+
+func TestRun(t *testing.T) {
+	addrs := []common.Address{
+		common.HexToAddress("0x1"),
+		common.HexToAddress("0x2"),
+		common.HexToAddress("0x3"),
+	}
+	var lines []string
+	for _, a := range addrs {
+		lines = append(lines, a.Hex())
+	}
+
+	out := new(bytes.Buffer)
+	if err := run(strings.NewReader(strings.Join(lines, "\n")), out); err != nil {
+		t.Fatalf("run(…) error %v", err)
+	}
+
+	var got output
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s, %T) error %v", out, &got, err)
+	}
+	if n := len(got.Proofs); n != len(addrs) {
+		t.Errorf("run() output has %d proofs; want %d", n, len(addrs))
+	}
+
+	for _, a := range addrs {
+		leaf, err := merkle.LeafHash([]string{"address"}, a)
+		if err != nil {
+			t.Fatalf("merkle.LeafHash(address, %v) error %v", a, err)
+		}
+		if !merkle.Verify(got.Root, leaf, got.Proofs[a]) {
+			t.Errorf("merkle.Verify(%v, [leaf of %v], %v) got false; want true", got.Root, a, got.Proofs[a])
+		}
+	}
+}
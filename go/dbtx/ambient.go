@@ -0,0 +1,197 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// A ContextFunc is equivalent to a Func except that it also receives a
+// Context carrying the transaction, from which functions deep in a call stack
+// can retrieve it with From(). It MUST NOT call Commit() nor Rollback().
+type ContextFunc func(context.Context, *sql.Tx) error
+
+var (
+	// ErrNoTx is returned by From() if the Context doesn't carry a
+	// transaction.
+	ErrNoTx = errors.New("no transaction in context")
+	// ErrTxEnded is returned by From() if the Context carries a transaction
+	// that has since been committed or rolled back; typically because the
+	// Context was retained beyond the ContextFunc to which it was passed.
+	ErrTxEnded = errors.New("transaction in context already ended")
+	// ErrForeignTx is returned by DoContext() if the Context carries a
+	// transaction begun by a different Beginner.
+	ErrForeignTx = errors.New("transaction in context begun by different Beginner")
+	// ErrTxOptionsMismatch is returned by DoContext() if joining a transaction
+	// begun with different sql.TxOptions.
+	ErrTxOptionsMismatch = errors.New("transaction in context has different options")
+	// ErrRollbackOnly is returned by DoContext() if a ContextFunc that joined
+	// the transaction returned an error that was then swallowed by a caller.
+	// The transaction is rolled back, instead of committing partial work.
+	ErrRollbackOnly = errors.New("transaction marked rollback-only by joined function")
+)
+
+// ctxKey is the Context key under which an *ambient is stored.
+type ctxKey struct{}
+
+// An ambient is a transaction carried by a Context.
+type ambient struct {
+	tx *sql.Tx
+	// beginner and opts are nil if the transaction was attached with WithTx()
+	// instead of begun by DoContext().
+	beginner Beginner
+	opts     *sql.TxOptions
+
+	mu                  sync.Mutex
+	ended, rollbackOnly bool
+}
+
+func ambientFrom(ctx context.Context) (*ambient, bool) {
+	a, ok := ctx.Value(ctxKey{}).(*ambient)
+	return a, ok
+}
+
+func (a *ambient) isEnded() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.ended
+}
+
+// end marks the transaction as ended, returning whether it was marked as
+// rollback-only. It MUST be called before Commit() or Rollback().
+func (a *ambient) end() (rollbackOnly bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ended = true
+	return a.rollbackOnly
+}
+
+func (a *ambient) setRollbackOnly() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rollbackOnly = true
+}
+
+// WithTx returns a copy of ctx carrying tx, for retrieval with From(). It is
+// only necessary for transactions not begun by DoContext(), which already
+// passes such a Context to each ContextFunc, and the caller is then
+// responsible for not using the returned Context after the transaction ends.
+//
+// WithTx panics if ctx already carries a different transaction that hasn't
+// ended, as functions would otherwise unknowingly split their work across
+// transactions. If ctx already carries tx, it is returned unchanged.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	if a, ok := ambientFrom(ctx); ok && !a.isEnded() {
+		if a.tx == tx {
+			return ctx
+		}
+		panic("dbtx.WithTx() with Context already carrying a different, active transaction")
+	}
+	return context.WithValue(ctx, ctxKey{}, &ambient{tx: tx})
+}
+
+// From returns the transaction carried by ctx, as attached by DoContext() or
+// WithTx(). The returned error is ErrNoTx if there is no such transaction, or
+// ErrTxEnded if it was begun by DoContext() and has since been committed or
+// rolled back.
+func From(ctx context.Context) (*sql.Tx, error) {
+	a, ok := ambientFrom(ctx)
+	if !ok {
+		return nil, ErrNoTx
+	}
+	if a.isEnded() {
+		return nil, ErrTxEnded
+	}
+	return a.tx, nil
+}
+
+// DoContext is equivalent to Do() except that each ContextFunc receives a
+// Context carrying its transaction; see From(). Unlike Do(), it joins any
+// transaction already carried by ctx, as described below.
+//
+// If ctx already carries an active transaction begun by DoContext() with the
+// same Beginner, all ContextFuncs join it instead of beginning their own, and
+// the outermost call remains responsible for committing or rolling back. A
+// ContextFunc that returns an error marks the joined transaction as
+// rollback-only, so the outermost call returns ErrRollbackOnly even if the
+// error is swallowed along the way. Joining returns ErrForeignTx if the
+// transaction was begun by a different Beginner, or attached with WithTx(),
+// and ErrTxOptionsMismatch if opts is non-nil and different to those of the
+// transaction.
+func (t Transactor) DoContext(ctx context.Context, opts *sql.TxOptions, fns ...ContextFunc) error {
+	if a, ok := ambientFrom(ctx); ok && !a.isEnded() {
+		return t.join(ctx, a, opts, fns)
+	}
+
+	for _, fn := range fns {
+		tx, err := t.BeginTx(ctx, opts)
+		if err != nil {
+			return fmt.Errorf("%T.BeginTx(%+v): %v", t.Beginner, opts, err)
+		}
+
+		a := &ambient{
+			tx:       tx,
+			beginner: t.Beginner,
+			opts:     opts,
+		}
+		err = fn(context.WithValue(ctx, ctxKey{}, a), tx)
+		if a.end() && err == nil {
+			err = ErrRollbackOnly
+		}
+		if err != nil {
+			return multierror.Append(err, tx.Rollback()) // nil error on Rollback is ignored
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// join runs all fns in the ambient transaction a; see DoContext().
+func (t Transactor) join(ctx context.Context, a *ambient, opts *sql.TxOptions, fns []ContextFunc) error {
+	if a.beginner == nil || !sameBeginner(a.beginner, t.Beginner) {
+		return fmt.Errorf("%w: joining %T with %T", ErrForeignTx, a.beginner, t.Beginner)
+	}
+	if opts != nil && txOptions(opts) != txOptions(a.opts) {
+		return fmt.Errorf("%w: joining %+v with %+v", ErrTxOptionsMismatch, txOptions(a.opts), *opts)
+	}
+
+	for _, fn := range fns {
+		if err := fn(ctx, a.tx); err != nil {
+			a.setRollbackOnly()
+			return err
+		}
+	}
+	return nil
+}
+
+// txOptions returns *o, or the zero value (i.e. the driver's defaults) if o is
+// nil.
+func txOptions(o *sql.TxOptions) sql.TxOptions {
+	if o == nil {
+		return sql.TxOptions{}
+	}
+	return *o
+}
+
+// sameBeginner reports whether a and b are equal, without panicking if their
+// dynamic type isn't comparable.
+func sameBeginner(a, b Beginner) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb || !ta.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// DoContext is a convenience wrapper for Transactor{b}.DoContext(), in the same
+// manner as Do().
+func DoContext(ctx context.Context, b Beginner, opts *sql.TxOptions, fns ...ContextFunc) error {
+	return Transactor{b}.DoContext(ctx, opts, fns...)
+}
@@ -0,0 +1,228 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDoContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	const create = `
+CREATE TABLE called (
+	func_id integer NOT NULL,
+	PRIMARY KEY(func_id)
+)
+`
+
+	// insert is a repository-style function that only has access to the
+	// transaction via the Context.
+	insert := func(ctx context.Context, id int) error {
+		tx, err := From(ctx)
+		if err != nil {
+			return fmt.Errorf("From(ctx): %w", err)
+		}
+		const qry = `INSERT INTO called (func_id) VALUES ($1)`
+		if _, err := tx.ExecContext(ctx, qry, id); err != nil {
+			return fmt.Errorf("%T.Exec(%q, %d) error %v", tx, qry, id, err)
+		}
+		return nil
+	}
+	insertFunc := func(id int) ContextFunc {
+		return func(ctx context.Context, _ *sql.Tx) error {
+			return insert(ctx, id)
+		}
+	}
+
+	errFail := errors.New("fail")
+
+	tests := []struct {
+		name string
+		// fn is run by DoContext() with db, which it MAY use to nest calls.
+		fn           func(context.Context, *sql.DB, *sql.Tx) error
+		wantInserted []int
+		wantErr      error
+	}{
+		{
+			name: "nested DoContext joins",
+			fn: func(ctx context.Context, db *sql.DB, outer *sql.Tx) error {
+				if err := insert(ctx, 0); err != nil {
+					return err
+				}
+				return Transactor{db}.DoContext(ctx, nil, insertFunc(1), func(_ context.Context, inner *sql.Tx) error {
+					if inner != outer {
+						return errors.New("nested DoContext() began new transaction")
+					}
+					return nil
+				})
+			},
+			wantInserted: []int{0, 1},
+		},
+		{
+			name: "nested Do doesn't join",
+			fn: func(ctx context.Context, db *sql.DB, outer *sql.Tx) error {
+				if err := insert(ctx, 0); err != nil {
+					return err
+				}
+				err := Do(ctx, db, &sql.TxOptions{}, func(inner *sql.Tx) error {
+					if inner == outer {
+						return errors.New("nested Do() joined transaction")
+					}
+					_, err := inner.ExecContext(ctx, `INSERT INTO called (func_id) VALUES (1)`)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+				// Do()'s transaction was already committed.
+				return errFail
+			},
+			wantInserted: []int{1},
+			wantErr:      errFail,
+		},
+		{
+			name: "swallowed error marks rollback-only",
+			fn: func(ctx context.Context, db *sql.DB, _ *sql.Tx) error {
+				if err := insert(ctx, 0); err != nil {
+					return err
+				}
+				err := DoContext(ctx, db, nil, insertFunc(1), func(context.Context, *sql.Tx) error {
+					return errFail
+				})
+				if !errors.Is(err, errFail) {
+					return fmt.Errorf("nested DoContext() got err %v; want %v", err, errFail)
+				}
+				return nil
+			},
+			wantErr: ErrRollbackOnly,
+		},
+		{
+			name: "different Beginner",
+			fn: func(ctx context.Context, db *sql.DB, _ *sql.Tx) error {
+				conn, err := db.Conn(ctx)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				return DoContext(ctx, conn, nil, insertFunc(0))
+			},
+			wantErr: ErrForeignTx,
+		},
+		{
+			name: "different options",
+			fn: func(ctx context.Context, db *sql.DB, _ *sql.Tx) error {
+				return DoContext(ctx, db, &sql.TxOptions{ReadOnly: true}, insertFunc(0))
+			},
+			wantErr: ErrTxOptionsMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			db := newDB(ctx, t)
+			if _, err := db.Exec(create); err != nil {
+				t.Fatalf("%T.Exec(%q) error %v", db, create, err)
+			}
+
+			var leaked context.Context
+			err := DoContext(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+				leaked = ctx
+				return tt.fn(ctx, db, tx)
+			})
+			if !errors.Is(err, tt.wantErr) || (err != nil && tt.wantErr == nil) {
+				t.Errorf("DoContext(…) got err %v; want %v", err, tt.wantErr)
+			}
+
+			if _, err := From(leaked); !errors.Is(err, ErrTxEnded) {
+				t.Errorf("From([Context retained after DoContext() returned]) got err %v; want %v", err, ErrTxEnded)
+			}
+
+			const qry = `SELECT func_id FROM called ORDER BY func_id`
+			rows, err := db.Query(qry)
+			if err != nil {
+				t.Fatalf("%T.Query(%q) error %v", db, qry, err)
+			}
+			var got []int
+			for rows.Next() {
+				var i int
+				if err := rows.Scan(&i); err != nil {
+					t.Fatalf("%T.Scan(%T) error %v", rows, &i, err)
+				}
+				got = append(got, i)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("%T.Err() = %v", rows, err)
+			}
+			if diff := cmp.Diff(tt.wantInserted, got); diff != "" {
+				t.Errorf("%q results after DoContext(); diff (-want +got):\n%s", qry, diff)
+			}
+		})
+	}
+}
+
+// failingBeginner is a Beginner that always returns its error.
+type failingBeginner struct {
+	err error
+}
+
+func (b failingBeginner) BeginTx(context.Context, *sql.TxOptions) (*sql.Tx, error) {
+	return nil, b.err
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	// The transactions are never used, only compared by identity.
+	a, b := new(sql.Tx), new(sql.Tx)
+
+	if _, err := From(ctx); !errors.Is(err, ErrNoTx) {
+		t.Errorf("From(context.Background()) got err %v; want %v", err, ErrNoTx)
+	}
+
+	withA := WithTx(ctx, a)
+	if got, err := From(withA); err != nil || got != a {
+		t.Errorf("From(WithTx(ctx, a)) got %p, err %v; want %p, nil", got, err, a)
+	}
+	if got := WithTx(withA, a); got != withA {
+		t.Errorf("WithTx() with Context already carrying the same transaction returned new Context")
+	}
+
+	t.Run("different transaction", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("WithTx() with Context already carrying a different transaction did not panic")
+			}
+		}()
+		WithTx(withA, b)
+	})
+
+	t.Run("Do", func(t *testing.T) {
+		errBegin := errors.New("begin")
+		err := Do(withA, failingBeginner{errBegin}, nil, func(*sql.Tx) error {
+			return errors.New("Func called despite BeginTx() error")
+		})
+		// Do() ignores the transaction so returns the Beginner's error instead
+		// of ErrForeignTx.
+		if err == nil || errors.Is(err, ErrForeignTx) || !strings.Contains(err.Error(), errBegin.Error()) {
+			t.Errorf("Do(WithTx(…), [failing Beginner]) got err %v; want from BeginTx()", err)
+		}
+	})
+
+	t.Run("DoContext", func(t *testing.T) {
+		err := DoContext(withA, nil, nil, func(context.Context, *sql.Tx) error {
+			return errors.New("joined transaction attached by WithTx()")
+		})
+		if !errors.Is(err, ErrForeignTx) {
+			t.Errorf("DoContext(WithTx(…)) got err %v; want %v", err, ErrForeignTx)
+		}
+	})
+}
@@ -5,7 +5,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"database/sql"
+	"encoding/binary"
 	"fmt"
+	"regexp"
 
 	"github.com/hashicorp/go-multierror"
 
@@ -35,6 +37,8 @@ type Transactor struct {
 //
 // See PgTxLock() re unlocking as rationale for accepting multiple functions.
 // Note, however, that Do() is agnostic to the underlying database type.
+//
+// Any transaction carried by ctx is ignored; use DoContext() to join it.
 func (t Transactor) Do(ctx context.Context, opts *sql.TxOptions, fns ...Func) error {
 	for _, fn := range fns {
 		tx, err := t.BeginTx(ctx, opts)
@@ -121,10 +125,25 @@ func (e Exclusivity) TryPgTxLock(ctx context.Context, tx *sql.Tx, key int64) (bo
 
 // PgLockKey returns an int64 for use as a key in obtaining a PostgreSQL
 // advisory lock. The returned value is derived from interpretting the first 8
-// bytes of the sha256 sum of key as an int64.
+// bytes of the sha256 sum of key as a little-endian int64. Little-endian was
+// historically implied by the platform, so is now explicit to keep keys stable.
 func PgLockKey(key string) int64 {
 	h := sha256.Sum256([]byte(key))
 	var b [8]byte
 	copy(b[:], h[:])
-	return memconv.Cast[[8]byte, int64](&b)
+	return memconv.Int64FromBytes(binary.LittleEndian, b)
+}
+
+// pgTableName matches table names that are safe to interpolate into queries
+// without quoting.
+var pgTableName = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// ValidatePgTableName returns an error if name isn't a lower-case, unquoted
+// PostgreSQL identifier, optionally schema-qualified. Names that pass are safe
+// to interpolate into queries.
+func ValidatePgTableName(name string) error {
+	if !pgTableName.MatchString(name) {
+		return fmt.Errorf("invalid table name %q; must be lower-case, unquoted PostgreSQL identifier", name)
+	}
+	return nil
 }
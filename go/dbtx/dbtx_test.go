@@ -197,3 +197,26 @@ func TestPgTxLock(t *testing.T) {
 		try(t, begin(t), exclusiveKey, false)
 	})
 }
+
+func TestValidatePgTableName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "cursors"},
+		{name: "_private"},
+		{name: "indexing.ownership_v2"},
+		{name: "", wantErr: true},
+		{name: "Cursors", wantErr: true},
+		{name: "2cursors", wantErr: true},
+		{name: "a.b.c", wantErr: true},
+		{name: `"quoted"`, wantErr: true},
+		{name: "t; DROP TABLE t", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidatePgTableName(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePgTableName(%q) got err %v; want error %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
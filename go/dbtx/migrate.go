@@ -0,0 +1,143 @@
+package dbtx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MigrationsTable is the PostgreSQL table in which Migrate() records applied
+// migrations.
+const MigrationsTable = "dbtx_migrations"
+
+// migrationLockKey is the advisory-lock key used to serialise concurrent calls
+// to Migrate(), even from different processes.
+var migrationLockKey = PgLockKey("github.com/cxkoda/solgo/go/dbtx.Migrate")
+
+// Migrate applies all SQL migrations in the root of the file system (typically
+// an embed.FS) that haven't already been applied to the PostgreSQL database.
+// Files MUST be named <version>.sql or <version>_<description>.sql, where
+// version is a positive integer (leading zeros are allowed and encouraged, for
+// readability), and are applied in increasing order of version. Files not
+// ending in .sql are ignored.
+//
+// Each migration is applied in its own transaction, along with recording its
+// version in MigrationsTable, so a failed migration is never partially
+// applied; Migrate() returns the first such error without attempting later
+// versions. A file MAY contain multiple statements if the database driver
+// supports them, as pgx does for statements without arguments.
+//
+// Every transaction holds an Exclusive PgTxLock() so that concurrent
+// deployments of the same service never apply a migration twice. Modifying a
+// migration after it has been applied is an error, as is detected by a
+// checksum of its contents.
+func Migrate(ctx context.Context, db Beginner, migrations fs.FS) error {
+	ms, err := readMigrations(migrations)
+	if err != nil {
+		return err
+	}
+
+	fns := make([]Func, len(ms))
+	for i, m := range ms {
+		m := m
+		fns[i] = func(tx *sql.Tx) error {
+			return m.apply(ctx, tx)
+		}
+	}
+	return Do(ctx, db, nil, fns...)
+}
+
+// A migration is a single SQL file to be applied by Migrate().
+type migration struct {
+	version  int64
+	name     string
+	sql      string
+	checksum []byte
+}
+
+// readMigrations returns all migrations in the root of fsys, sorted by
+// version.
+func readMigrations(fsys fs.FS) ([]*migration, error) {
+	paths, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("fs.Glob(%T, *.sql): %v", fsys, err)
+	}
+
+	var ms []*migration
+	for _, p := range paths {
+		v, _, _ := strings.Cut(strings.TrimSuffix(p, ".sql"), "_")
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %q not named <version>[_<description>].sql with positive integer version", p)
+		}
+
+		buf, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, fmt.Errorf("fs.ReadFile(%T, %q): %v", fsys, p, err)
+		}
+		sum := sha256.Sum256(buf)
+		ms = append(ms, &migration{
+			version:  version,
+			name:     p,
+			sql:      string(buf),
+			checksum: sum[:],
+		})
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].version < ms[j].version
+	})
+	for i := 1; i < len(ms); i++ {
+		if ms[i].version == ms[i-1].version {
+			return nil, fmt.Errorf("migrations %q and %q have the same version %d", ms[i-1].name, ms[i].name, ms[i].version)
+		}
+	}
+	return ms, nil
+}
+
+// apply applies the migration within the transaction, unless it has already
+// been applied.
+func (m *migration) apply(ctx context.Context, tx *sql.Tx) error {
+	if err := Exclusive.PgTxLock(ctx, tx, migrationLockKey); err != nil {
+		return err
+	}
+
+	const create = `CREATE TABLE IF NOT EXISTS ` + MigrationsTable + ` (
+	version bigint NOT NULL,
+	name text NOT NULL,
+	checksum bytea NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY(version)
+)`
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("creating %s table: %v", MigrationsTable, err)
+	}
+
+	const qry = `SELECT checksum FROM ` + MigrationsTable + ` WHERE version = $1`
+	var sum []byte
+	switch err := tx.QueryRowContext(ctx, qry, m.version).Scan(&sum); {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return fmt.Errorf("%T.QueryRow(%q, %d): %v", tx, qry, m.version, err)
+	case !bytes.Equal(sum, m.checksum):
+		return fmt.Errorf("migration %q modified since version %d was applied", m.name, m.version)
+	default:
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return fmt.Errorf("applying migration %q: %v", m.name, err)
+	}
+	const insert = `INSERT INTO ` + MigrationsTable + ` (version, name, checksum) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, insert, m.version, m.name, m.checksum); err != nil {
+		return fmt.Errorf("recording migration %q: %v", m.name, err)
+	}
+	return nil
+}
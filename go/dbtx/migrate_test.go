@@ -0,0 +1,164 @@
+package dbtx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadMigrations(t *testing.T) {
+	tests := []struct {
+		name        string
+		fsys        fstest.MapFS
+		wantVersion []int64
+		wantErr     bool
+	}{
+		{
+			name: "sorted numerically",
+			fsys: fstest.MapFS{
+				"10_later.sql":  {},
+				"0002.sql":      {},
+				"1_first.sql":   {},
+				"README.md":     {},
+				"sub/3_sub.sql": {},
+			},
+			wantVersion: []int64{1, 2, 10},
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"1_a.sql":  {},
+				"01_b.sql": {},
+			},
+			wantErr: true,
+		},
+		{
+			name: "non-numeric version",
+			fsys: fstest.MapFS{
+				"create_users.sql": {},
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero version",
+			fsys: fstest.MapFS{
+				"0_init.sql": {},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms, err := readMigrations(tt.fsys)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("readMigrations() got err %v; want error? %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var got []int64
+			for _, m := range ms {
+				got = append(got, m.version)
+			}
+			if diff := cmp.Diff(tt.wantVersion, got); diff != "" {
+				t.Errorf("readMigrations() versions diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := newDB(ctx, t)
+
+	migrations := fstest.MapFS{
+		"0001_create.sql": {Data: []byte(`CREATE TABLE things (id integer NOT NULL, PRIMARY KEY(id))`)},
+		"0002_insert.sql": {Data: []byte(`INSERT INTO things (id) VALUES (1); INSERT INTO things (id) VALUES (2);`)},
+	}
+
+	// Concurrent deployers MUST NOT apply any migration more than once, which
+	// would fail due to the table already existing and the primary key.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := Migrate(ctx, db, migrations); err != nil {
+				t.Errorf("Migrate() concurrent call error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	count := func(t *testing.T, qry string) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRowContext(ctx, qry).Scan(&n); err != nil {
+			t.Fatalf("%T.QueryRow(%q) error %v", db, qry, err)
+		}
+		return n
+	}
+	const (
+		countThings   = `SELECT COUNT(*) FROM things`
+		countRecorded = `SELECT COUNT(*) FROM ` + MigrationsTable
+	)
+	if got, want := count(t, countThings), 2; got != want {
+		t.Errorf("After Migrate(); got %d things; want %d", got, want)
+	}
+
+	t.Run("incremental", func(t *testing.T) {
+		migrations["0003_more.sql"] = &fstest.MapFile{Data: []byte(`INSERT INTO things (id) VALUES (3)`)}
+		if err := Migrate(ctx, db, migrations); err != nil {
+			t.Fatalf("Migrate() with additional migration error %v", err)
+		}
+		if got, want := count(t, countThings), 3; got != want {
+			t.Errorf("After Migrate() with additional migration; got %d things; want %d", got, want)
+		}
+		if got, want := count(t, countRecorded), 3; got != want {
+			t.Errorf("After Migrate() with additional migration; got %d recorded migrations; want %d", got, want)
+		}
+	})
+
+	t.Run("failure rolled back", func(t *testing.T) {
+		fsys := fstest.MapFS{}
+		for k, v := range migrations {
+			fsys[k] = v
+		}
+		fsys["0004_ok.sql"] = &fstest.MapFile{Data: []byte(`INSERT INTO things (id) VALUES (4)`)}
+		fsys["0005_fail.sql"] = &fstest.MapFile{Data: []byte(`INSERT INTO things (id) VALUES (5); INSERT INTO things (id) VALUES (1);`)}
+
+		if err := Migrate(ctx, db, fsys); err == nil {
+			t.Fatalf("Migrate() with primary-key violation got nil error")
+		}
+		if got, want := count(t, countThings), 4; got != want {
+			t.Errorf("After failed Migrate(); got %d things; want %d", got, want)
+		}
+		if got, want := count(t, countRecorded), 4; got != want {
+			t.Errorf("After failed Migrate(); got %d recorded migrations; want %d", got, want)
+		}
+	})
+
+	t.Run("modified migration", func(t *testing.T) {
+		modified := fstest.MapFS{
+			"0001_create.sql": {Data: []byte(`CREATE TABLE other (id integer)`)},
+		}
+		if err := Migrate(ctx, db, modified); err == nil {
+			t.Errorf("Migrate() with modified migration got nil error")
+		}
+		var exists bool
+		const qry = `SELECT EXISTS (SELECT FROM information_schema.tables WHERE table_name = 'other')`
+		if err := db.QueryRowContext(ctx, qry).Scan(&exists); err != nil {
+			t.Fatalf("%T.QueryRow(%q) error %v", db, qry, err)
+		}
+		if exists {
+			t.Errorf("Migrate() applied modified migration")
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package erc721
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// TransferTopic is the topic of the ERC721 Transfer event. ERC20 Transfer
+// events have the same topic but fewer indexed arguments.
+var TransferTopic = eth.ERC721TransferTopic
+
+// A HolderChange is the change in a single holder's balance between two
+// blocks.
+type HolderChange struct {
+	Holder        common.Address
+	Before, After uint64
+}
+
+// Delta returns After - Before.
+func (c HolderChange) Delta() int64 {
+	return int64(c.After) - int64(c.Before)
+}
+
+// Gained returns whether the holder had no tokens before.
+func (c HolderChange) Gained() bool {
+	return c.Before == 0
+}
+
+// Lost returns whether the holder has no tokens after.
+func (c HolderChange) Lost() bool {
+	return c.After == 0
+}
+
+// A HolderDiffOption modifies the behaviour of HolderDiff().
+type HolderDiffOption func(*eth.Subscriber)
+
+// WithMaxBlockRange limits the number of blocks requested in a single call to
+// FilterLogs(); see eth.Subscriber.MaxBackfillRange.
+func WithMaxBlockRange(n uint64) HolderDiffOption {
+	return func(s *eth.Subscriber) {
+		s.MaxBackfillRange = n
+	}
+}
+
+// HolderDiff returns the change in balance of every holder whose balance of
+// the collection differs between the ends of blocks before and after, sorted
+// by holder address.
+//
+// Balances are computed from Transfer logs emitted in blocks [deployed,
+// after]; deployed SHOULD therefore be the block in which the collection was
+// deployed, or earlier.
+func HolderDiff(ctx context.Context, src eth.LogSource, collection common.Address, deployed, before, after uint64, opts ...HolderDiffOption) ([]HolderChange, error) {
+	if deployed > before || before > after {
+		return nil, fmt.Errorf("blocks must be ordered deployed (%d) <= before (%d) <= after (%d)", deployed, before, after)
+	}
+
+	sub := eth.NewSubscriber(src, ethereum.FilterQuery{
+		Addresses: []common.Address{collection},
+		Topics:    [][]common.Hash{{TransferTopic}},
+	})
+	for _, o := range opts {
+		o(sub)
+	}
+
+	balances := make(map[common.Address]uint64)
+	apply := func(l types.Log) error {
+		// ERC20 Transfers have a non-indexed value.
+		if len(l.Topics) != 4 || l.Topics[0] != TransferTopic {
+			return nil
+		}
+		from := common.BytesToAddress(l.Topics[1].Bytes())
+		to := common.BytesToAddress(l.Topics[2].Bytes())
+
+		if from != (common.Address{}) {
+			if balances[from] == 0 {
+				return fmt.Errorf("transfer from %v with zero balance in block %d, log %d; was %v deployed after block %d?", from, l.BlockNumber, l.Index, collection, deployed)
+			}
+			balances[from]--
+			if balances[from] == 0 {
+				delete(balances, from)
+			}
+		}
+		if to != (common.Address{}) {
+			balances[to]++
+		}
+		return nil
+	}
+
+	if err := sub.Range(ctx, deployed, before, apply); err != nil {
+		return nil, fmt.Errorf("scanning blocks [%d, %d]: %v", deployed, before, err)
+	}
+	snapshot := make(map[common.Address]uint64, len(balances))
+	for h, b := range balances {
+		snapshot[h] = b
+	}
+	if err := sub.Range(ctx, before+1, after, apply); err != nil {
+		return nil, fmt.Errorf("scanning blocks [%d, %d]: %v", before+1, after, err)
+	}
+
+	var changes []HolderChange
+	for h, b := range snapshot {
+		if a := balances[h]; a != b {
+			changes = append(changes, HolderChange{Holder: h, Before: b, After: a})
+		}
+	}
+	for h, a := range balances {
+		if _, ok := snapshot[h]; !ok {
+			changes = append(changes, HolderChange{Holder: h, After: a})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Holder[:], changes[j].Holder[:]) < 0
+	})
+	return changes, nil
+}
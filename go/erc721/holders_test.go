@@ -0,0 +1,129 @@
+package erc721
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeLogs is an eth.LogSource that serves a fixed set of logs, filtered by
+// block range and address.
+type fakeLogs []types.Log
+
+func (f fakeLogs) BlockNumber(context.Context) (uint64, error) {
+	return f[len(f)-1].BlockNumber, nil
+}
+
+func (f fakeLogs) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, l := range f {
+		if l.BlockNumber < q.FromBlock.Uint64() || l.BlockNumber > q.ToBlock.Uint64() {
+			continue
+		}
+		if len(q.Addresses) > 0 && l.Address != q.Addresses[0] {
+			continue
+		}
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+func (f fakeLogs) SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error) {
+	panic("unimplemented")
+}
+
+func TestHolderDiff(t *testing.T) {
+	collection := common.HexToAddress("0xc011")
+	other := common.HexToAddress("0x0de7")
+	var zero common.Address
+	alice := common.HexToAddress("0xa11ce")
+	bob := common.HexToAddress("0xb0b")
+	carol := common.HexToAddress("0xca201")
+	dave := common.HexToAddress("0xda7e")
+
+	var logs fakeLogs
+	transfer := func(block uint64, from, to common.Address, tokenID int64) {
+		logs = append(logs, types.Log{
+			Address: collection,
+			Topics: []common.Hash{
+				TransferTopic,
+				common.BytesToHash(from.Bytes()),
+				common.BytesToHash(to.Bytes()),
+				common.BigToHash(big.NewInt(tokenID)),
+			},
+			BlockNumber: block,
+			Index:       uint(len(logs)),
+		})
+	}
+
+	transfer(10, zero, alice, 0)
+	transfer(10, zero, alice, 1)
+	transfer(11, zero, bob, 2)
+	transfer(12, zero, carol, 3)
+	// ERC20 Transfer from the same contract, which MUST be ignored.
+	logs = append(logs, types.Log{
+		Address:     collection,
+		Topics:      []common.Hash{TransferTopic, common.BytesToHash(zero.Bytes()), common.BytesToHash(dave.Bytes())},
+		BlockNumber: 12,
+	})
+	// Another collection, which MUST be ignored.
+	logs = append(logs, types.Log{
+		Address:     other,
+		Topics:      []common.Hash{TransferTopic, common.BytesToHash(zero.Bytes()), common.BytesToHash(dave.Bytes()), {}},
+		BlockNumber: 13,
+	})
+	// Snapshot at 20
+	transfer(21, alice, dave, 0)
+	transfer(22, bob, dave, 2)
+	transfer(23, carol, zero, 3) // burn
+	transfer(24, zero, carol, 4)
+	transfer(25, zero, bob, 5)
+	transfer(25, bob, alice, 5) // balance changes within the range but not overall
+	// Beyond the range
+	transfer(31, dave, carol, 0)
+
+	ctx := context.Background()
+	got, err := HolderDiff(ctx, logs, collection, 10, 20, 30)
+	if err != nil {
+		t.Fatalf("HolderDiff(…) error %v", err)
+	}
+
+	// Alice and Carol have the same balances despite transfers so are
+	// excluded.
+	want := []HolderChange{
+		{Holder: bob, Before: 1, After: 0},
+		{Holder: dave, Before: 0, After: 2},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("HolderDiff(…) diff (-want +got):\n%s", diff)
+	}
+
+	for _, c := range got {
+		switch c.Holder {
+		case bob:
+			if !c.Lost() || c.Gained() || c.Delta() != -1 {
+				t.Errorf("%+v got Lost()=%t Gained()=%t Delta()=%d; want true false -1", c, c.Lost(), c.Gained(), c.Delta())
+			}
+		case dave:
+			if c.Lost() || !c.Gained() || c.Delta() != 2 {
+				t.Errorf("%+v got Lost()=%t Gained()=%t Delta()=%d; want false true 2", c, c.Lost(), c.Gained(), c.Delta())
+			}
+		}
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := HolderDiff(ctx, logs, collection, 10, 30, 20); err == nil {
+			t.Errorf("HolderDiff(…, before=30, after=20) got nil error; want non-nil")
+		}
+		// Starting after the mints results in transfers from holders without
+		// balances.
+		if _, err := HolderDiff(ctx, logs, collection, 15, 20, 30); err == nil {
+			t.Errorf("HolderDiff(…, deployed=15 [after mints], …) got nil error; want non-nil")
+		}
+	})
+}
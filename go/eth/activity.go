@@ -0,0 +1,434 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Topics of the ERC1155 transfer events.
+var (
+	ERC1155TransferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	ERC1155TransferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// A TokenStandard identifies the type of an Asset.
+type TokenStandard string
+
+// TokenStandards of Assets in a WalletActivity.
+const (
+	NativeToken TokenStandard = "native"
+	ERC20Token  TokenStandard = "ERC20"
+	ERC721Token TokenStandard = "ERC721"
+	// ERC1155Token Assets are keyed by contract only, not by token ID.
+	ERC1155Token TokenStandard = "ERC1155"
+)
+
+// An Asset is a token contract, or the chain's native token, in which case
+// Contract is the zero address.
+type Asset struct {
+	Standard TokenStandard
+	Contract common.Address
+}
+
+// A Flow aggregates transfers in a single direction.
+type Flow struct {
+	// Transfers is the number of transfers. Each token in an ERC1155
+	// TransferBatch is considered a separate transfer.
+	Transfers int
+	// Amount is the sum of the transferred amounts, which is the same as
+	// Transfers for ERC721 tokens. It is never nil.
+	Amount *big.Int
+}
+
+func (f *Flow) add(amount *big.Int) {
+	f.Transfers++
+	f.Amount.Add(f.Amount, amount)
+}
+
+// Flows are the inbound and outbound transfers of an Asset.
+type Flows struct {
+	In, Out Flow
+}
+
+func newFlows() *Flows {
+	return &Flows{
+		In:  Flow{Amount: new(big.Int)},
+		Out: Flow{Amount: new(big.Int)},
+	}
+}
+
+// Net returns In.Amount - Out.Amount.
+func (f *Flows) Net() *big.Int {
+	return new(big.Int).Sub(f.In.Amount, f.Out.Amount)
+}
+
+// AssetActivity summarises a wallet's transfers of a single Asset.
+type AssetActivity struct {
+	Asset Asset
+	Flows
+	// Counterparties are the Flows to and from each other party, keyed by
+	// their address. Mints and burns have the zero address as counterparty,
+	// and transfers by the wallet to itself have the wallet's address.
+	Counterparties map[common.Address]*Flows
+}
+
+// A WalletActivity summarises all transfers to and from a wallet over a range
+// of blocks, as returned by SummariseWalletActivity().
+type WalletActivity struct {
+	Wallet             common.Address
+	FromBlock, ToBlock uint64
+	Assets             map[Asset]*AssetActivity
+	// GasFees is the total transaction fee, including blob fees, paid by the
+	// wallet. It is nil unless native transfers were included; see
+	// WithNativeTransfers(). Fees are not included in the NativeToken Asset.
+	GasFees *big.Int
+}
+
+// SortedAssets returns all AssetActivity values ordered by TokenStandard, with
+// NativeToken first, and then by contract address.
+func (w *WalletActivity) SortedAssets() []*AssetActivity {
+	rank := map[TokenStandard]int{
+		NativeToken:  0,
+		ERC20Token:   1,
+		ERC721Token:  2,
+		ERC1155Token: 3,
+	}
+	out := make([]*AssetActivity, 0, len(w.Assets))
+	for _, a := range w.Assets {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ai, aj := out[i].Asset, out[j].Asset
+		if ai.Standard != aj.Standard {
+			return rank[ai.Standard] < rank[aj.Standard]
+		}
+		return ai.Contract.Cmp(aj.Contract) < 0
+	})
+	return out
+}
+
+// record adds a transfer of the asset to the summary, in either or both
+// directions depending on whether from and/or to are the wallet.
+func (w *WalletActivity) record(asset Asset, from, to common.Address, amount *big.Int) {
+	if from != w.Wallet && to != w.Wallet {
+		return
+	}
+	a, ok := w.Assets[asset]
+	if !ok {
+		a = &AssetActivity{
+			Asset:          asset,
+			Flows:          *newFlows(),
+			Counterparties: make(map[common.Address]*Flows),
+		}
+		w.Assets[asset] = a
+	}
+
+	cp := func(addr common.Address) *Flows {
+		f, ok := a.Counterparties[addr]
+		if !ok {
+			f = newFlows()
+			a.Counterparties[addr] = f
+		}
+		return f
+	}
+	if from == w.Wallet {
+		a.Out.add(amount)
+		cp(to).Out.add(amount)
+	}
+	if to == w.Wallet {
+		a.In.add(amount)
+		cp(from).In.add(amount)
+	}
+}
+
+// A NativeTransferBackend provides the chain data required for
+// WithNativeTransfers(). It is satisfied by *ethclient.Client.
+type NativeTransferBackend interface {
+	BlockFetcher
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// An ActivityOption modifies the behaviour of SummariseWalletActivity().
+type ActivityOption func(*activityConfig)
+
+type activityConfig struct {
+	maxRange uint64
+	native   NativeTransferBackend
+	tracer   RPCCaller
+}
+
+// WithActivityMaxBlockRange limits the number of blocks requested in a single
+// call to FilterLogs(); see Subscriber.MaxBackfillRange.
+func WithActivityMaxBlockRange(n uint64) ActivityOption {
+	return func(c *activityConfig) {
+		c.maxRange = n
+	}
+}
+
+// WithNativeTransfers includes the value of top-level transactions sent or
+// received by the wallet, and the gas fees that it paid. As native transfers
+// don't emit logs, every block in the range is fetched from b, which SHOULD
+// therefore be cached (see BlockCache) or the range kept small. Transactions
+// that failed contribute only to the gas fees.
+func WithNativeTransfers(b NativeTransferBackend) ActivityOption {
+	return func(c *activityConfig) {
+		c.native = b
+	}
+}
+
+// WithInternalTransfers includes native transfers made by contracts, as
+// returned by CallFrame.InternalTransfers(). Only transactions already known
+// to involve the wallet, either by a token transfer or by it being the
+// transaction's sender or recipient (see WithNativeTransfers()), are traced.
+// Internal transfers in other transactions, e.g. a contract paying out to the
+// wallet at another account's request, are therefore missed.
+func WithInternalTransfers(c RPCCaller) ActivityOption {
+	return func(cfg *activityConfig) {
+		cfg.tracer = c
+	}
+}
+
+// SummariseWalletActivity aggregates all ERC20, ERC721, and ERC1155 transfers
+// to and from the wallet in blocks [from, to], by scanning their logs, into a
+// per-Asset summary. Native transfers are only included if requested with
+// WithNativeTransfers() and/or WithInternalTransfers().
+//
+// Transfer logs that don't conform to their respective standards, e.g. with
+// truncated data, are ignored. ERC20 and ERC721 Transfers are differentiated
+// by their number of topics, as with ParseRawLogs().
+func SummariseWalletActivity(ctx context.Context, src LogSource, wallet common.Address, from, to uint64, opts ...ActivityOption) (*WalletActivity, error) {
+	cfg := new(activityConfig)
+	for _, o := range opts {
+		o(cfg)
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range [%d, %d]", from, to)
+	}
+
+	w := &WalletActivity{
+		Wallet:    wallet,
+		FromBlock: from,
+		ToBlock:   to,
+		Assets:    make(map[Asset]*AssetActivity),
+	}
+	// involved are the positions of transactions in which the wallet
+	// transferred any asset, keyed by hash, for tracing. The logPosition index
+	// is that of the transaction, not a log.
+	involved := make(map[common.Hash]logPosition)
+
+	if err := w.scanTokenLogs(ctx, src, cfg.maxRange, involved); err != nil {
+		return nil, err
+	}
+	if cfg.native != nil {
+		if err := w.scanNativeTransfers(ctx, cfg.native, involved); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.tracer != nil {
+		if err := w.traceInternalTransfers(ctx, cfg.tracer, involved); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// scanTokenLogs records all token transfers involving w.Wallet.
+func (w *WalletActivity) scanTokenLogs(ctx context.Context, src LogSource, maxRange uint64, involved map[common.Hash]logPosition) error {
+	addr := common.BytesToHash(w.Wallet.Bytes())
+	erc1155 := []common.Hash{ERC1155TransferSingleTopic, ERC1155TransferBatchTopic}
+
+	// Topics are ANDed across positions so each position of the wallet requires
+	// its own query.
+	queries := [][][]common.Hash{
+		{{ERC721TransferTopic}, {addr}},
+		{{ERC721TransferTopic}, nil, {addr}},
+		{erc1155, nil, {addr}},
+		{erc1155, nil, nil, {addr}},
+	}
+
+	// Self-transfers match multiple queries.
+	seen := make(map[logID]bool)
+	for _, topics := range queries {
+		sub := NewSubscriber(src, ethereum.FilterQuery{Topics: topics})
+		sub.MaxBackfillRange = maxRange
+		err := sub.Range(ctx, w.FromBlock, w.ToBlock, func(l types.Log) error {
+			id := logID{tx: l.TxHash, index: l.Index}
+			if seen[id] {
+				return nil
+			}
+			seen[id] = true
+
+			if w.recordLog(&l) {
+				involved[l.TxHash] = logPosition{block: l.BlockNumber, index: l.TxIndex}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("scanning blocks [%d, %d]: %v", w.FromBlock, w.ToBlock, err)
+		}
+	}
+	return nil
+}
+
+// logID uniquely identifies a log.
+type logID struct {
+	tx    common.Hash
+	index uint
+}
+
+const erc1155ABIJSON = `[{
+	"type": "event",
+	"name": "TransferBatch",
+	"inputs": [
+		{"name": "operator", "type": "address", "indexed": true},
+		{"name": "from", "type": "address", "indexed": true},
+		{"name": "to", "type": "address", "indexed": true},
+		{"name": "ids", "type": "uint256[]", "indexed": false},
+		{"name": "values", "type": "uint256[]", "indexed": false}
+	]
+}]`
+
+var erc1155ABI = func() abi.ABI {
+	a, err := abi.JSON(strings.NewReader(erc1155ABIJSON))
+	if err != nil {
+		panic(fmt.Sprintf("abi.JSON(erc1155ABIJSON): %v", err))
+	}
+	return a
+}()
+
+// recordLog records the token transfer(s) in the log, reporting whether it
+// conformed to a supported standard.
+func (w *WalletActivity) recordLog(l *types.Log) bool {
+	topicAddr := func(i int) common.Address {
+		return common.BytesToAddress(l.Topics[i].Bytes())
+	}
+
+	if len(l.Topics) == 0 {
+		return false
+	}
+	switch t := l.Topics; {
+	case t[0] == ERC721TransferTopic && len(t) == 3 && len(l.Data) == 32:
+		w.record(Asset{Standard: ERC20Token, Contract: l.Address}, topicAddr(1), topicAddr(2), new(big.Int).SetBytes(l.Data))
+
+	case t[0] == ERC721TransferTopic && len(t) == 4 && len(l.Data) == 0:
+		w.record(Asset{Standard: ERC721Token, Contract: l.Address}, topicAddr(1), topicAddr(2), big.NewInt(1))
+
+	case t[0] == ERC1155TransferSingleTopic && len(t) == 4 && len(l.Data) == 64:
+		w.record(Asset{Standard: ERC1155Token, Contract: l.Address}, topicAddr(2), topicAddr(3), new(big.Int).SetBytes(l.Data[32:]))
+
+	case t[0] == ERC1155TransferBatchTopic && len(t) == 4:
+		vals, err := erc1155ABI.Events["TransferBatch"].Inputs.NonIndexed().Unpack(l.Data)
+		if err != nil || len(vals) != 2 {
+			return false
+		}
+		ids, okIDs := vals[0].([]*big.Int)
+		amounts, okAmounts := vals[1].([]*big.Int)
+		if !okIDs || !okAmounts || len(ids) != len(amounts) {
+			return false
+		}
+		for _, amt := range amounts {
+			w.record(Asset{Standard: ERC1155Token, Contract: l.Address}, topicAddr(2), topicAddr(3), amt)
+		}
+
+	default:
+		return false
+	}
+	return true
+}
+
+// scanNativeTransfers records the value of all successful top-level
+// transactions involving w.Wallet, and the fees of those that it sent.
+func (w *WalletActivity) scanNativeTransfers(ctx context.Context, b NativeTransferBackend, involved map[common.Hash]logPosition) error {
+	w.GasFees = new(big.Int)
+	native := Asset{Standard: NativeToken}
+
+	for num := w.FromBlock; ; num++ {
+		block, err := b.BlockByNumber(ctx, new(big.Int).SetUint64(num))
+		if err != nil {
+			return fmt.Errorf("%T.BlockByNumber(%d): %v", b, num, err)
+		}
+
+		for i, tx := range block.Transactions() {
+			from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+			if err != nil {
+				return fmt.Errorf("block %d: types.Sender(tx %v): %v", num, tx.Hash(), err)
+			}
+			var to common.Address
+			if tx.To() != nil {
+				to = *tx.To()
+			}
+			if from != w.Wallet && to != w.Wallet {
+				continue
+			}
+
+			r, err := b.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return fmt.Errorf("%T.TransactionReceipt(%v): %v", b, tx.Hash(), err)
+			}
+			if from == w.Wallet {
+				w.GasFees.Add(w.GasFees, receiptFee(r))
+			}
+			if r.Status != types.ReceiptStatusSuccessful {
+				continue
+			}
+			involved[tx.Hash()] = logPosition{block: num, index: uint(i)}
+
+			if tx.Value().Sign() == 0 {
+				continue
+			}
+			if tx.To() == nil {
+				to = r.ContractAddress
+			}
+			w.record(native, from, to, tx.Value())
+		}
+
+		if num == w.ToBlock {
+			return nil
+		}
+	}
+}
+
+// receiptFee returns the total fee paid for the transaction.
+func receiptFee(r *types.Receipt) *big.Int {
+	fee := new(big.Int)
+	if r.EffectiveGasPrice != nil {
+		fee.Mul(r.EffectiveGasPrice, new(big.Int).SetUint64(r.GasUsed))
+	}
+	if r.BlobGasPrice != nil {
+		fee.Add(fee, new(big.Int).Mul(r.BlobGasPrice, new(big.Int).SetUint64(r.BlobGasUsed)))
+	}
+	return fee
+}
+
+// traceInternalTransfers records all internal native transfers involving
+// w.Wallet in the transactions, which are traced in chain order.
+func (w *WalletActivity) traceInternalTransfers(ctx context.Context, c RPCCaller, involved map[common.Hash]logPosition) error {
+	txs := make([]common.Hash, 0, len(involved))
+	for h := range involved {
+		txs = append(txs, h)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		return involved[txs[j]].after(involved[txs[i]])
+	})
+
+	native := Asset{Standard: NativeToken}
+	for _, h := range txs {
+		root, err := TraceTransaction(ctx, c, h)
+		if err != nil {
+			return err
+		}
+		for _, t := range root.InternalTransfers() {
+			w.record(native, t.From, t.To, t.Value)
+		}
+	}
+	return nil
+}
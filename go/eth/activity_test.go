@@ -0,0 +1,393 @@
+package eth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeActivityBackend extends fakeProvenanceBackend with blocks, receipts, and
+// call traces keyed by transaction hash.
+type fakeActivityBackend struct {
+	fakeProvenanceBackend
+	blocks   map[uint64]*types.Block
+	receipts map[common.Hash]*types.Receipt
+	traces   map[common.Hash]*CallFrame
+	traced   []common.Hash
+}
+
+func (b *fakeActivityBackend) BlockByNumber(_ context.Context, num *big.Int) (*types.Block, error) {
+	if blk, ok := b.blocks[num.Uint64()]; ok {
+		return blk, nil
+	}
+	return types.NewBlockWithHeader(&types.Header{Number: num}), nil
+}
+
+func (b *fakeActivityBackend) TransactionReceipt(_ context.Context, h common.Hash) (*types.Receipt, error) {
+	if r, ok := b.receipts[h]; ok {
+		return r, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (b *fakeActivityBackend) CallContext(_ context.Context, result interface{}, method string, args ...interface{}) error {
+	if method != "debug_traceTransaction" {
+		return fmt.Errorf("unsupported method %q", method)
+	}
+	h := args[0].(common.Hash)
+	b.traced = append(b.traced, h)
+
+	f, ok := b.traces[h]
+	if !ok {
+		f = &CallFrame{Type: CallFrameCall}
+	}
+	*result.(**CallFrame) = f
+	return nil
+}
+
+func addrTopic(a common.Address) common.Hash {
+	return common.BytesToHash(a.Bytes())
+}
+
+func uint256Data(vals ...int64) []byte {
+	var buf []byte
+	for _, v := range vals {
+		buf = append(buf, common.BigToHash(big.NewInt(v)).Bytes()...)
+	}
+	return buf
+}
+
+// flows returns Flows with the respective number of transfers and amounts.
+func flows(inN int, in int64, outN int, out int64) Flows {
+	return Flows{
+		In:  Flow{Transfers: inN, Amount: big.NewInt(in)},
+		Out: Flow{Transfers: outN, Amount: big.NewInt(out)},
+	}
+}
+
+func flowsPtr(inN int, in int64, outN int, out int64) *Flows {
+	f := flows(inN, in, outN, out)
+	return &f
+}
+
+func TestSummariseWalletActivityTokens(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		wallet   = common.HexToAddress("0xAA")
+		alice    = common.HexToAddress("0xA1")
+		bob      = common.HexToAddress("0xB0")
+		carol    = common.HexToAddress("0xC0")
+		operator = common.HexToAddress("0x0F")
+		erc20    = common.HexToAddress("0x20")
+		erc721   = common.HexToAddress("0x721")
+		erc1155  = common.HexToAddress("0x1155")
+	)
+
+	batchArgs := abi.Arguments{
+		{Type: mustABIType(t, "uint256[]")},
+		{Type: mustABIType(t, "uint256[]")},
+	}
+	batchData, err := batchArgs.Pack([]*big.Int{big.NewInt(1), big.NewInt(2)}, []*big.Int{big.NewInt(3), big.NewInt(4)})
+	if err != nil {
+		t.Fatalf("%T.Pack(TransferBatch data) error %v", batchArgs, err)
+	}
+
+	tx := func(n byte) common.Hash { return common.Hash{n} }
+	b := &fakeActivityBackend{
+		fakeProvenanceBackend: fakeProvenanceBackend{
+			head: 10,
+			logs: []*types.Log{
+				{
+					BlockNumber: 1, TxHash: tx(1), Index: 0,
+					Address: erc20,
+					Topics:  []common.Hash{ERC721TransferTopic, addrTopic(alice), addrTopic(wallet)},
+					Data:    uint256Data(100),
+				},
+				{
+					BlockNumber: 2, TxHash: tx(2), Index: 1,
+					Address: erc20,
+					Topics:  []common.Hash{ERC721TransferTopic, addrTopic(wallet), addrTopic(bob)},
+					Data:    uint256Data(30),
+				},
+				{
+					BlockNumber: 2, TxHash: tx(2), Index: 2,
+					Address: erc20,
+					Topics:  []common.Hash{ERC721TransferTopic, addrTopic(wallet), addrTopic(wallet)},
+					Data:    uint256Data(5),
+				},
+				{
+					BlockNumber: 3, TxHash: tx(3), Index: 3,
+					Address: erc721,
+					Topics:  []common.Hash{ERC721TransferTopic, {}, addrTopic(wallet), common.BigToHash(big.NewInt(7))},
+				},
+				{
+					// Not involving the wallet
+					BlockNumber: 3, TxHash: tx(3), Index: 4,
+					Address: erc20,
+					Topics:  []common.Hash{ERC721TransferTopic, addrTopic(alice), addrTopic(bob)},
+					Data:    uint256Data(1000),
+				},
+				{
+					// Malformed
+					BlockNumber: 3, TxHash: tx(3), Index: 5,
+					Address: erc20,
+					Topics:  []common.Hash{ERC721TransferTopic, addrTopic(alice), addrTopic(wallet)},
+					Data:    []byte{1},
+				},
+				{
+					BlockNumber: 4, TxHash: tx(4), Index: 6,
+					Address: erc1155,
+					Topics:  []common.Hash{ERC1155TransferSingleTopic, addrTopic(operator), addrTopic(alice), addrTopic(wallet)},
+					Data:    uint256Data(1, 10),
+				},
+				{
+					BlockNumber: 4, TxHash: tx(4), Index: 7,
+					Address: erc1155,
+					Topics:  []common.Hash{ERC1155TransferBatchTopic, addrTopic(operator), addrTopic(wallet), addrTopic(carol)},
+					Data:    batchData,
+				},
+				{
+					// Outside of block range
+					BlockNumber: 5, TxHash: tx(5), Index: 8,
+					Address: erc721,
+					Topics:  []common.Hash{ERC721TransferTopic, addrTopic(wallet), addrTopic(carol), common.BigToHash(big.NewInt(7))},
+				},
+			},
+		},
+		traces: map[common.Hash]*CallFrame{
+			tx(2): {
+				Type: CallFrameCall,
+				Calls: []*CallFrame{
+					{Type: CallFrameCall, From: bob, To: wallet, Value: big.NewInt(42)},
+				},
+			},
+		},
+	}
+
+	got, err := SummariseWalletActivity(ctx, b, wallet, 1, 4, WithActivityMaxBlockRange(2), WithInternalTransfers(b))
+	if err != nil {
+		t.Fatalf("SummariseWalletActivity(…) error %v", err)
+	}
+
+	want := &WalletActivity{
+		Wallet:    wallet,
+		FromBlock: 1,
+		ToBlock:   4,
+		Assets: map[Asset]*AssetActivity{
+			{Standard: NativeToken}: {
+				Asset: Asset{Standard: NativeToken},
+				Flows: flows(1, 42, 0, 0),
+				Counterparties: map[common.Address]*Flows{
+					bob: flowsPtr(1, 42, 0, 0),
+				},
+			},
+			{Standard: ERC20Token, Contract: erc20}: {
+				Asset: Asset{Standard: ERC20Token, Contract: erc20},
+				Flows: flows(2, 105, 2, 35),
+				Counterparties: map[common.Address]*Flows{
+					alice:  flowsPtr(1, 100, 0, 0),
+					bob:    flowsPtr(0, 0, 1, 30),
+					wallet: flowsPtr(1, 5, 1, 5),
+				},
+			},
+			{Standard: ERC721Token, Contract: erc721}: {
+				Asset: Asset{Standard: ERC721Token, Contract: erc721},
+				Flows: flows(1, 1, 0, 0),
+				Counterparties: map[common.Address]*Flows{
+					{}: flowsPtr(1, 1, 0, 0),
+				},
+			},
+			{Standard: ERC1155Token, Contract: erc1155}: {
+				Asset: Asset{Standard: ERC1155Token, Contract: erc1155},
+				Flows: flows(1, 10, 2, 7),
+				Counterparties: map[common.Address]*Flows{
+					alice: flowsPtr(1, 10, 0, 0),
+					carol: flowsPtr(0, 0, 2, 7),
+				},
+			},
+		},
+	}
+
+	bigCmp := cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })
+	if diff := cmp.Diff(want, got, bigCmp); diff != "" {
+		t.Errorf("SummariseWalletActivity(…) diff (-want +got):\n%s", diff)
+	}
+
+	t.Run("traced", func(t *testing.T) {
+		// Transactions are traced in chain order, and only once even if they
+		// have multiple transfers.
+		want := []common.Hash{tx(1), tx(2), tx(3), tx(4)}
+		if diff := cmp.Diff(want, b.traced); diff != "" {
+			t.Errorf("traced transactions diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("SortedAssets", func(t *testing.T) {
+		var gotOrder []Asset
+		for _, a := range got.SortedAssets() {
+			gotOrder = append(gotOrder, a.Asset)
+		}
+		wantOrder := []Asset{
+			{Standard: NativeToken},
+			{Standard: ERC20Token, Contract: erc20},
+			{Standard: ERC721Token, Contract: erc721},
+			{Standard: ERC1155Token, Contract: erc1155},
+		}
+		if diff := cmp.Diff(wantOrder, gotOrder); diff != "" {
+			t.Errorf("%T.SortedAssets() diff (-want +got):\n%s", got, diff)
+		}
+	})
+
+	t.Run("Net", func(t *testing.T) {
+		a := got.Assets[Asset{Standard: ERC20Token, Contract: erc20}]
+		if got, want := a.Net(), big.NewInt(70); got.Cmp(want) != 0 {
+			t.Errorf("%T.Net() got %d; want %d", a, got, want)
+		}
+	})
+}
+
+func mustABIType(t *testing.T, typ string) abi.Type {
+	t.Helper()
+	ty, err := abi.NewType(typ, "", nil)
+	if err != nil {
+		t.Fatalf("abi.NewType(%q) error %v", typ, err)
+	}
+	return ty
+}
+
+func TestSummariseWalletActivityNative(t *testing.T) {
+	ctx := context.Background()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := range keys {
+		k, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("crypto.GenerateKey() error %v", err)
+		}
+		keys[i] = k
+	}
+	walletKey, aliceKey, bobKey := keys[0], keys[1], keys[2]
+	var (
+		wallet   = crypto.PubkeyToAddress(walletKey.PublicKey)
+		alice    = crypto.PubkeyToAddress(aliceKey.PublicKey)
+		bob      = crypto.PubkeyToAddress(bobKey.PublicKey)
+		contract = common.HexToAddress("0xC0")
+		deployed = common.HexToAddress("0xDE")
+	)
+
+	chainID := big.NewInt(1)
+	signer := types.LatestSignerForChainID(chainID)
+	nonce := uint64(0)
+	sign := func(key *ecdsa.PrivateKey, to *common.Address, value int64) *types.Transaction {
+		t.Helper()
+		nonce++
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			To:        to,
+			Value:     big.NewInt(value),
+			Gas:       21000,
+			GasFeeCap: big.NewInt(10),
+		})
+		if err != nil {
+			t.Fatalf("types.SignNewTx() error %v", err)
+		}
+		return tx
+	}
+
+	var (
+		sendToAlice  = sign(walletKey, &alice, 1000)
+		fromBob      = sign(bobKey, &wallet, 2000)
+		failed       = sign(walletKey, &contract, 3000)
+		unrelated    = sign(aliceKey, &bob, 4000)
+		callContract = sign(walletKey, &contract, 0)
+		deploy       = sign(walletKey, nil, 500)
+	)
+
+	blocks := map[uint64][]*types.Transaction{
+		1: {sendToAlice},
+		2: {fromBob, failed},
+		3: {unrelated, callContract},
+		4: {deploy},
+	}
+	b := &fakeActivityBackend{
+		fakeProvenanceBackend: fakeProvenanceBackend{head: 10},
+		blocks:                make(map[uint64]*types.Block),
+		receipts:              make(map[common.Hash]*types.Receipt),
+		traces: map[common.Hash]*CallFrame{
+			callContract.Hash(): {
+				Type: CallFrameCall,
+				Calls: []*CallFrame{
+					{Type: CallFrameCall, From: contract, To: wallet, Value: big.NewInt(50)},
+					{Type: CallFrameCall, From: contract, To: bob, Value: big.NewInt(60)},
+				},
+			},
+		},
+	}
+	for num, txs := range blocks {
+		b.blocks[num] = types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(num)}).WithBody(txs, nil)
+		for _, tx := range txs {
+			b.receipts[tx.Hash()] = &types.Receipt{
+				Status:            types.ReceiptStatusSuccessful,
+				GasUsed:           21000,
+				EffectiveGasPrice: big.NewInt(10),
+			}
+		}
+	}
+	b.receipts[failed.Hash()].Status = types.ReceiptStatusFailed
+	b.receipts[deploy.Hash()].ContractAddress = deployed
+	b.receipts[deploy.Hash()].BlobGasUsed = 100
+	b.receipts[deploy.Hash()].BlobGasPrice = big.NewInt(1)
+
+	got, err := SummariseWalletActivity(ctx, b, wallet, 1, 4, WithNativeTransfers(b), WithInternalTransfers(b))
+	if err != nil {
+		t.Fatalf("SummariseWalletActivity(…) error %v", err)
+	}
+
+	native := Asset{Standard: NativeToken}
+	want := &WalletActivity{
+		Wallet:    wallet,
+		FromBlock: 1,
+		ToBlock:   4,
+		Assets: map[Asset]*AssetActivity{
+			native: {
+				Asset: native,
+				Flows: flows(2, 2050, 2, 1500),
+				Counterparties: map[common.Address]*Flows{
+					alice:    flowsPtr(0, 0, 1, 1000),
+					bob:      flowsPtr(1, 2000, 0, 0),
+					contract: flowsPtr(1, 50, 0, 0),
+					deployed: flowsPtr(0, 0, 1, 500),
+				},
+			},
+		},
+		// 4 transactions sent by the wallet, including the failed one, plus
+		// blob gas.
+		GasFees: big.NewInt(4*21000*10 + 100),
+	}
+
+	bigCmp := cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })
+	if diff := cmp.Diff(want, got, bigCmp); diff != "" {
+		t.Errorf("SummariseWalletActivity(…) diff (-want +got):\n%s", diff)
+	}
+
+	wantTraced := []common.Hash{sendToAlice.Hash(), fromBob.Hash(), callContract.Hash(), deploy.Hash()}
+	if diff := cmp.Diff(wantTraced, b.traced); diff != "" {
+		t.Errorf("traced transactions diff (-want +got):\n%s", diff)
+	}
+}
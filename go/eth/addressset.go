@@ -0,0 +1,180 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// An AddressSet is an immutable set of addresses, optimised for checking
+// membership of many candidates of which most are absent; e.g. filtering all
+// transfers to a set of known users.
+//
+// Membership is first tested against a bloom filter, with a false-positive rate
+// of at most ~0.1%, and only then confirmed with a binary search so Contains()
+// is exact. Neither step allocates, and the set uses fewer than 25 bytes per
+// address.
+type AddressSet struct {
+	sorted []common.Address
+	bloom  []uint64
+	mask   uint64 // len(bloom)*64 - 1
+}
+
+const (
+	// addressSetBloomBits is the minimum number of bits in the bloom filter
+	// per address, rounded up to the next power of 2 in total.
+	addressSetBloomBits = 16
+	// addressSetBloomHashes is the number of bits set per address.
+	addressSetBloomHashes = 6
+)
+
+// NewAddressSet returns an AddressSet containing the addresses. Duplicates are
+// ignored.
+func NewAddressSet(addrs []common.Address) *AddressSet {
+	sorted := make([]common.Address, len(addrs))
+	copy(sorted, addrs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	// Deduplicate in place.
+	n := 0
+	for i, a := range sorted {
+		if i > 0 && a == sorted[n-1] {
+			continue
+		}
+		sorted[n] = a
+		n++
+	}
+	sorted = sorted[:n:n]
+
+	words := uint64(1)
+	for words*64 < uint64(n)*addressSetBloomBits {
+		words <<= 1
+	}
+	s := &AddressSet{
+		sorted: sorted,
+		bloom:  make([]uint64, words),
+		mask:   words*64 - 1,
+	}
+	for _, a := range sorted {
+		h1, h2 := addressSetHashes(a[:])
+		for i := uint64(0); i < addressSetBloomHashes; i++ {
+			bit := (h1 + i*h2) & s.mask
+			s.bloom[bit/64] |= 1 << (bit % 64)
+		}
+	}
+	return s
+}
+
+// addressSetHashes returns two independent hashes of the address for use in
+// the bloom filter. Addresses are typically uniformly distributed, but vanity
+// and precompile addresses aren't, so the bytes are still mixed.
+func addressSetHashes(b []byte) (uint64, uint64) {
+	x := binary.LittleEndian.Uint64(b[:8])
+	y := binary.LittleEndian.Uint64(b[8:16])
+	z := uint64(binary.LittleEndian.Uint32(b[16:20]))
+	h1 := splitMix64(x ^ splitMix64(z))
+	h2 := splitMix64(y^splitMix64(h1)) | 1 // odd so all bits are reachable
+	return h1, h2
+}
+
+// splitMix64 is the finalizer of the SplitMix64 PRNG.
+func splitMix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Len returns the number of distinct addresses in the set.
+func (s *AddressSet) Len() int {
+	return len(s.sorted)
+}
+
+// Contains returns whether the address represented by b is in the set. It reads
+// b in place, without copying, and b is typically a slice of a larger buffer;
+// e.g. a log topic or a protobuf field. If len(b) != common.AddressLength,
+// Contains returns false.
+func (s *AddressSet) Contains(b []byte) bool {
+	if len(b) != common.AddressLength || len(s.sorted) == 0 {
+		return false
+	}
+
+	h1, h2 := addressSetHashes(b)
+	for i := uint64(0); i < addressSetBloomHashes; i++ {
+		bit := (h1 + i*h2) & s.mask
+		if s.bloom[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	i := sort.Search(len(s.sorted), func(i int) bool {
+		return bytes.Compare(s.sorted[i][:], b) >= 0
+	})
+	return i < len(s.sorted) && bytes.Equal(s.sorted[i][:], b)
+}
+
+// ContainsAddress is equivalent to Contains(a.Bytes()).
+func (s *AddressSet) ContainsAddress(a common.Address) bool {
+	return s.Contains(a[:])
+}
+
+// Addresses returns the addresses in the set, sorted in ascending order. The
+// returned slice MUST NOT be modified.
+func (s *AddressSet) Addresses() []common.Address {
+	return s.sorted
+}
+
+// AddressSetFromCSV reads a CSV with a header row from r and returns an
+// AddressSet of the values in the named column. Empty values are skipped, and
+// other columns are ignored, so the CSV can be, for example, a database export.
+func AddressSetFromCSV(r io.Reader, column string) (*AddressSet, error) {
+	c := csv.NewReader(r)
+	c.ReuseRecord = true
+
+	header, err := c.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %v", err)
+	}
+	col := -1
+	for i, h := range header {
+		if strings.TrimSpace(h) == column {
+			col = i
+			break
+		}
+	}
+	if col == -1 {
+		return nil, fmt.Errorf("CSV header %q missing column %q", header, column)
+	}
+
+	var addrs []common.Address
+	for {
+		rec, err := c.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %v", err)
+		}
+
+		raw := strings.TrimSpace(rec[col])
+		if raw == "" {
+			continue
+		}
+		if !common.IsHexAddress(raw) {
+			line, _ := c.FieldPos(col)
+			return nil, fmt.Errorf("invalid address %q on line %d", raw, line)
+		}
+		addrs = append(addrs, common.HexToAddress(raw))
+	}
+	return NewAddressSet(addrs), nil
+}
@@ -0,0 +1,146 @@
+package eth_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestAddressSet(t *testing.T) {
+	const n = 10_000
+	var in []common.Address
+	for i := int64(0); i < n; i++ {
+		// Multiples of 3, including small values that aren't uniformly
+		// distributed like typical addresses.
+		in = append(in, common.BigToAddress(big.NewInt(3*i)))
+	}
+	in = append(in, in[:10]...) // duplicates
+
+	s := NewAddressSet(in)
+	if got := s.Len(); got != n {
+		t.Errorf("NewAddressSet([%d addresses, %d distinct]).Len() got %d; want %d", len(in), n, got, n)
+	}
+
+	for i := int64(0); i < 3*n; i++ {
+		a := common.BigToAddress(big.NewInt(i))
+		want := i%3 == 0
+		if got := s.Contains(a.Bytes()); got != want {
+			t.Errorf("%T.Contains(%v) got %t; want %t", s, a, got, want)
+		}
+		if got := s.ContainsAddress(a); got != want {
+			t.Errorf("%T.ContainsAddress(%v) got %t; want %t", s, a, got, want)
+		}
+	}
+
+	for _, b := range [][]byte{nil, make([]byte, 19), make([]byte, 32)} {
+		if s.Contains(b) {
+			t.Errorf("%T.Contains([%d bytes]) got true; want false", s, len(b))
+		}
+	}
+
+	if NewAddressSet(nil).Contains(make([]byte, common.AddressLength)) {
+		t.Errorf("NewAddressSet(nil).Contains(…) got true; want false")
+	}
+
+	t.Run("allocations", func(t *testing.T) {
+		// The address is part of a larger buffer, as with a log topic.
+		topic := common.BigToHash(big.NewInt(3 * n / 2))
+		hit := topic[common.HashLength-common.AddressLength:]
+		miss := common.HexToAddress("0xdeadbeef").Bytes()
+
+		allocs := testing.AllocsPerRun(100, func() {
+			if !s.Contains(hit) || s.Contains(miss) {
+				t.Fatal("bad test setup")
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("%T.Contains() allocated %v times per run; want 0", s, allocs)
+		}
+	})
+}
+
+func TestAddressSetFromCSV(t *testing.T) {
+	alice := common.HexToAddress("0xa11ce")
+	bob := common.HexToAddress("0xb0b")
+
+	tests := []struct {
+		name    string
+		csv     string
+		column  string
+		want    []common.Address
+		wantErr bool
+	}{
+		{
+			name:   "single column",
+			csv:    "address\n" + bob.Hex() + "\n" + alice.Hex() + "\n" + bob.Hex() + "\n",
+			column: "address",
+			want:   []common.Address{bob, alice}, // sorted
+		},
+		{
+			name:   "other columns and empty values",
+			csv:    "id,user\n1," + bob.Hex() + "\n2,\n3, " + strings.ToLower(alice.Hex()) + "\n",
+			column: "user",
+			want:   []common.Address{bob, alice}, // sorted
+		},
+		{
+			name:    "missing column",
+			csv:     "id,user\n1," + bob.Hex() + "\n",
+			column:  "address",
+			wantErr: true,
+		},
+		{
+			name:    "invalid address",
+			csv:     "address\n0xnope\n",
+			column:  "address",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			column:  "address",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := AddressSetFromCSV(strings.NewReader(tt.csv), tt.column)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("AddressSetFromCSV(%q, %q) got err %v; want err? %t", tt.csv, tt.column, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tt.want, s.Addresses()); diff != "" {
+				t.Errorf("AddressSetFromCSV(%q, %q).Addresses() diff (-want +got):\n%s", tt.csv, tt.column, diff)
+			}
+		})
+	}
+}
+
+func BenchmarkAddressSetContains(b *testing.B) {
+	addrs := make([]common.Address, 1_000_000)
+	for i := range addrs {
+		addrs[i] = common.BigToAddress(big.NewInt(int64(2 * i)))
+	}
+	s := NewAddressSet(addrs)
+
+	// Predominantly misses, as when filtering all transfers.
+	candidates := make([][]byte, 1024)
+	for i := range candidates {
+		candidates[i] = common.BigToAddress(big.NewInt(int64(2*i + 1))).Bytes()
+	}
+	candidates[0] = addrs[42].Bytes()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Contains(candidates[i%len(candidates)])
+	}
+}
@@ -0,0 +1,243 @@
+package eth
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/holiman/uint256"
+)
+
+// An Amount is a token quantity, coupling its raw on-chain Value with the
+// number of Decimals used to represent it to humans; e.g. 1.5 ETH is a Value of
+// 1.5e18 with 18 Decimals. Amounts allow precise parsing, formatting, and
+// arithmetic without resorting to floating-point numbers.
+//
+// Amounts are marshalled to JSON and CSV as decimal strings with exactly
+// Decimals fractional digits (e.g. "1.500000" for 1.5 USDC), which allows the
+// Decimals to be recovered when unmarshalling.
+type Amount struct {
+	Value    uint256.Int
+	Decimals uint8
+}
+
+// maxDecimals is the number of digits in the largest uint256 value, beyond
+// which all Amounts are fractional.
+const maxDecimals = 78
+
+// NewAmount returns an Amount with the value and decimals.
+func NewAmount(value *uint256.Int, decimals uint8) Amount {
+	return Amount{Value: *value, Decimals: decimals}
+}
+
+// EtherAmount returns wei as an Amount with 18 decimals.
+func EtherAmount(wei *uint256.Int) Amount {
+	return NewAmount(wei, 18)
+}
+
+// ParseAmount parses a non-negative, human-readable decimal string (e.g. "1.5")
+// as an Amount with the specified decimals. It returns an error if s has more
+// significant fractional digits than decimals allows, or if the value overflows
+// a uint256.
+func ParseAmount(s string, decimals uint8) (Amount, error) {
+	if decimals > maxDecimals {
+		return Amount{}, fmt.Errorf("%d decimals exceeds maximum of %d", decimals, maxDecimals)
+	}
+
+	whole, frac, hasPoint := strings.Cut(s, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(frac)) {
+		return Amount{}, fmt.Errorf("invalid amount %q; must be non-negative decimal number", s)
+	}
+
+	if extra := len(frac) - int(decimals); extra > 0 {
+		if strings.TrimRight(frac[decimals:], "0") != "" {
+			return Amount{}, fmt.Errorf("amount %q has more than %d decimals", s, decimals)
+		}
+		frac = frac[:decimals]
+	}
+	frac += strings.Repeat("0", int(decimals)-len(frac))
+
+	digits := strings.TrimLeft(whole+frac, "0")
+	if digits == "" {
+		digits = "0"
+	}
+	v, err := uint256.FromDecimal(digits)
+	if err != nil {
+		return Amount{}, fmt.Errorf("amount %q with %d decimals: %v", s, decimals, err)
+	}
+	return NewAmount(v, decimals), nil
+}
+
+// MustParseAmount is equivalent to ParseAmount(), but panics on error. It is
+// intended for constants.
+func MustParseAmount(s string, decimals uint8) Amount {
+	a, err := ParseAmount(s, decimals)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// isDigits reports whether s is non-empty and only contains ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Big returns the Value as a *big.Int, as used by abigen bindings.
+func (a Amount) Big() *big.Int {
+	return a.Value.ToBig()
+}
+
+// IsZero reports whether the Value is zero.
+func (a Amount) IsZero() bool {
+	return a.Value.IsZero()
+}
+
+// String returns the Amount as a decimal string at full precision, without
+// trailing fractional zeros; e.g. "1.5" or "42".
+func (a Amount) String() string {
+	s := a.fixed()
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// Format returns the Amount as a decimal string with exactly precision
+// fractional digits. Excess digits are truncated, not rounded, so the returned
+// value never overstates the Amount.
+func (a Amount) Format(precision uint8) string {
+	s := a.fixed()
+	if precision >= a.Decimals {
+		if a.Decimals == 0 && precision > 0 {
+			s += "."
+		}
+		return s + strings.Repeat("0", int(precision-a.Decimals))
+	}
+	s = s[:len(s)-int(a.Decimals-precision)]
+	return strings.TrimSuffix(s, ".")
+}
+
+// fixed returns the Amount as a decimal string with exactly a.Decimals
+// fractional digits.
+func (a Amount) fixed() string {
+	digits := a.Value.Dec()
+	if a.Decimals == 0 {
+		return digits
+	}
+
+	d := int(a.Decimals)
+	if len(digits) <= d {
+		digits = strings.Repeat("0", d-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-d] + "." + digits[len(digits)-d:]
+}
+
+// checkDecimals returns an error if a and b have different Decimals.
+func checkDecimals(op string, a, b Amount) error {
+	if a.Decimals != b.Decimals {
+		return fmt.Errorf("%s of amounts with %d and %d decimals", op, a.Decimals, b.Decimals)
+	}
+	return nil
+}
+
+// Add returns a+b, which MUST have the same Decimals. It returns an error if
+// they don't or if the sum overflows.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if err := checkDecimals("addition", a, b); err != nil {
+		return Amount{}, err
+	}
+	var sum uint256.Int
+	if _, overflow := sum.AddOverflow(&a.Value, &b.Value); overflow {
+		return Amount{}, fmt.Errorf("%v + %v overflows uint256", a, b)
+	}
+	return NewAmount(&sum, a.Decimals), nil
+}
+
+// Sub returns a-b, which MUST have the same Decimals. It returns an error if
+// they don't or if b > a.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if err := checkDecimals("subtraction", a, b); err != nil {
+		return Amount{}, err
+	}
+	var diff uint256.Int
+	if _, underflow := diff.SubOverflow(&a.Value, &b.Value); underflow {
+		return Amount{}, fmt.Errorf("%v - %v underflows uint256", a, b)
+	}
+	return NewAmount(&diff, a.Decimals), nil
+}
+
+// MulDiv returns a*num/den, rounded down, without overflow of the intermediate
+// product; e.g. MulDiv(1, 3) returns a third of the Amount, as used when
+// splitting an airdrop. It returns an error if den is zero or the result
+// overflows.
+func (a Amount) MulDiv(num, den uint64) (Amount, error) {
+	if den == 0 {
+		return Amount{}, fmt.Errorf("%v.MulDiv(%d, 0): division by zero", a, num)
+	}
+	var res uint256.Int
+	if _, overflow := res.MulDivOverflow(&a.Value, uint256.NewInt(num), uint256.NewInt(den)); overflow {
+		return Amount{}, fmt.Errorf("%v.MulDiv(%d, %d) overflows uint256", a, num, den)
+	}
+	return NewAmount(&res, a.Decimals), nil
+}
+
+// Cmp compares a and b, which MUST have the same Decimals, returning -1 if
+// a < b, 0 if a == b, and +1 if a > b. It returns an error if the Decimals
+// differ.
+func (a Amount) Cmp(b Amount) (int, error) {
+	if err := checkDecimals("comparison", a, b); err != nil {
+		return 0, err
+	}
+	return a.Value.Cmp(&b.Value), nil
+}
+
+// MarshalJSON marshals the Amount as a string with exactly Decimals fractional
+// digits.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.fixed())
+}
+
+// MarshalCSV marshals the Amount as a string with exactly Decimals fractional
+// digits.
+func (a Amount) MarshalCSV() (string, error) {
+	return a.fixed(), nil
+}
+
+// UnmarshalJSON unmarshals an Amount from a string, inferring Decimals from the
+// number of fractional digits.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s, err := unmarshalJSONToString(data)
+	if err != nil {
+		return err
+	}
+	return a.UnmarshalCSV(s)
+}
+
+// UnmarshalCSV unmarshals an Amount from a string, inferring Decimals from the
+// number of fractional digits.
+func (a *Amount) UnmarshalCSV(s string) error {
+	var decimals int
+	if _, frac, ok := strings.Cut(s, "."); ok {
+		decimals = len(frac)
+	}
+	if decimals > maxDecimals {
+		return fmt.Errorf("amount %q has %d decimals; maximum %d", s, decimals, maxDecimals)
+	}
+
+	parsed, err := ParseAmount(s, uint8(decimals))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
@@ -0,0 +1,267 @@
+package eth_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gocarina/gocsv"
+	"github.com/google/go-cmp/cmp"
+	"github.com/holiman/uint256"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestParseAndFormatAmount(t *testing.T) {
+	tests := []struct {
+		in         string
+		decimals   uint8
+		wantValue  string
+		wantString string
+		wantFormat map[uint8]string
+	}{
+		{
+			in:         "1.5",
+			decimals:   18,
+			wantValue:  "1500000000000000000",
+			wantString: "1.5",
+			wantFormat: map[uint8]string{
+				0:  "1",
+				2:  "1.50",
+				18: "1.500000000000000000",
+				20: "1.50000000000000000000",
+			},
+		},
+		{
+			in:         "0.000001",
+			decimals:   6,
+			wantValue:  "1",
+			wantString: "0.000001",
+			wantFormat: map[uint8]string{
+				0: "0",
+				4: "0.0000",
+				6: "0.000001",
+			},
+		},
+		{
+			in:         "42",
+			decimals:   0,
+			wantValue:  "42",
+			wantString: "42",
+			wantFormat: map[uint8]string{
+				0: "42",
+				2: "42.00",
+			},
+		},
+		{
+			in:         "42.000",
+			decimals:   0,
+			wantValue:  "42",
+			wantString: "42",
+		},
+		{
+			in:         "0",
+			decimals:   18,
+			wantValue:  "0",
+			wantString: "0",
+			wantFormat: map[uint8]string{
+				3: "0.000",
+			},
+		},
+		{
+			in:         "0012.3400",
+			decimals:   4,
+			wantValue:  "123400",
+			wantString: "12.34",
+			wantFormat: map[uint8]string{
+				1: "12.3",
+			},
+		},
+		{
+			in:         "1.999",
+			decimals:   3,
+			wantValue:  "1999",
+			wantString: "1.999",
+			wantFormat: map[uint8]string{
+				// Truncated, not rounded.
+				2: "1.99",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			a, err := ParseAmount(tt.in, tt.decimals)
+			if err != nil {
+				t.Fatalf("ParseAmount(%q, %d) error %v", tt.in, tt.decimals, err)
+			}
+			if got := a.Value.Dec(); got != tt.wantValue {
+				t.Errorf("ParseAmount(%q, %d).Value got %s; want %s", tt.in, tt.decimals, got, tt.wantValue)
+			}
+			if got := a.String(); got != tt.wantString {
+				t.Errorf("ParseAmount(%q, %d).String() got %q; want %q", tt.in, tt.decimals, got, tt.wantString)
+			}
+			for prec, want := range tt.wantFormat {
+				if got := a.Format(prec); got != want {
+					t.Errorf("ParseAmount(%q, %d).Format(%d) got %q; want %q", tt.in, tt.decimals, prec, got, want)
+				}
+			}
+		})
+	}
+
+	for _, in := range []string{
+		"",
+		".5",
+		"1.",
+		"-1",
+		"+1",
+		"1e18",
+		"1,5",
+		"1.5.0",
+		"0.0000001", // 7 decimals
+		"115792089237316195423570985008687907853269984665640564039457584007913129639936", // 2^256
+	} {
+		if _, err := ParseAmount(in, 6); err == nil {
+			t.Errorf("ParseAmount(%q, 6) got nil error; want non-nil", in)
+		}
+	}
+	if _, err := ParseAmount("1", 79); err == nil {
+		t.Errorf("ParseAmount(1, 79) got nil error; want non-nil")
+	}
+}
+
+func TestAmountArithmetic(t *testing.T) {
+	one := MustParseAmount("1", 6)
+	half := MustParseAmount("0.5", 6)
+	max := NewAmount(new(uint256.Int).SetAllOne(), 6)
+
+	tests := []struct {
+		name    string
+		fn      func() (Amount, error)
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "1 + 0.5",
+			fn:   func() (Amount, error) { return one.Add(half) },
+			want: "1.5",
+		},
+		{
+			name: "1 - 0.5",
+			fn:   func() (Amount, error) { return one.Sub(half) },
+			want: "0.5",
+		},
+		{
+			name: "1 * 2/3",
+			fn:   func() (Amount, error) { return one.MulDiv(2, 3) },
+			want: "0.666666",
+		},
+		{
+			name: "max * 2/2",
+			fn:   func() (Amount, error) { return max.MulDiv(2, 2) },
+			want: max.String(),
+		},
+		{
+			name:    "max + 0.5",
+			fn:      func() (Amount, error) { return max.Add(half) },
+			wantErr: true,
+		},
+		{
+			name:    "0.5 - 1",
+			fn:      func() (Amount, error) { return half.Sub(one) },
+			wantErr: true,
+		},
+		{
+			name:    "max * 2/1",
+			fn:      func() (Amount, error) { return max.MulDiv(2, 1) },
+			wantErr: true,
+		},
+		{
+			name:    "division by zero",
+			fn:      func() (Amount, error) { return one.MulDiv(1, 0) },
+			wantErr: true,
+		},
+		{
+			name:    "mismatched decimals",
+			fn:      func() (Amount, error) { return one.Add(MustParseAmount("1", 18)) },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v; want error: %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %v; want %s", got, tt.want)
+			}
+		})
+	}
+
+	if c, err := half.Cmp(one); err != nil || c != -1 {
+		t.Errorf("%v.Cmp(%v) got %d, err = %v; want -1, nil err", half, one, c, err)
+	}
+	if _, err := half.Cmp(MustParseAmount("1", 18)); err == nil {
+		t.Errorf("Cmp() of amounts with different decimals got nil error; want non-nil")
+	}
+}
+
+func TestAmountMarshalling(t *testing.T) {
+	type holder struct {
+		Balance Amount `json:"balance" csv:"balance"`
+	}
+	in := []*holder{
+		{MustParseAmount("1.5", 6)},
+		{MustParseAmount("42", 0)},
+		{MustParseAmount("0.000000000000000001", 18)},
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			t.Fatalf("json.Marshal(%+v) error %v", in, err)
+		}
+		const want = `[{"balance":"1.500000"},{"balance":"42"},{"balance":"0.000000000000000001"}]`
+		if got := string(buf); got != want {
+			t.Errorf("json.Marshal(%+v) got %s; want %s", in, got, want)
+		}
+
+		var got []*holder
+		if err := json.Unmarshal(buf, &got); err != nil {
+			t.Fatalf("json.Unmarshal(%s) error %v", buf, err)
+		}
+		if diff := cmp.Diff(in, got); diff != "" {
+			t.Errorf("JSON round trip diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		out, err := gocsv.MarshalString(in)
+		if err != nil {
+			t.Fatalf("gocsv.MarshalString(%+v) error %v", in, err)
+		}
+		const want = "balance\n1.500000\n42\n0.000000000000000001\n"
+		if out != want {
+			t.Errorf("gocsv.MarshalString(%+v) got %q; want %q", in, out, want)
+		}
+
+		var got []*holder
+		if err := gocsv.UnmarshalString(out, &got); err != nil {
+			t.Fatalf("gocsv.UnmarshalString(%q) error %v", out, err)
+		}
+		if diff := cmp.Diff(in, got); diff != "" {
+			t.Errorf("CSV round trip diff (-want +got):\n%s", diff)
+		}
+	})
+
+	if err := json.Unmarshal([]byte(`"1.5.0"`), new(Amount)); err == nil {
+		t.Errorf("json.Unmarshal(%q) got nil error; want non-nil", "1.5.0")
+	}
+}
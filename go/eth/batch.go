@@ -0,0 +1,195 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DefaultMaxBatchSize is the default value of BatchClient.MaxBatchSize. Most
+// providers reject batches of more than 100 to 1000 requests.
+const DefaultMaxBatchSize = 100
+
+// A BatchClient is an ethclient.Client that can also send multiple requests
+// in a single JSON-RPC batch, which is a single round trip when connected
+// over HTTP. The Batch*() methods each return values in the same order as
+// their inputs.
+type BatchClient struct {
+	*ethclient.Client
+	rpc *rpc.Client
+
+	// MaxBatchSize is the maximum number of requests sent in each batch;
+	// larger inputs are split into multiple, sequential batches. If zero,
+	// DefaultMaxBatchSize is used.
+	MaxBatchSize int
+}
+
+// NewBatchClient returns a BatchClient that uses the rpc.Client.
+func NewBatchClient(c *rpc.Client) *BatchClient {
+	return &BatchClient{
+		Client: ethclient.NewClient(c),
+		rpc:    c,
+	}
+}
+
+// DialBatch is equivalent to Dial() except that it returns a BatchClient.
+func (c *Dialer) DialBatch(ctx context.Context) (*BatchClient, error) {
+	url, err := c.url(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return NewBatchClient(rc), nil
+}
+
+func (c *BatchClient) maxBatchSize() int {
+	if c.MaxBatchSize <= 0 {
+		return DefaultMaxBatchSize
+	}
+	return c.MaxBatchSize
+}
+
+// batch sends the elements in batches of at most c.MaxBatchSize, returning the
+// first error, be it of a batch or an individual element.
+func (c *BatchClient) batch(ctx context.Context, elems []rpc.BatchElem) error {
+	for start := 0; start < len(elems); start += c.maxBatchSize() {
+		end := start + c.maxBatchSize()
+		if end > len(elems) {
+			end = len(elems)
+		}
+		if err := c.rpc.BatchCallContext(ctx, elems[start:end]); err != nil {
+			return fmt.Errorf("%T.BatchCallContext(…) of elements [%d,%d): %v", c.rpc, start, end, err)
+		}
+		for i, e := range elems[start:end] {
+			if e.Error != nil {
+				return fmt.Errorf("%s batch element %d: %w", e.Method, start+i, e.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// blockNumArg is equivalent to the unexported function of the same purpose in
+// the ethclient package.
+func blockNumArg(n *big.Int) string {
+	if n == nil {
+		return "latest"
+	}
+	if n.Sign() >= 0 {
+		return hexutil.EncodeBig(n)
+	}
+	// Negative numbers are special blocks; e.g. rpc.PendingBlockNumber.
+	return rpc.BlockNumber(n.Int64()).String()
+}
+
+// BatchHeaderByNumber is the batched equivalent of HeaderByNumber(). As with
+// HeaderByNumber(), a nil number returns the latest header. If any header
+// isn't found, the returned error wraps ethereum.NotFound.
+func (c *BatchClient) BatchHeaderByNumber(ctx context.Context, numbers []*big.Int) ([]*types.Header, error) {
+	headers := make([]*types.Header, len(numbers))
+	elems := make([]rpc.BatchElem, len(numbers))
+	for i, n := range numbers {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []any{blockNumArg(n), false},
+			Result: &headers[i],
+		}
+	}
+	if err := c.batch(ctx, elems); err != nil {
+		return nil, err
+	}
+	for i, h := range headers {
+		if h == nil {
+			return nil, fmt.Errorf("header of block %s: %w", blockNumArg(numbers[i]), ethereum.NotFound)
+		}
+	}
+	return headers, nil
+}
+
+// BatchBalanceAt is the batched equivalent of BalanceAt(), returning the
+// balances of all accounts at the same block.
+func (c *BatchClient) BatchBalanceAt(ctx context.Context, accounts []common.Address, blockNumber *big.Int) ([]*big.Int, error) {
+	results := make([]hexutil.Big, len(accounts))
+	elems := make([]rpc.BatchElem, len(accounts))
+	for i, a := range accounts {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_getBalance",
+			Args:   []any{a, blockNumArg(blockNumber)},
+			Result: &results[i],
+		}
+	}
+	if err := c.batch(ctx, elems); err != nil {
+		return nil, err
+	}
+
+	bals := make([]*big.Int, len(results))
+	for i := range results {
+		bals[i] = results[i].ToInt()
+	}
+	return bals, nil
+}
+
+// BatchCallContract is the batched equivalent of CallContract(), executing all
+// calls at the same block. A reverted call results in an error for the entire
+// batch, from which RevertFromError() can determine the reason.
+func (c *BatchClient) BatchCallContract(ctx context.Context, msgs []ethereum.CallMsg, blockNumber *big.Int) ([][]byte, error) {
+	results := make([]hexutil.Bytes, len(msgs))
+	elems := make([]rpc.BatchElem, len(msgs))
+	for i, msg := range msgs {
+		elems[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []any{callArg(msg), blockNumArg(blockNumber)},
+			Result: &results[i],
+		}
+	}
+	if err := c.batch(ctx, elems); err != nil {
+		return nil, err
+	}
+
+	out := make([][]byte, len(results))
+	for i, r := range results {
+		out[i] = r
+	}
+	return out, nil
+}
+
+// callArg is equivalent to the unexported function of the same purpose in the
+// ethclient package.
+func callArg(msg ethereum.CallMsg) any {
+	arg := map[string]any{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.GasFeeCap != nil {
+		arg["maxFeePerGas"] = (*hexutil.Big)(msg.GasFeeCap)
+	}
+	if msg.GasTipCap != nil {
+		arg["maxPriorityFeePerGas"] = (*hexutil.Big)(msg.GasTipCap)
+	}
+	if msg.AccessList != nil {
+		arg["accessList"] = msg.AccessList
+	}
+	return arg
+}
@@ -0,0 +1,163 @@
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cxkoda/solgo/go/secrets"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// batchService implements the subset of the eth namespace used by
+// BatchClient, with values derived from the inputs.
+type batchService struct{}
+
+const batchHead = 100
+
+func (batchService) GetBlockByNumber(n rpc.BlockNumber, _ bool) (*types.Header, error) {
+	if n == rpc.LatestBlockNumber {
+		n = batchHead
+	}
+	if n > batchHead {
+		return nil, nil
+	}
+	return &types.Header{
+		Number:     big.NewInt(n.Int64()),
+		Difficulty: new(big.Int),
+	}, nil
+}
+
+func (batchService) GetBalance(addr common.Address, n rpc.BlockNumber) *hexutil.Big {
+	b := new(big.Int).SetBytes(addr.Bytes())
+	return (*hexutil.Big)(b.Add(b, big.NewInt(n.Int64())))
+}
+
+type callArgs struct {
+	To    *common.Address `json:"to"`
+	Input hexutil.Bytes   `json:"input"`
+}
+
+func (batchService) Call(args callArgs, _ rpc.BlockNumber) (hexutil.Bytes, error) {
+	if len(args.Input) == 0 {
+		return nil, errors.New("empty input")
+	}
+	return append(args.To.Bytes(), args.Input...), nil
+}
+
+func TestBatchClient(t *testing.T) {
+	ctx := context.Background()
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", batchService{}); err != nil {
+		t.Fatalf("%T.RegisterName(eth, %T) error %v", srv, batchService{}, err)
+	}
+	t.Cleanup(srv.Stop)
+
+	var roundTrips atomic.Int64
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		roundTrips.Add(1)
+		srv.ServeHTTP(w, r)
+	}))
+	t.Cleanup(h.Close)
+
+	dialer := NewDialer(&secrets.Secret{Source: secrets.Raw, ID: h.URL})
+	client, err := dialer.DialBatch(ctx)
+	if err != nil {
+		t.Fatalf("%T.DialBatch() error %v", dialer, err)
+	}
+	t.Cleanup(client.Close)
+	client.MaxBatchSize = 4
+
+	// expectRoundTrips checks the number of HTTP requests since it was last
+	// called.
+	expectRoundTrips := func(t *testing.T, want int64) {
+		t.Helper()
+		if got := roundTrips.Swap(0); got != want {
+			t.Errorf("HTTP round trips got %d; want %d", got, want)
+		}
+	}
+
+	t.Run("BatchHeaderByNumber", func(t *testing.T) {
+		nums := []*big.Int{big.NewInt(1), nil, big.NewInt(42)}
+		headers, err := client.BatchHeaderByNumber(ctx, nums)
+		if err != nil {
+			t.Fatalf("BatchHeaderByNumber(%v) error %v", nums, err)
+		}
+		var got []uint64
+		for _, h := range headers {
+			got = append(got, h.Number.Uint64())
+		}
+		if diff := cmp.Diff([]uint64{1, batchHead, 42}, got); diff != "" {
+			t.Errorf("BatchHeaderByNumber(%v) block numbers diff (-want +got):\n%s", nums, diff)
+		}
+		expectRoundTrips(t, 1)
+
+		missing := []*big.Int{big.NewInt(1), big.NewInt(batchHead + 1)}
+		if _, err := client.BatchHeaderByNumber(ctx, missing); !errors.Is(err, ethereum.NotFound) {
+			t.Errorf("BatchHeaderByNumber(%v) got err %v; want %v", missing, err, ethereum.NotFound)
+		}
+		expectRoundTrips(t, 1)
+	})
+
+	t.Run("BatchBalanceAt", func(t *testing.T) {
+		var (
+			accounts []common.Address
+			want     []*big.Int
+		)
+		for i := int64(1); i <= 10; i++ {
+			accounts = append(accounts, common.BigToAddress(big.NewInt(i*1000)))
+			want = append(want, big.NewInt(i*1000+7))
+		}
+		got, err := client.BatchBalanceAt(ctx, accounts, big.NewInt(7))
+		if err != nil {
+			t.Fatalf("BatchBalanceAt(…) error %v", err)
+		}
+		if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })); diff != "" {
+			t.Errorf("BatchBalanceAt(…) diff (-want +got):\n%s", diff)
+		}
+		// 10 requests with a maximum of 4 per batch.
+		expectRoundTrips(t, 3)
+	})
+
+	t.Run("BatchCallContract", func(t *testing.T) {
+		to := common.HexToAddress("0xc0de")
+		msgs := []ethereum.CallMsg{
+			{To: &to, Data: []byte{1}},
+			{To: &to, Data: []byte{2, 3}},
+		}
+		got, err := client.BatchCallContract(ctx, msgs, nil)
+		if err != nil {
+			t.Fatalf("BatchCallContract(…) error %v", err)
+		}
+		want := [][]byte{
+			append(to.Bytes(), 1),
+			append(to.Bytes(), 2, 3),
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("BatchCallContract(…) diff (-want +got):\n%s", diff)
+		}
+		expectRoundTrips(t, 1)
+
+		msgs = append(msgs, ethereum.CallMsg{To: &to})
+		if _, err := client.BatchCallContract(ctx, msgs, nil); err == nil {
+			t.Errorf("BatchCallContract(…, [call with error]) got nil error; want non-nil")
+		}
+		expectRoundTrips(t, 1)
+	})
+}
@@ -0,0 +1,265 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrLogMismatch is returned by EventBinder.Bind() if the log wasn't emitted by
+// the bound event.
+var ErrLogMismatch = errors.New("log not emitted by bound event")
+
+// An EventBinder decodes logs of a single event into values of type T, without
+// the need for abigen-generated bindings. It is safe for concurrent use.
+type EventBinder[T any] struct {
+	event    abi.Event
+	nIndexed int
+	args     []boundArg
+	// raw is the index of T's Raw field, or nil if it has none.
+	raw []int
+}
+
+// A boundArg is an event argument along with the struct field, if any, into
+// which it is decoded.
+type boundArg struct {
+	abi.Argument
+	field []int
+	// hashed is true for indexed arguments of reference types, for which the
+	// topic only carries the Keccak256 hash of the value.
+	hashed bool
+}
+
+// NewEventBinder parses the JSON ABI and returns an EventBinder for the named
+// event, keyed as in abi.ABI.Events (i.e. overloaded events have numerical
+// suffixes). T MUST be a struct.
+//
+// Each event argument is bound to the exported field of T with the tag
+// `abi:"<argument name>"`, as also used by abi.ABI.UnpackIntoInterface(), or,
+// in the absence of such a tag, to the field named as in abigen-generated types
+// (e.g. tokenId becomes TokenId). Unnamed arguments are named arg0, arg1, etc.
+// by their position. Arguments without a field are ignored, as are fields
+// tagged `abi:"-"`, but a tag naming a non-existent argument is an error, as is
+// a field type to which the argument can't be converted. As with
+// ParseRawLogs(), an unbound types.Log field named Raw is populated with the
+// log itself.
+//
+// Indexed arguments of reference types (strings, bytes, arrays, slices, and
+// tuples) are only available as the Keccak256 hash of their value, so MUST be
+// bound to fields of type common.Hash.
+func NewEventBinder[T any](abiJSON, eventName string) (*EventBinder[T], error) {
+	contract, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, fmt.Errorf("abi.JSON(…): %v", err)
+	}
+	ev, ok := contract.Events[eventName]
+	if !ok {
+		return nil, fmt.Errorf("event %q not in ABI", eventName)
+	}
+	if ev.Anonymous {
+		return nil, fmt.Errorf("anonymous event %q unsupported", eventName)
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if k := typ.Kind(); k != reflect.Struct {
+		var t T
+		return nil, fmt.Errorf("type %T of kind %v; must be a struct", t, k)
+	}
+
+	tagged := make(map[string]reflect.StructField)
+	untagged := make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag, ok := f.Tag.Lookup("abi")
+		switch {
+		case !ok:
+			untagged[f.Name] = f
+		case tag == "-":
+		default:
+			if g, ok := tagged[tag]; ok {
+				return nil, fmt.Errorf("%v fields %s and %s both tagged %q", typ, g.Name, f.Name, tag)
+			}
+			tagged[tag] = f
+		}
+	}
+
+	b := &EventBinder[T]{event: ev}
+	for _, arg := range ev.Inputs {
+		a := boundArg{
+			Argument: arg,
+			hashed:   arg.Indexed && isReferenceType(arg.Type),
+		}
+		if arg.Indexed {
+			b.nIndexed++
+		}
+
+		f, ok := tagged[arg.Name]
+		if ok {
+			delete(tagged, arg.Name)
+		} else if f, ok = untagged[abi.ToCamelCase(arg.Name)]; ok {
+			delete(untagged, f.Name)
+		}
+		if ok {
+			from := arg.Type.GetType()
+			if a.hashed {
+				from = reflect.TypeOf(common.Hash{})
+			}
+			if !bindable(from, f.Type) {
+				return nil, fmt.Errorf("%s argument %q decoded as %v; not convertible to %v field %s", ev.Sig, arg.Name, from, typ, f.Name)
+			}
+			a.field = f.Index
+		}
+		b.args = append(b.args, a)
+	}
+
+	if len(tagged) > 0 {
+		var names []string
+		for n := range tagged {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%v fields tagged with non-existent %s argument(s) %q", typ, ev.Sig, names)
+	}
+	if f, ok := untagged["Raw"]; ok && f.Type == reflect.TypeOf(types.Log{}) {
+		b.raw = f.Index
+	}
+
+	return b, nil
+}
+
+// isReferenceType reports whether t is only hashed when an indexed argument.
+func isReferenceType(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return true
+	}
+	return false
+}
+
+// bindable reports whether values of type `from`, as decoded by the abi
+// package, can be set on fields of type `to` by setField().
+func bindable(from, to reflect.Type) bool {
+	switch {
+	case from.AssignableTo(to):
+		return true
+	case from.Kind() == reflect.Slice && to.Kind() == reflect.Array:
+		// Convertible since Go 1.20, but panics if the slice is too short.
+		return false
+	case to.Kind() == reflect.String && from.Kind() != reflect.String:
+		// Integers are convertible to strings, as runes.
+		return false
+	case from.ConvertibleTo(to):
+		return true
+	}
+
+	// Tuples are decoded as anonymous structs, which abi.ConvertType() copies
+	// into named ones by field name, including within slices and arrays.
+	switch to.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array:
+		return from.Kind() == to.Kind()
+	}
+	return false
+}
+
+// setField sets f to v, converting it if necessary.
+func setField(f reflect.Value, v interface{}) (err error) {
+	val := reflect.ValueOf(v)
+	switch t := f.Type(); {
+	case val.Type().AssignableTo(t):
+		f.Set(val)
+	case val.Type().ConvertibleTo(t):
+		f.Set(val.Convert(t))
+	default:
+		// abi.ConvertType() panics instead of returning an error.
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("converting %T to %v: %v", v, t, r)
+			}
+		}()
+		f.Set(reflect.ValueOf(abi.ConvertType(v, reflect.New(t).Interface())).Elem())
+	}
+	return nil
+}
+
+// ID returns the event's signature hash, which is the first topic of all of its
+// logs and can therefore be used in an ethereum.FilterQuery.
+func (b *EventBinder[T]) ID() common.Hash {
+	return b.event.ID
+}
+
+// Matches reports whether l was emitted by the bound event; i.e. its first topic
+// is b.ID() and it has a topic for each indexed argument. The latter
+// distinguishes, for example, ERC20 and ERC721 Transfers. As the log's address
+// isn't checked, the same event emitted by any contract matches.
+func (b *EventBinder[T]) Matches(l *types.Log) bool {
+	return len(l.Topics) == b.nIndexed+1 && l.Topics[0] == b.event.ID
+}
+
+// Bind decodes l into a new T. The returned error wraps ErrLogMismatch if
+// !b.Matches(l).
+func (b *EventBinder[T]) Bind(l *types.Log) (T, error) {
+	var t T
+	if !b.Matches(l) {
+		return t, fmt.Errorf("tx %v log index %d: %w %s", l.TxHash, l.Index, ErrLogMismatch, b.event.Sig)
+	}
+
+	data, err := b.event.Inputs.NonIndexed().Unpack(l.Data)
+	if err != nil {
+		return t, fmt.Errorf("tx %v log index %d: unpacking %s data: %v", l.TxHash, l.Index, b.event.Sig, err)
+	}
+
+	out := reflect.ValueOf(&t).Elem()
+	topics := l.Topics[1:]
+	for _, a := range b.args {
+		var v interface{}
+		if a.Indexed {
+			v, topics = topics[0], topics[1:]
+		} else {
+			v, data = data[0], data[1:]
+		}
+		if a.field == nil {
+			continue
+		}
+
+		if a.Indexed && !a.hashed {
+			vals := make(map[string]interface{})
+			if err := abi.ParseTopicsIntoMap(vals, abi.Arguments{a.Argument}, []common.Hash{v.(common.Hash)}); err != nil {
+				return t, fmt.Errorf("tx %v log index %d: parsing %s topic %q: %v", l.TxHash, l.Index, b.event.Sig, a.Name, err)
+			}
+			v = vals[a.Name]
+		}
+		if err := setField(out.FieldByIndex(a.field), v); err != nil {
+			return t, fmt.Errorf("tx %v log index %d: binding %s argument %q: %v", l.TxHash, l.Index, b.event.Sig, a.Name, err)
+		}
+	}
+
+	if b.raw != nil {
+		out.FieldByIndex(b.raw).Set(reflect.ValueOf(*l))
+	}
+	return t, nil
+}
+
+// BindAll decodes, in order, all logs that b.Matches(), ignoring all others.
+func (b *EventBinder[T]) BindAll(logs []*types.Log) ([]T, error) {
+	var out []T
+	for _, l := range logs {
+		if !b.Matches(l) {
+			continue
+		}
+		t, err := b.Bind(l)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
@@ -0,0 +1,216 @@
+package eth_test
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+const listedABI = `[
+	{
+		"type": "event",
+		"name": "Listed",
+		"inputs": [
+			{"name": "seller", "type": "address", "indexed": true},
+			{"name": "collection", "type": "string", "indexed": true},
+			{"name": "kind", "type": "uint8", "indexed": true},
+			{"name": "price", "type": "uint256", "indexed": false},
+			{
+				"name": "fees",
+				"type": "tuple[]",
+				"indexed": false,
+				"components": [
+					{"name": "recipient", "type": "address"},
+					{"name": "bps", "type": "uint16"}
+				]
+			},
+			{"name": "memo", "type": "bytes", "indexed": false},
+			{"name": "", "type": "uint256", "indexed": false}
+		]
+	}
+]`
+
+type fee struct {
+	Recipient common.Address
+	Bps       uint16
+}
+
+type listed struct {
+	Seller     common.Address
+	Collection common.Hash `abi:"collection"`
+	Kind       uint64      `abi:"kind"`
+	Price      *big.Int
+	Fees       []fee
+	Memo       []byte   `abi:"-"`
+	Nonce      *big.Int `abi:"arg6"`
+	Raw        types.Log
+}
+
+func TestEventBinder(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(listedABI))
+	if err != nil {
+		t.Fatalf("abi.JSON(…) error %v", err)
+	}
+	ev := contract.Events["Listed"]
+
+	seller := common.HexToAddress("0x5e11e4")
+	fees := []fee{
+		{common.HexToAddress("0xa"), 250},
+		{common.HexToAddress("0xb"), 50},
+	}
+	data, err := ev.Inputs.NonIndexed().Pack(big.NewInt(1e18), fees, []byte("gm"), big.NewInt(42))
+	if err != nil {
+		t.Fatalf("%T.Pack(…) error %v", ev.Inputs.NonIndexed(), err)
+	}
+
+	log := &types.Log{
+		Topics: []common.Hash{
+			ev.ID,
+			common.BytesToHash(seller.Bytes()),
+			crypto.Keccak256Hash([]byte("Glyphs")),
+			common.BigToHash(big.NewInt(3)),
+		},
+		Data:   data,
+		TxHash: common.HexToHash("0x7a"),
+		Index:  4,
+	}
+	// Same signature, but without the final indexed argument.
+	fewerTopics := &types.Log{
+		Topics: log.Topics[:3],
+		Data:   data,
+		Index:  5,
+	}
+	other := &types.Log{
+		Topics: []common.Hash{crypto.Keccak256Hash([]byte("Other()"))},
+		Index:  6,
+	}
+
+	b, err := NewEventBinder[listed](listedABI, "Listed")
+	if err != nil {
+		t.Fatalf("NewEventBinder[listed](…, %q) error %v", "Listed", err)
+	}
+	if got, want := b.ID(), ev.ID; got != want {
+		t.Errorf("%T.ID() got %v; want %v", b, got, want)
+	}
+
+	got, err := b.BindAll([]*types.Log{other, log, fewerTopics})
+	if err != nil {
+		t.Fatalf("%T.BindAll(…) error %v", b, err)
+	}
+	want := []listed{{
+		Seller:     seller,
+		Collection: crypto.Keccak256Hash([]byte("Glyphs")),
+		Kind:       3,
+		Price:      big.NewInt(1e18),
+		Fees:       fees,
+		Nonce:      big.NewInt(42),
+		Raw:        *log,
+	}}
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })); diff != "" {
+		t.Errorf("%T.BindAll(…) diff (-want +got):\n%s", b, diff)
+	}
+
+	if _, err := b.Bind(fewerTopics); !errors.Is(err, ErrLogMismatch) {
+		t.Errorf("%T.Bind([log with too few topics]) got err %v; want %v", b, err, ErrLogMismatch)
+	}
+}
+
+func TestNewEventBinderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() error
+	}{
+		{
+			name: "event not in ABI",
+			new: func() error {
+				_, err := NewEventBinder[listed](listedABI, "Missing")
+				return err
+			},
+		},
+		{
+			name: "invalid ABI",
+			new: func() error {
+				_, err := NewEventBinder[listed](`{`, "Listed")
+				return err
+			},
+		},
+		{
+			name: "non-struct",
+			new: func() error {
+				_, err := NewEventBinder[*listed](listedABI, "Listed")
+				return err
+			},
+		},
+		{
+			name: "tag of non-existent argument",
+			new: func() error {
+				type typo struct {
+					Price *big.Int `abi:"prize"`
+				}
+				_, err := NewEventBinder[typo](listedABI, "Listed")
+				return err
+			},
+		},
+		{
+			name: "duplicate tags",
+			new: func() error {
+				type dup struct {
+					A *big.Int `abi:"price"`
+					B *big.Int `abi:"price"`
+				}
+				_, err := NewEventBinder[dup](listedABI, "Listed")
+				return err
+			},
+		},
+		{
+			name: "indexed string as string",
+			new: func() error {
+				type unhashed struct {
+					Collection string
+				}
+				_, err := NewEventBinder[unhashed](listedABI, "Listed")
+				return err
+			},
+		},
+		{
+			name: "uint256 as uint64",
+			new: func() error {
+				type narrow struct {
+					Price uint64
+				}
+				_, err := NewEventBinder[narrow](listedABI, "Listed")
+				return err
+			},
+		},
+		{
+			name: "bytes as fixed-size array",
+			new: func() error {
+				type fixed struct {
+					Memo [32]byte
+				}
+				_, err := NewEventBinder[fixed](listedABI, "Listed")
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.new(); err == nil {
+				t.Errorf("NewEventBinder() got nil error; want non-nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// blobBytesPerFieldElement is the number of data bytes packed into each 32-byte
+// field element of a blob. The leading byte is always zero to guarantee that
+// the element is less than the BLS12-381 scalar-field modulus.
+const blobBytesPerFieldElement = 31
+
+// BlobCapacity is the number of data bytes stored in each blob by
+// BlobsFromData().
+const BlobCapacity = blobBytesPerFieldElement * params.BlobTxFieldElementsPerBlob
+
+// BlobsFromData packs data into the minimum number of blobs, BlobCapacity bytes
+// per blob. The final blob is zero-padded so the length of data MUST be
+// recorded elsewhere (e.g. in calldata) if it is to be recovered with
+// DataFromBlobs(). Empty data results in a single, empty blob.
+func BlobsFromData(data []byte) []kzg4844.Blob {
+	n := (len(data) + BlobCapacity - 1) / BlobCapacity
+	if n == 0 {
+		n = 1
+	}
+
+	blobs := make([]kzg4844.Blob, n)
+	for i := 0; len(data) > 0; i++ {
+		b := &blobs[i/params.BlobTxFieldElementsPerBlob]
+		el := i % params.BlobTxFieldElementsPerBlob
+		data = data[copy(b[el*32+1:(el+1)*32], data):]
+	}
+	return blobs
+}
+
+// DataFromBlobs is the inverse of BlobsFromData(), returning the first n bytes
+// of data packed into the blobs.
+func DataFromBlobs(blobs []kzg4844.Blob, n int) ([]byte, error) {
+	if max := len(blobs) * BlobCapacity; n < 0 || n > max {
+		return nil, fmt.Errorf("%d bytes requested from %d blobs; must be in [0,%d]", n, len(blobs), max)
+	}
+
+	data := make([]byte, 0, n)
+	for i := 0; len(data) < n; i++ {
+		b := &blobs[i/params.BlobTxFieldElementsPerBlob]
+		el := i % params.BlobTxFieldElementsPerBlob
+		if b[el*32] != 0 {
+			return nil, fmt.Errorf("blob %d field element %d has non-zero leading byte; not packed by BlobsFromData()", i/params.BlobTxFieldElementsPerBlob, el)
+		}
+		chunk := b[el*32+1 : (el+1)*32]
+		if rem := n - len(data); rem < len(chunk) {
+			chunk = chunk[:rem]
+		}
+		data = append(data, chunk...)
+	}
+	return data, nil
+}
+
+// NewBlobTxSidecar computes the KZG commitment and proof of each blob,
+// returning a sidecar for use in an EIP-4844 blob transaction.
+func NewBlobTxSidecar(blobs []kzg4844.Blob) (*types.BlobTxSidecar, error) {
+	if n := len(blobs); n == 0 || n > params.MaxBlobGasPerBlock/params.BlobTxBlobGasPerBlob {
+		return nil, fmt.Errorf("%d blobs; must be in [1,%d]", n, params.MaxBlobGasPerBlock/params.BlobTxBlobGasPerBlob)
+	}
+
+	sc := &types.BlobTxSidecar{
+		Blobs:       blobs,
+		Commitments: make([]kzg4844.Commitment, len(blobs)),
+		Proofs:      make([]kzg4844.Proof, len(blobs)),
+	}
+	for i, b := range blobs {
+		c, err := kzg4844.BlobToCommitment(b)
+		if err != nil {
+			return nil, fmt.Errorf("kzg4844.BlobToCommitment(blob %d): %v", i, err)
+		}
+		p, err := kzg4844.ComputeBlobProof(b, c)
+		if err != nil {
+			return nil, fmt.Errorf("kzg4844.ComputeBlobProof(blob %d, …): %v", i, err)
+		}
+		sc.Commitments[i] = c
+		sc.Proofs[i] = p
+	}
+	return sc, nil
+}
+
+// SignBlobTx constructs an EIP-4844 blob transaction, carrying the sidecar, and
+// signs it with opts.Signer. Blob transactions can't be created by
+// bind.BoundContract so, unlike with a bound contract, opts.Nonce,
+// opts.GasLimit, opts.GasTipCap, and opts.GasFeeCap are required; see
+// FeeEstimator for the latter two. The blobFeeCap, typically from
+// FeeEstimator.EstimateBlobFeeCap(), is the maximum price paid per unit of blob
+// gas.
+//
+// opts.Value MAY be nil, and opts.NoSend is ignored. Blob transactions can't
+// create contracts so a recipient is always required.
+func SignBlobTx(opts *bind.TransactOpts, chainID *big.Int, to common.Address, data []byte, blobFeeCap *big.Int, sidecar *types.BlobTxSidecar) (*types.Transaction, error) {
+	if opts.Nonce == nil || opts.GasLimit == 0 || opts.GasTipCap == nil || opts.GasFeeCap == nil {
+		return nil, fmt.Errorf("%T.{Nonce,GasLimit,GasTipCap,GasFeeCap} must all be set for blob transactions", opts)
+	}
+	if sidecar == nil || len(sidecar.Blobs) == 0 {
+		return nil, errors.New("blob transaction requires non-empty sidecar")
+	}
+	if len(sidecar.Commitments) != len(sidecar.Blobs) || len(sidecar.Proofs) != len(sidecar.Blobs) {
+		return nil, fmt.Errorf("sidecar with %d blobs, %d commitments, and %d proofs; must be equal", len(sidecar.Blobs), len(sidecar.Commitments), len(sidecar.Proofs))
+	}
+
+	value := opts.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	var (
+		inner types.BlobTx
+		err   error
+	)
+	for _, f := range []struct {
+		name string
+		dst  **uint256.Int
+		src  *big.Int
+	}{
+		{"chain ID", &inner.ChainID, chainID},
+		{"GasTipCap", &inner.GasTipCap, opts.GasTipCap},
+		{"GasFeeCap", &inner.GasFeeCap, opts.GasFeeCap},
+		{"value", &inner.Value, value},
+		{"blob fee cap", &inner.BlobFeeCap, blobFeeCap},
+	} {
+		if *f.dst, err = toUint256(f.src); err != nil {
+			return nil, fmt.Errorf("%s: %v", f.name, err)
+		}
+	}
+	inner.Nonce = opts.Nonce.Uint64()
+	inner.Gas = opts.GasLimit
+	inner.To = to
+	inner.Data = data
+	inner.BlobHashes = sidecar.BlobHashes()
+	inner.Sidecar = sidecar
+
+	tx := types.NewTx(&inner)
+	signed, err := opts.Signer(opts.From, tx)
+	if err != nil {
+		return nil, fmt.Errorf("%T.Signer(%v, [blob tx]): %v", opts, opts.From, err)
+	}
+	return signed, nil
+}
+
+// toUint256 converts v, which MUST be non-nil and non-negative, to a uint256.
+func toUint256(v *big.Int) (*uint256.Int, error) {
+	if v == nil {
+		return nil, errors.New("nil value")
+	}
+	if v.Sign() < 0 {
+		return nil, fmt.Errorf("negative value %v", v)
+	}
+	u, overflow := uint256.FromBig(v)
+	if overflow {
+		return nil, fmt.Errorf("value %v overflows uint256", v)
+	}
+	return u, nil
+}
+
+// A BlobFeeBackend provides the chain data required by
+// FeeEstimator.EstimateBlobFeeCap(). It is satisfied by *ethclient.Client.
+type BlobFeeBackend interface {
+	HeaderByNumber(context.Context, *big.Int) (*types.Header, error)
+}
+
+// ErrNoBlobGas is returned by FeeEstimator.EstimateBlobFeeCap() if the latest
+// block predates EIP-4844.
+var ErrNoBlobGas = errors.New("block has no blob-gas fields")
+
+// EstimateBlobFeeCap returns a cap on the price per unit of blob gas, for use
+// with SignBlobTx(). It is the next block's blob base fee, projected forward
+// over e.HeadroomBlocks assuming that every block in the interim contains the
+// maximum number of blobs. The FeeEstimator's backend MUST also implement
+// BlobFeeBackend.
+func (e *FeeEstimator) EstimateBlobFeeCap(ctx context.Context) (*big.Int, error) {
+	b, ok := e.backend.(BlobFeeBackend)
+	if !ok {
+		return nil, fmt.Errorf("%T does not implement BlobFeeBackend", e.backend)
+	}
+
+	h, err := b.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%T.HeaderByNumber(latest): %v", b, err)
+	}
+	if h.ExcessBlobGas == nil || h.BlobGasUsed == nil {
+		return nil, fmt.Errorf("%w: block %v", ErrNoBlobGas, h.Number)
+	}
+
+	excess := eip4844.CalcExcessBlobGas(*h.ExcessBlobGas, *h.BlobGasUsed)
+	for i := uint(0); i < e.HeadroomBlocks; i++ {
+		excess = eip4844.CalcExcessBlobGas(excess, params.MaxBlobGasPerBlock)
+	}
+	return eip4844.CalcBlobFee(excess), nil
+}
@@ -0,0 +1,250 @@
+package eth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestBlobsFromData(t *testing.T) {
+	tests := []struct {
+		name      string
+		len       int
+		wantBlobs int
+	}{
+		{name: "empty", len: 0, wantBlobs: 1},
+		{name: "single byte", len: 1, wantBlobs: 1},
+		{name: "partial field element", len: 40, wantBlobs: 1},
+		{name: "exactly one blob", len: BlobCapacity, wantBlobs: 1},
+		{name: "one byte more than a blob", len: BlobCapacity + 1, wantBlobs: 2},
+		{name: "multiple blobs", len: 3*BlobCapacity - 100, wantBlobs: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.len)
+			for i := range data {
+				data[i] = byte(i%255) + 1
+			}
+
+			blobs := BlobsFromData(data)
+			if got, want := len(blobs), tt.wantBlobs; got != want {
+				t.Fatalf("len(BlobsFromData([%d bytes])) got %d; want %d", tt.len, got, want)
+			}
+			for i, b := range blobs {
+				for el := 0; el < params.BlobTxFieldElementsPerBlob; el++ {
+					if b[el*32] != 0 {
+						t.Fatalf("BlobsFromData() blob %d field element %d has non-zero leading byte", i, el)
+					}
+				}
+			}
+
+			got, err := DataFromBlobs(blobs, tt.len)
+			if err != nil {
+				t.Fatalf("DataFromBlobs(BlobsFromData(data), %d) error %v", tt.len, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("DataFromBlobs(BlobsFromData(data), %d) did not round-trip", tt.len)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		blobs := BlobsFromData(nil)
+		if _, err := DataFromBlobs(blobs, BlobCapacity+1); err == nil {
+			t.Errorf("DataFromBlobs([1 blob], %d) got nil error; want error", BlobCapacity+1)
+		}
+
+		blobs[0][0] = 1
+		if _, err := DataFromBlobs(blobs, 1); err == nil {
+			t.Error("DataFromBlobs([blob with non-zero leading byte]) got nil error; want error")
+		}
+	})
+}
+
+func TestSignBlobTx(t *testing.T) {
+	signer, err := NewSigner(128)
+	if err != nil {
+		t.Fatalf("NewSigner(128) error %v", err)
+	}
+	chainID := big.NewInt(1337)
+	opts, err := signer.TransactorWithChainID(chainID)
+	if err != nil {
+		t.Fatalf("%T.TransactorWithChainID(%d) error %v", signer, chainID, err)
+	}
+
+	data := []byte("hello blobs")
+	sidecar, err := NewBlobTxSidecar(BlobsFromData(data))
+	if err != nil {
+		t.Fatalf("NewBlobTxSidecar() error %v", err)
+	}
+	for i, b := range sidecar.Blobs {
+		if err := kzg4844.VerifyBlobProof(b, sidecar.Commitments[i], sidecar.Proofs[i]); err != nil {
+			t.Errorf("kzg4844.VerifyBlobProof([blob %d from NewBlobTxSidecar()]) error %v", i, err)
+		}
+	}
+
+	to := common.HexToAddress("0x5a1d")
+	blobFeeCap := big.NewInt(params.GWei)
+
+	t.Run("missing fields", func(t *testing.T) {
+		if _, err := SignBlobTx(opts, chainID, to, nil, blobFeeCap, sidecar); err == nil {
+			t.Errorf("SignBlobTx(%T without Nonce etc.) got nil error; want error", opts)
+		}
+	})
+
+	opts.Nonce = big.NewInt(42)
+	opts.GasLimit = 21_000
+	opts.GasTipCap = big.NewInt(2 * params.GWei)
+	opts.GasFeeCap = big.NewInt(50 * params.GWei)
+
+	t.Run("invalid sidecar", func(t *testing.T) {
+		bad := &types.BlobTxSidecar{Blobs: sidecar.Blobs}
+		if _, err := SignBlobTx(opts, chainID, to, nil, blobFeeCap, bad); err == nil {
+			t.Error("SignBlobTx([sidecar without commitments]) got nil error; want error")
+		}
+	})
+
+	tx, err := SignBlobTx(opts, chainID, to, data, blobFeeCap, sidecar)
+	if err != nil {
+		t.Fatalf("SignBlobTx(…) error %v", err)
+	}
+
+	if got, want := tx.Type(), uint8(types.BlobTxType); got != want {
+		t.Errorf("SignBlobTx().Type() got %d; want %d", got, want)
+	}
+	if diff := cmp.Diff(sidecar.BlobHashes(), tx.BlobHashes()); diff != "" {
+		t.Errorf("SignBlobTx().BlobHashes() diff (-want +got):\n%s", diff)
+	}
+	if sc := tx.BlobTxSidecar(); sc == nil {
+		t.Error("SignBlobTx().BlobTxSidecar() got nil; want sidecar retained")
+	} else if diff := cmp.Diff(sidecar.Commitments, sc.Commitments); diff != "" {
+		t.Errorf("SignBlobTx().BlobTxSidecar().Commitments diff (-want +got):\n%s", diff)
+	}
+
+	for _, c := range []struct {
+		name      string
+		got, want *big.Int
+	}{
+		{"BlobGasFeeCap", tx.BlobGasFeeCap(), blobFeeCap},
+		{"GasTipCap", tx.GasTipCap(), opts.GasTipCap},
+		{"GasFeeCap", tx.GasFeeCap(), opts.GasFeeCap},
+		{"ChainId", tx.ChainId(), chainID},
+	} {
+		if c.got.Cmp(c.want) != 0 {
+			t.Errorf("SignBlobTx().%s() got %d; want %d", c.name, c.got, c.want)
+		}
+	}
+
+	got, err := types.Sender(types.NewCancunSigner(chainID), tx)
+	if err != nil {
+		t.Fatalf("types.Sender(CancunSigner, [blob tx]) error %v", err)
+	}
+	if want := signer.Address(); got != want {
+		t.Errorf("types.Sender(CancunSigner, [blob tx signed by %T.TransactorWithChainID()]) got %v; want %v", signer, got, want)
+	}
+
+	msg := CallMsg(got, tx)
+	if msg.GasPrice != nil || msg.GasFeeCap.Cmp(opts.GasFeeCap) != 0 || msg.GasTipCap.Cmp(opts.GasTipCap) != 0 {
+		t.Errorf("CallMsg(…, [blob tx]) got {GasPrice: %v, GasFeeCap: %v, GasTipCap: %v}; want {GasPrice: nil, GasFeeCap: %v, GasTipCap: %v}", msg.GasPrice, msg.GasFeeCap, msg.GasTipCap, opts.GasFeeCap, opts.GasTipCap)
+	}
+}
+
+// fakeBlobFeeBackend is a BlobFeeBackend that returns a fixed latest header.
+type fakeBlobFeeBackend struct {
+	*fakeFeeBackend
+	header *types.Header
+}
+
+func (f *fakeBlobFeeBackend) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return f.header, nil
+}
+
+func TestEstimateBlobFeeCap(t *testing.T) {
+	ptr := func(x uint64) *uint64 { return &x }
+
+	const target = params.BlobTxTargetBlobGasPerBlock
+	const growth = params.MaxBlobGasPerBlock - target
+
+	tests := []struct {
+		name           string
+		header         *types.Header
+		headroomBlocks uint
+		wantExcess     uint64
+	}{
+		{
+			name: "below target without headroom",
+			header: &types.Header{
+				ExcessBlobGas: ptr(0),
+				BlobGasUsed:   ptr(target / 3),
+			},
+			wantExcess: 0,
+		},
+		{
+			name: "above target without headroom",
+			header: &types.Header{
+				ExcessBlobGas: ptr(10 * target),
+				BlobGasUsed:   ptr(params.MaxBlobGasPerBlock),
+			},
+			wantExcess: 10*target + growth,
+		},
+		{
+			name: "with headroom",
+			header: &types.Header{
+				ExcessBlobGas: ptr(10 * target),
+				BlobGasUsed:   ptr(0),
+			},
+			headroomBlocks: 4,
+			wantExcess:     10*target - target + 4*growth,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewFeeEstimator(&fakeBlobFeeBackend{
+				fakeFeeBackend: &fakeFeeBackend{chainID: MainnetChainID},
+				header:         tt.header,
+			})
+			e.HeadroomBlocks = tt.headroomBlocks
+
+			got, err := e.EstimateBlobFeeCap(context.Background())
+			if err != nil {
+				t.Fatalf("EstimateBlobFeeCap() error %v", err)
+			}
+			if want := eip4844.CalcBlobFee(tt.wantExcess); got.Cmp(want) != 0 {
+				t.Errorf("EstimateBlobFeeCap() got %d; want %d (excess blob gas %d)", got, want, tt.wantExcess)
+			}
+		})
+	}
+
+	t.Run("pre-Cancun block", func(t *testing.T) {
+		e := NewFeeEstimator(&fakeBlobFeeBackend{
+			fakeFeeBackend: &fakeFeeBackend{chainID: MainnetChainID},
+			header:         &types.Header{Number: big.NewInt(1)},
+		})
+		if _, err := e.EstimateBlobFeeCap(context.Background()); !errors.Is(err, ErrNoBlobGas) {
+			t.Errorf("EstimateBlobFeeCap() got err %v; want %v", err, ErrNoBlobGas)
+		}
+	})
+
+	t.Run("backend without headers", func(t *testing.T) {
+		e := NewFeeEstimator(&fakeFeeBackend{chainID: MainnetChainID})
+		if _, err := e.EstimateBlobFeeCap(context.Background()); err == nil {
+			t.Error("EstimateBlobFeeCap() with non-BlobFeeBackend backend got nil error; want error")
+		}
+	})
+}
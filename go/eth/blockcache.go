@@ -0,0 +1,317 @@
+package eth
+
+import (
+	"container/list"
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultBlockCache is a process-wide BlockCache available to all tools, and
+// MAY be replaced, e.g. to change its size. As blocks are keyed by number, it
+// MUST NOT be shared by BlockFetchers of different chains; binaries that use
+// more than one chain SHOULD create a BlockCache per chain instead.
+var DefaultBlockCache = NewBlockCache(4096)
+
+// A BlockCacheOption configures a BlockCache.
+type BlockCacheOption func(*BlockCache)
+
+// WithConfirmations overrides the default number of confirmations, 64, that a
+// block requires before it can be cached by number, thus protecting against
+// chain reorganisations. Blocks and headers are always cached by hash.
+func WithConfirmations(n uint64) BlockCacheOption {
+	return func(c *BlockCache) {
+		c.confirmations = n
+	}
+}
+
+// A BlockCache is an LRU cache of blocks and headers, keyed by both number and
+// hash. Use Fetcher() to wrap a BlockFetcher such that it reads through the
+// cache, e.g. when passing it to LastBlockBy(), to avoid repeatedly fetching
+// the same blocks in binary searches or log scans.
+//
+// A BlockCache is safe for concurrent use.
+type BlockCache struct {
+	size          int
+	confirmations uint64
+
+	mu     sync.Mutex
+	lru    *list.List // of *blockCacheEntry; front is most recently used
+	byNum  map[uint64]*list.Element
+	byHash map[common.Hash]*list.Element
+	head   uint64
+	stats  BlockCacheStats
+}
+
+// NewBlockCache returns a BlockCache holding at most size entries, each of
+// which is a block or a header. A non-positive size disables caching.
+func NewBlockCache(size int, opts ...BlockCacheOption) *BlockCache {
+	c := &BlockCache{
+		size:          size,
+		confirmations: 64,
+		lru:           list.New(),
+		byNum:         make(map[uint64]*list.Element),
+		byHash:        make(map[common.Hash]*list.Element),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// BlockCacheStats are cumulative statistics of a BlockCache's usage.
+type BlockCacheStats struct {
+	Hits, Misses, Evictions uint64
+	// Len is the number of entries at the time the stats were collected.
+	Len int
+}
+
+// HitRate returns Hits/(Hits+Misses), or 0 if there have been no lookups.
+func (s BlockCacheStats) HitRate() float64 {
+	n := s.Hits + s.Misses
+	if n == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(n)
+}
+
+// Stats returns the cache's usage statistics.
+func (c *BlockCache) Stats() BlockCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Len = c.lru.Len()
+	return s
+}
+
+type blockCacheEntry struct {
+	hash   common.Hash
+	header *types.Header
+	// block is nil if only the header is known.
+	block *types.Block
+	byNum bool
+}
+
+// ObserveHead informs the cache of the latest block number, which determines
+// which blocks have sufficient confirmations to be cached by number. Lower
+// numbers than previously observed are ignored.
+func (c *BlockCache) ObserveHead(num uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if num > c.head {
+		c.head = num
+	}
+}
+
+// Block returns the block with the specified number, if cached.
+func (c *BlockCache) Block(num uint64) (*types.Block, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(c.byNum[num], true)
+	if e == nil {
+		return nil, false
+	}
+	return e.block, true
+}
+
+// BlockByHash returns the block with the specified hash, if cached.
+func (c *BlockCache) BlockByHash(h common.Hash) (*types.Block, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(c.byHash[h], true)
+	if e == nil {
+		return nil, false
+	}
+	return e.block, true
+}
+
+// Header returns the header of the block with the specified number, if either
+// the block or the header is cached.
+func (c *BlockCache) Header(num uint64) (*types.Header, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(c.byNum[num], false)
+	if e == nil {
+		return nil, false
+	}
+	return e.header, true
+}
+
+// HeaderByHash returns the header of the block with the specified hash, if
+// either the block or the header is cached.
+func (c *BlockCache) HeaderByHash(h common.Hash) (*types.Header, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.lookup(c.byHash[h], false)
+	if e == nil {
+		return nil, false
+	}
+	return e.header, true
+}
+
+// lookup records a hit or miss for the element, returning its entry iff it's
+// a hit. If needBlock is true, entries with only a header are misses.
+func (c *BlockCache) lookup(el *list.Element, needBlock bool) *blockCacheEntry {
+	if el == nil {
+		c.stats.Misses++
+		return nil
+	}
+	e := el.Value.(*blockCacheEntry)
+	if needBlock && e.block == nil {
+		c.stats.Misses++
+		return nil
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(el)
+	return e
+}
+
+// AddBlock adds the block to the cache, replacing its header if only that was
+// cached.
+func (c *BlockCache) AddBlock(b *types.Block) {
+	c.add(b.Header(), b)
+}
+
+// AddHeader adds the header to the cache, unless its block is already cached.
+func (c *BlockCache) AddHeader(h *types.Header) {
+	c.add(h, nil)
+}
+
+func (c *BlockCache) add(h *types.Header, b *types.Block) {
+	if c.size <= 0 || h.Number == nil || !h.Number.IsUint64() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash := h.Hash()
+	num := h.Number.Uint64()
+	cacheNum := c.confirmations == 0 || num+c.confirmations <= c.head
+
+	if el, ok := c.byHash[hash]; ok {
+		e := el.Value.(*blockCacheEntry)
+		if e.block == nil && b != nil {
+			e.block = b
+			e.header = b.Header()
+		}
+		if cacheNum && !e.byNum {
+			c.indexByNum(num, el)
+		}
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	el := c.lru.PushFront(&blockCacheEntry{hash: hash, header: h, block: b})
+	c.byHash[hash] = el
+	if cacheNum {
+		c.indexByNum(num, el)
+	}
+
+	for c.lru.Len() > c.size {
+		c.evict(c.lru.Back())
+	}
+}
+
+// indexByNum indexes the element by block number, evicting any other element
+// with the same number.
+func (c *BlockCache) indexByNum(num uint64, el *list.Element) {
+	if old, ok := c.byNum[num]; ok && old != el {
+		c.evict(old)
+	}
+	c.byNum[num] = el
+	el.Value.(*blockCacheEntry).byNum = true
+}
+
+func (c *BlockCache) evict(el *list.Element) {
+	e := c.lru.Remove(el).(*blockCacheEntry)
+	delete(c.byHash, e.hash)
+	if e.byNum {
+		delete(c.byNum, e.header.Number.Uint64())
+	}
+	c.stats.Evictions++
+}
+
+// A HeaderFetcher can fetch block headers. Typically this would be an
+// *ethclient.Client.
+type HeaderFetcher interface {
+	HeaderByNumber(context.Context, *big.Int) (*types.Header, error)
+}
+
+// Fetcher returns a BlockFetcher that reads blocks through the cache, falling
+// back to f on a miss and caching the result. Calls to BlockNumber() are never
+// cached, but are used to determine the number of confirmations that a block
+// has; blocks are therefore only cached by number after BlockNumber() has been
+// called at least once, unless WithConfirmations(0) is used.
+//
+// The returned value also has a HeaderByNumber() method, which uses f's own
+// method if f is a HeaderFetcher, and BlockByNumber() otherwise.
+func (c *BlockCache) Fetcher(f BlockFetcher) *CachedBlockFetcher {
+	return &CachedBlockFetcher{f: f, c: c}
+}
+
+// A CachedBlockFetcher is returned by BlockCache.Fetcher().
+type CachedBlockFetcher struct {
+	f BlockFetcher
+	c *BlockCache
+}
+
+var (
+	_ BlockFetcher  = (*CachedBlockFetcher)(nil)
+	_ HeaderFetcher = (*CachedBlockFetcher)(nil)
+)
+
+// BlockNumber propagates the call to the underlying BlockFetcher and informs
+// the cache of the returned value.
+func (f *CachedBlockFetcher) BlockNumber(ctx context.Context) (uint64, error) {
+	n, err := f.f.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	f.c.ObserveHead(n)
+	return n, nil
+}
+
+// BlockByNumber returns the cached block if it exists, otherwise it propagates
+// the call to the underlying BlockFetcher. A nil number, i.e. the latest
+// block, is never read from the cache.
+func (f *CachedBlockFetcher) BlockByNumber(ctx context.Context, num *big.Int) (*types.Block, error) {
+	if num != nil && num.IsUint64() {
+		if b, ok := f.c.Block(num.Uint64()); ok {
+			return b, nil
+		}
+	}
+	b, err := f.f.BlockByNumber(ctx, num)
+	if err != nil {
+		return nil, err
+	}
+	f.c.AddBlock(b)
+	return b, nil
+}
+
+// HeaderByNumber is the header equivalent of BlockByNumber().
+func (f *CachedBlockFetcher) HeaderByNumber(ctx context.Context, num *big.Int) (*types.Header, error) {
+	if num != nil && num.IsUint64() {
+		if h, ok := f.c.Header(num.Uint64()); ok {
+			return h, nil
+		}
+	}
+
+	hf, ok := f.f.(HeaderFetcher)
+	if !ok {
+		b, err := f.BlockByNumber(ctx, num)
+		if err != nil {
+			return nil, err
+		}
+		return b.Header(), nil
+	}
+
+	h, err := hf.HeaderByNumber(ctx, num)
+	if err != nil {
+		return nil, err
+	}
+	f.c.AddHeader(h)
+	return h, nil
+}
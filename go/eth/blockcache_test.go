@@ -0,0 +1,195 @@
+package eth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cxkoda/solgo/go/ethtest"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestBlockCacheLRU(t *testing.T) {
+	c := NewBlockCache(2, WithConfirmations(0))
+	blocks := []*types.Block{
+		ethtest.NewBlock(0, 100),
+		ethtest.NewBlock(1, 101),
+		ethtest.NewBlock(2, 102),
+	}
+
+	c.AddBlock(blocks[0])
+	c.AddBlock(blocks[1])
+	if _, ok := c.Block(0); !ok { // makes 1 the least recently used
+		t.Fatalf("%T.Block(0) after AddBlock() got false; want true", c)
+	}
+	c.AddBlock(blocks[2])
+
+	for _, tt := range []struct {
+		num    uint64
+		wantOK bool
+	}{
+		{num: 0, wantOK: true},
+		{num: 1, wantOK: false},
+		{num: 2, wantOK: true},
+	} {
+		got, ok := c.Block(tt.num)
+		if ok != tt.wantOK {
+			t.Errorf("%T.Block(%d) got ok %t; want %t", c, tt.num, ok, tt.wantOK)
+		}
+		if ok && got.Hash() != blocks[tt.num].Hash() {
+			t.Errorf("%T.Block(%d) returned different block", c, tt.num)
+		}
+
+		_, ok = c.BlockByHash(blocks[tt.num].Hash())
+		if ok != tt.wantOK {
+			t.Errorf("%T.BlockByHash(<block %d>) got ok %t; want %t", c, tt.num, ok, tt.wantOK)
+		}
+	}
+
+	want := BlockCacheStats{
+		Hits:      5,
+		Misses:    2,
+		Evictions: 1,
+		Len:       2,
+	}
+	got := c.Stats()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%T.Stats() diff (-want +got):\n%s", c, diff)
+	}
+	if got, want := got.HitRate(), 5.0/7; got != want {
+		t.Errorf("%T.Stats().HitRate() got %f; want %f", c, got, want)
+	}
+}
+
+func TestBlockCacheConfirmations(t *testing.T) {
+	c := NewBlockCache(10, WithConfirmations(5))
+	b := ethtest.NewBlock(10, 0)
+
+	c.AddBlock(b)
+	if _, ok := c.Block(10); ok {
+		t.Errorf("%T.Block(10) without observed head got true; want false", c)
+	}
+	if _, ok := c.BlockByHash(b.Hash()); !ok {
+		t.Errorf("%T.BlockByHash() without observed head got false; want true", c)
+	}
+
+	c.ObserveHead(14)
+	c.AddBlock(b)
+	if _, ok := c.Block(10); ok {
+		t.Errorf("%T.Block(10) with 4 confirmations got true; want false", c)
+	}
+
+	c.ObserveHead(15)
+	c.ObserveHead(0) // ignored
+	c.AddBlock(b)
+	if _, ok := c.Block(10); !ok {
+		t.Errorf("%T.Block(10) with 5 confirmations got false; want true", c)
+	}
+	if got, want := c.Stats().Len, 1; got != want {
+		t.Errorf("%T.Stats().Len after adding same block thrice got %d; want %d", c, got, want)
+	}
+}
+
+func TestBlockCacheHeaderUpgrade(t *testing.T) {
+	c := NewBlockCache(10, WithConfirmations(0))
+	b := ethtest.NewBlock(3, 42)
+
+	c.AddHeader(b.Header())
+	if _, ok := c.Header(3); !ok {
+		t.Errorf("%T.Header(3) after AddHeader() got false; want true", c)
+	}
+	if _, ok := c.Block(3); ok {
+		t.Errorf("%T.Block(3) after AddHeader() got true; want false", c)
+	}
+
+	c.AddBlock(b)
+	if _, ok := c.Block(3); !ok {
+		t.Errorf("%T.Block(3) after AddBlock() got false; want true", c)
+	}
+	c.AddHeader(b.Header())
+	if _, ok := c.Block(3); !ok {
+		t.Errorf("%T.Block(3) after AddHeader() of cached block got false; want true", c)
+	}
+	if got, want := c.Stats().Len, 1; got != want {
+		t.Errorf("%T.Stats().Len got %d; want %d", c, got, want)
+	}
+}
+
+func TestCachedBlockFetcher(t *testing.T) {
+	ctx := context.Background()
+
+	times := make(ethtest.BlockTimes, 1000)
+	for i := range times {
+		times[i] = uint64(10 * i)
+	}
+
+	tests := []struct {
+		name string
+		opts []BlockCacheOption
+		// Calls to the underlying BlockFetcher for the second LastBlockBy()
+		// search are a function of whether blocks are cached by number.
+		wantCached bool
+	}{
+		{
+			name:       "no confirmations required",
+			opts:       []BlockCacheOption{WithConfirmations(0)},
+			wantCached: true,
+		},
+		{
+			name:       "all searched blocks confirmed",
+			opts:       []BlockCacheOption{WithConfirmations(10)},
+			wantCached: true,
+		},
+		{
+			name:       "no searched blocks confirmed",
+			opts:       []BlockCacheOption{WithConfirmations(2000)},
+			wantCached: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := &ethtest.CountingBlockFetcher{BlockFetcher: times}
+			cache := NewBlockCache(100, tt.opts...)
+			f := cache.Fetcher(counter)
+
+			const minedBy = 4321
+			search := func() int {
+				t.Helper()
+				before := counter.TotalCalls()
+				got, err := LastBlockBy(ctx, f, minedBy, nil)
+				if err != nil {
+					t.Fatalf("LastBlockBy(ctx, %T, %d, nil) error %v", f, minedBy, err)
+				}
+				if got, want := got.NumberU64(), uint64(432); got != want {
+					t.Fatalf("LastBlockBy(ctx, %T, %d, nil) got block %d; want %d", f, minedBy, got, want)
+				}
+				return counter.TotalCalls() - before
+			}
+
+			first := search()
+			if first == 0 {
+				t.Fatalf("first LastBlockBy() made no calls to underlying %T", counter)
+			}
+			second := search()
+			if gotCached := second == 0; gotCached != tt.wantCached {
+				t.Errorf("second LastBlockBy() made %d calls to underlying %T, first made %d; want fully cached = %t", second, counter, first, tt.wantCached)
+			}
+
+			hdr, err := f.HeaderByNumber(ctx, big.NewInt(432))
+			if err != nil {
+				t.Fatalf("%T.HeaderByNumber(432) error %v", f, err)
+			}
+			if got, want := hdr.Time, uint64(4320); got != want {
+				t.Errorf("%T.HeaderByNumber(432) got time %d; want %d", f, got, want)
+			}
+		})
+	}
+}
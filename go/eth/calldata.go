@@ -0,0 +1,301 @@
+package eth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// ParseMethod parses a human-readable function signature, as found in Solidity
+// source or Etherscan, into an abi.Method. Parameter names, data locations, a
+// leading "function" keyword, trailing modifiers, and a "returns (…)" clause
+// are all optional, and tuples MAY be nested; for example, each of the
+// following is valid:
+//
+//	transfer(address,uint256)
+//	transfer(address to, uint256 amount)
+//	function transfer(address to, uint256 amount) external returns (bool)
+//	mint((address to, uint96 qty)[] calldata orders)
+//
+// Unnamed tuple components are named field0, field1, etc. as go-ethereum
+// requires names for tuple components.
+func ParseMethod(sig string) (*abi.Method, error) {
+	s := strings.TrimSpace(sig)
+	s = strings.TrimSpace(strings.TrimPrefix(s, "function "))
+
+	open := strings.IndexByte(s, '(')
+	if open <= 0 {
+		return nil, fmt.Errorf("signature %q: missing function name or parameters", sig)
+	}
+	name := strings.TrimSpace(s[:open])
+	if strings.ContainsAny(name, " \t,)") {
+		return nil, fmt.Errorf("signature %q: invalid function name %q", sig, name)
+	}
+
+	closing, err := matchingParen(s, open)
+	if err != nil {
+		return nil, fmt.Errorf("signature %q: %v", sig, err)
+	}
+	inputs, err := parseArguments(s[open+1 : closing])
+	if err != nil {
+		return nil, fmt.Errorf("signature %q: parsing inputs: %v", sig, err)
+	}
+
+	var outputs abi.Arguments
+	rest := s[closing+1:]
+	if i := strings.Index(rest, "returns"); i != -1 {
+		rest = strings.TrimSpace(rest[i+len("returns"):])
+		if !strings.HasPrefix(rest, "(") {
+			return nil, fmt.Errorf("signature %q: returns clause missing parentheses", sig)
+		}
+		end, err := matchingParen(rest, 0)
+		if err != nil {
+			return nil, fmt.Errorf("signature %q: returns clause: %v", sig, err)
+		}
+		outputs, err = parseArguments(rest[1:end])
+		if err != nil {
+			return nil, fmt.Errorf("signature %q: parsing outputs: %v", sig, err)
+		}
+	}
+
+	m := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, outputs)
+	return &m, nil
+}
+
+// MustParseMethod is equivalent to ParseMethod(), but panics on error. It is
+// intended for use with constant signatures.
+func MustParseMethod(sig string) *abi.Method {
+	m, err := ParseMethod(sig)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// matchingParen returns the index of the parenthesis closing the one at s[open].
+func matchingParen(s string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unbalanced parentheses in %q", s[open:])
+}
+
+// splitTopLevel splits s on commas that aren't nested within parentheses.
+func splitTopLevel(s string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseArguments parses a comma-separated list of parameters, excluding the
+// enclosing parentheses.
+func parseArguments(s string) (abi.Arguments, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var args abi.Arguments
+	for _, p := range splitTopLevel(s) {
+		m, err := parseParam(p)
+		if err != nil {
+			return nil, err
+		}
+		t, err := abi.NewType(m.Type, "", m.Components)
+		if err != nil {
+			return nil, fmt.Errorf("abi.NewType(%q, …): %v", m.Type, err)
+		}
+		args = append(args, abi.Argument{Name: m.Name, Type: t})
+	}
+	return args, nil
+}
+
+// parseParam parses a single parameter, including its optional name.
+func parseParam(p string) (abi.ArgumentMarshaling, error) {
+	var m abi.ArgumentMarshaling
+	p = strings.TrimSpace(p)
+	if p == "" {
+		return m, errors.New("empty parameter")
+	}
+
+	var rest string
+	if strings.HasPrefix(p, "(") || strings.HasPrefix(p, "tuple(") {
+		open := strings.IndexByte(p, '(')
+		closing, err := matchingParen(p, open)
+		if err != nil {
+			return m, err
+		}
+		for i, c := range splitTopLevel(p[open+1 : closing]) {
+			comp, err := parseParam(c)
+			if err != nil {
+				return m, fmt.Errorf("tuple component %d: %v", i, err)
+			}
+			if comp.Name == "" {
+				comp.Name = fmt.Sprintf("field%d", i)
+			}
+			m.Components = append(m.Components, comp)
+		}
+		// Array suffixes MUST directly follow the closing parenthesis.
+		end := closing + 1
+		for end < len(p) && p[end] != ' ' && p[end] != '\t' {
+			end++
+		}
+		m.Type = "tuple" + p[closing+1:end]
+		rest = p[end:]
+	} else {
+		fields := strings.Fields(p)
+		m.Type = fields[0]
+		rest = strings.Join(fields[1:], " ")
+	}
+
+	for _, f := range strings.Fields(rest) {
+		switch f {
+		case "memory", "calldata", "storage", "indexed", "payable":
+			continue
+		}
+		if m.Name != "" {
+			return m, fmt.Errorf("parameter %q: unexpected token %q", p, f)
+		}
+		m.Name = f
+	}
+	return m, nil
+}
+
+// PackCall returns calldata for calling the function with the human-readable
+// signature (see ParseMethod()) with the arguments; i.e. the 4-byte selector
+// followed by the ABI-encoded arguments. Argument types are as expected by
+// go-ethereum's abi package; e.g. *big.Int for uint256.
+func PackCall(sig string, args ...interface{}) ([]byte, error) {
+	m, err := ParseMethod(sig)
+	if err != nil {
+		return nil, err
+	}
+	return PackMethod(m, args...)
+}
+
+// PackMethod is equivalent to PackCall() with an already-parsed method.
+func PackMethod(m *abi.Method, args ...interface{}) ([]byte, error) {
+	packed, err := m.Inputs.Pack(args...)
+	if err != nil {
+		return nil, fmt.Errorf("packing arguments to %s: %v", m.Sig, err)
+	}
+	return append(append([]byte{}, m.ID...), packed...), nil
+}
+
+// A Call is decoded calldata.
+type Call struct {
+	Method *abi.Method
+	Args   []CallArg
+}
+
+// A CallArg is a single decoded argument of a Call.
+type CallArg struct {
+	// Name is the parameter name from the method signature, or argN for the
+	// Nth (zero-indexed) parameter if it is unnamed.
+	Name  string
+	Type  abi.Type
+	Value interface{}
+}
+
+// Arg returns the value of the named argument, and whether it exists.
+func (c *Call) Arg(name string) (interface{}, bool) {
+	for _, a := range c.Args {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// String returns the call in a human-readable form, e.g.
+// transfer(to: 0xa11ce…, amount: 42), intended for debugging output.
+func (c *Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = fmt.Sprintf("%s: %v", a.Name, a.Value)
+	}
+	return fmt.Sprintf("%s(%s)", c.Method.RawName, strings.Join(args, ", "))
+}
+
+// ErrUnknownSelector is returned by DecodeCall() if calldata doesn't match any
+// of the provided signatures.
+var ErrUnknownSelector = errors.New("unknown function selector")
+
+// DecodeCall decodes calldata, or transaction / trace input, into the function
+// arguments. The calldata's selector is matched against each of the
+// human-readable signatures (see ParseMethod()), allowing the input of
+// arbitrary calls to be decoded against a set of known methods. If no
+// signature matches, the returned error wraps ErrUnknownSelector.
+func DecodeCall(data []byte, sigs ...string) (*Call, error) {
+	methods := make([]*abi.Method, len(sigs))
+	for i, sig := range sigs {
+		m, err := ParseMethod(sig)
+		if err != nil {
+			return nil, err
+		}
+		methods[i] = m
+	}
+	return DecodeMethodCall(data, methods...)
+}
+
+// DecodeMethodCall is equivalent to DecodeCall() with already-parsed methods.
+func DecodeMethodCall(data []byte, methods ...*abi.Method) (*Call, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("calldata %#x shorter than 4-byte selector", data)
+	}
+	sel := data[:4]
+
+	for _, m := range methods {
+		if !bytes.Equal(m.ID, sel) {
+			continue
+		}
+		vals, err := m.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, fmt.Errorf("unpacking arguments to %s: %v", m.Sig, err)
+		}
+		c := &Call{
+			Method: m,
+			Args:   make([]CallArg, len(vals)),
+		}
+		for i, v := range vals {
+			in := m.Inputs[i]
+			name := in.Name
+			if name == "" {
+				name = fmt.Sprintf("arg%d", i)
+			}
+			c.Args[i] = CallArg{
+				Name:  name,
+				Type:  in.Type,
+				Value: v,
+			}
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w %#x", ErrUnknownSelector, sel)
+}
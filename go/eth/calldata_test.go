@@ -0,0 +1,180 @@
+package eth_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestParseMethod(t *testing.T) {
+	tests := []struct {
+		sig         string
+		wantSig     string
+		wantID      string
+		wantInputs  []string
+		wantOutputs int
+	}{
+		{
+			sig:        "transfer(address,uint256)",
+			wantSig:    "transfer(address,uint256)",
+			wantID:     "0xa9059cbb",
+			wantInputs: []string{"", ""},
+		},
+		{
+			sig:         "function transfer(address to, uint256 amount) external returns (bool)",
+			wantSig:     "transfer(address,uint256)",
+			wantID:      "0xa9059cbb",
+			wantInputs:  []string{"to", "amount"},
+			wantOutputs: 1,
+		},
+		{
+			sig:        "safeTransferFrom(address from, address to, uint256 tokenId, bytes memory data)",
+			wantSig:    "safeTransferFrom(address,address,uint256,bytes)",
+			wantID:     "0xb88d4fde",
+			wantInputs: []string{"from", "to", "tokenId", "data"},
+		},
+		{
+			sig:        "mint((address to, uint96 qty)[] calldata orders, (uint8,(bool,string)) nested)",
+			wantSig:    "mint((address,uint96)[],(uint8,(bool,string)))",
+			wantInputs: []string{"orders", "nested"},
+		},
+		{
+			sig:     "totalSupply()",
+			wantSig: "totalSupply()",
+			wantID:  "0x18160ddd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sig, func(t *testing.T) {
+			m, err := ParseMethod(tt.sig)
+			if err != nil {
+				t.Fatalf("ParseMethod(%q) error %v", tt.sig, err)
+			}
+			if got := m.Sig; got != tt.wantSig {
+				t.Errorf("ParseMethod(%q).Sig got %q; want %q", tt.sig, got, tt.wantSig)
+			}
+			if tt.wantID != "" {
+				if got := hexutil.Encode(m.ID); got != tt.wantID {
+					t.Errorf("ParseMethod(%q).ID got %s; want %s", tt.sig, got, tt.wantID)
+				}
+			}
+
+			var names []string
+			for _, in := range m.Inputs {
+				names = append(names, in.Name)
+			}
+			if diff := cmp.Diff(tt.wantInputs, names); diff != "" {
+				t.Errorf("ParseMethod(%q) input names diff (-want +got):\n%s", tt.sig, diff)
+			}
+			if got := len(m.Outputs); got != tt.wantOutputs {
+				t.Errorf("len(ParseMethod(%q).Outputs) got %d; want %d", tt.sig, got, tt.wantOutputs)
+			}
+		})
+	}
+
+	for _, sig := range []string{
+		"",
+		"transfer",
+		"(address)",
+		"transfer(address",
+		"transfer(address,)",
+		"transfer(notatype)",
+		"transfer(address to from)",
+		"f() returns bool",
+	} {
+		if _, err := ParseMethod(sig); err == nil {
+			t.Errorf("ParseMethod(%q) got nil error; want non-nil", sig)
+		}
+	}
+}
+
+func TestPackDecodeCall(t *testing.T) {
+	to := common.HexToAddress("0xa11ce")
+	amount := big.NewInt(42)
+
+	data, err := PackCall("transfer(address,uint256)", to, amount)
+	if err != nil {
+		t.Fatalf("PackCall(transfer, %v, %d) error %v", to, amount, err)
+	}
+	const want = "0xa9059cbb" +
+		"00000000000000000000000000000000000000000000000000000000000a11ce" +
+		"000000000000000000000000000000000000000000000000000000000000002a"
+	if got := hexutil.Encode(data); got != want {
+		t.Errorf("PackCall(transfer, %v, %d) got %s; want %s", to, amount, got, want)
+	}
+
+	sigs := []string{
+		"approve(address spender, uint256 amount)",
+		"transfer(address to, uint256 amount)",
+	}
+	call, err := DecodeCall(data, sigs...)
+	if err != nil {
+		t.Fatalf("DecodeCall(%#x, %q) error %v", data, sigs, err)
+	}
+	if got, want := call.Method.RawName, "transfer"; got != want {
+		t.Errorf("DecodeCall(…).Method.RawName got %q; want %q", got, want)
+	}
+	if got, ok := call.Arg("to"); !ok || got != to {
+		t.Errorf("DecodeCall(…).Arg(to) got %v, %t; want %v, true", got, ok, to)
+	}
+	if got, ok := call.Arg("amount"); !ok || got.(*big.Int).Cmp(amount) != 0 {
+		t.Errorf("DecodeCall(…).Arg(amount) got %v, %t; want %d, true", got, ok, amount)
+	}
+	if got, want := call.String(), "transfer(to: "+to.Hex()+", amount: 42)"; got != want {
+		t.Errorf("DecodeCall(…).String() got %q; want %q", got, want)
+	}
+
+	t.Run("unnamed arguments", func(t *testing.T) {
+		call, err := DecodeCall(data, "transfer(address,uint256)")
+		if err != nil {
+			t.Fatalf("DecodeCall(…) error %v", err)
+		}
+		if got, ok := call.Arg("arg0"); !ok || got != to {
+			t.Errorf("DecodeCall(…).Arg(arg0) got %v, %t; want %v, true", got, ok, to)
+		}
+	})
+
+	t.Run("unknown selector", func(t *testing.T) {
+		if _, err := DecodeCall(data, "approve(address,uint256)"); !errors.Is(err, ErrUnknownSelector) {
+			t.Errorf("DecodeCall([transfer], approve) got err %v; want %v", err, ErrUnknownSelector)
+		}
+	})
+
+	t.Run("tuple round trip", func(t *testing.T) {
+		const sig = "mint((address to, uint96 qty)[] orders)"
+		type order struct {
+			To  common.Address `json:"to"`
+			Qty *big.Int       `json:"qty"`
+		}
+		in := []order{{to, big.NewInt(1)}, {common.HexToAddress("0xb0b"), big.NewInt(2)}}
+
+		data, err := PackCall(sig, in)
+		if err != nil {
+			t.Fatalf("PackCall(%q, %+v) error %v", sig, in, err)
+		}
+		call, err := DecodeCall(data, sig)
+		if err != nil {
+			t.Fatalf("DecodeCall(PackCall(%q, …)) error %v", sig, err)
+		}
+		if got, want := len(call.Args), 1; got != want {
+			t.Fatalf("len(DecodeCall(PackCall(%q, …)).Args) got %d; want %d", sig, got, want)
+		}
+		if _, err := call.Method.Inputs.Pack(call.Args[0].Value); err != nil {
+			t.Errorf("re-packing decoded tuple: %v", err)
+		}
+	})
+
+	if _, err := DecodeCall([]byte{1, 2, 3}, sigs...); err == nil {
+		t.Error("DecodeCall([3 bytes]) got nil error; want non-nil")
+	}
+}
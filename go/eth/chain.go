@@ -0,0 +1,229 @@
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// GoerliChainID is the deprecated Goerli testnet, retained for existing
+// deployments. See the ChainID constants for supported networks.
+const GoerliChainID uint64 = 5
+
+// Multicall3Address is the address at which Multicall3 is deployed on (almost)
+// every EVM chain, via a pre-signed transaction.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// A Chain describes constant properties of an EVM chain.
+type Chain struct {
+	ID uint64
+	// Name is a unique, lower-case identifier; e.g. "mainnet".
+	Name string
+	// NativeSymbol is the ticker of the native currency used for gas.
+	NativeSymbol string
+	// BlockTime is the target (or typical) interval between blocks.
+	BlockTime time.Duration
+	// ExplorerURL is the base URL of the canonical block explorer, without a
+	// trailing slash. It MAY be empty.
+	ExplorerURL string
+	// Multicall is the address of a Multicall3 deployment, or the zero address
+	// if there is none.
+	Multicall common.Address
+	// EIP1559 indicates that the chain supports dynamic-fee transactions.
+	EIP1559 bool
+	// OPStack indicates that the chain is built on the OP stack, and therefore
+	// has deposit transactions that go-ethereum can't decode; see OPClient.
+	OPStack bool
+}
+
+// BigID returns c.ID as a *big.Int, as required by many go-ethereum APIs.
+func (c Chain) BigID() *big.Int {
+	return new(big.Int).SetUint64(c.ID)
+}
+
+// String returns the chain's name and ID.
+func (c Chain) String() string {
+	return fmt.Sprintf("%s (%d)", c.Name, c.ID)
+}
+
+// TxURL returns the URL of the transaction on c.ExplorerURL, or an empty
+// string if the chain has no explorer.
+func (c Chain) TxURL(tx common.Hash) string {
+	return c.explorerURL("tx", tx.Hex())
+}
+
+// AddressURL returns the URL of the address on c.ExplorerURL, or an empty
+// string if the chain has no explorer.
+func (c Chain) AddressURL(addr common.Address) string {
+	return c.explorerURL("address", addr.Hex())
+}
+
+func (c Chain) explorerURL(kind, id string) string {
+	if c.ExplorerURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s", c.ExplorerURL, kind, id)
+}
+
+// The chain registry, populated with defaultChains by init() and added to with
+// RegisterChain().
+var (
+	chainsMu     sync.RWMutex
+	chainsByID   = make(map[uint64]Chain)
+	chainsByName = make(map[string]Chain)
+)
+
+var defaultChains = []Chain{
+	{
+		ID:           MainnetChainID,
+		Name:         "mainnet",
+		NativeSymbol: "ETH",
+		BlockTime:    12 * time.Second,
+		ExplorerURL:  "https://etherscan.io",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+	},
+	{
+		ID:           GoerliChainID,
+		Name:         "goerli",
+		NativeSymbol: "ETH",
+		BlockTime:    12 * time.Second,
+		ExplorerURL:  "https://goerli.etherscan.io",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+	},
+	{
+		ID:           SepoliaChainID,
+		Name:         "sepolia",
+		NativeSymbol: "ETH",
+		BlockTime:    12 * time.Second,
+		ExplorerURL:  "https://sepolia.etherscan.io",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+	},
+	{
+		ID:           OptimismChainID,
+		Name:         "optimism",
+		NativeSymbol: "ETH",
+		BlockTime:    2 * time.Second,
+		ExplorerURL:  "https://optimistic.etherscan.io",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+		OPStack:      true,
+	},
+	{
+		ID:           PolygonChainID,
+		Name:         "polygon",
+		NativeSymbol: "POL",
+		BlockTime:    2 * time.Second,
+		ExplorerURL:  "https://polygonscan.com",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+	},
+	{
+		ID:           BaseChainID,
+		Name:         "base",
+		NativeSymbol: "ETH",
+		BlockTime:    2 * time.Second,
+		ExplorerURL:  "https://basescan.org",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+		OPStack:      true,
+	},
+	{
+		ID:           BaseSepoliaChainID,
+		Name:         "base-sepolia",
+		NativeSymbol: "ETH",
+		BlockTime:    2 * time.Second,
+		ExplorerURL:  "https://sepolia.basescan.org",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+		OPStack:      true,
+	},
+	{
+		ID:           ArbitrumChainID,
+		Name:         "arbitrum",
+		NativeSymbol: "ETH",
+		BlockTime:    250 * time.Millisecond,
+		ExplorerURL:  "https://arbiscan.io",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+	},
+	{
+		ID:           ArbitrumSepoliaChainID,
+		Name:         "arbitrum-sepolia",
+		NativeSymbol: "ETH",
+		BlockTime:    250 * time.Millisecond,
+		ExplorerURL:  "https://sepolia.arbiscan.io",
+		Multicall:    Multicall3Address,
+		EIP1559:      true,
+	},
+}
+
+func init() {
+	for _, c := range defaultChains {
+		if err := RegisterChain(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterChain adds the Chain to the registry, making it available to
+// ChainByID() and ChainByName(). Names are case insensitive. It is an error to
+// register a chain with an ID or name that is already registered.
+func RegisterChain(c Chain) error {
+	name := strings.ToLower(c.Name)
+	if name == "" {
+		return fmt.Errorf("registering chain %d: empty name", c.ID)
+	}
+	c.Name = name
+
+	chainsMu.Lock()
+	defer chainsMu.Unlock()
+
+	if existing, ok := chainsByID[c.ID]; ok {
+		return fmt.Errorf("registering chain %v: ID already registered to %v", c, existing)
+	}
+	if existing, ok := chainsByName[name]; ok {
+		return fmt.Errorf("registering chain %v: name already registered to %v", c, existing)
+	}
+	chainsByID[c.ID] = c
+	chainsByName[name] = c
+	return nil
+}
+
+// ChainByID returns the registered Chain with the ID, and a boolean indicating
+// whether it was found.
+func ChainByID(id uint64) (Chain, bool) {
+	chainsMu.RLock()
+	defer chainsMu.RUnlock()
+	c, ok := chainsByID[id]
+	return c, ok
+}
+
+// ChainByName returns the registered Chain with the name, matched case
+// insensitively, and a boolean indicating whether it was found.
+func ChainByName(name string) (Chain, bool) {
+	chainsMu.RLock()
+	defer chainsMu.RUnlock()
+	c, ok := chainsByName[strings.ToLower(name)]
+	return c, ok
+}
+
+// Chains returns all registered Chains, sorted by ID.
+func Chains() []Chain {
+	chainsMu.RLock()
+	defer chainsMu.RUnlock()
+
+	cs := make([]Chain, 0, len(chainsByID))
+	for _, c := range chainsByID {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i].ID < cs[j].ID })
+	return cs
+}
@@ -0,0 +1,119 @@
+package eth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestChainRegistry(t *testing.T) {
+	tests := []struct {
+		id      uint64
+		name    string
+		wantSym string
+	}{
+		{id: MainnetChainID, name: "mainnet", wantSym: "ETH"},
+		{id: GoerliChainID, name: "goerli", wantSym: "ETH"},
+		{id: SepoliaChainID, name: "sepolia", wantSym: "ETH"},
+		{id: OptimismChainID, name: "optimism", wantSym: "ETH"},
+		{id: PolygonChainID, name: "polygon", wantSym: "POL"},
+		{id: BaseChainID, name: "base", wantSym: "ETH"},
+		{id: BaseSepoliaChainID, name: "base-sepolia", wantSym: "ETH"},
+		{id: ArbitrumChainID, name: "arbitrum", wantSym: "ETH"},
+		{id: ArbitrumSepoliaChainID, name: "arbitrum-sepolia", wantSym: "ETH"},
+	}
+
+	for _, tt := range tests {
+		byID, ok := ChainByID(tt.id)
+		if !ok {
+			t.Errorf("ChainByID(%d) got false; want true", tt.id)
+			continue
+		}
+		if byID.Name != tt.name || byID.NativeSymbol != tt.wantSym {
+			t.Errorf("ChainByID(%d) got name %q, symbol %q; want %q, %q", tt.id, byID.Name, byID.NativeSymbol, tt.name, tt.wantSym)
+		}
+		if !byID.EIP1559 || byID.Multicall != Multicall3Address || byID.BlockTime <= 0 {
+			t.Errorf("ChainByID(%d) got %+v; want EIP1559, Multicall3Address and positive block time", tt.id, byID)
+		}
+
+		byName, ok := ChainByName(tt.name)
+		if !ok {
+			t.Errorf("ChainByName(%q) got false; want true", tt.name)
+			continue
+		}
+		if diff := cmp.Diff(byID, byName); diff != "" {
+			t.Errorf("ChainByName(%q) diff (-ChainByID(%d) +ChainByName()):\n%s", tt.name, tt.id, diff)
+		}
+	}
+
+	// Other tests MAY register additional chains.
+	all := Chains()
+	if got, want := len(all), len(tests); got < want {
+		t.Errorf("len(Chains()) got %d; want >= %d", got, want)
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].ID >= all[i].ID {
+			t.Errorf("Chains() not sorted by ID; got %d before %d", all[i-1].ID, all[i].ID)
+		}
+	}
+	if _, ok := ChainByName("MainNet"); !ok {
+		t.Errorf("ChainByName() not case insensitive")
+	}
+	if _, ok := ChainByID(0); ok {
+		t.Errorf("ChainByID(0) got true; want false")
+	}
+}
+
+func TestRegisterChain(t *testing.T) {
+	c := Chain{
+		ID:           1337,
+		Name:         "Sim-Backend",
+		NativeSymbol: "ETH",
+		BlockTime:    time.Second,
+	}
+	if err := RegisterChain(c); err != nil {
+		t.Fatalf("RegisterChain(%+v) error %v", c, err)
+	}
+
+	got, ok := ChainByName("sim-backend")
+	if !ok {
+		t.Fatalf("ChainByName(%q) after RegisterChain() got false; want true", "sim-backend")
+	}
+	if got.Name != "sim-backend" {
+		t.Errorf("RegisterChain() did not lower-case name; got %q", got.Name)
+	}
+	if got.TxURL(common.Hash{}) != "" {
+		t.Errorf("%T.TxURL() with empty ExplorerURL got non-empty string", got)
+	}
+
+	for _, dup := range []Chain{
+		{ID: 1337, Name: "other"},
+		{ID: 1338, Name: "SIM-BACKEND"},
+		{ID: 1339},
+	} {
+		if err := RegisterChain(dup); err == nil {
+			t.Errorf("RegisterChain(%+v) got nil error; want duplicate or empty-name error", dup)
+		}
+	}
+}
+
+func TestChainExplorerURLs(t *testing.T) {
+	c, _ := ChainByID(MainnetChainID)
+
+	addr := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	if got, want := c.AddressURL(addr), "https://etherscan.io/address/"+addr.Hex(); got != want {
+		t.Errorf("%T.AddressURL(%v) got %q; want %q", c, addr, got, want)
+	}
+
+	tx := common.HexToHash("0xabcd")
+	if got, want := c.TxURL(tx), "https://etherscan.io/tx/"+tx.Hex(); got != want {
+		t.Errorf("%T.TxURL(%v) got %q; want %q", c, tx, got, want)
+	}
+}
@@ -2,13 +2,16 @@ package eth
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/url"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/cxkoda/solgo/go/secrets"
 )
@@ -18,11 +21,19 @@ import (
 type Dialer struct {
 	nodeURL    *secrets.Secret
 	secretOpts []secrets.Option
+
+	// resolve, if non-nil, is used instead of nodeURL.
+	resolve func(context.Context) (string, error)
+	// chainID, if non-zero, is the chain of the node, known without dialing.
+	chainID uint64
 }
 
 // DialerFlag is the flag name configured by NewDialerFromFlags.
 const DialerFlag = "dialer_eth_node_url"
 
+// WSDialerFlag is the flag name configured by NewWSDialerFromFlag.
+const WSDialerFlag = "dialer_eth_ws_node_url"
+
 func MustNewDialerFromFlag(fs *flag.FlagSet, defaultNodeURL *secrets.Secret, opts ...secrets.Option) *Dialer {
 	d, err := NewDialerFromFlag(fs, defaultNodeURL, opts...)
 	if err != nil {
@@ -34,6 +45,18 @@ func MustNewDialerFromFlag(fs *flag.FlagSet, defaultNodeURL *secrets.Secret, opt
 // NewDialerFromFlag returns a Dialer that is configurable via command-line
 // flags; see DialerFlag. The Options are propagated when Fetch()ing the Secret.
 func NewDialerFromFlag(fs *flag.FlagSet, defaultNodeURL *secrets.Secret, opts ...secrets.Option) (*Dialer, error) {
+	return newDialerFromFlag(fs, DialerFlag, "URL of Ethereum node to dial, stored as a secrets.Secret; e.g. env://NODE_URL or gcp://path/to/secret", defaultNodeURL, opts...)
+}
+
+// NewWSDialerFromFlag is equivalent to NewDialerFromFlag except that it
+// configures WSDialerFlag, intended for a WebSocket (ws:// or wss://) URL. The
+// two can therefore be used together for a call-based and a streaming
+// consumer; see DialDual().
+func NewWSDialerFromFlag(fs *flag.FlagSet, defaultNodeURL *secrets.Secret, opts ...secrets.Option) (*Dialer, error) {
+	return newDialerFromFlag(fs, WSDialerFlag, "WebSocket URL of Ethereum node to dial for subscriptions, stored as a secrets.Secret; e.g. env://WS_NODE_URL or gcp://path/to/secret", defaultNodeURL, opts...)
+}
+
+func newDialerFromFlag(fs *flag.FlagSet, name, usage string, defaultNodeURL *secrets.Secret, opts ...secrets.Option) (*Dialer, error) {
 	if fs.Parsed() {
 		return nil, fmt.Errorf("%T already parsed", fs)
 	}
@@ -42,7 +65,7 @@ func NewDialerFromFlag(fs *flag.FlagSet, defaultNodeURL *secrets.Secret, opts ..
 	if defaultNodeURL != nil {
 		url = *defaultNodeURL
 	}
-	fs.Var(&url, DialerFlag, "URL of Ethereum node to dial, stored as a secrets.Secret; e.g. env://NODE_URL or gcp://path/to/secret")
+	fs.Var(&url, name, usage)
 
 	return NewDialer(&url, opts...), nil
 }
@@ -52,14 +75,179 @@ func NewDialer(nodeURL *secrets.Secret, opts ...secrets.Option) *Dialer {
 	return &Dialer{nodeURL: nodeURL, secretOpts: opts}
 }
 
+// NewChainDialer returns a Dialer that, at the time of dialing, sources its
+// node URL from RPCURL(chainID, providers). The Options are propagated when
+// Fetch()ing API keys.
+func NewChainDialer(chainID uint64, providers []NodeProvider, opts ...secrets.Option) *Dialer {
+	return &Dialer{
+		secretOpts: opts,
+		resolve: func(ctx context.Context) (string, error) {
+			return RPCURL(ctx, chainID, providers, opts...)
+		},
+		chainID: chainID,
+	}
+}
+
 // Dial Fetch()es the Dialer's secret node URL and returns
-// ethclient.DialContext(ctx, [secret]).
+// ethclient.DialContext(ctx, [secret]). The URL MAY be HTTP(S), WebSocket, or
+// an IPC path; see TransportOf().
 func (c *Dialer) Dial(ctx context.Context) (*ethclient.Client, error) {
+	url, err := c.url(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.DialContext(ctx, url)
+}
+
+// url returns the node URL to be dialed.
+func (c *Dialer) url(ctx context.Context) (string, error) {
+	if c.resolve != nil {
+		return c.resolve(ctx)
+	}
 	url, err := c.nodeURL.Fetch(ctx, c.secretOpts...)
 	if err != nil {
-		return nil, fmt.Errorf("%T(%q).Fetch(…): %v", c.nodeURL, c.nodeURL.String(), err)
+		return "", fmt.Errorf("%T(%q).Fetch(…): %v", c.nodeURL, c.nodeURL.String(), err)
+	}
+	return string(url), nil
+}
+
+// A Transport is the means by which a node URL is dialed.
+type Transport int
+
+// Transports supported by the underlying go-ethereum RPC client.
+const (
+	UnknownTransport Transport = iota
+	HTTPTransport
+	WebSocketTransport
+	IPCTransport
+)
+
+func (t Transport) String() string {
+	switch t {
+	case HTTPTransport:
+		return "HTTP"
+	case WebSocketTransport:
+		return "WebSocket"
+	case IPCTransport:
+		return "IPC"
+	default:
+		return fmt.Sprintf("Transport(%d)", int(t))
+	}
+}
+
+// SupportsSubscriptions returns whether the transport is able to carry
+// subscriptions (e.g. SubscribeFilterLogs()), although the node itself may
+// still not support them; see SupportsSubscriptions().
+func (t Transport) SupportsSubscriptions() bool {
+	return t == WebSocketTransport || t == IPCTransport
+}
+
+// TransportOf returns the Transport that will be used to dial the node URL,
+// detected in the same manner as ethclient.Dial().
+func TransportOf(nodeURL string) (Transport, error) {
+	u, err := url.Parse(nodeURL)
+	if err != nil {
+		return UnknownTransport, fmt.Errorf("url.Parse([node URL]): %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return HTTPTransport, nil
+	case "ws", "wss":
+		return WebSocketTransport, nil
+	case "":
+		return IPCTransport, nil
+	default:
+		return UnknownTransport, fmt.Errorf("unsupported node URL scheme %q", u.Scheme)
+	}
+}
+
+// Transport Fetch()es the Dialer's secret node URL and returns the Transport
+// that Dial() will use.
+func (c *Dialer) Transport(ctx context.Context) (Transport, error) {
+	url, err := c.url(ctx)
+	if err != nil {
+		return UnknownTransport, err
+	}
+	return TransportOf(url)
+}
+
+// SupportsSubscriptions probes the node by subscribing to, and immediately
+// unsubscribing from, new heads. It returns false, without an error, if either
+// the transport or the node doesn't support subscriptions.
+func SupportsSubscriptions(ctx context.Context, client *ethclient.Client) (bool, error) {
+	ch := make(chan *types.Header)
+	sub, err := client.SubscribeNewHead(ctx, ch)
+	var rpcErr rpc.Error
+	switch {
+	case errors.Is(err, rpc.ErrNotificationsUnsupported):
+		return false, nil
+	case errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601: // method not found
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("%T.SubscribeNewHead(): %v", client, err)
+	}
+	sub.Unsubscribe()
+	return true, nil
+}
+
+// A ClientPair is a pair of clients for call-based and streaming consumers.
+// The two MAY be the same client.
+type ClientPair struct {
+	// Call is typically connected over HTTP.
+	Call *ethclient.Client
+	// Subscribe supports subscriptions, as confirmed by
+	// SupportsSubscriptions().
+	Subscribe *ethclient.Client
+}
+
+// Close closes both clients.
+func (p *ClientPair) Close() {
+	p.Call.Close()
+	if p.Subscribe != p.Call {
+		p.Subscribe.Close()
+	}
+}
+
+// DialDual dials both Dialers, returning a ClientPair. If ws is nil, the
+// client dialed by call is also used for subscriptions, which is only possible
+// if it isn't connected over HTTP. An error is returned if the subscription
+// client doesn't SupportsSubscriptions().
+func DialDual(ctx context.Context, call, ws *Dialer) (_ *ClientPair, retErr error) {
+	p := new(ClientPair)
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		if p.Call != nil {
+			p.Call.Close()
+		}
+		if p.Subscribe != nil && p.Subscribe != p.Call {
+			p.Subscribe.Close()
+		}
+	}()
+
+	var err error
+	p.Call, err = call.Dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dialing call client: %v", err)
+	}
+	if ws == nil {
+		p.Subscribe = p.Call
+	} else {
+		p.Subscribe, err = ws.Dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("dialing subscription client: %v", err)
+		}
+	}
+
+	ok, err := SupportsSubscriptions(ctx, p.Subscribe)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("subscription client doesn't support subscriptions; use a WebSocket (ws:// or wss://) or IPC node URL")
 	}
-	return ethclient.DialContext(ctx, string(url))
+	return p, nil
 }
 
 // An RWDemuxBackend splits calls to ContractBackend methods to a read-only and
@@ -4,9 +4,12 @@ import (
 	"context"
 	"flag"
 	"math/big"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/cxkoda/solgo/go/ethtest"
 	"github.com/cxkoda/solgo/go/secrets"
@@ -207,3 +210,96 @@ func TestDemux(t *testing.T) {
 		}
 	})
 }
+
+func TestTransportOf(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Transport
+		wantErr bool
+	}{
+		{url: "https://mainnet.infura.io/v3/key", want: HTTPTransport},
+		{url: "http://localhost:8545", want: HTTPTransport},
+		{url: "wss://mainnet.infura.io/ws/v3/key", want: WebSocketTransport},
+		{url: "ws://localhost:8546", want: WebSocketTransport},
+		{url: "/path/to/geth.ipc", want: IPCTransport},
+		{url: "ftp://localhost", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := TransportOf(tt.url)
+		if gotErr := err != nil; gotErr != tt.wantErr || got != tt.want {
+			t.Errorf("TransportOf(%q) got %v, err %v; want %v, err? %t", tt.url, got, err, tt.want, tt.wantErr)
+		}
+		if !tt.wantErr {
+			if got, want := got.SupportsSubscriptions(), tt.want != HTTPTransport; got != want {
+				t.Errorf("%v.SupportsSubscriptions() got %t; want %t", tt.want, got, want)
+			}
+		}
+	}
+}
+
+// newHeadsService is an RPC service supporting only eth_subscribe("newHeads").
+type newHeadsService struct{}
+
+func (newHeadsService) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	return notifier.CreateSubscription(), nil
+}
+
+func TestDialDual(t *testing.T) {
+	ctx := context.Background()
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", newHeadsService{}); err != nil {
+		t.Fatalf("%T.RegisterName(eth, …) error %v", srv, err)
+	}
+	t.Cleanup(srv.Stop)
+	ws := httptest.NewServer(srv.WebsocketHandler([]string{"*"}))
+	t.Cleanup(ws.Close)
+
+	dialer := func(url string) *Dialer {
+		return NewDialer(&secrets.Secret{Source: secrets.Raw, ID: url})
+	}
+	httpDialer := dialer(ethtest.NewRPCStub(1, 0).ServeHTTP(t))
+	wsDialer := dialer("ws" + strings.TrimPrefix(ws.URL, "http"))
+
+	for d, want := range map[*Dialer]Transport{httpDialer: HTTPTransport, wsDialer: WebSocketTransport} {
+		if got, err := d.Transport(ctx); err != nil || got != want {
+			t.Errorf("%T.Transport() got %v, err %v; want %v, nil err", d, got, err, want)
+		}
+	}
+
+	t.Run("HTTP and WebSocket", func(t *testing.T) {
+		p, err := DialDual(ctx, httpDialer, wsDialer)
+		if err != nil {
+			t.Fatalf("DialDual(ctx, [HTTP], [WS]) error %v", err)
+		}
+		defer p.Close()
+		if id, err := p.Call.ChainID(ctx); err != nil || id.Uint64() != 1 {
+			t.Errorf("DialDual(…).Call.ChainID() got %v, err %v; want 1, nil err", id, err)
+		}
+	})
+
+	t.Run("WebSocket only", func(t *testing.T) {
+		p, err := DialDual(ctx, wsDialer, nil)
+		if err != nil {
+			t.Fatalf("DialDual(ctx, [WS], nil) error %v", err)
+		}
+		defer p.Close()
+		if p.Call != p.Subscribe {
+			t.Errorf("DialDual(ctx, [WS], nil) got different clients; want the same")
+		}
+	})
+
+	t.Run("HTTP only", func(t *testing.T) {
+		if _, err := DialDual(ctx, httpDialer, nil); err == nil {
+			t.Errorf("DialDual(ctx, [HTTP], nil) got nil error; want non-nil")
+		}
+		if _, err := DialDual(ctx, httpDialer, httpDialer); err == nil {
+			t.Errorf("DialDual(ctx, [HTTP], [HTTP]) got nil error; want non-nil")
+		}
+	})
+}
@@ -1,9 +1,12 @@
 package eth
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
 )
 
 // EtherFraction returns numerator/denominator ETH in Wei.
@@ -16,3 +19,88 @@ func EtherFraction(numerator, denominator int64) *big.Int {
 func Ether(e int64) *big.Int {
 	return EtherFraction(e, 1)
 }
+
+// ErrOverflow is returned, wrapped, by checked conversions when a value can't
+// be represented by the destination type. Use errors.Is() to test for it.
+var ErrOverflow = errors.New("integer overflow")
+
+// ToUint64 returns x as a uint64 or an error wrapping ErrOverflow if x is
+// negative or too large. Unlike x.Uint64(), it never silently truncates.
+func ToUint64(x *big.Int) (uint64, error) {
+	if !x.IsUint64() {
+		return 0, fmt.Errorf("%w: %v as uint64", ErrOverflow, x)
+	}
+	return x.Uint64(), nil
+}
+
+// ToInt64 returns x as an int64 or an error wrapping ErrOverflow if x is out of
+// range. Unlike x.Int64(), it never silently truncates.
+func ToInt64(x *big.Int) (int64, error) {
+	if !x.IsInt64() {
+		return 0, fmt.Errorf("%w: %v as int64", ErrOverflow, x)
+	}
+	return x.Int64(), nil
+}
+
+// ToUint256 returns x as a uint256.Int or an error wrapping ErrOverflow if x
+// is negative or at least 2^256.
+func ToUint256(x *big.Int) (*uint256.Int, error) {
+	if x.Sign() < 0 {
+		return nil, fmt.Errorf("%w: negative %v as uint256", ErrOverflow, x)
+	}
+	u, overflow := uint256.FromBig(x)
+	if overflow {
+		return nil, fmt.Errorf("%w: %v as uint256", ErrOverflow, x)
+	}
+	return u, nil
+}
+
+// Uint256ToUint64 returns x as a uint64 or an error wrapping ErrOverflow if x
+// is too large. Unlike x.Uint64(), it never silently truncates.
+func Uint256ToUint64(x *uint256.Int) (uint64, error) {
+	if !x.IsUint64() {
+		return 0, fmt.Errorf("%w: %v as uint64", ErrOverflow, x.Dec())
+	}
+	return x.Uint64(), nil
+}
+
+// ToInt256Bytes returns x as a big-endian, two's complement Solidity int256;
+// i.e. as would be ABI-encoded. An error wrapping ErrOverflow is returned if x
+// is outside of [-2^255, 2^255).
+func ToInt256Bytes(x *big.Int) ([32]byte, error) {
+	var b [32]byte
+	if err := checkIntWidth(x, 256); err != nil {
+		return b, err
+	}
+	y := new(big.Int).Set(x)
+	if y.Sign() < 0 {
+		y.Add(y, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	y.FillBytes(b[:])
+	return b, nil
+}
+
+// FromTwosComplement interprets b as a big-endian, two's complement signed
+// integer with a width of 8*len(b) bits; e.g. 32 bytes for a Solidity int256
+// or 1 for an int8. An error is returned if len(b) isn't a valid Solidity
+// width, i.e. in [1, 32].
+func FromTwosComplement(b []byte) (*big.Int, error) {
+	if n := len(b); n == 0 || n > 32 {
+		return nil, fmt.Errorf("%d-byte two's complement integer; must be 1-32 bytes", n)
+	}
+	x := new(big.Int).SetBytes(b)
+	if b[0]&0x80 != 0 {
+		x.Sub(x, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return x, nil
+}
+
+// checkIntWidth returns an error wrapping ErrOverflow if x can't be represented
+// as a signed integer of the specified number of bits.
+func checkIntWidth(x *big.Int, bits uint) error {
+	limit := new(big.Int).Lsh(big.NewInt(1), bits-1)
+	if x.Cmp(limit) >= 0 || x.Cmp(limit.Neg(limit)) < 0 {
+		return fmt.Errorf("%w: %v as int%d", ErrOverflow, x, bits)
+	}
+	return nil
+}
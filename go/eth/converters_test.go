@@ -0,0 +1,216 @@
+package eth_test
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func bigFromString(t *testing.T, s string) *big.Int {
+	t.Helper()
+	x, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		t.Fatalf("%T.SetString(%q, 0) failed", x, s)
+	}
+	return x
+}
+
+func TestToUint64AndInt64(t *testing.T) {
+	tests := []struct {
+		in                 string
+		wantUint64         uint64
+		wantUint64Overflow bool
+		wantInt64          int64
+		wantInt64Overflow  bool
+	}{
+		{
+			in: "0",
+		},
+		{
+			in:         "42",
+			wantUint64: 42,
+			wantInt64:  42,
+		},
+		{
+			in:                 "-1",
+			wantUint64Overflow: true,
+			wantInt64:          -1,
+		},
+		{
+			in:                "18446744073709551615", // MaxUint64
+			wantUint64:        math.MaxUint64,
+			wantInt64Overflow: true,
+		},
+		{
+			in:                 "18446744073709551616",
+			wantUint64Overflow: true,
+			wantInt64Overflow:  true,
+		},
+		{
+			in:                 "-9223372036854775808", // MinInt64
+			wantUint64Overflow: true,
+			wantInt64:          math.MinInt64,
+		},
+		{
+			in:                 "-9223372036854775809",
+			wantUint64Overflow: true,
+			wantInt64Overflow:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		x := bigFromString(t, tt.in)
+
+		u, err := ToUint64(x)
+		if got := errors.Is(err, ErrOverflow); got != tt.wantUint64Overflow {
+			t.Errorf("ToUint64(%v) got err %v; want ErrOverflow %t", x, err, tt.wantUint64Overflow)
+		}
+		if err == nil && u != tt.wantUint64 {
+			t.Errorf("ToUint64(%v) got %d; want %d", x, u, tt.wantUint64)
+		}
+
+		i, err := ToInt64(x)
+		if got := errors.Is(err, ErrOverflow); got != tt.wantInt64Overflow {
+			t.Errorf("ToInt64(%v) got err %v; want ErrOverflow %t", x, err, tt.wantInt64Overflow)
+		}
+		if err == nil && i != tt.wantInt64 {
+			t.Errorf("ToInt64(%v) got %d; want %d", x, i, tt.wantInt64)
+		}
+	}
+}
+
+func TestToUint256(t *testing.T) {
+	tests := []struct {
+		in           string
+		wantOverflow bool
+	}{
+		{in: "0"},
+		{in: "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{in: "0x10000000000000000000000000000000000000000000000000000000000000000", wantOverflow: true},
+		{in: "-1", wantOverflow: true},
+	}
+
+	for _, tt := range tests {
+		x := bigFromString(t, tt.in)
+		got, err := ToUint256(x)
+		if errors.Is(err, ErrOverflow) != tt.wantOverflow {
+			t.Errorf("ToUint256(%v) got err %v; want ErrOverflow %t", x, err, tt.wantOverflow)
+		}
+		if err != nil {
+			continue
+		}
+		if got.ToBig().Cmp(x) != 0 {
+			t.Errorf("ToUint256(%v) got %v", x, got.Dec())
+		}
+
+		u, err := Uint256ToUint64(got)
+		if wantErr := !x.IsUint64(); errors.Is(err, ErrOverflow) != wantErr {
+			t.Errorf("Uint256ToUint64(%v) got err %v; want ErrOverflow %t", got.Dec(), err, wantErr)
+		}
+		if err == nil && u != x.Uint64() {
+			t.Errorf("Uint256ToUint64(%v) got %d; want %d", got.Dec(), u, x.Uint64())
+		}
+	}
+
+	if _, err := Uint256ToUint64(uint256.NewInt(0).Lsh(uint256.NewInt(1), 64)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Uint256ToUint64(2^64) got err %v; want %v", err, ErrOverflow)
+	}
+}
+
+func TestInt256TwosComplement(t *testing.T) {
+	tests := []struct {
+		in           string
+		want         common.Hash
+		wantOverflow bool
+	}{
+		{
+			in:   "0",
+			want: common.Hash{},
+		},
+		{
+			in:   "1",
+			want: common.HexToHash("0x01"),
+		},
+		{
+			in:   "-1",
+			want: common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		},
+		{
+			in:   "0x7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", // max int256
+			want: common.HexToHash("0x7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"),
+		},
+		{
+			in:   "-0x8000000000000000000000000000000000000000000000000000000000000000", // min int256
+			want: common.HexToHash("0x8000000000000000000000000000000000000000000000000000000000000000"),
+		},
+		{
+			in:           "0x8000000000000000000000000000000000000000000000000000000000000000",
+			wantOverflow: true,
+		},
+		{
+			in:           "-0x8000000000000000000000000000000000000000000000000000000000000001",
+			wantOverflow: true,
+		},
+	}
+
+	for _, tt := range tests {
+		x := bigFromString(t, tt.in)
+		got, err := ToInt256Bytes(x)
+		if errors.Is(err, ErrOverflow) != tt.wantOverflow {
+			t.Errorf("ToInt256Bytes(%v) got err %v; want ErrOverflow %t", x, err, tt.wantOverflow)
+		}
+		if err != nil {
+			continue
+		}
+		if common.Hash(got) != tt.want {
+			t.Errorf("ToInt256Bytes(%v) got %#x; want %v", x, got, tt.want)
+		}
+
+		back, err := FromTwosComplement(got[:])
+		if err != nil {
+			t.Errorf("FromTwosComplement(ToInt256Bytes(%v)) error %v", x, err)
+			continue
+		}
+		if back.Cmp(x) != 0 {
+			t.Errorf("FromTwosComplement(ToInt256Bytes(%v)) got %v; want round trip", x, back)
+		}
+	}
+}
+
+func TestFromTwosComplementWidths(t *testing.T) {
+	tests := []struct {
+		in      []byte
+		want    int64
+		wantErr bool
+	}{
+		{in: []byte{0x7f}, want: 127},
+		{in: []byte{0x80}, want: -128},
+		{in: []byte{0xff}, want: -1},
+		{in: []byte{0xff, 0xfe}, want: -2},
+		{in: []byte{0x00, 0xff}, want: 255},
+		{in: nil, wantErr: true},
+		{in: make([]byte, 33), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := FromTwosComplement(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FromTwosComplement(%#x) got err %v; want err %t", tt.in, err, tt.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if got.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("FromTwosComplement(%#x) got %v; want %d", tt.in, got, tt.want)
+		}
+	}
+}
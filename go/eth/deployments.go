@@ -0,0 +1,201 @@
+package eth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrNotDeployed is returned, wrapped, when a contract has no address on a
+// chain.
+var ErrNotDeployed = errors.New("contract not deployed")
+
+// Deployments is an address book of contracts, keyed by name and then chain
+// ID, typically stored as JSON alongside deployment scripts or embedded in a
+// binary; e.g.
+//
+//	{
+//	  "DelegationRegistry": {"0": "0x00000000000076A84feF008CDAbe6409d2FE638B"},
+//	  "Token": {"1": "0x…", "5": "0x…"}
+//	}
+//
+// As with Labels, chain ID 0 denotes an address that applies to all chains,
+// which is typical of deterministically deployed contracts, and is only used
+// if there is no chain-specific address. A nil *Deployments is valid and
+// empty.
+type Deployments struct {
+	byName map[string]map[uint64]common.Address
+}
+
+var (
+	_ json.Marshaler   = (*Deployments)(nil)
+	_ json.Unmarshaler = (*Deployments)(nil)
+)
+
+// NewDeployments returns an empty Deployments.
+func NewDeployments() *Deployments {
+	return &Deployments{
+		byName: make(map[string]map[uint64]common.Address),
+	}
+}
+
+// LoadDeployments reads JSON Deployments from the file; see
+// DeploymentsFromJSON().
+func LoadDeployments(path string) (*Deployments, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := DeploymentsFromJSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("deployments file %q: %v", path, err)
+	}
+	return d, nil
+}
+
+// DeploymentsFromJSON reads Deployments from r, in the format described in
+// the Deployments documentation.
+func DeploymentsFromJSON(r io.Reader) (*Deployments, error) {
+	d := NewDeployments()
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(d); err != nil {
+		return nil, fmt.Errorf("decoding JSON deployments: %v", err)
+	}
+	return d, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, replacing all existing
+// addresses.
+func (d *Deployments) UnmarshalJSON(buf []byte) error {
+	var raw map[string]map[uint64]common.Address
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return err
+	}
+	d.byName = make(map[string]map[uint64]common.Address)
+	for name, byChain := range raw {
+		for chainID, addr := range byChain {
+			if err := d.Set(name, chainID, addr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, in the format described in the
+// Deployments documentation.
+func (d *Deployments) MarshalJSON() ([]byte, error) {
+	if d == nil || d.byName == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(d.byName)
+}
+
+// Save atomically writes the Deployments to the file as indented JSON, via a
+// temporary file in the same directory, so that it is never left partially
+// written.
+func (d *Deployments) Save(path string) error {
+	buf, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent(%T): %v", d, err)
+	}
+	buf = append(buf, '\n')
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("os.CreateTemp(): %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after successful rename
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing deployments: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing deployments: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("os.Rename(%q, %q): %v", tmp.Name(), path, err)
+	}
+	return nil
+}
+
+// Set records the contract's address on the chain, overwriting any existing
+// one; use chain ID 0 for an address on all chains.
+func (d *Deployments) Set(name string, chainID uint64, addr common.Address) error {
+	if name == "" {
+		return fmt.Errorf("empty contract name for address %v on chain %d", addr, chainID)
+	}
+	if addr == (common.Address{}) {
+		return fmt.Errorf("zero address for contract %q on chain %d", name, chainID)
+	}
+	if d.byName == nil {
+		d.byName = make(map[string]map[uint64]common.Address)
+	}
+	byChain, ok := d.byName[name]
+	if !ok {
+		byChain = make(map[uint64]common.Address)
+		d.byName[name] = byChain
+	}
+	byChain[chainID] = addr
+	return nil
+}
+
+// Lookup returns the contract's address on the chain, falling back to its
+// address on all chains, and a boolean indicating whether one was found.
+func (d *Deployments) Lookup(name string, chainID uint64) (common.Address, bool) {
+	if d == nil {
+		return common.Address{}, false
+	}
+	if addr, ok := d.byName[name][chainID]; ok {
+		return addr, true
+	}
+	addr, ok := d.byName[name][0]
+	return addr, ok
+}
+
+// Address returns the address found by Lookup(), or an error wrapping
+// ErrNotDeployed if there is none.
+func (d *Deployments) Address(name string, chainID uint64) (common.Address, error) {
+	addr, ok := d.Lookup(name, chainID)
+	if !ok {
+		return common.Address{}, fmt.Errorf("%q on chain %d: %w", name, chainID, ErrNotDeployed)
+	}
+	return addr, nil
+}
+
+// Names returns the names of all contracts, in lexical order.
+func (d *Deployments) Names() []string {
+	if d == nil {
+		return nil
+	}
+	names := make([]string, 0, len(d.byName))
+	for n := range d.byName {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BindDeployment binds the named contract, at its Address() on the chain, with
+// an abigen-style constructor; e.g.
+//
+//	reg, err := eth.BindDeployment(d, "DelegationRegistry", chainID, client, delegate.NewIDelegationRegistry)
+func BindDeployment[T any](d *Deployments, name string, chainID uint64, backend bind.ContractBackend, newBinding func(common.Address, bind.ContractBackend) (T, error)) (T, error) {
+	addr, err := d.Address(name, chainID)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return newBinding(addr, backend)
+}
@@ -0,0 +1,162 @@
+package eth_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestDeployments(t *testing.T) {
+	var (
+		registry = common.HexToAddress("0x00000000000076A84feF008CDAbe6409d2FE638B")
+		mainnet  = common.HexToAddress("0x1")
+		goerli   = common.HexToAddress("0x5")
+	)
+
+	const raw = `{
+		"DelegationRegistry": {"0": "0x00000000000076A84feF008CDAbe6409d2FE638B"},
+		"Token": {
+			"1": "0x0000000000000000000000000000000000000001",
+			"5": "0x0000000000000000000000000000000000000005"
+		}
+	}`
+	parsed, err := DeploymentsFromJSON(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("DeploymentsFromJSON(…) error %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "deployments.json")
+	if err := parsed.Save(path); err != nil {
+		t.Fatalf("%T.Save(%q) error %v", parsed, path, err)
+	}
+	loaded, err := LoadDeployments(path)
+	if err != nil {
+		t.Fatalf("LoadDeployments(%q) error %v", path, err)
+	}
+
+	built := NewDeployments()
+	for _, s := range []struct {
+		name    string
+		chainID uint64
+		addr    common.Address
+	}{
+		{"DelegationRegistry", 0, registry},
+		{"Token", 1, mainnet},
+		{"Token", 5, common.HexToAddress("0xdead")},
+		{"Token", 5, goerli}, // overwrites
+	} {
+		if err := built.Set(s.name, s.chainID, s.addr); err != nil {
+			t.Fatalf("%T.Set(%q, %d, %v) error %v", built, s.name, s.chainID, s.addr, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		chainID uint64
+		want    common.Address
+		wantErr error
+	}{
+		{
+			name:    "DelegationRegistry",
+			chainID: 1,
+			want:    registry,
+		},
+		{
+			name:    "DelegationRegistry",
+			chainID: 137,
+			want:    registry,
+		},
+		{
+			name:    "Token",
+			chainID: 1,
+			want:    mainnet,
+		},
+		{
+			name:    "Token",
+			chainID: 5,
+			want:    goerli,
+		},
+		{
+			name:    "Token",
+			chainID: 137,
+			wantErr: ErrNotDeployed,
+		},
+		{
+			name:    "Unknown",
+			chainID: 1,
+			wantErr: ErrNotDeployed,
+		},
+	}
+
+	for desc, d := range map[string]*Deployments{
+		"parsed": parsed,
+		"loaded": loaded,
+		"built":  built,
+	} {
+		t.Run(desc, func(t *testing.T) {
+			if diff := cmp.Diff([]string{"DelegationRegistry", "Token"}, d.Names()); diff != "" {
+				t.Errorf("%T.Names() diff (-want +got):\n%s", d, diff)
+			}
+			for _, tt := range tests {
+				got, err := d.Address(tt.name, tt.chainID)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("%T.Address(%q, %d) got err %v; want %v", d, tt.name, tt.chainID, err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("%T.Address(%q, %d) got %v; want %v", d, tt.name, tt.chainID, got, tt.want)
+				}
+			}
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		var d *Deployments
+		if _, err := d.Address("Token", 1); !errors.Is(err, ErrNotDeployed) {
+			t.Errorf("nil %T.Address() got err %v; want %v", d, err, ErrNotDeployed)
+		}
+	})
+
+	t.Run("BindDeployment", func(t *testing.T) {
+		type binding struct {
+			addr common.Address
+		}
+		newBinding := func(addr common.Address, _ bind.ContractBackend) (*binding, error) {
+			return &binding{addr}, nil
+		}
+
+		got, err := BindDeployment(parsed, "Token", 5, nil, newBinding)
+		if err != nil {
+			t.Fatalf("BindDeployment(…, Token, 5, …) error %v", err)
+		}
+		if got.addr != goerli {
+			t.Errorf("BindDeployment(…, Token, 5, …) bound to %v; want %v", got.addr, goerli)
+		}
+
+		if _, err := BindDeployment(parsed, "Token", 137, nil, newBinding); !errors.Is(err, ErrNotDeployed) {
+			t.Errorf("BindDeployment(…, Token, 137, …) got err %v; want %v", err, ErrNotDeployed)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, raw := range []string{
+			`{"Token": {"mainnet": "0x0000000000000000000000000000000000000001"}}`,
+			`{"Token": {"1": "0x0000000000000000000000000000000000000000"}}`,
+			`{"": {"1": "0x0000000000000000000000000000000000000001"}}`,
+			`[]`,
+		} {
+			if _, err := DeploymentsFromJSON(strings.NewReader(raw)); err == nil {
+				t.Errorf("DeploymentsFromJSON(%s) got nil error", raw)
+			}
+		}
+	})
+}
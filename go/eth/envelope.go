@@ -0,0 +1,205 @@
+package eth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// A PersonalSigner produces EIP-191 personal signatures, as verified by
+// RecoverPersonalSign(). It is implemented by *Signer and, via
+// WithContext(), by ethkms.GCP.
+type PersonalSigner interface {
+	Address() common.Address
+	PersonalSign(message []byte) ([]byte, error)
+}
+
+var _ PersonalSigner = (*Signer)(nil)
+
+// EnvelopeTypeHash is the first word of every encoded Envelope, allowing
+// contracts to reject signatures over other messages that happen to be
+// ABI-encoded with the same types.
+var EnvelopeTypeHash = crypto.Keccak256Hash([]byte("Envelope(uint256 chainId,string purpose,uint64 expiry,bytes payload)"))
+
+// An Envelope binds an arbitrary payload to a chain, a purpose, and an optional
+// expiry so that a signature over it can't be replayed on another chain, in
+// another context, or indefinitely. Oracles that sign off-chain data SHOULD
+// sign Envelopes instead of ad-hoc concatenations of their payload.
+//
+// The encoding, and hence the signed message, is
+// abi.encode(EnvelopeTypeHash, chainId, purpose, expiry, payload), which can be
+// reproduced and verified on-chain.
+type Envelope struct {
+	ChainID *big.Int
+	// Purpose identifies the context in which the signature is valid; e.g.
+	// "entropy". Verifiers MUST reject unexpected purposes.
+	Purpose string
+	// Expiry is truncated to seconds when encoded. The zero value means that
+	// the Envelope doesn't expire.
+	Expiry  time.Time
+	Payload []byte
+}
+
+// envelopeArgs are the ABI types of an encoded Envelope.
+var envelopeArgs = mustEnvelopeArgs()
+
+func mustEnvelopeArgs() abi.Arguments {
+	var args abi.Arguments
+	for _, typ := range []string{"bytes32", "uint256", "string", "uint64", "bytes"} {
+		t, err := abi.NewType(typ, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		args = append(args, abi.Argument{Type: t})
+	}
+	return args
+}
+
+// expiryUnix returns the Expiry as seconds since the Unix epoch, or 0 if it is
+// the zero time.
+func (e *Envelope) expiryUnix() (uint64, error) {
+	if e.Expiry.IsZero() {
+		return 0, nil
+	}
+	u := e.Expiry.Unix()
+	if u <= 0 {
+		return 0, fmt.Errorf("%T.Expiry %v not after the Unix epoch", e, e.Expiry)
+	}
+	return uint64(u), nil
+}
+
+// Encode returns the deterministic encoding of the Envelope, which is the
+// message signed by Sign().
+func (e *Envelope) Encode() ([]byte, error) {
+	if e.ChainID == nil || e.ChainID.Sign() <= 0 {
+		return nil, fmt.Errorf("%T.ChainID %v; MUST be positive", e, e.ChainID)
+	}
+	if e.Purpose == "" {
+		return nil, fmt.Errorf("empty %T.Purpose", e)
+	}
+	expiry, err := e.expiryUnix()
+	if err != nil {
+		return nil, err
+	}
+	payload := e.Payload
+	if payload == nil {
+		payload = []byte{}
+	}
+
+	buf, err := envelopeArgs.Pack(EnvelopeTypeHash, e.ChainID, e.Purpose, expiry, payload)
+	if err != nil {
+		return nil, fmt.Errorf("packing %T: %v", e, err)
+	}
+	return buf, nil
+}
+
+// DecodeEnvelope is the inverse of Envelope.Encode(). Encodings that aren't
+// canonical, i.e. that Encode() wouldn't produce, are rejected.
+func DecodeEnvelope(buf []byte) (*Envelope, error) {
+	vals, err := envelopeArgs.Unpack(buf)
+	if err != nil {
+		return nil, fmt.Errorf("unpacking Envelope: %v", err)
+	}
+	if typeHash := common.Hash(vals[0].([32]byte)); typeHash != EnvelopeTypeHash {
+		return nil, fmt.Errorf("Envelope type hash %v; want %v", typeHash, EnvelopeTypeHash)
+	}
+
+	e := &Envelope{
+		ChainID: vals[1].(*big.Int),
+		Purpose: vals[2].(string),
+		Payload: vals[4].([]byte),
+	}
+	if exp := vals[3].(uint64); exp != 0 {
+		e.Expiry = time.Unix(int64(exp), 0)
+	}
+
+	reenc, err := e.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(reenc, buf) {
+		return nil, errors.New("non-canonical Envelope encoding")
+	}
+	return e, nil
+}
+
+// Sign returns the Envelope's encoding and the signer's personal signature of
+// it.
+func (e *Envelope) Sign(s PersonalSigner) (encoded, sig []byte, _ error) {
+	buf, err := e.Encode()
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err = s.PersonalSign(buf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%T.PersonalSign([%T]): %v", s, e, err)
+	}
+	return buf, sig, nil
+}
+
+// ErrEnvelopeExpired is returned, wrapped, by EnvelopeVerifier.Verify() if the
+// signature is valid but the Envelope's Expiry has passed.
+var ErrEnvelopeExpired = errors.New("envelope expired")
+
+// An EnvelopeVerifier verifies signed Envelopes; see Verify().
+type EnvelopeVerifier struct {
+	// ChainID and Purpose MUST equal those of verified Envelopes.
+	ChainID *big.Int
+	Purpose string
+	// Signers are the addresses trusted to sign Envelopes.
+	Signers *AddressSet
+	// RequireExpiry, if true, rejects Envelopes without an Expiry.
+	RequireExpiry bool
+	// Now, if non-nil, replaces time.Now() when checking expiry.
+	Now func() time.Time
+}
+
+// Verify decodes the Envelope and returns it if and only if:
+//
+//   - sig is a personal signature of encoded by one of v.Signers;
+//   - the Envelope's chain ID and purpose match those of v; and
+//   - the Envelope hasn't expired.
+//
+// Errors due to invalid or untrusted signatures, or to mismatched fields, wrap
+// ErrInvalidSignature; those due to expiry wrap ErrEnvelopeExpired instead.
+func (v *EnvelopeVerifier) Verify(encoded, sig []byte) (*Envelope, error) {
+	e, err := DecodeEnvelope(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	signer, err := RecoverPersonalSign(encoded, sig)
+	if err != nil {
+		return nil, err
+	}
+	if v.Signers == nil || !v.Signers.ContainsAddress(signer) {
+		return nil, fmt.Errorf("%w: Envelope signed by untrusted %v", ErrInvalidSignature, signer)
+	}
+
+	switch {
+	case v.ChainID == nil || e.ChainID.Cmp(v.ChainID) != 0:
+		return nil, fmt.Errorf("%w: Envelope for chain %d; want %d", ErrInvalidSignature, e.ChainID, v.ChainID)
+	case e.Purpose != v.Purpose:
+		return nil, fmt.Errorf("%w: Envelope purpose %q; want %q", ErrInvalidSignature, e.Purpose, v.Purpose)
+	case e.Expiry.IsZero():
+		if v.RequireExpiry {
+			return nil, fmt.Errorf("%w: Envelope without expiry", ErrInvalidSignature)
+		}
+		return e, nil
+	}
+
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if t := now(); !t.Before(e.Expiry) {
+		return nil, fmt.Errorf("%w at %v; now %v", ErrEnvelopeExpired, e.Expiry, t)
+	}
+	return e, nil
+}
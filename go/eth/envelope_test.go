@@ -0,0 +1,255 @@
+package eth_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestEnvelopeEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		env  *Envelope
+	}{
+		{
+			name: "no expiry",
+			env: &Envelope{
+				ChainID: big.NewInt(1),
+				Purpose: "entropy",
+				Payload: []byte("hello"),
+			},
+		},
+		{
+			name: "with expiry",
+			env: &Envelope{
+				ChainID: big.NewInt(8453),
+				Purpose: "price",
+				Expiry:  time.Unix(1_700_000_000, 0),
+				Payload: common.Hash{42}.Bytes(),
+			},
+		},
+		{
+			name: "empty payload",
+			env: &Envelope{
+				ChainID: big.NewInt(5),
+				Purpose: "ping",
+				Payload: []byte{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := tt.env.Encode()
+			if err != nil {
+				t.Fatalf("%+v.Encode() error %v", tt.env, err)
+			}
+			again, err := tt.env.Encode()
+			if err != nil || string(again) != string(buf) {
+				t.Fatalf("%+v.Encode() not deterministic; got %#x and %#x (err %v)", tt.env, buf, again, err)
+			}
+
+			got, err := DecodeEnvelope(buf)
+			if err != nil {
+				t.Fatalf("DecodeEnvelope(%+v.Encode()) error %v", tt.env, err)
+			}
+			if got.ChainID.Cmp(tt.env.ChainID) != 0 || got.Purpose != tt.env.Purpose || !got.Expiry.Equal(tt.env.Expiry) || string(got.Payload) != string(tt.env.Payload) {
+				t.Errorf("DecodeEnvelope(%+v.Encode()) got %+v; want equivalent", tt.env, got)
+			}
+		})
+	}
+}
+
+func TestEnvelopeEncodeErrors(t *testing.T) {
+	for _, env := range []*Envelope{
+		{Purpose: "missing chain"},
+		{ChainID: big.NewInt(0), Purpose: "zero chain"},
+		{ChainID: big.NewInt(1)},
+		{ChainID: big.NewInt(1), Purpose: "pre-epoch", Expiry: time.Unix(-1, 0)},
+	} {
+		if _, err := env.Encode(); err == nil {
+			t.Errorf("%+v.Encode() got nil error; want non-nil", env)
+		}
+	}
+}
+
+func TestDecodeEnvelopeRejectsNonCanonical(t *testing.T) {
+	env := &Envelope{
+		ChainID: big.NewInt(1),
+		Purpose: "entropy",
+		Payload: []byte("hello"),
+	}
+	buf, err := env.Encode()
+	if err != nil {
+		t.Fatalf("%+v.Encode() error %v", env, err)
+	}
+
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{
+			name: "trailing bytes",
+			buf:  append(append([]byte{}, buf...), make([]byte, 32)...),
+		},
+		{
+			name: "wrong type hash",
+			buf: func() []byte {
+				b := append([]byte{}, buf...)
+				b[0] ^= 1
+				return b
+			}(),
+		},
+		{
+			name: "truncated",
+			buf:  buf[:len(buf)-32],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := DecodeEnvelope(tt.buf); err == nil {
+				t.Errorf("DecodeEnvelope(%#x) got %+v, nil error; want error", tt.buf, got)
+			}
+		})
+	}
+}
+
+func TestEnvelopeVerifier(t *testing.T) {
+	signer, err := NewSigner(256)
+	if err != nil {
+		t.Fatalf("NewSigner(256) error %v", err)
+	}
+	other, err := NewSigner(256)
+	if err != nil {
+		t.Fatalf("NewSigner(256) error %v", err)
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	verifier := &EnvelopeVerifier{
+		ChainID: big.NewInt(1),
+		Purpose: "entropy",
+		Signers: NewAddressSet([]common.Address{signer.Address()}),
+		Now:     func() time.Time { return now },
+	}
+
+	valid := func() *Envelope {
+		return &Envelope{
+			ChainID: big.NewInt(1),
+			Purpose: "entropy",
+			Expiry:  now.Add(time.Minute),
+			Payload: []byte("payload"),
+		}
+	}
+
+	tests := []struct {
+		name          string
+		env           *Envelope
+		signer        PersonalSigner
+		requireExpiry bool
+		wantErrIs     error
+	}{
+		{
+			name:   "valid",
+			env:    valid(),
+			signer: signer,
+		},
+		{
+			name: "valid without expiry",
+			env: func() *Envelope {
+				e := valid()
+				e.Expiry = time.Time{}
+				return e
+			}(),
+			signer: signer,
+		},
+		{
+			name: "expiry required",
+			env: func() *Envelope {
+				e := valid()
+				e.Expiry = time.Time{}
+				return e
+			}(),
+			signer:        signer,
+			requireExpiry: true,
+			wantErrIs:     ErrInvalidSignature,
+		},
+		{
+			name:      "untrusted signer",
+			env:       valid(),
+			signer:    other,
+			wantErrIs: ErrInvalidSignature,
+		},
+		{
+			name: "wrong chain",
+			env: func() *Envelope {
+				e := valid()
+				e.ChainID = big.NewInt(5)
+				return e
+			}(),
+			signer:    signer,
+			wantErrIs: ErrInvalidSignature,
+		},
+		{
+			name: "wrong purpose",
+			env: func() *Envelope {
+				e := valid()
+				e.Purpose = "price"
+				return e
+			}(),
+			signer:    signer,
+			wantErrIs: ErrInvalidSignature,
+		},
+		{
+			name: "expired",
+			env: func() *Envelope {
+				e := valid()
+				e.Expiry = now
+				return e
+			}(),
+			signer:    signer,
+			wantErrIs: ErrEnvelopeExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, sig, err := tt.env.Sign(tt.signer)
+			if err != nil {
+				t.Fatalf("%+v.Sign(%T) error %v", tt.env, tt.signer, err)
+			}
+
+			v := *verifier
+			v.RequireExpiry = tt.requireExpiry
+			got, err := v.Verify(buf, sig)
+			if !errors.Is(err, tt.wantErrIs) || (tt.wantErrIs != nil && err == nil) {
+				t.Fatalf("%T.Verify() got err %v; want errors.Is(…, %v)", v, err, tt.wantErrIs)
+			}
+			if err != nil {
+				return
+			}
+			if string(got.Payload) != string(tt.env.Payload) {
+				t.Errorf("%T.Verify() got payload %q; want %q", v, got.Payload, tt.env.Payload)
+			}
+		})
+	}
+
+	t.Run("tampered payload", func(t *testing.T) {
+		buf, sig, err := valid().Sign(signer)
+		if err != nil {
+			t.Fatalf("%T.Sign() error %v", valid(), err)
+		}
+		buf[len(buf)-1] ^= 1
+		if _, err := verifier.Verify(buf, sig); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%T.Verify([tampered], sig) got err %v; want %v", verifier, err, ErrInvalidSignature)
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	erc20BalanceOfMethod = MustParseMethod("balanceOf(address owner) returns (uint256)")
+	erc20AllowanceMethod = MustParseMethod("allowance(address owner, address spender) returns (uint256)")
+	aggregate3Method     = MustParseMethod("aggregate3((address target, bool allowFailure, bytes callData)[] calls) returns ((bool success, bytes returnData)[] returnData)")
+)
+
+// multicall3Call and multicall3Result mirror the Multicall3 Call3 and Result
+// structs for (un)packing with aggregate3Method.
+type (
+	multicall3Call struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	multicall3Result struct {
+		Success    bool
+		ReturnData []byte
+	}
+)
+
+// DefaultERC20CheckBatchSize is the default value of
+// ERC20Checker.BatchSize.
+const DefaultERC20CheckBatchSize = 500
+
+// An OwnerSpender is an ERC20 token owner and an account that it MAY have
+// approved to spend its tokens.
+type OwnerSpender struct {
+	Owner, Spender common.Address
+}
+
+// An ERC20Position is the balance of an owner, and the amount that a spender
+// is allowed to transfer on its behalf, in a single token.
+type ERC20Position struct {
+	Balance, Allowance *big.Int
+	// Err is non-nil if either call reverted (e.g. the address isn't an ERC20
+	// contract) or returned undecodable data, in which case the respective
+	// value is nil.
+	Err error
+}
+
+// Spendable returns the amount that the spender can transfer from the owner;
+// i.e. the lesser of the balance and allowance. It returns nil if either is
+// unknown.
+func (p ERC20Position) Spendable() *big.Int {
+	if p.Balance == nil || p.Allowance == nil {
+		return nil
+	}
+	if p.Balance.Cmp(p.Allowance) < 0 {
+		return new(big.Int).Set(p.Balance)
+	}
+	return new(big.Int).Set(p.Allowance)
+}
+
+// An ERC20Matrix is the result of ERC20Checker.Check().
+type ERC20Matrix struct {
+	Tokens []common.Address
+	Pairs  []OwnerSpender
+	// Positions[i][j] is the position of Pairs[j] in Tokens[i].
+	Positions [][]ERC20Position
+}
+
+// At returns the position of the pair in the token, and whether both were
+// included in the check.
+func (m *ERC20Matrix) At(token common.Address, pair OwnerSpender) (ERC20Position, bool) {
+	for i, t := range m.Tokens {
+		if t != token {
+			continue
+		}
+		for j, p := range m.Pairs {
+			if p == pair {
+				return m.Positions[i][j], true
+			}
+		}
+	}
+	return ERC20Position{}, false
+}
+
+// Insufficient returns the pairs that can't spend at least amount of the
+// token, including those for which the position is unknown. It is typically
+// used as a pre-flight check before transferring on behalf of many owners.
+func (m *ERC20Matrix) Insufficient(token common.Address, amount *big.Int) []OwnerSpender {
+	var out []OwnerSpender
+	for i, t := range m.Tokens {
+		if t != token {
+			continue
+		}
+		for j, p := range m.Positions[i] {
+			if s := p.Spendable(); s == nil || s.Cmp(amount) < 0 {
+				out = append(out, m.Pairs[j])
+			}
+		}
+	}
+	return out
+}
+
+// An ERC20Checker fetches ERC20 balances and allowances for many tokens,
+// owners, and spenders, aggregating calls with Multicall3.
+type ERC20Checker struct {
+	Backend ethereum.ContractCaller
+	// Multicall is the address of the Multicall3 deployment. If zero,
+	// Multicall3Address is used.
+	Multicall common.Address
+	// BatchSize is the maximum number of calls aggregated into a single
+	// eth_call. If zero, DefaultERC20CheckBatchSize is used.
+	BatchSize int
+}
+
+func (c *ERC20Checker) multicall() common.Address {
+	if c.Multicall == (common.Address{}) {
+		return Multicall3Address
+	}
+	return c.Multicall
+}
+
+func (c *ERC20Checker) batchSize() int {
+	if c.BatchSize <= 0 {
+		return DefaultERC20CheckBatchSize
+	}
+	return c.BatchSize
+}
+
+// Check returns the position of every pair in every token, all at the same
+// block; a nil blockNumber is the latest block. Balances are only fetched once
+// per token and owner, regardless of the number of spenders.
+//
+// Failures of individual calls are reported in the respective
+// ERC20Position.Err whereas the returned error is only non-nil if an
+// aggregated call fails in its entirety.
+func (c *ERC20Checker) Check(ctx context.Context, tokens []common.Address, pairs []OwnerSpender, blockNumber *big.Int) (*ERC20Matrix, error) {
+	m := &ERC20Matrix{
+		Tokens:    tokens,
+		Pairs:     pairs,
+		Positions: make([][]ERC20Position, len(tokens)),
+	}
+
+	// handlers[i] receives the decoded result of calls[i].
+	var (
+		calls    []multicall3Call
+		methods  []*abi.Method
+		handlers []func(*big.Int, error)
+	)
+	add := func(token common.Address, method *abi.Method, handler func(*big.Int, error), args ...interface{}) error {
+		data, err := PackMethod(method, args...)
+		if err != nil {
+			return err
+		}
+		calls = append(calls, multicall3Call{Target: token, AllowFailure: true, CallData: data})
+		methods = append(methods, method)
+		handlers = append(handlers, handler)
+		return nil
+	}
+
+	// Indices of pairs by owner, in order of first appearance.
+	var owners []common.Address
+	byOwner := make(map[common.Address][]int)
+	for j, p := range pairs {
+		if _, ok := byOwner[p.Owner]; !ok {
+			owners = append(owners, p.Owner)
+		}
+		byOwner[p.Owner] = append(byOwner[p.Owner], j)
+	}
+
+	for i, token := range tokens {
+		row := make([]ERC20Position, len(pairs))
+		m.Positions[i] = row
+
+		for _, owner := range owners {
+			idx := byOwner[owner]
+			err := add(token, erc20BalanceOfMethod, func(bal *big.Int, err error) {
+				for _, j := range idx {
+					if err != nil {
+						row[j].Err = err
+						continue
+					}
+					row[j].Balance = new(big.Int).Set(bal)
+				}
+			}, owner)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for j, p := range pairs {
+			pos := &row[j]
+			err := add(token, erc20AllowanceMethod, func(allowance *big.Int, err error) {
+				if err != nil {
+					if pos.Err == nil {
+						pos.Err = err
+					}
+					return
+				}
+				pos.Allowance = allowance
+			}, p.Owner, p.Spender)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for start := 0; start < len(calls); start += c.batchSize() {
+		end := start + c.batchSize()
+		if end > len(calls) {
+			end = len(calls)
+		}
+		results, err := c.aggregate3(ctx, calls[start:end], blockNumber)
+		if err != nil {
+			return nil, err
+		}
+		for k, r := range results {
+			i := start + k
+			handlers[i](decodeUint256(methods[i], calls[i].Target, r))
+		}
+	}
+	return m, nil
+}
+
+// aggregate3 calls Multicall3.aggregate3() with the calls, returning the
+// results in the same order.
+func (c *ERC20Checker) aggregate3(ctx context.Context, calls []multicall3Call, blockNumber *big.Int) ([]multicall3Result, error) {
+	data, err := PackMethod(aggregate3Method, calls)
+	if err != nil {
+		return nil, err
+	}
+	mc := c.multicall()
+	out, err := c.Backend.CallContract(ctx, ethereum.CallMsg{To: &mc, Data: data}, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("%T.CallContract([Multicall3(%v).aggregate3(%d calls)]): %v", c.Backend, mc, len(calls), err)
+	}
+
+	vals, err := aggregate3Method.Outputs.Unpack(out)
+	if err != nil {
+		return nil, fmt.Errorf("unpacking Multicall3.aggregate3() return data: %v", err)
+	}
+	var results []multicall3Result
+	if err := aggregate3Method.Outputs.Copy(&results, vals); err != nil {
+		return nil, fmt.Errorf("copying Multicall3.aggregate3() return values into %T: %v", results, err)
+	}
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("Multicall3.aggregate3() returned %d results for %d calls", len(results), len(calls))
+	}
+	return results, nil
+}
+
+// decodeUint256 decodes the single uint256 returned by the method called on
+// the token.
+func decodeUint256(m *abi.Method, token common.Address, r multicall3Result) (*big.Int, error) {
+	if !r.Success {
+		return nil, fmt.Errorf("%v.%s() reverted", token, m.Name)
+	}
+	vals, err := m.Outputs.Unpack(r.ReturnData)
+	if err != nil {
+		return nil, fmt.Errorf("unpacking %v.%s() return data %#x: %v", token, m.Name, r.ReturnData, err)
+	}
+	v, ok := vals[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("%v.%s() unpacked as %T; want %T", token, m.Name, vals[0], v)
+	}
+	return v, nil
+}
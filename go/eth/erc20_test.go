@@ -0,0 +1,230 @@
+package eth_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+const (
+	aggregate3Sig = "aggregate3((address target, bool allowFailure, bytes callData)[] calls) returns ((bool success, bytes returnData)[] returnData)"
+	balanceOfSig  = "balanceOf(address owner) returns (uint256)"
+	allowanceSig  = "allowance(address owner, address spender) returns (uint256)"
+)
+
+type fakeERC20 struct {
+	balances   map[common.Address]int64
+	allowances map[OwnerSpender]int64
+}
+
+// fakeMulticall implements ethereum.ContractCaller, acting as Multicall3 and
+// a set of ERC20 tokens. Addresses without a token revert.
+type fakeMulticall struct {
+	tokens map[common.Address]*fakeERC20
+
+	// batches records the number of calls aggregated into each eth_call, and
+	// calls the number of calls by method name.
+	batches []int
+	calls   map[string]int
+}
+
+func (f *fakeMulticall) CallContract(ctx context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	if msg.To == nil || *msg.To != Multicall3Address {
+		return nil, fmt.Errorf("call to %v; only Multicall3 supported", msg.To)
+	}
+	agg := MustParseMethod(aggregate3Sig)
+	call, err := DecodeMethodCall(msg.Data, agg)
+	if err != nil {
+		return nil, err
+	}
+
+	type call3 struct {
+		Target       common.Address
+		AllowFailure bool
+		CallData     []byte
+	}
+	in := *abi.ConvertType(call.Args[0].Value, new([]call3)).(*[]call3)
+
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.batches = append(f.batches, len(in))
+
+	type result struct {
+		Success    bool
+		ReturnData []byte
+	}
+	out := make([]result, len(in))
+	for i, c := range in {
+		ret, err := f.tokenCall(c.Target, c.CallData)
+		if err != nil {
+			if !c.AllowFailure {
+				return nil, err
+			}
+			continue
+		}
+		out[i] = result{true, ret}
+	}
+	return agg.Outputs.Pack(out)
+}
+
+func (f *fakeMulticall) tokenCall(addr common.Address, data []byte) ([]byte, error) {
+	tok, ok := f.tokens[addr]
+	if !ok {
+		return nil, fmt.Errorf("no token at %v", addr)
+	}
+
+	call, err := DecodeCall(data, balanceOfSig, allowanceSig)
+	if err != nil {
+		return nil, err
+	}
+	f.calls[call.Method.RawName]++
+
+	var v int64
+	switch call.Method.RawName {
+	case "balanceOf":
+		v = tok.balances[call.Args[0].Value.(common.Address)]
+	case "allowance":
+		v = tok.allowances[OwnerSpender{
+			Owner:   call.Args[0].Value.(common.Address),
+			Spender: call.Args[1].Value.(common.Address),
+		}]
+	}
+	return call.Method.Outputs.Pack(big.NewInt(v))
+}
+
+func TestERC20Checker(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		alice   = common.HexToAddress("0xa11ce")
+		bob     = common.HexToAddress("0xb0b")
+		market  = common.HexToAddress("0x3a4e7")
+		airdrop = common.HexToAddress("0xa14d40")
+
+		usdc     = common.HexToAddress("0x05dc")
+		weth     = common.HexToAddress("0x3e74")
+		notERC20 = common.HexToAddress("0xdead")
+	)
+
+	backend := &fakeMulticall{
+		tokens: map[common.Address]*fakeERC20{
+			usdc: {
+				balances: map[common.Address]int64{alice: 100, bob: 5},
+				allowances: map[OwnerSpender]int64{
+					{alice, market}:  1000,
+					{alice, airdrop}: 50,
+					{bob, market}:    10,
+				},
+			},
+			weth: {
+				balances:   map[common.Address]int64{alice: 7},
+				allowances: map[OwnerSpender]int64{{alice, airdrop}: 7},
+			},
+		},
+	}
+
+	tokens := []common.Address{usdc, weth, notERC20}
+	pairs := []OwnerSpender{
+		{alice, market},
+		{alice, airdrop},
+		{bob, market},
+	}
+
+	c := &ERC20Checker{
+		Backend:   backend,
+		BatchSize: 4,
+	}
+	got, err := c.Check(ctx, tokens, pairs, nil)
+	if err != nil {
+		t.Fatalf("%T.Check(%v, %v) error %v", c, tokens, pairs, err)
+	}
+
+	t.Run("positions", func(t *testing.T) {
+		type position struct {
+			Balance, Allowance int64
+			Err                bool
+		}
+		simplify := func(p ERC20Position) position {
+			s := position{Err: p.Err != nil}
+			if p.Balance != nil {
+				s.Balance = p.Balance.Int64()
+			}
+			if p.Allowance != nil {
+				s.Allowance = p.Allowance.Int64()
+			}
+			return s
+		}
+
+		var gotPos [][]position
+		for _, row := range got.Positions {
+			var r []position
+			for _, p := range row {
+				r = append(r, simplify(p))
+			}
+			gotPos = append(gotPos, r)
+		}
+
+		want := [][]position{
+			{{100, 1000, false}, {100, 50, false}, {5, 10, false}},
+			{{7, 0, false}, {7, 7, false}, {0, 0, false}},
+			{{0, 0, true}, {0, 0, true}, {0, 0, true}},
+		}
+		if diff := cmp.Diff(want, gotPos); diff != "" {
+			t.Errorf("%T.Check(%v, %v) positions diff (-want +got):\n%s", c, tokens, pairs, diff)
+		}
+
+		if p, ok := got.At(usdc, OwnerSpender{alice, airdrop}); !ok || simplify(p) != want[0][1] {
+			t.Errorf("%T.At(usdc, alice→airdrop) got %+v, %t; want %+v, true", got, p, ok, want[0][1])
+		}
+		if _, ok := got.At(usdc, OwnerSpender{bob, airdrop}); ok {
+			t.Errorf("%T.At() of unchecked pair got true; want false", got)
+		}
+		if p := got.Positions[2][0]; p.Err == nil || !strings.Contains(p.Err.Error(), "reverted") {
+			t.Errorf("position in non-ERC20 contract got Err %v; want revert", p.Err)
+		}
+	})
+
+	t.Run("calls", func(t *testing.T) {
+		// Two owners and three pairs for each of the three tokens, but the
+		// non-ERC20 contract's calls aren't counted.
+		wantCalls := map[string]int{"balanceOf": 4, "allowance": 6}
+		if diff := cmp.Diff(wantCalls, backend.calls); diff != "" {
+			t.Errorf("token calls diff (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]int{4, 4, 4, 3}, backend.batches); diff != "" {
+			t.Errorf("Multicall3 batch sizes diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Insufficient", func(t *testing.T) {
+		tests := []struct {
+			token  common.Address
+			amount int64
+			want   []OwnerSpender
+		}{
+			{usdc, 5, nil},
+			{usdc, 50, []OwnerSpender{{bob, market}}},
+			{usdc, 51, []OwnerSpender{{alice, airdrop}, {bob, market}}},
+			{weth, 1, []OwnerSpender{{alice, market}, {bob, market}}},
+			{notERC20, 0, pairs},
+		}
+		for _, tt := range tests {
+			if diff := cmp.Diff(tt.want, got.Insufficient(tt.token, big.NewInt(tt.amount))); diff != "" {
+				t.Errorf("%T.Insufficient(%v, %d) diff (-want +got):\n%s", got, tt.token, tt.amount, diff)
+			}
+		}
+	})
+}
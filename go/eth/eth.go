@@ -16,7 +16,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/holiman/uint256"
 
 	"github.com/cxkoda/solgo/go/memconv"
 )
@@ -78,6 +77,9 @@ type BlockRange struct {
 // by the specified unix timestamp, inclusive. If a nil hint is provided, the
 // search defaults to [0,blocks.BlockNumber()]. If hint.Last == 0, it defaults
 // to the latest block.
+//
+// Searches for nearby times fetch many of the same blocks so, if more than one
+// is performed, blocks SHOULD be wrapped with BlockCache.Fetcher().
 func LastBlockBy(ctx context.Context, blocks BlockFetcher, minedBy uint64, hint *BlockRange) (_ *types.Block, retErr error) {
 	if hint == nil {
 		hint = &BlockRange{}
@@ -143,7 +145,7 @@ func LastBlockBy(ctx context.Context, blocks BlockFetcher, minedBy uint64, hint
 // by xor. The raw bits of the resulting uint64 are treated as an int64 passed
 // to rand.NewSource().
 func RandFromHash(h common.Hash) *rand.Rand {
-	u := *uint256.MustFromBig(h.Big()) // h.Big() can't overflow a uint256
+	u := *memconv.Uint256FromWord(h)
 	seed := u[0] ^ u[1] ^ u[2] ^ u[3]
 	src := rand.NewSource(memconv.Cast[uint64, int64](&seed))
 	return rand.New(src)
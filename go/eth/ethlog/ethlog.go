@@ -0,0 +1,354 @@
+// Package ethlog provides structured, leveled logging shared by servers and
+// command-line tools, with fields for common Ethereum values (e.g. chain ID,
+// block number, and transaction hash) and hooks for redacting secrets.
+//
+// Lines are a message followed by logfmt key=value fields; e.g.
+//
+//	Sent block chain_id=1 block=19000000 txs=3
+//
+// # Verbosity policy
+//
+// Levels SHOULD be chosen as follows, so that operators can rely on consistent
+// volume across binaries:
+//   - Error: a failure requiring operator attention, e.g. a request that
+//     couldn't be served for reasons other than the client's input;
+//   - Warning: degraded operation that is automatically recovered from, e.g.
+//     retries and resubscriptions;
+//   - Info: lifecycle events, e.g. configuration at startup, new streams, or
+//     coarse progress of command-line tools;
+//   - Debug: per-unit-of-work progress, e.g. every block or stream message;
+//   - Trace: per-datum detail, e.g. parsing of individual logs.
+//
+// With the Glog Sink (the default), Debug and Trace are glog verbosity levels
+// 1 and 2 respectively, matching the prior convention of V(1) and V(2) logs.
+package ethlog
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/golang/glog"
+)
+
+// A Level is the severity of a log line; see the package documentation for
+// the verbosity policy. Level implements flag.Value for use with the Writer
+// Sink.
+type Level int
+
+// Levels, in increasing order of severity.
+const (
+	Trace Level = iota - 2
+	Debug
+	Info
+	Warning
+	Error
+)
+
+var levelNames = map[Level]string{
+	Trace:   "TRACE",
+	Debug:   "DEBUG",
+	Info:    "INFO",
+	Warning: "WARNING",
+	Error:   "ERROR",
+}
+
+// String returns the upper-case name of the Level.
+func (l Level) String() string {
+	if n, ok := levelNames[l]; ok {
+		return n
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Set parses the Level's name, ignoring case.
+func (l *Level) Set(s string) error {
+	for lvl, n := range levelNames {
+		if strings.EqualFold(s, n) {
+			*l = lvl
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown log level %q", s)
+}
+
+// A Field is a key-value pair attached to a log line.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Any returns a Field with an arbitrary value, formatted with fmt.Sprint()
+// unless it is an error, common.Address, or common.Hash.
+func Any(key string, val any) Field {
+	return Field{Key: key, Value: val}
+}
+
+// String returns a Field with a string value.
+func String(key, val string) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Int returns a Field with an integer value.
+func Int(key string, val int64) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Uint returns a Field with an unsigned integer value.
+func Uint(key string, val uint64) Field {
+	return Field{Key: key, Value: val}
+}
+
+// ChainID returns a "chain_id" Field.
+func ChainID(id uint64) Field {
+	return Uint("chain_id", id)
+}
+
+// Block returns a "block" Field with the block number.
+func Block(num uint64) Field {
+	return Uint("block", num)
+}
+
+// TxHash returns a "tx" Field with the transaction hash.
+func TxHash(h common.Hash) Field {
+	return Field{Key: "tx", Value: h}
+}
+
+// Address returns a Field with an address value.
+func Address(key string, addr common.Address) Field {
+	return Field{Key: key, Value: addr}
+}
+
+// Err returns an "err" Field.
+func Err(err error) Field {
+	return Field{Key: "err", Value: err}
+}
+
+// URL returns a Field with the scheme and host of the URL, which is otherwise
+// redacted because RPC URLs typically carry API keys in their paths, queries,
+// or user info. If the URL can't be parsed, it is redacted entirely.
+func URL(key, rawURL string) Field {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return String(key, Redacted)
+	}
+	v := u.Scheme + "://" + u.Host
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		v += "/" + Redacted
+	}
+	return String(key, v)
+}
+
+// Redacted replaces the values of redacted Fields.
+const Redacted = "REDACTED"
+
+// A Redactor modifies every Field before it is logged, typically to remove
+// secrets; see RedactKeys().
+type Redactor func(Field) Field
+
+// RedactKeys returns a Redactor that replaces the values of all Fields with
+// any of the keys.
+func RedactKeys(keys ...string) Redactor {
+	set := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		set[k] = true
+	}
+	return func(f Field) Field {
+		if set[f.Key] {
+			f.Value = Redacted
+		}
+		return f
+	}
+}
+
+// A Sink outputs formatted log lines. The depth is the number of stack frames
+// between the Sink's caller and the call site that is logging, allowing Sinks
+// to report the latter (and glog to apply -vmodule).
+//
+// Loggers only format, and Log(), lines for which Enabled() returns true, so
+// disabled Debug and Trace logs are cheap enough for hot paths.
+type Sink interface {
+	Enabled(depth int, lvl Level) bool
+	Log(depth int, lvl Level, line string)
+}
+
+// Glog is a Sink that logs with glog; see the package documentation re
+// verbosity.
+var Glog Sink = glogSink{}
+
+type glogSink struct{}
+
+func (glogSink) Enabled(depth int, lvl Level) bool {
+	switch lvl {
+	case Trace:
+		return bool(glog.VDepth(depth+1, 2))
+	case Debug:
+		return bool(glog.VDepth(depth+1, 1))
+	}
+	return true
+}
+
+func (glogSink) Log(depth int, lvl Level, line string) {
+	d := depth + 1
+	switch lvl {
+	case Warning:
+		glog.WarningDepth(d, line)
+	case Error:
+		glog.ErrorDepth(d, line)
+	default:
+		glog.InfoDepth(d, line)
+	}
+}
+
+// Writer returns a Sink that writes lines of at least the minimum Level to w,
+// prefixed with a timestamp and the Level. It is intended for command-line
+// tools, which typically write logs to stderr, whereas glog writes to files by
+// default. The Sink is safe for concurrent use.
+func Writer(w io.Writer, min Level) Sink {
+	return &writerSink{w: w, min: &min, now: time.Now}
+}
+
+// NewFromFlag registers a -log_level flag on the FlagSet and returns a Logger
+// with a Writer Sink that outputs to w, typically os.Stderr, at the parsed
+// Level (default Info). The Level is read when logging so the Logger MAY be
+// created before the FlagSet is parsed.
+func NewFromFlag(fs *flag.FlagSet, w io.Writer) *Logger {
+	min := Info
+	fs.Var(&min, "log_level", "Minimum level of logs written to stderr; one of TRACE, DEBUG, INFO, WARNING, or ERROR")
+	return New(&writerSink{w: w, min: &min, now: time.Now})
+}
+
+type writerSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	min *Level
+	now func() time.Time
+}
+
+func (s *writerSink) Enabled(_ int, lvl Level) bool {
+	return lvl >= *s.min
+}
+
+func (s *writerSink) Log(_ int, lvl Level, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "%s %s %s\n", s.now().UTC().Format(time.RFC3339), lvl, line)
+}
+
+// A Logger formats messages and Fields, passing the resulting lines to a Sink.
+// Loggers are immutable and safe for concurrent use.
+type Logger struct {
+	sink      Sink
+	fields    []Field
+	redactors []Redactor
+}
+
+// New returns a Logger that outputs to the Sink.
+func New(s Sink) *Logger {
+	return &Logger{sink: s}
+}
+
+// std is returned by Default().
+var std = New(Glog)
+
+// Default returns a Logger that outputs to the Glog Sink.
+func Default() *Logger {
+	return std
+}
+
+// With returns a copy of the Logger that includes the Fields in every line,
+// before those passed to individual calls.
+func (l *Logger) With(fields ...Field) *Logger {
+	c := *l
+	c.fields = append(append([]Field{}, l.fields...), fields...)
+	return &c
+}
+
+// WithRedactors returns a copy of the Logger that applies the Redactors, in
+// order and after any existing ones, to every Field.
+func (l *Logger) WithRedactors(rs ...Redactor) *Logger {
+	c := *l
+	c.redactors = append(append([]Redactor{}, l.redactors...), rs...)
+	return &c
+}
+
+// Error logs the message and Fields at the Error Level.
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.log(Error, msg, fields)
+}
+
+// Warning logs the message and Fields at the Warning Level.
+func (l *Logger) Warning(msg string, fields ...Field) {
+	l.log(Warning, msg, fields)
+}
+
+// Info logs the message and Fields at the Info Level.
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.log(Info, msg, fields)
+}
+
+// Debug logs the message and Fields at the Debug Level.
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.log(Debug, msg, fields)
+}
+
+// Trace logs the message and Fields at the Trace Level.
+func (l *Logger) Trace(msg string, fields ...Field) {
+	l.log(Trace, msg, fields)
+}
+
+// log MUST only be called directly by the exported logging methods so that
+// the reported call site is correct.
+func (l *Logger) log(lvl Level, msg string, fields []Field) {
+	// Frames: log() and the exported method.
+	const depth = 2
+	if !l.sink.Enabled(depth, lvl) {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for _, fs := range [][]Field{l.fields, fields} {
+		for _, f := range fs {
+			for _, r := range l.redactors {
+				f = r(f)
+			}
+			b.WriteByte(' ')
+			b.WriteString(f.Key)
+			b.WriteByte('=')
+			b.WriteString(formatValue(f.Value))
+		}
+	}
+	l.sink.Log(depth, lvl, b.String())
+}
+
+// formatValue returns the logfmt representation of v, quoting it if
+// necessary.
+func formatValue(v any) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		s = "nil"
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case common.Address:
+		s = v.Hex()
+	case common.Hash:
+		s = v.Hex()
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+// Package export writes tabular results of on-chain queries, e.g. token
+// holders or delegations, to CSV, JSON lines, or Google Sheets, formatting
+// Ethereum types consistently across all tools.
+//
+// Values are formatted as follows, regardless of the Sink:
+//   - nil, including typed nil pointers, as an empty cell (JSON null);
+//   - addresses and hashes as checksummed / 0x-prefixed hex;
+//   - *big.Int and *uint256.Int as decimal strings, which are also strings in
+//     JSON to avoid loss of precision by consumers that parse numbers as
+//     float64;
+//   - Go integers, floats, and booleans as decimal / literal values;
+//   - []byte as 0x-prefixed hex;
+//   - types with a MarshalCSV() (string, error) method, e.g. eth.Amount and
+//     eth.Nullable types, with that method (but with MarshalJSON() in JSON
+//     lines, if available);
+//   - fmt.Stringers with String(); and
+//   - all other values with fmt.Sprint().
+package export
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/holiman/uint256"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// A Column describes a single column of output, deriving its value from each
+// row of type T. The typed constructors (e.g. Address(), Uint256()) SHOULD be
+// preferred over populating Value directly as they provide compile-time
+// guarantees of the formatting rules that will be applied.
+type Column[T any] struct {
+	Name  string
+	Value func(T) any
+}
+
+// newColumn returns a Column with Value wrapping fn.
+func newColumn[T, V any](name string, fn func(T) V) Column[T] {
+	return Column[T]{
+		Name:  name,
+		Value: func(row T) any { return fn(row) },
+	}
+}
+
+// Address returns a Column of checksummed hex addresses.
+func Address[T any](name string, fn func(T) common.Address) Column[T] {
+	return newColumn(name, fn)
+}
+
+// Hash returns a Column of 0x-prefixed hex hashes.
+func Hash[T any](name string, fn func(T) common.Hash) Column[T] {
+	return newColumn(name, fn)
+}
+
+// Uint256 returns a Column of decimal integers; nil values are empty.
+func Uint256[T any](name string, fn func(T) *uint256.Int) Column[T] {
+	return newColumn(name, fn)
+}
+
+// BigInt returns a Column of decimal integers; nil values are empty.
+func BigInt[T any](name string, fn func(T) *big.Int) Column[T] {
+	return newColumn(name, fn)
+}
+
+// Uint returns a Column of decimal integers.
+func Uint[T any](name string, fn func(T) uint64) Column[T] {
+	return newColumn(name, fn)
+}
+
+// Int returns a Column of decimal integers.
+func Int[T any](name string, fn func(T) int64) Column[T] {
+	return newColumn(name, fn)
+}
+
+// String returns a Column of verbatim strings.
+func String[T any](name string, fn func(T) string) Column[T] {
+	return newColumn(name, fn)
+}
+
+// Any returns a Column of arbitrary values, formatted according to the rules
+// described in the package documentation.
+func Any[T any](name string, fn func(T) any) Column[T] {
+	return Column[T]{Name: name, Value: fn}
+}
+
+// LabeledAddress returns an Address() Column followed by one holding the name
+// of each address's label on the chain, as found by labels.Name(). The label
+// column is named as for eth.Labels.Annotate(), i.e. with a "_label" suffix.
+//
+// If labels is nil, only the Address() Column is returned, so that labels are
+// only added to outputs of tools when requested.
+func LabeledAddress[T any](name string, fn func(T) common.Address, labels *eth.Labels, chainID uint64) []Column[T] {
+	cols := []Column[T]{Address(name, fn)}
+	if labels == nil {
+		return cols
+	}
+	return append(cols, String(name+"_label", func(row T) string {
+		return labels.Name(chainID, fn(row))
+	}))
+}
+
+// Names returns the Name of each Column.
+func Names[T any](cols []Column[T]) []string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Values returns the Value of each Column for the row.
+func Values[T any](cols []Column[T], row T) []any {
+	vals := make([]any, len(cols))
+	for i, c := range cols {
+		vals[i] = c.Value(row)
+	}
+	return vals
+}
+
+// Write writes a header of the Columns' Names, followed by the Values of every
+// row, to the Sink before flushing it.
+func Write[T any](s Sink, cols []Column[T], rows []T) error {
+	if err := s.Header(Names(cols)); err != nil {
+		return fmt.Errorf("%T.Header(…): %v", s, err)
+	}
+	for i, r := range rows {
+		if err := s.Row(Values(cols, r)); err != nil {
+			return fmt.Errorf("%T.Row([row %d]): %v", s, i, err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		return fmt.Errorf("%T.Flush(): %v", s, err)
+	}
+	return nil
+}
+
+// A csvMarshaler is implemented by types that can be marshalled with gocsv,
+// which predates this package.
+type csvMarshaler interface {
+	MarshalCSV() (string, error)
+}
+
+// isNil reports whether v is nil or a typed nil pointer.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// FormatString returns the string representation of v as written to CSV and
+// Google Sheets; see the package documentation for formatting rules.
+func FormatString(v any) (string, error) {
+	if isNil(v) {
+		return "", nil
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case common.Address:
+		return v.Hex(), nil
+	case *common.Address:
+		return v.Hex(), nil
+	case common.Hash:
+		return v.Hex(), nil
+	case *big.Int:
+		return v.String(), nil
+	case *uint256.Int:
+		return v.Dec(), nil
+	case uint256.Int:
+		return v.Dec(), nil
+	case []byte:
+		return hexutil.Encode(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case csvMarshaler:
+		return v.MarshalCSV()
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	return fmt.Sprint(v), nil
+}
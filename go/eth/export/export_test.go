@@ -0,0 +1,178 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+	"github.com/holiman/uint256"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+var update = flag.Bool("update", false, "Overwrite golden files in testdata/ with current outputs")
+
+// golden compares got to the contents of testdata/name, overwriting the file
+// instead if -update is set.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("os.WriteFile(%q) error %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) error %v", path, err)
+	}
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("output diff against golden file %q (-want +got):\n%s\n\nIf the change is intended, run with -update", path, diff)
+	}
+}
+
+type holding struct {
+	Holder   common.Address
+	Token    common.Address
+	TxHash   common.Hash
+	ID       *uint256.Int
+	Supply   *big.Int
+	Balance  uint64
+	Delta    int64
+	Contract eth.NullableAddress
+	Amount   eth.Amount
+	Note     string
+	Extra    any
+}
+
+func holdingColumns(labels *eth.Labels) []Column[*holding] {
+	cols := LabeledAddress("holder", func(h *holding) common.Address { return h.Holder }, labels, 1)
+	return append(cols,
+		Address("token", func(h *holding) common.Address { return h.Token }),
+		Hash("tx", func(h *holding) common.Hash { return h.TxHash }),
+		Uint256("id", func(h *holding) *uint256.Int { return h.ID }),
+		BigInt("supply", func(h *holding) *big.Int { return h.Supply }),
+		Uint("balance", func(h *holding) uint64 { return h.Balance }),
+		Int("delta", func(h *holding) int64 { return h.Delta }),
+		Any("contract", func(h *holding) any { return h.Contract }),
+		Any("amount", func(h *holding) any { return h.Amount }),
+		String("note", func(h *holding) string { return h.Note }),
+		Any("extra", func(h *holding) any { return h.Extra }),
+	)
+}
+
+func holdings(t *testing.T) []*holding {
+	t.Helper()
+
+	huge, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	if !ok {
+		t.Fatal("big.Int.SetString() failed")
+	}
+	maxUint256 := new(uint256.Int).SetAllOne()
+
+	return []*holding{
+		{
+			Holder:   common.HexToAddress("0x5aeda56215b167893e80b4fe645ba6d5bab767de"),
+			Token:    common.HexToAddress("0xbc4ca0eda7647a8ab7c2061c2e118a18a936f13d"),
+			TxHash:   common.HexToHash("0xdeadbeef"),
+			ID:       uint256.NewInt(42),
+			Supply:   huge,
+			Balance:  3,
+			Delta:    -2,
+			Contract: eth.NullableAddress{Address: common.HexToAddress("0xc0ffee"), Valid: true},
+			Amount:   eth.Amount{Value: *uint256.NewInt(1_500_000), Decimals: 6},
+			Note:     `comma, "quoted"`,
+			Extra:    []byte{0xc0, 0xff, 0xee},
+		},
+		{
+			Holder:   common.HexToAddress("0x000000000000000000000000000000000000dead"),
+			ID:       maxUint256,
+			Contract: eth.NullableAddress{},
+			Amount:   eth.Amount{Decimals: 18},
+			Extra:    true,
+		},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	labels, err := eth.NewLabels([]eth.Label{
+		{Address: common.HexToAddress("0x5aeda56215b167893e80b4fe645ba6d5bab767de"), Name: "vault"},
+	})
+	if err != nil {
+		t.Fatalf("eth.NewLabels(…) error %v", err)
+	}
+
+	tests := []struct {
+		golden  string
+		newSink func(*bytes.Buffer) Sink
+		labels  *eth.Labels
+	}{
+		{
+			golden:  "holdings.csv",
+			newSink: func(b *bytes.Buffer) Sink { return NewCSV(b) },
+		},
+		{
+			golden:  "holdings_labeled.csv",
+			newSink: func(b *bytes.Buffer) Sink { return NewCSV(b) },
+			labels:  labels,
+		},
+		{
+			golden:  "holdings.jsonl",
+			newSink: func(b *bytes.Buffer) Sink { return NewJSONLines(b) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			got := new(bytes.Buffer)
+			s := tt.newSink(got)
+			if err := Write(s, holdingColumns(tt.labels), holdings(t)); err != nil {
+				t.Fatalf("Write(%T, …) error %v", s, err)
+			}
+			golden(t, tt.golden, got.Bytes())
+		})
+	}
+}
+
+func TestSinkErrors(t *testing.T) {
+	for _, s := range []Sink{NewCSV(new(bytes.Buffer)), NewJSONLines(new(bytes.Buffer))} {
+		if err := s.Row([]any{1}); err == nil {
+			t.Errorf("%T.Row() before Header() got nil error", s)
+		}
+		if err := s.Header([]string{"a", "b"}); err != nil {
+			t.Fatalf("%T.Header() error %v", s, err)
+		}
+		if err := s.Row([]any{1}); err == nil {
+			t.Errorf("%T.Row() with fewer values than header got nil error", s)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	var f Format
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&f, "format", "")
+
+	if err := fs.Parse([]string{"-format", "jsonl"}); err != nil {
+		t.Fatalf("%T.Parse(-format jsonl) error %v", fs, err)
+	}
+	s, err := NewSink(f, new(bytes.Buffer))
+	if err != nil {
+		t.Fatalf("NewSink(%q) error %v", f, err)
+	}
+	if _, ok := s.(*JSONLines); !ok {
+		t.Errorf("NewSink(%q) got %T; want %T", f, s, &JSONLines{})
+	}
+
+	if err := f.Set("xlsx"); err == nil {
+		t.Errorf("%T.Set(xlsx) got nil error", f)
+	}
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// A Sheet is a Sink that replaces the contents of a range in a Google Sheets
+// spreadsheet. Rows are buffered until Flush(), which clears the range and
+// then writes all rows in a single request.
+//
+// All values are written as strings formatted with FormatString(), and with
+// the RAW input option, so that large integers aren't truncated to the
+// precision of a float64 and addresses aren't interpreted as numbers.
+type Sheet struct {
+	ctx           context.Context
+	svc           *sheets.Service
+	spreadsheetID string
+	sheetRange    string
+
+	header []string
+	rows   [][]any
+}
+
+var _ Sink = (*Sheet)(nil)
+
+// NewSheet returns a Sheet Sink that writes to the range, in A1 notation
+// (e.g. "Holders!A1" or simply the name of a sheet), of the spreadsheet. The
+// Context is used by Flush().
+func NewSheet(ctx context.Context, svc *sheets.Service, spreadsheetID, sheetRange string) *Sheet {
+	return &Sheet{
+		ctx:           ctx,
+		svc:           svc,
+		spreadsheetID: spreadsheetID,
+		sheetRange:    sheetRange,
+	}
+}
+
+// Header buffers the header row.
+func (s *Sheet) Header(names []string) error {
+	s.header = names
+	row := make([]any, len(names))
+	for i, n := range names {
+		row[i] = n
+	}
+	s.rows = append(s.rows, row)
+	return nil
+}
+
+// Row buffers the values, formatted with FormatString().
+func (s *Sheet) Row(values []any) error {
+	if err := checkRow(s.header, values); err != nil {
+		return err
+	}
+	row := make([]any, len(values))
+	for i, v := range values {
+		str, err := FormatString(v)
+		if err != nil {
+			return fmt.Errorf("column %q: %v", s.header[i], err)
+		}
+		row[i] = str
+	}
+	s.rows = append(s.rows, row)
+	return nil
+}
+
+// Flush clears the range and writes all buffered rows to it.
+func (s *Sheet) Flush() error {
+	vals := s.svc.Spreadsheets.Values
+	if _, err := vals.Clear(s.spreadsheetID, s.sheetRange, &sheets.ClearValuesRequest{}).Context(s.ctx).Do(); err != nil {
+		return fmt.Errorf("clearing range %q of spreadsheet %q: %v", s.sheetRange, s.spreadsheetID, err)
+	}
+
+	vr := &sheets.ValueRange{
+		MajorDimension: "ROWS",
+		Values:         s.rows,
+	}
+	if _, err := vals.Update(s.spreadsheetID, s.sheetRange, vr).ValueInputOption("RAW").Context(s.ctx).Do(); err != nil {
+		return fmt.Errorf("updating range %q of spreadsheet %q: %v", s.sheetRange, s.spreadsheetID, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestSheet(t *testing.T) {
+	ctx := context.Background()
+
+	type request struct {
+		Method, Path, ValueInputOption string
+		Body                           string
+	}
+	var got []request
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("io.ReadAll([request body]) error %v", err)
+		}
+		got = append(got, request{
+			Method:           r.Method,
+			Path:             r.URL.Path,
+			ValueInputOption: r.URL.Query().Get("valueInputOption"),
+			Body:             string(body),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := sheets.NewService(ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("sheets.NewService(…) error %v", err)
+	}
+
+	s := NewSheet(ctx, svc, "sheet-id", "Holders")
+	if err := Write(s, holdingColumns(nil), holdings(t)); err != nil {
+		t.Fatalf("Write(%T, …) error %v", s, err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("%T.Flush() sent %d requests; want 2 (clear then update)", s, len(got))
+	}
+	update := got[1]
+	got[1].Body = ""
+
+	want := []request{
+		{
+			Method: http.MethodPost,
+			Path:   "/v4/spreadsheets/sheet-id/values/Holders:clear",
+			Body:   "{}\n",
+		},
+		{
+			Method:           http.MethodPut,
+			Path:             "/v4/spreadsheets/sheet-id/values/Holders",
+			ValueInputOption: "RAW",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%T.Flush() requests diff (-want +got):\n%s", s, diff)
+	}
+
+	var vr sheets.ValueRange
+	if err := json.Unmarshal([]byte(update.Body), &vr); err != nil {
+		t.Fatalf("json.Unmarshal([update body], %T) error %v", &vr, err)
+	}
+	vals, err := json.MarshalIndent(vr.Values, "", "  ")
+	if err != nil {
+		t.Fatalf("json.MarshalIndent(%T) error %v", vr.Values, err)
+	}
+	golden(t, "holdings_sheet.json", append(vals, '\n'))
+}
@@ -0,0 +1,213 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// A Sink receives tabular output. Header() MUST be called exactly once, before
+// any calls to Row(), and Flush() MUST be called after the last Row(). Rows
+// MUST have the same number of values as the header.
+type Sink interface {
+	Header(names []string) error
+	Row(values []any) error
+	Flush() error
+}
+
+// errNoHeader is returned by Sinks if Row() is called before Header().
+var errNoHeader = errors.New("Row() called before Header()")
+
+// checkRow returns an error if the row doesn't match the header.
+func checkRow(header []string, row []any) error {
+	if header == nil {
+		return errNoHeader
+	}
+	if len(row) != len(header) {
+		return fmt.Errorf("row with %d values; header has %d", len(row), len(header))
+	}
+	return nil
+}
+
+// A CSV is a Sink that writes RFC 4180 CSV to an io.Writer.
+type CSV struct {
+	w      *csv.Writer
+	header []string
+}
+
+var _ Sink = (*CSV)(nil)
+
+// NewCSV returns a CSV Sink that writes to w. Flush() does not close w.
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{w: csv.NewWriter(w)}
+}
+
+// Header writes the header row.
+func (c *CSV) Header(names []string) error {
+	c.header = names
+	return c.w.Write(names)
+}
+
+// Row writes the values, formatted with FormatString().
+func (c *CSV) Row(values []any) error {
+	if err := checkRow(c.header, values); err != nil {
+		return err
+	}
+	rec := make([]string, len(values))
+	for i, v := range values {
+		s, err := FormatString(v)
+		if err != nil {
+			return fmt.Errorf("column %q: %v", c.header[i], err)
+		}
+		rec[i] = s
+	}
+	return c.w.Write(rec)
+}
+
+// Flush flushes all buffered rows to the underlying io.Writer.
+func (c *CSV) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// A JSONLines is a Sink that writes a JSON object per row, keyed by column
+// name and with keys in column order, to an io.Writer. Unlike CSV, types are
+// preserved where doing so is lossless; see the package documentation.
+type JSONLines struct {
+	w      io.Writer
+	header []string
+	keys   [][]byte
+}
+
+var _ Sink = (*JSONLines)(nil)
+
+// NewJSONLines returns a JSONLines Sink that writes to w. Flush() does not
+// close w.
+func NewJSONLines(w io.Writer) *JSONLines {
+	return &JSONLines{w: w}
+}
+
+// Header records the names used as keys in every subsequent Row(); nothing is
+// written.
+func (j *JSONLines) Header(names []string) error {
+	j.keys = make([][]byte, len(names))
+	for i, n := range names {
+		k, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		j.keys[i] = k
+	}
+	j.header = names
+	return nil
+}
+
+// Row writes the values, formatted with FormatJSON(), as a single line.
+func (j *JSONLines) Row(values []any) error {
+	if err := checkRow(j.header, values); err != nil {
+		return err
+	}
+
+	buf := bytes.NewBufferString("{")
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		val, err := FormatJSON(v)
+		if err != nil {
+			return fmt.Errorf("column %q: %v", j.header[i], err)
+		}
+		buf.Write(j.keys[i])
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteString("}\n")
+
+	_, err := j.w.Write(buf.Bytes())
+	return err
+}
+
+// Flush is a no-op as rows are written immediately.
+func (j *JSONLines) Flush() error {
+	return nil
+}
+
+// FormatJSON returns the JSON representation of v as written by JSONLines; see
+// the package documentation for formatting rules.
+func FormatJSON(v any) ([]byte, error) {
+	if isNil(v) {
+		return []byte("null"), nil
+	}
+
+	switch v.(type) {
+	case string, bool,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return json.Marshal(v)
+
+	case common.Address, *common.Address, common.Hash, *big.Int, *uint256.Int, uint256.Int, []byte:
+		// Although all of these types implement json.Marshaler, their
+		// formats aren't consistent with CSV; e.g. addresses are lower case,
+		// *big.Int is a (potentially imprecise) number, and []byte is base64.
+		return formatJSONString(v)
+
+	case json.Marshaler:
+		return json.Marshal(v)
+	}
+	return formatJSONString(v)
+}
+
+// formatJSONString returns the JSON string of FormatString(v).
+func formatJSONString(v any) ([]byte, error) {
+	s, err := FormatString(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(s)
+}
+
+// A Format is a flag.Value naming a Sink that writes to an io.Writer.
+type Format string
+
+// Formats accepted by NewSink().
+const (
+	CSVFormat       Format = "csv"
+	JSONLinesFormat Format = "jsonl"
+)
+
+// String returns f as a string.
+func (f *Format) String() string {
+	if f == nil {
+		return ""
+	}
+	return string(*f)
+}
+
+// Set sets f to the Format, returning an error if it isn't supported.
+func (f *Format) Set(s string) error {
+	switch g := Format(s); g {
+	case CSVFormat, JSONLinesFormat:
+		*f = g
+		return nil
+	}
+	return fmt.Errorf("unsupported export format %q; must be %q or %q", s, CSVFormat, JSONLinesFormat)
+}
+
+// NewSink returns a Sink of the Format, writing to w.
+func NewSink(f Format, w io.Writer) (Sink, error) {
+	switch f {
+	case CSVFormat:
+		return NewCSV(w), nil
+	case JSONLinesFormat:
+		return NewJSONLines(w), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", f)
+}
@@ -0,0 +1,180 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// A FeeBackend provides the chain data required by a FeeEstimator. It is
+// satisfied by *ethclient.Client.
+type FeeBackend interface {
+	ChainID(context.Context) (*big.Int, error)
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+// MinPriorityFees are the default, per-chain minimum priority fees (tips) used
+// by a FeeEstimator. Chains not in the map have no minimum.
+var MinPriorityFees = map[uint64]*big.Int{
+	// Polygon validators reject transactions with tips below 30 gwei.
+	PolygonChainID: big.NewInt(30 * params.GWei),
+}
+
+// A FeeEstimator estimates EIP-1559 fees from eth_feeHistory, for use instead
+// of the SuggestGasPrice() / SuggestGasTipCap() defaults used by
+// bind.TransactOpts.
+//
+// The priority fee is the median, across recent blocks, of each block's
+// Percentile priority fee, subject to a per-chain minimum. The fee cap is the
+// next block's base fee, projected forward assuming that every block in the
+// interim is full, plus the priority fee.
+//
+// The zero value is not usable; use NewFeeEstimator().
+type FeeEstimator struct {
+	backend FeeBackend
+
+	// Blocks is the number of historical blocks from which priority fees are
+	// sampled.
+	Blocks uint64
+	// Percentile is the percentile, in [0,100], of priority fees paid in each
+	// historical block.
+	Percentile float64
+	// HeadroomBlocks is the number of blocks over which the base fee is
+	// projected, each increasing by the maximum of 12.5%. This allows a
+	// transaction to remain valid if it isn't included immediately.
+	HeadroomBlocks uint
+	// MinPriorityFees override the package-level defaults, keyed by chain ID.
+	// A nil map uses the defaults.
+	MinPriorityFees map[uint64]*big.Int
+}
+
+// NewFeeEstimator returns a FeeEstimator with default values; the exported
+// fields MAY be modified before use.
+func NewFeeEstimator(backend FeeBackend) *FeeEstimator {
+	return &FeeEstimator{
+		backend:        backend,
+		Blocks:         20,
+		Percentile:     50,
+		HeadroomBlocks: 6,
+	}
+}
+
+// Fees are EIP-1559 fee values, returned by FeeEstimator.Estimate().
+type Fees struct {
+	// BaseFee is the base fee of the next block, before projection.
+	BaseFee   *big.Int
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// Apply sets the GasTipCap and GasFeeCap of the TransactOpts, and clears
+// GasPrice to force an EIP-1559 transaction.
+func (f *Fees) Apply(opts *bind.TransactOpts) {
+	opts.GasPrice = nil
+	opts.GasTipCap = new(big.Int).Set(f.GasTipCap)
+	opts.GasFeeCap = new(big.Int).Set(f.GasFeeCap)
+}
+
+// Estimate returns fee estimates based on the latest block.
+func (e *FeeEstimator) Estimate(ctx context.Context) (*Fees, error) {
+	if e.Blocks == 0 {
+		return nil, fmt.Errorf("%T.Blocks must be non-zero", e)
+	}
+	if e.Percentile < 0 || e.Percentile > 100 {
+		return nil, fmt.Errorf("%T.Percentile = %v; must be in [0,100]", e, e.Percentile)
+	}
+
+	hist, err := e.backend.FeeHistory(ctx, e.Blocks, nil, []float64{e.Percentile})
+	if err != nil {
+		return nil, fmt.Errorf("%T.FeeHistory(%d, latest, [%v]): %v", e.backend, e.Blocks, e.Percentile, err)
+	}
+	if len(hist.BaseFee) == 0 {
+		return nil, fmt.Errorf("%T.FeeHistory() returned no base fees", e.backend)
+	}
+
+	tip, err := e.minPriorityFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if med := medianReward(hist.Reward); med.Cmp(tip) > 0 {
+		tip = med
+	}
+
+	// The last base fee is that of the block after the latest one.
+	next := hist.BaseFee[len(hist.BaseFee)-1]
+	feeCap := new(big.Int).Set(next)
+	for i := uint(0); i < e.HeadroomBlocks; i++ {
+		feeCap.Mul(feeCap, big.NewInt(9))
+		feeCap.Div(feeCap, big.NewInt(8))
+	}
+	feeCap.Add(feeCap, tip)
+
+	return &Fees{
+		BaseFee:   new(big.Int).Set(next),
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+	}, nil
+}
+
+// TransactOpts is a convenience wrapper around Estimate() and Fees.Apply().
+func (e *FeeEstimator) TransactOpts(ctx context.Context, opts *bind.TransactOpts) error {
+	fees, err := e.Estimate(ctx)
+	if err != nil {
+		return err
+	}
+	fees.Apply(opts)
+	return nil
+}
+
+// minPriorityFee returns the minimum priority fee for the backend's chain.
+func (e *FeeEstimator) minPriorityFee(ctx context.Context) (*big.Int, error) {
+	mins := e.MinPriorityFees
+	if mins == nil {
+		mins = MinPriorityFees
+	}
+	if len(mins) == 0 {
+		return new(big.Int), nil
+	}
+
+	id, err := e.backend.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%T.ChainID(): %v", e.backend, err)
+	}
+	if !id.IsUint64() {
+		return new(big.Int), nil
+	}
+	if m, ok := mins[id.Uint64()]; ok && m != nil {
+		return new(big.Int).Set(m), nil
+	}
+	return new(big.Int), nil
+}
+
+// medianReward returns the median of the first reward of each block, ignoring
+// empty blocks, for which nodes report a reward of zero (or, for some, none at
+// all). It returns zero if there are no non-zero rewards.
+func medianReward(rewards [][]*big.Int) *big.Int {
+	var rs []*big.Int
+	for _, r := range rewards {
+		if len(r) > 0 && r[0] != nil && r[0].Sign() > 0 {
+			rs = append(rs, r[0])
+		}
+	}
+	if len(rs) == 0 {
+		return new(big.Int)
+	}
+
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].Cmp(rs[j]) < 0
+	})
+	mid := len(rs) / 2
+	if len(rs)%2 == 1 {
+		return new(big.Int).Set(rs[mid])
+	}
+	m := new(big.Int).Add(rs[mid-1], rs[mid])
+	return m.Div(m, big.NewInt(2))
+}
@@ -0,0 +1,160 @@
+package eth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeFeeBackend is a FeeBackend that returns fixed values.
+type fakeFeeBackend struct {
+	chainID  uint64
+	baseFees []int64
+	// rewards are the first reward of each block; negative values result in
+	// no rewards, whereas zero is reported by nodes for empty blocks.
+	rewards []int64
+}
+
+func (f *fakeFeeBackend) ChainID(context.Context) (*big.Int, error) {
+	return new(big.Int).SetUint64(f.chainID), nil
+}
+
+func (f *fakeFeeBackend) FeeHistory(_ context.Context, _ uint64, _ *big.Int, _ []float64) (*ethereum.FeeHistory, error) {
+	h := new(ethereum.FeeHistory)
+	for _, b := range f.baseFees {
+		h.BaseFee = append(h.BaseFee, big.NewInt(b))
+	}
+	for _, r := range f.rewards {
+		if r < 0 {
+			h.Reward = append(h.Reward, nil)
+			continue
+		}
+		h.Reward = append(h.Reward, []*big.Int{big.NewInt(r)})
+	}
+	return h, nil
+}
+
+func TestFeeEstimator(t *testing.T) {
+	const gwei = params.GWei
+
+	tests := []struct {
+		name           string
+		backend        *fakeFeeBackend
+		headroomBlocks uint
+		want           *Fees
+	}{
+		{
+			name: "odd number of rewards",
+			backend: &fakeFeeBackend{
+				chainID:  MainnetChainID,
+				baseFees: []int64{10 * gwei, 11 * gwei, 8 * gwei},
+				rewards:  []int64{3 * gwei, 1 * gwei, 2 * gwei},
+			},
+			want: &Fees{
+				BaseFee:   big.NewInt(8 * gwei),
+				GasTipCap: big.NewInt(2 * gwei),
+				GasFeeCap: big.NewInt(10 * gwei),
+			},
+		},
+		{
+			name: "even number of rewards and empty blocks ignored",
+			backend: &fakeFeeBackend{
+				chainID:  MainnetChainID,
+				baseFees: []int64{8 * gwei},
+				rewards:  []int64{1 * gwei, 0, 2 * gwei, -1, 4 * gwei, 0, 3 * gwei},
+			},
+			want: &Fees{
+				BaseFee:   big.NewInt(8 * gwei),
+				GasTipCap: big.NewInt(2.5 * gwei),
+				GasFeeCap: big.NewInt(10.5 * gwei),
+			},
+		},
+		{
+			name: "majority of empty blocks",
+			backend: &fakeFeeBackend{
+				chainID:  MainnetChainID,
+				baseFees: []int64{8 * gwei},
+				rewards:  []int64{0, 0, 0, 3 * gwei, 0},
+			},
+			want: &Fees{
+				BaseFee:   big.NewInt(8 * gwei),
+				GasTipCap: big.NewInt(3 * gwei),
+				GasFeeCap: big.NewInt(11 * gwei),
+			},
+		},
+		{
+			name: "only empty blocks",
+			backend: &fakeFeeBackend{
+				chainID:  MainnetChainID,
+				baseFees: []int64{8 * gwei},
+				rewards:  []int64{0, 0, -1},
+			},
+			want: &Fees{
+				BaseFee:   big.NewInt(8 * gwei),
+				GasTipCap: big.NewInt(0),
+				GasFeeCap: big.NewInt(8 * gwei),
+			},
+		},
+		{
+			name: "base fee headroom",
+			backend: &fakeFeeBackend{
+				chainID:  MainnetChainID,
+				baseFees: []int64{64 * gwei},
+				rewards:  []int64{1 * gwei},
+			},
+			headroomBlocks: 2,
+			want: &Fees{
+				BaseFee:   big.NewInt(64 * gwei),
+				GasTipCap: big.NewInt(1 * gwei),
+				GasFeeCap: big.NewInt(64*9*9/64*gwei + 1*gwei),
+			},
+		},
+		{
+			name: "chain minimum",
+			backend: &fakeFeeBackend{
+				chainID:  PolygonChainID,
+				baseFees: []int64{100 * gwei},
+				rewards:  []int64{1 * gwei},
+			},
+			want: &Fees{
+				BaseFee:   big.NewInt(100 * gwei),
+				GasTipCap: big.NewInt(30 * gwei),
+				GasFeeCap: big.NewInt(130 * gwei),
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewFeeEstimator(tt.backend)
+			e.HeadroomBlocks = tt.headroomBlocks
+
+			got, err := e.Estimate(ctx)
+			if err != nil {
+				t.Fatalf("%T.Estimate() error %v", e, err)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })); diff != "" {
+				t.Errorf("%T.Estimate() diff (-want +got):\n%s", e, diff)
+			}
+
+			opts := &bind.TransactOpts{GasPrice: big.NewInt(1)}
+			if err := e.TransactOpts(ctx, opts); err != nil {
+				t.Fatalf("%T.TransactOpts() error %v", e, err)
+			}
+			if opts.GasPrice != nil || opts.GasTipCap.Cmp(tt.want.GasTipCap) != 0 || opts.GasFeeCap.Cmp(tt.want.GasFeeCap) != 0 {
+				t.Errorf("%T.TransactOpts() got {GasPrice: %v, GasTipCap: %v, GasFeeCap: %v}; want {nil, %v, %v}", e, opts.GasPrice, opts.GasTipCap, opts.GasFeeCap, tt.want.GasTipCap, tt.want.GasFeeCap)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// A HoldingBackend provides the chain data required by TimeWeightedHoldings().
+// It is satisfied by *ethclient.Client.
+type HoldingBackend interface {
+	LogSource
+	HeaderFetcher
+}
+
+// A Holding is the total time for which a single holder held a single ERC721
+// token within the window of a HoldingSnapshot, possibly across multiple
+// periods if the token was transferred away and back again.
+type Holding struct {
+	Holder  common.Address
+	TokenID *big.Int
+	// Blocks and Duration are the totals of all periods, each measured from
+	// the block in which the token was received (or the start of the window)
+	// to that in which it was sent (or the end of the window).
+	Blocks   uint64
+	Duration time.Duration
+	Periods  int
+	// HeldAtEnd reports whether the holder still held the token at the end of
+	// the window.
+	HeldAtEnd bool
+}
+
+// A HoldingSnapshot is the result of TimeWeightedHoldings().
+type HoldingSnapshot struct {
+	Collection         common.Address
+	FromBlock, ToBlock uint64
+	// FromTime and ToTime are the timestamps of FromBlock and ToBlock.
+	FromTime, ToTime time.Time
+	// Holdings are sorted by holder and then by token ID.
+	Holdings []Holding
+}
+
+// Window returns the duration of the snapshot's window, which is also the
+// total Duration of all Holdings of any token minted before FromBlock.
+func (s *HoldingSnapshot) Window() time.Duration {
+	return s.ToTime.Sub(s.FromTime)
+}
+
+// Weight returns h.Duration as a fraction of s.Window(), or 0 if the window is
+// empty.
+func (s *HoldingSnapshot) Weight(h Holding) float64 {
+	w := s.Window()
+	if w <= 0 {
+		return 0
+	}
+	return float64(h.Duration) / float64(w)
+}
+
+// Rows returns the Holdings as CSV rows, the first of which is a header, with
+// columns holder, token_id, blocks, seconds, periods, held_at_end, and weight
+// (see Weight()). The rows can be passed directly to Labels.Annotate() with the
+// "holder" column.
+func (s *HoldingSnapshot) Rows() [][]string {
+	rows := [][]string{{"holder", "token_id", "blocks", "seconds", "periods", "held_at_end", "weight"}}
+	for _, h := range s.Holdings {
+		rows = append(rows, []string{
+			h.Holder.Hex(),
+			h.TokenID.String(),
+			strconv.FormatUint(h.Blocks, 10),
+			strconv.FormatInt(int64(h.Duration/time.Second), 10),
+			strconv.Itoa(h.Periods),
+			strconv.FormatBool(h.HeldAtEnd),
+			strconv.FormatFloat(s.Weight(h), 'f', -1, 64),
+		})
+	}
+	return rows
+}
+
+// A HoldingOption modifies the behaviour of TimeWeightedHoldings().
+type HoldingOption func(*holdingConfig)
+
+type holdingConfig struct {
+	maxRange uint64
+}
+
+// WithHoldingMaxBlockRange limits the number of blocks requested in a single
+// call to FilterLogs(); see Subscriber.MaxBackfillRange.
+func WithHoldingMaxBlockRange(n uint64) HoldingOption {
+	return func(c *holdingConfig) {
+		c.maxRange = n
+	}
+}
+
+// TimeWeightedHoldings computes, for every holder and token of the ERC721
+// collection, the total time for which the token was held in the window
+// between blocks `from` and `to`, typically as the basis of loyalty rewards.
+// Windows defined by time can be converted to blocks with LastBlockBy().
+//
+// Ownership is computed from Transfer logs emitted in blocks [deployed, to];
+// deployed SHOULD therefore be the block in which the collection was deployed,
+// or earlier. The logs are scanned with Subscriber.Range(), which doesn't
+// detect reorganisations, so to SHOULD be a finalised block. A token
+// transferred in block b is considered held by the sender until, and by the
+// recipient from, the timestamp of b, so the Holdings of each token partition
+// the window. Periods of zero length, e.g. of tokens transferred more than once
+// in the same block, are ignored unless the token is still held at the end of
+// the window.
+//
+// The header of every block in which a token was transferred within the window
+// is fetched from b, which SHOULD therefore be cached (see BlockCache) for
+// active collections.
+func TimeWeightedHoldings(ctx context.Context, b HoldingBackend, collection common.Address, deployed, from, to uint64, opts ...HoldingOption) (*HoldingSnapshot, error) {
+	if deployed > from || from > to {
+		return nil, fmt.Errorf("blocks must be ordered deployed (%d) <= from (%d) <= to (%d)", deployed, from, to)
+	}
+	cfg := new(holdingConfig)
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	sub := NewSubscriber(b, ethereum.FilterQuery{
+		Addresses: []common.Address{collection},
+		Topics:    [][]common.Hash{{ERC721TransferTopic}},
+	})
+	sub.MaxBackfillRange = cfg.maxRange
+
+	type owner struct {
+		addr  common.Address
+		since uint64
+	}
+	type period struct {
+		holder     common.Address
+		token      common.Hash
+		start, end uint64
+		atEnd      bool
+	}
+	var (
+		owners  = make(map[common.Hash]owner)
+		periods []period
+	)
+	// end records the period for which o held the token, if it overlaps with
+	// the window.
+	end := func(token common.Hash, o owner, block uint64, atEnd bool) {
+		if block < from {
+			return
+		}
+		start := o.since
+		if start < from {
+			start = from
+		}
+		if start == block && !atEnd {
+			return
+		}
+		periods = append(periods, period{o.addr, token, start, block, atEnd})
+	}
+
+	err := sub.Range(ctx, deployed, to, func(l types.Log) error {
+		// ERC20 Transfers have a non-indexed value.
+		if len(l.Topics) != 4 || l.Topics[0] != ERC721TransferTopic {
+			return nil
+		}
+		src := common.BytesToAddress(l.Topics[1].Bytes())
+		dst := common.BytesToAddress(l.Topics[2].Bytes())
+		token := l.Topics[3]
+
+		o, ok := owners[token]
+		switch {
+		case ok && o.addr != src:
+			return fmt.Errorf("transfer of token %v from %v in block %d, log %d; owned by %v", token.Big(), src, l.BlockNumber, l.Index, o.addr)
+		case !ok && src != (common.Address{}):
+			return fmt.Errorf("transfer of unowned token %v from %v in block %d, log %d; was %v deployed after block %d?", token.Big(), src, l.BlockNumber, l.Index, collection, deployed)
+		case ok:
+			end(token, o, l.BlockNumber, false)
+		}
+
+		if dst == (common.Address{}) {
+			delete(owners, token)
+		} else {
+			owners[token] = owner{addr: dst, since: l.BlockNumber}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning blocks [%d, %d]: %v", deployed, to, err)
+	}
+	for token, o := range owners {
+		end(token, o, to, true)
+	}
+
+	times := map[uint64]time.Time{from: {}, to: {}}
+	for _, p := range periods {
+		times[p.start] = time.Time{}
+		times[p.end] = time.Time{}
+	}
+	for num := range times {
+		h, err := b.HeaderByNumber(ctx, new(big.Int).SetUint64(num))
+		if err != nil {
+			return nil, fmt.Errorf("%T.HeaderByNumber(%d): %v", b, num, err)
+		}
+		times[num] = time.Unix(int64(h.Time), 0)
+	}
+
+	type holdingKey struct {
+		holder common.Address
+		token  common.Hash
+	}
+	byKey := make(map[holdingKey]*Holding)
+	for _, p := range periods {
+		k := holdingKey{p.holder, p.token}
+		h, ok := byKey[k]
+		if !ok {
+			h = &Holding{
+				Holder:  p.holder,
+				TokenID: p.token.Big(),
+			}
+			byKey[k] = h
+		}
+		h.Blocks += p.end - p.start
+		h.Duration += times[p.end].Sub(times[p.start])
+		h.Periods++
+		h.HeldAtEnd = h.HeldAtEnd || p.atEnd
+	}
+
+	s := &HoldingSnapshot{
+		Collection: collection,
+		FromBlock:  from,
+		ToBlock:    to,
+		FromTime:   times[from],
+		ToTime:     times[to],
+	}
+	for _, h := range byKey {
+		s.Holdings = append(s.Holdings, *h)
+	}
+	sort.Slice(s.Holdings, func(i, j int) bool {
+		hi, hj := s.Holdings[i], s.Holdings[j]
+		if c := bytes.Compare(hi.Holder[:], hj.Holder[:]); c != 0 {
+			return c < 0
+		}
+		return hi.TokenID.Cmp(hj.TokenID) < 0
+	})
+	return s, nil
+}
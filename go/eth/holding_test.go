@@ -0,0 +1,144 @@
+package eth_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeHoldingBackend extends fakeProvenanceBackend with headers mined every 12
+// seconds.
+type fakeHoldingBackend struct {
+	fakeProvenanceBackend
+}
+
+const fakeBlockTime = 12
+
+func (b *fakeHoldingBackend) HeaderByNumber(_ context.Context, num *big.Int) (*types.Header, error) {
+	return &types.Header{
+		Number: num,
+		Time:   1_700_000_000 + fakeBlockTime*num.Uint64(),
+	}, nil
+}
+
+func TestTimeWeightedHoldings(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		collection = common.HexToAddress("0xc011ec7")
+		zero       common.Address
+		alice      = common.HexToAddress("0xa11ce")
+		bob        = common.HexToAddress("0xb0b")
+		carol      = common.HexToAddress("0xca401")
+	)
+
+	var logs []*types.Log
+	transfer := func(block uint64, from, to common.Address, tokenID int64) {
+		logs = append(logs, &types.Log{
+			Address: collection,
+			Topics: []common.Hash{
+				ERC721TransferTopic,
+				common.BytesToHash(from.Bytes()),
+				common.BytesToHash(to.Bytes()),
+				common.BigToHash(big.NewInt(tokenID)),
+			},
+			BlockNumber: block,
+			Index:       uint(len(logs)),
+		})
+	}
+
+	const deployed, from, to = 10, 20, 30
+
+	transfer(10, zero, alice, 1)
+	transfer(12, zero, alice, 3)
+	transfer(15, zero, carol, 6)
+	transfer(15, carol, zero, 6)
+	transfer(21, alice, zero, 3)
+	transfer(22, zero, bob, 2)
+	transfer(24, bob, alice, 2)
+	// ERC20 Transfer, which MUST be ignored.
+	logs = append(logs, &types.Log{
+		Address:     collection,
+		Topics:      []common.Hash{ERC721TransferTopic, common.BytesToHash(bob.Bytes()), common.BytesToHash(carol.Bytes())},
+		Data:        common.BigToHash(big.NewInt(1)).Bytes(),
+		BlockNumber: 24,
+		Index:       uint(len(logs)),
+	})
+	transfer(25, alice, bob, 1)
+	transfer(26, zero, carol, 5)
+	transfer(26, carol, alice, 5)
+	transfer(27, alice, bob, 2)
+	transfer(31, zero, carol, 4)
+
+	backend := &fakeHoldingBackend{
+		fakeProvenanceBackend{head: 40, logs: logs},
+	}
+
+	snap, err := TimeWeightedHoldings(ctx, backend, collection, deployed, from, to, WithHoldingMaxBlockRange(4))
+	if err != nil {
+		t.Fatalf("TimeWeightedHoldings(…) error %v", err)
+	}
+
+	held := func(holder common.Address, tokenID int64, blocks uint64, periods int, atEnd bool) Holding {
+		return Holding{
+			Holder:    holder,
+			TokenID:   big.NewInt(tokenID),
+			Blocks:    blocks,
+			Duration:  time.Duration(blocks*fakeBlockTime) * time.Second,
+			Periods:   periods,
+			HeldAtEnd: atEnd,
+		}
+	}
+	// Sorted by holder address, so bob (0x…0b0b) before alice (0x…0a11ce).
+	// Carol only ever held token 5 for zero time so has no Holding.
+	want := []Holding{
+		held(bob, 1, 5, 1, true),
+		held(bob, 2, 5, 2, true),
+		held(alice, 1, 5, 1, false),
+		held(alice, 2, 3, 1, false),
+		held(alice, 3, 1, 1, false),
+		held(alice, 5, 4, 1, true),
+	}
+	if diff := cmp.Diff(want, snap.Holdings, cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })); diff != "" {
+		t.Errorf("TimeWeightedHoldings(…) Holdings diff (-want +got):\n%s", diff)
+	}
+
+	if got, want := snap.Window(), (to-from)*fakeBlockTime*time.Second; got != want {
+		t.Errorf("%T.Window() got %v; want %v", snap, got, want)
+	}
+
+	t.Run("Rows", func(t *testing.T) {
+		rows := snap.Rows()
+		wantRows := [][]string{
+			{"holder", "token_id", "blocks", "seconds", "periods", "held_at_end", "weight"},
+			{bob.Hex(), "1", "5", "60", "1", "true", "0.5"},
+		}
+		if diff := cmp.Diff(wantRows, rows[:2]); diff != "" {
+			t.Errorf("%T.Rows()[:2] diff (-want +got):\n%s", snap, diff)
+		}
+		if got, want := len(rows), len(snap.Holdings)+1; got != want {
+			t.Errorf("len(%T.Rows()) got %d; want %d", snap, got, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := TimeWeightedHoldings(ctx, backend, collection, deployed, to, from); err == nil {
+			t.Errorf("TimeWeightedHoldings(…, [from > to]) got nil error; want non-nil")
+		}
+		// Scanning from after the mint of token 1 results in a transfer of an
+		// unowned token.
+		if _, err := TimeWeightedHoldings(ctx, backend, collection, 11, from, to); err == nil {
+			t.Errorf("TimeWeightedHoldings(…, [deployed after first mint], …) got nil error; want non-nil")
+		}
+	})
+}
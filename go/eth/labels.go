@@ -0,0 +1,240 @@
+package eth
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// A Label is a human-readable name for an address; e.g. that of an exchange,
+// team wallet, or contract.
+type Label struct {
+	Address common.Address `json:"address"`
+	// ChainID scopes the Label to a single chain. If zero, the Label applies to
+	// all chains, which is typical for EOAs and deterministically deployed
+	// contracts.
+	ChainID uint64 `json:"chain_id,omitempty"`
+	Name    string `json:"label"`
+	// Category is an optional grouping; e.g. "exchange" or "team".
+	Category string `json:"category,omitempty"`
+}
+
+// Labels resolves addresses to Labels, preferring those scoped to the chain
+// over unscoped ones. A nil *Labels is valid and has no Labels, which allows
+// labelling to be optional in tools.
+type Labels struct {
+	byChain map[uint64]map[common.Address]Label
+}
+
+// NewLabels returns Labels containing all of ls. It returns an error if an
+// address has more than one Label with the same ChainID, unless they are
+// identical.
+func NewLabels(ls []Label) (*Labels, error) {
+	l := &Labels{
+		byChain: make(map[uint64]map[common.Address]Label),
+	}
+	for _, lab := range ls {
+		if lab.Name == "" {
+			return nil, fmt.Errorf("empty name for address %v on chain %d", lab.Address, lab.ChainID)
+		}
+		byAddr, ok := l.byChain[lab.ChainID]
+		if !ok {
+			byAddr = make(map[common.Address]Label)
+			l.byChain[lab.ChainID] = byAddr
+		}
+		if prev, ok := byAddr[lab.Address]; ok && prev != lab {
+			return nil, fmt.Errorf("address %v on chain %d labelled both %q and %q", lab.Address, lab.ChainID, prev.Name, lab.Name)
+		}
+		byAddr[lab.Address] = lab
+	}
+	return l, nil
+}
+
+// LoadLabels reads Labels from the file, which MUST have a .json or .csv
+// extension; see LabelsFromJSON() and LabelsFromCSV() respectively.
+func LoadLabels(path string) (*Labels, error) {
+	var parse func(io.Reader) (*Labels, error)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		parse = LabelsFromJSON
+	case ".csv":
+		parse = LabelsFromCSV
+	default:
+		return nil, fmt.Errorf("labels file %q with unsupported extension %q", path, ext)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("labels file %q: %v", path, err)
+	}
+	return l, nil
+}
+
+// LabelsFromJSON reads a JSON array of Labels from r.
+func LabelsFromJSON(r io.Reader) (*Labels, error) {
+	var ls []Label
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ls); err != nil {
+		return nil, fmt.Errorf("decoding JSON labels: %v", err)
+	}
+	return NewLabels(ls)
+}
+
+// LabelsFromCSV reads Labels from a CSV with a header row. The address and
+// label columns are required, and the chain_id and category columns are
+// optional, as with the JSON fields of a Label. Other columns are ignored.
+func LabelsFromCSV(r io.Reader) (*Labels, error) {
+	c := csv.NewReader(r)
+	c.ReuseRecord = true
+
+	header, err := c.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %v", err)
+	}
+	cols := map[string]int{
+		"address":  -1,
+		"label":    -1,
+		"chain_id": -1,
+		"category": -1,
+	}
+	for i, h := range header {
+		if _, ok := cols[strings.TrimSpace(h)]; ok {
+			cols[strings.TrimSpace(h)] = i
+		}
+	}
+	for _, req := range []string{"address", "label"} {
+		if cols[req] == -1 {
+			return nil, fmt.Errorf("CSV header %q missing column %q", header, req)
+		}
+	}
+	field := func(rec []string, col string) string {
+		if i := cols[col]; i != -1 {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var ls []Label
+	for {
+		rec, err := c.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading CSV: %v", err)
+		}
+		line, _ := c.FieldPos(0)
+
+		raw := field(rec, "address")
+		if !common.IsHexAddress(raw) {
+			return nil, fmt.Errorf("invalid address %q on line %d", raw, line)
+		}
+		lab := Label{
+			Address:  common.HexToAddress(raw),
+			Name:     field(rec, "label"),
+			Category: field(rec, "category"),
+		}
+		if id := field(rec, "chain_id"); id != "" {
+			lab.ChainID, err = strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chain ID %q on line %d: %v", id, line, err)
+			}
+		}
+		ls = append(ls, lab)
+	}
+	return NewLabels(ls)
+}
+
+// Len returns the number of Labels, across all chains.
+func (l *Labels) Len() int {
+	if l == nil {
+		return 0
+	}
+	var n int
+	for _, byAddr := range l.byChain {
+		n += len(byAddr)
+	}
+	return n
+}
+
+// Lookup returns the Label of the address on the chain, falling back to an
+// unscoped Label, and a boolean indicating whether one was found.
+func (l *Labels) Lookup(chainID uint64, addr common.Address) (Label, bool) {
+	if l == nil {
+		return Label{}, false
+	}
+	if lab, ok := l.byChain[chainID][addr]; ok {
+		return lab, true
+	}
+	lab, ok := l.byChain[0][addr]
+	return lab, ok
+}
+
+// Name returns the name of the address's Label, as found by Lookup(), or an
+// empty string if it has none.
+func (l *Labels) Name(chainID uint64, addr common.Address) string {
+	lab, _ := l.Lookup(chainID, addr)
+	return lab.Name
+}
+
+// Annotate adds a label column to CSV rows, the first of which MUST be a
+// header, for use by tools that export addresses. The new column is inserted
+// immediately after the named column, which MUST contain hex addresses, and
+// its header is the name suffixed with "_label". Rows are modified in place
+// and returned for convenience.
+//
+// If l is nil, rows are returned unmodified so that labels are only added to
+// outputs of tools when requested.
+func (l *Labels) Annotate(chainID uint64, rows [][]string, column string) ([][]string, error) {
+	if l == nil {
+		return rows, nil
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no header row")
+	}
+
+	col := -1
+	for i, h := range rows[0] {
+		if h == column {
+			col = i
+			break
+		}
+	}
+	if col == -1 {
+		return nil, fmt.Errorf("header %q missing column %q", rows[0], column)
+	}
+
+	for i, row := range rows {
+		var val string
+		switch {
+		case i == 0:
+			val = column + "_label"
+		case col >= len(row):
+			return nil, fmt.Errorf("row %d has %d columns; want > %d", i, len(row), col)
+		case !common.IsHexAddress(row[col]):
+			return nil, fmt.Errorf("row %d: invalid address %q in column %q", i, row[col], column)
+		default:
+			val = l.Name(chainID, common.HexToAddress(row[col]))
+		}
+		row = append(row, "")
+		copy(row[col+2:], row[col+1:])
+		row[col+1] = val
+		rows[i] = row
+	}
+	return rows, nil
+}
@@ -0,0 +1,166 @@
+package eth_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestLabels(t *testing.T) {
+	var (
+		exchange = common.HexToAddress("0xe")
+		team     = common.HexToAddress("0x7")
+		other    = common.HexToAddress("0x0")
+	)
+
+	const jsonLabels = `[
+		{"address": "0x000000000000000000000000000000000000000e", "label": "Exchange", "category": "exchange"},
+		{"address": "0x0000000000000000000000000000000000000007", "label": "Team (mainnet)", "chain_id": 1},
+		{"address": "0x0000000000000000000000000000000000000007", "label": "Team (Polygon)", "chain_id": 137}
+	]`
+	const csvLabels = `label,extra,address,chain_id,category
+Exchange,ignored,0x000000000000000000000000000000000000000e,,exchange
+Team (mainnet),,0x0000000000000000000000000000000000000007,1,
+Team (Polygon),,0x0000000000000000000000000000000000000007,137,
+`
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"labels.json": jsonLabels,
+		"labels.csv":  csvLabels,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("os.WriteFile(%q) error %v", name, err)
+		}
+	}
+
+	for _, file := range []string{"labels.json", "labels.csv"} {
+		t.Run(file, func(t *testing.T) {
+			labels, err := LoadLabels(filepath.Join(dir, file))
+			if err != nil {
+				t.Fatalf("LoadLabels(%q) error %v", file, err)
+			}
+			if got, want := labels.Len(), 3; got != want {
+				t.Errorf("%T.Len() got %d; want %d", labels, got, want)
+			}
+
+			tests := []struct {
+				chainID uint64
+				addr    common.Address
+				want    Label
+				wantOK  bool
+			}{
+				{
+					chainID: 1,
+					addr:    exchange,
+					want:    Label{Address: exchange, Name: "Exchange", Category: "exchange"},
+					wantOK:  true,
+				},
+				{
+					chainID: 1,
+					addr:    team,
+					want:    Label{Address: team, ChainID: 1, Name: "Team (mainnet)"},
+					wantOK:  true,
+				},
+				{
+					chainID: 137,
+					addr:    team,
+					want:    Label{Address: team, ChainID: 137, Name: "Team (Polygon)"},
+					wantOK:  true,
+				},
+				{
+					chainID: 5,
+					addr:    team,
+				},
+				{
+					chainID: 1,
+					addr:    other,
+				},
+			}
+			for _, tt := range tests {
+				got, ok := labels.Lookup(tt.chainID, tt.addr)
+				if ok != tt.wantOK {
+					t.Errorf("%T.Lookup(%d, %v) got ok = %t; want %t", labels, tt.chainID, tt.addr, ok, tt.wantOK)
+				}
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Errorf("%T.Lookup(%d, %v) diff (-want +got):\n%s", labels, tt.chainID, tt.addr, diff)
+				}
+			}
+		})
+	}
+
+	t.Run("Annotate", func(t *testing.T) {
+		labels, err := LabelsFromJSON(strings.NewReader(jsonLabels))
+		if err != nil {
+			t.Fatalf("LabelsFromJSON() error %v", err)
+		}
+		rows := func() [][]string {
+			return [][]string{
+				{"holder", "balance"},
+				{exchange.Hex(), "10"},
+				{team.Hex(), "2"},
+				{other.Hex(), "1"},
+			}
+		}
+
+		got, err := labels.Annotate(1, rows(), "holder")
+		if err != nil {
+			t.Fatalf("%T.Annotate(1, …, %q) error %v", labels, "holder", err)
+		}
+		want := [][]string{
+			{"holder", "holder_label", "balance"},
+			{exchange.Hex(), "Exchange", "10"},
+			{team.Hex(), "Team (mainnet)", "2"},
+			{other.Hex(), "", "1"},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%T.Annotate(1, …, %q) diff (-want +got):\n%s", labels, "holder", diff)
+		}
+
+		var nilLabels *Labels
+		got, err = nilLabels.Annotate(1, rows(), "holder")
+		if err != nil {
+			t.Fatalf("%T(nil).Annotate() error %v", nilLabels, err)
+		}
+		if diff := cmp.Diff(rows(), got); diff != "" {
+			t.Errorf("%T(nil).Annotate() modified rows; diff (-want +got):\n%s", nilLabels, diff)
+		}
+
+		if _, err := labels.Annotate(1, rows(), "missing"); err == nil {
+			t.Errorf("%T.Annotate(…, [missing column]) got nil error; want non-nil", labels)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, in := range []string{
+			`[{"address": "0x000000000000000000000000000000000000000e", "label": "A"}, {"address": "0x000000000000000000000000000000000000000e", "label": "B"}]`,
+			`[{"address": "0x000000000000000000000000000000000000000e", "label": ""}]`,
+			`[{"address": "0x000000000000000000000000000000000000000e", "label": "A", "unknown": 1}]`,
+		} {
+			if _, err := LabelsFromJSON(strings.NewReader(in)); err == nil {
+				t.Errorf("LabelsFromJSON(%s) got nil error; want non-nil", in)
+			}
+		}
+		for _, in := range []string{
+			"address\n0x000000000000000000000000000000000000000e\n",
+			"address,label\nnot-an-address,A\n",
+			"address,label,chain_id\n0x000000000000000000000000000000000000000e,A,mainnet\n",
+		} {
+			if _, err := LabelsFromCSV(strings.NewReader(in)); err == nil {
+				t.Errorf("LabelsFromCSV(%q) got nil error; want non-nil", in)
+			}
+		}
+		if _, err := LoadLabels("labels.txt"); err == nil {
+			t.Errorf("LoadLabels([unsupported extension]) got nil error; want non-nil")
+		}
+	})
+}
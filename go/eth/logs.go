@@ -0,0 +1,67 @@
+package eth
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ParseLogs is equivalent to ParseRawLogs(contract, r.Logs, eventName).
+func ParseLogs[T any](contract *abi.ABI, r *types.Receipt, eventName string) ([]T, error) {
+	return ParseRawLogs[T](contract, r.Logs, eventName)
+}
+
+// ParseRawLogs decodes all logs emitted by the named event into values of type
+// T, which MUST be a struct with fields named as with abigen-generated event
+// types; indeed, these types can be used directly. If T has a Raw field of type
+// types.Log then it is populated with the respective log.
+//
+// Logs of other events are ignored, as are those with the same signature but a
+// different number of indexed arguments (e.g. ERC20 and ERC721 Transfers). As
+// logs aren't filtered by address, those of the same event emitted by other
+// contracts are included.
+func ParseRawLogs[T any](contract *abi.ABI, logs []*types.Log, eventName string) ([]T, error) {
+	ev, ok := contract.Events[eventName]
+	if !ok {
+		return nil, fmt.Errorf("event %q not in ABI", eventName)
+	}
+	if ev.Anonymous {
+		return nil, fmt.Errorf("anonymous event %q unsupported", eventName)
+	}
+	if k := reflect.TypeOf((*T)(nil)).Elem().Kind(); k != reflect.Struct {
+		var t T
+		return nil, fmt.Errorf("type %T of kind %v; must be a struct", t, k)
+	}
+
+	var indexed abi.Arguments
+	for _, arg := range ev.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+
+	var out []T
+	for _, l := range logs {
+		if len(l.Topics) == 0 || l.Topics[0] != ev.ID || len(l.Topics) != len(indexed)+1 {
+			continue
+		}
+
+		var t T
+		if len(l.Data) > 0 {
+			if err := contract.UnpackIntoInterface(&t, eventName, l.Data); err != nil {
+				return nil, fmt.Errorf("tx %v log index %d: %T.UnpackIntoInterface(%T, %q, …): %v", l.TxHash, l.Index, contract, &t, eventName, err)
+			}
+		}
+		if err := abi.ParseTopics(&t, indexed, l.Topics[1:]); err != nil {
+			return nil, fmt.Errorf("tx %v log index %d: abi.ParseTopics(%T, …): %v", l.TxHash, l.Index, &t, err)
+		}
+
+		if raw := reflect.ValueOf(&t).Elem().FieldByName("Raw"); raw.IsValid() && raw.Type() == reflect.TypeOf(types.Log{}) && raw.CanSet() {
+			raw.Set(reflect.ValueOf(*l))
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
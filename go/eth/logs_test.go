@@ -0,0 +1,97 @@
+package eth_test
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+const transferABI = `[
+	{
+		"type": "event",
+		"name": "Transfer",
+		"inputs": [
+			{"name": "from", "type": "address", "indexed": true},
+			{"name": "to", "type": "address", "indexed": true},
+			{"name": "value", "type": "uint256", "indexed": false}
+		]
+	},
+	{
+		"type": "event",
+		"name": "Other",
+		"inputs": []
+	}
+]`
+
+// transfer mirrors an abigen-generated event type.
+type transfer struct {
+	From  common.Address
+	To    common.Address
+	Value *big.Int
+	Raw   types.Log
+}
+
+func TestParseLogs(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(transferABI))
+	if err != nil {
+		t.Fatalf("abi.JSON(…) error %v", err)
+	}
+	transferSig := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	otherSig := crypto.Keccak256Hash([]byte("Other()"))
+
+	from := common.HexToAddress("0xf00")
+	to := common.HexToAddress("0xba7")
+
+	erc20 := &types.Log{
+		Topics: []common.Hash{transferSig, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:   common.BigToHash(big.NewInt(42)).Bytes(),
+		Index:  1,
+	}
+	// Same signature, but with an extra indexed argument.
+	erc721 := &types.Log{
+		Topics: []common.Hash{transferSig, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(1))},
+		Index:  2,
+	}
+	other := &types.Log{
+		Topics: []common.Hash{otherSig},
+		Index:  3,
+	}
+
+	rcpt := &types.Receipt{
+		Logs: []*types.Log{other, erc20, erc721},
+	}
+
+	got, err := ParseLogs[transfer](&contract, rcpt, "Transfer")
+	if err != nil {
+		t.Fatalf("ParseLogs[transfer](…) error %v", err)
+	}
+	want := []transfer{{
+		From:  from,
+		To:    to,
+		Value: big.NewInt(42),
+		Raw:   *erc20,
+	}}
+	if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })); diff != "" {
+		t.Errorf("ParseLogs[transfer](…) diff (-want +got):\n%s", diff)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := ParseLogs[transfer](&contract, rcpt, "Missing"); err == nil {
+			t.Errorf("ParseLogs(…, [event not in ABI]) got nil error; want non-nil")
+		}
+		if _, err := ParseLogs[*transfer](&contract, rcpt, "Transfer"); err == nil {
+			t.Errorf("ParseLogs[*transfer](…) got nil error; want non-nil")
+		}
+	})
+}
@@ -0,0 +1,307 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/glog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/cxkoda/solgo/go/otelonce"
+)
+
+// A BackendMiddleware wraps a bind.ContractBackend, typically to add behaviour
+// to some or all of its methods before propagating calls to the wrapped
+// backend. Middleware is composed with WrapBackend().
+type BackendMiddleware func(bind.ContractBackend) bind.ContractBackend
+
+// WrapBackend returns b wrapped in all of the middleware, with mw[0] being the
+// outermost; i.e. the first to receive each call. For example,
+//
+//	WrapBackend(client, LogBackendCalls(1), RetryBackendCalls(RetryPolicy{}), rl.BackendMiddleware())
+//
+// logs every call once, regardless of how many times it is retried, and
+// retries are themselves rate limited.
+func WrapBackend(b bind.ContractBackend, mw ...BackendMiddleware) bind.ContractBackend {
+	for i := len(mw) - 1; i >= 0; i-- {
+		b = mw[i](b)
+	}
+	return b
+}
+
+// BackendMiddleware returns the RateLimiter's ContractBackend() method as a
+// BackendMiddleware.
+func (rl *RateLimiter) BackendMiddleware() BackendMiddleware {
+	return rl.ContractBackend
+}
+
+// DemuxWrites returns a BackendMiddleware that sends writes to w and
+// everything else to the wrapped backend; see RWDemuxBackend(). Middleware
+// after DemuxWrites() in a WrapBackend() chain therefore only sees reads.
+func DemuxWrites(w bind.ContractBackend) BackendMiddleware {
+	return func(r bind.ContractBackend) bind.ContractBackend {
+		return RWDemuxBackend(r, w)
+	}
+}
+
+// A BackendCall describes a call to a bind.ContractBackend method, as passed
+// to a BackendInterceptor.
+type BackendCall struct {
+	// Method is the name of the ContractBackend method; e.g. "CallContract".
+	Method string
+	// Group is the MethodGroup to which Method belongs.
+	Group MethodGroup
+	// Args are the arguments passed to Method, excluding the Context. They
+	// MUST NOT be modified.
+	Args []interface{}
+}
+
+// A BackendInterceptor is called instead of every method of a
+// bind.ContractBackend. It MUST call invoke to propagate the call to the
+// wrapped backend, and SHOULD return the resulting error. invoke MAY be called
+// with a different Context or more than once (e.g. to retry), in which case
+// the method's other return values are those of the last call.
+type BackendInterceptor func(ctx context.Context, call *BackendCall, invoke func(context.Context) error) error
+
+// InterceptBackend returns a BackendMiddleware that calls the interceptor for
+// every method of the wrapped backend.
+func InterceptBackend(i BackendInterceptor) BackendMiddleware {
+	return func(b bind.ContractBackend) bind.ContractBackend {
+		return &interceptedBackend{b: b, i: i}
+	}
+}
+
+type interceptedBackend struct {
+	b bind.ContractBackend
+	i BackendInterceptor
+}
+
+// intercept is a convenience wrapper for passing a method call through the
+// backend's interceptor.
+func intercept[T any](ctx context.Context, b *interceptedBackend, method string, g MethodGroup, args []interface{}, fn func(context.Context) (T, error)) (T, error) {
+	var res T
+	err := b.i(ctx, &BackendCall{Method: method, Group: g, Args: args}, func(ctx context.Context) error {
+		var err error
+		res, err = fn(ctx)
+		return err
+	})
+	return res, err
+}
+
+func (b *interceptedBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return intercept(ctx, b, "CodeAt", CallGroup, []interface{}{contract, blockNumber}, func(ctx context.Context) ([]byte, error) {
+		return b.b.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+func (b *interceptedBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return intercept(ctx, b, "CallContract", CallGroup, []interface{}{call, blockNumber}, func(ctx context.Context) ([]byte, error) {
+		return b.b.CallContract(ctx, call, blockNumber)
+	})
+}
+
+func (b *interceptedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return intercept(ctx, b, "HeaderByNumber", BlockGroup, []interface{}{number}, func(ctx context.Context) (*types.Header, error) {
+		return b.b.HeaderByNumber(ctx, number)
+	})
+}
+
+func (b *interceptedBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return intercept(ctx, b, "PendingCodeAt", CallGroup, []interface{}{account}, func(ctx context.Context) ([]byte, error) {
+		return b.b.PendingCodeAt(ctx, account)
+	})
+}
+
+func (b *interceptedBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return intercept(ctx, b, "PendingNonceAt", TxGroup, []interface{}{account}, func(ctx context.Context) (uint64, error) {
+		return b.b.PendingNonceAt(ctx, account)
+	})
+}
+
+func (b *interceptedBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return intercept(ctx, b, "SuggestGasPrice", TxGroup, nil, func(ctx context.Context) (*big.Int, error) {
+		return b.b.SuggestGasPrice(ctx)
+	})
+}
+
+func (b *interceptedBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return intercept(ctx, b, "SuggestGasTipCap", TxGroup, nil, func(ctx context.Context) (*big.Int, error) {
+		return b.b.SuggestGasTipCap(ctx)
+	})
+}
+
+func (b *interceptedBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return intercept(ctx, b, "EstimateGas", CallGroup, []interface{}{call}, func(ctx context.Context) (uint64, error) {
+		return b.b.EstimateGas(ctx, call)
+	})
+}
+
+func (b *interceptedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := intercept(ctx, b, "SendTransaction", TxGroup, []interface{}{tx}, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, b.b.SendTransaction(ctx, tx)
+	})
+	return err
+}
+
+func (b *interceptedBackend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return intercept(ctx, b, "FilterLogs", LogGroup, []interface{}{query}, func(ctx context.Context) ([]types.Log, error) {
+		return b.b.FilterLogs(ctx, query)
+	})
+}
+
+// SubscribeFilterLogs is intercepted only while the subscription is being
+// established, not for its lifetime.
+func (b *interceptedBackend) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return intercept(ctx, b, "SubscribeFilterLogs", LogGroup, []interface{}{query}, func(ctx context.Context) (ethereum.Subscription, error) {
+		return b.b.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
+
+// LogBackendCalls returns a BackendMiddleware that logs every call, its
+// duration, and any error at the specified glog verbosity.
+func LogBackendCalls(v glog.Level) BackendMiddleware {
+	return InterceptBackend(func(ctx context.Context, call *BackendCall, invoke func(context.Context) error) error {
+		start := time.Now()
+		err := invoke(ctx)
+		if glog.V(v) {
+			glog.Infof("ContractBackend.%s(%v) in %v; error: %v", call.Method, call.Args, time.Since(start), err)
+		}
+		return err
+	})
+}
+
+// BackendInstrumentationName is the name of the OpenTelemetry Meter used by
+// MeasureBackendCalls(). As with all OpenTelemetry metrics, binaries that wish
+// to export them MUST install a provider with otel.SetMeterProvider().
+//
+// Metrics, all of which have "method", "group", and (boolean) "error"
+// attributes, are:
+//   - eth.backend.calls
+//   - eth.backend.duration (seconds)
+const BackendInstrumentationName = "github.com/cxkoda/solgo/go/eth/backend"
+
+// backendInstruments are the OpenTelemetry metric instruments used by
+// MeasureBackendCalls().
+type backendInstruments struct {
+	calls    metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// backendMetrics returns the MeasureBackendCalls() metric instruments.
+var backendMetrics = otelonce.Instruments(BackendInstrumentationName, func(m metric.Meter) (*backendInstruments, error) {
+	inst := new(backendInstruments)
+	var errs [2]error
+	inst.calls, errs[0] = m.Int64Counter(
+		"eth.backend.calls",
+		metric.WithDescription("Number of calls to ContractBackend methods."),
+	)
+	inst.duration, errs[1] = m.Float64Histogram(
+		"eth.backend.duration",
+		metric.WithDescription("Duration of calls to ContractBackend methods."),
+		metric.WithUnit("s"),
+	)
+	return inst, errors.Join(errs[:]...)
+})
+
+// MeasureBackendCalls returns a BackendMiddleware that records metrics of
+// every call; see BackendInstrumentationName.
+func MeasureBackendCalls() BackendMiddleware {
+	return InterceptBackend(func(ctx context.Context, call *BackendCall, invoke func(context.Context) error) error {
+		inst := backendMetrics()
+		start := time.Now()
+		err := invoke(ctx)
+
+		attrs := metric.WithAttributes(
+			attribute.String("method", call.Method),
+			attribute.String("group", string(call.Group)),
+			attribute.Bool("error", err != nil),
+		)
+		inst.calls.Add(ctx, 1, attrs)
+		inst.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+		return err
+	})
+}
+
+// A RetryPolicy configures RetryBackendCalls().
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first, of
+	// each call. It defaults to 3.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, which doubles with every
+	// subsequent one. It defaults to 500ms.
+	Backoff time.Duration
+	// Retryable reports whether a failed call should be retried. It defaults
+	// to IsTransientError().
+	Retryable func(*BackendCall, error) bool
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return 3
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) backoff() time.Duration {
+	if p.Backoff <= 0 {
+		return 500 * time.Millisecond
+	}
+	return p.Backoff
+}
+
+func (p RetryPolicy) retryable(call *BackendCall, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(call, err)
+	}
+	return IsTransientError(err)
+}
+
+// IsTransientError reports whether err is likely to be resolved by retrying
+// the call; i.e. it was returned by the transport (e.g. a network error or an
+// HTTP 429 or 5xx status) as opposed to the node (e.g. an execution revert).
+// Context errors are never transient.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// RetryBackendCalls returns a BackendMiddleware that retries failed calls in
+// accordance with the RetryPolicy. SendTransaction() is never retried, even if
+// the policy allows it, because a transport error doesn't preclude the
+// transaction having been received.
+func RetryBackendCalls(p RetryPolicy) BackendMiddleware {
+	return InterceptBackend(func(ctx context.Context, call *BackendCall, invoke func(context.Context) error) error {
+		backoff := p.backoff()
+		for attempt := 1; ; attempt++ {
+			err := invoke(ctx)
+			if err == nil || attempt >= p.maxAttempts() || call.Method == "SendTransaction" || !p.retryable(call, err) {
+				return err
+			}
+
+			glog.V(1).Infof("Retrying ContractBackend.%s() in %v after attempt %d: %v", call.Method, backoff, attempt, err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return err
+			}
+			backoff *= 2
+		}
+	})
+}
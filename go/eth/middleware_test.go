@@ -0,0 +1,189 @@
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// flakyBackend fails the first `failures` calls to CallContract() and
+// SendTransaction() with err. All other methods panic.
+type flakyBackend struct {
+	bind.ContractBackend
+	name     string
+	failures int
+	err      error
+	calls    int
+}
+
+func (b *flakyBackend) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	b.calls++
+	if b.calls <= b.failures {
+		return nil, b.err
+	}
+	return []byte(b.name), nil
+}
+
+func (b *flakyBackend) SendTransaction(context.Context, *types.Transaction) error {
+	b.calls++
+	if b.calls <= b.failures {
+		return b.err
+	}
+	return nil
+}
+
+func TestWrapBackendOrder(t *testing.T) {
+	var got []string
+	record := func(name string) BackendMiddleware {
+		return InterceptBackend(func(ctx context.Context, call *BackendCall, invoke func(context.Context) error) error {
+			got = append(got, fmt.Sprintf("%s>%s:%s", name, call.Method, call.Group))
+			err := invoke(ctx)
+			got = append(got, fmt.Sprintf("%s<%s", name, call.Method))
+			return err
+		})
+	}
+
+	b := WrapBackend(&flakyBackend{name: "inner"}, record("a"), record("b"))
+	out, err := b.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	if err != nil {
+		t.Fatalf("CallContract() error %v", err)
+	}
+	if string(out) != "inner" {
+		t.Errorf("CallContract() got %q; want %q", out, "inner")
+	}
+
+	want := []string{"a>CallContract:call", "b>CallContract:call", "b<CallContract", "a<CallContract"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("WrapBackend(…, a, b) interceptor calls diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRetryBackendCalls(t *testing.T) {
+	ctx := context.Background()
+	transient := rpc.HTTPError{StatusCode: http.StatusTooManyRequests}
+	permanent := errors.New("execution reverted")
+
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		send      bool
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "success",
+			wantCalls: 1,
+		},
+		{
+			name:      "transient then success",
+			failures:  2,
+			err:       transient,
+			wantCalls: 3,
+		},
+		{
+			name:      "transient exceeds attempts",
+			failures:  5,
+			err:       transient,
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "permanent",
+			failures:  1,
+			err:       permanent,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "SendTransaction never retried",
+			failures:  1,
+			err:       transient,
+			send:      true,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakyBackend{failures: tt.failures, err: tt.err}
+			b := WrapBackend(inner, RetryBackendCalls(RetryPolicy{Backoff: time.Millisecond}))
+
+			var err error
+			if tt.send {
+				err = b.SendTransaction(ctx, nil)
+			} else {
+				_, err = b.CallContract(ctx, ethereum.CallMsg{}, nil)
+			}
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("call got err %v; want error? %t", err, tt.wantErr)
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("wrapped backend got %d calls; want %d", inner.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New("execution reverted"), want: false},
+		{err: context.DeadlineExceeded, want: false},
+		{err: rpc.HTTPError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{err: fmt.Errorf("wrapped: %w", rpc.HTTPError{StatusCode: http.StatusBadGateway}), want: true},
+		{err: rpc.HTTPError{StatusCode: http.StatusUnauthorized}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTransientError(tt.err); got != tt.want {
+			t.Errorf("IsTransientError(%v) got %t; want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDemuxWrites(t *testing.T) {
+	ctx := context.Background()
+	r := &flakyBackend{name: "r"}
+	w := &flakyBackend{name: "w"}
+
+	var intercepted []string
+	after := InterceptBackend(func(ctx context.Context, call *BackendCall, invoke func(context.Context) error) error {
+		intercepted = append(intercepted, call.Method)
+		return invoke(ctx)
+	})
+	b := WrapBackend(r, DemuxWrites(w), after)
+
+	if _, err := b.CallContract(ctx, ethereum.CallMsg{}, nil); err != nil {
+		t.Fatalf("CallContract() error %v", err)
+	}
+	if err := b.SendTransaction(ctx, nil); err != nil {
+		t.Fatalf("SendTransaction() error %v", err)
+	}
+
+	if r.calls != 1 || w.calls != 1 {
+		t.Errorf("after one read and one write; got %d read-backend and %d write-backend calls; want 1 each", r.calls, w.calls)
+	}
+	if diff := cmp.Diff([]string{"CallContract"}, intercepted); diff != "" {
+		t.Errorf("middleware after DemuxWrites() intercepted methods diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,264 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// A MinedBackend provides the chain data required by WaitMined(). It is
+// satisfied by *ethclient.Client and simulated backends.
+type MinedBackend interface {
+	ethereum.TransactionReader
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+var (
+	// ErrDropped is wrapped by a *WaitMinedError if the transaction was neither
+	// mined nor known to the node for longer than the period set with
+	// WithDroppedAfter().
+	ErrDropped = errors.New("transaction dropped")
+	// ErrReplaced is wrapped by a *WaitMinedError if a different transaction
+	// with the same sender and nonce was mined and confirmed.
+	ErrReplaced = errors.New("transaction replaced")
+	// ErrReverted is wrapped by a *WaitMinedError if the transaction was mined
+	// and confirmed, but failed.
+	ErrReverted = errors.New("transaction reverted")
+)
+
+// A WaitMinedError is returned by WaitMined() when a transaction will never be
+// successfully mined. Use errors.Is() with ErrDropped, ErrReplaced, or
+// ErrReverted to determine the reason.
+type WaitMinedError struct {
+	Err error
+	Tx  *types.Transaction
+	// Receipt is non-nil iff Err is ErrReverted.
+	Receipt *types.Receipt
+}
+
+func (e *WaitMinedError) Error() string {
+	return fmt.Sprintf("tx %v: %v", e.Tx.Hash(), e.Err)
+}
+
+func (e *WaitMinedError) Unwrap() error {
+	return e.Err
+}
+
+// WaitProgress describes the state of a transaction being awaited by
+// WaitMined(), as reported to a callback set with WithProgress().
+type WaitProgress struct {
+	Tx *types.Transaction
+	// Head is the latest block number at the time of polling.
+	Head uint64
+	// Receipt is nil until the transaction is mined. As a chain reorganisation
+	// may remove the transaction, it can also return to being nil.
+	Receipt *types.Receipt
+	// Confirmations is the number of blocks, inclusive of the one in which it
+	// was mined, that the transaction has; i.e. 0 until mined, and 1 once in
+	// the Head block.
+	Confirmations uint64
+	// Replaced is true if another transaction with the same nonce has been
+	// mined, but has yet to receive sufficient confirmations.
+	Replaced bool
+}
+
+// A WaitOption configures the behaviour of WaitMined().
+type WaitOption func(*waitConfig)
+
+type waitConfig struct {
+	poll         time.Duration
+	droppedAfter time.Duration
+	progress     func(WaitProgress)
+}
+
+// WithPollInterval sets the period between checks of the chain state; it
+// defaults to 1s.
+func WithPollInterval(d time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.poll = d
+	}
+}
+
+// WithDroppedAfter sets how long a transaction may be unknown to the node
+// before ErrDropped is returned; it defaults to 1 minute. Recently sent
+// transactions may take time to propagate, so this SHOULD be longer than a
+// single poll interval.
+func WithDroppedAfter(d time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.droppedAfter = d
+	}
+}
+
+// WithProgress registers a callback that is called after every poll of the
+// chain state. The callback MUST NOT block.
+func WithProgress(fn func(WaitProgress)) WaitOption {
+	return func(c *waitConfig) {
+		c.progress = fn
+	}
+}
+
+// WaitMined blocks until tx has been mined and has the specified number of
+// confirmations, inclusive of the block in which it was mined; a value of 0 is
+// treated as 1.
+//
+// If tx will never be successfully mined, the returned error is a
+// *WaitMinedError describing whether it was dropped, replaced by another
+// transaction with the same nonce, or reverted. In the last case, the receipt
+// is returned along with the error. Other errors, including ctx.Err(), are
+// returned as is.
+func WaitMined(ctx context.Context, client MinedBackend, tx *types.Transaction, confirmations uint64, opts ...WaitOption) (*types.Receipt, error) {
+	cfg := waitConfig{
+		poll:         time.Second,
+		droppedAfter: time.Minute,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if confirmations == 0 {
+		confirmations = 1
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("types.Sender(…, %v): %v", tx.Hash(), err)
+	}
+
+	w := &waiter{
+		client:        client,
+		tx:            tx,
+		from:          from,
+		confirmations: confirmations,
+		cfg:           &cfg,
+	}
+
+	ticker := time.NewTicker(cfg.poll)
+	defer ticker.Stop()
+
+	for {
+		rcpt, done, err := w.poll(ctx)
+		if done || err != nil {
+			return rcpt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// A waiter holds the state of a single call to WaitMined().
+type waiter struct {
+	client        MinedBackend
+	tx            *types.Transaction
+	from          common.Address
+	confirmations uint64
+	cfg           *waitConfig
+
+	// unknownSince is the time at which the transaction was first found to be
+	// unknown to the node, since last being known; zero if it's known.
+	unknownSince time.Time
+}
+
+// poll checks the chain state once, returning done=true if WaitMined() must
+// return.
+func (w *waiter) poll(ctx context.Context) (_ *types.Receipt, done bool, _ error) {
+	head, err := w.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("%T.HeaderByNumber(nil): %v", w.client, err)
+	}
+	p := WaitProgress{
+		Tx:   w.tx,
+		Head: head.Number.Uint64(),
+	}
+	defer func() {
+		if w.cfg.progress != nil {
+			w.cfg.progress(p)
+		}
+	}()
+
+	rcpt, err := w.receipt(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if rcpt != nil {
+		w.unknownSince = time.Time{}
+
+		p.Receipt = rcpt
+		if n := rcpt.BlockNumber.Uint64(); p.Head >= n {
+			p.Confirmations = p.Head - n + 1
+		}
+		if p.Confirmations < w.confirmations {
+			return nil, false, nil
+		}
+		if rcpt.Status != types.ReceiptStatusSuccessful {
+			return rcpt, true, &WaitMinedError{Err: ErrReverted, Tx: w.tx, Receipt: rcpt}
+		}
+		return rcpt, true, nil
+	}
+
+	nonce, err := w.client.NonceAt(ctx, w.from, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("%T.NonceAt(%v, latest): %v", w.client, w.from, err)
+	}
+	if nonce > w.tx.Nonce() {
+		w.unknownSince = time.Time{}
+
+		// The transaction may have been mined between the two checks.
+		switch rcpt, err := w.receipt(ctx); {
+		case err != nil:
+			return nil, false, err
+		case rcpt != nil:
+			return nil, false, nil
+		}
+
+		p.Replaced = true
+		if p.Head+1 < w.confirmations {
+			return nil, false, nil
+		}
+		confirmed := new(big.Int).SetUint64(p.Head + 1 - w.confirmations)
+		nonce, err := w.client.NonceAt(ctx, w.from, confirmed)
+		if err != nil {
+			return nil, false, fmt.Errorf("%T.NonceAt(%v, %d): %v", w.client, w.from, confirmed, err)
+		}
+		if nonce > w.tx.Nonce() {
+			return nil, true, &WaitMinedError{Err: ErrReplaced, Tx: w.tx}
+		}
+		return nil, false, nil
+	}
+
+	switch _, _, err := w.client.TransactionByHash(ctx, w.tx.Hash()); {
+	case errors.Is(err, ethereum.NotFound):
+		if w.unknownSince.IsZero() {
+			w.unknownSince = time.Now()
+		}
+		if time.Since(w.unknownSince) >= w.cfg.droppedAfter {
+			return nil, true, &WaitMinedError{Err: ErrDropped, Tx: w.tx}
+		}
+	case err != nil:
+		return nil, false, fmt.Errorf("%T.TransactionByHash(%v): %v", w.client, w.tx.Hash(), err)
+	default:
+		w.unknownSince = time.Time{}
+	}
+	return nil, false, nil
+}
+
+// receipt returns the transaction's receipt, or nil if it hasn't been mined.
+func (w *waiter) receipt(ctx context.Context) (*types.Receipt, error) {
+	rcpt, err := w.client.TransactionReceipt(ctx, w.tx.Hash())
+	switch {
+	case errors.Is(err, ethereum.NotFound):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("%T.TransactionReceipt(%v): %v", w.client, w.tx.Hash(), err)
+	}
+	return rcpt, nil
+}
@@ -0,0 +1,199 @@
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/cxkoda/solgo/go/ethtest"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestWaitMined(t *testing.T) {
+	ctx := context.Background()
+
+	// PUSH1 0 DUP1 REVERT, as contract-creation code.
+	revert := []byte{0x60, 0x00, 0x80, 0xfd}
+
+	tests := []struct {
+		name          string
+		confirmations uint64
+		// txs returns the transaction to be awaited and those to be sent, which
+		// MAY exclude the awaited one.
+		txs         func(t *testing.T, sim *ethtest.SimulatedBackend) (await *types.Transaction, send []*types.Transaction)
+		extraBlocks int
+		wantErr     error
+	}{
+		{
+			name: "success",
+			txs: func(t *testing.T, sim *ethtest.SimulatedBackend) (*types.Transaction, []*types.Transaction) {
+				tx := signTx(t, sim, 0, &common.Address{}, nil)
+				return tx, []*types.Transaction{tx}
+			},
+		},
+		{
+			name:          "success with confirmations",
+			confirmations: 3,
+			txs: func(t *testing.T, sim *ethtest.SimulatedBackend) (*types.Transaction, []*types.Transaction) {
+				tx := signTx(t, sim, 0, &common.Address{}, nil)
+				return tx, []*types.Transaction{tx}
+			},
+			extraBlocks: 2,
+		},
+		{
+			name: "reverted",
+			txs: func(t *testing.T, sim *ethtest.SimulatedBackend) (*types.Transaction, []*types.Transaction) {
+				tx := signTx(t, sim, 0, nil, revert)
+				return tx, []*types.Transaction{tx}
+			},
+			wantErr: ErrReverted,
+		},
+		{
+			name: "replaced",
+			txs: func(t *testing.T, sim *ethtest.SimulatedBackend) (*types.Transaction, []*types.Transaction) {
+				return signTx(t, sim, 0, &common.Address{}, nil), []*types.Transaction{
+					signTx(t, sim, 0, &common.Address{1}, nil),
+				}
+			},
+			wantErr: ErrReplaced,
+		},
+		{
+			name:          "replaced with confirmations",
+			confirmations: 2,
+			txs: func(t *testing.T, sim *ethtest.SimulatedBackend) (*types.Transaction, []*types.Transaction) {
+				return signTx(t, sim, 0, &common.Address{}, nil), []*types.Transaction{
+					signTx(t, sim, 0, &common.Address{1}, nil),
+				}
+			},
+			extraBlocks: 1,
+			wantErr:     ErrReplaced,
+		},
+		{
+			name: "dropped",
+			txs: func(t *testing.T, sim *ethtest.SimulatedBackend) (*types.Transaction, []*types.Transaction) {
+				return signTx(t, sim, 0, &common.Address{}, nil), nil
+			},
+			wantErr: ErrDropped,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := ethtest.NewSimulatedBackendTB(t, 1)
+
+			await, send := tt.txs(t, sim)
+			for _, tx := range send {
+				if err := sim.SendTransaction(ctx, tx); err != nil {
+					t.Fatalf("%T.SendTransaction(%v) error %v", sim, tx.Hash(), err)
+				}
+			}
+			for i := 0; i < tt.extraBlocks; i++ {
+				sim.Commit()
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+
+			var progress []WaitProgress
+			rcpt, err := WaitMined(
+				ctx, sim, await, tt.confirmations,
+				WithPollInterval(time.Millisecond),
+				WithDroppedAfter(10*time.Millisecond),
+				WithProgress(func(p WaitProgress) {
+					progress = append(progress, p)
+				}),
+			)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WaitMined(…, %d) got err %v; want %v", tt.confirmations, err, tt.wantErr)
+			}
+			if len(progress) == 0 {
+				t.Errorf("WaitMined(…, WithProgress(fn)) never called fn")
+			}
+
+			var wmErr *WaitMinedError
+			if tt.wantErr != nil && !errors.As(err, &wmErr) {
+				t.Fatalf("WaitMined(…) got err %T; want %T", err, wmErr)
+			}
+
+			switch {
+			case tt.wantErr == nil, errors.Is(tt.wantErr, ErrReverted):
+				if rcpt == nil || rcpt.TxHash != await.Hash() {
+					t.Fatalf("WaitMined(…) got receipt %+v; want for tx %v", rcpt, await.Hash())
+				}
+				want := tt.confirmations
+				if want == 0 {
+					want = 1
+				}
+				if got := progress[len(progress)-1].Confirmations; got < want {
+					t.Errorf("WaitMined(…) last progress reported %d confirmations; want at least %d", got, want)
+				}
+			default:
+				if rcpt != nil {
+					t.Errorf("WaitMined(…) got receipt %+v; want nil", rcpt)
+				}
+			}
+		})
+	}
+
+	t.Run("awaiting confirmations", func(t *testing.T) {
+		sim := ethtest.NewSimulatedBackendTB(t, 1)
+		tx := signTx(t, sim, 0, &common.Address{}, nil)
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("%T.SendTransaction(…) error %v", sim, err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		var last WaitProgress
+		_, err := WaitMined(ctx, sim, tx, 3, WithPollInterval(time.Millisecond), WithProgress(func(p WaitProgress) {
+			last = p
+		}))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("WaitMined(…, 3) with insufficient blocks got err %v; want %v", err, context.DeadlineExceeded)
+		}
+		if last.Receipt == nil || last.Confirmations != 1 {
+			t.Errorf("WaitMined(…, 3) last progress got {Receipt: %v, Confirmations: %d}; want non-nil receipt and 1 confirmation", last.Receipt, last.Confirmations)
+		}
+	})
+}
+
+// signTx returns a transaction with the account's next nonce, signed by the
+// account but not sent.
+func signTx(t *testing.T, sim *ethtest.SimulatedBackend, account int, to *common.Address, data []byte) *types.Transaction {
+	t.Helper()
+	ctx := context.Background()
+
+	nonce, err := sim.PendingNonceAt(ctx, sim.Addr(account))
+	if err != nil {
+		t.Fatalf("%T.PendingNonceAt(…) error %v", sim, err)
+	}
+	chainID, err := sim.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("%T.ChainID() error %v", sim, err)
+	}
+
+	tx, err := types.SignNewTx(sim.PrivateKey(account), types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: big.NewInt(100 * params.GWei),
+		Gas:       100_000,
+		To:        to,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("types.SignNewTx(…) error %v", err)
+	}
+	return tx
+}
@@ -0,0 +1,261 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// DepositTxType is the EIP-2718 type of OP-stack deposit transactions, which
+// carry L1-initiated messages (and the L1 attributes of every block) onto L2
+// chains such as Optimism and Base. They are unknown to go-ethereum, which
+// fails to decode any block that includes one, i.e. every OP-stack block.
+//
+// Receipts of deposit transactions are decoded without error by go-ethereum,
+// with Receipt.Type equal to DepositTxType, but OP-stack-specific fields (e.g.
+// the deposit nonce) are dropped.
+const DepositTxType = 0x7e
+
+// A DepositTx is an OP-stack deposit transaction; see DepositTxType.
+type DepositTx struct {
+	Hash common.Hash
+	// SourceHash uniquely identifies the source of the deposit; e.g. the L1
+	// log that emitted it.
+	SourceHash common.Hash
+	From       common.Address
+	// To is nil for contract creation.
+	To *common.Address
+	// Mint is the amount of ETH minted on L2; it MAY be nil.
+	Mint       *big.Int
+	Value      *big.Int
+	Gas        uint64
+	IsSystemTx bool
+	Input      []byte
+}
+
+// rpcDepositTx is the JSON-RPC representation of a DepositTx.
+type rpcDepositTx struct {
+	Type       hexutil.Uint64  `json:"type"`
+	Hash       common.Hash     `json:"hash"`
+	SourceHash common.Hash     `json:"sourceHash"`
+	From       common.Address  `json:"from"`
+	To         *common.Address `json:"to"`
+	Mint       *hexutil.Big    `json:"mint"`
+	Value      *hexutil.Big    `json:"value"`
+	Gas        hexutil.Uint64  `json:"gas"`
+	IsSystemTx bool            `json:"isSystemTx"`
+	Input      hexutil.Bytes   `json:"input"`
+}
+
+// UnmarshalJSON parses the JSON-RPC representation of a deposit transaction,
+// as returned by OP-stack nodes.
+func (tx *DepositTx) UnmarshalJSON(buf []byte) error {
+	var raw rpcDepositTx
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return err
+	}
+	if raw.Type != DepositTxType {
+		return fmt.Errorf("transaction type %#x; want deposit type %#x", uint64(raw.Type), DepositTxType)
+	}
+	*tx = DepositTx{
+		Hash:       raw.Hash,
+		SourceHash: raw.SourceHash,
+		From:       raw.From,
+		To:         raw.To,
+		Mint:       raw.Mint.ToInt(),
+		Value:      raw.Value.ToInt(),
+		Gas:        uint64(raw.Gas),
+		IsSystemTx: raw.IsSystemTx,
+		Input:      raw.Input,
+	}
+	return nil
+}
+
+// An OPBlock is a block from an OP-stack chain, with its deposit transactions
+// separated from all others. The OP-stack protocol places all deposits at the
+// start of a block so the index of Block.Transactions()[i] in the original
+// block is len(Deposits)+i.
+//
+// As Block lacks the deposits, its transactions don't match its header's
+// TxHash, but its Hash() is that of the original block.
+type OPBlock struct {
+	Block    *types.Block
+	Deposits []*DepositTx
+}
+
+// An OPClient is an ethclient.Client that can also decode blocks from OP-stack
+// chains; see DepositTxType.
+type OPClient struct {
+	*ethclient.Client
+	rpc *rpc.Client
+}
+
+var (
+	_ BlockFetcher  = (*OPClient)(nil)
+	_ HeaderFetcher = (*OPClient)(nil)
+)
+
+// NewOPClient returns an OPClient that uses the rpc.Client.
+func NewOPClient(c *rpc.Client) *OPClient {
+	return &OPClient{
+		Client: ethclient.NewClient(c),
+		rpc:    c,
+	}
+}
+
+// DialOP is equivalent to Dial() except that it returns an OPClient.
+func (c *Dialer) DialOP(ctx context.Context) (*OPClient, error) {
+	url, err := c.url(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return NewOPClient(rc), nil
+}
+
+// BlockByNumber is equivalent to the ethclient.Client method of the same name
+// except that deposit transactions are dropped instead of causing an error;
+// see OPBlockByNumber().
+func (c *OPClient) BlockByNumber(ctx context.Context, num *big.Int) (*types.Block, error) {
+	b, err := c.OPBlockByNumber(ctx, num)
+	if err != nil {
+		return nil, err
+	}
+	return b.Block, nil
+}
+
+// BlockByHash is the hash equivalent of BlockByNumber().
+func (c *OPClient) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	b, err := c.OPBlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return b.Block, nil
+}
+
+// OPBlockByNumber returns the block, with deposit transactions separated. As
+// with BlockByNumber(), a nil number returns the latest block.
+func (c *OPClient) OPBlockByNumber(ctx context.Context, num *big.Int) (*OPBlock, error) {
+	return c.getBlock(ctx, "eth_getBlockByNumber", blockNumArg(num), true)
+}
+
+// OPBlockByHash is the hash equivalent of OPBlockByNumber().
+func (c *OPClient) OPBlockByHash(ctx context.Context, hash common.Hash) (*OPBlock, error) {
+	return c.getBlock(ctx, "eth_getBlockByHash", hash, true)
+}
+
+// rpcOPBlock is the body of a JSON-RPC block with full transactions.
+type rpcOPBlock struct {
+	Transactions []json.RawMessage `json:"transactions"`
+	UncleHashes  []common.Hash     `json:"uncles"`
+	Withdrawals  types.Withdrawals `json:"withdrawals,omitempty"`
+}
+
+func (c *OPClient) getBlock(ctx context.Context, method string, args ...any) (*OPBlock, error) {
+	var raw json.RawMessage
+	if err := c.rpc.CallContext(ctx, &raw, method, args...); err != nil {
+		return nil, err
+	}
+
+	var head *types.Header
+	if err := json.Unmarshal(raw, &head); err != nil {
+		return nil, fmt.Errorf("decoding %s header: %v", method, err)
+	}
+	// When the block is not found, the API returns JSON null.
+	if head == nil {
+		return nil, ethereum.NotFound
+	}
+
+	var body rpcOPBlock
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, fmt.Errorf("decoding %s body: %v", method, err)
+	}
+	if len(body.UncleHashes) > 0 {
+		return nil, fmt.Errorf("block %d has %d uncles; not an OP-stack chain?", head.Number, len(body.UncleHashes))
+	}
+	if head.TxHash == types.EmptyTxsHash && len(body.Transactions) > 0 {
+		return nil, errors.New("server returned non-empty transaction list but block header indicates no transactions")
+	}
+
+	b := new(OPBlock)
+	var txs []*types.Transaction
+	for i, rawTx := range body.Transactions {
+		var typ struct {
+			Type hexutil.Uint64 `json:"type"`
+		}
+		if err := json.Unmarshal(rawTx, &typ); err != nil {
+			return nil, fmt.Errorf("decoding type of transaction %d of block %d: %v", i, head.Number, err)
+		}
+
+		if typ.Type != DepositTxType {
+			tx := new(types.Transaction)
+			if err := json.Unmarshal(rawTx, tx); err != nil {
+				return nil, fmt.Errorf("decoding transaction %d of block %d: %v", i, head.Number, err)
+			}
+			txs = append(txs, tx)
+			continue
+		}
+
+		if len(txs) > 0 {
+			return nil, fmt.Errorf("deposit transaction %d of block %d after non-deposit transaction", i, head.Number)
+		}
+		dep := new(DepositTx)
+		if err := json.Unmarshal(rawTx, dep); err != nil {
+			return nil, fmt.Errorf("decoding deposit transaction %d of block %d: %v", i, head.Number, err)
+		}
+		b.Deposits = append(b.Deposits, dep)
+	}
+
+	b.Block = types.NewBlockWithHeader(head).WithBody(txs, nil).WithWithdrawals(body.Withdrawals)
+	return b, nil
+}
+
+// IsOPStack reports whether the chain is a registered Chain built on the
+// OP stack.
+func IsOPStack(chainID uint64) bool {
+	c, ok := ChainByID(chainID)
+	return ok && c.OPStack
+}
+
+// BlockFetcherFor returns an *OPClient sharing the client's connection if the
+// chain IsOPStack(), otherwise it returns the client itself.
+func BlockFetcherFor(client *ethclient.Client, chainID uint64) BlockFetcher {
+	if IsOPStack(chainID) {
+		return NewOPClient(client.Client())
+	}
+	return client
+}
+
+// DialBlockFetcher dials the node and returns BlockFetcherFor() its chain. If
+// the Dialer wasn't constructed with NewChainDialer(), the chain ID is queried
+// from the node. The returned BlockFetcher is also a HeaderFetcher, and has a
+// Close() method.
+func (c *Dialer) DialBlockFetcher(ctx context.Context) (BlockFetcher, error) {
+	client, err := c.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chainID := c.chainID
+	if chainID == 0 {
+		id, err := client.ChainID(ctx)
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("%T.ChainID(): %v", client, err)
+		}
+		chainID = id.Uint64()
+	}
+	return BlockFetcherFor(client, chainID), nil
+}
@@ -0,0 +1,231 @@
+package eth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cxkoda/solgo/go/secrets"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// opService implements the subset of the eth namespace used by OPClient,
+// returning blocks in the same format as an OP-stack node.
+type opService struct {
+	chainID uint64
+	// blocks are returned by GetBlockByNumber(), indexed by number.
+	blocks []json.RawMessage
+}
+
+func (s *opService) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(new(big.Int).SetUint64(s.chainID))
+}
+
+func (s *opService) GetBlockByNumber(n rpc.BlockNumber, full bool) (json.RawMessage, error) {
+	if !full {
+		return nil, errors.New("only full blocks supported")
+	}
+	if n < 0 || int(n) >= len(s.blocks) {
+		return json.RawMessage("null"), nil
+	}
+	return s.blocks[n], nil
+}
+
+// opBlockJSON returns the JSON-RPC representation of a block with the header
+// and transactions, each of which MUST be a *types.Transaction or a JSON
+// deposit transaction.
+func opBlockJSON(t *testing.T, header *types.Header, txs ...any) json.RawMessage {
+	t.Helper()
+
+	hBuf, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("json.Marshal(%T) error %v", header, err)
+	}
+	block := make(map[string]any)
+	if err := json.Unmarshal(hBuf, &block); err != nil {
+		t.Fatalf("json.Unmarshal([header], %T) error %v", block, err)
+	}
+	block["transactions"] = txs
+	block["uncles"] = []common.Hash{}
+
+	buf, err := json.Marshal(block)
+	if err != nil {
+		t.Fatalf("json.Marshal([block]) error %v", err)
+	}
+	return buf
+}
+
+func TestOPClient(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("crypto.GenerateKey() error %v", err)
+	}
+	to := common.HexToAddress("0xc0ffee")
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(int64(BaseChainID))), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(int64(BaseChainID)),
+		Nonce:     42,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(3),
+	})
+	if err != nil {
+		t.Fatalf("types.SignNewTx(…) error %v", err)
+	}
+
+	const deposit = `{
+		"type": "0x7e",
+		"hash": "0x0000000000000000000000000000000000000000000000000000000000000d0d",
+		"sourceHash": "0x0000000000000000000000000000000000000000000000000000000000005ce0",
+		"from": "0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001",
+		"to": "0x4200000000000000000000000000000000000015",
+		"mint": "0x0",
+		"value": "0x0",
+		"gas": "0xf4240",
+		"isSystemTx": false,
+		"input": "0x440a5e20",
+		"nonce": "0x1",
+		"depositReceiptVersion": "0x1"
+	}`
+	wantDeposit := &DepositTx{
+		Hash:       common.HexToHash("0xd0d"),
+		SourceHash: common.HexToHash("0x5ce0"),
+		From:       common.HexToAddress("0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001"),
+		To:         addrPtr(common.HexToAddress("0x4200000000000000000000000000000000000015")),
+		Mint:       big.NewInt(0),
+		Value:      big.NewInt(0),
+		Gas:        1_000_000,
+		Input:      []byte{0x44, 0x0a, 0x5e, 0x20},
+	}
+
+	header := func(num int64) *types.Header {
+		return &types.Header{
+			Number:     big.NewInt(num),
+			Difficulty: new(big.Int),
+			BaseFee:    big.NewInt(1),
+			UncleHash:  types.EmptyUncleHash,
+			TxHash:     common.HexToHash("0x01"), // only compared to EmptyTxsHash
+		}
+	}
+
+	svc := &opService{
+		chainID: BaseChainID,
+		blocks: []json.RawMessage{
+			opBlockJSON(t, header(0), json.RawMessage(deposit), tx),
+			opBlockJSON(t, header(1), tx, json.RawMessage(deposit)),
+		},
+	}
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", svc); err != nil {
+		t.Fatalf("%T.RegisterName(eth, %T) error %v", srv, svc, err)
+	}
+	t.Cleanup(srv.Stop)
+	h := httptest.NewServer(srv)
+	t.Cleanup(h.Close)
+
+	dialer := NewDialer(&secrets.Secret{Source: secrets.Raw, ID: h.URL})
+
+	t.Run("go-ethereum fails", func(t *testing.T) {
+		// This demonstrates the need for OPClient; if it fails then
+		// go-ethereum has added support for deposit transactions.
+		client, err := dialer.Dial(ctx)
+		if err != nil {
+			t.Fatalf("%T.Dial() error %v", dialer, err)
+		}
+		defer client.Close()
+		if _, err := client.BlockByNumber(ctx, big.NewInt(0)); err == nil {
+			t.Errorf("%T.BlockByNumber(0) with deposit transaction got nil error", client)
+		}
+	})
+
+	client, err := dialer.DialOP(ctx)
+	if err != nil {
+		t.Fatalf("%T.DialOP() error %v", dialer, err)
+	}
+	t.Cleanup(client.Close)
+
+	t.Run("OPBlockByNumber", func(t *testing.T) {
+		got, err := client.OPBlockByNumber(ctx, big.NewInt(0))
+		if err != nil {
+			t.Fatalf("%T.OPBlockByNumber(0) error %v", client, err)
+		}
+		if diff := cmp.Diff([]*DepositTx{wantDeposit}, got.Deposits, cmp.Comparer(func(a, b *big.Int) bool {
+			return a.Cmp(b) == 0
+		})); diff != "" {
+			t.Errorf("%T.OPBlockByNumber(0).Deposits diff (-want +got):\n%s", client, diff)
+		}
+
+		var gotHashes []common.Hash
+		for _, tx := range got.Block.Transactions() {
+			gotHashes = append(gotHashes, tx.Hash())
+		}
+		if diff := cmp.Diff([]common.Hash{tx.Hash()}, gotHashes); diff != "" {
+			t.Errorf("%T.OPBlockByNumber(0).Block.Transactions() hashes diff (-want +got):\n%s", client, diff)
+		}
+		if got, want := got.Block.Hash(), header(0).Hash(); got != want {
+			t.Errorf("%T.OPBlockByNumber(0).Block.Hash() got %v; want %v", client, got, want)
+		}
+	})
+
+	t.Run("BlockByNumber", func(t *testing.T) {
+		b, err := client.BlockByNumber(ctx, big.NewInt(0))
+		if err != nil {
+			t.Fatalf("%T.BlockByNumber(0) error %v", client, err)
+		}
+		if got, want := len(b.Transactions()), 1; got != want {
+			t.Errorf("%T.BlockByNumber(0) got %d transactions; want %d (deposit dropped)", client, got, want)
+		}
+	})
+
+	t.Run("deposit after non-deposit", func(t *testing.T) {
+		if _, err := client.OPBlockByNumber(ctx, big.NewInt(1)); err == nil {
+			t.Errorf("%T.OPBlockByNumber(1) with deposit transaction after non-deposit got nil error", client)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := client.OPBlockByNumber(ctx, big.NewInt(2)); !errors.Is(err, ethereum.NotFound) {
+			t.Errorf("%T.OPBlockByNumber([missing]) got err %v; want %v", client, err, ethereum.NotFound)
+		}
+	})
+
+	t.Run("DialBlockFetcher", func(t *testing.T) {
+		for _, chainID := range []uint64{MainnetChainID, BaseChainID} {
+			svc.chainID = chainID
+
+			f, err := dialer.DialBlockFetcher(ctx)
+			if err != nil {
+				t.Fatalf("%T.DialBlockFetcher() with chain %d error %v", dialer, chainID, err)
+			}
+			defer f.(interface{ Close() }).Close()
+			_, gotOP := f.(*OPClient)
+			_, gotEth := f.(*ethclient.Client)
+			if wantOP := IsOPStack(chainID); gotOP != wantOP || gotEth == wantOP {
+				t.Errorf("%T.DialBlockFetcher() with chain %d got %T; want *OPClient? %t", dialer, chainID, f, wantOP)
+			}
+		}
+	})
+}
+
+func addrPtr(a common.Address) *common.Address {
+	return &a
+}
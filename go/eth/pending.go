@@ -0,0 +1,289 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang/glog"
+)
+
+// A PendingTxFilter selects pending transactions. A transaction matches the
+// filter if it matches every non-empty field, and it matches a field if it
+// matches any of the field's values. A zero PendingTxFilter therefore matches
+// all transactions.
+type PendingTxFilter struct {
+	From []common.Address
+	// To is typically a contract address. Contract creations never match a
+	// non-empty To.
+	To []common.Address
+	// Methods are matched by their selector, and matching calldata is decoded
+	// into PendingTx.Call.
+	Methods []*abi.Method
+}
+
+// A PendingTx is a transaction in a node's mempool that matched a
+// PendingTxFilter.
+type PendingTx struct {
+	Tx   *types.Transaction
+	From common.Address
+	// Call is the decoded calldata if the PendingTxFilter has Methods,
+	// otherwise it is nil.
+	Call *Call
+}
+
+// A PendingTxWatcher delivers pending transactions matching a filter, as they
+// enter the mempool of the node to which it is connected. It subscribes to
+// newPendingTransactions where supported, gracefully degrading to polling a
+// pending-transaction filter otherwise (e.g. over HTTP), and resubscribing
+// after errors.
+//
+// Transactions are typically delivered once, but MAY be repeated after a
+// resubscription. Transactions that are mined or dropped before they can be
+// fetched are not delivered.
+type PendingTxWatcher struct {
+	client *rpc.Client
+	eth    *ethclient.Client
+	filter PendingTxFilter
+
+	// PollInterval is the period between polls of the pending-transaction
+	// filter if subscriptions aren't supported. It defaults to 1s if zero.
+	PollInterval time.Duration
+	// Backoff is the delay before resubscribing after an error. It defaults to
+	// 1s if zero.
+	Backoff time.Duration
+}
+
+// NewPendingTxWatcher returns a PendingTxWatcher for transactions matching the
+// filter. The *rpc.Client of an *ethclient.Client is available via its
+// Client() method.
+func NewPendingTxWatcher(client *rpc.Client, filter PendingTxFilter) *PendingTxWatcher {
+	return &PendingTxWatcher{
+		client: client,
+		eth:    ethclient.NewClient(client),
+		filter: filter,
+	}
+}
+
+func (w *PendingTxWatcher) pollInterval() time.Duration {
+	if w.PollInterval == 0 {
+		return time.Second
+	}
+	return w.PollInterval
+}
+
+func (w *PendingTxWatcher) backoff() time.Duration {
+	if w.Backoff == 0 {
+		return time.Second
+	}
+	return w.Backoff
+}
+
+// Watch sends matching transactions to ch until ctx is cancelled, returning
+// ctx.Err(). Errors other than those when determining the chain ID are logged
+// and retried after PendingTxWatcher.Backoff.
+func (w *PendingTxWatcher) Watch(ctx context.Context, ch chan<- *PendingTx) error {
+	chainID, err := w.eth.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.ChainID(): %v", w.eth, err)
+	}
+	r := &pendingTxRun{
+		PendingTxWatcher: w,
+		out:              ch,
+		signer:           types.LatestSignerForChainID(chainID),
+		seen:             make(map[common.Hash]bool),
+	}
+
+	for {
+		err := r.subscribe(ctx)
+		if errors.Is(err, errSubscriptionsUnsupported) {
+			glog.Infof("%T: subscriptions unsupported; polling every %v", w, w.pollInterval())
+			err = r.poll(ctx)
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		glog.Warningf("%T: resubscribing after error: %v", w, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.backoff()):
+		}
+	}
+}
+
+var errSubscriptionsUnsupported = errors.New("subscriptions unsupported")
+
+// pendingTxRun carries the state of a single PendingTxWatcher.Watch() call.
+type pendingTxRun struct {
+	*PendingTxWatcher
+	out    chan<- *PendingTx
+	signer types.Signer
+
+	// seen and prevSeen are the generations of a bounded set of transaction
+	// hashes that have already been handled.
+	seen, prevSeen map[common.Hash]bool
+}
+
+// maxSeen is the number of transaction hashes in each generation of a
+// pendingTxRun's set of handled transactions.
+const maxSeen = 1 << 14
+
+// subscribe handles pending transactions from a subscription until an error
+// occurs, which wraps errSubscriptionsUnsupported if relevant.
+func (r *pendingTxRun) subscribe(ctx context.Context) error {
+	hashes := make(chan common.Hash, 256)
+	sub, err := r.client.EthSubscribe(ctx, hashes, "newPendingTransactions")
+	var rpcErr rpc.Error
+	switch {
+	case errors.Is(err, rpc.ErrNotificationsUnsupported):
+		return fmt.Errorf("%w: %v", errSubscriptionsUnsupported, err)
+	case errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601: // method not found
+		return fmt.Errorf("%w: %v", errSubscriptionsUnsupported, err)
+	case err != nil:
+		return fmt.Errorf("%T.EthSubscribe(ctx, …, %q): %v", r.client, "newPendingTransactions", err)
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return fmt.Errorf("subscription: %v", err)
+		case h := <-hashes:
+			if err := r.handle(ctx, h); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// poll handles pending transactions from a filter until an error occurs.
+func (r *pendingTxRun) poll(ctx context.Context) error {
+	var id string
+	if err := r.client.CallContext(ctx, &id, "eth_newPendingTransactionFilter"); err != nil {
+		return fmt.Errorf("eth_newPendingTransactionFilter: %v", err)
+	}
+	defer func() {
+		// The filter would time out on the node anyway, so this is best-effort
+		// and MUST NOT use the (possibly cancelled) ctx.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		var ok bool
+		r.client.CallContext(ctx, &ok, "eth_uninstallFilter", id)
+	}()
+
+	t := time.NewTicker(r.pollInterval())
+	defer t.Stop()
+	for {
+		var hashes []common.Hash
+		if err := r.client.CallContext(ctx, &hashes, "eth_getFilterChanges", id); err != nil {
+			return fmt.Errorf("eth_getFilterChanges(%q): %v", id, err)
+		}
+		for _, h := range hashes {
+			if err := r.handle(ctx, h); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// handle fetches the transaction, delivering it if it matches the filter. Only
+// ctx errors are returned; others are logged.
+func (r *pendingTxRun) handle(ctx context.Context, h common.Hash) error {
+	if r.seen[h] || r.prevSeen[h] {
+		return nil
+	}
+	if len(r.seen) >= maxSeen {
+		r.prevSeen, r.seen = r.seen, make(map[common.Hash]bool)
+	}
+	r.seen[h] = true
+
+	tx, isPending, err := r.eth.TransactionByHash(ctx, h)
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(err, ethereum.NotFound):
+		return nil
+	case err != nil:
+		glog.Warningf("%T.TransactionByHash(%v): %v", r.eth, h, err)
+		return nil
+	case !isPending:
+		return nil
+	}
+
+	p, err := r.match(tx)
+	if err != nil {
+		glog.V(1).Infof("Pending tx %v: %v", h, err)
+		return nil
+	}
+	if p == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case r.out <- p:
+		return nil
+	}
+}
+
+// match returns the PendingTx if tx matches the filter, otherwise nil.
+func (r *pendingTxRun) match(tx *types.Transaction) (*PendingTx, error) {
+	f := r.filter
+
+	if len(f.To) > 0 && (tx.To() == nil || !containsAddress(f.To, *tx.To())) {
+		return nil, nil
+	}
+
+	var call *Call
+	if len(f.Methods) > 0 {
+		c, err := DecodeMethodCall(tx.Data(), f.Methods...)
+		if errors.Is(err, ErrUnknownSelector) || (err != nil && len(tx.Data()) < 4) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		call = c
+	}
+
+	from, err := types.Sender(r.signer, tx)
+	if err != nil {
+		return nil, fmt.Errorf("types.Sender(): %v", err)
+	}
+	if len(f.From) > 0 && !containsAddress(f.From, from) {
+		return nil, nil
+	}
+
+	return &PendingTx{
+		Tx:   tx,
+		From: from,
+		Call: call,
+	}, nil
+}
+
+func containsAddress(addrs []common.Address, a common.Address) bool {
+	for _, b := range addrs {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
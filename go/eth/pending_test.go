@@ -0,0 +1,229 @@
+package eth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeMempool implements the subset of the eth RPC namespace required by a
+// PendingTxWatcher that polls. All transactions are pending.
+type fakeMempool struct {
+	chainID *big.Int
+
+	mu  sync.Mutex
+	txs []*types.Transaction
+	// polled is the number of txs already returned by GetFilterChanges().
+	polled int
+}
+
+func (m *fakeMempool) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(m.chainID)
+}
+
+func (m *fakeMempool) GetTransactionByHash(h common.Hash) *types.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tx := range m.txs {
+		if tx.Hash() == h {
+			return tx
+		}
+	}
+	return nil
+}
+
+func (m *fakeMempool) NewPendingTransactionFilter() string {
+	return "0x1"
+}
+
+func (m *fakeMempool) GetFilterChanges(id string) []common.Hash {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var hs []common.Hash
+	for _, tx := range m.txs[m.polled:] {
+		hs = append(hs, tx.Hash())
+	}
+	m.polled = len(m.txs)
+	return hs
+}
+
+func (m *fakeMempool) UninstallFilter(id string) bool {
+	return true
+}
+
+func (m *fakeMempool) hashes() []common.Hash {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var hs []common.Hash
+	for _, tx := range m.txs {
+		hs = append(hs, tx.Hash())
+	}
+	return hs
+}
+
+// fakeSubscribingMempool extends fakeMempool with support for the
+// newPendingTransactions subscription, which sends the hashes of all txs.
+type fakeSubscribingMempool struct {
+	*fakeMempool
+}
+
+func (m fakeSubscribingMempool) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	n, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := n.CreateSubscription()
+	hs := m.hashes()
+	go func() {
+		// Notifications are buffered until the subscription is active.
+		for _, h := range hs {
+			if err := n.Notify(sub.ID, h); err != nil {
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+func TestPendingTxWatcher(t *testing.T) {
+	chainID := big.NewInt(1337)
+
+	alice, err := crypto.ToECDSA(crypto.Keccak256([]byte("alice")))
+	if err != nil {
+		t.Fatalf("crypto.ToECDSA() error %v", err)
+	}
+	bob, err := crypto.ToECDSA(crypto.Keccak256([]byte("bob")))
+	if err != nil {
+		t.Fatalf("crypto.ToECDSA() error %v", err)
+	}
+	aliceAddr := crypto.PubkeyToAddress(alice.PublicKey)
+
+	contract := common.HexToAddress("0xc0")
+	other := common.HexToAddress("0x07")
+	mint := MustParseMethod("mint(uint256 n)")
+	burn := MustParseMethod("burn(uint256 n)")
+
+	var nonce uint64
+	newTx := func(t *testing.T, from *ecdsa.PrivateKey, to *common.Address, data []byte) *types.Transaction {
+		t.Helper()
+		nonce++
+		tx, err := types.SignNewTx(from, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			To:        to,
+			Gas:       21000,
+			GasFeeCap: big.NewInt(1),
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("types.SignNewTx() error %v", err)
+		}
+		return tx
+	}
+	pack := func(t *testing.T, m *abi.Method, n int64) []byte {
+		t.Helper()
+		buf, err := PackMethod(m, big.NewInt(n))
+		if err != nil {
+			t.Fatalf("PackMethod(%q, %d) error %v", m.Sig, n, err)
+		}
+		return buf
+	}
+
+	txs := []*types.Transaction{
+		newTx(t, alice, &contract, pack(t, mint, 1)), // match
+		newTx(t, bob, &contract, pack(t, mint, 2)),   // wrong sender
+		newTx(t, alice, &other, pack(t, mint, 3)),    // wrong recipient
+		newTx(t, alice, &contract, pack(t, burn, 4)), // wrong method
+		newTx(t, alice, &contract, []byte{1, 2}),     // no selector
+		newTx(t, alice, nil, pack(t, mint, 5)),       // contract creation
+		newTx(t, alice, &contract, pack(t, mint, 6)), // match
+	}
+	filter := PendingTxFilter{
+		From:    []common.Address{aliceAddr},
+		To:      []common.Address{contract},
+		Methods: []*abi.Method{mint},
+	}
+	wantNs := []int64{1, 6}
+
+	tests := []struct {
+		name   string
+		server func(*fakeMempool) interface{}
+	}{
+		{
+			name:   "polling",
+			server: func(m *fakeMempool) interface{} { return m },
+		},
+		{
+			name:   "subscription",
+			server: func(m *fakeMempool) interface{} { return fakeSubscribingMempool{m} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			srv := rpc.NewServer()
+			t.Cleanup(srv.Stop)
+			mempool := &fakeMempool{chainID: chainID, txs: txs}
+			if err := srv.RegisterName("eth", tt.server(mempool)); err != nil {
+				t.Fatalf("%T.RegisterName(%q, …) error %v", srv, "eth", err)
+			}
+			client := rpc.DialInProc(srv)
+			t.Cleanup(client.Close)
+
+			w := NewPendingTxWatcher(client, filter)
+			w.PollInterval = 10 * time.Millisecond
+
+			ch := make(chan *PendingTx)
+			done := make(chan error)
+			go func() {
+				done <- w.Watch(ctx, ch)
+			}()
+
+			// Transactions are handled in order so receiving the last one means
+			// that all non-matching ones have been filtered out.
+			var gotNs []int64
+			for len(gotNs) < len(wantNs) {
+				select {
+				case p := <-ch:
+					if p.From != aliceAddr {
+						t.Errorf("%T.From got %v; want %v", p, p.From, aliceAddr)
+					}
+					n, ok := p.Call.Arg("n")
+					if !ok {
+						t.Fatalf("%T.Call.Arg(%q) got false; want true", p, "n")
+					}
+					gotNs = append(gotNs, n.(*big.Int).Int64())
+				case <-ctx.Done():
+					t.Fatalf("%T.Watch() got %d pending txs before %v; want %d", w, len(gotNs), ctx.Err(), len(wantNs))
+				}
+			}
+			if diff := cmp.Diff(wantNs, gotNs); diff != "" {
+				t.Errorf("%T.Watch() arguments of delivered txs diff (-want +got):\n%s", w, diff)
+			}
+
+			cancel()
+			if err := <-done; err != context.Canceled {
+				t.Errorf("%T.Watch() after cancelling context got err %v; want %v", w, err, context.Canceled)
+			}
+		})
+	}
+}
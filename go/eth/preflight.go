@@ -0,0 +1,138 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// A Simulator executes a transaction without broadcasting it. If the
+// transaction would revert, the returned error MUST be a *RevertError, with
+// the raw revert data if available; all other errors indicate that simulation
+// itself failed.
+type Simulator interface {
+	Simulate(ctx context.Context, from common.Address, tx *types.Transaction) ([]byte, error)
+}
+
+// CallSimulator is a Simulator that uses eth_call against the latest block.
+// It is satisfied by *ethclient.Client and simulated backends.
+type CallSimulator struct {
+	ethereum.ContractCaller
+}
+
+var _ Simulator = CallSimulator{}
+
+// CallMsg returns the ethereum.CallMsg equivalent of the transaction, as sent
+// by from.
+func CallMsg(from common.Address, tx *types.Transaction) ethereum.CallMsg {
+	msg := ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	// Setting both GasPrice and the EIP-1559 fields is an error.
+	switch tx.Type() {
+	case types.DynamicFeeTxType, types.BlobTxType:
+		msg.GasFeeCap = tx.GasFeeCap()
+		msg.GasTipCap = tx.GasTipCap()
+	default:
+		msg.GasPrice = tx.GasPrice()
+	}
+	return msg
+}
+
+// Simulate implements the Simulator interface.
+func (s CallSimulator) Simulate(ctx context.Context, from common.Address, tx *types.Transaction) ([]byte, error) {
+	out, err := s.CallContract(ctx, CallMsg(from, tx), nil)
+	if err == nil {
+		return out, nil
+	}
+	if data, ok := revertData(err); ok {
+		return nil, &RevertError{Data: data, Reason: DecodeRevert(data).String()}
+	}
+	// Nodes report reverts without data as plain execution errors.
+	if strings.Contains(err.Error(), "execution reverted") {
+		return nil, &RevertError{Reason: err.Error()}
+	}
+	return nil, fmt.Errorf("%T.CallContract(…): %v", s.ContractCaller, err)
+}
+
+// A RevertError describes a simulated transaction that reverted.
+type RevertError struct {
+	// Data is the raw revert data, which MAY be empty.
+	Data []byte
+	// Reason is the human-readable, decoded form of Data; see Revert.String().
+	Reason string
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return "execution reverted"
+	}
+	return fmt.Sprintf("execution reverted: %s", e.Reason)
+}
+
+// Is reports whether target is ErrReverted, allowing a single check regardless
+// of whether a revert was detected before or after mining.
+func (e *RevertError) Is(target error) bool {
+	return target == ErrReverted
+}
+
+// A PreflightError is returned by Preflight() and PreflightAndSend() when a
+// transaction's simulation reverts.
+type PreflightError struct {
+	Tx     *types.Transaction
+	Revert *RevertError
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("preflight of tx %v: %v", e.Tx.Hash(), e.Revert)
+}
+
+func (e *PreflightError) Unwrap() error {
+	return e.Revert
+}
+
+// Preflight simulates the transaction, as sent by from, and returns a
+// *PreflightError if it would revert. If the Simulator returns a RevertError,
+// its Reason is re-decoded with the ABIs to include custom errors.
+func Preflight(ctx context.Context, sim Simulator, from common.Address, tx *types.Transaction, abis ...abi.ABI) error {
+	_, err := sim.Simulate(ctx, from, tx)
+	if err == nil {
+		return nil
+	}
+
+	var rev *RevertError
+	if !errors.As(err, &rev) {
+		return fmt.Errorf("simulating tx %v: %v", tx.Hash(), err)
+	}
+	if len(rev.Data) > 0 {
+		rev.Reason = DecodeRevert(rev.Data, abis...).String()
+	}
+	return &PreflightError{Tx: tx, Revert: rev}
+}
+
+// PreflightAndSend calls Preflight() and only sends the transaction if the
+// simulation succeeds. The sender is derived from the transaction's signature.
+func PreflightAndSend(ctx context.Context, sim Simulator, sender ethereum.TransactionSender, tx *types.Transaction, abis ...abi.ABI) error {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return fmt.Errorf("types.Sender(…, tx %v): %v", tx.Hash(), err)
+	}
+	if err := Preflight(ctx, sim, from, tx, abis...); err != nil {
+		return err
+	}
+	if err := sender.SendTransaction(ctx, tx); err != nil {
+		return fmt.Errorf("%T.SendTransaction(tx %v): %v", sender, tx.Hash(), err)
+	}
+	return nil
+}
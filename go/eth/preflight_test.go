@@ -0,0 +1,138 @@
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/cxkoda/solgo/go/ethtest"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// revertWithCalldata is contract-creation code for a contract that reverts
+// with its calldata as the revert data:
+//
+//	CALLDATASIZE PUSH1 0 DUP1 CALLDATACOPY CALLDATASIZE PUSH1 0 REVERT
+var revertWithCalldata = append(
+	[]byte{0x60, 0x09, 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, 0x09, 0x60, 0x00, 0xf3},
+	0x36, 0x60, 0x00, 0x80, 0x37, 0x36, 0x60, 0x00, 0xfd,
+)
+
+const customErrorABI = `[{"type":"error","name":"Unauthorized","inputs":[{"name":"who","type":"address"}]}]`
+
+func TestPreflight(t *testing.T) {
+	ctx := context.Background()
+	sim := ethtest.NewSimulatedBackendTB(t, 2)
+
+	deploy := signTx(t, sim, 0, nil, revertWithCalldata)
+	if err := sim.SendTransaction(ctx, deploy); err != nil {
+		t.Fatalf("%T.SendTransaction([deploy]) error %v", sim, err)
+	}
+	sim.Commit()
+	rcpt, err := sim.TransactionReceipt(ctx, deploy.Hash())
+	if err != nil {
+		t.Fatalf("%T.TransactionReceipt([deploy]) error %v", sim, err)
+	}
+	if rcpt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("%T.TransactionReceipt([deploy]).Status got %d; want %d", sim, rcpt.Status, types.ReceiptStatusSuccessful)
+	}
+	reverter := rcpt.ContractAddress
+
+	custom, err := abi.JSON(strings.NewReader(customErrorABI))
+	if err != nil {
+		t.Fatalf("abi.JSON(%q) error %v", customErrorABI, err)
+	}
+
+	errorString, err := PackCall("Error(string)", "not allowed")
+	if err != nil {
+		t.Fatalf("PackCall(Error(string)) error %v", err)
+	}
+	panicOverflow, err := PackCall("Panic(uint256)", big.NewInt(0x11))
+	if err != nil {
+		t.Fatalf("PackCall(Panic(uint256)) error %v", err)
+	}
+	// Errors are encoded identically to calls.
+	unauthorized, err := PackCall("Unauthorized(address)", common.HexToAddress("0xb0b"))
+	if err != nil {
+		t.Fatalf("PackCall(Unauthorized(address)) error %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		to         *common.Address
+		data       []byte
+		wantReason string // empty implies success
+	}{
+		{
+			name: "success",
+			to:   &common.Address{},
+		},
+		{
+			name:       "Error(string)",
+			to:         &reverter,
+			data:       errorString,
+			wantReason: "not allowed",
+		},
+		{
+			name:       "Panic(uint256)",
+			to:         &reverter,
+			data:       panicOverflow,
+			wantReason: "panic 0x11 (arithmetic overflow or underflow)",
+		},
+		{
+			name:       "custom error",
+			to:         &reverter,
+			data:       unauthorized,
+			wantReason: "Unauthorized(who: " + common.HexToAddress("0xb0b").Hex() + ")",
+		},
+		{
+			name:       "undecodable",
+			to:         &reverter,
+			data:       []byte{0xde, 0xad, 0xbe, 0xef, 0x01},
+			wantReason: "0xdeadbeef01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := signTx(t, sim, 1, tt.to, tt.data)
+			err := PreflightAndSend(ctx, CallSimulator{sim}, sim, tx, custom)
+
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("PreflightAndSend() error %v", err)
+				}
+				sim.Commit()
+				if _, err := sim.TransactionReceipt(ctx, tx.Hash()); err != nil {
+					t.Errorf("%T.TransactionReceipt(%v) after successful PreflightAndSend() error %v", sim, tx.Hash(), err)
+				}
+				return
+			}
+
+			var perr *PreflightError
+			if !errors.As(err, &perr) {
+				t.Fatalf("PreflightAndSend() got err %v; want %T", err, perr)
+			}
+			if got := perr.Revert.Reason; got != tt.wantReason {
+				t.Errorf("PreflightAndSend() got revert reason %q; want %q", got, tt.wantReason)
+			}
+			if !errors.Is(err, ErrReverted) {
+				t.Errorf("errors.Is(PreflightAndSend() error, ErrReverted) got false; want true")
+			}
+
+			if _, pending, err := sim.TransactionByHash(ctx, tx.Hash()); err == nil || pending {
+				t.Errorf("%T.TransactionByHash(%v) after failed preflight got pending %t, err %v; want not found", sim, tx.Hash(), pending, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+// Package pricefeeds reads exchange rates from Chainlink price-feed
+// aggregators, allowing on-chain amounts to be converted to fiat values
+// without depending on external APIs.
+package pricefeeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// A Pair is a currency pair, priced as the number of Quote units per Base
+// unit; e.g. ETH/USD.
+type Pair struct {
+	Base, Quote string
+}
+
+// ParsePair parses a pair of the form "BASE/QUOTE", converting both to upper
+// case.
+func ParsePair(s string) (Pair, error) {
+	base, quote, ok := strings.Cut(s, "/")
+	base, quote = strings.TrimSpace(base), strings.TrimSpace(quote)
+	if !ok || base == "" || quote == "" || strings.Contains(quote, "/") {
+		return Pair{}, fmt.Errorf("invalid pair %q; must be BASE/QUOTE", s)
+	}
+	return Pair{strings.ToUpper(base), strings.ToUpper(quote)}, nil
+}
+
+// String returns the pair as "BASE/QUOTE".
+func (p Pair) String() string {
+	return p.Base + "/" + p.Quote
+}
+
+// A Feed is a Chainlink aggregator, typically the proxy (e.g.
+// EACAggregatorProxy) that is stable across aggregator upgrades.
+type Feed struct {
+	ChainID uint64
+	Pair    Pair
+	Address common.Address
+	// Heartbeat is the maximum interval between updates, regardless of price
+	// deviation. Rounds older than the Heartbeat (plus Client.Grace) are
+	// considered stale.
+	Heartbeat time.Duration
+}
+
+// String returns the feed's pair, chain, and address.
+func (f Feed) String() string {
+	return fmt.Sprintf("%v on chain %d @ %v", f.Pair, f.ChainID, f.Address)
+}
+
+type feedKey struct {
+	chainID uint64
+	pair    Pair
+}
+
+// The feed registry, populated with defaultFeeds by init() and added to with
+// RegisterFeed().
+var (
+	feedsMu sync.RWMutex
+	feeds   = make(map[feedKey]Feed)
+)
+
+// defaultFeeds are well-known proxies, as listed at
+// https://docs.chain.link/data-feeds/price-feeds/addresses.
+var defaultFeeds = []Feed{
+	{eth.MainnetChainID, Pair{"ETH", "USD"}, common.HexToAddress("0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419"), time.Hour},
+	{eth.MainnetChainID, Pair{"BTC", "USD"}, common.HexToAddress("0xF4030086522a5bEEa4988F8cA5B36dbC97BeE88c"), time.Hour},
+	{eth.MainnetChainID, Pair{"LINK", "USD"}, common.HexToAddress("0x2c1d072e956AFFC0D435Cb7AC38EF18d24d9127c"), time.Hour},
+	{eth.MainnetChainID, Pair{"DAI", "USD"}, common.HexToAddress("0xAed0c38402a5d19df6E4c03F4E2DceD6e29c1ee9"), time.Hour},
+	{eth.MainnetChainID, Pair{"USDC", "USD"}, common.HexToAddress("0x8fFfFfd4AfB6115b954Bd326cbe7B4BA576818f6"), 24 * time.Hour},
+	{eth.MainnetChainID, Pair{"USDT", "USD"}, common.HexToAddress("0x3E7d1eAB13ad0104d2750B8863b489D65364e32D"), 24 * time.Hour},
+	{eth.SepoliaChainID, Pair{"ETH", "USD"}, common.HexToAddress("0x694AA1769357215DE4FAC081bf1f309aDC325306"), time.Hour},
+	{eth.OptimismChainID, Pair{"ETH", "USD"}, common.HexToAddress("0x13e3Ee699D1909E989722E753853AE30b17e08c5"), 20 * time.Minute},
+	{eth.BaseChainID, Pair{"ETH", "USD"}, common.HexToAddress("0x71041dddad3595F9CEd3DcCFBe3D1F4b0a16Bb70"), 20 * time.Minute},
+	{eth.ArbitrumChainID, Pair{"ETH", "USD"}, common.HexToAddress("0x639Fe6ab55C921f74e7fac1ee960C0B6293ba612"), 24 * time.Hour},
+}
+
+func init() {
+	for _, f := range defaultFeeds {
+		if err := RegisterFeed(f); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterFeed adds the Feed to the registry, making it available to
+// FeedFor(). Pairs are upper-cased. It is an error to register a feed for a
+// chain and pair that are already registered.
+func RegisterFeed(f Feed) error {
+	f.Pair = Pair{strings.ToUpper(f.Pair.Base), strings.ToUpper(f.Pair.Quote)}
+	if f.Pair.Base == "" || f.Pair.Quote == "" {
+		return fmt.Errorf("registering feed %v: empty currency", f)
+	}
+	if f.Heartbeat <= 0 {
+		return fmt.Errorf("registering feed %v: non-positive heartbeat %v", f, f.Heartbeat)
+	}
+
+	feedsMu.Lock()
+	defer feedsMu.Unlock()
+
+	k := feedKey{f.ChainID, f.Pair}
+	if existing, ok := feeds[k]; ok {
+		return fmt.Errorf("registering feed %v: already registered as %v", f, existing)
+	}
+	feeds[k] = f
+	return nil
+}
+
+// FeedFor returns the registered Feed for the chain and pair, and a boolean
+// indicating whether it was found.
+func FeedFor(chainID uint64, pair Pair) (Feed, bool) {
+	feedsMu.RLock()
+	defer feedsMu.RUnlock()
+	f, ok := feeds[feedKey{chainID, Pair{strings.ToUpper(pair.Base), strings.ToUpper(pair.Quote)}}]
+	return f, ok
+}
+
+// Feeds returns all Feeds registered for the chain, sorted by pair.
+func Feeds(chainID uint64) []Feed {
+	feedsMu.RLock()
+	defer feedsMu.RUnlock()
+
+	var fs []Feed
+	for k, f := range feeds {
+		if k.chainID == chainID {
+			fs = append(fs, f)
+		}
+	}
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Pair.String() < fs[j].Pair.String() })
+	return fs
+}
+
+// Errors returned, wrapped, by Client methods when a round can't be trusted.
+var (
+	// ErrStaleRound is returned if a round is older than its feed's
+	// Heartbeat plus the Client's Grace.
+	ErrStaleRound = errors.New("stale price-feed round")
+	// ErrInvalidRound is returned if a round has a non-positive answer, was
+	// never updated, or was carried over from an earlier round.
+	ErrInvalidRound = errors.New("invalid price-feed round")
+)
+
+// A Round is the result of an aggregator's latestRoundData(), along with its
+// decimals().
+type Round struct {
+	Feed            Feed
+	RoundID         *big.Int
+	Answer          *big.Int
+	StartedAt       time.Time
+	UpdatedAt       time.Time
+	AnsweredInRound *big.Int
+	Decimals        uint8
+}
+
+// Price returns the Answer as an Amount of the Feed's quote currency per unit
+// of its base currency. Rounds returned by a Client always have a positive
+// Answer.
+func (r *Round) Price() eth.Amount {
+	return eth.NewAmount(uint256.MustFromBig(r.Answer), r.Decimals)
+}
+
+// Convert returns the value, in the Feed's quote currency, of an Amount of its
+// base currency. The returned Amount has the Round's Decimals, and is truncated
+// rather than rounded.
+func (r *Round) Convert(a eth.Amount) (eth.Amount, error) {
+	price, overflow := uint256.FromBig(r.Answer)
+	if overflow || r.Answer.Sign() <= 0 {
+		return eth.Amount{}, fmt.Errorf("%w: answer %v", ErrInvalidRound, r.Answer)
+	}
+	scale := new(uint256.Int).Exp(uint256.NewInt(10), uint256.NewInt(uint64(a.Decimals)))
+
+	v, overflow := new(uint256.Int).MulDivOverflow(&a.Value, price, scale)
+	if overflow {
+		return eth.Amount{}, fmt.Errorf("converting %v %s at %v %v: overflow", a, r.Feed.Pair.Base, r.Price(), r.Feed.Pair)
+	}
+	return eth.NewAmount(v, r.Decimals), nil
+}
+
+// Aggregator methods, as defined by Chainlink's AggregatorV3Interface.
+var (
+	decimalsMethod        = eth.MustParseMethod("decimals() returns (uint8)")
+	latestRoundDataMethod = eth.MustParseMethod("latestRoundData() returns (uint80 roundId, int256 answer, uint256 startedAt, uint256 updatedAt, uint80 answeredInRound)")
+)
+
+// A Client reads Rounds from Chainlink aggregators.
+type Client struct {
+	Backend bind.ContractCaller
+	// ChainID is the chain of the Backend, used to find registered Feeds.
+	ChainID uint64
+	// Grace is added to a Feed's Heartbeat when checking for stale rounds,
+	// to allow for delays in including updates. If zero, DefaultGrace is used.
+	Grace time.Duration
+	// Now, if non-nil, replaces time.Now() when checking for stale rounds.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	decimals map[common.Address]uint8
+}
+
+// DefaultGrace is the default value of Client.Grace.
+const DefaultGrace = 5 * time.Minute
+
+// call calls the method on the contract at the latest block, returning its
+// unpacked outputs.
+func (c *Client) call(ctx context.Context, addr common.Address, m *abi.Method) ([]interface{}, error) {
+	out, err := c.Backend.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: m.ID}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%T.CallContract(%v.%s): %v", c.Backend, addr, m.Sig, err)
+	}
+	vals, err := m.Outputs.Unpack(out)
+	if err != nil {
+		return nil, fmt.Errorf("unpacking %v.%s return data %#x: %v", addr, m.Sig, out, err)
+	}
+	return vals, nil
+}
+
+// feedDecimals returns the decimals() of the feed, which are cached as they
+// are immutable for a given aggregator.
+func (c *Client) feedDecimals(ctx context.Context, f Feed) (uint8, error) {
+	c.mu.Lock()
+	d, ok := c.decimals[f.Address]
+	c.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	vals, err := c.call(ctx, f.Address, decimalsMethod)
+	if err != nil {
+		return 0, err
+	}
+	d = vals[0].(uint8)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.decimals == nil {
+		c.decimals = make(map[common.Address]uint8)
+	}
+	c.decimals[f.Address] = d
+	return d, nil
+}
+
+// LatestRound returns the feed's latest Round, after checking that it is
+// neither invalid nor stale. Returned errors wrap ErrInvalidRound or
+// ErrStaleRound if the round was read but can't be trusted.
+func (c *Client) LatestRound(ctx context.Context, f Feed) (*Round, error) {
+	decimals, err := c.feedDecimals(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := c.call(ctx, f.Address, latestRoundDataMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Round{
+		Feed:            f,
+		RoundID:         vals[0].(*big.Int),
+		Answer:          vals[1].(*big.Int),
+		StartedAt:       time.Unix(vals[2].(*big.Int).Int64(), 0),
+		UpdatedAt:       time.Unix(vals[3].(*big.Int).Int64(), 0),
+		AnsweredInRound: vals[4].(*big.Int),
+		Decimals:        decimals,
+	}
+
+	switch {
+	case r.Answer.Sign() <= 0:
+		return nil, fmt.Errorf("%w: %v round %v has answer %v", ErrInvalidRound, f, r.RoundID, r.Answer)
+	case vals[3].(*big.Int).Sign() == 0:
+		return nil, fmt.Errorf("%w: %v round %v never updated", ErrInvalidRound, f, r.RoundID)
+	case r.AnsweredInRound.Cmp(r.RoundID) < 0:
+		return nil, fmt.Errorf("%w: %v round %v answered in earlier round %v", ErrInvalidRound, f, r.RoundID, r.AnsweredInRound)
+	}
+
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	grace := c.Grace
+	if grace == 0 {
+		grace = DefaultGrace
+	}
+	if age := now().Sub(r.UpdatedAt); age > f.Heartbeat+grace {
+		return nil, fmt.Errorf("%w: %v round %v updated %v ago at %v; heartbeat %v", ErrStaleRound, f, r.RoundID, age.Truncate(time.Second), r.UpdatedAt.UTC(), f.Heartbeat)
+	}
+	return r, nil
+}
+
+// Price returns the LatestRound() of the Feed registered for the Client's
+// chain and the pair.
+func (c *Client) Price(ctx context.Context, pair Pair) (*Round, error) {
+	f, ok := FeedFor(c.ChainID, pair)
+	if !ok {
+		return nil, fmt.Errorf("no %v feed registered for chain %d", pair, c.ChainID)
+	}
+	return c.LatestRound(ctx, f)
+}
+
+// NativeValue returns the value of the wei amount of the chain's native
+// currency in the quote currency (e.g. "USD"), along with the Round used for
+// conversion.
+func (c *Client) NativeValue(ctx context.Context, wei *big.Int, quote string) (eth.Amount, *Round, error) {
+	chain, ok := eth.ChainByID(c.ChainID)
+	if !ok {
+		return eth.Amount{}, nil, fmt.Errorf("chain %d not registered", c.ChainID)
+	}
+	v, overflow := uint256.FromBig(wei)
+	if overflow || wei.Sign() < 0 {
+		return eth.Amount{}, nil, fmt.Errorf("wei amount %v out of range", wei)
+	}
+
+	r, err := c.Price(ctx, Pair{chain.NativeSymbol, quote})
+	if err != nil {
+		return eth.Amount{}, nil, err
+	}
+	a, err := r.Convert(eth.EtherAmount(v))
+	if err != nil {
+		return eth.Amount{}, nil, err
+	}
+	return a, r, nil
+}
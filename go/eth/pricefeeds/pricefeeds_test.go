@@ -0,0 +1,275 @@
+package pricefeeds
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeAggregator implements bind.ContractCaller for a single Chainlink
+// aggregator.
+type fakeAggregator struct {
+	addr     common.Address
+	decimals uint8
+	round    struct {
+		id, answer           *big.Int
+		startedAt, updatedAt int64
+		answeredIn           *big.Int
+	}
+
+	calls map[string]int
+}
+
+func (f *fakeAggregator) setRound(id, answer int64, updatedAt time.Time) {
+	f.round.id = big.NewInt(id)
+	f.round.answer = big.NewInt(answer)
+	f.round.startedAt = updatedAt.Unix()
+	f.round.updatedAt = updatedAt.Unix()
+	f.round.answeredIn = big.NewInt(id)
+}
+
+func (f *fakeAggregator) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return []byte{0}, nil
+}
+
+func (f *fakeAggregator) CallContract(ctx context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	if call.To == nil || *call.To != f.addr {
+		return nil, fmt.Errorf("call to %v; only %v supported", call.To, f.addr)
+	}
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+
+	switch {
+	case bytes.Equal(call.Data, decimalsMethod.ID):
+		f.calls["decimals"]++
+		return decimalsMethod.Outputs.Pack(f.decimals)
+	case bytes.Equal(call.Data, latestRoundDataMethod.ID):
+		f.calls["latestRoundData"]++
+		r := f.round
+		return latestRoundDataMethod.Outputs.Pack(r.id, r.answer, big.NewInt(r.startedAt), big.NewInt(r.updatedAt), r.answeredIn)
+	}
+	return nil, fmt.Errorf("unsupported calldata %#x", call.Data)
+}
+
+func TestLatestRound(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+
+	feed := Feed{
+		ChainID:   eth.MainnetChainID,
+		Pair:      Pair{"ETH", "USD"},
+		Address:   common.HexToAddress("0xfeed"),
+		Heartbeat: time.Hour,
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(*fakeAggregator)
+		wantErr error
+	}{
+		{
+			name:  "fresh",
+			setup: func(f *fakeAggregator) { f.setRound(42, 2000_00000000, now.Add(-time.Minute)) },
+		},
+		{
+			name:  "within grace",
+			setup: func(f *fakeAggregator) { f.setRound(42, 2000_00000000, now.Add(-time.Hour-DefaultGrace)) },
+		},
+		{
+			name:    "stale",
+			setup:   func(f *fakeAggregator) { f.setRound(42, 2000_00000000, now.Add(-time.Hour-DefaultGrace-time.Second)) },
+			wantErr: ErrStaleRound,
+		},
+		{
+			name:    "zero answer",
+			setup:   func(f *fakeAggregator) { f.setRound(42, 0, now) },
+			wantErr: ErrInvalidRound,
+		},
+		{
+			name:    "negative answer",
+			setup:   func(f *fakeAggregator) { f.setRound(42, -1, now) },
+			wantErr: ErrInvalidRound,
+		},
+		{
+			name: "never updated",
+			setup: func(f *fakeAggregator) {
+				f.setRound(42, 2000_00000000, now)
+				f.round.updatedAt = 0
+			},
+			wantErr: ErrInvalidRound,
+		},
+		{
+			name: "carried over",
+			setup: func(f *fakeAggregator) {
+				f.setRound(42, 2000_00000000, now)
+				f.round.answeredIn = big.NewInt(41)
+			},
+			wantErr: ErrInvalidRound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := &fakeAggregator{addr: feed.Address, decimals: 8}
+			tt.setup(agg)
+			c := &Client{
+				Backend: agg,
+				ChainID: feed.ChainID,
+				Now:     func() time.Time { return now },
+			}
+
+			got, err := c.LatestRound(ctx, feed)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr != nil && err == nil) {
+				t.Fatalf("%T.LatestRound(%v) err: %v; want errors.Is(…, %v)", c, feed, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Answer.Cmp(agg.round.answer) != 0 || got.Decimals != agg.decimals || !got.UpdatedAt.Equal(time.Unix(agg.round.updatedAt, 0)) {
+				t.Errorf("%T.LatestRound(%v) got %+v; want answer %v, %d decimals, updated at %d", c, feed, got, agg.round.answer, agg.decimals, agg.round.updatedAt)
+			}
+			if got, want := got.Price().String(), "2000"; got != want {
+				t.Errorf("%T.LatestRound(%v).Price() got %s; want %s", c, feed, got, want)
+			}
+		})
+	}
+}
+
+func TestDecimalsCached(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	feed := Feed{Address: common.HexToAddress("0xfeed"), Pair: Pair{"ETH", "USD"}, Heartbeat: time.Hour}
+	agg := &fakeAggregator{addr: feed.Address, decimals: 8}
+	agg.setRound(1, 1, now)
+	c := &Client{Backend: agg}
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.LatestRound(ctx, feed); err != nil {
+			t.Fatalf("%T.LatestRound(%v) error %v", c, feed, err)
+		}
+	}
+	want := map[string]int{"decimals": 1, "latestRoundData": 3}
+	if diff := cmp.Diff(want, agg.calls); diff != "" {
+		t.Errorf("calls to aggregator diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestNativeValue(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+
+	feed, ok := FeedFor(eth.MainnetChainID, Pair{"eth", "usd"})
+	if !ok {
+		t.Fatalf("FeedFor(mainnet, eth/usd) not found")
+	}
+	agg := &fakeAggregator{addr: feed.Address, decimals: 8}
+	agg.setRound(1, 1234_56789012, now) // $1234.56789012
+	c := &Client{
+		Backend: agg,
+		ChainID: eth.MainnetChainID,
+		Now:     func() time.Time { return now },
+	}
+
+	tests := []struct {
+		wei  *big.Int
+		want string
+	}{
+		{eth.Ether(1), "1234.56789012"},
+		{eth.EtherFraction(1, 2), "617.28394506"},
+		{big.NewInt(1), "0"}, // truncated
+		{eth.Ether(1_000_000), "1234567890.12"},
+	}
+
+	for _, tt := range tests {
+		got, r, err := c.NativeValue(ctx, tt.wei, "USD")
+		if err != nil {
+			t.Errorf("%T.NativeValue(%d wei, USD) error %v", c, tt.wei, err)
+			continue
+		}
+		if got.String() != tt.want || got.Decimals != 8 {
+			t.Errorf("%T.NativeValue(%d wei, USD) got %s with %d decimals; want %s with 8", c, tt.wei, got, got.Decimals, tt.want)
+		}
+		if r.Feed != feed {
+			t.Errorf("%T.NativeValue() used feed %v; want %v", c, r.Feed, feed)
+		}
+	}
+
+	if _, _, err := c.NativeValue(ctx, eth.Ether(1), "EUR"); err == nil {
+		t.Errorf("%T.NativeValue(…, EUR) with no registered feed got nil error; want non-nil", c)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	const chainID = 31337
+	f := Feed{
+		ChainID:   chainID,
+		Pair:      Pair{"foo", "bar"},
+		Address:   common.HexToAddress("0xf00"),
+		Heartbeat: time.Minute,
+	}
+	if err := RegisterFeed(f); err != nil {
+		t.Fatalf("RegisterFeed(%v) error %v", f, err)
+	}
+	if err := RegisterFeed(f); err == nil {
+		t.Errorf("RegisterFeed(%v) a second time got nil error; want non-nil", f)
+	}
+	noHeartbeat := f
+	noHeartbeat.Pair.Base = "BAZ"
+	noHeartbeat.Heartbeat = 0
+	if err := RegisterFeed(noHeartbeat); err == nil {
+		t.Errorf("RegisterFeed(%v) without heartbeat got nil error; want non-nil", noHeartbeat)
+	}
+
+	want := f
+	want.Pair = Pair{"FOO", "BAR"}
+	if got, ok := FeedFor(chainID, Pair{"Foo", "Bar"}); !ok || got != want {
+		t.Errorf("FeedFor(%d, Foo/Bar) got %v, %t; want %v, true", chainID, got, ok, want)
+	}
+	if diff := cmp.Diff([]Feed{want}, Feeds(chainID)); diff != "" {
+		t.Errorf("Feeds(%d) diff (-want +got):\n%s", chainID, diff)
+	}
+
+	for _, c := range eth.Chains() {
+		for _, f := range Feeds(c.ID) {
+			if f.Address == (common.Address{}) {
+				t.Errorf("default feed %v has zero address", f)
+			}
+		}
+	}
+}
+
+func TestParsePair(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Pair
+	}{
+		{"ETH/USD", Pair{"ETH", "USD"}},
+		{" eth / usd ", Pair{"ETH", "USD"}},
+	} {
+		got, err := ParsePair(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePair(%q) got %v, err %v; want %v, nil", tt.in, got, err, tt.want)
+		}
+		if s := got.String(); s != tt.want.Base+"/"+tt.want.Quote {
+			t.Errorf("%T.String() got %q", got, s)
+		}
+	}
+
+	for _, in := range []string{"", "ETH", "ETH/", "/USD", "A/B/C"} {
+		if _, err := ParsePair(in); err == nil {
+			t.Errorf("ParsePair(%q) got nil error; want non-nil", in)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ERC721TransferTopic is the topic of the ERC721 Transfer event. ERC20
+// Transfer events have the same topic but fewer indexed arguments.
+var ERC721TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// A ProvenanceBackend provides the chain data required by TokenProvenance().
+// It is satisfied by *ethclient.Client.
+type ProvenanceBackend interface {
+	LogSource
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// An OwnershipChange is a single ERC721 Transfer of a token, including its mint
+// (From is the zero address) and burn (To is the zero address).
+type OwnershipChange struct {
+	Block    uint64
+	TxHash   common.Hash
+	LogIndex uint
+	From, To common.Address
+	// Sale is non-nil iff a SaleDecoder detected a sale of the token in the
+	// same transaction.
+	Sale *Sale
+}
+
+// A Sale is the price paid for a token on a marketplace.
+type Sale struct {
+	Marketplace string
+	// Currency is the ERC20 contract in which the price was paid, or the zero
+	// address for the chain's native token.
+	Currency common.Address
+	// Price is the total paid by the buyer, including marketplace and creator
+	// fees.
+	Price *big.Int
+}
+
+// A SaleDecoder detects marketplace sales from the logs of transactions in
+// which a token was transferred.
+type SaleDecoder interface {
+	// DecodeSale returns the Sale of the token if the log records one,
+	// otherwise it returns nil. Logs that record sales of multiple tokens
+	// (i.e. bundles) SHOULD be ignored as the price of each can't be
+	// determined.
+	DecodeSale(l *types.Log, contract common.Address, tokenID *big.Int) (*Sale, error)
+}
+
+// A ProvenanceOption modifies the behaviour of TokenProvenance().
+type ProvenanceOption func(*provenanceConfig)
+
+type provenanceConfig struct {
+	fromBlock, maxRange uint64
+	decoders            []SaleDecoder
+}
+
+// WithProvenanceFromBlock limits TokenProvenance() to Transfers in or after the
+// block, which SHOULD be that in which the contract was deployed, or earlier.
+// It defaults to 0.
+func WithProvenanceFromBlock(n uint64) ProvenanceOption {
+	return func(c *provenanceConfig) {
+		c.fromBlock = n
+	}
+}
+
+// WithProvenanceMaxBlockRange limits the number of blocks requested in a
+// single call to FilterLogs(); see Subscriber.MaxBackfillRange. By default,
+// all blocks are requested at once as filtering by token ID typically results
+// in few logs, which most nodes allow over unlimited ranges.
+func WithProvenanceMaxBlockRange(n uint64) ProvenanceOption {
+	return func(c *provenanceConfig) {
+		c.maxRange = n
+	}
+}
+
+// WithSaleDecoders replaces the SaleDecoders used by TokenProvenance(), which
+// default to SeaportSales. Passing none disables price detection, avoiding the
+// need to fetch receipts.
+func WithSaleDecoders(ds ...SaleDecoder) ProvenanceOption {
+	return func(c *provenanceConfig) {
+		c.decoders = ds
+	}
+}
+
+// TokenProvenance returns the full ownership history of an ERC721 token, in
+// chain order, by scanning the contract's Transfer logs. The receipt of each
+// Transfer's transaction is checked by the SaleDecoders to detect the price
+// paid, if any. If a transaction transfers the token more than once (e.g. via
+// an aggregator) then the Sale is attached to the first such transfer.
+func TokenProvenance(ctx context.Context, client ProvenanceBackend, contract common.Address, tokenID *big.Int, opts ...ProvenanceOption) ([]OwnershipChange, error) {
+	cfg := &provenanceConfig{
+		decoders: []SaleDecoder{SeaportSales},
+	}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%T.BlockNumber(): %v", client, err)
+	}
+	if cfg.fromBlock > head {
+		return nil, nil
+	}
+
+	sub := NewSubscriber(client, ethereum.FilterQuery{
+		Addresses: []common.Address{contract},
+		Topics:    [][]common.Hash{{ERC721TransferTopic}, nil, nil, {common.BigToHash(tokenID)}},
+	})
+	sub.MaxBackfillRange = cfg.maxRange
+	if sub.MaxBackfillRange == 0 {
+		sub.MaxBackfillRange = head - cfg.fromBlock + 1
+	}
+
+	var changes []OwnershipChange
+	err = sub.Range(ctx, cfg.fromBlock, head, func(l types.Log) error {
+		if len(l.Topics) != 4 || l.Topics[0] != ERC721TransferTopic {
+			return nil
+		}
+		changes = append(changes, OwnershipChange{
+			Block:    l.BlockNumber,
+			TxHash:   l.TxHash,
+			LogIndex: l.Index,
+			From:     common.BytesToAddress(l.Topics[1].Bytes()),
+			To:       common.BytesToAddress(l.Topics[2].Bytes()),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning blocks [%d, %d]: %v", cfg.fromBlock, head, err)
+	}
+
+	if len(cfg.decoders) == 0 {
+		return changes, nil
+	}
+	checked := make(map[common.Hash]bool)
+	for i, c := range changes {
+		if checked[c.TxHash] {
+			continue
+		}
+		checked[c.TxHash] = true
+
+		rcpt, err := client.TransactionReceipt(ctx, c.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("%T.TransactionReceipt(%v): %v", client, c.TxHash, err)
+		}
+		sale, err := decodeSale(cfg.decoders, rcpt, contract, tokenID)
+		if err != nil {
+			return nil, fmt.Errorf("tx %v: %v", c.TxHash, err)
+		}
+		changes[i].Sale = sale
+	}
+	return changes, nil
+}
+
+// decodeSale returns the first Sale detected by any of the decoders, in log
+// order, or nil if there are none.
+func decodeSale(decoders []SaleDecoder, rcpt *types.Receipt, contract common.Address, tokenID *big.Int) (*Sale, error) {
+	for _, l := range rcpt.Logs {
+		for _, d := range decoders {
+			s, err := d.DecodeSale(l, contract, tokenID)
+			if err != nil {
+				return nil, fmt.Errorf("log index %d: %T.DecodeSale(): %v", l.Index, d, err)
+			}
+			if s != nil {
+				return s, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// SeaportSales is a SaleDecoder of OrderFulfilled events emitted by the
+// canonical Seaport deployments, as used by OpenSea and others. Both accepted
+// listings (the token is offered) and accepted bids (the token is a
+// consideration item) are detected, and the price is the sum of the other
+// side's native or ERC20 items. Orders in multiple currencies are ignored.
+var SeaportSales SaleDecoder = seaportDecoder{
+	addresses: map[common.Address]bool{
+		common.HexToAddress("0x00000000006c3852cbEf3e08E8dF289169EdE581"): true, // v1.1
+		common.HexToAddress("0x00000000000001ad428e4906aE43D8F9852d0dD6"): true, // v1.4
+		common.HexToAddress("0x00000000000000ADc04C56Bf30aC9d3c0aAF14dC"): true, // v1.5
+		common.HexToAddress("0x0000000000000068F116a894984e2DB1123eB395"): true, // v1.6
+	},
+}
+
+const seaportABIJSON = `[{
+	"type": "event",
+	"name": "OrderFulfilled",
+	"inputs": [
+		{"name": "orderHash", "type": "bytes32", "indexed": false},
+		{"name": "offerer", "type": "address", "indexed": true},
+		{"name": "zone", "type": "address", "indexed": true},
+		{"name": "recipient", "type": "address", "indexed": false},
+		{"name": "offer", "type": "tuple[]", "indexed": false, "components": [
+			{"name": "itemType", "type": "uint8"},
+			{"name": "token", "type": "address"},
+			{"name": "identifier", "type": "uint256"},
+			{"name": "amount", "type": "uint256"}
+		]},
+		{"name": "consideration", "type": "tuple[]", "indexed": false, "components": [
+			{"name": "itemType", "type": "uint8"},
+			{"name": "token", "type": "address"},
+			{"name": "identifier", "type": "uint256"},
+			{"name": "amount", "type": "uint256"},
+			{"name": "recipient", "type": "address"}
+		]}
+	]
+}]`
+
+var seaportABI = func() abi.ABI {
+	a, err := abi.JSON(strings.NewReader(seaportABIJSON))
+	if err != nil {
+		panic(fmt.Sprintf("abi.JSON(seaportABIJSON): %v", err))
+	}
+	return a
+}()
+
+type seaportDecoder struct {
+	addresses map[common.Address]bool
+}
+
+// seaportItem holds the fields common to Seaport's SpentItem and
+// ReceivedItem.
+type seaportItem struct {
+	ItemType   uint8
+	Token      common.Address
+	Identifier *big.Int
+	Amount     *big.Int
+}
+
+// Seaport ItemType values.
+const (
+	seaportNative = iota
+	seaportERC20
+	seaportERC721
+	seaportERC1155
+	seaportERC721WithCriteria
+	seaportERC1155WithCriteria
+)
+
+func (d seaportDecoder) DecodeSale(l *types.Log, contract common.Address, tokenID *big.Int) (*Sale, error) {
+	ev := seaportABI.Events["OrderFulfilled"]
+	if !d.addresses[l.Address] || len(l.Topics) != 3 || l.Topics[0] != ev.ID {
+		return nil, nil
+	}
+	vals, err := ev.Inputs.NonIndexed().Unpack(l.Data)
+	if err != nil {
+		return nil, fmt.Errorf("unpack OrderFulfilled: %v", err)
+	}
+	if len(vals) != 4 {
+		return nil, fmt.Errorf("unpack OrderFulfilled: got %d values; want 4", len(vals))
+	}
+
+	var offer, consideration []seaportItem
+	for i, dst := range []*[]seaportItem{&offer, &consideration} {
+		items, err := convertSeaportItems(vals[2+i])
+		if err != nil {
+			return nil, err
+		}
+		*dst = items
+	}
+
+	var payment []seaportItem
+	switch {
+	case isSoleNFT(offer, contract, tokenID):
+		payment = consideration
+	case isSoleNFT(consideration, contract, tokenID):
+		payment = offer
+	default:
+		return nil, nil
+	}
+
+	var sale *Sale
+	for _, it := range payment {
+		if it.ItemType != seaportNative && it.ItemType != seaportERC20 {
+			continue
+		}
+		if sale == nil {
+			sale = &Sale{
+				Marketplace: "Seaport",
+				Currency:    it.Token,
+				Price:       new(big.Int),
+			}
+		}
+		if it.Token != sale.Currency {
+			return nil, nil
+		}
+		sale.Price.Add(sale.Price, it.Amount)
+	}
+	return sale, nil
+}
+
+// convertSeaportItems converts an unpacked SpentItem[] or ReceivedItem[] into
+// seaportItems.
+func convertSeaportItems(v interface{}) ([]seaportItem, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("unpacked Seaport items of type %T; want slice", v)
+	}
+	items := make([]seaportItem, rv.Len())
+	for i := range items {
+		el := rv.Index(i)
+		it := &items[i]
+		it.ItemType = el.FieldByName("ItemType").Interface().(uint8)
+		it.Token = el.FieldByName("Token").Interface().(common.Address)
+		it.Identifier = el.FieldByName("Identifier").Interface().(*big.Int)
+		it.Amount = el.FieldByName("Amount").Interface().(*big.Int)
+	}
+	return items, nil
+}
+
+// isSoleNFT reports whether items contain the specified token and no other
+// NFTs.
+func isSoleNFT(items []seaportItem, contract common.Address, tokenID *big.Int) bool {
+	var found bool
+	for _, it := range items {
+		switch it.ItemType {
+		case seaportERC721, seaportERC721WithCriteria, seaportERC1155, seaportERC1155WithCriteria:
+		default:
+			continue
+		}
+		if found || it.Token != contract || it.Identifier.Cmp(tokenID) != 0 {
+			return false
+		}
+		found = true
+	}
+	return found
+}
@@ -0,0 +1,304 @@
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeProvenanceBackend serves logs, grouped into receipts by transaction, and
+// records the block ranges of FilterLogs() calls.
+type fakeProvenanceBackend struct {
+	head     uint64
+	logs     []*types.Log
+	filtered [][2]uint64
+}
+
+func (b *fakeProvenanceBackend) BlockNumber(context.Context) (uint64, error) {
+	return b.head, nil
+}
+
+func (b *fakeProvenanceBackend) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+	b.filtered = append(b.filtered, [2]uint64{from, to})
+
+	var out []types.Log
+logs:
+	for _, l := range b.logs {
+		if l.BlockNumber < from || l.BlockNumber > to {
+			continue
+		}
+		if len(q.Addresses) > 0 && !containsAddr(q.Addresses, l.Address) {
+			continue
+		}
+		for i, want := range q.Topics {
+			if len(want) == 0 {
+				continue
+			}
+			if i >= len(l.Topics) || !containsHash(want, l.Topics[i]) {
+				continue logs
+			}
+		}
+		out = append(out, *l)
+	}
+	return out, nil
+}
+
+func (b *fakeProvenanceBackend) SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, errors.New("unsupported")
+}
+
+func (b *fakeProvenanceBackend) TransactionReceipt(_ context.Context, h common.Hash) (*types.Receipt, error) {
+	r := &types.Receipt{TxHash: h}
+	for _, l := range b.logs {
+		if l.TxHash == h {
+			r.Logs = append(r.Logs, l)
+		}
+	}
+	if len(r.Logs) == 0 {
+		return nil, ethereum.NotFound
+	}
+	return r, nil
+}
+
+func containsAddr(as []common.Address, a common.Address) bool {
+	for _, b := range as {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHash(hs []common.Hash, h common.Hash) bool {
+	for _, g := range hs {
+		if g == h {
+			return true
+		}
+	}
+	return false
+}
+
+// Mirrors of Seaport's SpentItem and ReceivedItem for packing OrderFulfilled
+// events.
+type (
+	spentItem struct {
+		ItemType   uint8
+		Token      common.Address
+		Identifier *big.Int
+		Amount     *big.Int
+	}
+	receivedItem struct {
+		ItemType   uint8
+		Token      common.Address
+		Identifier *big.Int
+		Amount     *big.Int
+		Recipient  common.Address
+	}
+)
+
+const orderFulfilledABI = `[{
+	"type": "event",
+	"name": "OrderFulfilled",
+	"inputs": [
+		{"name": "orderHash", "type": "bytes32", "indexed": false},
+		{"name": "offerer", "type": "address", "indexed": true},
+		{"name": "zone", "type": "address", "indexed": true},
+		{"name": "recipient", "type": "address", "indexed": false},
+		{"name": "offer", "type": "tuple[]", "indexed": false, "components": [
+			{"name": "itemType", "type": "uint8"},
+			{"name": "token", "type": "address"},
+			{"name": "identifier", "type": "uint256"},
+			{"name": "amount", "type": "uint256"}
+		]},
+		{"name": "consideration", "type": "tuple[]", "indexed": false, "components": [
+			{"name": "itemType", "type": "uint8"},
+			{"name": "token", "type": "address"},
+			{"name": "identifier", "type": "uint256"},
+			{"name": "amount", "type": "uint256"},
+			{"name": "recipient", "type": "address"}
+		]}
+	]
+}]`
+
+func TestTokenProvenance(t *testing.T) {
+	seaport, err := abi.JSON(strings.NewReader(orderFulfilledABI))
+	if err != nil {
+		t.Fatalf("abi.JSON(…) error %v", err)
+	}
+	ev := seaport.Events["OrderFulfilled"]
+	seaportAddr := common.HexToAddress("0x00000000000000ADc04C56Bf30aC9d3c0aAF14dC")
+
+	nft := common.HexToAddress("0x721")
+	otherNFT := common.HexToAddress("0x1155")
+	weth := common.HexToAddress("0xe7")
+	tokenID := big.NewInt(42)
+
+	var (
+		alice   = common.HexToAddress("0xa")
+		bob     = common.HexToAddress("0xb")
+		charlie = common.HexToAddress("0xc")
+		dave    = common.HexToAddress("0xd")
+		zero    common.Address
+	)
+
+	var logs []*types.Log
+	txHash := func(block uint64) common.Hash {
+		return common.BigToHash(new(big.Int).SetUint64(block))
+	}
+	transfer := func(block uint64, from, to common.Address, id *big.Int) {
+		logs = append(logs, &types.Log{
+			Address:     nft,
+			Topics:      []common.Hash{ERC721TransferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(id)},
+			BlockNumber: block,
+			TxHash:      txHash(block),
+			Index:       uint(len(logs)),
+		})
+	}
+	fulfilled := func(block uint64, emitter common.Address, offer []spentItem, consideration []receivedItem) {
+		data, err := ev.Inputs.NonIndexed().Pack(common.Hash{}, zero, offer, consideration)
+		if err != nil {
+			t.Fatalf("%T.Pack(OrderFulfilled) error %v", ev.Inputs, err)
+		}
+		logs = append(logs, &types.Log{
+			Address:     emitter,
+			Topics:      []common.Hash{ev.ID, {}, {}},
+			Data:        data,
+			BlockNumber: block,
+			TxHash:      txHash(block),
+			Index:       uint(len(logs)),
+		})
+	}
+	nftItem := func(contract common.Address, id int64) spentItem {
+		return spentItem{ItemType: 2, Token: contract, Identifier: big.NewInt(id), Amount: big.NewInt(1)}
+	}
+	payment := func(currency common.Address, amount int64) receivedItem {
+		var typ uint8 = 1
+		if currency == zero {
+			typ = 0
+		}
+		return receivedItem{ItemType: typ, Token: currency, Identifier: new(big.Int), Amount: big.NewInt(amount)}
+	}
+	asSpent := func(r receivedItem) spentItem {
+		return spentItem{ItemType: r.ItemType, Token: r.Token, Identifier: r.Identifier, Amount: r.Amount}
+	}
+	asReceived := func(s spentItem) receivedItem {
+		return receivedItem{ItemType: s.ItemType, Token: s.Token, Identifier: s.Identifier, Amount: s.Amount}
+	}
+
+	// Mint
+	transfer(10, zero, alice, tokenID)
+	// Listing filled in ETH, with fees
+	fulfilled(20, seaportAddr, []spentItem{nftItem(nft, 42)}, []receivedItem{payment(zero, 975), payment(zero, 25)})
+	transfer(20, alice, bob, tokenID)
+	// A different token
+	transfer(25, alice, bob, big.NewInt(43))
+	// Bid accepted in WETH
+	transfer(30, bob, charlie, tokenID)
+	fulfilled(30, seaportAddr, []spentItem{asSpent(payment(weth, 500))}, []receivedItem{asReceived(nftItem(nft, 42))})
+	// Bundle, so no price
+	fulfilled(40, seaportAddr, []spentItem{nftItem(nft, 42), nftItem(otherNFT, 1)}, []receivedItem{payment(zero, 2000)})
+	transfer(40, charlie, dave, tokenID)
+	// Spoofed event from a non-Seaport contract
+	fulfilled(50, common.HexToAddress("0xbad"), []spentItem{nftItem(nft, 42)}, []receivedItem{payment(zero, 1)})
+	transfer(50, dave, alice, tokenID)
+	// Via an aggregator
+	fulfilled(60, seaportAddr, []spentItem{nftItem(nft, 42)}, []receivedItem{payment(zero, 3000)})
+	transfer(60, alice, charlie, tokenID)
+	transfer(60, charlie, bob, tokenID)
+	// Burn
+	transfer(70, bob, zero, tokenID)
+
+	change := func(block uint64, from, to common.Address, sale *Sale) OwnershipChange {
+		c := OwnershipChange{
+			Block:  block,
+			TxHash: txHash(block),
+			From:   from,
+			To:     to,
+			Sale:   sale,
+		}
+		for _, l := range logs {
+			if l.BlockNumber == block && len(l.Topics) == 4 && l.Topics[1] == common.BytesToHash(from.Bytes()) {
+				c.LogIndex = l.Index
+			}
+		}
+		return c
+	}
+	sale := func(currency common.Address, price int64) *Sale {
+		return &Sale{Marketplace: "Seaport", Currency: currency, Price: big.NewInt(price)}
+	}
+
+	ctx := context.Background()
+	bigCmp := cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })
+
+	tests := []struct {
+		name         string
+		opts         []ProvenanceOption
+		want         []OwnershipChange
+		wantFiltered [][2]uint64
+	}{
+		{
+			name: "defaults",
+			want: []OwnershipChange{
+				change(10, zero, alice, nil),
+				change(20, alice, bob, sale(zero, 1000)),
+				change(30, bob, charlie, sale(weth, 500)),
+				change(40, charlie, dave, nil),
+				change(50, dave, alice, nil),
+				change(60, alice, charlie, sale(zero, 3000)),
+				change(60, charlie, bob, nil),
+				change(70, bob, zero, nil),
+			},
+			wantFiltered: [][2]uint64{{0, 100}},
+		},
+		{
+			name: "from block with max range and no sale decoders",
+			opts: []ProvenanceOption{
+				WithProvenanceFromBlock(30),
+				WithProvenanceMaxBlockRange(50),
+				WithSaleDecoders(),
+			},
+			want: []OwnershipChange{
+				change(30, bob, charlie, nil),
+				change(40, charlie, dave, nil),
+				change(50, dave, alice, nil),
+				change(60, alice, charlie, nil),
+				change(60, charlie, bob, nil),
+				change(70, bob, zero, nil),
+			},
+			wantFiltered: [][2]uint64{{30, 79}, {80, 100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &fakeProvenanceBackend{
+				head: 100,
+				logs: logs,
+			}
+			got, err := TokenProvenance(ctx, backend, nft, tokenID, tt.opts...)
+			if err != nil {
+				t.Fatalf("TokenProvenance(…) error %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got, bigCmp); diff != "" {
+				t.Errorf("TokenProvenance(…) diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantFiltered, backend.filtered); diff != "" {
+				t.Errorf("TokenProvenance(…) FilterLogs() block ranges diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
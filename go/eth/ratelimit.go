@@ -0,0 +1,449 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/time/rate"
+
+	"github.com/cxkoda/solgo/go/otelonce"
+)
+
+// A MethodGroup is a set of node methods that share a rate limit. Providers
+// typically price (and therefore throttle) methods differently, so e.g. log
+// filters can be limited more aggressively than contract calls.
+type MethodGroup string
+
+// MethodGroups used by a RateLimiter.
+const (
+	// CallGroup includes CallContract(), CodeAt(), PendingCodeAt(), and
+	// EstimateGas().
+	CallGroup MethodGroup = "call"
+	// BlockGroup includes BlockNumber(), BlockByNumber(), and
+	// HeaderByNumber().
+	BlockGroup MethodGroup = "block"
+	// LogGroup includes FilterLogs() and SubscribeFilterLogs().
+	LogGroup MethodGroup = "logs"
+	// TxGroup includes PendingNonceAt(), SuggestGasPrice(),
+	// SuggestGasTipCap(), and SendTransaction().
+	TxGroup MethodGroup = "tx"
+	// DefaultGroup is used as the RateLimit of any group without its own.
+	DefaultGroup MethodGroup = "*"
+)
+
+// A RateLimit is a token bucket that refills at Rate tokens per second up to a
+// maximum of Burst tokens, with every call consuming one. A non-positive Rate
+// is unlimited and a non-positive Burst is treated as 1.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) String() string {
+	return fmt.Sprintf("%s:%d", strconv.FormatFloat(l.Rate, 'f', -1, 64), l.Burst)
+}
+
+// RateLimits map MethodGroups to their respective RateLimit. It implements
+// flag.Value, parsing comma-separated group=rate[:burst] pairs; e.g.
+// "*=10,logs=1:2" limits log filters to 1 per second (with a burst of 2) and
+// all other methods to 10 per second.
+type RateLimits map[MethodGroup]RateLimit
+
+var _ flag.Value = (*RateLimits)(nil)
+
+// String returns the limits in the form accepted by Set(), sorted by group.
+func (ls RateLimits) String() string {
+	var parts []string
+	for g, l := range ls {
+		parts = append(parts, fmt.Sprintf("%s=%s", g, l))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// Set parses s, as described in the RateLimits documentation, replacing all
+// existing limits.
+func (ls *RateLimits) Set(s string) error {
+	parsed := make(RateLimits)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		group, limit, ok := strings.Cut(part, "=")
+		if !ok || group == "" {
+			return fmt.Errorf("rate limit %q not of form group=rate[:burst]", part)
+		}
+		rate, burst, hasBurst := strings.Cut(limit, ":")
+
+		var (
+			l   RateLimit
+			err error
+		)
+		l.Rate, err = strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return fmt.Errorf("rate limit %q: strconv.ParseFloat(%q): %v", part, rate, err)
+		}
+		if hasBurst {
+			if l.Burst, err = strconv.Atoi(burst); err != nil {
+				return fmt.Errorf("rate limit %q: strconv.Atoi(%q): %v", part, burst, err)
+			}
+		}
+
+		g := MethodGroup(group)
+		if _, ok := parsed[g]; ok {
+			return fmt.Errorf("duplicate rate limit for group %q", g)
+		}
+		parsed[g] = l
+	}
+	*ls = parsed
+	return nil
+}
+
+// Flags configured by NewRateLimiterFromFlags.
+const (
+	RateLimitsFlag     = "eth_rate_limits"
+	MaxConcurrencyFlag = "eth_max_concurrent_calls"
+)
+
+// A RateLimiter throttles calls to an Ethereum node, allowing tools to remain
+// within the quotas of (particularly free-tier) providers. Each MethodGroup has
+// its own token bucket, and the total number of in-flight calls can be capped.
+// Use the ContractBackend(), BlockFetcher(), or Backend() methods to wrap
+// existing clients, all of which may share the same RateLimiter.
+//
+// Metrics are recorded with OpenTelemetry; see RateLimitInstrumentationName.
+type RateLimiter struct {
+	limits         *RateLimits
+	maxConcurrency *int
+
+	initOnce sync.Once
+	limiters map[MethodGroup]*rate.Limiter
+	sem      chan struct{}
+	inst     *rateLimitInstruments
+}
+
+// NewRateLimiter returns a RateLimiter that applies the limits, falling back
+// to limits[DefaultGroup] for groups without their own; groups without any
+// RateLimit are unlimited. If maxConcurrency is positive, at most this number
+// of calls are in flight at any time.
+func NewRateLimiter(limits RateLimits, maxConcurrency int) *RateLimiter {
+	return &RateLimiter{
+		limits:         &limits,
+		maxConcurrency: &maxConcurrency,
+	}
+}
+
+// MustNewRateLimiterFromFlags calls NewRateLimiterFromFlags() and panics on
+// error.
+func MustNewRateLimiterFromFlags(fs *flag.FlagSet, defaults RateLimits, defaultMaxConcurrency int) *RateLimiter {
+	l, err := NewRateLimiterFromFlags(fs, defaults, defaultMaxConcurrency)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// NewRateLimiterFromFlags returns a RateLimiter that is configurable via
+// command-line flags; see RateLimitsFlag and MaxConcurrencyFlag. The flags
+// MUST be parsed before the RateLimiter, or any client that it wraps, is used.
+func NewRateLimiterFromFlags(fs *flag.FlagSet, defaults RateLimits, defaultMaxConcurrency int) (*RateLimiter, error) {
+	if fs.Parsed() {
+		return nil, fmt.Errorf("%T already parsed", fs)
+	}
+
+	limits := make(RateLimits)
+	for g, l := range defaults {
+		limits[g] = l
+	}
+	fs.Var(&limits, RateLimitsFlag, `Comma-separated group=rate[:burst] limits on calls per second to the Ethereum node, by method group (call, block, logs, tx, or * for all others); e.g. "*=10,logs=1:2"`)
+	maxConcurrency := fs.Int(MaxConcurrencyFlag, defaultMaxConcurrency, "Maximum number of concurrent calls to the Ethereum node; non-positive for no limit")
+
+	return &RateLimiter{
+		limits:         &limits,
+		maxConcurrency: maxConcurrency,
+	}, nil
+}
+
+// init lazily builds the RateLimiter's limiters so that flags can be parsed
+// after construction.
+func (rl *RateLimiter) init() {
+	rl.initOnce.Do(func() {
+		rl.limiters = make(map[MethodGroup]*rate.Limiter)
+		for g, l := range *rl.limits {
+			// An explicitly unlimited group MUST NOT fall back to the default,
+			// so it's recorded with a nil limiter.
+			var lim *rate.Limiter
+			if l.Rate > 0 {
+				lim = newLimiter(l)
+			}
+			rl.limiters[g] = lim
+		}
+		if n := *rl.maxConcurrency; n > 0 {
+			rl.sem = make(chan struct{}, n)
+		}
+		rl.inst = rateLimitMetrics()
+	})
+}
+
+// wait blocks until a call in the MethodGroup is permitted, returning a
+// function that MUST be called when the call completes. An error is only
+// returned if ctx is Done before the call is permitted.
+func (rl *RateLimiter) wait(ctx context.Context, g MethodGroup) (func(), error) {
+	rl.init()
+	attrs := metric.WithAttributes(attribute.String("group", string(g)))
+	start := time.Now()
+
+	lim, ok := rl.limiters[g]
+	if !ok {
+		lim = rl.limiters[DefaultGroup]
+	}
+	if lim != nil {
+		if err := waitLimiter(ctx, lim); err != nil {
+			rl.inst.rejected.Add(ctx, 1, attrs)
+			return nil, err
+		}
+	}
+
+	if rl.sem != nil {
+		select {
+		case rl.sem <- struct{}{}:
+		case <-ctx.Done():
+			rl.inst.rejected.Add(ctx, 1, attrs)
+			return nil, ctx.Err()
+		}
+	}
+
+	rl.inst.calls.Add(ctx, 1, attrs)
+	rl.inst.wait.Record(ctx, time.Since(start).Seconds(), attrs)
+	rl.inst.inFlight.Add(ctx, 1, attrs)
+
+	return func() {
+		rl.inst.inFlight.Add(context.Background(), -1, attrs)
+		if rl.sem != nil {
+			<-rl.sem
+		}
+	}, nil
+}
+
+// newLimiter returns a rate.Limiter implementing the RateLimit, which MUST
+// have a positive Rate.
+func newLimiter(l RateLimit) *rate.Limiter {
+	burst := l.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), burst)
+}
+
+// waitLimiter blocks until the Limiter permits an event or ctx is Done, in
+// which case ctx.Err() is returned. Unlike rate.Limiter.Wait(), it waits for
+// ctx to be Done instead of failing early if ctx has a deadline that the
+// Limiter wouldn't meet.
+func waitLimiter(ctx context.Context, l *rate.Limiter) error {
+	r := l.Reserve()
+	d := r.Delay()
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// RateLimitInstrumentationName is the name of the OpenTelemetry Meter used by
+// RateLimiters. As with all OpenTelemetry metrics, binaries that wish to
+// export them MUST install a provider with otel.SetMeterProvider().
+//
+// Metrics, all of which have a "group" attribute, are:
+//   - eth.ratelimit.calls
+//   - eth.ratelimit.rejected (context Done while waiting)
+//   - eth.ratelimit.in_flight
+//   - eth.ratelimit.wait.duration (seconds)
+const RateLimitInstrumentationName = "github.com/cxkoda/solgo/go/eth/ratelimit"
+
+// rateLimitInstruments are the OpenTelemetry metric instruments used by a
+// RateLimiter.
+type rateLimitInstruments struct {
+	calls, rejected metric.Int64Counter
+	inFlight        metric.Int64UpDownCounter
+	wait            metric.Float64Histogram
+}
+
+// rateLimitMetrics returns the RateLimiter metric instruments.
+var rateLimitMetrics = otelonce.Instruments(RateLimitInstrumentationName, func(m metric.Meter) (*rateLimitInstruments, error) {
+	inst := new(rateLimitInstruments)
+	var errs [4]error
+	inst.calls, errs[0] = m.Int64Counter(
+		"eth.ratelimit.calls",
+		metric.WithDescription("Number of calls permitted by the rate limiter."),
+	)
+	inst.rejected, errs[1] = m.Int64Counter(
+		"eth.ratelimit.rejected",
+		metric.WithDescription("Number of calls abandoned while waiting for the rate limiter."),
+	)
+	inst.inFlight, errs[2] = m.Int64UpDownCounter(
+		"eth.ratelimit.in_flight",
+		metric.WithDescription("Number of calls currently in flight."),
+	)
+	inst.wait, errs[3] = m.Float64Histogram(
+		"eth.ratelimit.wait.duration",
+		metric.WithDescription("Time that calls were delayed by the rate limiter."),
+		metric.WithUnit("s"),
+	)
+	return inst, errors.Join(errs[:]...)
+})
+
+// limit is a convenience wrapper for calling a method of the MethodGroup once
+// the RateLimiter permits it.
+func limit[T any](ctx context.Context, rl *RateLimiter, g MethodGroup, fn func() (T, error)) (T, error) {
+	done, err := rl.wait(ctx, g)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer done()
+	return fn()
+}
+
+// ContractBackend returns a bind.ContractBackend that propagates all calls to
+// b once permitted by the RateLimiter.
+func (rl *RateLimiter) ContractBackend(b bind.ContractBackend) bind.ContractBackend {
+	return &rateLimitedContractBackend{b: b, rl: rl}
+}
+
+// BlockFetcher returns a BlockFetcher that propagates all calls to b once
+// permitted by the RateLimiter.
+func (rl *RateLimiter) BlockFetcher(b BlockFetcher) BlockFetcher {
+	return &rateLimitedBlockFetcher{b: b, rl: rl}
+}
+
+// A Backend is both a bind.ContractBackend and a BlockFetcher, typically an
+// *ethclient.Client.
+type Backend interface {
+	bind.ContractBackend
+	BlockFetcher
+}
+
+// Backend returns a Backend that propagates all calls to b once permitted by
+// the RateLimiter.
+func (rl *RateLimiter) Backend(b Backend) Backend {
+	return struct {
+		*rateLimitedContractBackend
+		*rateLimitedBlockFetcher
+	}{
+		&rateLimitedContractBackend{b: b, rl: rl},
+		&rateLimitedBlockFetcher{b: b, rl: rl},
+	}
+}
+
+type rateLimitedBlockFetcher struct {
+	b  BlockFetcher
+	rl *RateLimiter
+}
+
+func (f *rateLimitedBlockFetcher) BlockNumber(ctx context.Context) (uint64, error) {
+	return limit(ctx, f.rl, BlockGroup, func() (uint64, error) {
+		return f.b.BlockNumber(ctx)
+	})
+}
+
+func (f *rateLimitedBlockFetcher) BlockByNumber(ctx context.Context, num *big.Int) (*types.Block, error) {
+	return limit(ctx, f.rl, BlockGroup, func() (*types.Block, error) {
+		return f.b.BlockByNumber(ctx, num)
+	})
+}
+
+type rateLimitedContractBackend struct {
+	b  bind.ContractBackend
+	rl *RateLimiter
+}
+
+func (c *rateLimitedContractBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return limit(ctx, c.rl, CallGroup, func() ([]byte, error) {
+		return c.b.CodeAt(ctx, contract, blockNumber)
+	})
+}
+
+func (c *rateLimitedContractBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return limit(ctx, c.rl, CallGroup, func() ([]byte, error) {
+		return c.b.CallContract(ctx, call, blockNumber)
+	})
+}
+
+func (c *rateLimitedContractBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return limit(ctx, c.rl, BlockGroup, func() (*types.Header, error) {
+		return c.b.HeaderByNumber(ctx, number)
+	})
+}
+
+func (c *rateLimitedContractBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return limit(ctx, c.rl, CallGroup, func() ([]byte, error) {
+		return c.b.PendingCodeAt(ctx, account)
+	})
+}
+
+func (c *rateLimitedContractBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return limit(ctx, c.rl, TxGroup, func() (uint64, error) {
+		return c.b.PendingNonceAt(ctx, account)
+	})
+}
+
+func (c *rateLimitedContractBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return limit(ctx, c.rl, TxGroup, func() (*big.Int, error) {
+		return c.b.SuggestGasPrice(ctx)
+	})
+}
+
+func (c *rateLimitedContractBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return limit(ctx, c.rl, TxGroup, func() (*big.Int, error) {
+		return c.b.SuggestGasTipCap(ctx)
+	})
+}
+
+func (c *rateLimitedContractBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return limit(ctx, c.rl, CallGroup, func() (uint64, error) {
+		return c.b.EstimateGas(ctx, call)
+	})
+}
+
+func (c *rateLimitedContractBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := limit(ctx, c.rl, TxGroup, func() (struct{}, error) {
+		return struct{}{}, c.b.SendTransaction(ctx, tx)
+	})
+	return err
+}
+
+func (c *rateLimitedContractBackend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return limit(ctx, c.rl, LogGroup, func() ([]types.Log, error) {
+		return c.b.FilterLogs(ctx, query)
+	})
+}
+
+// SubscribeFilterLogs is limited only while the subscription is being
+// established, not for its lifetime.
+func (c *rateLimitedContractBackend) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return limit(ctx, c.rl, LogGroup, func() (ethereum.Subscription, error) {
+		return c.b.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
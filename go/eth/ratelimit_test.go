@@ -0,0 +1,256 @@
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestRateLimitsFlag(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    RateLimits
+		wantErr bool
+	}{
+		{
+			in:   "",
+			want: RateLimits{},
+		},
+		{
+			in: "*=10,logs=1.5:3",
+			want: RateLimits{
+				DefaultGroup: {Rate: 10},
+				LogGroup:     {Rate: 1.5, Burst: 3},
+			},
+		},
+		{
+			in: " call=0 , block=2:1 ",
+			want: RateLimits{
+				CallGroup:  {Rate: 0},
+				BlockGroup: {Rate: 2, Burst: 1},
+			},
+		},
+		{
+			in:      "10",
+			wantErr: true,
+		},
+		{
+			in:      "=10",
+			wantErr: true,
+		},
+		{
+			in:      "call=fast",
+			wantErr: true,
+		},
+		{
+			in:      "call=1:lots",
+			wantErr: true,
+		},
+		{
+			in:      "call=1,call=2",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		var got RateLimits
+		if err := got.Set(tt.in); (err != nil) != tt.wantErr {
+			t.Errorf("%T.Set(%q) got err %v; want err %t", got, tt.in, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%T.Set(%q) diff (-want +got):\n%s", got, tt.in, diff)
+		}
+
+		var roundTrip RateLimits
+		if err := roundTrip.Set(got.String()); err != nil {
+			t.Errorf("%T.Set(%T.String() = %q) error %v", roundTrip, got, got.String(), err)
+			continue
+		}
+		if diff := cmp.Diff(got, roundTrip); diff != "" {
+			t.Errorf("%T.Set(%T.String()) round trip diff (-want +got):\n%s", roundTrip, got, diff)
+		}
+	}
+}
+
+// RateLimiter.Backend() is intended to wrap clients.
+var _ Backend = &ethclient.Client{}
+
+// fakeBlocks is a BlockFetcher that records concurrency and, if non-nil,
+// blocks on its release channel before returning.
+type fakeBlocks struct {
+	release chan struct{}
+
+	mu                       sync.Mutex
+	calls, active, maxActive int
+}
+
+func (f *fakeBlocks) BlockNumber(ctx context.Context) (uint64, error) {
+	f.mu.Lock()
+	f.calls++
+	f.active++
+	if f.active > f.maxActive {
+		f.maxActive = f.active
+	}
+	f.mu.Unlock()
+
+	if f.release != nil {
+		<-f.release
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.active--
+	return 42, nil
+}
+
+func (f *fakeBlocks) BlockByNumber(ctx context.Context, num *big.Int) (*types.Block, error) {
+	if _, err := f.BlockNumber(ctx); err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(&types.Header{Number: num}), nil
+}
+
+func TestRateLimiterRate(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		rate  = 50
+		calls = 6
+	)
+	// The first call uses the initial token and the rest wait for a refill.
+	minDuration := time.Duration(calls-1) * time.Second / rate
+
+	tests := []struct {
+		name      string
+		limits    RateLimits
+		wantLimit bool
+	}{
+		{
+			name:      "group limit",
+			limits:    RateLimits{BlockGroup: {Rate: rate, Burst: 1}},
+			wantLimit: true,
+		},
+		{
+			name:      "default limit",
+			limits:    RateLimits{DefaultGroup: {Rate: rate}},
+			wantLimit: true,
+		},
+		{
+			name:   "other group limited",
+			limits: RateLimits{LogGroup: {Rate: 1}},
+		},
+		{
+			name: "explicitly unlimited group",
+			limits: RateLimits{
+				DefaultGroup: {Rate: 1},
+				BlockGroup:   {Rate: 0},
+			},
+		},
+		{
+			name:   "burst",
+			limits: RateLimits{BlockGroup: {Rate: 1, Burst: calls}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := new(fakeBlocks)
+			blocks := NewRateLimiter(tt.limits, 0).BlockFetcher(fake)
+
+			start := time.Now()
+			for i := 0; i < calls; i++ {
+				if _, err := blocks.BlockNumber(ctx); err != nil {
+					t.Fatalf("BlockNumber() error %v", err)
+				}
+			}
+			got := time.Since(start)
+
+			if tt.wantLimit && got < minDuration {
+				t.Errorf("%d calls to BlockNumber() took %v; want >= %v", calls, got, minDuration)
+			}
+			if !tt.wantLimit && got >= minDuration {
+				t.Errorf("%d calls to BlockNumber() took %v; want < %v as not limited", calls, got, minDuration)
+			}
+			if fake.calls != calls {
+				t.Errorf("%d calls to BlockNumber() propagated %d", calls, fake.calls)
+			}
+		})
+	}
+}
+
+func TestRateLimiterContextDone(t *testing.T) {
+	fake := new(fakeBlocks)
+	blocks := NewRateLimiter(RateLimits{BlockGroup: {Rate: 0.001}}, 0).BlockFetcher(fake)
+
+	ctx := context.Background()
+	if _, err := blocks.BlockNumber(ctx); err != nil {
+		t.Fatalf("BlockNumber() first call error %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := blocks.BlockNumber(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("BlockNumber() with exhausted tokens got err %v; want %v", err, context.DeadlineExceeded)
+	}
+	if fake.calls != 1 {
+		t.Errorf("BlockNumber() propagated %d calls; want 1", fake.calls)
+	}
+}
+
+func TestRateLimiterConcurrency(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeBlocks{release: make(chan struct{})}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	rl, err := NewRateLimiterFromFlags(fs, nil, 0)
+	if err != nil {
+		t.Fatalf("NewRateLimiterFromFlags() error %v", err)
+	}
+	const max = 3
+	if err := fs.Parse([]string{"--" + MaxConcurrencyFlag, "3"}); err != nil {
+		t.Fatalf("%T.Parse() error %v", fs, err)
+	}
+	blocks := rl.BlockFetcher(fake)
+
+	const calls = 10
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			blocks.BlockNumber(ctx)
+		}()
+	}
+	for i := 0; i < calls; i++ {
+		fake.release <- struct{}{}
+	}
+	wg.Wait()
+
+	if fake.maxActive > max {
+		t.Errorf("%d concurrent calls to BlockNumber() with --%s=%d; got %d in flight", calls, MaxConcurrencyFlag, max, fake.maxActive)
+	}
+	if fake.calls != calls {
+		t.Errorf("%d calls to BlockNumber() propagated %d", calls, fake.calls)
+	}
+
+	if _, err := NewRateLimiterFromFlags(fs, nil, 0); err == nil {
+		t.Errorf("NewRateLimiterFromFlags(<parsed %T>) got nil error; want non-nil", fs)
+	}
+}
@@ -0,0 +1,231 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/glog"
+)
+
+// A Reorg describes a chain reorganisation detected by a ReorgDetector.
+type Reorg struct {
+	// CommonAncestor is the highest block that is in both the old and new
+	// canonical chains.
+	CommonAncestor *types.Header
+	// Dropped are the blocks that are no longer canonical, and Added are the
+	// blocks that replaced them, both in ascending order of number. There is
+	// always at least one Dropped block, but the chains need not be of equal
+	// length.
+	Dropped, Added []*types.Header
+}
+
+// ErrReorgTooDeep is returned by a ReorgDetector if it observes a chain that
+// doesn't share any of the blocks that it tracks. Consumers SHOULD treat all
+// data derived from the tracked blocks as invalid.
+var ErrReorgTooDeep = errors.New("reorg deeper than tracked blocks")
+
+// A ReorgDetector tracks the hashes of recent canonical blocks, by number, and
+// detects when the canonical block at any height changes. It is intended for
+// polling-based consumers, e.g. indexers using FilterLogs(), that need to roll
+// back state derived from dropped blocks.
+//
+// Heads are reported to the ReorgDetector with Observe(), or fetched with
+// Poll() or Run(). Missing blocks, either between the last-observed head and a
+// new one, or on a new fork, are fetched from the HeaderFetcher. A
+// ReorgDetector is safe for concurrent use.
+type ReorgDetector struct {
+	src   HeaderFetcher
+	depth uint64
+
+	mu sync.Mutex
+	// chain is a contiguous sequence of canonical headers, in ascending order
+	// of number, with at most depth entries.
+	chain []*types.Header
+
+	subMu   sync.Mutex
+	nextSub uint64
+	subs    map[uint64]func(Reorg)
+}
+
+// NewReorgDetector returns a ReorgDetector that tracks up to depth recent
+// blocks, fetching headers from src as required. A reorg that replaces the
+// oldest tracked block results in ErrReorgTooDeep, so depth SHOULD comfortably
+// exceed the deepest expected reorg; note that fewer blocks are tracked after a
+// reorg to a shorter chain, until the chain grows again.
+func NewReorgDetector(src HeaderFetcher, depth uint64) *ReorgDetector {
+	if depth < 2 {
+		depth = 2
+	}
+	return &ReorgDetector{
+		src:   src,
+		depth: depth,
+		subs:  make(map[uint64]func(Reorg)),
+	}
+}
+
+// Subscribe registers fn to be called with every Reorg that is detected,
+// returning a function to unsubscribe. Calls are made synchronously, before
+// Observe() returns, so fn MUST NOT call any of the ReorgDetector's methods
+// other than Subscribe() and the returned function.
+func (d *ReorgDetector) Subscribe(fn func(Reorg)) (unsubscribe func()) {
+	d.subMu.Lock()
+	defer d.subMu.Unlock()
+	id := d.nextSub
+	d.nextSub++
+	d.subs[id] = fn
+
+	return func() {
+		d.subMu.Lock()
+		defer d.subMu.Unlock()
+		delete(d.subs, id)
+	}
+}
+
+// Head returns the most recently observed canonical head, or nil if none has
+// been observed.
+func (d *ReorgDetector) Head() *types.Header {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.chain) == 0 {
+		return nil
+	}
+	return d.chain[len(d.chain)-1]
+}
+
+// Poll is equivalent to Observe() with the latest header from the
+// HeaderFetcher.
+func (d *ReorgDetector) Poll(ctx context.Context) (*Reorg, error) {
+	h, err := d.src.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%T.HeaderByNumber(latest): %v", d.src, err)
+	}
+	return d.Observe(ctx, h)
+}
+
+// Run calls Poll() every interval until ctx is cancelled, returning ctx.Err().
+// Errors other than ErrReorgTooDeep are logged and retried at the next
+// interval; ErrReorgTooDeep is returned as it requires the consumer to resync.
+func (d *ReorgDetector) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := d.Poll(ctx); errors.Is(err, ErrReorgTooDeep) {
+			return err
+		} else if err != nil && ctx.Err() == nil {
+			glog.Warningf("%T.Poll(): %v", d, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Observe informs the ReorgDetector of a canonical head, which need not be
+// higher than those previously observed. If the head implies a reorg, any
+// missing blocks of the new fork are fetched and the returned Reorg, which is
+// also sent to all subscribers, is non-nil. A head that is already tracked, or
+// that extends the tracked chain, returns a nil Reorg.
+//
+// If the new chain doesn't share any tracked blocks, the returned error wraps
+// ErrReorgTooDeep and tracking restarts from the new chain.
+func (d *ReorgDetector) Observe(ctx context.Context, head *types.Header) (*Reorg, error) {
+	if head.Number == nil || !head.Number.IsUint64() {
+		return nil, fmt.Errorf("invalid header number %v", head.Number)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.chain) == 0 {
+		d.chain = []*types.Header{head}
+		return nil, nil
+	}
+	if t := d.tracked(head.Number.Uint64()); t != nil && t.Hash() == head.Hash() {
+		return nil, nil
+	}
+
+	// Walk back from the new head, fetching parents, until one is tracked.
+	added := []*types.Header{head}
+	var ancestor *types.Header
+	for cur := head; ; {
+		num := cur.Number.Uint64()
+		if num == 0 || num-1 < d.chain[0].Number.Uint64() {
+			d.chain = added
+			d.trim()
+			return nil, fmt.Errorf("%w: no common ancestor of block %d (%v) within %d tracked blocks", ErrReorgTooDeep, head.Number, head.Hash(), d.depth)
+		}
+
+		if t := d.tracked(num - 1); t != nil && t.Hash() == cur.ParentHash {
+			ancestor = t
+			break
+		}
+
+		parent, err := d.src.HeaderByNumber(ctx, new(big.Int).SetUint64(num-1))
+		if err != nil {
+			return nil, fmt.Errorf("%T.HeaderByNumber(%d): %v", d.src, num-1, err)
+		}
+		if got, want := parent.Hash(), cur.ParentHash; got != want {
+			return nil, fmt.Errorf("%T.HeaderByNumber(%d) returned block %v; want parent %v of block %d; chain changed while fetching", d.src, num-1, got, want, num)
+		}
+		added = append([]*types.Header{parent}, added...)
+		cur = parent
+	}
+
+	n := ancestor.Number.Uint64() - d.chain[0].Number.Uint64() + 1
+	dropped := append([]*types.Header(nil), d.chain[n:]...)
+	d.chain = append(d.chain[:n:n], added...)
+	d.trim()
+
+	if len(dropped) == 0 {
+		return nil, nil
+	}
+	r := &Reorg{
+		CommonAncestor: ancestor,
+		Dropped:        dropped,
+		Added:          added,
+	}
+	d.notify(*r)
+	return r, nil
+}
+
+// tracked returns the tracked header with the number, or nil if there is none.
+// d.mu MUST be held.
+func (d *ReorgDetector) tracked(num uint64) *types.Header {
+	if len(d.chain) == 0 {
+		return nil
+	}
+	first := d.chain[0].Number.Uint64()
+	if num < first || num-first >= uint64(len(d.chain)) {
+		return nil
+	}
+	return d.chain[num-first]
+}
+
+// trim removes the oldest headers in excess of d.depth. d.mu MUST be held.
+func (d *ReorgDetector) trim() {
+	if n := uint64(len(d.chain)); n > d.depth {
+		d.chain = d.chain[n-d.depth:]
+	}
+}
+
+// notify calls all subscribers with the Reorg.
+func (d *ReorgDetector) notify(r Reorg) {
+	d.subMu.Lock()
+	fns := make([]func(Reorg), 0, len(d.subs))
+	for _, fn := range d.subs {
+		fns = append(fns, fn)
+	}
+	d.subMu.Unlock()
+
+	for _, fn := range fns {
+		fn(r)
+	}
+}
@@ -0,0 +1,208 @@
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeHeaderChain is a HeaderFetcher of a canonical chain that can be forked.
+type fakeHeaderChain struct {
+	mu      sync.Mutex
+	headers []*types.Header // index is block number
+}
+
+// newFakeHeaderChain returns a fakeHeaderChain with blocks [0,head].
+func newFakeHeaderChain(head uint64) *fakeHeaderChain {
+	c := new(fakeHeaderChain)
+	c.extend("", head+1)
+	return c
+}
+
+// extend appends n blocks to the canonical chain, with the fork label in their
+// extra data so that forks have different hashes.
+func (c *fakeHeaderChain) extend(fork string, n uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := uint64(0); i < n; i++ {
+		h := &types.Header{
+			Number: big.NewInt(int64(len(c.headers))),
+			Extra:  []byte(fork),
+		}
+		if len(c.headers) > 0 {
+			h.ParentHash = c.headers[len(c.headers)-1].Hash()
+		}
+		c.headers = append(c.headers, h)
+	}
+}
+
+// fork replaces all blocks after ancestor with n new ones, returning those
+// that were dropped.
+func (c *fakeHeaderChain) fork(fork string, ancestor, n uint64) []*types.Header {
+	c.mu.Lock()
+	dropped := c.headers[ancestor+1:]
+	c.headers = c.headers[: ancestor+1 : ancestor+1]
+	c.mu.Unlock()
+	c.extend(fork, n)
+	return dropped
+}
+
+// segment returns canonical blocks [from,to].
+func (c *fakeHeaderChain) segment(from, to uint64) []*types.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*types.Header(nil), c.headers[from:to+1]...)
+}
+
+func (c *fakeHeaderChain) HeaderByNumber(_ context.Context, num *big.Int) (*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if num == nil {
+		return c.headers[len(c.headers)-1], nil
+	}
+	if n := num.Uint64(); n < uint64(len(c.headers)) {
+		return c.headers[n], nil
+	}
+	return nil, fmt.Errorf("block %d not found", num)
+}
+
+func TestReorgDetector(t *testing.T) {
+	ctx := context.Background()
+
+	hashes := func(hs []*types.Header) []common.Hash {
+		var out []common.Hash
+		for _, h := range hs {
+			out = append(out, h.Hash())
+		}
+		return out
+	}
+
+	chain := newFakeHeaderChain(100)
+	d := NewReorgDetector(chain, 20)
+
+	var notified []Reorg
+	unsubscribe := d.Subscribe(func(r Reorg) {
+		notified = append(notified, r)
+	})
+
+	poll := func(t *testing.T) *Reorg {
+		t.Helper()
+		r, err := d.Poll(ctx)
+		if err != nil {
+			t.Fatalf("%T.Poll() error %v", d, err)
+		}
+		return r
+	}
+
+	t.Run("initial and extensions", func(t *testing.T) {
+		for _, n := range []uint64{0, 1, 5, 0, 30} {
+			chain.extend("", n)
+			if r := poll(t); r != nil {
+				t.Errorf("%T.Poll() after extending chain by %d got reorg %+v; want nil", d, n, r)
+			}
+			want, _ := chain.HeaderByNumber(ctx, nil)
+			if got := d.Head(); got == nil || got.Hash() != want.Hash() {
+				t.Errorf("%T.Head() got %v; want %v", d, got, want.Hash())
+			}
+		}
+	})
+
+	tests := []struct {
+		name             string
+		ancestorFromHead uint64
+		newBlocks        uint64
+	}{
+		// Shorter chains reduce the number of tracked blocks so this MUST be
+		// first.
+		{name: "maximum depth", ancestorFromHead: 19, newBlocks: 19},
+		{name: "single-block reorg", ancestorFromHead: 1, newBlocks: 1},
+		{name: "longer new chain", ancestorFromHead: 3, newBlocks: 7},
+		{name: "shorter new chain", ancestorFromHead: 5, newBlocks: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notified = nil
+
+			head := d.Head().Number.Uint64()
+			ancestor := head - tt.ancestorFromHead
+			dropped := chain.fork(tt.name, ancestor, tt.newBlocks)
+			added := chain.segment(ancestor+1, ancestor+tt.newBlocks)
+
+			got := poll(t)
+			if got == nil {
+				t.Fatalf("%T.Poll() after fork got nil Reorg", d)
+			}
+			if diff := cmp.Diff(chain.segment(ancestor, ancestor)[0].Hash(), got.CommonAncestor.Hash()); diff != "" {
+				t.Errorf("Reorg.CommonAncestor diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(hashes(dropped), hashes(got.Dropped)); diff != "" {
+				t.Errorf("Reorg.Dropped diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(hashes(added), hashes(got.Added)); diff != "" {
+				t.Errorf("Reorg.Added diff (-want +got):\n%s", diff)
+			}
+
+			if len(notified) != 1 || notified[0].CommonAncestor != got.CommonAncestor {
+				t.Errorf("subscriber notified of %d reorgs; want 1, equal to that returned by Poll()", len(notified))
+			}
+			if r := poll(t); r != nil {
+				t.Errorf("second %T.Poll() after fork got reorg %+v; want nil", d, r)
+			}
+		})
+	}
+
+	t.Run("stale head on same chain", func(t *testing.T) {
+		head := d.Head()
+		older := chain.segment(head.Number.Uint64()-3, head.Number.Uint64()-3)[0]
+		if r, err := d.Observe(ctx, older); r != nil || err != nil {
+			t.Errorf("%T.Observe([tracked ancestor of head]) got (%+v, %v); want (nil, nil)", d, r, err)
+		}
+		if got, want := d.Head().Hash(), head.Hash(); got != want {
+			t.Errorf("%T.Head() after observing stale head got %v; want unchanged %v", d, got, want)
+		}
+	})
+
+	t.Run("too deep", func(t *testing.T) {
+		notified = nil
+		head := d.Head().Number.Uint64()
+		chain.fork("too deep", head-25, 30)
+
+		if _, err := d.Poll(ctx); !errors.Is(err, ErrReorgTooDeep) {
+			t.Fatalf("%T.Poll() after fork beyond depth got err %v; want %v", d, err, ErrReorgTooDeep)
+		}
+		if len(notified) != 0 {
+			t.Errorf("subscriber notified of %d reorgs after %v; want 0", len(notified), ErrReorgTooDeep)
+		}
+
+		// Tracking restarts from the new chain.
+		chain.fork("after too deep", d.Head().Number.Uint64()-2, 3)
+		if r := poll(t); r == nil || len(r.Dropped) != 2 {
+			t.Errorf("%T.Poll() after restarted tracking got %+v; want reorg dropping 2 blocks", d, r)
+		}
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		unsubscribe()
+		notified = nil
+		chain.fork("unsubscribed", d.Head().Number.Uint64()-1, 2)
+		if r := poll(t); r == nil {
+			t.Fatalf("%T.Poll() after fork got nil Reorg", d)
+		}
+		if len(notified) != 0 {
+			t.Errorf("unsubscribed function notified of %d reorgs; want 0", len(notified))
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package eth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// A Revert is decoded revert data.
+type Revert struct {
+	// Data is the raw revert data.
+	Data []byte
+	// Error is the decoded error, which is nil if the data couldn't be matched
+	// to any known error. The built-in Error(string) and Panic(uint256) errors
+	// have their respective arguments named "reason" and "code".
+	Error *abi.Error
+	// Args are the decoded arguments of Error.
+	Args []CallArg
+}
+
+// Arg returns the value of the named argument, and whether it exists.
+func (r *Revert) Arg(name string) (interface{}, bool) {
+	for _, a := range r.Args {
+		if a.Name == name {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Built-in Solidity errors.
+var (
+	errorStringError = mustNewBuiltinError("Error", "string", "reason")
+	panicError       = mustNewBuiltinError("Panic", "uint256", "code")
+)
+
+func mustNewBuiltinError(name, typ, argName string) *abi.Error {
+	t, err := abi.NewType(typ, "", nil)
+	if err != nil {
+		panic(err)
+	}
+	e := abi.NewError(name, abi.Arguments{{Name: argName, Type: t}})
+	return &e
+}
+
+// IsErrorString reports whether the revert is the built-in Error(string), as
+// used by require() and revert() with a message.
+func (r *Revert) IsErrorString() bool {
+	return r.Error == errorStringError
+}
+
+// IsPanic reports whether the revert is the built-in Panic(uint256), as caused
+// by, for example, arithmetic overflow or a failed assert().
+func (r *Revert) IsPanic() bool {
+	return r.Error == panicError
+}
+
+// panicReasons maps Solidity panic codes to their descriptions; see
+// https://docs.soliditylang.org/en/latest/control-structures.html#panic-via-assert-and-error-via-require
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum conversion",
+	0x22: "invalid storage byte array encoding",
+	0x31: "pop() on empty array",
+	0x32: "array index out of bounds",
+	0x41: "memory allocation overflow",
+	0x51: "call to zero-initialized function",
+}
+
+// String returns a human-readable form of the revert:
+//   - the message of an Error(string);
+//   - the code and description of a Panic(uint256); e.g. panic 0x11
+//     (arithmetic overflow or underflow);
+//   - custom errors as Name(arg: value, …), as with Call.String(); or
+//   - the hex-encoded data if it couldn't be decoded.
+func (r *Revert) String() string {
+	switch {
+	case len(r.Data) == 0:
+		return ""
+	case r.Error == nil:
+		return hexutil.Encode(r.Data)
+	case r.IsErrorString():
+		return r.Args[0].Value.(string)
+	case r.IsPanic():
+		code := r.Args[0].Value.(*big.Int)
+		if code.IsUint64() {
+			if desc, ok := panicReasons[code.Uint64()]; ok {
+				return fmt.Sprintf("panic %#x (%s)", code, desc)
+			}
+		}
+		return fmt.Sprintf("panic %#x", code)
+	}
+
+	args := make([]string, len(r.Args))
+	for i, a := range r.Args {
+		args[i] = fmt.Sprintf("%s: %v", a.Name, a.Value)
+	}
+	return fmt.Sprintf("%s(%s)", r.Error.Name, strings.Join(args, ", "))
+}
+
+// DecodeRevert decodes revert data. The built-in Error(string) and
+// Panic(uint256) errors are always decoded, while custom errors are decoded if
+// defined in any of the ABIs; the first matching error is used. Data that
+// can't be decoded results in a Revert with a nil Error.
+func DecodeRevert(data []byte, abis ...abi.ABI) *Revert {
+	r := &Revert{Data: data}
+	if len(data) < 4 {
+		return r
+	}
+	sel, packed := data[:4], data[4:]
+
+	candidates := []*abi.Error{errorStringError, panicError}
+	for _, a := range abis {
+		for _, e := range a.Errors {
+			e := e
+			candidates = append(candidates, &e)
+		}
+	}
+
+	for _, e := range candidates {
+		if !bytes.Equal(e.ID[:4], sel) {
+			continue
+		}
+		vals, err := e.Inputs.Unpack(packed)
+		if err != nil {
+			continue
+		}
+		r.Error = e
+		r.Args = make([]CallArg, len(vals))
+		for i, v := range vals {
+			name := e.Inputs[i].Name
+			if name == "" {
+				name = fmt.Sprintf("arg%d", i)
+			}
+			r.Args[i] = CallArg{
+				Name:  name,
+				Type:  e.Inputs[i].Type,
+				Value: v,
+			}
+		}
+		return r
+	}
+	return r
+}
+
+// RevertFromError extracts revert data from an error returned by eth_call or
+// gas estimation, as exposed by both RPC clients and simulated backends (and
+// hence abigen bindings), and decodes it with DecodeRevert(). It returns false
+// if err doesn't carry revert data.
+func RevertFromError(err error, abis ...abi.ABI) (*Revert, bool) {
+	data, ok := revertData(err)
+	if !ok {
+		return nil, false
+	}
+	return DecodeRevert(data, abis...), true
+}
+
+// revertData extracts raw revert data from an error returned by eth_call.
+func revertData(err error) ([]byte, bool) {
+	var de interface{ ErrorData() interface{} }
+	if !errors.As(err, &de) {
+		return nil, false
+	}
+	switch d := de.ErrorData().(type) {
+	case []byte:
+		return d, true
+	case string:
+		b, err := hexutil.Decode(d)
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	default:
+		return nil, false
+	}
+}
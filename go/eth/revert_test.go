@@ -0,0 +1,201 @@
+package eth_test
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// dataError mimics the errors returned by RPC clients and simulated backends
+// when eth_call reverts.
+type dataError struct {
+	data interface{}
+}
+
+func (e dataError) Error() string          { return "execution reverted" }
+func (e dataError) ErrorData() interface{} { return e.data }
+
+func TestDecodeRevert(t *testing.T) {
+	const abiJSON = `[
+		{"type":"error","name":"Unauthorized","inputs":[{"name":"who","type":"address"}]},
+		{"type":"error","name":"TooMany","inputs":[{"name":"","type":"uint256"},{"name":"max","type":"uint256"}]}
+	]`
+	custom, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		t.Fatalf("abi.JSON(%q) error %v", abiJSON, err)
+	}
+
+	pack := func(sig string, args ...interface{}) []byte {
+		t.Helper()
+		b, err := PackCall(sig, args...)
+		if err != nil {
+			t.Fatalf("PackCall(%q, %v) error %v", sig, args, err)
+		}
+		return b
+	}
+	bob := common.HexToAddress("0xb0b")
+
+	tests := []struct {
+		name       string
+		data       []byte
+		abis       []abi.ABI
+		wantError  string // empty implies undecoded
+		wantArgs   map[string]interface{}
+		wantString string
+		wantPanic  bool
+	}{
+		{
+			name:       "Error(string)",
+			data:       pack("Error(string)", "not allowed"),
+			wantError:  "Error",
+			wantArgs:   map[string]interface{}{"reason": "not allowed"},
+			wantString: "not allowed",
+		},
+		{
+			name:       "known panic code",
+			data:       pack("Panic(uint256)", big.NewInt(0x12)),
+			wantError:  "Panic",
+			wantArgs:   map[string]interface{}{"code": big.NewInt(0x12)},
+			wantString: "panic 0x12 (division or modulo by zero)",
+			wantPanic:  true,
+		},
+		{
+			name:       "unknown panic code",
+			data:       pack("Panic(uint256)", big.NewInt(0x99)),
+			wantError:  "Panic",
+			wantArgs:   map[string]interface{}{"code": big.NewInt(0x99)},
+			wantString: "panic 0x99",
+			wantPanic:  true,
+		},
+		{
+			name:       "custom error",
+			data:       pack("Unauthorized(address)", bob),
+			abis:       []abi.ABI{custom},
+			wantError:  "Unauthorized",
+			wantArgs:   map[string]interface{}{"who": bob},
+			wantString: fmt.Sprintf("Unauthorized(who: %v)", bob),
+		},
+		{
+			name:       "custom error with unnamed argument",
+			data:       pack("TooMany(uint256,uint256)", big.NewInt(5), big.NewInt(3)),
+			abis:       []abi.ABI{custom},
+			wantError:  "TooMany",
+			wantArgs:   map[string]interface{}{"arg0": big.NewInt(5), "max": big.NewInt(3)},
+			wantString: "TooMany(arg0: 5, max: 3)",
+		},
+		{
+			name:       "custom error without ABI",
+			data:       pack("Unauthorized(address)", bob),
+			wantString: hexutil.Encode(pack("Unauthorized(address)", bob)),
+		},
+		{
+			name:       "short data",
+			data:       []byte{1, 2},
+			wantString: "0x0102",
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	bigCmp := cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := DecodeRevert(tt.data, tt.abis...)
+
+			var gotError string
+			if r.Error != nil {
+				gotError = r.Error.Name
+			}
+			if gotError != tt.wantError {
+				t.Errorf("DecodeRevert(%#x).Error.Name got %q; want %q", tt.data, gotError, tt.wantError)
+			}
+
+			gotArgs := make(map[string]interface{})
+			for _, a := range r.Args {
+				gotArgs[a.Name] = a.Value
+			}
+			if tt.wantArgs == nil {
+				tt.wantArgs = make(map[string]interface{})
+			}
+			if diff := cmp.Diff(tt.wantArgs, gotArgs, bigCmp); diff != "" {
+				t.Errorf("DecodeRevert(%#x).Args diff (-want +got):\n%s", tt.data, diff)
+			}
+
+			if got := r.String(); got != tt.wantString {
+				t.Errorf("DecodeRevert(%#x).String() got %q; want %q", tt.data, got, tt.wantString)
+			}
+			if got := r.IsPanic(); got != tt.wantPanic {
+				t.Errorf("DecodeRevert(%#x).IsPanic() got %t; want %t", tt.data, got, tt.wantPanic)
+			}
+			if got, want := r.IsErrorString(), tt.wantError == "Error"; got != want {
+				t.Errorf("DecodeRevert(%#x).IsErrorString() got %t; want %t", tt.data, got, want)
+			}
+		})
+	}
+}
+
+func TestRevertFromError(t *testing.T) {
+	data, err := PackCall("Error(string)", "nope")
+	if err != nil {
+		t.Fatalf("PackCall(Error(string)) error %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		wantOK bool
+	}{
+		{
+			name:   "hex string",
+			err:    dataError{hexutil.Encode(data)},
+			wantOK: true,
+		},
+		{
+			name:   "bytes",
+			err:    dataError{data},
+			wantOK: true,
+		},
+		{
+			name:   "wrapped",
+			err:    fmt.Errorf("calling: %w", dataError{hexutil.Encode(data)}),
+			wantOK: true,
+		},
+		{
+			name: "invalid hex",
+			err:  dataError{"nope"},
+		},
+		{
+			name: "no data",
+			err:  errors.New("execution reverted"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := RevertFromError(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("RevertFromError(%v) got ok = %t; want %t", tt.err, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got, want := r.String(), "nope"; got != want {
+				t.Errorf("RevertFromError(%v).String() got %q; want %q", tt.err, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"github.com/cxkoda/solgo/go/secrets"
+)
+
+// A NodeProvider builds node URLs for multiple chains from a single API key.
+type NodeProvider struct {
+	// Name is used only for error reporting.
+	Name string
+	// Template is the node URL with placeholders {network} and {key}, which are
+	// replaced by the respective value from Networks and the fetched APIKey.
+	Template string
+	// Networks maps chain IDs to the provider-specific network name; only
+	// these chains are supported by the provider.
+	Networks map[uint64]string
+	// APIKey is fetched when building a URL. It MAY be nil if Template
+	// doesn't include {key}.
+	APIKey *secrets.Secret
+}
+
+// Chain IDs of networks with default NodeProvider and public fallback support.
+const (
+	MainnetChainID         uint64 = 1
+	OptimismChainID        uint64 = 10
+	PolygonChainID         uint64 = 137
+	BaseChainID            uint64 = 8453
+	ArbitrumChainID        uint64 = 42161
+	BaseSepoliaChainID     uint64 = 84532
+	ArbitrumSepoliaChainID uint64 = 421614
+	SepoliaChainID         uint64 = 11155111
+)
+
+// Infura returns a NodeProvider for Infura, using the API key.
+func Infura(apiKey *secrets.Secret) NodeProvider {
+	return NodeProvider{
+		Name:     "Infura",
+		Template: "https://{network}.infura.io/v3/{key}",
+		Networks: map[uint64]string{
+			MainnetChainID:         "mainnet",
+			SepoliaChainID:         "sepolia",
+			OptimismChainID:        "optimism-mainnet",
+			PolygonChainID:         "polygon-mainnet",
+			BaseChainID:            "base-mainnet",
+			BaseSepoliaChainID:     "base-sepolia",
+			ArbitrumChainID:        "arbitrum-mainnet",
+			ArbitrumSepoliaChainID: "arbitrum-sepolia",
+		},
+		APIKey: apiKey,
+	}
+}
+
+// Alchemy returns a NodeProvider for Alchemy, using the API key.
+func Alchemy(apiKey *secrets.Secret) NodeProvider {
+	return NodeProvider{
+		Name:     "Alchemy",
+		Template: "https://{network}.g.alchemy.com/v2/{key}",
+		Networks: map[uint64]string{
+			MainnetChainID:         "eth-mainnet",
+			SepoliaChainID:         "eth-sepolia",
+			OptimismChainID:        "opt-mainnet",
+			PolygonChainID:         "polygon-mainnet",
+			BaseChainID:            "base-mainnet",
+			BaseSepoliaChainID:     "base-sepolia",
+			ArbitrumChainID:        "arb-mainnet",
+			ArbitrumSepoliaChainID: "arb-sepolia",
+		},
+		APIKey: apiKey,
+	}
+}
+
+// PublicRPCURLs are rate-limited, keyless node URLs used as a fallback by
+// RPCURL(). They are suitable for low-volume tools but not production servers.
+var PublicRPCURLs = map[uint64][]string{
+	MainnetChainID:         {"https://ethereum-rpc.publicnode.com", "https://cloudflare-eth.com"},
+	SepoliaChainID:         {"https://ethereum-sepolia-rpc.publicnode.com", "https://rpc.sepolia.org"},
+	OptimismChainID:        {"https://mainnet.optimism.io"},
+	PolygonChainID:         {"https://polygon-rpc.com"},
+	BaseChainID:            {"https://mainnet.base.org"},
+	BaseSepoliaChainID:     {"https://sepolia.base.org"},
+	ArbitrumChainID:        {"https://arb1.arbitrum.io/rpc"},
+	ArbitrumSepoliaChainID: {"https://sepolia-rollup.arbitrum.io/rpc"},
+}
+
+// ErrUnsupportedChain is returned when no node URL can be built for a chain.
+var ErrUnsupportedChain = fmt.Errorf("unsupported chain")
+
+// Supports reports whether p can build a URL for the chain.
+func (p NodeProvider) Supports(chainID uint64) bool {
+	_, ok := p.Networks[chainID]
+	return ok
+}
+
+// URL returns p.Template with placeholders replaced for the chain. The Options
+// are propagated when Fetch()ing the API key.
+func (p NodeProvider) URL(ctx context.Context, chainID uint64, opts ...secrets.Option) (string, error) {
+	network, ok := p.Networks[chainID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s doesn't support chain %d", ErrUnsupportedChain, p.Name, chainID)
+	}
+
+	url := strings.ReplaceAll(p.Template, "{network}", network)
+	if !strings.Contains(url, "{key}") {
+		return url, nil
+	}
+	if p.APIKey == nil {
+		return "", fmt.Errorf("%s URL template requires API key but %T is nil", p.Name, p.APIKey)
+	}
+	key, err := p.APIKey.Fetch(ctx, opts...)
+	if err != nil {
+		return "", fmt.Errorf("%T(%q).Fetch(…) for %s API key: %v", p.APIKey, p.APIKey.String(), p.Name, err)
+	}
+	return strings.ReplaceAll(url, "{key}", string(key)), nil
+}
+
+// RPCURL returns a node URL for the chain from the first NodeProvider that
+// both supports the chain and has an API key that can be fetched. If none of
+// the providers are able to build a URL, the first of PublicRPCURLs for the
+// chain is returned. Errors from providers are returned if there is also no
+// public fallback, and otherwise logged as a warning because they typically
+// indicate misconfiguration (e.g. a missing API key) that would silently
+// degrade to a rate-limited public node.
+func RPCURL(ctx context.Context, chainID uint64, providers []NodeProvider, opts ...secrets.Option) (string, error) {
+	var errs []string
+	for _, p := range providers {
+		if !p.Supports(chainID) {
+			continue
+		}
+		url, err := p.URL(ctx, chainID, opts...)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		return url, nil
+	}
+
+	if urls := PublicRPCURLs[chainID]; len(urls) > 0 {
+		if len(errs) > 0 {
+			glog.Warningf("Falling back to public node URL for chain %d: %s", chainID, strings.Join(errs, "; "))
+		}
+		return urls[0], nil
+	}
+	if len(errs) > 0 {
+		return "", fmt.Errorf("no node URL for chain %d: %s", chainID, strings.Join(errs, "; "))
+	}
+	return "", fmt.Errorf("%w: no provider or public URL for chain %d", ErrUnsupportedChain, chainID)
+}
@@ -0,0 +1,143 @@
+package eth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cxkoda/solgo/go/secrets"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestRPCURL(t *testing.T) {
+	ctx := context.Background()
+
+	key := &secrets.Secret{Source: secrets.Raw, ID: "s3cr3t"}
+	missing := &secrets.Secret{Source: secrets.Environment, ID: "SOLGO_TEST_VAR_THAT_IS_NEVER_SET"}
+
+	const unknownChain = 42 << 42
+
+	tests := []struct {
+		name      string
+		chainID   uint64
+		providers []NodeProvider
+		want      string
+		wantErrIs error
+	}{
+		{
+			name:      "Infura mainnet",
+			chainID:   MainnetChainID,
+			providers: []NodeProvider{Infura(key), Alchemy(key)},
+			want:      "https://mainnet.infura.io/v3/s3cr3t",
+		},
+		{
+			name:      "Alchemy Base",
+			chainID:   BaseChainID,
+			providers: []NodeProvider{Alchemy(key), Infura(key)},
+			want:      "https://base-mainnet.g.alchemy.com/v2/s3cr3t",
+		},
+		{
+			name:      "skip provider with missing key",
+			chainID:   ArbitrumChainID,
+			providers: []NodeProvider{Infura(missing), Alchemy(key)},
+			want:      "https://arb-mainnet.g.alchemy.com/v2/s3cr3t",
+		},
+		{
+			name:    "skip unsupported custom provider",
+			chainID: SepoliaChainID,
+			providers: []NodeProvider{
+				{
+					Name:     "custom",
+					Template: "https://{network}.example.com",
+					Networks: map[uint64]string{MainnetChainID: "eth"},
+				},
+				Infura(key),
+			},
+			want: "https://sepolia.infura.io/v3/s3cr3t",
+		},
+		{
+			name:    "custom template without key",
+			chainID: MainnetChainID,
+			providers: []NodeProvider{{
+				Name:     "custom",
+				Template: "https://{network}.example.com",
+				Networks: map[uint64]string{MainnetChainID: "eth"},
+			}},
+			want: "https://eth.example.com",
+		},
+		{
+			name:      "public fallback",
+			chainID:   BaseSepoliaChainID,
+			providers: []NodeProvider{Infura(missing)},
+			want:      "https://sepolia.base.org",
+		},
+		{
+			name:    "no providers",
+			chainID: PolygonChainID,
+			want:    "https://polygon-rpc.com",
+		},
+		{
+			name:      "unsupported chain",
+			chainID:   unknownChain,
+			providers: []NodeProvider{Infura(key)},
+			wantErrIs: ErrUnsupportedChain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RPCURL(ctx, tt.chainID, tt.providers)
+			if !errors.Is(err, tt.wantErrIs) {
+				t.Fatalf("RPCURL(ctx, %d, …) got err %v; want %v", tt.chainID, err, tt.wantErrIs)
+			}
+			if got != tt.want {
+				t.Errorf("RPCURL(ctx, %d, …) got %q; want %q", tt.chainID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewChainDialer(t *testing.T) {
+	ctx := context.Background()
+
+	const chainID = 1337
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_chainId" {
+			http.Error(w, "unsupported", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"%#x"}`, req.ID, chainID)
+	}))
+	defer srv.Close()
+
+	d := NewChainDialer(chainID, []NodeProvider{{
+		Name:     "stub",
+		Template: srv.URL + "/{network}",
+		Networks: map[uint64]string{chainID: "local"},
+	}})
+	client, err := d.Dial(ctx)
+	if err != nil {
+		t.Fatalf("%T.Dial() error %v", d, err)
+	}
+	defer client.Close()
+
+	got, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("%T.ChainID() error %v", client, err)
+	}
+	if got.Uint64() != chainID {
+		t.Errorf("%T.ChainID() got %d; want %d", client, got, chainID)
+	}
+}
@@ -57,6 +57,10 @@ const DefaultHDPathPrefix = HDPathPrefix("m/44'/60'/0'/0/")
 
 // SignerFromSeedPhrase confirms that the mnemonic is valid under BIP39 and then
 // uses it to derive a private key (see HDPathF)
+//
+// For backwards compatibility, derivation is non-standard for a small fraction
+// of keys, which therefore differ from those of other wallets. Use a Wallet if
+// compatibility is required; e.g. WalletFromMnemonic(…, MetaMaskHDPath).
 func (hdp HDPathPrefix) SignerFromSeedPhrase(mnemonic, password string, account uint) (*Signer, error) {
 	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, password)
 	if err != nil {
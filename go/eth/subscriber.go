@@ -0,0 +1,275 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/glog"
+)
+
+// A LogSource can filter and subscribe to logs. Typically this would be an
+// *ethclient.Client dialed over a WebSocket or IPC connection.
+type LogSource interface {
+	ethereum.LogFilterer
+	BlockNumber(context.Context) (uint64, error)
+}
+
+// A Subscriber delivers an ordered, gap-free stream of logs matching a filter.
+// It wraps SubscribeFilterLogs(), detecting dropped subscriptions and
+// resubscribing, and backfills any logs missed in the interim with
+// FilterLogs().
+//
+// Logs are delivered in (block number, log index) order and each is delivered
+// exactly once, with the exception of chain reorganisations. A log flagged as
+// Removed by a reorganisation is delivered, with Removed set, if and only if it
+// was previously delivered. Delivery then rewinds to the removed log's position
+// so the replacement logs are delivered even though they MAY be at the same or
+// earlier positions than those already delivered; callbacks MUST therefore
+// check Removed. Reorganisations that occur while resubscribing are not
+// detected because FilterLogs() only returns canonical logs.
+type Subscriber struct {
+	src   LogSource
+	query ethereum.FilterQuery
+
+	// Backoff is the delay before resubscribing after a dropped subscription.
+	// It defaults to 1s if zero.
+	Backoff time.Duration
+	// MaxBackfillRange is the maximum number of blocks requested in a single
+	// call to FilterLogs(), as many nodes limit this. It defaults to 2000 if
+	// zero.
+	MaxBackfillRange uint64
+}
+
+// NewSubscriber returns a Subscriber for logs matching the query. The
+// FromBlock and ToBlock fields of the query are ignored; see Subscriber.Run().
+func NewSubscriber(src LogSource, query ethereum.FilterQuery) *Subscriber {
+	query.FromBlock = nil
+	query.ToBlock = nil
+	query.BlockHash = nil
+	return &Subscriber{
+		src:   src,
+		query: query,
+	}
+}
+
+// logPosition is the position of a log in the chain.
+type logPosition struct {
+	block uint64
+	index uint
+}
+
+func (p logPosition) after(q logPosition) bool {
+	return p.block > q.block || (p.block == q.block && p.index > q.index)
+}
+
+// prev returns the position immediately before p. The returned boolean is false
+// if p is the first possible position.
+func (p logPosition) prev() (logPosition, bool) {
+	switch {
+	case p.index > 0:
+		return logPosition{p.block, p.index - 1}, true
+	case p.block > 0:
+		return logPosition{p.block - 1, ^uint(0)}, true
+	default:
+		return logPosition{}, false
+	}
+}
+
+// Run delivers logs to fn, starting with those in fromBlock, until ctx is
+// cancelled or fn returns an error, which is returned by Run. Errors from the
+// LogSource are retried after Subscriber.Backoff.
+func (s *Subscriber) Run(ctx context.Context, fromBlock uint64, fn func(types.Log) error) error {
+	r := &subscriberRun{
+		Subscriber: s,
+		fn:         fn,
+		from:       fromBlock,
+		next:       fromBlock,
+	}
+	for {
+		err := r.subscribeAndDeliver(ctx)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if cbErr, ok := err.(callbackError); ok {
+			return cbErr.error
+		}
+		glog.Warningf("%T: resubscribing after error: %v", s, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.backoff()):
+		}
+	}
+}
+
+// Range delivers all logs in blocks [from, to] to fn, in the same order as
+// Run(), but without subscribing; i.e. only FilterLogs() is used, in chunks of
+// MaxBackfillRange blocks. Unlike Run(), errors from the LogSource are not
+// retried, and reorganisations between chunks aren't detected so to SHOULD be
+// a finalised block.
+func (s *Subscriber) Range(ctx context.Context, from, to uint64, fn func(types.Log) error) error {
+	r := &subscriberRun{
+		Subscriber: s,
+		fn:         fn,
+		from:       from,
+		next:       from,
+	}
+	err := r.backfill(ctx, to)
+	if cbErr, ok := err.(callbackError); ok {
+		return cbErr.error
+	}
+	return err
+}
+
+func (s *Subscriber) backoff() time.Duration {
+	if s.Backoff == 0 {
+		return time.Second
+	}
+	return s.Backoff
+}
+
+func (s *Subscriber) maxRange() uint64 {
+	if s.MaxBackfillRange == 0 {
+		return 2000
+	}
+	return s.MaxBackfillRange
+}
+
+// A callbackError wraps an error returned by the user-provided callback so it
+// can be differentiated from those to be retried.
+type callbackError struct {
+	error
+}
+
+// subscriberRun carries the state of a single Subscriber.Run() call.
+type subscriberRun struct {
+	*Subscriber
+	fn func(types.Log) error
+
+	// from is the first block from which logs are delivered.
+	from uint64
+	// next is the first block for which not all logs have been delivered.
+	next uint64
+	// last is the position of the last delivered log, rewound by removals; it
+	// is only valid if delivered is true.
+	last      logPosition
+	delivered bool
+	// high is the position of the highest log ever delivered, regardless of
+	// removals; it is only valid if any is true.
+	high logPosition
+	any  bool
+}
+
+// deliver calls fn(l) if, and only if, l is after the last delivered log. See
+// remove() for logs flagged as Removed.
+func (r *subscriberRun) deliver(l types.Log) error {
+	pos := logPosition{block: l.BlockNumber, index: l.Index}
+	if l.Removed {
+		return r.remove(l, pos)
+	}
+	if l.BlockNumber < r.next || (r.delivered && !pos.after(r.last)) {
+		return nil
+	}
+	if err := r.fn(l); err != nil {
+		return callbackError{err}
+	}
+	r.last = pos
+	r.delivered = true
+	r.next = l.BlockNumber
+	if !r.any || pos.after(r.high) {
+		r.high, r.any = pos, true
+	}
+	return nil
+}
+
+// remove calls fn(l) if, and only if, the removed log was previously delivered,
+// and then rewinds r so that the log's replacements are delivered. Logs are
+// delivered without gaps so those between r.from and r.high are exactly those
+// that were delivered. Removals are handled independently of the order in
+// which they are received.
+func (r *subscriberRun) remove(l types.Log, pos logPosition) error {
+	if !r.any || l.BlockNumber < r.from || pos.after(r.high) {
+		return nil
+	}
+	if err := r.fn(l); err != nil {
+		return callbackError{err}
+	}
+	if r.delivered && !pos.after(r.last) {
+		r.last, r.delivered = pos.prev()
+	}
+	if l.BlockNumber < r.next {
+		r.next = l.BlockNumber
+	}
+	return nil
+}
+
+// subscribeAndDeliver subscribes to new logs, backfills from r.next to the
+// current head, and then delivers from the subscription until an error occurs.
+func (r *subscriberRun) subscribeAndDeliver(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before backfilling so that no logs are missed between the two;
+	// duplicates are dropped by deliver().
+	ch := make(chan types.Log, 1024)
+	sub, err := r.src.SubscribeFilterLogs(ctx, r.query, ch)
+	if err != nil {
+		return fmt.Errorf("%T.SubscribeFilterLogs(): %v", r.src, err)
+	}
+	defer sub.Unsubscribe()
+
+	head, err := r.src.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("%T.BlockNumber(): %v", r.src, err)
+	}
+	if err := r.backfill(ctx, head); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			if err == nil {
+				return fmt.Errorf("subscription closed")
+			}
+			return fmt.Errorf("subscription: %v", err)
+		case l := <-ch:
+			if err := r.deliver(l); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// backfill delivers all logs in [r.next, head] using FilterLogs().
+func (r *subscriberRun) backfill(ctx context.Context, head uint64) error {
+	for r.next <= head {
+		to := r.next + r.maxRange() - 1
+		if to > head {
+			to = head
+		}
+
+		q := r.query
+		q.FromBlock = new(big.Int).SetUint64(r.next)
+		q.ToBlock = new(big.Int).SetUint64(to)
+		logs, err := r.src.FilterLogs(ctx, q)
+		if err != nil {
+			return fmt.Errorf("%T.FilterLogs([%d, %d]): %v", r.src, r.next, to, err)
+		}
+		for _, l := range logs {
+			if err := r.deliver(l); err != nil {
+				return err
+			}
+		}
+		// All logs up to and including `to` have been delivered.
+		r.next = to + 1
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeLogSource is a LogSource with an in-memory chain of logs. Only a single
+// subscription is supported at a time.
+type fakeLogSource struct {
+	mu     sync.Mutex
+	logs   []types.Log // in chain order
+	head   uint64
+	sub    *fakeSubscription
+	subbed chan struct{}
+}
+
+type fakeSubscription struct {
+	ch   chan<- types.Log
+	errs chan error
+}
+
+func (s *fakeSubscription) Unsubscribe()      {}
+func (s *fakeSubscription) Err() <-chan error { return s.errs }
+
+func (f *fakeLogSource) BlockNumber(context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.head, nil
+}
+
+func (f *fakeLogSource) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	from, to := q.FromBlock.Uint64(), q.ToBlock.Uint64()
+	var logs []types.Log
+	for _, l := range f.logs {
+		if l.BlockNumber >= from && l.BlockNumber <= to {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func (f *fakeLogSource) SubscribeFilterLogs(_ context.Context, _ ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sub = &fakeSubscription{ch: ch, errs: make(chan error, 1)}
+	f.subbed <- struct{}{}
+	return f.sub, nil
+}
+
+// mine adds the log to the chain and advances the head.
+func (f *fakeLogSource) mine(l types.Log) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logs = append(f.logs, l)
+	f.head = l.BlockNumber
+}
+
+// reorg drops all logs in blocks from the one specified onwards, without
+// sending removals, and rewinds the head to its parent.
+func (f *fakeLogSource) reorg(from uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var logs []types.Log
+	for _, l := range f.logs {
+		if l.BlockNumber < from {
+			logs = append(logs, l)
+		}
+	}
+	f.logs = logs
+	f.head = from - 1
+}
+
+// send sends the log to the current subscription.
+func (f *fakeLogSource) send(l types.Log) {
+	f.mu.Lock()
+	ch := f.sub.ch
+	f.mu.Unlock()
+	ch <- l
+}
+
+// mineAndSend is equivalent to mine(l) followed by send(l).
+func (f *fakeLogSource) mineAndSend(l types.Log) {
+	f.mine(l)
+	f.send(l)
+}
+
+func (f *fakeLogSource) dropSubscription() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sub.errs <- errors.New("connection lost")
+}
+
+func logAt(block uint64, index uint) types.Log {
+	return types.Log{BlockNumber: block, Index: index}
+}
+
+func TestSubscriber(t *testing.T) {
+	src := &fakeLogSource{
+		logs: []types.Log{
+			logAt(1, 0),
+			logAt(1, 1),
+			logAt(3, 0),
+			logAt(5, 7),
+		},
+		head:   6,
+		subbed: make(chan struct{}, 1),
+	}
+
+	sub := NewSubscriber(src, ethereum.FilterQuery{})
+	sub.Backoff = time.Millisecond
+	sub.MaxBackfillRange = 2
+
+	removed := func(l types.Log) types.Log {
+		l.Removed = true
+		return l
+	}
+	// replacement is a log at the same position as logAt(7, 0) but in a block
+	// from a different fork.
+	replacement := types.Log{BlockNumber: 7, Index: 0, TxIndex: 1}
+
+	want := []types.Log{
+		logAt(3, 0),
+		logAt(5, 7),
+		logAt(7, 0),
+		logAt(7, 1),
+		removed(logAt(7, 1)),
+		removed(logAt(7, 0)),
+		replacement,
+		logAt(7, 2),
+		logAt(8, 0),
+		logAt(9, 3),
+		logAt(10, 0),
+	}
+
+	var got []types.Log
+	stop := errors.New("stop")
+	runErr := make(chan error)
+	numDelivered := make(chan int, len(want))
+	go func() {
+		runErr <- sub.Run(context.Background(), 2, func(l types.Log) error {
+			got = append(got, l)
+			numDelivered <- len(got)
+			if len(got) == len(want) {
+				return stop
+			}
+			return nil
+		})
+	}()
+
+	// waitFor blocks until n logs have been delivered, as logs buffered in the
+	// subscription channel are lost when it is dropped.
+	waitFor := func(n int) {
+		t.Helper()
+		for {
+			select {
+			case d := <-numDelivered:
+				if d >= n {
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for %d logs to be delivered", n)
+			}
+		}
+	}
+
+	<-src.subbed
+	src.mineAndSend(logAt(7, 0))
+	src.mineAndSend(logAt(7, 1))
+	src.send(logAt(7, 1)) // duplicate
+	waitFor(4)
+
+	src.reorg(7)
+	// Removals are sent in reverse order to demonstrate independence.
+	src.send(removed(logAt(7, 1)))
+	src.send(removed(logAt(7, 0)))
+	src.send(removed(logAt(8, 5))) // never delivered
+	src.send(removed(logAt(1, 0))) // before fromBlock
+	src.mineAndSend(replacement)
+	waitFor(7)
+
+	src.dropSubscription()
+	// Mined while disconnected so must be backfilled, including the remainder
+	// of the partially delivered block.
+	src.mine(logAt(7, 2))
+	src.mine(logAt(8, 0))
+	<-src.subbed
+
+	src.send(logAt(8, 0)) // duplicate of backfilled log
+	src.mineAndSend(logAt(9, 3))
+	src.mineAndSend(logAt(10, 0))
+
+	select {
+	case err := <-runErr:
+		if !errors.Is(err, stop) {
+			t.Errorf("%T.Run() got err %v; want %v", sub, err, stop)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%T.Run() timed out", sub)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%T.Run() delivered logs diff (-want +got):\n%s", sub, diff)
+	}
+}
+
+func TestSubscriberRange(t *testing.T) {
+	src := &fakeLogSource{
+		logs: []types.Log{
+			logAt(1, 0),
+			logAt(2, 0),
+			logAt(2, 1),
+			logAt(4, 3),
+			logAt(5, 0),
+			logAt(6, 0),
+		},
+		head: 6,
+	}
+
+	sub := NewSubscriber(src, ethereum.FilterQuery{})
+	sub.MaxBackfillRange = 2
+
+	var got []types.Log
+	if err := sub.Range(context.Background(), 2, 5, func(l types.Log) error {
+		got = append(got, l)
+		return nil
+	}); err != nil {
+		t.Fatalf("%T.Range(2, 5) error %v", sub, err)
+	}
+
+	want := []types.Log{
+		logAt(2, 0),
+		logAt(2, 1),
+		logAt(4, 3),
+		logAt(5, 0),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%T.Range(2, 5) delivered logs diff (-want +got):\n%s", sub, diff)
+	}
+
+	stop := errors.New("stop")
+	if err := sub.Range(context.Background(), 0, 6, func(types.Log) error {
+		return stop
+	}); !errors.Is(err, stop) {
+		t.Errorf("%T.Range() with erroring callback got err %v; want %v", sub, err, stop)
+	}
+}
@@ -0,0 +1,229 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// An RPCCaller performs raw JSON-RPC calls; it is typically an *rpc.Client,
+// as returned by ethclient.Client.Client().
+type RPCCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// Types of CallFrame, as reported by the callTracer.
+const (
+	CallFrameCall         = "CALL"
+	CallFrameStaticCall   = "STATICCALL"
+	CallFrameDelegateCall = "DELEGATECALL"
+	CallFrameCallCode     = "CALLCODE"
+	CallFrameCreate       = "CREATE"
+	CallFrameCreate2      = "CREATE2"
+	CallFrameSelfDestruct = "SELFDESTRUCT"
+)
+
+// A CallFrame is a node in the call tree of a transaction, as returned by
+// debug_traceTransaction with the built-in callTracer. The root frame is the
+// transaction itself.
+type CallFrame struct {
+	Type string
+	From common.Address
+	// To is the callee or, for CREATE and CREATE2, the deployed contract.
+	To      common.Address
+	Value   *big.Int // nil if the frame type doesn't carry value
+	Gas     uint64
+	GasUsed uint64
+	Input   []byte
+	Output  []byte
+	// Error is non-empty if the frame failed, in which case all of its
+	// effects, including those of its descendants, were reverted.
+	Error        string
+	RevertReason string
+	Calls        []*CallFrame
+}
+
+// callFrameJSON is the wire format of a CallFrame.
+type callFrameJSON struct {
+	Type         string         `json:"type"`
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Value        *hexutil.Big   `json:"value"`
+	Gas          hexutil.Uint64 `json:"gas"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	Input        hexutil.Bytes  `json:"input"`
+	Output       hexutil.Bytes  `json:"output"`
+	Error        string         `json:"error"`
+	RevertReason string         `json:"revertReason"`
+	Calls        []*CallFrame   `json:"calls"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, parsing callTracer output.
+func (f *CallFrame) UnmarshalJSON(buf []byte) error {
+	var j callFrameJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+	*f = CallFrame{
+		Type:         j.Type,
+		From:         j.From,
+		To:           j.To,
+		Value:        j.Value.ToInt(),
+		Gas:          uint64(j.Gas),
+		GasUsed:      uint64(j.GasUsed),
+		Input:        j.Input,
+		Output:       j.Output,
+		Error:        j.Error,
+		RevertReason: j.RevertReason,
+		Calls:        j.Calls,
+	}
+	return nil
+}
+
+// TraceTransaction returns the call tree of the transaction, as traced by the
+// node's callTracer. The node MUST support the debug namespace, which is
+// typically only available from archive nodes or specialist providers.
+func TraceTransaction(ctx context.Context, c RPCCaller, tx common.Hash) (*CallFrame, error) {
+	var root *CallFrame
+	cfg := map[string]interface{}{"tracer": "callTracer"}
+	if err := c.CallContext(ctx, &root, "debug_traceTransaction", tx, cfg); err != nil {
+		return nil, fmt.Errorf("debug_traceTransaction(%v, callTracer): %v", tx, err)
+	}
+	if root == nil {
+		return nil, fmt.Errorf("debug_traceTransaction(%v, callTracer): empty trace", tx)
+	}
+	return root, nil
+}
+
+// Walk calls fn for f and all of its descendants, in depth-first pre-order,
+// with the depth of each frame relative to f. If fn returns false, the frame's
+// descendants are skipped.
+func (f *CallFrame) Walk(fn func(frame *CallFrame, depth int) bool) {
+	f.walk(fn, 0)
+}
+
+func (f *CallFrame) walk(fn func(*CallFrame, int) bool, depth int) {
+	if !fn(f, depth) {
+		return
+	}
+	for _, c := range f.Calls {
+		c.walk(fn, depth+1)
+	}
+}
+
+// Failed reports whether the frame failed, reverting all of its effects.
+func (f *CallFrame) Failed() bool {
+	return f.Error != ""
+}
+
+// A DecodedFrame is a CallFrame's input and output decoded against known ABIs.
+type DecodedFrame struct {
+	// Call is the decoded input, which is nil if the input is empty (e.g. a
+	// plain ETH transfer) or doesn't match any known method.
+	Call *Call
+	// Outputs are the decoded return values of Call's method, which are nil if
+	// Call is nil or the frame failed.
+	Outputs []CallArg
+	// Revert is the decoded output of a failed frame, which is nil if the
+	// frame succeeded or failed without revert data (e.g. out of gas).
+	Revert *Revert
+}
+
+// Decode decodes the frame's input and output against the methods and errors
+// of the ABIs. An error is only returned if a matched method's arguments or
+// return values can't be unpacked. Input of CREATE and CREATE2 frames is
+// contract init code so is never decoded.
+func (f *CallFrame) Decode(abis ...abi.ABI) (*DecodedFrame, error) {
+	d := new(DecodedFrame)
+	if f.Failed() && len(f.Output) > 0 {
+		d.Revert = DecodeRevert(f.Output, abis...)
+	}
+	if f.Type == CallFrameCreate || f.Type == CallFrameCreate2 || len(f.Input) < 4 {
+		return d, nil
+	}
+
+	var methods []*abi.Method
+	for _, a := range abis {
+		for _, m := range a.Methods {
+			m := m
+			methods = append(methods, &m)
+		}
+	}
+	call, err := DecodeMethodCall(f.Input, methods...)
+	switch {
+	case errors.Is(err, ErrUnknownSelector):
+		return d, nil
+	case err != nil:
+		return nil, fmt.Errorf("decoding input of %s frame to %v: %v", f.Type, f.To, err)
+	}
+	d.Call = call
+
+	if f.Failed() {
+		return d, nil
+	}
+	vals, err := call.Method.Outputs.Unpack(f.Output)
+	if err != nil {
+		return nil, fmt.Errorf("unpacking outputs of %s: %v", call.Method.Sig, err)
+	}
+	d.Outputs = make([]CallArg, len(vals))
+	for i, v := range vals {
+		out := call.Method.Outputs[i]
+		name := out.Name
+		if name == "" {
+			name = fmt.Sprintf("ret%d", i)
+		}
+		d.Outputs[i] = CallArg{
+			Name:  name,
+			Type:  out.Type,
+			Value: v,
+		}
+	}
+	return d, nil
+}
+
+// A ValueTransfer is a movement of ETH between accounts within a transaction.
+type ValueTransfer struct {
+	From, To common.Address
+	Value    *big.Int
+	// Type is that of the CallFrame effecting the transfer.
+	Type string
+	// Depth is that of the CallFrame relative to the root, which is depth 0.
+	Depth int
+}
+
+// InternalTransfers returns all ETH transfers effected by descendants of f,
+// excluding f's own value, in the order in which they occurred. Transfers in
+// failed frames, or in descendants of failed frames, are excluded as they
+// were reverted. DELEGATECALL and CALLCODE frames are also excluded because
+// they execute code in the context of the caller and therefore don't move
+// ETH.
+func (f *CallFrame) InternalTransfers() []ValueTransfer {
+	var ts []ValueTransfer
+	f.Walk(func(frame *CallFrame, depth int) bool {
+		if frame.Failed() {
+			return false
+		}
+		if depth == 0 || frame.Value == nil || frame.Value.Sign() == 0 {
+			return true
+		}
+		switch frame.Type {
+		case CallFrameDelegateCall, CallFrameCallCode:
+			return true
+		}
+		ts = append(ts, ValueTransfer{
+			From:  frame.From,
+			To:    frame.To,
+			Value: new(big.Int).Set(frame.Value),
+			Type:  frame.Type,
+			Depth: depth,
+		})
+		return true
+	})
+	return ts
+}
@@ -0,0 +1,204 @@
+package eth_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// fakeTracer responds to debug_traceTransaction with a fixed JSON trace.
+type fakeTracer struct {
+	trace string
+}
+
+func (f fakeTracer) CallContext(_ context.Context, result interface{}, method string, args ...interface{}) error {
+	if method != "debug_traceTransaction" {
+		return fmt.Errorf("unsupported method %q", method)
+	}
+	return json.Unmarshal([]byte(f.trace), result)
+}
+
+const traceABI = `[
+	{"type": "function", "name": "swap", "inputs": [{"name": "amount", "type": "uint256"}], "outputs": [{"name": "out", "type": "uint256"}]},
+	{"type": "error", "name": "Slippage", "inputs": [{"name": "got", "type": "uint256"}]}
+]`
+
+func TestTraceTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	parsed, err := abi.JSON(strings.NewReader(traceABI))
+	if err != nil {
+		t.Fatalf("abi.JSON(…) error %v", err)
+	}
+	swap := parsed.Methods["swap"]
+	swapInput, err := swap.Inputs.Pack(big.NewInt(42))
+	if err != nil {
+		t.Fatalf("%T.Pack(42) error %v", swap.Inputs, err)
+	}
+	swapInput = append(swap.ID, swapInput...)
+	swapOutput, err := swap.Outputs.Pack(big.NewInt(99))
+	if err != nil {
+		t.Fatalf("%T.Pack(99) error %v", swap.Outputs, err)
+	}
+	slippage := parsed.Errors["Slippage"]
+	revertData, err := slippage.Inputs.Pack(big.NewInt(1))
+	if err != nil {
+		t.Fatalf("%T.Pack(1) error %v", slippage.Inputs, err)
+	}
+	revertData = append(slippage.ID[:4:4], revertData...)
+
+	var (
+		eoa    = common.HexToAddress("0xe0a")
+		router = common.HexToAddress("0x4047e4")
+		pool   = common.HexToAddress("0x9001")
+		impl   = common.HexToAddress("0x1111")
+		alice  = common.HexToAddress("0xa11ce")
+		bob    = common.HexToAddress("0xb0b")
+	)
+
+	trace := fmt.Sprintf(`{
+		"type": "CALL", "from": "%[1]s", "to": "%[2]s", "value": "0x64", "gas": "0x5208", "gasUsed": "0x5000",
+		"input": "%[7]s", "output": "%[8]s",
+		"calls": [
+			{"type": "DELEGATECALL", "from": "%[2]s", "to": "%[4]s", "value": "0x64", "gas": "0x10", "gasUsed": "0x1", "input": "0x"},
+			{"type": "CALL", "from": "%[2]s", "to": "%[3]s", "value": "0x0", "gas": "0x10", "gasUsed": "0x1", "input": "%[7]s", "output": "%[8]s",
+				"calls": [
+					{"type": "CALL", "from": "%[3]s", "to": "%[5]s", "value": "0xa", "gas": "0x10", "gasUsed": "0x1", "input": "0x"}
+				]
+			},
+			{"type": "CALL", "from": "%[2]s", "to": "%[3]s", "value": "0x0", "gas": "0x10", "gasUsed": "0x1", "input": "%[7]s", "output": "%[9]s",
+				"error": "execution reverted",
+				"calls": [
+					{"type": "CALL", "from": "%[3]s", "to": "%[6]s", "value": "0x7", "gas": "0x10", "gasUsed": "0x1", "input": "0x"}
+				]
+			},
+			{"type": "STATICCALL", "from": "%[2]s", "to": "%[3]s", "gas": "0x10", "gasUsed": "0x1", "input": "0xdeadbeef"},
+			{"type": "SELFDESTRUCT", "from": "%[3]s", "to": "%[6]s", "value": "0x5", "gas": "0x0", "gasUsed": "0x0", "input": "0x"}
+		]
+	}`, eoa.Hex(), router.Hex(), pool.Hex(), impl.Hex(), alice.Hex(), bob.Hex(), hexBytes(swapInput), hexBytes(swapOutput), hexBytes(revertData))
+
+	root, err := TraceTransaction(ctx, fakeTracer{trace}, common.Hash{})
+	if err != nil {
+		t.Fatalf("TraceTransaction() error %v", err)
+	}
+
+	t.Run("Walk", func(t *testing.T) {
+		var got []string
+		root.Walk(func(f *CallFrame, depth int) bool {
+			got = append(got, fmt.Sprintf("%d:%s", depth, f.Type))
+			return !f.Failed()
+		})
+		want := []string{"0:CALL", "1:DELEGATECALL", "1:CALL", "2:CALL", "1:CALL", "1:STATICCALL", "1:SELFDESTRUCT"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%T.Walk() frames diff (-want +got):\n%s", root, diff)
+		}
+	})
+
+	t.Run("InternalTransfers", func(t *testing.T) {
+		got := root.InternalTransfers()
+		want := []ValueTransfer{
+			{From: pool, To: alice, Value: big.NewInt(10), Type: CallFrameCall, Depth: 2},
+			{From: pool, To: bob, Value: big.NewInt(5), Type: CallFrameSelfDestruct, Depth: 1},
+		}
+		if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })); diff != "" {
+			t.Errorf("%T.InternalTransfers() diff (-want +got):\n%s", root, diff)
+		}
+	})
+
+	t.Run("Decode", func(t *testing.T) {
+		tests := []struct {
+			name        string
+			frame       *CallFrame
+			wantMethod  string
+			wantArgs    map[string]interface{}
+			wantOutputs map[string]interface{}
+			wantRevert  string
+		}{
+			{
+				name:        "success",
+				frame:       root,
+				wantMethod:  "swap",
+				wantArgs:    map[string]interface{}{"amount": big.NewInt(42)},
+				wantOutputs: map[string]interface{}{"out": big.NewInt(99)},
+			},
+			{
+				name:       "reverted",
+				frame:      root.Calls[2],
+				wantMethod: "swap",
+				wantArgs:   map[string]interface{}{"amount": big.NewInt(42)},
+				wantRevert: "Slippage",
+			},
+			{
+				name:  "unknown selector",
+				frame: root.Calls[3],
+			},
+			{
+				name:  "value transfer",
+				frame: root.Calls[1].Calls[0],
+			},
+		}
+
+		argMap := func(args []CallArg) map[string]interface{} {
+			if args == nil {
+				return nil
+			}
+			m := make(map[string]interface{})
+			for _, a := range args {
+				m[a.Name] = a.Value
+			}
+			return m
+		}
+		bigCmp := cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				got, err := tt.frame.Decode(parsed)
+				if err != nil {
+					t.Fatalf("%T.Decode() error %v", tt.frame, err)
+				}
+
+				var (
+					method string
+					args   map[string]interface{}
+					revert string
+				)
+				if got.Call != nil {
+					method = got.Call.Method.RawName
+					args = argMap(got.Call.Args)
+				}
+				if got.Revert != nil && got.Revert.Error != nil {
+					revert = got.Revert.Error.Name
+				}
+
+				if method != tt.wantMethod {
+					t.Errorf("%T.Decode().Call.Method got %q; want %q", tt.frame, method, tt.wantMethod)
+				}
+				if diff := cmp.Diff(tt.wantArgs, args, bigCmp); diff != "" {
+					t.Errorf("%T.Decode().Call.Args diff (-want +got):\n%s", tt.frame, diff)
+				}
+				if diff := cmp.Diff(tt.wantOutputs, argMap(got.Outputs), bigCmp); diff != "" {
+					t.Errorf("%T.Decode().Outputs diff (-want +got):\n%s", tt.frame, diff)
+				}
+				if revert != tt.wantRevert {
+					t.Errorf("%T.Decode().Revert got error %q; want %q", tt.frame, revert, tt.wantRevert)
+				}
+			})
+		}
+	})
+}
+
+func hexBytes(b []byte) string {
+	return fmt.Sprintf("%#x", b)
+}
@@ -0,0 +1,141 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrInvalidSignature is returned, wrapped, by signature-verification functions
+// if a signature is malformed or wasn't produced by the expected signer.
+// Errors that don't wrap it (e.g. network errors) mean that the validity of the
+// signature couldn't be determined.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// RecoverPersonalSign returns the address that produced sig, an EIP-191
+// personal signature of the message as returned by Signer.PersonalSign() or
+// the personal_sign / eth_sign JSON-RPC methods of wallets. The final byte of
+// sig MAY be either {0,1} or {27,28}. Signatures with a high S value are
+// rejected as they are malleable copies of another, valid signature.
+func RecoverPersonalSign(message, sig []byte) (common.Address, error) {
+	if n := len(sig); n != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("%w: length %d; want %d", ErrInvalidSignature, n, crypto.SignatureLength)
+	}
+
+	// Don't modify the caller's slice when normalising V.
+	sig = bytes.Clone(sig)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(sig[64], r, s, true) {
+		return common.Address{}, fmt.Errorf("%w: invalid V, R, or S value", ErrInvalidSignature)
+	}
+
+	pub, err := crypto.SigToPub(crypto.Keccak256(WithPersonalMessagePrefix(message)), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// VerifyPersonalSign returns nil if and only if sig is an EIP-191 personal
+// signature of the message, produced by addr; see RecoverPersonalSign(). All
+// returned errors wrap ErrInvalidSignature.
+//
+// Smart-contract wallets can't produce such signatures; see VerifyERC1271().
+func VerifyPersonalSign(message, sig []byte, addr common.Address) error {
+	got, err := RecoverPersonalSign(message, sig)
+	if err != nil {
+		return err
+	}
+	if got != addr {
+		return fmt.Errorf("%w: signed by %v; want %v", ErrInvalidSignature, got, addr)
+	}
+	return nil
+}
+
+// ERC1271MagicValue is returned by isValidSignature(bytes32,bytes) of EIP-1271
+// contracts to signal a valid signature. It is equal to the function's
+// selector.
+var ERC1271MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+// erc1271IsValidSignature is the isValidSignature(bytes32,bytes) function of
+// EIP-1271.
+var erc1271IsValidSignature = mustNewERC1271Method()
+
+func mustNewERC1271Method() abi.Method {
+	var args abi.Arguments
+	for _, typ := range []string{"bytes32", "bytes"} {
+		t, err := abi.NewType(typ, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		args = append(args, abi.Argument{Type: t})
+	}
+	m := abi.NewMethod("isValidSignature", "isValidSignature", abi.Function, "view", false, false, args, nil)
+	if !bytes.Equal(m.ID, ERC1271MagicValue[:]) {
+		panic(fmt.Sprintf("isValidSignature(bytes32,bytes) selector %#x; want %#x", m.ID, ERC1271MagicValue))
+	}
+	return m
+}
+
+// VerifyERC1271 returns nil if and only if the contract, typically a
+// smart-contract wallet such as a Safe, considers sig to be a valid signature
+// of the hash, as reported by its EIP-1271 isValidSignature(bytes32,bytes)
+// function. The hash is usually that of an EIP-191 personal message or of
+// EIP-712 typed data, depending on the contract.
+//
+// Returned errors wrap ErrInvalidSignature if the contract returns any value
+// other than ERC1271MagicValue, reverts, or has no code; other errors, e.g.
+// from the network, are returned unwrapped.
+func VerifyERC1271(ctx context.Context, backend bind.ContractCaller, contract common.Address, hash [32]byte, sig []byte) error {
+	data, err := erc1271IsValidSignature.Inputs.Pack(hash, sig)
+	if err != nil {
+		return fmt.Errorf("packing isValidSignature() arguments: %v", err)
+	}
+	data = append(bytes.Clone(erc1271IsValidSignature.ID), data...)
+
+	ret, err := backend.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if rev, ok := RevertFromError(err); ok {
+		return fmt.Errorf("%w: %v.isValidSignature() reverted: %v", ErrInvalidSignature, contract, rev)
+	}
+	if err != nil && strings.Contains(err.Error(), "execution reverted") {
+		// Reverts without data aren't propagated as data errors.
+		return fmt.Errorf("%w: %v.isValidSignature(): %v", ErrInvalidSignature, contract, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%T.CallContract(%v.isValidSignature()): %v", backend, contract, err)
+	}
+
+	// A call to an address without code succeeds with empty return data.
+	if len(ret) == 0 {
+		return fmt.Errorf("%w: %v.isValidSignature() returned no data; not a contract?", ErrInvalidSignature, contract)
+	}
+	// The bytes4 is left-aligned in a 32-byte word.
+	if len(ret) != 32 || !bytes.Equal(ret[:4], ERC1271MagicValue[:]) || !isZero(ret[4:]) {
+		return fmt.Errorf("%w: %v.isValidSignature() returned %#x; want %#x", ErrInvalidSignature, contract, ret, ERC1271MagicValue)
+	}
+	return nil
+}
+
+// isZero reports whether all bytes in buf are zero.
+func isZero(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
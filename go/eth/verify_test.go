@@ -0,0 +1,254 @@
+package eth_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+func TestVerifyPersonalSign(t *testing.T) {
+	signer, err := NewSigner(256)
+	if err != nil {
+		t.Fatalf("NewSigner(256) error %v", err)
+	}
+	other, err := NewSigner(256)
+	if err != nil {
+		t.Fatalf("NewSigner(256) error %v", err)
+	}
+
+	msg := []byte("hello world")
+	sig, err := signer.PersonalSign(msg)
+	if err != nil {
+		t.Fatalf("%T.PersonalSign(%q) error %v", signer, msg, err)
+	}
+
+	zeroV := bytes.Clone(sig)
+	zeroV[64] -= 27
+
+	// secp256k1n - s is the malleable twin of a valid signature.
+	n := crypto.S256().Params().N
+	highS := bytes.Clone(sig)
+	s := new(big.Int).SetBytes(sig[32:64])
+	new(big.Int).Sub(n, s).FillBytes(highS[32:64])
+	highS[64] ^= 1
+
+	tests := []struct {
+		name    string
+		msg     []byte
+		sig     []byte
+		addr    common.Address
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			msg:  msg,
+			sig:  sig,
+			addr: signer.Address(),
+		},
+		{
+			name: "V in {0,1}",
+			msg:  msg,
+			sig:  zeroV,
+			addr: signer.Address(),
+		},
+		{
+			name:    "different signer",
+			msg:     msg,
+			sig:     sig,
+			addr:    other.Address(),
+			wantErr: true,
+		},
+		{
+			name:    "different message",
+			msg:     []byte("hello world!"),
+			sig:     sig,
+			addr:    signer.Address(),
+			wantErr: true,
+		},
+		{
+			name:    "short signature",
+			msg:     msg,
+			sig:     sig[:64],
+			addr:    signer.Address(),
+			wantErr: true,
+		},
+		{
+			name:    "high S",
+			msg:     msg,
+			sig:     highS,
+			addr:    signer.Address(),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := bytes.Clone(tt.sig)
+			err := VerifyPersonalSign(tt.msg, tt.sig, tt.addr)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("VerifyPersonalSign(%q, %#x, %v) got err %v; want err? %t", tt.msg, tt.sig, tt.addr, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("VerifyPersonalSign(…) got err %v; want wrapping %v", err, ErrInvalidSignature)
+			}
+			if !bytes.Equal(before, tt.sig) {
+				t.Errorf("VerifyPersonalSign(…) modified signature from %#x to %#x", before, tt.sig)
+			}
+		})
+	}
+}
+
+// erc1271Wallet is a bind.ContractCaller that mimics a single EIP-1271
+// contract, returning the result of isValid() from isValidSignature().
+type erc1271Wallet struct {
+	t       *testing.T
+	addr    common.Address
+	isValid func(hash [32]byte, sig []byte) ([]byte, error)
+}
+
+func (w *erc1271Wallet) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return nil, errors.New("unimplemented")
+}
+
+func (w *erc1271Wallet) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	if *call.To != w.addr {
+		return nil, nil
+	}
+	if !bytes.Equal(call.Data[:4], ERC1271MagicValue[:]) {
+		w.t.Fatalf("CallContract() with selector %#x; want %#x", call.Data[:4], ERC1271MagicValue)
+	}
+
+	bytes32, err := abi.NewType("bytes32", "", nil)
+	if err != nil {
+		w.t.Fatalf(`abi.NewType("bytes32") error %v`, err)
+	}
+	bytesT, err := abi.NewType("bytes", "", nil)
+	if err != nil {
+		w.t.Fatalf(`abi.NewType("bytes") error %v`, err)
+	}
+	args, err := abi.Arguments{{Type: bytes32}, {Type: bytesT}}.Unpack(call.Data[4:])
+	if err != nil {
+		w.t.Fatalf("Unpacking isValidSignature() arguments: %v", err)
+	}
+	return w.isValid(args[0].([32]byte), args[1].([]byte))
+}
+
+func TestVerifyERC1271(t *testing.T) {
+	ctx := context.Background()
+
+	wallet := common.HexToAddress("0x5afe")
+	hash := crypto.Keccak256Hash([]byte("sign in"))
+	sig := []byte("approved")
+
+	magic := make([]byte, 32)
+	copy(magic, ERC1271MagicValue[:])
+
+	dirty := bytes.Clone(magic)
+	dirty[31] = 1
+	errNetwork := errors.New("network down")
+
+	tests := []struct {
+		name string
+		// ret and err are returned by isValidSignature() iff the hash and
+		// signature are as expected, otherwise it returns zero.
+		ret, sig []byte
+		err      error
+		contract common.Address
+		// wantErr is nil if no error is expected.
+		wantErr error
+	}{
+		{
+			name:     "valid",
+			ret:      magic,
+			sig:      sig,
+			contract: wallet,
+		},
+		{
+			name:     "different signature",
+			ret:      magic,
+			sig:      []byte("forged"),
+			contract: wallet,
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "short return data",
+			ret:      ERC1271MagicValue[:],
+			sig:      sig,
+			contract: wallet,
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "dirty return data",
+			ret:      dirty,
+			sig:      sig,
+			contract: wallet,
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "revert with data",
+			err:      dataError{data: "0x08c379a0"},
+			sig:      sig,
+			contract: wallet,
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "revert without data",
+			err:      errors.New("execution reverted"),
+			sig:      sig,
+			contract: wallet,
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "not a contract",
+			ret:      magic,
+			sig:      sig,
+			contract: common.HexToAddress("0xe0a"),
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "call error",
+			err:      errNetwork,
+			sig:      sig,
+			contract: wallet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &erc1271Wallet{
+				t:    t,
+				addr: wallet,
+				isValid: func(gotHash [32]byte, gotSig []byte) ([]byte, error) {
+					if gotHash != hash || !bytes.Equal(gotSig, sig) {
+						return make([]byte, 32), nil
+					}
+					return tt.ret, tt.err
+				},
+			}
+
+			err := VerifyERC1271(ctx, backend, tt.contract, hash, tt.sig)
+			if tt.err == errNetwork {
+				// The validity of the signature is unknown.
+				if err == nil || errors.Is(err, ErrInvalidSignature) {
+					t.Errorf("VerifyERC1271() with network error got err %v; want non-nil error not wrapping %v", err, ErrInvalidSignature)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyERC1271() got err %v; want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package eth
+
+import (
+	"fmt"
+	"strings"
+
+	hdwallet "github.com/divergencetech/go-ethereum-hdwallet"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// An HDPath is a BIP32 derivation path in which the account index is
+// substituted for the sole %d verb.
+type HDPath string
+
+// Standard HDPaths, as used by common wallet software and hardware.
+const (
+	// MetaMaskHDPath is the BIP44 path used by MetaMask, Trezor, and most
+	// other software wallets. It is equivalent to DefaultHDPathPrefix.
+	MetaMaskHDPath = HDPath(DefaultHDPathPrefix + "%d")
+	// LedgerLiveHDPath is the path used by Ledger Live, which increments the
+	// BIP44 account instead of the address index.
+	LedgerLiveHDPath = HDPath("m/44'/60'/%d'/0/0")
+	// LedgerLegacyHDPath is the (non-BIP44) path used by the original Ledger
+	// Chrome app and MyEtherWallet.
+	LedgerLegacyHDPath = HDPath("m/44'/60'/0'/%d")
+)
+
+// Derivation returns the parsed derivation path of the account.
+func (p HDPath) Derivation(account uint) (accounts.DerivationPath, error) {
+	if n := strings.Count(string(p), "%"); n != 1 || !strings.Contains(string(p), "%d") {
+		return nil, fmt.Errorf("HDPath %q must contain exactly one %%d verb and no other", p)
+	}
+	path, err := hdwallet.ParseDerivationPath(fmt.Sprintf(string(p), account))
+	if err != nil {
+		return nil, fmt.Errorf("parse derivation path: %v", err)
+	}
+	return path, nil
+}
+
+// A Wallet deterministically derives Signers from a BIP39 mnemonic, following
+// BIP32 such that accounts are the same as those derived by the standard
+// wallet for the respective HDPath; e.g. MetaMask or Ledger. This is unlike
+// HDPathPrefix.SignerFromSeedPhrase(), which retains a non-standard derivation
+// (affecting a small fraction of keys) for backwards compatibility.
+//
+// Wallets are intended for test fixtures and low-value automation that use
+// standard mnemonics; for automated key management see
+// HDPathPrefix.SignerFromPRF().
+type Wallet struct {
+	w        *hdwallet.Wallet
+	path     HDPath
+	mnemonic string
+}
+
+// WalletFromMnemonic is equivalent to WalletFromMnemonicWithPassword() with an
+// empty password, which is the default for most wallets.
+func WalletFromMnemonic(mnemonic string, path HDPath) (*Wallet, error) {
+	return WalletFromMnemonicWithPassword(mnemonic, "", path)
+}
+
+// WalletFromMnemonicWithPassword confirms that the mnemonic is valid under
+// BIP39 and returns a Wallet that derives accounts from it with the specified
+// HDPath.
+func WalletFromMnemonicWithPassword(mnemonic, password string, path HDPath) (*Wallet, error) {
+	if _, err := path.Derivation(0); err != nil {
+		return nil, err
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, password)
+	if err != nil {
+		return nil, fmt.Errorf("create seed from mnemonic: %v", err)
+	}
+	w, err := hdwallet.NewFromSeed(seed)
+	if err != nil {
+		return nil, fmt.Errorf("create wallet from seed: %v", err)
+	}
+	w.SetFixIssue172(true) // standard BIP32 derivation
+	return &Wallet{
+		w:        w,
+		path:     path,
+		mnemonic: mnemonic,
+	}, nil
+}
+
+// Path returns the HDPath with which the Wallet derives accounts.
+func (w *Wallet) Path() HDPath {
+	return w.path
+}
+
+// Mnemonic returns the mnemonic from which the Wallet derives accounts. USE
+// WITH CAUTION.
+func (w *Wallet) Mnemonic() string {
+	return w.mnemonic
+}
+
+func (w *Wallet) derive(account uint) (accounts.Account, error) {
+	path, err := w.path.Derivation(account)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	acc, err := w.w.Derive(path, false)
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("derive account %d: %v", account, err)
+	}
+	return acc, nil
+}
+
+// Signer returns a Signer for the account.
+func (w *Wallet) Signer(account uint) (*Signer, error) {
+	acc, err := w.derive(account)
+	if err != nil {
+		return nil, err
+	}
+	key, err := w.w.PrivateKey(acc)
+	if err != nil {
+		return nil, fmt.Errorf("obtain private key of account %d: %v", account, err)
+	}
+	return &Signer{key, w.mnemonic}, nil
+}
+
+// Address returns the address of the account without exposing its private key.
+func (w *Wallet) Address(account uint) (common.Address, error) {
+	acc, err := w.derive(account)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return acc.Address, nil
+}
+
+// Addresses returns the addresses of the first n accounts, in order; i.e. as
+// listed by wallet software.
+func (w *Wallet) Addresses(n uint) ([]common.Address, error) {
+	addrs := make([]common.Address, n)
+	for i := range addrs {
+		a, err := w.Address(uint(i))
+		if err != nil {
+			return nil, err
+		}
+		addrs[i] = a
+	}
+	return addrs, nil
+}
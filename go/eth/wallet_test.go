@@ -0,0 +1,117 @@
+package eth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	// See eth_test.go for rationale behind a dot import. This MUST NOT be
+	// considered precedent outside of tests and SHOULD be avoided where
+	// possible.
+	. "github.com/cxkoda/solgo/go/eth"
+)
+
+// testMnemonic is the default used by Hardhat and Anvil, the accounts of which
+// are widely published.
+const testMnemonic = "test test test test test test test test test test test junk"
+
+func TestWalletFromMnemonic(t *testing.T) {
+	w, err := WalletFromMnemonic(testMnemonic, MetaMaskHDPath)
+	if err != nil {
+		t.Fatalf("WalletFromMnemonic(%q, %q) error %v", testMnemonic, MetaMaskHDPath, err)
+	}
+
+	want := []common.Address{
+		common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
+		common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+		common.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC"),
+	}
+	got, err := w.Addresses(uint(len(want)))
+	if err != nil {
+		t.Fatalf("%T.Addresses(%d) error %v", w, len(want), err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%T.Addresses(%d) diff (-want +got):\n%s", w, len(want), diff)
+	}
+
+	for i, addr := range want {
+		s, err := w.Signer(uint(i))
+		if err != nil {
+			t.Fatalf("%T.Signer(%d) error %v", w, i, err)
+		}
+		if got := s.Address(); got != addr {
+			t.Errorf("%T.Signer(%d).Address() got %v; want %v", w, i, got, addr)
+		}
+		if got := s.Mnemonic(); got != testMnemonic {
+			t.Errorf("%T.Signer(%d).Mnemonic() got %q; want %q", w, i, got, testMnemonic)
+		}
+	}
+}
+
+func TestWalletHDPaths(t *testing.T) {
+	// The first account of MetaMask and Ledger Live share a derivation path,
+	// but subsequent ones differ, as do all of Ledger Legacy.
+	addrs := make(map[HDPath][]common.Address)
+	for _, p := range []HDPath{MetaMaskHDPath, LedgerLiveHDPath, LedgerLegacyHDPath} {
+		w, err := WalletFromMnemonic(testMnemonic, p)
+		if err != nil {
+			t.Fatalf("WalletFromMnemonic(%q, %q) error %v", testMnemonic, p, err)
+		}
+		addrs[p], err = w.Addresses(2)
+		if err != nil {
+			t.Fatalf("WalletFromMnemonic(…, %q).Addresses(2) error %v", p, err)
+		}
+	}
+
+	mm, live, legacy := addrs[MetaMaskHDPath], addrs[LedgerLiveHDPath], addrs[LedgerLegacyHDPath]
+	if mm[0] != live[0] {
+		t.Errorf("account 0: %q got %v and %q got %v; want equal", MetaMaskHDPath, mm[0], LedgerLiveHDPath, live[0])
+	}
+	if mm[1] == live[1] {
+		t.Errorf("account 1: %q and %q both got %v; want different", MetaMaskHDPath, LedgerLiveHDPath, mm[1])
+	}
+	for i := range legacy {
+		if legacy[i] == mm[i] || legacy[i] == live[i] {
+			t.Errorf("account %d: %q got %v, shared with another path; want different", i, LedgerLegacyHDPath, legacy[i])
+		}
+	}
+}
+
+func TestWalletErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mnemonic string
+		path     HDPath
+	}{
+		{
+			name:     "invalid checksum",
+			mnemonic: strings.Replace(testMnemonic, "junk", "test", 1),
+			path:     MetaMaskHDPath,
+		},
+		{
+			name:     "no verb",
+			mnemonic: testMnemonic,
+			path:     HDPath(DefaultHDPathPrefix + "0"),
+		},
+		{
+			name:     "extra verb",
+			mnemonic: testMnemonic,
+			path:     HDPath("m/44'/60'/%d'/0/%d"),
+		},
+		{
+			name:     "invalid path",
+			mnemonic: testMnemonic,
+			path:     HDPath("x/%d"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := WalletFromMnemonic(tt.mnemonic, tt.path); err == nil {
+				t.Errorf("WalletFromMnemonic(%q, %q) got nil error; want non-nil", tt.mnemonic, tt.path)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package ethkms
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// An Admin provisions and manages GCP KMS keys for use with NewGCP(), without
+// the need to shell out to gcloud.
+//
+// Errors returned by the KMS API are propagated with their gRPC status, so
+// idempotent scripts can test for codes.AlreadyExists.
+type Admin struct {
+	client *kms.KeyManagementClient
+}
+
+// NewAdmin returns a new Admin. Any ClientOptions are propagated to the
+// constructor for the backing KeyManagementClient.
+func NewAdmin(ctx context.Context, opts ...option.ClientOption) (*Admin, error) {
+	client, err := kms.NewKeyManagementClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("kms.NewKeyManagementClient(…): %v", err)
+	}
+	return &Admin{client}, nil
+}
+
+// Close closes the GCP connection.
+func (a *Admin) Close() error {
+	return a.client.Close()
+}
+
+// CreateKeyRing creates a key ring in the GCP project and location (e.g.
+// "global" or "us-east1"), returning its resource name.
+func (a *Admin) CreateKeyRing(ctx context.Context, project, location, keyRingID string) (string, error) {
+	req := &kmspb.CreateKeyRingRequest{
+		Parent:    fmt.Sprintf("projects/%s/locations/%s", project, location),
+		KeyRingId: keyRingID,
+		KeyRing:   &kmspb.KeyRing{},
+	}
+	ring, err := a.client.CreateKeyRing(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("%T.CreateKeyRing(ctx, %+v): %w", a.client, req, err)
+	}
+	return ring.Name, nil
+}
+
+// CreateKey creates an HSM-protected, secp256k1 signing key in the key ring,
+// which is identified by its resource name. GCP generates the first version of
+// the key asynchronously; see NewestEnabledVersion().
+func (a *Admin) CreateKey(ctx context.Context, keyRing, keyID string) (*kmspb.CryptoKey, error) {
+	req := &kmspb.CreateCryptoKeyRequest{
+		Parent:      keyRing,
+		CryptoKeyId: keyID,
+		CryptoKey: &kmspb.CryptoKey{
+			Purpose: kmspb.CryptoKey_ASYMMETRIC_SIGN,
+			VersionTemplate: &kmspb.CryptoKeyVersionTemplate{
+				Algorithm: kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256,
+				// secp256k1 is only supported by GCP HSMs.
+				ProtectionLevel: kmspb.ProtectionLevel_HSM,
+			},
+		},
+	}
+	key, err := a.client.CreateCryptoKey(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%T.CreateCryptoKey(ctx, %+v): %w", a.client, req, err)
+	}
+	return key, nil
+}
+
+// KeyVersions returns all versions of the CryptoKey, regardless of state.
+func (a *Admin) KeyVersions(ctx context.Context, cryptoKey string) ([]*kmspb.CryptoKeyVersion, error) {
+	req := &kmspb.ListCryptoKeyVersionsRequest{Parent: cryptoKey}
+	it := a.client.ListCryptoKeyVersions(ctx, req)
+
+	var versions []*kmspb.CryptoKeyVersion
+	for {
+		v, err := it.Next()
+		if err == iterator.Done {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%T.ListCryptoKeyVersions(ctx, %+v): %w", a.client, req, err)
+		}
+		versions = append(versions, v)
+	}
+}
+
+// Rotate creates a new version of the CryptoKey. As asymmetric keys don't have
+// a primary version, existing signers continue to use the version with which
+// they were constructed, and previous versions remain enabled.
+//
+// GCP generates the version asynchronously so it is typically returned in the
+// PENDING_GENERATION state.
+func (a *Admin) Rotate(ctx context.Context, cryptoKey string) (*kmspb.CryptoKeyVersion, error) {
+	req := &kmspb.CreateCryptoKeyVersionRequest{
+		Parent:           cryptoKey,
+		CryptoKeyVersion: &kmspb.CryptoKeyVersion{},
+	}
+	v, err := a.client.CreateCryptoKeyVersion(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%T.CreateCryptoKeyVersion(ctx, %+v): %w", a.client, req, err)
+	}
+	return v, nil
+}
+
+// NewestEnabledVersion returns the enabled version of the CryptoKey with the
+// highest version number. It returns an error if no versions are enabled.
+func (a *Admin) NewestEnabledVersion(ctx context.Context, cryptoKey string) (*kmspb.CryptoKeyVersion, error) {
+	versions, err := a.KeyVersions(ctx, cryptoKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		newest    *kmspb.CryptoKeyVersion
+		newestNum uint64
+	)
+	for _, v := range versions {
+		if v.State != kmspb.CryptoKeyVersion_ENABLED {
+			continue
+		}
+		n, err := versionNumber(v.Name)
+		if err != nil {
+			return nil, err
+		}
+		if newest == nil || n > newestNum {
+			newest, newestNum = v, n
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("CryptoKey %q has no enabled versions", cryptoKey)
+	}
+	return newest, nil
+}
+
+// versionNumber parses the trailing version number of a CryptoKeyVersion
+// resource name.
+func versionNumber(name string) (uint64, error) {
+	const sep = "/cryptoKeyVersions/"
+	i := strings.LastIndex(name, sep)
+	if i == -1 {
+		return 0, fmt.Errorf("CryptoKeyVersion name %q without %q", name, sep)
+	}
+	n, err := strconv.ParseUint(name[i+len(sep):], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("CryptoKeyVersion name %q: parse version number: %v", name, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,192 @@
+package ethkms
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cxkoda/solgo/go/grpctest"
+)
+
+// A fakeAdminGCP extends fakeGCP with in-memory key rings, keys, and versions.
+// New versions are immediately enabled.
+type fakeAdminGCP struct {
+	fakeGCP
+
+	mu       sync.Mutex
+	rings    map[string]bool
+	versions map[string][]*kmspb.CryptoKeyVersion // keyed by CryptoKey name
+	pubKeys  []string                             // names in GetPublicKey() requests
+}
+
+func (f *fakeAdminGCP) GetPublicKey(ctx context.Context, req *kmspb.GetPublicKeyRequest) (*kmspb.PublicKey, error) {
+	f.mu.Lock()
+	f.pubKeys = append(f.pubKeys, req.Name)
+	f.mu.Unlock()
+	return f.fakeGCP.GetPublicKey(ctx, req)
+}
+
+func (f *fakeAdminGCP) CreateKeyRing(ctx context.Context, req *kmspb.CreateKeyRingRequest) (*kmspb.KeyRing, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := fmt.Sprintf("%s/keyRings/%s", req.Parent, req.KeyRingId)
+	if f.rings[name] {
+		return nil, status.Errorf(codes.AlreadyExists, "KeyRing %q", name)
+	}
+	f.rings[name] = true
+	return &kmspb.KeyRing{Name: name}, nil
+}
+
+func (f *fakeAdminGCP) CreateCryptoKey(ctx context.Context, req *kmspb.CreateCryptoKeyRequest) (*kmspb.CryptoKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.rings[req.Parent] {
+		return nil, status.Errorf(codes.NotFound, "KeyRing %q", req.Parent)
+	}
+	if got, want := req.GetCryptoKey().GetVersionTemplate().GetAlgorithm(), kmspb.CryptoKeyVersion_EC_SIGN_SECP256K1_SHA256; got != want {
+		return nil, status.Errorf(codes.InvalidArgument, "algorithm %v; want %v", got, want)
+	}
+	name := fmt.Sprintf("%s/cryptoKeys/%s", req.Parent, req.CryptoKeyId)
+	if _, ok := f.versions[name]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, "CryptoKey %q", name)
+	}
+	f.versions[name] = nil
+	f.addVersion(name)
+	return &kmspb.CryptoKey{Name: name}, nil
+}
+
+func (f *fakeAdminGCP) CreateCryptoKeyVersion(ctx context.Context, req *kmspb.CreateCryptoKeyVersionRequest) (*kmspb.CryptoKeyVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.versions[req.Parent]; !ok {
+		return nil, status.Errorf(codes.NotFound, "CryptoKey %q", req.Parent)
+	}
+	return f.addVersion(req.Parent), nil
+}
+
+// addVersion MUST be called with f.mu held.
+func (f *fakeAdminGCP) addVersion(key string) *kmspb.CryptoKeyVersion {
+	v := &kmspb.CryptoKeyVersion{
+		Name:  fmt.Sprintf("%s/cryptoKeyVersions/%d", key, len(f.versions[key])+1),
+		State: kmspb.CryptoKeyVersion_ENABLED,
+	}
+	f.versions[key] = append(f.versions[key], v)
+	return v
+}
+
+func (f *fakeAdminGCP) ListCryptoKeyVersions(ctx context.Context, req *kmspb.ListCryptoKeyVersionsRequest) (*kmspb.ListCryptoKeyVersionsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	vs, ok := f.versions[req.Parent]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "CryptoKey %q", req.Parent)
+	}
+	return &kmspb.ListCryptoKeyVersionsResponse{
+		CryptoKeyVersions: vs,
+		TotalSize:         int32(len(vs)),
+	}, nil
+}
+
+// setState sets the state of the (1-indexed) version of the key.
+func (f *fakeAdminGCP) setState(key string, version int, state kmspb.CryptoKeyVersion_CryptoKeyVersionState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versions[key][version-1].State = state
+}
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	fake := &fakeAdminGCP{
+		rings:    make(map[string]bool),
+		versions: make(map[string][]*kmspb.CryptoKeyVersion),
+	}
+	conn := grpctest.NewClientConnTB[kmspb.KeyManagementServiceServer](
+		t,
+		kmspb.RegisterKeyManagementServiceServer,
+		fake,
+	)
+	admin, err := NewAdmin(ctx, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("NewAdmin(…, option.WithGRPCConn(%T)) error %v", fake, err)
+	}
+
+	ring, err := admin.CreateKeyRing(ctx, "proj", "global", "signers")
+	if err != nil {
+		t.Fatalf("%T.CreateKeyRing() error %v", admin, err)
+	}
+	if want := "projects/proj/locations/global/keyRings/signers"; ring != want {
+		t.Errorf("%T.CreateKeyRing() got %q; want %q", admin, ring, want)
+	}
+	if _, err := admin.CreateKeyRing(ctx, "proj", "global", "signers"); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("%T.CreateKeyRing() twice got err %v; want code %v", admin, err, codes.AlreadyExists)
+	}
+
+	key, err := admin.CreateKey(ctx, ring, "hot")
+	if err != nil {
+		t.Fatalf("%T.CreateKey(ctx, %q, %q) error %v", admin, ring, "hot", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := admin.Rotate(ctx, key.Name); err != nil {
+			t.Fatalf("%T.Rotate(ctx, %q) error %v", admin, key.Name, err)
+		}
+	}
+	fake.setState(key.Name, 3, kmspb.CryptoKeyVersion_DISABLED)
+
+	versions, err := admin.KeyVersions(ctx, key.Name)
+	if err != nil {
+		t.Fatalf("%T.KeyVersions(ctx, %q) error %v", admin, key.Name, err)
+	}
+	var gotStates []kmspb.CryptoKeyVersion_CryptoKeyVersionState
+	for _, v := range versions {
+		gotStates = append(gotStates, v.State)
+	}
+	wantStates := []kmspb.CryptoKeyVersion_CryptoKeyVersionState{
+		kmspb.CryptoKeyVersion_ENABLED,
+		kmspb.CryptoKeyVersion_ENABLED,
+		kmspb.CryptoKeyVersion_DISABLED,
+	}
+	if diff := cmp.Diff(wantStates, gotStates); diff != "" {
+		t.Errorf("%T.KeyVersions(ctx, %q) states diff (-want +got):\n%s", admin, key.Name, diff)
+	}
+
+	wantVersion := key.Name + "/cryptoKeyVersions/2"
+	newest, err := admin.NewestEnabledVersion(ctx, key.Name)
+	if err != nil {
+		t.Fatalf("%T.NewestEnabledVersion(ctx, %q) error %v", admin, key.Name, err)
+	}
+	if newest.Name != wantVersion {
+		t.Errorf("%T.NewestEnabledVersion(ctx, %q) got %q; want %q", admin, key.Name, newest.Name, wantVersion)
+	}
+
+	t.Run("NewGCPNewestVersion", func(t *testing.T) {
+		if _, err := NewGCPNewestVersion(ctx, key.Name, big.NewInt(1), option.WithGRPCConn(conn)); err != nil {
+			t.Fatalf("NewGCPNewestVersion(ctx, %q, …) error %v", key.Name, err)
+		}
+		if diff := cmp.Diff([]string{wantVersion}, fake.pubKeys); diff != "" {
+			t.Errorf("NewGCPNewestVersion(ctx, %q, …) public keys requested diff (-want +got):\n%s", key.Name, diff)
+		}
+	})
+
+	t.Run("no enabled versions", func(t *testing.T) {
+		for v := 1; v <= 3; v++ {
+			fake.setState(key.Name, v, kmspb.CryptoKeyVersion_DESTROYED)
+		}
+		if _, err := admin.NewestEnabledVersion(ctx, key.Name); err == nil {
+			t.Errorf("%T.NewestEnabledVersion(ctx, %q) with all versions destroyed got nil error; want non-nil", admin, key.Name)
+		}
+	})
+}
@@ -13,6 +13,7 @@ import (
 
 	kms "cloud.google.com/go/kms/apiv1"
 	"cloud.google.com/go/kms/apiv1/kmspb"
+	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -32,7 +33,39 @@ func NewGCP(ctx context.Context, key string, chainID *big.Int, opts ...option.Cl
 	if err != nil {
 		return nil, fmt.Errorf("kms.NewKeyManagementClient(…): %v", err)
 	}
+	g, err := newGCP(ctx, client, key, chainID)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+// NewGCPNewestVersion is equivalent to NewGCP() with the newest enabled version
+// of the CryptoKey, which MUST NOT itself be a CryptoKeyVersion. The version is
+// only resolved once so, after key rotation, a new signer must be constructed.
+func NewGCPNewestVersion(ctx context.Context, cryptoKey string, chainID *big.Int, opts ...option.ClientOption) (*GCP, error) {
+	client, err := kms.NewKeyManagementClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("kms.NewKeyManagementClient(…): %v", err)
+	}
+	g, err := func() (*GCP, error) {
+		v, err := (&Admin{client}).NewestEnabledVersion(ctx, cryptoKey)
+		if err != nil {
+			return nil, err
+		}
+		return newGCP(ctx, client, v.Name, chainID)
+	}()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return g, nil
+}
 
+// newGCP implements NewGCP() with an existing client, which is owned by the
+// returned GCP but MUST be closed by the caller if an error is returned.
+func newGCP(ctx context.Context, client *kms.KeyManagementClient, key string, chainID *big.Int) (*GCP, error) {
 	req := &kmspb.GetPublicKeyRequest{Name: key}
 	pub, err := client.GetPublicKey(ctx, req)
 	if err != nil {
@@ -69,9 +102,10 @@ func NewGCP(ctx context.Context, key string, chainID *big.Int, opts ...option.Cl
 		client: client,
 		keyID:  key,
 		pubKey: pubKey,
-		// The London signer falls back to older signers based on the tx type,
-		// so is safe to use as a catch-all.
-		signer: types.NewLondonSigner(chainID),
+		// The Cancun signer falls back to older signers based on the tx type,
+		// so is safe to use as a catch-all, including for EIP-4844 blob
+		// transactions. A blob tx's sidecar is retained by WithSignature().
+		signer: types.NewCancunSigner(chainID),
 	}, nil
 }
 
@@ -94,8 +128,10 @@ func (g *GCP) Address() common.Address {
 	return crypto.PubkeyToAddress(g.pubKey)
 }
 
-// SignTx returns tx, signed by the GCP KMS.
-func (g *GCP) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+// signDigest returns the 65-byte [R || S || V] signature of the digest, with
+// V set to 0 as it can't be determined from the KMS response; see
+// recoverableSig().
+func (g *GCP) signDigest(ctx context.Context, digest []byte) ([]byte, error) {
 	req := &kmspb.AsymmetricSignRequest{
 		Name: g.keyID,
 		// GCP accepts pre-hashed message digests for signing, expecting them to
@@ -104,7 +140,7 @@ func (g *GCP) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transac
 		// error.
 		Digest: &kmspb.Digest{
 			Digest: &kmspb.Digest_Sha256{
-				Sha256: g.signer.Hash(tx).Bytes(),
+				Sha256: digest,
 			},
 		},
 	}
@@ -125,9 +161,20 @@ func (g *GCP) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transac
 		parsed.S.Sub(order, parsed.S)
 	}
 
+	// R and S are left-padded as they are shorter than 32 bytes in ~1/128
+	// signatures.
 	sig := make([]byte, 65)
-	copy(sig[:32], parsed.R.Bytes())
-	copy(sig[32:64], parsed.S.Bytes())
+	parsed.R.FillBytes(sig[:32])
+	parsed.S.FillBytes(sig[32:64])
+	return sig, nil
+}
+
+// SignTx returns tx, signed by the GCP KMS.
+func (g *GCP) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	sig, err := g.signDigest(ctx, g.signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
 
 	addr := g.Address()
 	// The parity depends on a random value that we don't have access to, so
@@ -149,3 +196,51 @@ func (g *GCP) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transac
 	}
 	return nil, fmt.Errorf("signature doesn't match expected sender address %v", addr)
 }
+
+// PersonalSign returns an EIP-191 personal signature of buf, with V in {27,28},
+// equivalent to eth.Signer.PersonalSign().
+func (g *GCP) PersonalSign(ctx context.Context, buf []byte) ([]byte, error) {
+	digest := accounts.TextHash(buf)
+
+	sig, err := g.signDigest(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := g.Address()
+	// As with SignTx(), the parity can only be found by trial and error.
+	for _, v := range []byte{0, 1} {
+		sig[64] = v
+		pub, err := crypto.SigToPub(digest, sig)
+		if err != nil {
+			continue
+		}
+		if crypto.PubkeyToAddress(*pub) == addr {
+			sig[64] += 27
+			return sig, nil
+		}
+	}
+	return nil, fmt.Errorf("signature doesn't match expected signer address %v", addr)
+}
+
+// WithContext returns a view of g that uses ctx for all KMS requests, allowing
+// it to be used where a context isn't accepted; e.g. as an eth.PersonalSigner.
+func (g *GCP) WithContext(ctx context.Context) *GCPWithContext {
+	return &GCPWithContext{g: g, ctx: ctx}
+}
+
+// A GCPWithContext is returned by GCP.WithContext().
+type GCPWithContext struct {
+	g   *GCP
+	ctx context.Context
+}
+
+// Address returns the address of the underlying GCP signer.
+func (c *GCPWithContext) Address() common.Address {
+	return c.g.Address()
+}
+
+// PersonalSign is equivalent to GCP.PersonalSign() with the bound context.
+func (c *GCPWithContext) PersonalSign(buf []byte) ([]byte, error) {
+	return c.g.PersonalSign(c.ctx, buf)
+}
@@ -84,15 +84,13 @@ type fakeGCP struct {
 	kmspb.KeyManagementServiceServer
 }
 
-// keyFromSeed writes the seed to a Keccak256 state, which it then uses as a
-// source of entropy for generating a secp256k1 key.
+// keyFromSeed returns a secp256k1 key derived from the Keccak256 hash of the
+// seed. ecdsa.GenerateKey() can't be used with a deterministic source of
+// entropy as it randomly reads an extra byte (see randutil.MaybeReadByte).
 func keyFromSeed(seed string) (*ecdsa.PrivateKey, error) {
-	s := crypto.NewKeccakState()
-	s.Write([]byte(seed))
-
-	priv, err := ecdsa.GenerateKey(crypto.S256(), s)
+	priv, err := crypto.ToECDSA(crypto.Keccak256([]byte(seed)))
 	if err != nil {
-		return nil, fmt.Errorf("ecdsa.GenerateKey(%T, %T): %v", crypto.S256(), s, err)
+		return nil, fmt.Errorf("crypto.ToECDSA(keccak256(%q)): %v", seed, err)
 	}
 	return priv, nil
 }
@@ -264,3 +262,38 @@ func TestGCP(t *testing.T) {
 		wantBal(t, addr1, sendVal)
 	})
 }
+
+func TestGCPPersonalSign(t *testing.T) {
+	ctx := context.Background()
+
+	conn := grpctest.NewClientConnTB[kmspb.KeyManagementServiceServer](
+		t,
+		kmspb.RegisterKeyManagementServiceServer,
+		&fakeGCP{},
+	)
+
+	const key = "my/hsm/key"
+	gcp, err := NewGCP(ctx, key, big.NewInt(1), option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatalf("NewGCP(…, option.WithGRPCConn(%T)) error %v", &fakeGCP{}, err)
+	}
+	defer gcp.Close()
+
+	var signer eth.PersonalSigner = gcp.WithContext(ctx)
+	if got, want := signer.Address(), addressFromSeed(t, key); got != want {
+		t.Errorf("%T.Address() got %v; want %v", signer, got, want)
+	}
+
+	// Enough messages that R or S is shorter than 32 bytes in at least one,
+	// with high probability.
+	for i := 0; i < 512; i++ {
+		msg := []byte(fmt.Sprintf("message %d", i))
+		sig, err := signer.PersonalSign(msg)
+		if err != nil {
+			t.Fatalf("%T.PersonalSign(%q) error %v", signer, msg, err)
+		}
+		if err := eth.VerifyPersonalSign(msg, sig, signer.Address()); err != nil {
+			t.Errorf("eth.VerifyPersonalSign(%q, %T.PersonalSign(…), %v) error %v", msg, signer, signer.Address(), err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ethtest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// NamedAccountBalance is the ETH balance with which each NamedAccount() is
+// funded when first used.
+var NamedAccountBalance = eth.Ether(100)
+
+// Fund sends wei to the address from a faucet account with an effectively
+// unlimited balance. As with all transactions, it is only committed if
+// sb.AutoCommit is true.
+func (sb *SimulatedBackend) Fund(ctx context.Context, to common.Address, wei *big.Int) error {
+	// BoundContract.Transfer() refuses to estimate gas for addresses without
+	// code, but they are the most common recipients.
+	gas, err := sb.EstimateGas(ctx, ethereum.CallMsg{
+		From:  sb.faucet.From,
+		To:    &to,
+		Value: wei,
+	})
+	if err != nil {
+		return fmt.Errorf("%T.EstimateGas(…) for funding %v: %v", sb, to, err)
+	}
+	opts := &bind.TransactOpts{
+		From:     sb.faucet.From,
+		Signer:   sb.faucet.Signer,
+		Value:    wei,
+		GasLimit: gas,
+		Context:  ctx,
+	}
+	if _, err := bind.NewBoundContract(to, abi.ABI{}, sb, sb, sb).Transfer(opts); err != nil {
+		return fmt.Errorf("funding %v with %d wei: %v", to, wei, err)
+	}
+	return nil
+}
+
+// erc20Transfer is the ABI of the only ERC20 method required by FundERC20().
+var erc20Transfer = abi.ABI{
+	Methods: map[string]abi.Method{
+		"transfer": *eth.MustParseMethod("transfer(address to, uint256 amount) returns (bool)"),
+	},
+}
+
+// FundERC20 transfers amount of the ERC20 token from the holder to the
+// address. The holder is typically the account that deployed the token or
+// one to which it was minted; see Impersonate() if only its address is known.
+func (sb *SimulatedBackend) FundERC20(ctx context.Context, token common.Address, holder *bind.TransactOpts, to common.Address, amount *big.Int) error {
+	opts := &bind.TransactOpts{
+		From:    holder.From,
+		Signer:  holder.Signer,
+		Context: ctx,
+	}
+	if _, err := bind.NewBoundContract(token, erc20Transfer, sb, sb, sb).Transact(opts, "transfer", to, amount); err != nil {
+		return fmt.Errorf("ERC20(%v).transfer(%v, %d) from %v: %v", token, to, amount, holder.From, err)
+	}
+	return nil
+}
+
+// NamedAccount returns a TransactOpts signing as a deterministic account
+// derived from the name; e.g. "owner" or "attacker". This allows tests to
+// refer to accounts by role instead of threading account numbers or private
+// keys. Each account is funded with NamedAccountBalance, via Fund(), the first
+// time that it is requested from sb.
+func (sb *SimulatedBackend) NamedAccount(ctx context.Context, name string) (*bind.TransactOpts, error) {
+	acc, ok := sb.named[name]
+	if !ok {
+		var err error
+		acc, _, err = deterministicAccount([]byte("named:" + name))
+		if err != nil {
+			return nil, err
+		}
+		if err := sb.Fund(ctx, acc.From, NamedAccountBalance); err != nil {
+			return nil, err
+		}
+		sb.named[name] = acc
+	}
+	return &bind.TransactOpts{
+		From:   acc.From,
+		Signer: acc.Signer,
+	}, nil
+}
+
+// Impersonate returns a TransactOpts signing as the address, which MUST belong
+// to an account known to sb: a numbered account, a MockedEntity, or a
+// NamedAccount(). This allows tests to act as an address returned by a
+// contract (e.g. owner()) without knowing how it was derived.
+//
+// Arbitrary addresses can't be impersonated because the simulated backend,
+// unlike Anvil or Hardhat, verifies transaction signatures.
+func (sb *SimulatedBackend) Impersonate(addr common.Address) (*bind.TransactOpts, error) {
+	candidates := append([]*bind.TransactOpts{}, sb.accounts...)
+	for _, acc := range sb.mockAccounts {
+		candidates = append(candidates, acc)
+	}
+	for _, acc := range sb.named {
+		candidates = append(candidates, acc)
+	}
+
+	for _, acc := range candidates {
+		if acc.From == addr {
+			return &bind.TransactOpts{
+				From:   acc.From,
+				Signer: acc.Signer,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("no known private key for %v; only numbered, mocked, and named accounts can be impersonated", addr)
+}
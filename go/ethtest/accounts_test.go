@@ -0,0 +1,81 @@
+package ethtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+func TestFundAndNamedAccounts(t *testing.T) {
+	ctx := context.Background()
+	sim := NewSimulatedBackendTB(t, 2)
+
+	addr := common.HexToAddress("0xf00d")
+	wei := eth.Ether(1e6)
+	if err := sim.Fund(ctx, addr, wei); err != nil {
+		t.Fatalf("%T.Fund(%v, %d) error %v", sim, addr, wei, err)
+	}
+	if got := sim.BalanceOf(ctx, t, addr); got.Cmp(wei) != 0 {
+		t.Errorf("%T.BalanceOf(%v) after Fund(…, %d) got %d; want %d", sim, addr, wei, got, wei)
+	}
+
+	owner, err := sim.NamedAccount(ctx, "owner")
+	if err != nil {
+		t.Fatalf("%T.NamedAccount(owner) error %v", sim, err)
+	}
+	if got := sim.BalanceOf(ctx, t, owner.From); got.Cmp(NamedAccountBalance) != 0 {
+		t.Errorf("%T.BalanceOf([named account]) got %d; want %d", sim, got, NamedAccountBalance)
+	}
+
+	again, err := sim.NamedAccount(ctx, "owner")
+	if err != nil {
+		t.Fatalf("%T.NamedAccount(owner) second call error %v", sim, err)
+	}
+	if again.From != owner.From {
+		t.Errorf("%T.NamedAccount(owner) got different addresses %v and %v", sim, owner.From, again.From)
+	}
+	if got := sim.BalanceOf(ctx, t, owner.From); got.Cmp(NamedAccountBalance) != 0 {
+		t.Errorf("%T.BalanceOf([named account]) after second NamedAccount() got %d; want %d as it MUST only be funded once", sim, got, NamedAccountBalance)
+	}
+
+	other := NewSimulatedBackendTB(t, 0)
+	otherOwner, err := other.NamedAccount(ctx, "owner")
+	if err != nil {
+		t.Fatalf("%T.NamedAccount(owner) on other backend error %v", other, err)
+	}
+	if otherOwner.From != owner.From {
+		t.Errorf("%T.NamedAccount(owner) not deterministic across backends; got %v and %v", sim, owner.From, otherOwner.From)
+	}
+
+	t.Run("Impersonate", func(t *testing.T) {
+		for _, addr := range []common.Address{sim.Addr(1), owner.From} {
+			opts, err := sim.Impersonate(addr)
+			if err != nil {
+				t.Fatalf("%T.Impersonate(%v) error %v", sim, addr, err)
+			}
+			opts.Value = big.NewInt(1)
+			opts.GasLimit = params.TxGas
+			to := common.HexToAddress("0xbeef")
+			before := sim.BalanceOf(ctx, t, to)
+			tx := sim.Must(t, "sending value as impersonated %v", addr)(bind.NewBoundContract(to, abi.ABI{}, sim, sim, sim).Transfer(opts))
+			if _, err := sim.TransactionReceipt(ctx, tx.Hash()); err != nil {
+				t.Errorf("%T.TransactionReceipt(…) of transaction from impersonated %v; error %v", sim, addr, err)
+			}
+			if got, want := sim.BalanceOf(ctx, t, to), new(big.Int).Add(before, big.NewInt(1)); got.Cmp(want) != 0 {
+				t.Errorf("%T.BalanceOf(%v) after transfer from impersonated %v; got %d; want %d", sim, to, addr, got, want)
+			}
+		}
+
+		unknown := common.HexToAddress("0xdead")
+		if _, err := sim.Impersonate(unknown); err == nil {
+			t.Errorf("%T.Impersonate(%v) got nil error; want non-nil", sim, unknown)
+		}
+	})
+}
@@ -0,0 +1,423 @@
+package ethtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// DefaultBlockGasLimit is the gas limit of blocks built by a BlockBuilder
+// unless overridden with WithGasLimit().
+const DefaultBlockGasLimit = 30_000_000
+
+// DefaultBlockInterval is the number of seconds between a BlockFixture and the
+// block returned by its Child() builder.
+const DefaultBlockInterval = 12
+
+// A BlockBuilder constructs a BlockFixture with transactions, receipts, logs,
+// and withdrawals that are internally consistent (e.g. hashes, log indices,
+// and cumulative gas), unlike NewBlock(), which only populates the number and
+// time. Methods return the builder to allow chaining.
+type BlockBuilder struct {
+	header      types.Header
+	txs         []*types.Transaction
+	receipts    []*types.Receipt
+	withdrawals []*types.Withdrawal
+}
+
+// NewBlockBuilder returns a builder for a post-merge block with the specified
+// number and time, a base fee of params.InitialBaseFee, and a gas limit of
+// DefaultBlockGasLimit.
+func NewBlockBuilder(num, time uint64) *BlockBuilder {
+	return &BlockBuilder{
+		header: types.Header{
+			Number:     new(big.Int).SetUint64(num),
+			Time:       time,
+			Difficulty: big.NewInt(0),
+			GasLimit:   DefaultBlockGasLimit,
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+		},
+	}
+}
+
+// WithParentHash sets the block's parent hash.
+func (b *BlockBuilder) WithParentHash(h common.Hash) *BlockBuilder {
+	b.header.ParentHash = h
+	return b
+}
+
+// WithBaseFee sets the block's base fee, which is used to compute the
+// effective gas price of transactions.
+func (b *BlockBuilder) WithBaseFee(wei *big.Int) *BlockBuilder {
+	b.header.BaseFee = new(big.Int).Set(wei)
+	return b
+}
+
+// WithCoinbase sets the block's fee recipient.
+func (b *BlockBuilder) WithCoinbase(addr common.Address) *BlockBuilder {
+	b.header.Coinbase = addr
+	return b
+}
+
+// WithGasLimit sets the block's gas limit.
+func (b *BlockBuilder) WithGasLimit(gas uint64) *BlockBuilder {
+	b.header.GasLimit = gas
+	return b
+}
+
+// AddTx adds a successful transaction that used all of its gas limit and
+// emitted the logs. Only the Address, Topics, and Data of each log need be
+// set; all other fields are populated by Build(). The transaction SHOULD be
+// signed as the sender is otherwise unknown and ethclient rejects unsigned
+// transactions.
+func (b *BlockBuilder) AddTx(tx *types.Transaction, logs ...*types.Log) *BlockBuilder {
+	return b.addTx(tx, types.ReceiptStatusSuccessful, logs)
+}
+
+// AddFailedTx is equivalent to AddTx() except that the transaction reverted
+// and therefore emitted no logs.
+func (b *BlockBuilder) AddFailedTx(tx *types.Transaction) *BlockBuilder {
+	return b.addTx(tx, types.ReceiptStatusFailed, nil)
+}
+
+func (b *BlockBuilder) addTx(tx *types.Transaction, status uint64, logs []*types.Log) *BlockBuilder {
+	if logs == nil {
+		// Nodes return an empty array, and ethclient rejects null.
+		logs = []*types.Log{}
+	}
+	b.header.GasUsed += tx.Gas()
+	b.txs = append(b.txs, tx)
+	b.receipts = append(b.receipts, &types.Receipt{
+		Type:              tx.Type(),
+		Status:            status,
+		CumulativeGasUsed: b.header.GasUsed,
+		Logs:              logs,
+		TxHash:            tx.Hash(),
+		GasUsed:           tx.Gas(),
+	})
+	return b
+}
+
+// AddWithdrawal adds a beacon-chain withdrawal of the amount, in gwei, to the
+// address. The withdrawal index is sequential within the block.
+func (b *BlockBuilder) AddWithdrawal(validator uint64, addr common.Address, gwei uint64) *BlockBuilder {
+	b.withdrawals = append(b.withdrawals, &types.Withdrawal{
+		Index:     uint64(len(b.withdrawals)),
+		Validator: validator,
+		Address:   addr,
+		Amount:    gwei,
+	})
+	return b
+}
+
+// Build returns the BlockFixture, reporting any errors on tb.Fatal. Receipts
+// and logs have all of their derived fields populated, as they would be if
+// returned by a node.
+func (b *BlockBuilder) Build(tb testing.TB) *BlockFixture {
+	tb.Helper()
+
+	baseFee := b.header.BaseFee
+	for i, r := range b.receipts {
+		tx := b.txs[i]
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil {
+			tb.Fatalf("%T.EffectiveGasTip(%d) of tx %d: %v", tx, baseFee, i, err)
+		}
+		r.EffectiveGasPrice = new(big.Int).Add(baseFee, tip)
+		if to := tx.To(); to == nil {
+			if from, err := txSender(tx); err == nil {
+				r.ContractAddress = crypto.CreateAddress(from, tx.Nonce())
+			}
+		}
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+	}
+
+	blk := types.NewBlockWithWithdrawals(&b.header, b.txs, nil, b.receipts, b.withdrawals, trie.NewStackTrie(nil))
+
+	var logIndex uint
+	for i, r := range b.receipts {
+		r.BlockHash = blk.Hash()
+		r.BlockNumber = blk.Number()
+		r.TransactionIndex = uint(i)
+		for _, l := range r.Logs {
+			l.BlockNumber = blk.NumberU64()
+			l.BlockHash = blk.Hash()
+			l.TxHash = r.TxHash
+			l.TxIndex = uint(i)
+			l.Index = logIndex
+			logIndex++
+		}
+	}
+
+	return &BlockFixture{
+		Block:    blk,
+		Receipts: b.receipts,
+	}
+}
+
+// A BlockFixture is a block and the receipts of its transactions, in the same
+// order.
+type BlockFixture struct {
+	Block    *types.Block
+	Receipts types.Receipts
+}
+
+// Logs returns the logs of all receipts, in the order in which they were
+// emitted.
+func (f *BlockFixture) Logs() []*types.Log {
+	var logs []*types.Log
+	for _, r := range f.Receipts {
+		logs = append(logs, r.Logs...)
+	}
+	return logs
+}
+
+// Child returns a builder for the next block, with f.Block as its parent, a
+// time DefaultBlockInterval seconds later, and the same base fee, coinbase, and
+// gas limit.
+func (f *BlockFixture) Child() *BlockBuilder {
+	hdr := f.Block.Header()
+	return NewBlockBuilder(hdr.Number.Uint64()+1, hdr.Time+DefaultBlockInterval).
+		WithParentHash(f.Block.Hash()).
+		WithBaseFee(hdr.BaseFee).
+		WithCoinbase(hdr.Coinbase).
+		WithGasLimit(hdr.GasLimit)
+}
+
+// txSender returns the sender of the signed transaction.
+func txSender(tx *types.Transaction) (common.Address, error) {
+	return types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+}
+
+// AddBlock adds fixtures for all of the JSON-RPC methods used by ethclient to
+// fetch the block, its transactions, and its receipts, by number or hash:
+// eth_getBlockByNumber, eth_getBlockByHash, eth_getBlockReceipts,
+// eth_getTransactionByHash, and eth_getTransactionReceipt. Logs are only
+// served for explicit queries; see AddLogs().
+func (fs *Fixtures) AddBlock(f *BlockFixture) error {
+	blk := f.Block
+	num := hexutil.EncodeUint64(blk.NumberU64())
+	hash := blk.Hash()
+
+	for _, full := range []bool{false, true} {
+		res, err := marshalRPCBlock(blk, full)
+		if err != nil {
+			return err
+		}
+		if err := fs.addCall("eth_getBlockByNumber", res, num, full); err != nil {
+			return err
+		}
+		if err := fs.addCall("eth_getBlockByHash", res, hash, full); err != nil {
+			return err
+		}
+	}
+
+	receipts := f.Receipts
+	if receipts == nil {
+		receipts = types.Receipts{}
+	}
+	if err := fs.addCall("eth_getBlockReceipts", receipts, num); err != nil {
+		return err
+	}
+	if err := fs.addCall("eth_getBlockReceipts", receipts, hash); err != nil {
+		return err
+	}
+
+	for i, tx := range blk.Transactions() {
+		res, err := marshalRPCTx(blk, i, tx)
+		if err != nil {
+			return err
+		}
+		if err := fs.addCall("eth_getTransactionByHash", res, tx.Hash()); err != nil {
+			return err
+		}
+		if i < len(f.Receipts) {
+			if err := fs.addCall("eth_getTransactionReceipt", f.Receipts[i], tx.Hash()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AddLogs adds an eth_getLogs fixture for the query, as sent by
+// ethclient.FilterLogs(), responding with the logs from the blocks that match
+// it.
+func (fs *Fixtures) AddLogs(q ethereum.FilterQuery, blocks ...*BlockFixture) error {
+	// Mirrors the unexported toFilterArg() of ethclient so that the stub sees
+	// identical params.
+	arg := map[string]interface{}{
+		"address": q.Addresses,
+		"topics":  q.Topics,
+	}
+	if q.BlockHash != nil {
+		if q.FromBlock != nil || q.ToBlock != nil {
+			return fmt.Errorf("%T with both BlockHash and FromBlock/ToBlock", q)
+		}
+		arg["blockHash"] = *q.BlockHash
+	} else {
+		arg["fromBlock"] = "0x0"
+		if q.FromBlock != nil {
+			arg["fromBlock"] = hexutil.EncodeBig(q.FromBlock)
+		}
+		arg["toBlock"] = "latest"
+		if q.ToBlock != nil {
+			arg["toBlock"] = hexutil.EncodeBig(q.ToBlock)
+		}
+	}
+
+	logs := []*types.Log{}
+	for _, b := range blocks {
+		if !filterMatchesBlock(q, b.Block) {
+			continue
+		}
+		for _, l := range b.Logs() {
+			if filterMatchesLog(q, l) {
+				logs = append(logs, l)
+			}
+		}
+	}
+	return fs.addCall("eth_getLogs", logs, arg)
+}
+
+func filterMatchesBlock(q ethereum.FilterQuery, b *types.Block) bool {
+	if q.BlockHash != nil {
+		return *q.BlockHash == b.Hash()
+	}
+	if q.FromBlock != nil && b.Number().Cmp(q.FromBlock) < 0 {
+		return false
+	}
+	return q.ToBlock == nil || b.Number().Cmp(q.ToBlock) <= 0
+}
+
+func filterMatchesLog(q ethereum.FilterQuery, l *types.Log) bool {
+	if len(q.Addresses) > 0 {
+		var found bool
+		for _, a := range q.Addresses {
+			found = found || a == l.Address
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.Topics) > len(l.Topics) {
+		return false
+	}
+	for i, alternatives := range q.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		var found bool
+		for _, t := range alternatives {
+			found = found || t == l.Topics[i]
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// addCall adds a fixture for the method called with the params, responding
+// with the JSON encoding of result.
+func (fs *Fixtures) addCall(method string, result interface{}, params ...interface{}) error {
+	ps, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(%s params): %v", method, err)
+	}
+	res, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(%s result %T): %v", method, result, err)
+	}
+	fs.Add(&Fixture{
+		Method: method,
+		Params: ps,
+		Result: res,
+	})
+	return nil
+}
+
+// marshalRPCBlock returns the block in the format returned by
+// eth_getBlockBy{Number,Hash}, with either full transactions or only their
+// hashes.
+func marshalRPCBlock(b *types.Block, fullTx bool) (json.RawMessage, error) {
+	fields, err := jsonFields(b.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]interface{}, len(b.Transactions()))
+	for i, tx := range b.Transactions() {
+		if !fullTx {
+			txs[i] = tx.Hash()
+			continue
+		}
+		full, err := marshalRPCTx(b, i, tx)
+		if err != nil {
+			return nil, err
+		}
+		txs[i] = full
+	}
+	fields["transactions"] = txs
+	fields["uncles"] = []common.Hash{}
+	fields["size"] = hexutil.Uint64(b.Size())
+	if w := b.Withdrawals(); w != nil {
+		fields["withdrawals"] = w
+	}
+
+	buf, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(%T block fields): %v", fields, err)
+	}
+	return buf, nil
+}
+
+// marshalRPCTx returns the ith transaction of the block in the format returned
+// by eth_getTransactionByHash.
+func marshalRPCTx(b *types.Block, i int, tx *types.Transaction) (json.RawMessage, error) {
+	fields, err := jsonFields(tx)
+	if err != nil {
+		return nil, err
+	}
+	fields["blockHash"] = b.Hash()
+	fields["blockNumber"] = hexutil.EncodeBig(b.Number())
+	fields["transactionIndex"] = hexutil.Uint64(i)
+	if from, err := txSender(tx); err == nil {
+		fields["from"] = from
+	}
+
+	buf, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(%T tx fields): %v", fields, err)
+	}
+	return buf, nil
+}
+
+// jsonFields returns the top-level fields of v's JSON object, allowing others
+// to be added.
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(%T): %v", v, err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal([%T JSON], %T): %v", v, raw, err)
+	}
+
+	fields := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		fields[k] = v
+	}
+	return fields, nil
+}
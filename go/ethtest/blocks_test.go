@@ -0,0 +1,214 @@
+package ethtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestBlockBuilder(t *testing.T) {
+	ctx := context.Background()
+	const chainID = 1337
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("crypto.GenerateKey() error %v", err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.LatestSignerForChainID(big.NewInt(chainID))
+
+	nonce := uint64(0)
+	newTx := func(t *testing.T, to *common.Address) *types.Transaction {
+		t.Helper()
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   big.NewInt(chainID),
+			Nonce:     nonce,
+			To:        to,
+			Gas:       50_000,
+			GasTipCap: big.NewInt(2 * params.GWei),
+			GasFeeCap: big.NewInt(100 * params.GWei),
+		})
+		if err != nil {
+			t.Fatalf("types.SignNewTx() error %v", err)
+		}
+		nonce++
+		return tx
+	}
+
+	token := common.HexToAddress("0x70ce")
+	other := common.HexToAddress("0x07e4")
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	approval := crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+
+	tx0 := newTx(t, &token)
+	tx1 := newTx(t, nil)
+	tx2 := newTx(t, &other)
+
+	parent := NewBlockBuilder(100, 1_700_000_000).
+		WithBaseFee(big.NewInt(10*params.GWei)).
+		AddTx(
+			tx0,
+			&types.Log{Address: token, Topics: []common.Hash{transfer, common.BytesToHash(from.Bytes()), common.BytesToHash(other.Bytes())}},
+			&types.Log{Address: token, Topics: []common.Hash{approval, common.BytesToHash(from.Bytes()), common.BytesToHash(other.Bytes())}},
+		).
+		AddTx(tx1).
+		AddWithdrawal(42, other, 1e9).
+		Build(t)
+
+	child := parent.Child().
+		AddFailedTx(tx2).
+		AddTx(newTx(t, &token), &types.Log{Address: token, Topics: []common.Hash{transfer, common.BytesToHash(other.Bytes()), common.BytesToHash(from.Bytes())}}).
+		Build(t)
+
+	t.Run("derived fields", func(t *testing.T) {
+		if got, want := child.Block.ParentHash(), parent.Block.Hash(); got != want {
+			t.Errorf("Child().Build().ParentHash() got %v; want %v", got, want)
+		}
+		if got, want := child.Block.NumberU64(), parent.Block.NumberU64()+1; got != want {
+			t.Errorf("Child().Build().NumberU64() got %d; want %d", got, want)
+		}
+
+		type logIdx struct {
+			Block         uint64
+			TxIdx, LogIdx uint
+			Tx            common.Hash
+		}
+		var got []logIdx
+		for _, f := range []*BlockFixture{parent, child} {
+			for _, l := range f.Logs() {
+				if l.BlockHash != f.Block.Hash() {
+					t.Errorf("%T.Logs() includes log with BlockHash %v; want %v", f, l.BlockHash, f.Block.Hash())
+				}
+				got = append(got, logIdx{l.BlockNumber, l.TxIndex, l.Index, l.TxHash})
+			}
+		}
+		want := []logIdx{
+			{100, 0, 0, tx0.Hash()},
+			{100, 0, 1, tx0.Hash()},
+			{101, 1, 0, child.Block.Transactions()[1].Hash()},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("log positions diff (-want +got):\n%s", diff)
+		}
+
+		r := parent.Receipts[1]
+		if got, want := r.ContractAddress, crypto.CreateAddress(from, tx1.Nonce()); got != want {
+			t.Errorf("contract-creation receipt ContractAddress got %v; want %v", got, want)
+		}
+		if got, want := r.CumulativeGasUsed, tx0.Gas()+tx1.Gas(); got != want {
+			t.Errorf("second receipt CumulativeGasUsed got %d; want %d", got, want)
+		}
+		if got, want := r.EffectiveGasPrice, big.NewInt(12*params.GWei); got.Cmp(want) != 0 {
+			t.Errorf("receipt EffectiveGasPrice got %d; want %d (base fee + tip)", got, want)
+		}
+		if got, want := child.Receipts[0].Status, types.ReceiptStatusFailed; got != want {
+			t.Errorf("AddFailedTx() receipt Status got %d; want %d", got, want)
+		}
+	})
+
+	fixtures := new(Fixtures)
+	for _, f := range []*BlockFixture{parent, child} {
+		if err := fixtures.AddBlock(f); err != nil {
+			t.Fatalf("%T.AddBlock(block %d) error %v", fixtures, f.Block.NumberU64(), err)
+		}
+	}
+	q := ethereum.FilterQuery{
+		FromBlock: big.NewInt(100),
+		ToBlock:   big.NewInt(101),
+		Addresses: []common.Address{token},
+		Topics:    [][]common.Hash{{transfer}},
+	}
+	if err := fixtures.AddLogs(q, parent, child); err != nil {
+		t.Fatalf("%T.AddLogs(%+v, …) error %v", fixtures, q, err)
+	}
+
+	url := NewRPCStub(chainID, 101).WithFixtures(fixtures).ServeHTTP(t)
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		t.Fatalf("ethclient.DialContext(ctx, %q) error %v", url, err)
+	}
+	t.Cleanup(client.Close)
+
+	t.Run("ethclient", func(t *testing.T) {
+		for _, f := range []*BlockFixture{parent, child} {
+			want := f.Block
+
+			byNum, err := client.BlockByNumber(ctx, want.Number())
+			if err != nil {
+				t.Fatalf("%T.BlockByNumber(%d) error %v", client, want.Number(), err)
+			}
+			byHash, err := client.BlockByHash(ctx, want.Hash())
+			if err != nil {
+				t.Fatalf("%T.BlockByHash(%v) error %v", client, want.Hash(), err)
+			}
+			hdr, err := client.HeaderByNumber(ctx, want.Number())
+			if err != nil {
+				t.Fatalf("%T.HeaderByNumber(%d) error %v", client, want.Number(), err)
+			}
+			for _, got := range []common.Hash{byNum.Hash(), byHash.Hash(), hdr.Hash()} {
+				if got != want.Hash() {
+					t.Errorf("block %d fetched with hash %v; want %v", want.NumberU64(), got, want.Hash())
+				}
+			}
+			if got, want := len(byNum.Transactions()), len(want.Transactions()); got != want {
+				t.Errorf("%T.BlockByNumber().Transactions() got %d; want %d", client, got, want)
+			}
+			if got, want := len(byNum.Withdrawals()), len(want.Withdrawals()); got != want {
+				t.Errorf("%T.BlockByNumber().Withdrawals() got %d; want %d", client, got, want)
+			}
+
+			receipts, err := client.BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(want.Hash(), false))
+			if err != nil {
+				t.Fatalf("%T.BlockReceipts(%v) error %v", client, want.Hash(), err)
+			}
+			if diff := cmp.Diff(f.Receipts, types.Receipts(receipts), cmp.Comparer(bigEq), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("%T.BlockReceipts(%v) diff (-want +got):\n%s", client, want.Hash(), diff)
+			}
+
+			for i, tx := range want.Transactions() {
+				got, pending, err := client.TransactionByHash(ctx, tx.Hash())
+				if err != nil || pending || got.Hash() != tx.Hash() {
+					t.Errorf("%T.TransactionByHash(%v) got (%v, pending=%t, err=%v); want same tx, false, nil", client, tx.Hash(), got.Hash(), pending, err)
+				}
+				sender, err := client.TransactionSender(ctx, got, want.Hash(), uint(i))
+				if err != nil || sender != from {
+					t.Errorf("%T.TransactionSender(%v) got %v, err=%v; want %v", client, tx.Hash(), sender, err, from)
+				}
+
+				r, err := client.TransactionReceipt(ctx, tx.Hash())
+				if err != nil {
+					t.Fatalf("%T.TransactionReceipt(%v) error %v", client, tx.Hash(), err)
+				}
+				if diff := cmp.Diff(f.Receipts[i], r, cmp.Comparer(bigEq), cmpopts.EquateEmpty()); diff != "" {
+					t.Errorf("%T.TransactionReceipt(%v) diff (-want +got):\n%s", client, tx.Hash(), diff)
+				}
+			}
+		}
+
+		logs, err := client.FilterLogs(ctx, q)
+		if err != nil {
+			t.Fatalf("%T.FilterLogs(%+v) error %v", client, q, err)
+		}
+		want := []types.Log{*parent.Logs()[0], *child.Logs()[0]}
+		if diff := cmp.Diff(want, logs, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("%T.FilterLogs(%+v) diff (-want +got):\n%s", client, q, diff)
+		}
+	})
+}
+
+func bigEq(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
@@ -5,9 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/cxkoda/solgo/go/eth"
 )
 
 // BlockTimes implements eth.BlockFetcher, returning Blocks with nothing but a
@@ -48,7 +52,8 @@ func (t BlockTimes) BlockByNumber(ctx context.Context, num *big.Int) (*types.Blo
 	return NewBlock(num.Int64(), t[i]), nil
 }
 
-// NewBlock returns a new Block with only the number and time populated.
+// NewBlock returns a new Block with only the number and time populated. See
+// NewBlockBuilder() for more realistic blocks.
 func NewBlock(num int64, time uint64) *types.Block {
 	hdr := &types.Header{
 		Number: big.NewInt(num),
@@ -56,3 +61,49 @@ func NewBlock(num int64, time uint64) *types.Block {
 	}
 	return types.NewBlock(hdr, nil, nil, nil, nil)
 }
+
+// A CountingBlockFetcher wraps an eth.BlockFetcher, counting the number of
+// calls to BlockByNumber() for each block number. It is intended as a test
+// double for verifying caching behaviour; see eth.BlockCache.
+type CountingBlockFetcher struct {
+	eth.BlockFetcher
+
+	mu    sync.Mutex
+	calls map[uint64]int
+}
+
+// BlockByNumber counts the call before propagating it to the wrapped
+// BlockFetcher. Calls with a nil number are counted against math.MaxUint64.
+func (f *CountingBlockFetcher) BlockByNumber(ctx context.Context, num *big.Int) (*types.Block, error) {
+	n := uint64(math.MaxUint64)
+	if num != nil {
+		n = num.Uint64()
+	}
+
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[uint64]int)
+	}
+	f.calls[n]++
+	f.mu.Unlock()
+
+	return f.BlockFetcher.BlockByNumber(ctx, num)
+}
+
+// Calls returns the number of calls to BlockByNumber(num).
+func (f *CountingBlockFetcher) Calls(num uint64) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[num]
+}
+
+// TotalCalls returns the total number of calls to BlockByNumber().
+func (f *CountingBlockFetcher) TotalCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int
+	for _, c := range f.calls {
+		n += c
+	}
+	return n
+}
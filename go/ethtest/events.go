@@ -0,0 +1,142 @@
+package ethtest
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// AssertEvents decodes all logs in the receipt against the ABIs of the
+// abigen-generated bindings and reports, via tb.Errorf(), any difference
+// between the decoded events and those wanted.
+//
+// Each value in want MUST be an abigen-generated event type (or an equivalent
+// struct), or a pointer to one, e.g. &binding.ContractEventName{…}. The i-th
+// log is decoded into a new value of the same type as want[i], so the order
+// of want MUST match the order in which events were emitted; a value of the
+// wrong type is reported as a difference. Raw fields of type types.Log are
+// ignored and *big.Ints are compared by value. Logs that can't be decoded,
+// either because their event isn't in any of the ABIs or because there are
+// more logs than wanted events, are reported as *types.Logs in the diff.
+func AssertEvents(tb testing.TB, rcpt *types.Receipt, bindings []*bind.MetaData, want ...interface{}) {
+	tb.Helper()
+
+	var abis []*abi.ABI
+	for _, md := range bindings {
+		a, err := md.GetAbi()
+		if err != nil {
+			tb.Fatalf("%T.GetAbi() error %v", md, err)
+		}
+		abis = append(abis, a)
+	}
+
+	got := make([]interface{}, len(rcpt.Logs))
+	for i, l := range rcpt.Logs {
+		got[i] = l
+		if i >= len(want) {
+			continue
+		}
+		if ev, ok := decodeEvent(tb, abis, l, reflect.TypeOf(want[i])); ok {
+			got[i] = ev
+		}
+	}
+
+	if diff := cmp.Diff(want, got, eventCmpOpts...); diff != "" {
+		tb.Errorf("Events emitted by tx %v diff (-want +got):\n%s", rcpt.TxHash, diff)
+	}
+}
+
+// eventCmpOpts are the cmp.Options used by AssertEvents().
+var eventCmpOpts = []cmp.Option{
+	cmp.FilterPath(func(p cmp.Path) bool {
+		sf, ok := p.Last().(cmp.StructField)
+		return ok && sf.Name() == "Raw" && sf.Type() == reflect.TypeOf(types.Log{})
+	}, cmp.Ignore()),
+	cmpopts.EquateEmpty(),
+	cmp.Comparer(func(a, b *big.Int) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return a.Cmp(b) == 0
+	}),
+}
+
+// hasFields reports whether typ has a field for every argument, named as by
+// abigen.
+func hasFields(typ reflect.Type, args abi.Arguments) bool {
+	for _, arg := range args {
+		if _, ok := typ.FieldByName(abi.ToCamelCase(arg.Name)); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeEvent decodes the log into a new value of type typ, which MUST be a
+// struct or a pointer to one, returning false if the log's event isn't found in
+// any of the ABIs or if typ is of the wrong kind. Decoding errors are reported
+// with tb.Errorf().
+func decodeEvent(tb testing.TB, abis []*abi.ABI, l *types.Log, typ reflect.Type) (interface{}, bool) {
+	tb.Helper()
+
+	if typ == nil || len(l.Topics) == 0 {
+		return nil, false
+	}
+	isPtr := typ.Kind() == reflect.Pointer
+	if isPtr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	for _, a := range abis {
+		ev, err := a.EventByID(l.Topics[0])
+		if err != nil {
+			continue
+		}
+
+		// Mismatched types (e.g. events in the wrong order) are reported by the
+		// diff instead of as decoding errors.
+		if !hasFields(typ, ev.Inputs) {
+			return nil, false
+		}
+
+		var indexed abi.Arguments
+		for _, arg := range ev.Inputs {
+			if arg.Indexed {
+				indexed = append(indexed, arg)
+			}
+		}
+		if len(l.Topics) != len(indexed)+1 {
+			continue
+		}
+
+		out := reflect.New(typ)
+		if len(l.Data) > 0 {
+			if err := a.UnpackIntoInterface(out.Interface(), ev.Name, l.Data); err != nil {
+				tb.Errorf("Log index %d: %T.UnpackIntoInterface(%T, %q, …) error %v", l.Index, a, out.Interface(), ev.Name, err)
+				return nil, false
+			}
+		}
+		if err := abi.ParseTopics(out.Interface(), indexed, l.Topics[1:]); err != nil {
+			tb.Errorf("Log index %d: abi.ParseTopics(%T, …) error %v", l.Index, out.Interface(), err)
+			return nil, false
+		}
+		if raw := out.Elem().FieldByName("Raw"); raw.IsValid() && raw.Type() == reflect.TypeOf(types.Log{}) && raw.CanSet() {
+			raw.Set(reflect.ValueOf(*l))
+		}
+
+		if isPtr {
+			return out.Interface(), true
+		}
+		return out.Elem().Interface(), true
+	}
+	return nil, false
+}
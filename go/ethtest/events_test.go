@@ -0,0 +1,167 @@
+package ethtest
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const eventsABI = `[
+	{
+		"type": "event",
+		"name": "Transfer",
+		"inputs": [
+			{"name": "from", "type": "address", "indexed": true},
+			{"name": "to", "type": "address", "indexed": true},
+			{"name": "value", "type": "uint256", "indexed": false}
+		]
+	},
+	{
+		"type": "event",
+		"name": "Paused",
+		"inputs": [
+			{"name": "account", "type": "address", "indexed": false}
+		]
+	}
+]`
+
+// The following types mirror abigen-generated events.
+
+type tokenTransfer struct {
+	From  common.Address
+	To    common.Address
+	Value *big.Int
+	Raw   types.Log
+}
+
+type tokenPaused struct {
+	Account common.Address
+	Raw     types.Log
+}
+
+// recordingTB captures errors reported via Errorf() and Fatalf().
+type recordingTB struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func TestAssertEvents(t *testing.T) {
+	bindings := []*bind.MetaData{{ABI: eventsABI}}
+
+	from := common.HexToAddress("0xf00")
+	to := common.HexToAddress("0xba7")
+	pauser := common.HexToAddress("0xb0b")
+
+	transfer := &types.Log{
+		Topics: []common.Hash{
+			crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")),
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data:  common.BigToHash(big.NewInt(42)).Bytes(),
+		Index: 0,
+	}
+	paused := &types.Log{
+		Topics: []common.Hash{crypto.Keccak256Hash([]byte("Paused(address)"))},
+		Data:   common.BytesToHash(pauser.Bytes()).Bytes(),
+		Index:  1,
+	}
+	unknown := &types.Log{
+		Topics: []common.Hash{crypto.Keccak256Hash([]byte("Unknown()"))},
+		Index:  2,
+	}
+
+	tests := []struct {
+		name       string
+		logs       []*types.Log
+		want       []interface{}
+		wantErrors int
+	}{
+		{
+			name: "match",
+			logs: []*types.Log{transfer, paused},
+			want: []interface{}{
+				&tokenTransfer{From: from, To: to, Value: big.NewInt(42)},
+				tokenPaused{Account: pauser},
+			},
+		},
+		{
+			name: "no events",
+		},
+		{
+			name: "different value",
+			logs: []*types.Log{transfer},
+			want: []interface{}{
+				&tokenTransfer{From: from, To: to, Value: big.NewInt(43)},
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "out of order",
+			logs: []*types.Log{transfer, paused},
+			want: []interface{}{
+				&tokenPaused{Account: pauser},
+				&tokenTransfer{From: from, To: to, Value: big.NewInt(42)},
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "missing event",
+			logs: []*types.Log{transfer, paused},
+			want: []interface{}{
+				&tokenTransfer{From: from, To: to, Value: big.NewInt(42)},
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "extra event",
+			logs: []*types.Log{paused},
+			want: []interface{}{
+				&tokenPaused{Account: pauser},
+				&tokenPaused{Account: pauser},
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "event not in ABI",
+			logs: []*types.Log{unknown},
+			want: []interface{}{
+				&tokenPaused{},
+			},
+			wantErrors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recordingTB{TB: t}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				AssertEvents(rec, &types.Receipt{Logs: tt.logs}, bindings, tt.want...)
+			}()
+			<-done
+
+			if got := len(rec.errs); got != tt.wantErrors {
+				t.Errorf("AssertEvents() reported %d errors %q; want %d", got, rec.errs, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package ethtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// RecordRPCEnvVar is the environment variable that, if set to the URL of an
+// Ethereum JSON-RPC endpoint, causes RecordOrReplayRPC() to record responses
+// from the endpoint instead of replaying them.
+const RecordRPCEnvVar = "ETHTEST_RECORD_RPC"
+
+// A Fixture is a recorded response to a single JSON-RPC call.
+type Fixture struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RPCError       `json:"error,omitempty"`
+}
+
+// Fixtures are a set of recorded JSON-RPC responses, keyed by method and
+// parameters, suitable for storing as a golden file and serving with an
+// RPCStub. The zero value is ready to use and Fixtures are safe for concurrent
+// use.
+type Fixtures struct {
+	mu    sync.Mutex
+	calls map[string]*Fixture
+}
+
+// fixtureKey returns the key under which the response to the call is stored.
+// Params are compacted so insignificant whitespace is ignored.
+func fixtureKey(method string, params json.RawMessage) string {
+	buf := new(bytes.Buffer)
+	if err := json.Compact(buf, params); err != nil {
+		buf.Reset()
+		buf.Write(params)
+	}
+	return method + buf.String()
+}
+
+// Add adds the fixture, replacing any existing one for the same call.
+func (fs *Fixtures) Add(f *Fixture) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.calls == nil {
+		fs.calls = make(map[string]*Fixture)
+	}
+	fs.calls[fixtureKey(f.Method, f.Params)] = f
+}
+
+// lookup returns the fixture for the call, if one exists. It is safe to call on
+// nil Fixtures.
+func (fs *Fixtures) lookup(method string, params json.RawMessage) (*Fixture, bool) {
+	if fs == nil {
+		return nil, false
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	f, ok := fs.calls[fixtureKey(method, params)]
+	return f, ok
+}
+
+// Len returns the number of fixtures.
+func (fs *Fixtures) Len() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return len(fs.calls)
+}
+
+// MarshalJSON returns the fixtures as a JSON array, sorted by method and
+// parameters so that golden files are stable between recordings.
+func (fs *Fixtures) MarshalJSON() ([]byte, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	keys := make([]string, 0, len(fs.calls))
+	for k := range fs.calls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	all := make([]*Fixture, len(keys))
+	for i, k := range keys {
+		all[i] = fs.calls[k]
+	}
+	return json.MarshalIndent(all, "", "  ")
+}
+
+// UnmarshalJSON adds the fixtures in the JSON array returned by MarshalJSON().
+func (fs *Fixtures) UnmarshalJSON(buf []byte) error {
+	var all []*Fixture
+	if err := json.Unmarshal(buf, &all); err != nil {
+		return err
+	}
+	for _, f := range all {
+		fs.Add(f)
+	}
+	return nil
+}
+
+// LoadFixtures reads a golden file written by Fixtures.WriteFile().
+func LoadFixtures(path string) (*Fixtures, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fs := new(Fixtures)
+	if err := json.Unmarshal(buf, fs); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal([%s], %T): %v", path, fs, err)
+	}
+	return fs, nil
+}
+
+// WriteFile writes the fixtures as a golden file, to be read by
+// LoadFixtures().
+func (fs *Fixtures) WriteFile(path string) error {
+	buf, err := json.Marshal(fs)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(%T): %v", fs, err)
+	}
+	return os.WriteFile(path, append(buf, '\n'), 0644)
+}
+
+// An RPCRecorder is an HTTP proxy for an Ethereum JSON-RPC endpoint that
+// records every response as a Fixture.
+type RPCRecorder struct {
+	upstream string
+	client   *http.Client
+	fixtures Fixtures
+}
+
+// NewRPCRecorder returns an RPCRecorder proxying requests to the upstream URL.
+func NewRPCRecorder(upstream string) *RPCRecorder {
+	return &RPCRecorder{
+		upstream: upstream,
+		client:   http.DefaultClient,
+	}
+}
+
+// Fixtures returns all responses recorded so far.
+func (r *RPCRecorder) Fixtures() *Fixtures {
+	return &r.fixtures
+}
+
+// ServeHTTP starts an HTTP server for the recorder and returns its URL. The
+// server is closed by tb.Cleanup().
+func (r *RPCRecorder) ServeHTTP(tb testing.TB) string {
+	tb.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(r.handle))
+	tb.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// handle is the http.HandlerFunc of the recorder. Requests are forwarded
+// verbatim and responses returned verbatim, but the latter are only recorded if
+// they can be matched to a request by ID.
+func (r *RPCRecorder) handle(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reqs, _, err := parseRPCMessages[rpcRequest](body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	up, err := http.NewRequestWithContext(req.Context(), http.MethodPost, r.upstream, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	up.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(up)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		if resps, _, err := parseRPCMessages[rpcResponse](respBody); err == nil {
+			byID := make(map[string]*rpcRequest)
+			for _, q := range reqs {
+				byID[string(q.ID)] = q
+			}
+			for _, p := range resps {
+				if q, ok := byID[string(p.ID)]; ok {
+					r.fixtures.Add(&Fixture{
+						Method: q.Method,
+						Params: q.Params,
+						Result: p.Result,
+						Error:  p.Error,
+					})
+				}
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+}
+
+// ReplayRPC loads the golden file and returns the URL of an RPCStub serving its
+// fixtures. Calls without a fixture result in an error response.
+func ReplayRPC(tb testing.TB, path string) string {
+	tb.Helper()
+	fs, err := LoadFixtures(path)
+	if err != nil {
+		tb.Fatalf("LoadFixtures(%q) error %v; set $%s to record it", path, err, RecordRPCEnvVar)
+	}
+	return NewRPCStub(0, 0).WithFixtures(fs).ServeHTTP(tb)
+}
+
+// RecordOrReplayRPC returns the URL of an Ethereum JSON-RPC endpoint for use in
+// hermetic tests. Typically the endpoint replays fixtures from the golden file
+// at path, as with ReplayRPC(). If the RecordRPCEnvVar environment variable is
+// set, however, the endpoint is an RPCRecorder proxying the URL in the
+// variable, and the golden file is (over)written with all recorded responses
+// when the test completes.
+//
+// As the upstream URL typically contains an API key, it is never recorded.
+func RecordOrReplayRPC(tb testing.TB, path string) string {
+	tb.Helper()
+	upstream := os.Getenv(RecordRPCEnvVar)
+	if upstream == "" {
+		return ReplayRPC(tb, path)
+	}
+
+	rec := NewRPCRecorder(upstream)
+	tb.Cleanup(func() {
+		if tb.Failed() {
+			tb.Logf("Not writing golden file %q after test failure", path)
+			return
+		}
+		if err := rec.Fixtures().WriteFile(path); err != nil {
+			tb.Errorf("%T.WriteFile(%q) error %v", rec.Fixtures(), path, err)
+			return
+		}
+		tb.Logf("Wrote %d recorded RPC responses to %q", rec.Fixtures().Len(), path)
+	})
+	return rec.ServeHTTP(tb)
+}
@@ -0,0 +1,150 @@
+package ethtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// An RPCStub is a minimal Ethereum JSON-RPC server over HTTP. It responds to
+// eth_chainId and eth_blockNumber with fixed values and to all other methods
+// with responses from its Fixtures, if any; see ReplayRPC().
+type RPCStub struct {
+	chainID, blockNumber uint64
+	fixtures             *Fixtures
+}
+
+// NewRPCStub returns an RPCStub for the specified chain, with the specified
+// block as the head.
+func NewRPCStub(chainID, blockNumber uint64) *RPCStub {
+	return &RPCStub{
+		chainID:     chainID,
+		blockNumber: blockNumber,
+	}
+}
+
+// WithFixtures sets the Fixtures from which the stub serves responses, and
+// returns the stub. Fixtures take precedence over the fixed values passed to
+// NewRPCStub().
+func (s *RPCStub) WithFixtures(f *Fixtures) *RPCStub {
+	s.fixtures = f
+	return s
+}
+
+// ServeHTTP starts an HTTP server for the stub and returns its URL. The server
+// is closed by tb.Cleanup().
+func (s *RPCStub) ServeHTTP(tb testing.TB) string {
+	tb.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(s.handle))
+	tb.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// handle is the http.HandlerFunc of the stub.
+func (s *RPCStub) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reqs, batch, err := parseRPCMessages[rpcRequest](body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resps := make([]*rpcResponse, len(reqs))
+	for i, req := range reqs {
+		resps[i] = s.respond(req)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	var out interface{} = resps
+	if !batch {
+		out = resps[0]
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// respond returns the response to a single request.
+func (s *RPCStub) respond(req *rpcRequest) *rpcResponse {
+	resp := &rpcResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+	}
+	if f, ok := s.fixtures.lookup(req.Method, req.Params); ok {
+		resp.Result = f.Result
+		resp.Error = f.Error
+		return resp
+	}
+
+	var result interface{}
+	switch req.Method {
+	case "eth_chainId":
+		result = hexutil.Uint64(s.chainID)
+	case "eth_blockNumber":
+		result = hexutil.Uint64(s.blockNumber)
+	default:
+		resp.Error = &RPCError{
+			Code:    -32601,
+			Message: fmt.Sprintf("%T: no fixture for method %s with params %s", s, req.Method, req.Params),
+		}
+		return resp
+	}
+
+	buf, err := json.Marshal(result)
+	if err != nil {
+		resp.Error = &RPCError{Code: -32603, Message: err.Error()}
+		return resp
+	}
+	resp.Result = buf
+	return resp
+}
+
+// rpcRequest is a single JSON-RPC request.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a single JSON-RPC response.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// An RPCError is a JSON-RPC error object.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// parseRPCMessages parses either a single JSON-RPC message or a batch thereof,
+// reporting which it was.
+func parseRPCMessages[T any](buf []byte) (_ []*T, batch bool, _ error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) > 0 && buf[0] == '[' {
+		var msgs []*T
+		if err := json.Unmarshal(buf, &msgs); err != nil {
+			return nil, false, fmt.Errorf("json.Unmarshal(…, %T): %v", &msgs, err)
+		}
+		return msgs, true, nil
+	}
+
+	msg := new(T)
+	if err := json.Unmarshal(buf, msg); err != nil {
+		return nil, false, fmt.Errorf("json.Unmarshal(…, %T): %v", msg, err)
+	}
+	return []*T{msg}, false, nil
+}
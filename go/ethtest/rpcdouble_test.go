@@ -0,0 +1,95 @@
+package ethtest
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// chainState is a summary of the state returned by an RPC endpoint.
+type chainState struct {
+	chainID, blockNumber uint64
+	balance              *big.Int
+}
+
+func fetchState(ctx context.Context, t *testing.T, url string, addr common.Address) chainState {
+	t.Helper()
+
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		t.Fatalf("ethclient.DialContext(ctx, %q) error %v", url, err)
+	}
+	t.Cleanup(client.Close)
+
+	id, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("%T.ChainID() error %v", client, err)
+	}
+	num, err := client.BlockNumber(ctx)
+	if err != nil {
+		t.Fatalf("%T.BlockNumber() error %v", client, err)
+	}
+	bal, err := client.BalanceAt(ctx, addr, big.NewInt(int64(num)))
+	if err != nil {
+		t.Fatalf("%T.BalanceAt(%v, %d) error %v", client, addr, num, err)
+	}
+	return chainState{id.Uint64(), num, bal}
+}
+
+func TestRPCStub(t *testing.T) {
+	ctx := context.Background()
+	addr := common.HexToAddress("0xa11ce")
+
+	upstream := new(Fixtures)
+	upstream.Add(&Fixture{
+		Method: "eth_getBalance",
+		Params: json.RawMessage(`[ "` + strings.ToLower(addr.Hex()) + `", "0x2a" ]`), // whitespace is ignored
+		Result: json.RawMessage(`"0x1234"`),
+	})
+	want := chainState{1337, 42, big.NewInt(0x1234)}
+
+	url := NewRPCStub(want.chainID, want.blockNumber).WithFixtures(upstream).ServeHTTP(t)
+	if got := fetchState(ctx, t, url, addr); got.chainID != want.chainID || got.blockNumber != want.blockNumber || got.balance.Cmp(want.balance) != 0 {
+		t.Fatalf("fetchState([RPCStub]) got %+v; want %+v", got, want)
+	}
+
+	golden := filepath.Join(t.TempDir(), "golden.json")
+	t.Run("record", func(t *testing.T) {
+		t.Setenv(RecordRPCEnvVar, url)
+		recURL := RecordOrReplayRPC(t, golden)
+		if got := fetchState(ctx, t, recURL, addr); got.chainID != want.chainID || got.blockNumber != want.blockNumber || got.balance.Cmp(want.balance) != 0 {
+			t.Errorf("fetchState([RPCRecorder]) got %+v; want %+v", got, want)
+		}
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		fs, err := LoadFixtures(golden)
+		if err != nil {
+			t.Fatalf("LoadFixtures(%q) error %v", golden, err)
+		}
+		if got, want := fs.Len(), 3; got != want {
+			t.Errorf("LoadFixtures([recorded golden]).Len() got %d; want %d (chain ID, block number, and balance)", got, want)
+		}
+
+		t.Setenv(RecordRPCEnvVar, "")
+		replayURL := RecordOrReplayRPC(t, golden)
+		if got := fetchState(ctx, t, replayURL, addr); got.chainID != want.chainID || got.blockNumber != want.blockNumber || got.balance.Cmp(want.balance) != 0 {
+			t.Errorf("fetchState([replayed]) got %+v; want %+v", got, want)
+		}
+
+		client, err := ethclient.DialContext(ctx, replayURL)
+		if err != nil {
+			t.Fatalf("ethclient.DialContext(ctx, %q) error %v", replayURL, err)
+		}
+		defer client.Close()
+		if _, err := client.BalanceAt(ctx, common.HexToAddress("0xb0b"), nil); err == nil {
+			t.Errorf("%T.BalanceAt([unrecorded address]) got nil error; want non-nil", client)
+		}
+	})
+}
@@ -32,6 +32,10 @@ type SimulatedBackend struct {
 	// See comment on MockedEntity.
 	mockAccounts map[MockedEntity]*bind.TransactOpts
 
+	// See Fund() and NamedAccount().
+	faucet *bind.TransactOpts
+	named  map[string]*bind.TransactOpts
+
 	coverageReport func() []byte
 }
 
@@ -47,6 +51,7 @@ func NewSimulatedBackend(numAccounts int) (*SimulatedBackend, error) {
 	sb := &SimulatedBackend{
 		AutoCommit:   true,
 		mockAccounts: make(map[MockedEntity]*bind.TransactOpts),
+		named:        make(map[string]*bind.TransactOpts),
 	}
 	alloc := make(core.GenesisAlloc)
 
@@ -59,15 +64,9 @@ func NewSimulatedBackend(numAccounts int) (*SimulatedBackend, error) {
 	}
 
 	createAccount := func(seed []byte) (*bind.TransactOpts, *ecdsa.PrivateKey, error) {
-		// This is a bit stupid but it works
-		key, err := crypto.HexToECDSA(common.Bytes2Hex(crypto.Keccak256(seed)))
-		if err != nil {
-			return nil, nil, fmt.Errorf("crypto.HexToECDSA([deterministic entropy; Keccak256(%q)]): %v", seed, err)
-		}
-
-		txOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+		txOpts, key, err := deterministicAccount(seed)
 		if err != nil {
-			return nil, nil, fmt.Errorf("NewKeyedTransactorWithChainID(<new key>, sim-backend-id=1337): %v", err)
+			return nil, nil, err
 		}
 		alloc[txOpts.From] = core.GenesisAccount{
 			Balance: eth.Ether(100),
@@ -94,6 +93,15 @@ func NewSimulatedBackend(numAccounts int) (*SimulatedBackend, error) {
 		sb.mockAccounts[mock] = txOpts
 	}
 
+	faucet, _, err := createAccount([]byte("faucet"))
+	if err != nil {
+		return nil, err
+	}
+	alloc[faucet.From] = core.GenesisAccount{
+		Balance: new(big.Int).Mul(eth.Ether(1e9), big.NewInt(1e9)),
+	}
+	sb.faucet = faucet
+
 	sb.SimulatedBackend = backends.NewSimulatedBackend(alloc, 3e7)
 
 	sb.AdjustTime(365 * 24 * time.Hour)
@@ -107,6 +115,22 @@ func NewSimulatedBackend(numAccounts int) (*SimulatedBackend, error) {
 	return sb, nil
 }
 
+// deterministicAccount returns a TransactOpts, and its private key, derived
+// from the seed.
+func deterministicAccount(seed []byte) (*bind.TransactOpts, *ecdsa.PrivateKey, error) {
+	// This is a bit stupid but it works
+	key, err := crypto.HexToECDSA(common.Bytes2Hex(crypto.Keccak256(seed)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("crypto.HexToECDSA([deterministic entropy; Keccak256(%q)]): %v", seed, err)
+	}
+
+	txOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	if err != nil {
+		return nil, nil, fmt.Errorf("NewKeyedTransactorWithChainID(<new key>, sim-backend-id=1337): %v", err)
+	}
+	return txOpts, key, nil
+}
+
 // NewSimulatedBackendTB calls NewSimulatedBackend(), reports any errors with
 // tb.Fatal, and calls Close() with tb.Cleanup().
 func NewSimulatedBackendTB(tb testing.TB, numAccounts int) *SimulatedBackend {
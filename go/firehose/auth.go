@@ -0,0 +1,376 @@
+package firehose
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+)
+
+// APIKeyMetadata is the gRPC metadata key from which an Authenticator reads
+// API keys. An "authorization: Bearer <key>" header is also accepted.
+const APIKeyMetadata = "x-api-key"
+
+// A ClientConfig identifies a single client of the hydrant service and limits
+// its usage.
+type ClientConfig struct {
+	// ID is a unique, non-secret identifier used in audit logs and metrics.
+	ID string `json:"id"`
+	// APIKeys and CertCommonNames authenticate the client by API key or by the
+	// subject common name of a verified TLS client certificate (mTLS)
+	// respectively. At least one MUST be non-empty.
+	APIKeys         []string `json:"api_keys"`
+	CertCommonNames []string `json:"cert_common_names"`
+
+	// MaxStreams is the maximum number of concurrent streams, and MaxBlocks
+	// the maximum number of blocks sent per AuthConfig.QuotaWindow, across
+	// all streams. Zero values are unlimited.
+	MaxStreams int    `json:"max_streams"`
+	MaxBlocks  uint64 `json:"max_blocks"`
+}
+
+// AuthConfig configures an Authenticator. Only Clients can be parsed from
+// JSON.
+type AuthConfig struct {
+	Clients []ClientConfig `json:"clients"`
+
+	// QuotaWindow is the period after which block quotas are reset. If zero,
+	// quotas are never reset.
+	QuotaWindow time.Duration `json:"-"`
+	// AuditLog, if non-nil, receives a JSON object, on its own line, for every
+	// call, including those that fail authentication. Otherwise the same
+	// records are logged at Info level; see ethlog.
+	AuditLog io.Writer `json:"-"`
+}
+
+// An Authenticator authenticates calls to a gRPC server by API key or mTLS,
+// enforces per-client quotas, and writes an audit log of all calls. Its
+// ServerOptions() must be passed to grpc.NewServer(); mTLS additionally
+// requires server credentials that verify client certificates, e.g. with
+// tls.Config.ClientAuth = tls.RequireAndVerifyClientCert.
+type Authenticator struct {
+	window time.Duration
+	now    func() time.Time
+
+	byKey map[[sha256.Size]byte]*clientState
+	byCN  map[string]*clientState
+
+	auditMu sync.Mutex
+	audit   io.Writer
+}
+
+// NewAuthenticator validates the config and returns a new Authenticator.
+func NewAuthenticator(cfg AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		window: cfg.QuotaWindow,
+		now:    time.Now,
+		byKey:  make(map[[sha256.Size]byte]*clientState),
+		byCN:   make(map[string]*clientState),
+		audit:  cfg.AuditLog,
+	}
+
+	ids := make(map[string]bool)
+	for _, c := range cfg.Clients {
+		switch {
+		case c.ID == "":
+			return nil, fmt.Errorf("%T with empty ID", c)
+		case ids[c.ID]:
+			return nil, fmt.Errorf("duplicate %T ID %q", c, c.ID)
+		case len(c.APIKeys) == 0 && len(c.CertCommonNames) == 0:
+			return nil, fmt.Errorf("%T %q has neither API keys nor certificate common names", c, c.ID)
+		}
+		ids[c.ID] = true
+
+		s := &clientState{ClientConfig: c}
+		for _, k := range c.APIKeys {
+			h := sha256.Sum256([]byte(k))
+			if k == "" || a.byKey[h] != nil {
+				return nil, fmt.Errorf("%T %q has empty or duplicate API key", c, c.ID)
+			}
+			a.byKey[h] = s
+		}
+		for _, cn := range c.CertCommonNames {
+			if cn == "" || a.byCN[cn] != nil {
+				return nil, fmt.Errorf("%T %q has empty or duplicate certificate common name %q", c, c.ID, cn)
+			}
+			a.byCN[cn] = s
+		}
+	}
+	return a, nil
+}
+
+// ParseAuthConfig parses JSON of the form {"clients": [<ClientConfig>…]}, as
+// would typically be stored in a secrets.Secret.
+func ParseAuthConfig(buf []byte) (*AuthConfig, error) {
+	cfg := new(AuthConfig)
+	dec := json.NewDecoder(strings.NewReader(string(buf)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("json.Decode(…, %T): %v", cfg, err)
+	}
+	return cfg, nil
+}
+
+// ServerOptions returns the grpc.ServerOptions that install the
+// Authenticator's interceptors. Chained interceptors are used so others can
+// still be provided; those installed before the Authenticator receive
+// unauthenticated calls.
+func (a *Authenticator) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(a.unary),
+		grpc.ChainStreamInterceptor(a.stream),
+	}
+}
+
+type clientIDKey struct{}
+
+// ClientIDFromContext returns the ClientConfig.ID of the client authenticated
+// by an Authenticator, and whether one was found.
+func ClientIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(clientIDKey{}).(string)
+	return id, ok
+}
+
+// clientState tracks a single client's usage against its quotas.
+type clientState struct {
+	ClientConfig
+
+	mu          sync.Mutex
+	streams     int
+	blocks      uint64
+	windowStart time.Time
+}
+
+// authenticate returns the client identified by the credentials in ctx.
+func (a *Authenticator) authenticate(ctx context.Context) (*clientState, error) {
+	if key, ok := apiKey(ctx); ok {
+		c, ok := a.byKey[sha256.Sum256([]byte(key))]
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		return c, nil
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 && len(info.State.VerifiedChains[0]) > 0 {
+			cn := info.State.VerifiedChains[0][0].Subject.CommonName
+			c, ok := a.byCN[cn]
+			if !ok {
+				return nil, status.Errorf(codes.Unauthenticated, "unknown client certificate %q", cn)
+			}
+			return c, nil
+		}
+	}
+
+	return nil, status.Errorf(codes.Unauthenticated, "missing %s metadata or verified client certificate", APIKeyMetadata)
+}
+
+// apiKey returns the API key from the incoming metadata, if any.
+func apiKey(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	if k := md.Get(APIKeyMetadata); len(k) > 0 {
+		return k[0], true
+	}
+	for _, v := range md.Get("authorization") {
+		if k, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// startStream reserves one of the client's concurrent streams, returning a
+// function to release it.
+func (c *clientState) startStream() (func(), error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.MaxStreams > 0 && c.streams >= c.MaxStreams {
+		return nil, status.Errorf(codes.ResourceExhausted, "client %q at quota of %d concurrent streams", c.ID, c.MaxStreams)
+	}
+	c.streams++
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.streams--
+	}, nil
+}
+
+// takeBlock consumes one of the client's blocks for the current quota window.
+func (c *clientState) takeBlock(now time.Time, window time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if window > 0 && now.Sub(c.windowStart) >= window {
+		c.windowStart = now
+		c.blocks = 0
+	}
+	if c.MaxBlocks > 0 && c.blocks >= c.MaxBlocks {
+		return status.Errorf(codes.ResourceExhausted, "client %q at quota of %d blocks", c.ID, c.MaxBlocks)
+	}
+	c.blocks++
+	return nil
+}
+
+// An auditRecord is a single entry in the audit log.
+type auditRecord struct {
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	Client   string          `json:"client,omitempty"`
+	Peer     string          `json:"peer,omitempty"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Code     string          `json:"code"`
+	Error    string          `json:"error,omitempty"`
+	Blocks   uint64          `json:"blocks,omitempty"`
+	Duration float64         `json:"duration_seconds"`
+}
+
+// newAuditRecord returns a record for a call to the method, starting now.
+func (a *Authenticator) newAuditRecord(ctx context.Context, method string) *auditRecord {
+	r := &auditRecord{
+		Time:   a.now(),
+		Method: method,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.Peer = p.Addr.String()
+	}
+	return r
+}
+
+// setRequest records the request, typically an *svcpb.EventsRequest, as hex
+// JSON so that addresses are human-readable.
+func (r *auditRecord) setRequest(m interface{}) {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return
+	}
+	buf, err := ethpb.MarshalHexJSON(msg)
+	if err != nil {
+		logger.Warning("Audit log: marshalling request", ethlog.String("type", fmt.Sprintf("%T", msg)), ethlog.Err(err))
+		return
+	}
+	r.Request = buf
+}
+
+// write completes and writes the record to the audit log.
+func (a *Authenticator) write(r *auditRecord, err error) {
+	r.Duration = a.now().Sub(r.Time).Seconds()
+	r.Code = status.Code(err).String()
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	buf, mErr := json.Marshal(r)
+	if mErr != nil {
+		logger.Error("Audit log: marshalling record", ethlog.Err(mErr))
+		return
+	}
+	if a.audit == nil {
+		logger.Info("Audit", ethlog.String("record", string(buf)))
+		return
+	}
+
+	a.auditMu.Lock()
+	defer a.auditMu.Unlock()
+	if _, err := a.audit.Write(append(buf, '\n')); err != nil {
+		logger.Error("Writing audit log", ethlog.Err(err))
+	}
+}
+
+func (a *Authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, retErr error) {
+	rec := a.newAuditRecord(ctx, info.FullMethod)
+	rec.setRequest(req)
+	defer func() { a.write(rec, retErr) }()
+
+	c, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rec.Client = c.ID
+	return handler(context.WithValue(ctx, clientIDKey{}, c.ID), req)
+}
+
+func (a *Authenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (retErr error) {
+	rec := a.newAuditRecord(ss.Context(), info.FullMethod)
+	as := &authedStream{
+		ServerStream: ss,
+		a:            a,
+		rec:          rec,
+	}
+	defer func() {
+		rec.Blocks = as.blocks
+		a.write(rec, retErr)
+	}()
+
+	c, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	rec.Client = c.ID
+	as.client = c
+	as.ctx = context.WithValue(ss.Context(), clientIDKey{}, c.ID)
+
+	release, err := c.startStream()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return handler(srv, as)
+}
+
+// An authedStream is a grpc.ServerStream of an authenticated client. It
+// records the first received message in the audit log and enforces the
+// client's block quota.
+type authedStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	a      *Authenticator
+	client *clientState
+	rec    *auditRecord
+
+	recvd  bool
+	blocks uint64
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if !s.recvd {
+		s.recvd = true
+		s.rec.setRequest(m)
+	}
+	return nil
+}
+
+func (s *authedStream) SendMsg(m interface{}) error {
+	if _, ok := m.(*svcpb.BlockResponse); ok {
+		if err := s.client.takeBlock(s.a.now(), s.a.window); err != nil {
+			return err
+		}
+		s.blocks++
+	}
+	return s.ServerStream.SendMsg(m)
+}
@@ -0,0 +1,289 @@
+package firehose_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/cxkoda/solgo/go/grpctest"
+	"github.com/cxkoda/solgo/projects/indexing/firehose"
+
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+)
+
+// blockSender is a HydrantServiceServer that sends numBlocks empty blocks
+// in response to Events() and records the authenticated client IDs. If
+// non-nil, it blocks on release before returning.
+type blockSender struct {
+	svcpb.UnimplementedHydrantServiceServer
+	numBlocks int
+	release   chan struct{}
+
+	mu      sync.Mutex
+	clients []string
+}
+
+func (s *blockSender) Events(req *svcpb.EventsRequest, srv svcpb.HydrantService_EventsServer) error {
+	id, _ := firehose.ClientIDFromContext(srv.Context())
+	s.mu.Lock()
+	s.clients = append(s.clients, id)
+	s.mu.Unlock()
+
+	for i := 0; i < s.numBlocks; i++ {
+		if err := srv.Send(&svcpb.BlockResponse{}); err != nil {
+			return err
+		}
+	}
+	if s.release != nil {
+		<-s.release
+	}
+	return nil
+}
+
+func newAuthedClient(t *testing.T, impl *blockSender, cfg firehose.AuthConfig) svcpb.HydrantServiceClient {
+	t.Helper()
+	auth, err := firehose.NewAuthenticator(cfg)
+	if err != nil {
+		t.Fatalf("NewAuthenticator(%+v) error %v", cfg, err)
+	}
+	register := func(s *grpc.Server, impl svcpb.HydrantServiceServer) {
+		svcpb.RegisterHydrantServiceServer(s, impl)
+	}
+	conn := grpctest.NewClientConnTB[svcpb.HydrantServiceServer](t, register, impl, auth.ServerOptions()...)
+	return svcpb.NewHydrantServiceClient(conn)
+}
+
+// countBlocks calls Events(), returning the number of blocks received and the
+// stream's final error, which is nil on io.EOF.
+func countBlocks(ctx context.Context, t *testing.T, client svcpb.HydrantServiceClient, req *svcpb.EventsRequest) (int, error) {
+	t.Helper()
+	stream, err := client.Events(ctx, req)
+	if err != nil {
+		t.Fatalf("Events() error %v", err)
+	}
+	got, err := grpctest.RecvAll[*svcpb.BlockResponse](stream)
+	return len(got), err
+}
+
+func withKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, firehose.APIKeyMetadata, key)
+}
+
+func TestAuthenticator(t *testing.T) {
+	ctx := context.Background()
+
+	impl := &blockSender{numBlocks: 3}
+	audit := new(bytes.Buffer)
+	client := newAuthedClient(t, impl, firehose.AuthConfig{
+		Clients: []firehose.ClientConfig{
+			{ID: "unlimited", APIKeys: []string{"key-a"}},
+			{ID: "limited", APIKeys: []string{"key-b", "key-b2"}, MaxBlocks: 5},
+		},
+		AuditLog: audit,
+	})
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantBlocks int
+		wantCode   codes.Code
+	}{
+		{
+			name:     "no credentials",
+			ctx:      ctx,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "invalid key",
+			ctx:      withKey(ctx, "nope"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:       "valid key",
+			ctx:        withKey(ctx, "key-a"),
+			wantBlocks: 3,
+		},
+		{
+			name:       "bearer token",
+			ctx:        metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer key-a"),
+			wantBlocks: 3,
+		},
+		{
+			name:       "within block quota",
+			ctx:        withKey(ctx, "key-b"),
+			wantBlocks: 3,
+		},
+		{
+			name:       "block quota exhausted by other key of same client",
+			ctx:        withKey(ctx, "key-b2"),
+			wantBlocks: 2,
+			wantCode:   codes.ResourceExhausted,
+		},
+		{
+			name:     "block quota remains exhausted",
+			ctx:      withKey(ctx, "key-b"),
+			wantCode: codes.ResourceExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := countBlocks(tt.ctx, t, client, &svcpb.EventsRequest{StartBlockNum: 42})
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("Events() got err %v; want code %v", err, tt.wantCode)
+			}
+			if got != tt.wantBlocks {
+				t.Errorf("Events() got %d blocks; want %d", got, tt.wantBlocks)
+			}
+		})
+	}
+
+	wantClients := []string{"unlimited", "unlimited", "limited", "limited", "limited"}
+	if diff := cmp.Diff(wantClients, impl.clients); diff != "" {
+		t.Errorf("ClientIDFromContext() in handler diff (-want +got):\n%s", diff)
+	}
+
+	t.Run("audit log", func(t *testing.T) {
+		type record struct {
+			Client  string
+			Code    string
+			Blocks  uint64
+			Request struct {
+				StartBlockNum string
+			}
+		}
+		var got []record
+		for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+			var r record
+			if err := json.Unmarshal([]byte(line), &r); err != nil {
+				t.Fatalf("json.Unmarshal(%q) error %v", line, err)
+			}
+			got = append(got, r)
+		}
+
+		// Requests aren't received if authentication fails.
+		withReq := func(r record) record {
+			r.Request.StartBlockNum = "42"
+			return r
+		}
+		want := []record{
+			{Code: "Unauthenticated"},
+			{Code: "Unauthenticated"},
+			withReq(record{Client: "unlimited", Code: "OK", Blocks: 3}),
+			withReq(record{Client: "unlimited", Code: "OK", Blocks: 3}),
+			withReq(record{Client: "limited", Code: "OK", Blocks: 3}),
+			withReq(record{Client: "limited", Code: "ResourceExhausted", Blocks: 2}),
+			withReq(record{Client: "limited", Code: "ResourceExhausted"}),
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("audit log diff (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestAuthenticatorStreamQuota(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	impl := &blockSender{numBlocks: 1, release: make(chan struct{})}
+	client := newAuthedClient(t, impl, firehose.AuthConfig{
+		Clients: []firehose.ClientConfig{
+			{ID: "client", APIKeys: []string{"key"}, MaxStreams: 1},
+		},
+	})
+	ctx = withKey(ctx, "key")
+
+	first, err := client.Events(ctx, &svcpb.EventsRequest{})
+	if err != nil {
+		t.Fatalf("Events() error %v", err)
+	}
+	// Receiving the block guarantees that the first stream is active.
+	if _, err := first.Recv(); err != nil {
+		t.Fatalf("Events().Recv() error %v", err)
+	}
+
+	if _, err := countBlocks(ctx, t, client, &svcpb.EventsRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Events() with another stream active got err %v; want code %v", err, codes.ResourceExhausted)
+	}
+
+	impl.release <- struct{}{}
+	if _, err := grpctest.RecvAll[*svcpb.BlockResponse](first); err != nil {
+		t.Fatalf("Events() first stream error %v", err)
+	}
+
+	go func() { impl.release <- struct{}{} }()
+	if _, err := countBlocks(ctx, t, client, &svcpb.EventsRequest{}); err != nil {
+		t.Errorf("Events() after first stream ended got err %v; want nil", err)
+	}
+}
+
+func TestNewAuthenticatorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients []firehose.ClientConfig
+	}{
+		{
+			name:    "empty ID",
+			clients: []firehose.ClientConfig{{APIKeys: []string{"k"}}},
+		},
+		{
+			name: "duplicate ID",
+			clients: []firehose.ClientConfig{
+				{ID: "a", APIKeys: []string{"k"}},
+				{ID: "a", APIKeys: []string{"k2"}},
+			},
+		},
+		{
+			name:    "no credentials",
+			clients: []firehose.ClientConfig{{ID: "a"}},
+		},
+		{
+			name: "shared key",
+			clients: []firehose.ClientConfig{
+				{ID: "a", APIKeys: []string{"k"}},
+				{ID: "b", APIKeys: []string{"k"}},
+			},
+		},
+		{
+			name: "shared common name",
+			clients: []firehose.ClientConfig{
+				{ID: "a", CertCommonNames: []string{"cn"}},
+				{ID: "b", CertCommonNames: []string{"cn"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := firehose.NewAuthenticator(firehose.AuthConfig{Clients: tt.clients}); err == nil {
+				t.Errorf("NewAuthenticator(%+v) got nil error; want non-nil", tt.clients)
+			}
+		})
+	}
+}
+
+func TestParseAuthConfig(t *testing.T) {
+	got, err := firehose.ParseAuthConfig([]byte(`{"clients": [{"id": "a", "api_keys": ["k"], "max_streams": 2, "max_blocks": 100}]}`))
+	if err != nil {
+		t.Fatalf("ParseAuthConfig() error %v", err)
+	}
+	want := &firehose.AuthConfig{
+		Clients: []firehose.ClientConfig{{ID: "a", APIKeys: []string{"k"}, MaxStreams: 2, MaxBlocks: 100}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseAuthConfig() diff (-want +got):\n%s", diff)
+	}
+
+	if _, err := firehose.ParseAuthConfig([]byte(`{"clients": [{"id": "a", "apikeys": ["k"]}]}`)); err == nil {
+		t.Errorf("ParseAuthConfig() with unknown field got nil error; want non-nil")
+	}
+}
@@ -0,0 +1,367 @@
+package firehose
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+)
+
+// A BufferPolicy determines how a BufferBlocks option behaves when its buffer
+// is full.
+type BufferPolicy int
+
+const (
+	// BlockOnFull stops receiving from the Firehose stream until the consumer
+	// makes room in the buffer. No blocks are lost but a consistently slow
+	// consumer will still stall the stream.
+	BlockOnFull BufferPolicy = iota
+	// DropOldest discards the oldest buffered block to make room for the
+	// newest one. This is only appropriate for consumers that care about the
+	// head of the chain, not every block; in particular, a dropped block's
+	// cursor is never acknowledged so it SHOULD NOT be combined with
+	// PersistCursor unless loss is acceptable.
+	DropOldest
+	// SpillToDisk writes blocks that don't fit in the buffer to a temporary
+	// file, from which they are delivered in order once the consumer catches
+	// up. No blocks are lost and the stream is never stalled, at the cost of
+	// unbounded disk usage.
+	SpillToDisk
+)
+
+// String returns the name of the policy, as used in metric attributes.
+func (p BufferPolicy) String() string {
+	switch p {
+	case BlockOnFull:
+		return "block_on_full"
+	case DropOldest:
+		return "drop_oldest"
+	case SpillToDisk:
+		return "spill_to_disk"
+	default:
+		return fmt.Sprintf("BufferPolicy(%d)", int(p))
+	}
+}
+
+// BufferBlocks is a grpc.CallOption that, when passed to the Events methods of
+// a client returned by ETHClient(), buffers up to Size BlockResponses between
+// the Firehose stream and Recv(). Without it, every block is handed directly
+// to the consumer so a slow consumer stalls the stream, which can trigger
+// server-side disconnects.
+//
+// Blocks are buffered after being filtered by MonotonicBlocks, if also
+// provided. The DropOldest and SpillToDisk policies require a positive Size.
+// As with MonotonicBlocks, BufferBlocks is ignored by other clients and it
+// MUST NOT be copied once used.
+type BufferBlocks struct {
+	grpc.EmptyCallOption
+
+	Size   int
+	Policy BufferPolicy
+	// Dir is the directory in which SpillToDisk creates its temporary file. If
+	// empty, os.TempDir() is used.
+	Dir string
+
+	dropped, spilled atomic.Uint64
+}
+
+// Dropped returns the number of blocks discarded by the DropOldest policy.
+func (b *BufferBlocks) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// Spilled returns the number of blocks written to disk by the SpillToDisk
+// policy.
+func (b *BufferBlocks) Spilled() uint64 {
+	return b.spilled.Load()
+}
+
+// bufferOption returns the last *BufferBlocks in opts, or nil if there are
+// none.
+func bufferOption(opts []grpc.CallOption) *BufferBlocks {
+	var buf *BufferBlocks
+	for _, o := range opts {
+		if b, ok := o.(*BufferBlocks); ok {
+			buf = b
+		}
+	}
+	return buf
+}
+
+// A blockBuffer connects ethClient.events() to ethAdaptor.Recv(). All blocks
+// are received on the `blocks` channel, which MUST only be closed after
+// flush() returns.
+type blockBuffer struct {
+	blocks chan *svcpb.BlockResponse
+	// push is called for every block to be delivered.
+	push func(context.Context, *svcpb.BlockResponse) error
+	// flush is called after the last call to push() and blocks until all
+	// pushed blocks are on the `blocks` channel or the Context passed to
+	// newBuffer() is cancelled.
+	flush func() error
+}
+
+// newBuffer returns a blockBuffer implementing b's policy. A nil b results in
+// an unbuffered channel, with push() blocking until each block is received.
+// Any goroutines started by newBuffer() exit once flush() has returned or ctx
+// is cancelled.
+func (b *BufferBlocks) newBuffer(ctx context.Context) (*blockBuffer, error) {
+	if b == nil {
+		b = &BufferBlocks{Policy: BlockOnFull}
+	}
+	if b.Size < 0 || (b.Size == 0 && b.Policy != BlockOnFull) {
+		return nil, fmt.Errorf("%T with policy %v and invalid size %d", b, b.Policy, b.Size)
+	}
+
+	ch := make(chan *svcpb.BlockResponse, b.Size)
+	buf := &blockBuffer{
+		blocks: ch,
+		flush:  func() error { return nil },
+	}
+	attrs := metric.WithAttributes(attribute.Stringer("policy", b.Policy))
+
+	switch b.Policy {
+	case BlockOnFull:
+		buf.push = func(ctx context.Context, blk *svcpb.BlockResponse) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ch <- blk:
+				return nil
+			}
+		}
+
+	case DropOldest:
+		buf.push = func(ctx context.Context, blk *svcpb.BlockResponse) error {
+			// The pushing goroutine is the only sender so, after receiving a
+			// block, there is guaranteed to be room for another.
+			for {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				select {
+				case ch <- blk:
+					return nil
+				default:
+				}
+				select {
+				case old := <-ch:
+					logger.Debug("Buffer full; dropping block", ethlog.Block(old.GetBlock().GetNumber()))
+					b.dropped.Add(1)
+					metrics().bufferDropped.Add(ctx, 1, attrs)
+				default:
+				}
+			}
+		}
+
+	case SpillToDisk:
+		f, err := os.CreateTemp(b.Dir, "firehose-spill-*")
+		if err != nil {
+			return nil, fmt.Errorf("os.CreateTemp(%q, …): %v", b.Dir, err)
+		}
+		s := &spillFile{
+			file:   f,
+			out:    ch,
+			notify: make(chan struct{}, 1),
+			done:   make(chan struct{}),
+		}
+		go s.run(ctx)
+
+		buf.push = func(ctx context.Context, blk *svcpb.BlockResponse) error {
+			spilled, err := s.push(blk)
+			if spilled {
+				b.spilled.Add(1)
+				metrics().bufferSpilled.Add(ctx, 1, attrs)
+			}
+			return err
+		}
+		buf.flush = s.flush
+
+	default:
+		return nil, fmt.Errorf("unsupported %T %v", b.Policy, b.Policy)
+	}
+
+	return buf, nil
+}
+
+// A spillFile is the overflow of a buffered channel, stored on disk as
+// length-prefixed, marshalled BlockResponses. Blocks are moved from the file
+// to the channel by a goroutine running s.run().
+type spillFile struct {
+	file *os.File
+	out  chan<- *svcpb.BlockResponse
+	// notify is signalled, without blocking, whenever a block is spilled or
+	// the spillFile is closed.
+	notify chan struct{}
+	// done is closed when run() returns, after which runErr is valid.
+	done   chan struct{}
+	runErr error
+
+	mu sync.Mutex
+	// pending is the number of spilled blocks that are yet to be sent on out,
+	// including one that is in the process of being sent. While pending is
+	// non-zero, all pushed blocks MUST be spilled to maintain their order.
+	pending  int
+	writeOff int64
+	closed   bool
+	// err, if non-nil, is returned by all future calls to push().
+	err error
+
+	// readOff is only accessed by run().
+	readOff int64
+}
+
+// push sends blk on s.out if there is room and nothing is pending, otherwise
+// it appends it to the file. It reports whether the block was spilled.
+func (s *spillFile) push(blk *svcpb.BlockResponse) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return false, s.err
+	}
+	if s.pending == 0 {
+		select {
+		case s.out <- blk:
+			return false, nil
+		default:
+		}
+	}
+
+	buf, err := proto.Marshal(blk)
+	if err != nil {
+		return false, fmt.Errorf("proto.Marshal(%T): %v", blk, err)
+	}
+	rec := binary.AppendUvarint(nil, uint64(len(buf)))
+	rec = append(rec, buf...)
+	if _, err := s.file.WriteAt(rec, s.writeOff); err != nil {
+		s.err = fmt.Errorf("%T.WriteAt(…) spilling block: %v", s.file, err)
+		return false, s.err
+	}
+	s.writeOff += int64(len(rec))
+	s.pending++
+	s.signal()
+	return true, nil
+}
+
+// signal notifies run() of a change in state, without blocking.
+func (s *spillFile) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// flush MUST be called after the last call to push(). It waits for all
+// spilled blocks to be sent on s.out, or for the Context passed to run() to be
+// cancelled, and then removes the file.
+func (s *spillFile) flush() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.signal()
+
+	<-s.done
+	s.file.Close()
+	if err := os.Remove(s.file.Name()); err != nil {
+		logger.Warning("Removing spill file", ethlog.Err(err))
+	}
+	return s.runErr
+}
+
+// run sends spilled blocks on s.out, in order, until flush() is called and
+// there are none pending, or ctx is cancelled.
+func (s *spillFile) run(ctx context.Context) {
+	defer close(s.done)
+	if err := s.relay(ctx); err != nil {
+		s.runErr = err
+		s.mu.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.mu.Unlock()
+	}
+}
+
+// relay implements run(), returning the reason for stopping, if any.
+func (s *spillFile) relay(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		pending, closed := s.pending, s.closed
+		if pending == 0 && s.writeOff > 0 {
+			// Everything has been delivered so the file can be reused from
+			// the start, which bounds its size to the peak backlog.
+			if err := s.file.Truncate(0); err != nil {
+				s.mu.Unlock()
+				return fmt.Errorf("%T.Truncate(0): %v", s.file, err)
+			}
+			s.writeOff, s.readOff = 0, 0
+		}
+		s.mu.Unlock()
+
+		if pending == 0 {
+			if closed {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.notify:
+				continue
+			}
+		}
+
+		// Records before writeOff are complete and never modified, so they
+		// can be read without holding the lock.
+		blk, next, err := readSpilled(s.file, s.readOff)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s.out <- blk:
+		}
+		s.readOff = next
+
+		s.mu.Lock()
+		s.pending--
+		s.mu.Unlock()
+	}
+}
+
+// readSpilled reads the length-prefixed BlockResponse at off, returning it and
+// the offset of the next one.
+func readSpilled(r io.ReaderAt, off int64) (*svcpb.BlockResponse, int64, error) {
+	var hdr [binary.MaxVarintLen64]byte
+	n, err := r.ReadAt(hdr[:], off)
+	if err != nil && !(errors.Is(err, io.EOF) && n > 0) {
+		return nil, 0, fmt.Errorf("reading spilled block length: %v", err)
+	}
+	size, k := binary.Uvarint(hdr[:n])
+	if k <= 0 {
+		return nil, 0, fmt.Errorf("corrupt spilled block length at offset %d", off)
+	}
+
+	buf := make([]byte, size)
+	if _, err := r.ReadAt(buf, off+int64(k)); err != nil {
+		return nil, 0, fmt.Errorf("reading spilled block: %v", err)
+	}
+	blk := new(svcpb.BlockResponse)
+	if err := proto.Unmarshal(buf, blk); err != nil {
+		return nil, 0, fmt.Errorf("proto.Unmarshal(…, %T): %v", blk, err)
+	}
+	return blk, off + int64(k) + int64(size), nil
+}
@@ -0,0 +1,119 @@
+package firehose_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cxkoda/solgo/projects/indexing/firehose"
+	"github.com/cxkoda/solgo/projects/indexing/firehose/firehosetest"
+
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+)
+
+func TestETHClientBufferBlocks(t *testing.T) {
+	ctx := context.Background()
+
+	fake := firehosetest.NewFake(ctx, t)
+	var all []uint64
+	for i := 0; i < 5; i++ {
+		all = append(all, fake.MineBlock(ctx, t).NumberU64())
+	}
+
+	newReq := func() *svcpb.EventsRequest {
+		return &svcpb.EventsRequest{
+			Contracts: []*ethpb.Address{{Bytes: common.HexToAddress("0x01").Bytes()}},
+		}
+	}
+
+	// waitFor polls until cond() is true, which allows the stream to overflow
+	// the buffer before any blocks are received.
+	waitFor := func(t *testing.T, desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", desc)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	const size = 2
+	tests := []struct {
+		name string
+		buf  *firehose.BufferBlocks
+		// overflowed, if non-nil, reports whether the stream has finished
+		// overflowing the buffer.
+		overflowed func(*firehose.BufferBlocks) bool
+		want       []uint64
+	}{
+		{
+			name: "block on full",
+			buf:  &firehose.BufferBlocks{Size: size, Policy: firehose.BlockOnFull},
+			want: all,
+		},
+		{
+			name: "drop oldest",
+			buf:  &firehose.BufferBlocks{Size: size, Policy: firehose.DropOldest},
+			overflowed: func(b *firehose.BufferBlocks) bool {
+				return b.Dropped() == uint64(len(all)-size)
+			},
+			want: all[len(all)-size:],
+		},
+		{
+			name: "spill to disk",
+			buf:  &firehose.BufferBlocks{Size: size, Policy: firehose.SpillToDisk, Dir: t.TempDir()},
+			overflowed: func(b *firehose.BufferBlocks) bool {
+				return b.Spilled() == uint64(len(all)-size)
+			},
+			want: all,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newReq()
+			blocks, err := fake.Client.ERC721TransferEvents(ctx, req, tt.buf)
+			if err != nil {
+				t.Fatalf("%T.Client.ERC721TransferEvents(%+v, %+v) error %v", fake, req, tt.buf, err)
+			}
+			if tt.overflowed != nil {
+				waitFor(t, "buffer overflow", func() bool { return tt.overflowed(tt.buf) })
+			}
+
+			var got []uint64
+			for _, b := range firehosetest.CollectAll(t, blocks) {
+				got = append(got, b.Block.Number)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("%T.Client.ERC721TransferEvents(…, %+v) block numbers diff (-want +got):\n%s", fake, tt.buf, diff)
+			}
+
+			if tt.buf.Dir == "" {
+				return
+			}
+			entries, err := os.ReadDir(tt.buf.Dir)
+			if err != nil {
+				t.Fatalf("os.ReadDir(%q) error %v", tt.buf.Dir, err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("Spill directory not empty after end of stream; got %d entries", len(entries))
+			}
+		})
+	}
+
+	t.Run("invalid size", func(t *testing.T) {
+		for _, p := range []firehose.BufferPolicy{firehose.DropOldest, firehose.SpillToDisk} {
+			buf := &firehose.BufferBlocks{Policy: p}
+			if _, err := fake.Client.ERC721TransferEvents(ctx, newReq(), buf); err == nil {
+				t.Errorf("%T.Client.ERC721TransferEvents(…, %T{Policy: %v, Size: 0}) got nil error; want non-nil", fake, buf, p)
+			}
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package firehose
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/cxkoda/solgo/go/dbtx"
+)
+
+// A CursorStore persists Firehose cursors, keyed by an arbitrary stream
+// identifier, such that streams can be resumed after a process restart.
+type CursorStore interface {
+	// Load returns the last cursor stored for the key, or an empty string if
+	// there is none.
+	Load(ctx context.Context, key string) (string, error)
+	// Store records the cursor for the key, replacing any existing one.
+	Store(ctx context.Context, key, cursor string) error
+}
+
+// PersistCursor is a grpc.CallOption that, when passed to the Events methods
+// of a client returned by ETHClient(), loads and stores the stream's cursor in
+// the CursorStore, under Key.
+//
+// If the EventsRequest has an empty Cursor, it is populated with the one
+// loaded from the store, if any; an explicit Cursor therefore takes
+// precedence. A BlockResponse's cursor is only stored once it has been
+// acknowledged, which is signalled by calling Recv() again, so a consumer that
+// crashes while processing a block will receive it again after restarting. As
+// with MonotonicBlocks, PersistCursor is ignored by other clients.
+//
+// If storing a cursor fails, Recv() returns the error and the stream is
+// cancelled.
+type PersistCursor struct {
+	grpc.EmptyCallOption
+
+	Store CursorStore
+	Key   string
+}
+
+// persistCursorOption returns the last *PersistCursor in opts, or nil if there
+// are none.
+func persistCursorOption(opts []grpc.CallOption) *PersistCursor {
+	var persist *PersistCursor
+	for _, o := range opts {
+		if p, ok := o.(*PersistCursor); ok {
+			persist = p
+		}
+	}
+	return persist
+}
+
+// MemCursorStore is an in-memory CursorStore, primarily intended for tests.
+// The zero value is ready to use and it MUST NOT be copied.
+type MemCursorStore struct {
+	mu      sync.Mutex
+	cursors map[string]string
+}
+
+var _ CursorStore = (*MemCursorStore)(nil)
+
+// Load implements CursorStore.Load().
+func (s *MemCursorStore) Load(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursors[key], nil
+}
+
+// Store implements CursorStore.Store().
+func (s *MemCursorStore) Store(_ context.Context, key, cursor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cursors == nil {
+		s.cursors = make(map[string]string)
+	}
+	s.cursors[key] = cursor
+	return nil
+}
+
+// A PgCursorStore is a CursorStore backed by a PostgreSQL table. All access is
+// performed in transactions, via dbtx.
+type PgCursorStore struct {
+	db    dbtx.Transactor
+	table string
+}
+
+var _ CursorStore = (*PgCursorStore)(nil)
+
+// NewPgCursorStore returns a PgCursorStore using the named table, which MAY be
+// schema-qualified. The table can be created with CreateTable().
+func NewPgCursorStore(db dbtx.Beginner, table string) (*PgCursorStore, error) {
+	if err := dbtx.ValidatePgTableName(table); err != nil {
+		return nil, err
+	}
+	return &PgCursorStore{
+		db:    dbtx.Transactor{Beginner: db},
+		table: table,
+	}, nil
+}
+
+// CreateTable creates the store's table if it doesn't already exist.
+func (s *PgCursorStore) CreateTable(ctx context.Context) error {
+	qry := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	stream_key text NOT NULL,
+	cursor text NOT NULL,
+	updated_at timestamp with time zone NOT NULL DEFAULT now(),
+	PRIMARY KEY(stream_key)
+)`, s.table)
+
+	return s.db.Do(ctx, nil, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, qry); err != nil {
+			return fmt.Errorf("creating table %q: %v", s.table, err)
+		}
+		return nil
+	})
+}
+
+// Load implements CursorStore.Load().
+func (s *PgCursorStore) Load(ctx context.Context, key string) (string, error) {
+	qry := fmt.Sprintf(`SELECT cursor FROM %s WHERE stream_key = $1`, s.table)
+
+	var cursor string
+	err := s.db.Do(ctx, &sql.TxOptions{ReadOnly: true}, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, qry, key).Scan(&cursor)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("loading cursor for %q: %v", key, err)
+		}
+		return nil
+	})
+	return cursor, err
+}
+
+// Store implements CursorStore.Store().
+func (s *PgCursorStore) Store(ctx context.Context, key, cursor string) error {
+	return s.db.Do(ctx, nil, func(tx *sql.Tx) error {
+		return s.StoreTx(ctx, tx, key, cursor)
+	})
+}
+
+// StoreTx is equivalent to Store() but uses an existing transaction, allowing
+// consumers to atomically commit the cursor alongside data derived from the
+// respective block.
+func (s *PgCursorStore) StoreTx(ctx context.Context, tx *sql.Tx, key, cursor string) error {
+	qry := fmt.Sprintf(`
+INSERT INTO %s (stream_key, cursor) VALUES ($1, $2)
+ON CONFLICT (stream_key) DO UPDATE SET cursor = EXCLUDED.cursor, updated_at = now()`, s.table)
+
+	if _, err := tx.ExecContext(ctx, qry, key, cursor); err != nil {
+		return fmt.Errorf("storing cursor for %q: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package firehose_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/cxkoda/solgo/go/spawner"
+	"github.com/cxkoda/solgo/projects/indexing/firehose"
+
+	_ "github.com/jackc/pgx/v4/stdlib" // postgres driver
+)
+
+func TestPgCursorStore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	conn := spawner.NewPostgresT(ctx, t, "15", time.Minute)
+	db, err := sql.Open("pgx", conn.Dsn)
+	if err != nil {
+		t.Fatalf("sql.Open(pgx, %T.Dsn = %q) error %v", conn, conn.Dsn, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	const table = "firehose_cursors"
+	store, err := firehose.NewPgCursorStore(db, table)
+	if err != nil {
+		t.Fatalf("firehose.NewPgCursorStore(db, %q) error %v", table, err)
+	}
+	for i := 0; i < 2; i++ { // idempotent
+		if err := store.CreateTable(ctx); err != nil {
+			t.Fatalf("%T.CreateTable() error %v", store, err)
+		}
+	}
+
+	load := func(key string) string {
+		t.Helper()
+		c, err := store.Load(ctx, key)
+		if err != nil {
+			t.Fatalf("%T.Load(%q) error %v", store, key, err)
+		}
+		return c
+	}
+
+	if got := load("a"); got != "" {
+		t.Errorf("%T.Load([unstored key]) got %q; want empty", store, got)
+	}
+
+	steps := []struct {
+		key, cursor string
+	}{
+		{"a", "a0"},
+		{"b", "b0"},
+		{"a", "a1"},
+	}
+	for _, s := range steps {
+		if err := store.Store(ctx, s.key, s.cursor); err != nil {
+			t.Fatalf("%T.Store(%q, %q) error %v", store, s.key, s.cursor, err)
+		}
+	}
+	for key, want := range map[string]string{"a": "a1", "b": "b0"} {
+		if got := load(key); got != want {
+			t.Errorf("%T.Load(%q) got %q; want %q", store, key, got, want)
+		}
+	}
+
+	t.Run("rolled-back transaction", func(t *testing.T) {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("%T.BeginTx() error %v", db, err)
+		}
+		if err := store.StoreTx(ctx, tx, "a", "a2"); err != nil {
+			t.Fatalf("%T.StoreTx(…) error %v", store, err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatalf("%T.Rollback() error %v", tx, err)
+		}
+		if got, want := load("a"), "a1"; got != want {
+			t.Errorf("%T.Load(%q) after rolled-back StoreTx() got %q; want %q", store, "a", got, want)
+		}
+	})
+
+	for _, table := range []string{"", "Cursors", "cursors; DROP TABLE x", `"quoted"`} {
+		if _, err := firehose.NewPgCursorStore(db, table); err == nil {
+			t.Errorf("firehose.NewPgCursorStore(db, %q) got nil error; want non-nil", table)
+		}
+	}
+}
@@ -7,24 +7,31 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/golang/glog"
-	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 
 	filterpb "github.com/streamingfast/firehose-ethereum/types/pb/sf/ethereum/transform/v1"
 	sfethpb "github.com/streamingfast/firehose-ethereum/types/pb/sf/ethereum/type/v2"
 	hosepb "github.com/streamingfast/pbgo/sf/firehose/v2"
 
+	"github.com/cxkoda/solgo/go/eth"
+	"github.com/cxkoda/solgo/go/eth/ethlog"
 	"github.com/cxkoda/solgo/go/secrets"
 	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
 	ethpb "github.com/cxkoda/solgo/proto/eth"
 )
 
+// logger is used for all Ethereum stream logs; see ethlog re verbosity.
+var logger = ethlog.Default()
+
 type ethHandler struct {
 	proxy *Proxy[*sfethpb.Block]
+	// workers is the number of blocks from which events are extracted
+	// concurrently; see ExtractionWorkers.
+	workers int
 }
 
 type ethServer struct {
@@ -36,11 +43,12 @@ type ethClient struct {
 }
 
 func newETHHandler(ctx context.Context, endpointURL, tokenURL, apiKey string, opts ...grpc.DialOption) (*ethHandler, func() error, error) {
+	workers, opts := extractionWorkersOption(opts)
 	proxy, err := Dial[*sfethpb.Block](ctx, endpointURL, tokenURL, apiKey, opts...)
 	if err != nil {
 		return nil, func() error { return nil }, fmt.Errorf("Dial(): %v", err)
 	}
-	return &ethHandler{proxy: proxy}, proxy.Close, nil
+	return &ethHandler{proxy: proxy, workers: workers}, proxy.Close, nil
 }
 
 // ETHServer returns a new Ethereum Hydrant service server.
@@ -80,11 +88,14 @@ func ETHClientFromSecret(ctx context.Context, chainID uint64, apiKey *secrets.Se
 	var dial func(context.Context, string, ...grpc.DialOption) (svcpb.HydrantServiceClient, func() error, error)
 
 	switch chainID {
-	case 1:
+	case eth.MainnetChainID:
 		dial = ETHMainnetClient
-	case 5:
+	case eth.GoerliChainID:
 		dial = ETHGoerliClient
 	default:
+		if c, ok := eth.ChainByID(chainID); ok {
+			return nil, nil, fmt.Errorf("unsupported chain %v", c)
+		}
 		return nil, nil, fmt.Errorf("unsupported chain ID: %d", chainID)
 	}
 
@@ -157,21 +168,26 @@ func ERC721TransferEvent() *ethpb.Event {
 // To avoid leaking a goroutine and a channel, Recv() MUST be called until it
 // returns a non-nil error, be that due to context cancellation, end of stream
 // indicated by io.EOF, or a true error.
+//
+// See MonotonicBlocks re guaranteed ordering, PersistCursor re resuming after
+// restarts, and BufferBlocks re slow consumers.
 func (c *ethClient) Events(ctx context.Context, req *svcpb.EventsRequest, opts ...grpc.CallOption) (svcpb.HydrantService_EventsClient, error) {
-	return c.newETHAdaptor(ctx, req), nil
+	return c.newETHAdaptor(ctx, req, opts...)
 }
 
 // ERC721TransferEvents implements the HydrantService.ERC721TransferEvents
 // method. It overrides req.Signature with the appropriate ERC721 signature but
 // otherwise functions identically to the generic Events() method.
 //
-// See Events() re not leaking a goroutine.
+// See Events() re not leaking a goroutine, MonotonicBlocks re guaranteed
+// ordering, PersistCursor re resuming after restarts, and BufferBlocks re slow
+// consumers.
 func (c *ethClient) ERC721TransferEvents(ctx context.Context, req *svcpb.EventsRequest, opts ...grpc.CallOption) (svcpb.HydrantService_ERC721TransferEventsClient, error) {
 	req, err := withERC721TransferSig(req)
 	if err != nil {
 		return nil, err
 	}
-	return c.newETHAdaptor(ctx, req), nil
+	return c.newETHAdaptor(ctx, req, opts...)
 }
 
 // An ethAdaptor converts a HydrantService_EventsServer into a
@@ -183,28 +199,65 @@ type ethAdaptor struct {
 	err    error
 	// Embedded to allow this to be a drop-in replacement for streaming clients.
 	grpc.ClientStream
+
+	// persist, if non-nil, stores the cursor of the last block returned by
+	// Recv() when it is acknowledged by the next call to Recv().
+	persist  *PersistCursor
+	unacked  string
+	storeErr error
+	// ctx is the context passed to newETHAdaptor(), used for storing cursors.
+	// Unlike the context of the stream, it isn't cancelled when the stream
+	// ends, which is necessary as the final cursor is only acknowledged
+	// thereafter.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func (c *ethClient) newETHAdaptor(ctx context.Context, req *svcpb.EventsRequest) *ethAdaptor {
+func (c *ethClient) newETHAdaptor(ctx context.Context, req *svcpb.EventsRequest, opts ...grpc.CallOption) (*ethAdaptor, error) {
+	persist := persistCursorOption(opts)
+	if persist != nil && req.Cursor == "" {
+		cursor, err := persist.Store.Load(ctx, persist.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%T.Load(%q): %v", persist.Store, persist.Key, err)
+		}
+		// The caller's request MUST NOT be modified.
+		req = proto.Clone(req).(*svcpb.EventsRequest)
+		req.Cursor = cursor
+	}
+
+	a := &ethAdaptor{
+		persist: persist,
+		ctx:     ctx,
+	}
+	ctx, a.cancel = context.WithCancel(ctx)
+
 	// A single goroutine is spawned by this function. It is responsible for
 	// sending on (and hence closing) the BlockResponse channel although sending
-	// has a level of indirection via the send() function passed to c.events().
-	ch := make(chan *svcpb.BlockResponse)
-	a := &ethAdaptor{
-		blocks: ch,
+	// has a level of indirection via the send() function passed to c.events(),
+	// and then via the blockBuffer, which MAY spawn its own goroutine.
+	buf, err := bufferOption(opts).newBuffer(ctx)
+	if err != nil {
+		a.cancel()
+		return nil, err
 	}
+	a.blocks = buf.blocks
+
+	mono := monotonicOption(opts)
 
 	send := func(b *svcpb.BlockResponse) error {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case ch <- b:
+		if mono != nil && !mono.accept(b) {
 			return nil
 		}
+		return buf.push(ctx, b)
 	}
 	go func() {
-		defer close(ch)
-		switch err := c.events(ctx, req, send); err {
+		defer close(buf.blocks)
+		defer a.cancel()
+		err := c.events(ctx, req, send)
+		if ferr := buf.flush(); err == nil {
+			err = ferr
+		}
+		switch err {
 		case nil:
 			a.err = io.EOF
 		default:
@@ -212,7 +265,7 @@ func (c *ethClient) newETHAdaptor(ctx context.Context, req *svcpb.EventsRequest)
 		}
 	}()
 
-	return a
+	return a, nil
 }
 
 // Recv receives a block on the a.blocks channel and returns it. The channel is
@@ -220,14 +273,46 @@ func (c *ethClient) newETHAdaptor(ctx context.Context, req *svcpb.EventsRequest)
 // populates a.err; after which, any current and future calls to Recv() will
 // return said error. Receiving a non-nil error here is therefore proof that the
 // goroutine has finished, hence the comment on Events() re avoiding leaks.
+//
+// If a PersistCursor option was provided, Recv() first stores the cursor of
+// the previously returned block, thus acknowledging it.
 func (a *ethAdaptor) Recv() (*svcpb.BlockResponse, error) {
+	if err := a.ack(); err != nil {
+		return nil, err
+	}
 	b, ok := <-a.blocks
 	if !ok {
 		return nil, a.err
 	}
+	if a.persist != nil {
+		a.unacked = b.Cursor
+	}
 	return b, nil
 }
 
+// ack stores the cursor of the last block returned by Recv(), if any. If
+// storing fails, the stream is cancelled and drained so the goroutine spawned
+// by newETHAdaptor() exits, and the error is returned by all further calls.
+func (a *ethAdaptor) ack() error {
+	if a.storeErr != nil {
+		return a.storeErr
+	}
+	if a.persist == nil || a.unacked == "" {
+		return nil
+	}
+	if err := a.persist.Store.Store(a.ctx, a.persist.Key, a.unacked); err != nil {
+		a.storeErr = fmt.Errorf("%T.Store(%q, [cursor]): %v", a.persist.Store, a.persist.Key, err)
+		a.cancel()
+		go func() {
+			for range a.blocks {
+			}
+		}()
+		return a.storeErr
+	}
+	a.unacked = ""
+	return nil
+}
+
 // A blockResponseStreamer is a gRPC server stream of BlockResponses.
 type blockResponseStreamer interface {
 	Send(*svcpb.BlockResponse) error
@@ -240,23 +325,28 @@ type blockResponseStreamer interface {
 // - always log new valid request
 // - V(1) start/end of block, block stream (Firehose connection), and tx
 // - V(2) data parsing and conversion
-func (s *ethHandler) events(ctx context.Context, req *svcpb.EventsRequest, send func(*svcpb.BlockResponse) error) error {
-	if err := req.Validate(); err != nil {
-		return status.Error(codes.InvalidArgument, err.Error())
+//
+// Block-stream logs are also recorded as events on the OpenTelemetry span of
+// the stream; see InstrumentationName re metrics and traces.
+func (s *ethHandler) events(ctx context.Context, req *svcpb.EventsRequest, send func(*svcpb.BlockResponse) error) (retErr error) {
+	ctx, tel := startStreamTelemetry(ctx)
+	var sentBlocks, sentTxs int
+	defer func() {
+		tel.end(ctx, sentBlocks, sentTxs, retErr)
+	}()
+
+	extractors, warnings, err := validateEventsRequest(req)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		tel.debug("Request warning", ethlog.String("warning", w))
 	}
 
 	sigs := make([][]byte, len(req.Signatures))
 	sigStrings := make([]string, len(req.Signatures))
-
-	// Although these aren't used until later, they act as extra validation.
-	extractors := make(ethEventExtractors)
 	for i, sig := range req.Signatures {
-		x, err := newEthEventExtractor(sig)
-		if err != nil {
-			return status.Error(codes.InvalidArgument, err.Error())
-		}
-		extractors[x.hash] = x
-		sigs[i] = x.hash.Bytes()
+		sigs[i] = sig.EVMHash().Bytes()
 		sigStrings[i] = sig.EVMString()
 	}
 
@@ -269,7 +359,7 @@ func (s *ethHandler) events(ctx context.Context, req *svcpb.EventsRequest, send
 		contracts[common.BytesToAddress(addr.Bytes)] = struct{}{}
 		filter.Addresses[i] = addr.Bytes
 	}
-	glog.Infof("Fetching %q events emitted by %#x", sigStrings, filter.Addresses)
+	logger.Info("Fetching events", ethlog.Any("signatures", sigStrings), ethlog.String("contracts", fmt.Sprintf("%#x", filter.Addresses)))
 
 	transform, err := anypb.New(&filterpb.CombinedFilter{
 		LogFilters: []*filterpb.LogFilter{filter},
@@ -284,42 +374,57 @@ func (s *ethHandler) events(ctx context.Context, req *svcpb.EventsRequest, send
 		Transforms:    []*anypb.Any{transform},
 		Cursor:        req.Cursor,
 	}
+	// Cancelling stops the extraction workers if returning early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	blocks, err := s.proxy.Blocks(ctx, blockReq)
 	if err != nil {
 		return fmt.Errorf("%T.Blocks(): %v", s.proxy, err)
 	}
 	defer blocks.Close()
-	glog.V(1).Info("Block stream opened")
+	tel.debug("Block stream opened", ethlog.Int("workers", int64(s.workers)))
 
-	var sentBlocks, sentTxs int
+	extracted := extractors.extractAll(ctx, blocks.C, contracts, s.workers)
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 
-		case b, ok := <-blocks.C:
+		case x, ok := <-extracted:
 			if !ok {
-				glog.V(1).Infof("Block stream closed; sent %d transaction(s) across %d block(s)", sentTxs, sentBlocks)
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				tel.debug("Block stream closed", ethlog.Int("blocks", int64(sentBlocks)), ethlog.Int("txs", int64(sentTxs)))
 				return blocks.Err()
 			}
 
-			block, err := extractors.extract(b.Block, contracts)
-			if err != nil {
-				return err
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-x.done:
 			}
+			if x.err != nil {
+				return x.err
+			}
+			b := x.in
 			out := &svcpb.BlockResponse{
-				Block:         block,
-				Cursor:        b.Response.Cursor,
-				FirehoseBlock: b.Block,
-				FirehoseStep:  b.Response.Step,
+				Block:        x.block,
+				Cursor:       b.Response.Cursor,
+				FirehoseStep: b.Response.Step,
+			}
+			if req.IncludeFirehoseBlock {
+				out.FirehoseBlock = b.Block
 			}
 			if err := send(out); err != nil {
 				return err
 			}
 
-			glog.V(1).Infof("Sent block %d", out.Block.Number)
+			tel.debug("Sent block", ethlog.Block(out.Block.Number))
 			sentBlocks++
 			sentTxs += len(out.Block.Transactions)
+			tel.blockSent(ctx, len(out.Block.Transactions), x.took)
 		}
 	}
 }
@@ -331,7 +436,7 @@ type addressSet map[common.Address]struct{}
 // therefore == common.AddressLength.
 func (s addressSet) contains(b []byte) bool {
 	if len(b) != common.AddressLength {
-		glog.Fatalf("%T.contains() called with slice of length %d", s, len(b))
+		panic(fmt.Sprintf("%T.contains() called with slice of length %d", s, len(b)))
 	}
 	_, ok := s[*(*common.Address)(b)]
 	return ok
@@ -343,7 +448,7 @@ type ethEventExtractors map[common.Hash]*ethEventExtractor
 // block. The primary functionality is to find the correct event extractor for
 // each log and use it to extract structured data from the raw bytes.
 func (exs ethEventExtractors) extract(b *sfethpb.Block, contracts addressSet) (*ethpb.Block, error) {
-	glog.V(1).Infof("Parsing block %d", b.Number)
+	logger.Debug("Parsing block", ethlog.Block(b.Number))
 
 	block := &ethpb.Block{
 		Number:    b.Number,
@@ -354,7 +459,7 @@ func (exs ethEventExtractors) extract(b *sfethpb.Block, contracts addressSet) (*
 	}
 
 	for _, tx := range b.TransactionTraces {
-		glog.V(1).Infof("Parsing tx %#x", tx.Hash)
+		logger.Debug("Parsing tx", ethlog.Block(b.Number), ethlog.TxHash(common.BytesToHash(tx.Hash)))
 
 		var events []*ethpb.Event
 		for _, log := range tx.Receipt.Logs {
@@ -375,6 +480,9 @@ func (exs ethEventExtractors) extract(b *sfethpb.Block, contracts addressSet) (*
 			if err != nil {
 				return nil, fmt.Errorf("tx %#x: log index %d: %v", tx.Hash, log.Index, err)
 			}
+			if !xtractor.matches(ev) {
+				continue
+			}
 			events = append(events, ev)
 		}
 
@@ -382,15 +490,83 @@ func (exs ethEventExtractors) extract(b *sfethpb.Block, contracts addressSet) (*
 			continue
 		}
 
-		block.Transactions = append(block.Transactions, &ethpb.Transaction{
-			Hash: &ethpb.Hash{Bytes: tx.Hash},
-			Logs: events,
-		})
+		block.Transactions = append(block.Transactions, transactionFromTrace(tx, events))
 	}
 
 	return block, nil
 }
 
+// transactionFromTrace converts the StreamingFast transaction trace into a
+// Hydrant transaction with the specified events. Only a summary of the trace
+// is included so consumers don't require the FirehoseBlock to determine, for
+// example, the sender.
+func transactionFromTrace(tx *sfethpb.TransactionTrace, events []*ethpb.Event) *ethpb.Transaction {
+	out := &ethpb.Transaction{
+		Hash:    &ethpb.Hash{Bytes: tx.Hash},
+		Logs:    events,
+		Value:   tx.GetValue().GetBytes(),
+		GasUsed: tx.GasUsed,
+		Status:  transactionStatus(tx.Status),
+	}
+	if len(tx.From) > 0 {
+		out.From = &ethpb.Address{Bytes: tx.From}
+	}
+	if len(tx.To) > 0 {
+		out.To = &ethpb.Address{Bytes: tx.To}
+	}
+	return out
+}
+
+// transactionStatus converts a StreamingFast transaction status into its
+// Hydrant equivalent.
+func transactionStatus(s sfethpb.TransactionTraceStatus) ethpb.Transaction_Status {
+	switch s {
+	case sfethpb.TransactionTraceStatus_SUCCEEDED:
+		return ethpb.Transaction_STATUS_SUCCEEDED
+	case sfethpb.TransactionTraceStatus_FAILED:
+		return ethpb.Transaction_STATUS_FAILED
+	case sfethpb.TransactionTraceStatus_REVERTED:
+		return ethpb.Transaction_STATUS_REVERTED
+	default:
+		return ethpb.Transaction_STATUS_UNSPECIFIED
+	}
+}
+
+// addTopicFilters adds each of the filters to all extractors with an indexed
+// argument of the same name and type. It returns an InvalidArgument error if a
+// filter is empty or doesn't apply to any extractor.
+func (exs ethEventExtractors) addTopicFilters(filters []*svcpb.TopicFilter) error {
+	for i, f := range filters {
+		if len(f.Values) == 0 {
+			return status.Errorf(codes.InvalidArgument, "%T[%d] (%q) has no values", f, i, f.Argument)
+		}
+
+		var applied bool
+		for _, x := range exs {
+			idx, ok := x.argIndexByName[f.Argument]
+			if !ok {
+				continue
+			}
+			arg := x.sig.Arguments[idx]
+			if !arg.Indexed {
+				continue
+			}
+			for _, v := range f.Values {
+				if !arg.Value.SameType(v) {
+					return status.Errorf(codes.InvalidArgument, "%T[%d] (%q) value of type %T for %s argument of type %T", f, i, f.Argument, v.GetPayload(), x.sig.EVMString(), arg.Value.GetPayload())
+				}
+			}
+			x.filters = append(x.filters, f)
+			applied = true
+		}
+
+		if !applied {
+			return status.Errorf(codes.InvalidArgument, "%T[%d] (%q) doesn't match an indexed argument of any signature", f, i, f.Argument)
+		}
+	}
+	return nil
+}
+
 // An ethEventExtractor finds and parses topics and raw log data matching a
 // specific signature.
 type ethEventExtractor struct {
@@ -401,6 +577,30 @@ type ethEventExtractor struct {
 	indexed        abi.Arguments
 	nonIndexed     abi.Arguments
 	argIndexByName map[string]int
+
+	// filters are guaranteed, by addTopicFilters(), to only refer to indexed
+	// arguments of the correct type.
+	filters []*svcpb.TopicFilter
+}
+
+// matches reports whether the event, as returned by e.asEvent(), matches all of
+// e's filters.
+func (e *ethEventExtractor) matches(ev *ethpb.Event) bool {
+	for _, f := range e.filters {
+		got := ev.Arguments[e.argIndexByName[f.Argument]].Value
+
+		var ok bool
+		for _, want := range f.Values {
+			if got.Equal(want) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
 }
 
 func newEthEventExtractor(sig *ethpb.Event) (*ethEventExtractor, error) {
@@ -446,7 +646,7 @@ func (e *ethEventExtractor) asEvent(log *sfethpb.Log) (*ethpb.Event, error) {
 	event := proto.Clone(e.sig).(*ethpb.Event)
 	event.Emitter = &ethpb.Address{Bytes: log.Address}
 	event.LogIndex = log.Index
-	glog.V(2).Infof("Emitting contract: %#x", log.Address)
+	logger.Trace("Parsing log", ethlog.Address("emitter", common.BytesToAddress(log.Address)), ethlog.Uint("log_index", uint64(log.Index)))
 
 	// ======
 	// TOPICS
@@ -460,7 +660,7 @@ func (e *ethEventExtractor) asEvent(log *sfethpb.Log) (*ethpb.Event, error) {
 	if err := abi.ParseTopicsIntoMap(parsed, e.indexed, topics); err != nil {
 		return nil, err
 	}
-	glog.V(2).Infof("Topics: %+v", parsed)
+	logger.Trace("Parsed topics", ethlog.Any("topics", parsed))
 
 	for name, val := range parsed {
 		if err := e.setArgument(event, name, val); err != nil {
@@ -476,7 +676,7 @@ func (e *ethEventExtractor) asEvent(log *sfethpb.Log) (*ethpb.Event, error) {
 	if err != nil {
 		return nil, fmt.Errorf("%T.Unpack(%T.Data = %#x): %v", e.args, log, log.Data, err)
 	}
-	glog.V(2).Infof("Data: %v", data)
+	logger.Trace("Parsed data", ethlog.Any("data", data))
 
 	for i, val := range data {
 		if err := e.setArgument(event, e.nonIndexed[i].Name, val); err != nil {
@@ -502,6 +702,6 @@ func (e *ethEventExtractor) setArgument(ev *ethpb.Event, name string, val interf
 		// This would mean a serious bug so use codes.Internal
 		return status.Errorf(codes.Internal, "unknown argument name %q", name)
 	}
-	glog.V(2).Infof("arg[%d] (%q) => %T(%v)", idx, name, val, val)
+	logger.Trace("Setting argument", ethlog.Int("index", int64(idx)), ethlog.String("name", name), ethlog.String("type", fmt.Sprintf("%T", val)), ethlog.Any("value", val))
 	return ev.Arguments[idx].Value.SetPayload(val)
 }
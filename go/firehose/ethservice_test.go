@@ -2,7 +2,9 @@ package firehose_test
 
 import (
 	"context"
+	"errors"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -14,6 +16,8 @@ import (
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	sfethpb "github.com/streamingfast/firehose-ethereum/types/pb/sf/ethereum/type/v2"
+
 	"github.com/cxkoda/solgo/projects/indexing/firehose"
 	"github.com/cxkoda/solgo/projects/indexing/firehose/firehosetest"
 
@@ -84,6 +88,25 @@ func TestEvents(t *testing.T) {
 
 			mined := fake.MineBlock(ctx, t)
 
+			rpc := fake.RPCClient(ctx, t)
+			// txWithLogs returns the expected summary of the transaction,
+			// with the events.
+			txWithLogs := func(tx *types.Transaction, events ...*ethpb.Event) *ethpb.Transaction {
+				t.Helper()
+				rcpt, err := rpc.TransactionReceipt(ctx, tx.Hash())
+				if err != nil {
+					t.Fatalf("%T.TransactionReceipt(%v) error %v", rpc, tx.Hash(), err)
+				}
+				return &ethpb.Transaction{
+					Hash:    &ethpb.Hash{Bytes: tx.Hash().Bytes()},
+					Logs:    events,
+					From:    &ethpb.Address{Bytes: fake.TxOpts().From.Bytes()},
+					To:      &ethpb.Address{Bytes: emitterAddr.Bytes()},
+					GasUsed: rcpt.GasUsed,
+					Status:  ethpb.Transaction_STATUS_SUCCEEDED,
+				}
+			}
+
 			req := &svcpb.EventsRequest{
 				Contracts: []*ethpb.Address{{Bytes: emitterAddr.Bytes()}},
 				Signatures: []*ethpb.Event{
@@ -110,27 +133,23 @@ func TestEvents(t *testing.T) {
 					TimeStamp: &timestamppb.Timestamp{Seconds: int64(mined.Time())},
 					Hash:      &ethpb.Hash{Bytes: mined.Hash().Bytes()},
 					Transactions: []*ethpb.Transaction{
-						{
-							Hash: &ethpb.Hash{Bytes: transferTx.Hash().Bytes()},
-							Logs: []*ethpb.Event{
-								ethpb.NewEvent(
-									"Transfer", emitterAddr,
-									firehosetest.Arg(t, "from", from, true),
-									firehosetest.Arg(t, "to", to, true),
-									firehosetest.Arg(t, "tokenId", uint256.MustFromBig(big.NewInt(tokenID)), true),
-								),
-							},
-						},
-						{
-							Hash: &ethpb.Hash{Bytes: dataTx.Hash().Bytes()},
-							Logs: []*ethpb.Event{
-								ethpb.NewEvent(
-									"WithData", emitterAddr,
-									firehosetest.Arg(t, "topic", uint8(tokenID), true),
-									ethpb.NewArgument("data", &ethpb.Value_Bytes{Bytes: data}, false),
-								),
-							},
-						},
+						txWithLogs(
+							transferTx,
+							ethpb.NewEvent(
+								"Transfer", emitterAddr,
+								firehosetest.Arg(t, "from", from, true),
+								firehosetest.Arg(t, "to", to, true),
+								firehosetest.Arg(t, "tokenId", uint256.MustFromBig(big.NewInt(tokenID)), true),
+							),
+						),
+						txWithLogs(
+							dataTx,
+							ethpb.NewEvent(
+								"WithData", emitterAddr,
+								firehosetest.Arg(t, "topic", uint8(tokenID), true),
+								ethpb.NewArgument("data", &ethpb.Value_Bytes{Bytes: data}, false),
+							),
+						),
 					},
 				},
 				Cursor: firehosetest.Cursor(mined),
@@ -145,6 +164,48 @@ func TestEvents(t *testing.T) {
 	}
 }
 
+func TestEventsIncludeFirehoseBlock(t *testing.T) {
+	ctx := context.Background()
+	fake := firehosetest.NewFake(ctx, t)
+
+	emitterAddr, _, emit, err := DeployEmitter(fake.TxOpts(), fake.Backend())
+	if err != nil {
+		t.Fatalf("DeployEmitter(…) error %v", err)
+	}
+	from := common.HexToAddress("0xc0ffee")
+	to := common.HexToAddress("0xdead")
+	if _, err := emit.Transfer(fake.TxOpts(), from, to, big.NewInt(42)); err != nil {
+		t.Fatalf("%T.Transfer(%v, %v, 42) error %v", emit, from, to, err)
+	}
+	mined := fake.MineBlock(ctx, t)
+
+	for _, include := range []bool{false, true} {
+		req := &svcpb.EventsRequest{
+			Contracts:            []*ethpb.Address{{Bytes: emitterAddr.Bytes()}},
+			IncludeFirehoseBlock: include,
+		}
+		blocks, err := fake.Client.ERC721TransferEvents(ctx, req)
+		if err != nil {
+			t.Fatalf("%T.Client.ERC721TransferEvents(%+v) error %v", fake, req, err)
+		}
+
+		got := firehosetest.CollectAll(t, blocks)
+		if len(got) != 1 {
+			t.Fatalf("%T.Client.ERC721TransferEvents(%+v) got %d blocks; want 1", fake, req, len(got))
+		}
+		fb := got[0].GetFirehoseBlock()
+		if gotIncluded := fb != nil; gotIncluded != include {
+			t.Errorf("%T.Client.ERC721TransferEvents(include_firehose_block = %t) got non-nil FirehoseBlock? %t; want %t", fake, include, gotIncluded, include)
+		}
+		if include && fb.GetNumber() != mined.NumberU64() {
+			t.Errorf("%T.Client.ERC721TransferEvents(include_firehose_block = true) got FirehoseBlock.Number %d; want %d", fake, fb.GetNumber(), mined.NumberU64())
+		}
+		if n := len(got[0].GetBlock().GetTransactions()); n != 1 {
+			t.Errorf("%T.Client.ERC721TransferEvents(include_firehose_block = %t) got %d transactions; want 1, regardless of FirehoseBlock", fake, include, n)
+		}
+	}
+}
+
 func TestETHClientRecvError(t *testing.T) {
 	// Calling ETHClient doesn't return a real gRPC client stream, but one that
 	// simply adapts the server returned by ETHServer. It's therefore more
@@ -173,3 +234,329 @@ func TestETHClientRecvError(t *testing.T) {
 		t.Errorf("%T.Client.ERC721TransferEvents([invalid contract address]).Recv() %s", fake, diff)
 	}
 }
+
+func TestETHClientMonotonicBlocks(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := firehosetest.Config{
+		UseETHServer: false,
+		// Simulates a replay from a stale cursor, as well as duplicates.
+		Replay: func(blocks []*sfethpb.Block) []*sfethpb.Block {
+			b0, b1, b2 := blocks[0], blocks[1], blocks[2]
+			return []*sfethpb.Block{b0, b1, b1, b0, b1, b2, b2, b0}
+		},
+	}
+	fake := cfg.NewFake(ctx, t)
+
+	var want []uint64
+	for i := 0; i < 3; i++ {
+		want = append(want, fake.MineBlock(ctx, t).NumberU64())
+	}
+
+	// ERC721TransferEvents() modifies the request so each call requires a new
+	// one.
+	newReq := func() *svcpb.EventsRequest {
+		return &svcpb.EventsRequest{
+			Contracts: []*ethpb.Address{{Bytes: common.HexToAddress("0x01").Bytes()}},
+		}
+	}
+
+	t.Run("without option", func(t *testing.T) {
+		req := newReq()
+		blocks, err := fake.Client.ERC721TransferEvents(ctx, req)
+		if err != nil {
+			t.Fatalf("%T.Client.ERC721TransferEvents(%+v) error %v", fake, req, err)
+		}
+		if got, want := len(firehosetest.CollectAll(t, blocks)), 8; got != want {
+			t.Errorf("%T.Client.ERC721TransferEvents(%+v) without %T got %d blocks; want %d", fake, req, &firehose.MonotonicBlocks{}, got, want)
+		}
+	})
+
+	t.Run("with option", func(t *testing.T) {
+		req := newReq()
+		mono := new(firehose.MonotonicBlocks)
+		blocks, err := fake.Client.ERC721TransferEvents(ctx, req, mono)
+		if err != nil {
+			t.Fatalf("%T.Client.ERC721TransferEvents(%+v, %T) error %v", fake, req, mono, err)
+		}
+
+		var got []uint64
+		for _, b := range firehosetest.CollectAll(t, blocks) {
+			got = append(got, b.Block.Number)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%T.Client.ERC721TransferEvents(…, %T) block numbers diff (-want +got):\n%s", fake, mono, diff)
+		}
+
+		if got, want := mono.Duplicates(), uint64(3); got != want {
+			t.Errorf("%T.Duplicates() got %d; want %d", mono, got, want)
+		}
+		if got, want := mono.OutOfOrder(), uint64(2); got != want {
+			t.Errorf("%T.OutOfOrder() got %d; want %d", mono, got, want)
+		}
+	})
+}
+
+func TestEventsTopicFilters(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := firehosetest.Config{UseETHServer: true}
+	fake := cfg.NewFake(ctx, t)
+
+	emitterAddr, _, emit, err := DeployEmitter(fake.TxOpts(), fake.Backend())
+	if err != nil {
+		t.Fatalf("DeployEmitter(…) error %v", err)
+	}
+
+	from := common.HexToAddress("0xc0ffee")
+	to := common.HexToAddress("0xdead")
+	other := common.HexToAddress("0xbeef")
+
+	txs := make(map[int64]*types.Transaction)
+	for id, recipient := range map[int64]common.Address{
+		1: to,
+		2: other,
+		3: to,
+	} {
+		tx, err := emit.Transfer(fake.TxOpts(), from, recipient, big.NewInt(id))
+		if err != nil {
+			t.Fatalf("%T.Transfer(%v, %v, %v) error %v", emit, from, recipient, id, err)
+		}
+		txs[id] = tx
+	}
+
+	mined := fake.MineBlock(ctx, t)
+
+	toValue, err := ethpb.AsValue(to)
+	if err != nil {
+		t.Fatalf("ethpb.AsValue(%v) error %v", to, err)
+	}
+	tokenValue, err := ethpb.AsValue(uint256.NewInt(3))
+	if err != nil {
+		t.Fatalf("ethpb.AsValue(3) error %v", err)
+	}
+
+	transfer := func(id int64, recipient common.Address) *ethpb.Transaction {
+		return &ethpb.Transaction{
+			Hash: &ethpb.Hash{Bytes: txs[id].Hash().Bytes()},
+			Logs: []*ethpb.Event{
+				ethpb.NewEvent(
+					"Transfer", emitterAddr,
+					firehosetest.Arg(t, "from", from, true),
+					firehosetest.Arg(t, "to", recipient, true),
+					firehosetest.Arg(t, "tokenId", uint256.NewInt(uint64(id)), true),
+				),
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filters []*svcpb.TopicFilter
+		want    []*ethpb.Transaction
+	}{
+		{
+			name: "single filter",
+			filters: []*svcpb.TopicFilter{{
+				Argument: "to",
+				Values:   []*ethpb.Value{toValue},
+			}},
+			want: []*ethpb.Transaction{
+				transfer(1, to),
+				transfer(3, to),
+			},
+		},
+		{
+			name: "filters are ANDed",
+			filters: []*svcpb.TopicFilter{
+				{
+					Argument: "to",
+					Values:   []*ethpb.Value{toValue},
+				},
+				{
+					Argument: "tokenId",
+					Values:   []*ethpb.Value{tokenValue},
+				},
+			},
+			want: []*ethpb.Transaction{
+				transfer(3, to),
+			},
+		},
+	}
+
+	ignore := cmp.Options{
+		protocmp.IgnoreFields(&ethpb.Event{}, "log_index"),
+		// Transaction summaries are tested in TestEvents.
+		protocmp.IgnoreFields(&ethpb.Transaction{}, "from", "to", "value", "gas_used", "status"),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &svcpb.EventsRequest{
+				Contracts:    []*ethpb.Address{{Bytes: emitterAddr.Bytes()}},
+				Signatures:   []*ethpb.Event{firehose.ERC721TransferEvent()},
+				TopicFilters: tt.filters,
+			}
+			blocks, err := fake.Client.Events(ctx, req)
+			if err != nil {
+				t.Fatalf("%T.Client.Events(%+v) error %v", fake, req, err)
+			}
+
+			got := firehosetest.CollectAll(t, blocks)
+
+			want := []*svcpb.BlockResponse{{
+				Block: &ethpb.Block{
+					Number:       mined.NumberU64(),
+					TimeStamp:    &timestamppb.Timestamp{Seconds: int64(mined.Time())},
+					Hash:         &ethpb.Hash{Bytes: mined.Hash().Bytes()},
+					Transactions: tt.want,
+				},
+				Cursor: firehosetest.Cursor(mined),
+			}}
+
+			if diff := cmp.Diff(want, got, firehosetest.CmpOpts(), ignore); diff != "" {
+				t.Errorf("All blocks received by %T.Events(%+v) diff (-want +got):\n%s", fake.Client, req, diff)
+			}
+		})
+	}
+
+	t.Run("invalid filters", func(t *testing.T) {
+		for _, filter := range []*svcpb.TopicFilter{
+			{Argument: "to"},
+			{Argument: "unknown", Values: []*ethpb.Value{toValue}},
+			{Argument: "tokenId", Values: []*ethpb.Value{toValue}},
+		} {
+			req := &svcpb.EventsRequest{
+				Signatures:   []*ethpb.Event{firehose.ERC721TransferEvent()},
+				TopicFilters: []*svcpb.TopicFilter{filter},
+			}
+			blocks, err := fake.Client.Events(ctx, req)
+			if err != nil {
+				t.Fatalf("%T.Client.Events(%+v) error %v", fake, req, err)
+			}
+			_, err = blocks.Recv()
+			if diff := errdiff.Code(err, codes.InvalidArgument); diff != "" {
+				t.Errorf("%T.Client.Events(%+v).Recv() %s", fake, filter, diff)
+			}
+		}
+	})
+}
+
+// failingCursorStore is a CursorStore that fails to store cursors.
+type failingCursorStore struct {
+	firehose.MemCursorStore
+}
+
+var errStoreCursor = errors.New("store failed")
+
+func (*failingCursorStore) Store(context.Context, string, string) error {
+	return errStoreCursor
+}
+
+func TestETHClientPersistCursor(t *testing.T) {
+	ctx := context.Background()
+
+	fake := firehosetest.NewFake(ctx, t)
+	for i := 0; i < 3; i++ {
+		fake.MineBlock(ctx, t)
+	}
+
+	newReq := func() *svcpb.EventsRequest {
+		return &svcpb.EventsRequest{
+			Contracts: []*ethpb.Address{{Bytes: common.HexToAddress("0x01").Bytes()}},
+		}
+	}
+
+	const key = "stream"
+	store := new(firehose.MemCursorStore)
+	persist := &firehose.PersistCursor{Store: store, Key: key}
+
+	loadCursor := func(t *testing.T) string {
+		t.Helper()
+		c, err := store.Load(ctx, key)
+		if err != nil {
+			t.Fatalf("%T.Load(%q) error %v", store, key, err)
+		}
+		return c
+	}
+
+	var last string
+	t.Run("acknowledge on Recv", func(t *testing.T) {
+		req := newReq()
+		blocks, err := fake.Client.ERC721TransferEvents(ctx, req, persist)
+		if err != nil {
+			t.Fatalf("%T.Client.ERC721TransferEvents(%+v, %T) error %v", fake, req, persist, err)
+		}
+
+		first, err := blocks.Recv()
+		if err != nil {
+			t.Fatalf("%T.Recv() error %v", blocks, err)
+		}
+		if got := loadCursor(t); got != "" {
+			t.Errorf("Cursor stored before acknowledgement of first block; got %q; want empty", got)
+		}
+
+		if _, err := blocks.Recv(); err != nil {
+			t.Fatalf("%T.Recv() error %v", blocks, err)
+		}
+		if got, want := loadCursor(t), first.Cursor; got != want {
+			t.Errorf("Cursor stored after acknowledgement of first block; got %q; want %q", got, want)
+		}
+
+		rest := firehosetest.CollectAll(t, blocks)
+		if len(rest) == 0 {
+			t.Fatalf("%T.Recv() returned only 2 blocks; want at least 3", blocks)
+		}
+		last = rest[len(rest)-1].Cursor
+		if got := loadCursor(t); got != last {
+			t.Errorf("Cursor stored after end of stream; got %q; want %q", got, last)
+		}
+	})
+
+	t.Run("resume from stored cursor", func(t *testing.T) {
+		fake.MineBlock(ctx, t)
+
+		req := newReq()
+		blocks, err := fake.Client.ERC721TransferEvents(ctx, req, persist)
+		if err != nil {
+			t.Fatalf("%T.Client.ERC721TransferEvents(%+v, %T) error %v", fake, req, persist, err)
+		}
+		if got := firehosetest.CollectAll(t, blocks); len(got) != 1 || got[0].Cursor == last {
+			t.Errorf("%T.Client.ERC721TransferEvents(…, %T) after mining 1 block got %d blocks; want only the new block after stored cursor %q", fake, persist, len(got), last)
+		}
+		if got := req.Cursor; got != "" {
+			t.Errorf("%T.Cursor after ERC721TransferEvents(…, %T) got %q; want unchanged empty cursor", req, persist, got)
+		}
+	})
+
+	t.Run("explicit cursor takes precedence", func(t *testing.T) {
+		const explicit = "explicit"
+		req := newReq()
+		req.Cursor = explicit
+		blocks, err := fake.Client.ERC721TransferEvents(ctx, req, persist)
+		if err != nil {
+			t.Fatalf("%T.Client.ERC721TransferEvents(%+v, %T) error %v", fake, req, persist, err)
+		}
+		firehosetest.CollectAll(t, blocks)
+		if got := req.Cursor; got != explicit {
+			t.Errorf("%T.Cursor after ERC721TransferEvents(…, %T) got %q; want unchanged %q", req, persist, got, explicit)
+		}
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		req := newReq()
+		failing := &firehose.PersistCursor{Store: new(failingCursorStore), Key: key}
+		blocks, err := fake.Client.ERC721TransferEvents(ctx, req, failing)
+		if err != nil {
+			t.Fatalf("%T.Client.ERC721TransferEvents(%+v, %T) error %v", fake, req, failing, err)
+		}
+		if _, err := blocks.Recv(); err != nil {
+			t.Fatalf("%T.Recv() error %v", blocks, err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := blocks.Recv(); err == nil || !strings.Contains(err.Error(), errStoreCursor.Error()) {
+				t.Errorf("%T.Recv() after failure to store cursor got err %v; want containing %q", blocks, err, errStoreCursor)
+			}
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package firehose
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	sfethpb "github.com/streamingfast/firehose-ethereum/types/pb/sf/ethereum/type/v2"
+
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+)
+
+// ExtractionWorkers is a grpc.DialOption that, when passed to ETHServer(),
+// ETHClient(), or their chain-specific equivalents, extracts events from up to
+// N blocks concurrently. Blocks are still sent in the order received from
+// Firehose, so this only changes throughput; it is most useful for backfills
+// of dense blocks, for which extraction is the bottleneck.
+//
+// If N <= 1, blocks are extracted one at a time, which is also the behaviour
+// in the absence of the option. ExtractionWorkers is not propagated to the
+// underlying Firehose connection.
+type ExtractionWorkers struct {
+	grpc.EmptyDialOption
+	N int
+}
+
+// extractionWorkersOption returns the N of the last ExtractionWorkers in opts,
+// or 1 if there are none, along with all other options.
+func extractionWorkersOption(opts []grpc.DialOption) (int, []grpc.DialOption) {
+	n := 1
+	var rest []grpc.DialOption
+	for _, o := range opts {
+		switch w := o.(type) {
+		case ExtractionWorkers:
+			n = w.N
+		case *ExtractionWorkers:
+			n = w.N
+		default:
+			rest = append(rest, o)
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n, rest
+}
+
+// An extraction is the result of extracting events from a single Firehose
+// block. Its fields MUST NOT be read until done is closed.
+type extraction struct {
+	in    Block[*sfethpb.Block]
+	block *ethpb.Block
+	took  time.Duration
+	err   error
+	done  chan struct{}
+}
+
+// extractAll extracts events from every block received on `in`, using a pool
+// of the specified number of workers. Extractions are sent on the returned
+// channel in the same order as their blocks were received, without waiting
+// for them to be done, which bounds the number in flight. The channel is
+// closed once `in` is closed or ctx is cancelled; in the latter case, the
+// receiver MUST NOT wait for the done channel of any extraction without also
+// waiting for ctx.
+func (exs ethEventExtractors) extractAll(ctx context.Context, in <-chan Block[*sfethpb.Block], contracts addressSet, workers int) <-chan *extraction {
+	out := make(chan *extraction, workers)
+	jobs := make(chan *extraction)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for x := range jobs {
+				start := time.Now()
+				x.block, x.err = exs.extract(x.in.Block, contracts)
+				x.took = time.Since(start)
+				close(x.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer func() {
+			close(jobs)
+			wg.Wait()
+			close(out)
+		}()
+
+		for {
+			var x *extraction
+			select {
+			case <-ctx.Done():
+				return
+			case b, ok := <-in:
+				if !ok {
+					return
+				}
+				x = &extraction{in: b, done: make(chan struct{})}
+			}
+
+			// Queueing for output before a worker is available is what
+			// preserves ordering.
+			select {
+			case <-ctx.Done():
+				return
+			case out <- x:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case jobs <- x:
+			}
+		}
+	}()
+
+	return out
+}
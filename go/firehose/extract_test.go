@@ -0,0 +1,174 @@
+package firehose
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	sfethpb "github.com/streamingfast/firehose-ethereum/types/pb/sf/ethereum/type/v2"
+)
+
+// denseBlocks returns n Firehose blocks, each with txs transactions that emit
+// logsPerTx ERC721 Transfer events from the contract.
+func denseBlocks(n, txs, logsPerTx int, contract common.Address) []Block[*sfethpb.Block] {
+	sig := ERC721TransferEvent().EVMHash()
+
+	blocks := make([]Block[*sfethpb.Block], n)
+	for i := range blocks {
+		b := &sfethpb.Block{
+			Number: uint64(i),
+			Hash:   common.BigToHash(big.NewInt(int64(i))).Bytes(),
+			Header: &sfethpb.BlockHeader{},
+		}
+		for j := 0; j < txs; j++ {
+			tx := &sfethpb.TransactionTrace{
+				Hash:    common.BigToHash(big.NewInt(int64(i*txs + j))).Bytes(),
+				Receipt: &sfethpb.TransactionReceipt{},
+			}
+			for k := 0; k < logsPerTx; k++ {
+				tx.Receipt.Logs = append(tx.Receipt.Logs, &sfethpb.Log{
+					Address: contract.Bytes(),
+					Topics: [][]byte{
+						sig.Bytes(),
+						common.BigToHash(big.NewInt(int64(j))).Bytes(),
+						common.BigToHash(big.NewInt(int64(k))).Bytes(),
+						common.BigToHash(big.NewInt(int64(i))).Bytes(),
+					},
+					Index: uint32(k),
+				})
+			}
+			b.TransactionTraces = append(b.TransactionTraces, tx)
+		}
+		blocks[i] = Block[*sfethpb.Block]{Block: b}
+	}
+	return blocks
+}
+
+func transferExtractors(tb testing.TB) ethEventExtractors {
+	tb.Helper()
+	x, err := newEthEventExtractor(ERC721TransferEvent())
+	if err != nil {
+		tb.Fatalf("newEthEventExtractor(ERC721TransferEvent()) error %v", err)
+	}
+	return ethEventExtractors{x.hash: x}
+}
+
+// feed returns a channel on which all blocks are sent before it is closed.
+func feed(blocks []Block[*sfethpb.Block]) <-chan Block[*sfethpb.Block] {
+	ch := make(chan Block[*sfethpb.Block])
+	go func() {
+		defer close(ch)
+		for _, b := range blocks {
+			ch <- b
+		}
+	}()
+	return ch
+}
+
+func TestExtractAll(t *testing.T) {
+	ctx := context.Background()
+	contract := common.HexToAddress("0x721")
+	contracts := addressSet{contract: {}}
+	exs := transferExtractors(t)
+
+	// Varying density results in workers finishing out of order.
+	var blocks []Block[*sfethpb.Block]
+	for i := 0; i < 20; i++ {
+		b := denseBlocks(1, 1+(i%4)*10, 1+i%3, contract)[0]
+		b.Block.Number = uint64(i)
+		blocks = append(blocks, b)
+	}
+
+	for _, workers := range []int{1, 3, 16, 64} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			var i int
+			for x := range exs.extractAll(ctx, feed(blocks), contracts, workers) {
+				<-x.done
+				if x.err != nil {
+					t.Fatalf("extraction of block %d error %v", x.in.Block.Number, x.err)
+				}
+
+				want, err := exs.extract(blocks[i].Block, contracts)
+				if err != nil {
+					t.Fatalf("%T.extract(block %d) error %v", exs, i, err)
+				}
+				if diff := cmp.Diff(want, x.block, protocmp.Transform()); diff != "" {
+					t.Errorf("extraction %d diff (-serial +concurrent):\n%s", i, diff)
+				}
+				i++
+			}
+			if i != len(blocks) {
+				t.Errorf("got %d extractions; want %d", i, len(blocks))
+			}
+		})
+	}
+}
+
+func TestExtractAllCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	contract := common.HexToAddress("0x721")
+
+	// The input channel is never closed so only cancellation ends extraction.
+	in := make(chan Block[*sfethpb.Block], 1)
+	in <- denseBlocks(1, 1, 1, contract)[0]
+
+	out := transferExtractors(t).extractAll(ctx, in, addressSet{contract: {}}, 4)
+	x := <-out
+	<-x.done
+	cancel()
+
+	for range out {
+	}
+}
+
+func TestExtractionWorkersOption(t *testing.T) {
+	other := grpc.WithUserAgent("test")
+
+	tests := []struct {
+		opts     []grpc.DialOption
+		want     int
+		wantRest int
+	}{
+		{nil, 1, 0},
+		{[]grpc.DialOption{other}, 1, 1},
+		{[]grpc.DialOption{ExtractionWorkers{N: 8}, other}, 8, 1},
+		{[]grpc.DialOption{&ExtractionWorkers{N: 2}, ExtractionWorkers{N: 4}}, 4, 0},
+		{[]grpc.DialOption{ExtractionWorkers{N: -1}}, 1, 0},
+	}
+
+	for _, tt := range tests {
+		got, rest := extractionWorkersOption(tt.opts)
+		if got != tt.want || len(rest) != tt.wantRest {
+			t.Errorf("extractionWorkersOption(%v) got %d workers and %d other options; want %d and %d", tt.opts, got, len(rest), tt.want, tt.wantRest)
+		}
+	}
+}
+
+func BenchmarkExtractAll(b *testing.B) {
+	ctx := context.Background()
+	contract := common.HexToAddress("0x721")
+	contracts := addressSet{contract: {}}
+	exs := transferExtractors(b)
+
+	// Roughly equivalent to a busy mainnet block during an NFT mint.
+	blocks := denseBlocks(32, 200, 5, contract)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for x := range exs.extractAll(ctx, feed(blocks), contracts, workers) {
+					<-x.done
+					if x.err != nil {
+						b.Fatal(x.err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// The fakehose binary serves a fake Firehose Stream service, and optionally the
+// Hydrant service backed by it, from a recorded fixture of blocks. It allows
+// Hydrant consumers in any language to be tested against deterministic
+// streams, including reorgs (undo steps) and injected errors.
+//
+// See firehosetest.WriteFixture() for the fixture format.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/golang/glog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+
+	hosepb "github.com/streamingfast/pbgo/sf/firehose/v2"
+
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/grpctest"
+	"github.com/cxkoda/solgo/projects/indexing/firehose"
+	"github.com/cxkoda/solgo/projects/indexing/firehose/firehosetest"
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+)
+
+func main() {
+	var cfg config
+	flag.IntVar(&cfg.port, "port", 8080, "Port on which to listen for gRPC requests")
+	flag.StringVar(&cfg.fixture, "fixture", "", "Path to new-line delimited JSON fixture of blocks, undo steps, and errors")
+	flag.BoolVar(&cfg.hydrant, "hydrant", true, "Also serve the Hydrant service, backed by the fake Firehose")
+	flag.Parse()
+
+	if err := cfg.run(context.Background()); err != nil {
+		glog.Exit(err)
+	}
+}
+
+// logger is used for all logs other than fatal errors in main().
+var logger = ethlog.Default()
+
+type config struct {
+	port    int
+	fixture string
+	hydrant bool
+}
+
+func (cfg *config) run(ctx context.Context) (retErr error) {
+	if cfg.fixture == "" {
+		return fmt.Errorf("--fixture required")
+	}
+	f, err := os.Open(cfg.fixture)
+	if err != nil {
+		return fmt.Errorf("os.Open(%q): %v", cfg.fixture, err)
+	}
+	steps, err := firehosetest.ReadFixture(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("firehosetest.ReadFixture(%q): %v", cfg.fixture, err)
+	}
+	hose := firehosetest.NewServer(steps...)
+
+	s := grpc.NewServer()
+	hosepb.RegisterStreamServer(s, hose)
+	reflection.Register(s)
+
+	if cfg.hydrant {
+		// The Hydrant service is a client of the Firehose so it's connected to
+		// a second, in-memory copy of the fake to avoid dialling ourselves.
+		// Being a distinct Server, its one-off error steps fire independently
+		// of those of direct Firehose clients.
+		reg := func(s *grpc.Server, impl hosepb.StreamServer) {
+			hosepb.RegisterStreamServer(s, impl)
+		}
+		inMem, cleanupInMem := grpctest.NewWithRegistered[hosepb.StreamServer](reg, firehosetest.NewServer(steps...))
+		defer cleanupInMem()
+
+		srv, cleanup, err := firehose.ETHServer(ctx, "", "", "", inMem.DialOpts()...)
+		if err != nil {
+			return fmt.Errorf("firehose.ETHServer([in-memory fake Firehose]): %v", err)
+		}
+		defer func() {
+			if err := cleanup(); retErr == nil {
+				retErr = err
+			}
+		}()
+		svcpb.RegisterHydrantServiceServer(s, srv)
+	}
+
+	addr := fmt.Sprintf(":%d", cfg.port)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("net.Listen(tcp, %q): %v", addr, err)
+	}
+	logger.Info("fakehose serving", ethlog.Int("steps", int64(len(steps))), ethlog.String("fixture", cfg.fixture), ethlog.String("addr", addr))
+
+	return s.Serve(lis)
+}
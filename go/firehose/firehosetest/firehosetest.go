@@ -60,6 +60,14 @@ type Config struct {
 	// edge-case differences. If true, the returned server will be connected to
 	// with the grpctest package.
 	UseETHServer bool
+	// Replay, if non-nil, is called with all blocks that the fake Firehose
+	// server would send in response to a request, and the returned blocks are
+	// sent instead. This allows for simulation of cursor replays, which cause
+	// duplicate and out-of-order blocks.
+	Replay func([]*sfethpb.Block) []*sfethpb.Block
+	// ServerOptions are passed to the grpctest server if UseETHServer is true,
+	// allowing e.g. a grpctest.DeadlineChecker to be installed.
+	ServerOptions []grpc.ServerOption
 }
 
 // NewFake returns NewFake() called on a zero Config.
@@ -72,7 +80,7 @@ func NewFake(ctx context.Context, tb testing.TB) *Fake {
 func (c *Config) NewFake(ctx context.Context, tb testing.TB) *Fake {
 	tb.Helper()
 
-	h := new(hose)
+	h := &hose{replay: c.Replay}
 	// The firehose proto generates a deprecated registration method that we
 	// need to wrap for use with grpctest generic registration.
 	reg := func(s *grpc.Server, impl hosepb.StreamServer) {
@@ -93,7 +101,7 @@ func (c *Config) NewFake(ctx context.Context, tb testing.TB) *Fake {
 		if err != nil {
 			tb.Fatalf("firehose.ETHServer([dialling grpctest Firehose]) error %v", err)
 		}
-		conn := grpctest.NewClientConnTB(tb, svcpb.RegisterHydrantServiceServer, srv)
+		conn := grpctest.NewClientConnTB(tb, svcpb.RegisterHydrantServiceServer, srv, c.ServerOptions...)
 		client = svcpb.NewHydrantServiceClient(conn)
 		cleanup = clean
 	} else {
@@ -185,12 +193,28 @@ func (f *Fake) MineBlock(ctx context.Context, tb testing.TB) *types.Block {
 			logs = append(logs, ev)
 		}
 
-		txs = append(txs, &sfethpb.TransactionTrace{
-			Hash: rcpt.TxHash.Bytes(),
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			tb.Fatalf("types.Sender([tx from just-mined block]) error %v", err)
+		}
+		trace := &sfethpb.TransactionTrace{
+			Hash:    rcpt.TxHash.Bytes(),
+			From:    from.Bytes(),
+			Value:   &sfethpb.BigInt{Bytes: tx.Value().Bytes()},
+			GasUsed: rcpt.GasUsed,
+			Status:  sfethpb.TransactionTraceStatus_SUCCEEDED,
 			Receipt: &sfethpb.TransactionReceipt{
 				Logs: logs,
 			},
-		})
+		}
+		if to := tx.To(); to != nil {
+			trace.To = to.Bytes()
+		}
+		if rcpt.Status != types.ReceiptStatusSuccessful {
+			// Receipts don't differentiate between reverts and other failures.
+			trace.Status = sfethpb.TransactionTraceStatus_FAILED
+		}
+		txs = append(txs, trace)
 	}
 
 	f.hose.blocks = append(f.hose.blocks, &sfethpb.Block{
@@ -205,6 +229,17 @@ func (f *Fake) MineBlock(ctx context.Context, tb testing.TB) *types.Block {
 	return block
 }
 
+// WriteFixture writes all blocks mined so far as a fixture that can be read by
+// ReadFixture(), for use with a Server; e.g. to record a scenario for the
+// fakehose binary.
+func (f *Fake) WriteFixture(w io.Writer) error {
+	steps := make([]Step, len(f.hose.blocks))
+	for i, b := range f.hose.blocks {
+		steps[i] = Step{Block: b}
+	}
+	return WriteFixture(w, steps)
+}
+
 // Cursor returns the cursor returned by a fake Firehose for a given block. This
 // will change and its stability MUST NOT be depended upon. It is exposed to
 // couple test results with their expected values.
@@ -220,6 +255,7 @@ func blockCursor(num uint64) string {
 // send every block.
 type hose struct {
 	blocks []*sfethpb.Block
+	replay func([]*sfethpb.Block) []*sfethpb.Block
 }
 
 func (h *hose) Blocks(req *hosepb.Request, srv hosepb.Stream_BlocksServer) error {
@@ -227,7 +263,12 @@ func (h *hose) Blocks(req *hosepb.Request, srv hosepb.Stream_BlocksServer) error
 		return status.Errorf(codes.InvalidArgument, "negative %T.StartBlobkNum = %d", req, s)
 	}
 
-	for _, b := range h.blocks {
+	blocks := h.blocks
+	if h.replay != nil {
+		blocks = h.replay(blocks)
+	}
+
+	for _, b := range blocks {
 		if n := b.Number; n < uint64(req.StartBlockNum) {
 			continue
 		} else if req.StopBlockNum != 0 && n > req.StopBlockNum {
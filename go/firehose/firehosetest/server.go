@@ -0,0 +1,241 @@
+package firehosetest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	sfethpb "github.com/streamingfast/firehose-ethereum/types/pb/sf/ethereum/type/v2"
+	hosepb "github.com/streamingfast/pbgo/sf/firehose/v2"
+)
+
+// A Step is a single scripted event in a Server's stream. Exactly one of Block
+// or Err SHOULD be set.
+type Step struct {
+	// Block is sent as a STEP_NEW response, or as STEP_UNDO if Undo is true.
+	// An undo MUST refer to a block that was previously sent as new.
+	Block *sfethpb.Block
+	Undo  bool
+	// Err, if non-nil, ends the stream with the error. Each error Step is
+	// only returned by the first stream to reach it, allowing clients to
+	// reconnect, with the last-received cursor, and continue.
+	Err error
+}
+
+// A Server is a fake Firehose Stream server that sends scripted Steps. Unlike
+// a Fake, it isn't coupled to an EVM implementation nor to Go tests, and can be
+// constructed from a fixture file with ReadFixture(); see the fakehose binary.
+//
+// StartBlockNum and StopBlockNum filters apply to all block Steps, including
+// undos. If a request has a non-empty Cursor, the stream resumes after the
+// Step from which the cursor was sent. All other request fields are ignored.
+type Server struct {
+	mu    sync.Mutex
+	steps []Step
+	fired map[int]bool
+}
+
+// NewServer returns a new Server that sends the Steps.
+func NewServer(steps ...Step) *Server {
+	return &Server{
+		steps: steps,
+		fired: make(map[int]bool),
+	}
+}
+
+// Append appends Steps to those sent by the Server. Streams that are already
+// open will only send the new Steps if they haven't yet reached the end.
+func (s *Server) Append(steps ...Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, steps...)
+}
+
+// next returns the Step at index i, and a boolean indicating whether it exists.
+// If the Step is an error that has already been fired, the error is removed
+// from the returned value; otherwise it is marked as fired.
+func (s *Server) next(i int) (Step, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i >= len(s.steps) {
+		return Step{}, false
+	}
+	st := s.steps[i]
+	if st.Err != nil {
+		if s.fired[i] {
+			st.Err = nil
+		}
+		s.fired[i] = true
+	}
+	return st, true
+}
+
+// resumeAfter returns the index of the Step following the one from which the
+// cursor was sent.
+func (s *Server) resumeAfter(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, st := range s.steps {
+		if st.Block != nil && stepCursor(st) == cursor {
+			return i + 1, nil
+		}
+	}
+	return 0, status.Errorf(codes.InvalidArgument, "unknown cursor %q", cursor)
+}
+
+// Blocks implements the Firehose Stream service.
+func (s *Server) Blocks(req *hosepb.Request, srv hosepb.Stream_BlocksServer) error {
+	if n := req.StartBlockNum; n < 0 {
+		return status.Errorf(codes.InvalidArgument, "negative %T.StartBlockNum = %d", req, n)
+	}
+	i, err := s.resumeAfter(req.Cursor)
+	if err != nil {
+		return err
+	}
+
+	for ; ; i++ {
+		st, ok := s.next(i)
+		if !ok {
+			return nil
+		}
+		if st.Err != nil {
+			return st.Err
+		}
+		if st.Block == nil {
+			continue
+		}
+
+		if n := st.Block.Number; n < uint64(req.StartBlockNum) {
+			continue
+		} else if req.StopBlockNum != 0 && n > req.StopBlockNum {
+			return nil
+		}
+		if err := sendBlock(srv, st); err != nil {
+			return fmt.Errorf("[%T] %v", s, err)
+		}
+	}
+}
+
+// sendBlock sends st.Block on the stream.
+func sendBlock(srv hosepb.Stream_BlocksServer, st Step) error {
+	any, err := anypb.New(st.Block)
+	if err != nil {
+		return fmt.Errorf("anypb.New(%T): %v", st.Block, err)
+	}
+	resp := &hosepb.Response{
+		Block:  any,
+		Cursor: stepCursor(st),
+		Step:   hosepb.ForkStep_STEP_NEW,
+	}
+	if st.Undo {
+		resp.Step = hosepb.ForkStep_STEP_UNDO
+	}
+	if err := srv.Send(resp); err != nil {
+		return fmt.Errorf("%T.Send(): %v", srv, err)
+	}
+	return nil
+}
+
+// stepCursor returns the cursor sent with a block Step.
+func stepCursor(st Step) string {
+	if st.Undo {
+		return fmt.Sprintf("undo[%d]", st.Block.Number)
+	}
+	return blockCursor(st.Block.Number)
+}
+
+// A fixtureStep is the JSON representation of a Step, one per line of a
+// fixture. The Block is protojson-encoded, and the Code accepts either the
+// numeric value or the upper-case name; e.g. "UNAVAILABLE".
+type fixtureStep struct {
+	Block json.RawMessage `json:"block,omitempty"`
+	Undo  bool            `json:"undo,omitempty"`
+	Error *fixtureError   `json:"error,omitempty"`
+}
+
+type fixtureError struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message"`
+}
+
+// ReadFixture parses new-line delimited JSON Steps; see WriteFixture() for
+// the format. Empty lines are ignored.
+func ReadFixture(r io.Reader) ([]Step, error) {
+	var steps []Step
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 64<<20) // blocks with many traces exceed the default
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+
+		var fs fixtureStep
+		if err := json.Unmarshal(sc.Bytes(), &fs); err != nil {
+			return nil, fmt.Errorf("line %d: json.Unmarshal(…, %T): %v", line, &fs, err)
+		}
+
+		var st Step
+		if len(fs.Block) > 0 {
+			st.Block = new(sfethpb.Block)
+			if err := protojson.Unmarshal(fs.Block, st.Block); err != nil {
+				return nil, fmt.Errorf("line %d: protojson.Unmarshal(…, %T): %v", line, st.Block, err)
+			}
+			st.Undo = fs.Undo
+		}
+		if e := fs.Error; e != nil {
+			st.Err = status.Error(e.Code, e.Message)
+		}
+		if (st.Block == nil) == (st.Err == nil) {
+			return nil, fmt.Errorf("line %d: exactly one of block or error MUST be set", line)
+		}
+		steps = append(steps, st)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%T.Scan(): %v", sc, err)
+	}
+	return steps, nil
+}
+
+// WriteFixture writes the Steps as new-line delimited JSON, suitable for
+// ReadFixture(). Each line is an object with either a "block", as protojson,
+// and optional boolean "undo"; or an "error" object with "code" and "message".
+// Non-status errors are written with code UNKNOWN.
+func WriteFixture(w io.Writer, steps []Step) error {
+	enc := json.NewEncoder(w)
+	for i, st := range steps {
+		var fs fixtureStep
+		if st.Block != nil {
+			buf, err := protojson.Marshal(st.Block)
+			if err != nil {
+				return fmt.Errorf("step %d: protojson.Marshal(%T): %v", i, st.Block, err)
+			}
+			fs.Block = buf
+			fs.Undo = st.Undo
+		}
+		if st.Err != nil {
+			s := status.Convert(st.Err)
+			fs.Error = &fixtureError{
+				Code:    s.Code(),
+				Message: s.Message(),
+			}
+		}
+
+		if err := enc.Encode(fs); err != nil {
+			return fmt.Errorf("step %d: %T.Encode(): %v", i, enc, err)
+		}
+	}
+	return nil
+}
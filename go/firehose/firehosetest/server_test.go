@@ -0,0 +1,170 @@
+package firehosetest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	sfethpb "github.com/streamingfast/firehose-ethereum/types/pb/sf/ethereum/type/v2"
+	hosepb "github.com/streamingfast/pbgo/sf/firehose/v2"
+
+	"github.com/cxkoda/solgo/go/grpctest"
+)
+
+// received is a simplified view of a hosepb.Response.
+type received struct {
+	Num    uint64
+	Step   hosepb.ForkStep
+	Cursor string
+}
+
+// recvAll calls grpctest.RecvAll() and returns a simplified view of the
+// responses.
+func recvAll(tb testing.TB, stream hosepb.Stream_BlocksClient) ([]received, error) {
+	tb.Helper()
+
+	resps, err := grpctest.RecvAll[*hosepb.Response](stream)
+
+	var got []received
+	for _, resp := range resps {
+		b := new(sfethpb.Block)
+		if err := resp.Block.UnmarshalTo(b); err != nil {
+			tb.Fatalf("%T.UnmarshalTo(%T) error %v", resp.Block, b, err)
+		}
+		got = append(got, received{
+			Num:    b.Number,
+			Step:   resp.Step,
+			Cursor: resp.Cursor,
+		})
+	}
+	return got, err
+}
+
+func TestServerFixture(t *testing.T) {
+	ctx := context.Background()
+
+	block := func(n uint64) *sfethpb.Block {
+		return &sfethpb.Block{Number: n}
+	}
+	steps := []Step{
+		{Block: block(0)},
+		{Block: block(1)},
+		{Block: block(1), Undo: true},
+		{Err: status.Error(codes.Unavailable, "injected")},
+		{Block: block(1)},
+		{Block: block(2)},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFixture(&buf, steps); err != nil {
+		t.Fatalf("WriteFixture(…) error %v", err)
+	}
+	fixture, err := ReadFixture(&buf)
+	if err != nil {
+		t.Fatalf("ReadFixture([output of WriteFixture()]) error %v", err)
+	}
+
+	srv := NewServer(fixture...)
+	reg := func(s *grpc.Server, impl hosepb.StreamServer) {
+		hosepb.RegisterStreamServer(s, impl)
+	}
+	conn := grpctest.NewClientConnTB[hosepb.StreamServer](t, reg, srv)
+	client := hosepb.NewStreamClient(conn)
+
+	const (
+		stepNew  = hosepb.ForkStep_STEP_NEW
+		stepUndo = hosepb.ForkStep_STEP_UNDO
+	)
+
+	t.Run("first stream errors", func(t *testing.T) {
+		stream, err := client.Blocks(ctx, &hosepb.Request{})
+		if err != nil {
+			t.Fatalf("%T.Blocks() error %v", client, err)
+		}
+		got, err := recvAll(t, stream)
+		grpctest.AssertCode(t, err, codes.Unavailable)
+
+		want := []received{
+			{0, stepNew, "block[0]"},
+			{1, stepNew, "block[1]"},
+			{1, stepUndo, "undo[1]"},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Recv() diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("resume from cursor", func(t *testing.T) {
+		stream, err := client.Blocks(ctx, &hosepb.Request{Cursor: "undo[1]"})
+		if err != nil {
+			t.Fatalf("%T.Blocks() error %v", client, err)
+		}
+		got, err := recvAll(t, stream)
+		if err != nil {
+			t.Fatalf("Recv() error %v", err)
+		}
+
+		want := []received{
+			{1, stepNew, "block[1]"},
+			{2, stepNew, "block[2]"},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Recv() diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("block range after error fired", func(t *testing.T) {
+		stream, err := client.Blocks(ctx, &hosepb.Request{StartBlockNum: 1, StopBlockNum: 1})
+		if err != nil {
+			t.Fatalf("%T.Blocks() error %v", client, err)
+		}
+		got, err := recvAll(t, stream)
+		if err != nil {
+			t.Fatalf("Recv() error %v", err)
+		}
+
+		want := []received{
+			{1, stepNew, "block[1]"},
+			{1, stepUndo, "undo[1]"},
+			{1, stepNew, "block[1]"},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Recv() diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("unknown cursor", func(t *testing.T) {
+		stream, err := client.Blocks(ctx, &hosepb.Request{Cursor: "nope"})
+		if err != nil {
+			t.Fatalf("%T.Blocks() error %v", client, err)
+		}
+		_, err = recvAll(t, stream)
+		grpctest.AssertCode(t, err, codes.InvalidArgument)
+	})
+}
+
+func TestReadFixtureErrors(t *testing.T) {
+	for _, in := range []string{
+		`{}`,
+		`{"block": {"number": 1}, "error": {"code": "UNAVAILABLE"}}`,
+		`{"block": {"not_a_field": 1}}`,
+		`not json`,
+	} {
+		if _, err := ReadFixture(bytes.NewBufferString(in)); err == nil {
+			t.Errorf("ReadFixture(%q) got nil error", in)
+		}
+	}
+
+	steps, err := ReadFixture(bytes.NewBufferString(`{"error": {"code": "UNAVAILABLE", "message": "x"}}` + "\n\n"))
+	if err != nil {
+		t.Fatalf("ReadFixture([error with named code]) error %v", err)
+	}
+	if len(steps) != 1 || status.Code(steps[0].Err) != codes.Unavailable {
+		t.Errorf("ReadFixture([error with named code]) got %+v; want single step with %v", steps, codes.Unavailable)
+	}
+}
@@ -1,18 +1,33 @@
 // The hydrant binary connects to the StreamingFast Firehose service to expose
 // simplified APIs.
+//
+// Clients are authenticated, and their usage limited, if -auth_config is set;
+// see firehose.Authenticator. Without it, the service is open to anyone who can
+// reach the port so SHOULD NOT be exposed beyond localhost.
+//
+// If -sink is set, the binary also acts as an ingestion daemon, publishing the
+// stream described by -sink_request to a Google Pub/Sub or Kafka topic, with
+// cursors checkpointed in Postgres; see firehose.Sink. With -sink_only, gRPC
+// clients aren't served at all.
 package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"net"
+	"os"
 	"time"
 
 	"github.com/golang/glog"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/cxkoda/solgo/go/eth/ethlog"
 	"github.com/cxkoda/solgo/go/proof"
 	"github.com/cxkoda/solgo/go/secrets"
 
@@ -34,6 +49,21 @@ func main() {
 	flag.Var(cfg.firehoseAPIKey, "firehose_api_key", "Firehose API Key")
 	flag.StringVar(&cfg.ethChain, "eth_chain", Mainnet, "Ethereum Chain (mainnet or goerli)")
 	flag.DurationVar(&cfg.grpcStreamTimeout, "grpc_stream_timeout", 0, "gRPC stream timeout")
+	flag.IntVar(&cfg.extractionWorkers, "extraction_workers", 1, "Number of blocks from which events are extracted concurrently, for both gRPC clients and the -sink; blocks are still sent in order")
+	flag.Var(&cfg.authConfig, "auth_config", "JSON firehose.AuthConfig of clients, their API keys and/or certificate common names, and quotas, stored as a secrets.Secret; e.g. gcp://path/to/secret. If empty, the service is unauthenticated")
+	flag.DurationVar(&cfg.quotaWindow, "quota_window", 24*time.Hour, "Period after which per-client block quotas are reset")
+	flag.StringVar(&cfg.auditLog, "audit_log", "", "File to which the JSON audit log of calls is appended; if empty, audit records are logged with glog")
+	flag.StringVar(&cfg.tlsCert, "tls_cert", "", "PEM server certificate file; if set, the service is only available over TLS")
+	flag.StringVar(&cfg.tlsKey, "tls_key", "", "PEM server private key file for -tls_cert")
+	flag.StringVar(&cfg.tlsClientCA, "tls_client_ca", "", "PEM CA certificate file with which client certificates are verified, enabling mTLS; requires -tls_cert")
+	flag.StringVar(&cfg.sink.uri, "sink", "", "If non-empty, BlockResponses are published to this topic; pubsub://<project>/<topic> or kafka://<broker>[,<broker>…]/<topic>")
+	flag.BoolVar(&cfg.sink.only, "sink_only", false, "Only run the -sink, without serving gRPC clients")
+	flag.StringVar(&cfg.sink.request, "sink_request", "", "File containing the protojson EventsRequest to publish to the -sink")
+	flag.StringVar(&cfg.sink.key, "sink_key", "", "Key identifying the -sink stream, used for cursors and message ordering; defaults to the topic")
+	flag.Var(&cfg.sink.cursorDSN, "sink_cursor_dsn", "Postgres DSN, stored as a secrets.Secret, of the database in which -sink cursors are checkpointed")
+	flag.StringVar(&cfg.sink.cursorTable, "sink_cursor_table", "hydrant_sink_cursors", "Table in which -sink cursors are checkpointed; created if it doesn't exist")
+	flag.BoolVar(&cfg.sink.skipEmpty, "sink_skip_empty", false, "Don't publish blocks without matching transactions to the -sink")
+	flag.IntVar(&cfg.sink.checkpointEach, "sink_checkpoint_every", 1, "Number of blocks after which the -sink cursor is checkpointed; higher values result in more duplicates after a restart")
 	flag.Parse()
 
 	if err := cfg.run(context.Background()); err != nil {
@@ -41,15 +71,87 @@ func main() {
 	}
 }
 
+// logger is used for all logs other than fatal errors in main().
+var logger = ethlog.Default()
+
 type config struct {
 	ethChain          string
 	firehoseAPIKey    *secrets.Secret
 	grpcStreamTimeout time.Duration
+	extractionWorkers int
 	port              int
+
+	authConfig                   secrets.Secret
+	quotaWindow                  time.Duration
+	auditLog                     string
+	tlsCert, tlsKey, tlsClientCA string
+
+	sink sinkConfig
+}
+
+func (cfg *config) run(ctx context.Context) error {
+	if err := cfg.sink.validate(); err != nil {
+		return err
+	}
+
+	if cfg.extractionWorkers < 1 {
+		return fmt.Errorf("-extraction_workers must be positive; got %d", cfg.extractionWorkers)
+	}
+
+	opts := []grpc.DialOption{
+		firehose.ExtractionWorkers{N: cfg.extractionWorkers},
+	}
+	if cfg.grpcStreamTimeout > 0 {
+		opts = append(opts, grpc.WithTimeout(cfg.grpcStreamTimeout))
+	}
+
+	firehoseAPIKey, err := cfg.firehoseAPIKey.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("%T(%q).Fetch(): %v", cfg.firehoseAPIKey, cfg.firehoseAPIKey.String(), err)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	if cfg.sink.enabled() {
+		g.Go(func() error {
+			return cfg.runSink(ctx, string(firehoseAPIKey), opts...)
+		})
+	}
+	if !cfg.sink.only {
+		g.Go(func() error {
+			return cfg.serve(ctx, string(firehoseAPIKey), opts...)
+		})
+	}
+	return g.Wait()
 }
 
-func (cfg *config) run(ctx context.Context) (retErr error) {
-	var opts []grpc.DialOption
+// runSink runs the sink configured by the -sink* flags.
+func (cfg *config) runSink(ctx context.Context, apiKey string, opts ...grpc.DialOption) (retErr error) {
+	var dial func(ctx context.Context, apiKey string, opts ...grpc.DialOption) (svcpb.HydrantServiceClient, func() error, error)
+
+	switch cfg.ethChain {
+	case Mainnet:
+		dial = firehose.ETHMainnetClient
+	case Goerli:
+		dial = firehose.ETHGoerliClient
+	default:
+		return fmt.Errorf("unknown network %q", cfg.ethChain)
+	}
+
+	client, cleanup, err := dial(ctx, apiKey, opts...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cleanup(); retErr == nil {
+			retErr = err
+		}
+	}()
+
+	return cfg.sink.run(ctx, client)
+}
+
+// serve serves the HydrantService to gRPC clients until ctx is cancelled.
+func (cfg *config) serve(ctx context.Context, apiKey string, opts ...grpc.DialOption) (retErr error) {
 	var dial func(ctx context.Context, apiKey string, opts ...grpc.DialOption) (svcpb.HydrantServiceServer, func() error, error)
 
 	switch cfg.ethChain {
@@ -61,26 +163,27 @@ func (cfg *config) run(ctx context.Context) (retErr error) {
 		return fmt.Errorf("unknown network %q", cfg.ethChain)
 	}
 
-	if cfg.grpcStreamTimeout > 0 {
-		opts = append(opts, grpc.WithTimeout(cfg.grpcStreamTimeout))
-	}
-
-	firehoseAPIKey, err := cfg.firehoseAPIKey.Fetch(ctx)
+	srv, cleanup, err := dial(ctx, apiKey, opts...)
 	if err != nil {
-		return fmt.Errorf("%T(%q).Fetch(): %v", cfg.firehoseAPIKey, cfg.firehoseAPIKey.String(), err)
+		return err
 	}
+	defer func() {
+		if err := cleanup(); retErr == nil {
+			retErr = err
+		}
+	}()
 
-	srv, cleanup, err := dial(ctx, string(firehoseAPIKey), opts...)
+	srvOpts, closeAudit, err := cfg.serverOptions(ctx)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if err := cleanup(); retErr == nil {
+		if err := closeAudit(); retErr == nil {
 			retErr = err
 		}
 	}()
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(srvOpts...)
 	svcpb.RegisterHydrantServiceServer(s, srv)
 	reflection.Register(s)
 
@@ -89,7 +192,94 @@ func (cfg *config) run(ctx context.Context) (retErr error) {
 	if err != nil {
 		return fmt.Errorf("net.Listen(tcp, %q): %v", addr, err)
 	}
-	fmt.Println(fmt.Sprintf("hydrant service listening on [%s] network with port [%d]", cfg.ethChain, cfg.port))
+	logger.Info("Hydrant service listening", ethlog.String("network", cfg.ethChain), ethlog.String("addr", addr))
+
+	// If the sink fails, ctx is cancelled by the errgroup and the server must
+	// stop so that the binary exits.
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+	}()
+	if err := s.Serve(lis); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// serverOptions returns the gRPC server options for TLS and authentication, as
+// configured by flags, along with a function to close the audit log.
+func (cfg *config) serverOptions(ctx context.Context) (_ []grpc.ServerOption, closeAudit func() error, _ error) {
+	closeAudit = func() error { return nil }
+	var opts []grpc.ServerOption
+
+	switch {
+	case cfg.tlsCert != "":
+		tlsCfg, err := cfg.tlsConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	case cfg.tlsClientCA != "":
+		return nil, nil, fmt.Errorf("-tls_client_ca requires -tls_cert")
+	}
+
+	if cfg.authConfig.Source == "" {
+		logger.Warning("No -auth_config; service is unauthenticated and SHOULD NOT be exposed beyond localhost")
+		return opts, closeAudit, nil
+	}
+
+	buf, err := cfg.authConfig.Fetch(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%T(%q).Fetch(): %v", &cfg.authConfig, cfg.authConfig.String(), err)
+	}
+	authCfg, err := firehose.ParseAuthConfig(buf)
+	if err != nil {
+		return nil, nil, err
+	}
+	authCfg.QuotaWindow = cfg.quotaWindow
 
-	return s.Serve(lis)
+	if cfg.auditLog != "" {
+		f, err := os.OpenFile(cfg.auditLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("os.OpenFile(%q): %v", cfg.auditLog, err)
+		}
+		authCfg.AuditLog = f
+		closeAudit = f.Close
+	}
+
+	auth, err := firehose.NewAuthenticator(*authCfg)
+	if err != nil {
+		closeAudit()
+		return nil, nil, fmt.Errorf("firehose.NewAuthenticator(…): %v", err)
+	}
+	return append(opts, auth.ServerOptions()...), closeAudit, nil
+}
+
+// tlsConfig returns the server's TLS config, which verifies client
+// certificates if -tls_client_ca is set.
+func (cfg *config) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.tlsCert, cfg.tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("tls.LoadX509KeyPair(%q, %q): %v", cfg.tlsCert, cfg.tlsKey, err)
+	}
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.tlsClientCA == "" {
+		return tlsCfg, nil
+	}
+
+	pem, err := os.ReadFile(cfg.tlsClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile(%q): %v", cfg.tlsClientCA, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificates in %q", cfg.tlsClientCA)
+	}
+	tlsCfg.ClientCAs = pool
+	// Clients MAY still authenticate with an API key instead.
+	tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsCfg, nil
 }
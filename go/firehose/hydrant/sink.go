@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/secrets"
+
+	"github.com/cxkoda/solgo/projects/indexing/firehose"
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+
+	_ "github.com/jackc/pgx/v4/stdlib" // postgres driver
+)
+
+// sinkConfig configures the optional sink mode, in which BlockResponses are
+// published to a Pub/Sub or Kafka topic; see firehose.Sink.
+type sinkConfig struct {
+	uri            string
+	only           bool
+	request        string
+	key            string
+	cursorDSN      secrets.Secret
+	cursorTable    string
+	skipEmpty      bool
+	checkpointEach int
+}
+
+func (c *sinkConfig) enabled() bool {
+	return c.uri != ""
+}
+
+// validate returns an error if the sink flags are inconsistent.
+func (c *sinkConfig) validate() error {
+	switch {
+	case !c.enabled():
+		if c.only {
+			return fmt.Errorf("-sink_only requires -sink")
+		}
+		return nil
+	case c.request == "":
+		return fmt.Errorf("-sink requires -sink_request")
+	case c.cursorDSN.Source == "":
+		return fmt.Errorf("-sink requires -sink_cursor_dsn")
+	}
+	return nil
+}
+
+// eventsRequest reads the protojson EventsRequest from the -sink_request file
+// and validates it, logging any warnings, so that mistakes are reported before
+// connecting to Firehose.
+func (c *sinkConfig) eventsRequest() (*svcpb.EventsRequest, error) {
+	buf, err := os.ReadFile(c.request)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile(%q): %v", c.request, err)
+	}
+	req := new(svcpb.EventsRequest)
+	if err := protojson.Unmarshal(buf, req); err != nil {
+		return nil, fmt.Errorf("protojson.Unmarshal(%q, %T): %v", c.request, req, err)
+	}
+
+	warnings, err := firehose.ValidateEventsRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("-sink_request %q: %s", c.request, status.Convert(err).Message())
+	}
+	for _, w := range warnings {
+		logger.Warning("Sink request warning", ethlog.String("file", c.request), ethlog.String("warning", w))
+	}
+	return req, nil
+}
+
+// A sinkPublisher is a firehose.Publisher that MUST be closed after use.
+type sinkPublisher interface {
+	firehose.Publisher
+	Close() error
+}
+
+// publisher returns the Publisher described by the -sink URI, along with the
+// topic name.
+func (c *sinkConfig) publisher(ctx context.Context) (sinkPublisher, string, error) {
+	u, err := url.Parse(c.uri)
+	if err != nil {
+		return nil, "", fmt.Errorf("url.Parse(%q): %v", c.uri, err)
+	}
+	topic := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || topic == "" || strings.Contains(topic, "/") {
+		return nil, "", fmt.Errorf("-sink %q must be pubsub://<project>/<topic> or kafka://<broker>[,<broker>…]/<topic>", c.uri)
+	}
+
+	switch u.Scheme {
+	case "pubsub":
+		p, err := newPubSubPublisher(ctx, u.Host, topic)
+		return p, topic, err
+	case "kafka":
+		return newKafkaPublisher(strings.Split(u.Host, ","), topic), topic, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported -sink scheme %q; must be pubsub or kafka", u.Scheme)
+	}
+}
+
+// run runs a firehose.Sink, publishing the -sink_request stream from the
+// client, until the stream ends or ctx is cancelled.
+func (c *sinkConfig) run(ctx context.Context, client svcpb.HydrantServiceClient) (retErr error) {
+	req, err := c.eventsRequest()
+	if err != nil {
+		return err
+	}
+
+	pub, topic, err := c.publisher(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := pub.Close(); retErr == nil {
+			retErr = err
+		}
+	}()
+
+	dsn, err := c.cursorDSN.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("%T(%q).Fetch(): %v", &c.cursorDSN, c.cursorDSN.String(), err)
+	}
+	db, err := sql.Open("pgx", string(dsn))
+	if err != nil {
+		return fmt.Errorf("sql.Open(pgx, [-sink_cursor_dsn]): %v", err)
+	}
+	defer db.Close()
+
+	cursors, err := firehose.NewPgCursorStore(db, c.cursorTable)
+	if err != nil {
+		return err
+	}
+	if err := cursors.CreateTable(ctx); err != nil {
+		return err
+	}
+
+	key := c.key
+	if key == "" {
+		key = topic
+	}
+	s := &firehose.Sink{
+		Client:          client,
+		Publisher:       pub,
+		Cursors:         cursors,
+		Key:             key,
+		SkipEmpty:       c.skipEmpty,
+		CheckpointEvery: c.checkpointEach,
+	}
+	logger.Info("Sink publishing", ethlog.String("uri", c.uri), ethlog.String("key", key))
+	return s.Run(ctx, req)
+}
+
+// pubsubPublisher publishes to a Google Pub/Sub topic with message ordering
+// enabled, using the firehose.SinkMessage.Key as the ordering key. The topic's
+// subscriptions MUST also enable ordering for it to be respected.
+type pubsubPublisher struct {
+	client *pubsub.Client
+	topic  *pubsub.Topic
+}
+
+func newPubSubPublisher(ctx context.Context, project, topic string) (*pubsubPublisher, error) {
+	client, err := pubsub.NewClient(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub.NewClient(%q): %v", project, err)
+	}
+	t := client.Topic(topic)
+	t.EnableMessageOrdering = true
+	return &pubsubPublisher{client: client, topic: t}, nil
+}
+
+// Publish implements firehose.Publisher, blocking until the message is
+// acknowledged by the Pub/Sub service.
+func (p *pubsubPublisher) Publish(ctx context.Context, msg *firehose.SinkMessage) error {
+	res := p.topic.Publish(ctx, &pubsub.Message{
+		Data:        msg.Data,
+		Attributes:  msg.Attributes,
+		OrderingKey: msg.Key,
+	})
+	if _, err := res.Get(ctx); err != nil {
+		// Publishing for an ordering key is paused after a failure, to avoid
+		// out-of-order delivery. The Sink resumes from its last checkpoint so
+		// it's safe to resume.
+		p.topic.ResumePublish(msg.Key)
+		return fmt.Errorf("%T.Publish(%s).Get(): %v", p.topic, p.topic, err)
+	}
+	return nil
+}
+
+func (p *pubsubPublisher) Close() error {
+	p.topic.Stop()
+	return p.client.Close()
+}
+
+// kafkaPublisher publishes to a Kafka topic, using the firehose.SinkMessage.Key
+// as the message key, and hence partition, so a stream's blocks are in order.
+type kafkaPublisher struct {
+	w *kafka.Writer
+}
+
+func newKafkaPublisher(brokers []string, topic string) *kafkaPublisher {
+	return &kafkaPublisher{
+		w: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// Blocks are published one at a time, and synchronously, so
+			// batching only adds latency.
+			BatchSize: 1,
+		},
+	}
+}
+
+// Publish implements firehose.Publisher, blocking until the message is
+// acknowledged by all in-sync replicas. Attributes are sent as headers.
+func (p *kafkaPublisher) Publish(ctx context.Context, msg *firehose.SinkMessage) error {
+	keys := make([]string, 0, len(msg.Attributes))
+	for k := range msg.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	headers := make([]kafka.Header, len(keys))
+	for i, k := range keys {
+		headers[i] = kafka.Header{Key: k, Value: []byte(msg.Attributes[k])}
+	}
+
+	err := p.w.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(msg.Key),
+		Value:   msg.Data,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("%T.WriteMessages(topic %q): %v", p.w, p.w.Topic, err)
+	}
+	return nil
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.w.Close()
+}
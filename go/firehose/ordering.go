@@ -0,0 +1,94 @@
+package firehose
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+
+	hosepb "github.com/streamingfast/pbgo/sf/firehose/v2"
+
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+)
+
+// MonotonicBlocks is a grpc.CallOption that, when passed to the Events methods
+// of a client returned by ETHClient(), guarantees that BlockResponses are
+// received in strictly increasing order of block number. Duplicate and
+// out-of-order blocks, typically caused by cursor replays after reconnecting,
+// are dropped.
+//
+// The highest delivered block is tracked by the MonotonicBlocks itself, so the
+// same instance SHOULD be passed to each call when reconnecting. The zero value
+// is ready to use and it MUST NOT be copied.
+//
+// The only exception to ordering is for blocks with FirehoseStep == STEP_UNDO,
+// which are always delivered and indicate that the block has been reverted by
+// a chain reorganisation. The block number that follows such an undo MAY
+// therefore be equal to that of the undone block.
+//
+// MonotonicBlocks is ignored by clients other than those returned by
+// ETHClient().
+type MonotonicBlocks struct {
+	grpc.EmptyCallOption
+
+	mu sync.Mutex
+	// last is the number of the last delivered block, only valid if delivered
+	// is true.
+	last      uint64
+	delivered bool
+
+	duplicate, outOfOrder atomic.Uint64
+}
+
+// Duplicates returns the number of dropped blocks that had the same number as
+// the last one delivered.
+func (m *MonotonicBlocks) Duplicates() uint64 {
+	return m.duplicate.Load()
+}
+
+// OutOfOrder returns the number of dropped blocks that had a lower number than
+// the last one delivered.
+func (m *MonotonicBlocks) OutOfOrder() uint64 {
+	return m.outOfOrder.Load()
+}
+
+// monotonicOption returns the last *MonotonicBlocks in opts, or nil if there
+// are none.
+func monotonicOption(opts []grpc.CallOption) *MonotonicBlocks {
+	var mono *MonotonicBlocks
+	for _, o := range opts {
+		if m, ok := o.(*MonotonicBlocks); ok {
+			mono = m
+		}
+	}
+	return mono
+}
+
+// accept returns whether b should be delivered, updating m's state if so.
+func (m *MonotonicBlocks) accept(b *svcpb.BlockResponse) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := b.GetBlock().GetNumber()
+
+	if b.FirehoseStep == hosepb.ForkStep_STEP_UNDO {
+		// The next block to be delivered can be the replacement of the one
+		// being undone.
+		m.last, m.delivered = n-1, n > 0
+		return true
+	}
+
+	switch {
+	case !m.delivered || n > m.last:
+		m.last, m.delivered = n, true
+		return true
+	case n == m.last:
+		logger.Debug("Dropping duplicate block", ethlog.Block(n))
+		m.duplicate.Add(1)
+	default:
+		logger.Debug("Dropping out-of-order block", ethlog.Block(n), ethlog.Uint("last", m.last))
+		m.outOfOrder.Add(1)
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package firehose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+)
+
+// A Publisher publishes messages to a broker; e.g. Google Pub/Sub or Kafka.
+// Publish() MUST NOT return a nil error until the broker has durably accepted
+// the message, as a Sink checkpoints the cursor of a BlockResponse once it is
+// published.
+type Publisher interface {
+	Publish(context.Context, *SinkMessage) error
+}
+
+// A SinkMessage is a BlockResponse encoded for publishing by a Sink.
+type SinkMessage struct {
+	// Key is the Sink's stream key, for use as an ordering key (Pub/Sub) or
+	// message key (Kafka) such that a stream's blocks are delivered in order.
+	Key string
+	// Data is the binary-encoded BlockResponse.
+	Data []byte
+	// Attributes carry metadata of the BlockResponse, allowing subscribers to
+	// filter and deduplicate messages without decoding Data. See the
+	// SinkAttribute* constants for keys.
+	Attributes map[string]string
+}
+
+// Keys of SinkMessage.Attributes.
+const (
+	SinkAttributeStream      = "stream"
+	SinkAttributeBlockNumber = "block_number"
+	SinkAttributeBlockHash   = "block_hash"
+	SinkAttributeCursor      = "cursor"
+	// SinkAttributeStep is the string representation of the Firehose ForkStep;
+	// subscribers MUST handle STEP_UNDO by reverting the block.
+	SinkAttributeStep = "step"
+)
+
+// A Sink streams BlockResponses from a Hydrant client and publishes them,
+// turning the stream into an ingestion pipeline that doesn't require a
+// connected consumer.
+//
+// The cursor of each BlockResponse is checkpointed in the CursorStore only
+// after it has been published, and Run() resumes from the last checkpoint.
+// Delivery is therefore at-least-once: any blocks published after the last
+// checkpoint, e.g. before a crash, are published again, and subscribers SHOULD
+// deduplicate by SinkAttributeBlockHash and SinkAttributeStep.
+type Sink struct {
+	Client    svcpb.HydrantServiceClient
+	Publisher Publisher
+	Cursors   CursorStore
+	// Key identifies the stream, both for storing cursors and as the
+	// SinkMessage.Key. Multiple Sinks MUST NOT share a Key.
+	Key string
+
+	// SkipEmpty, if true, results in BlockResponses without transactions not
+	// being published. Their cursors are still checkpointed.
+	SkipEmpty bool
+	// CheckpointEvery is the number of BlockResponses after which the cursor
+	// is checkpointed. It defaults to 1 if zero. Larger values reduce load on
+	// the CursorStore but increase the number of duplicates after a restart.
+	// The final cursor is always checkpointed when Run() returns.
+	CheckpointEvery int
+}
+
+func (s *Sink) checkpointEvery() int {
+	if s.CheckpointEvery <= 0 {
+		return 1
+	}
+	return s.CheckpointEvery
+}
+
+// Run streams BlockResponses for the request, which MUST NOT be modified
+// during the call, publishing them until the stream ends, in which case it
+// returns nil, or until an error occurs. Errors returned by the Publisher are
+// wrapped. If the request has an empty Cursor, the last checkpoint, if any, is
+// used instead.
+func (s *Sink) Run(ctx context.Context, req *svcpb.EventsRequest) (retErr error) {
+	if s.Key == "" {
+		return errors.New("empty Sink.Key")
+	}
+
+	if req.Cursor == "" {
+		cursor, err := s.Cursors.Load(ctx, s.Key)
+		if err != nil {
+			return fmt.Errorf("%T.Load(%q): %v", s.Cursors, s.Key, err)
+		}
+		req = proto.Clone(req).(*svcpb.EventsRequest)
+		req.Cursor = cursor
+	}
+
+	// As with PersistCursor, the final checkpoint uses the original Context,
+	// which isn't cancelled when the stream is.
+	storeCtx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := s.Client.Events(ctx, req)
+	if err != nil {
+		return fmt.Errorf("%T.Events(): %v", s.Client, err)
+	}
+
+	// unchecked is the cursor of the last published BlockResponse if it hasn't
+	// been checkpointed.
+	var (
+		unchecked string
+		pending   int
+	)
+	checkpoint := func(ctx context.Context) error {
+		if unchecked == "" {
+			return nil
+		}
+		if err := s.Cursors.Store(ctx, s.Key, unchecked); err != nil {
+			return fmt.Errorf("%T.Store(%q, [cursor]): %v", s.Cursors, s.Key, err)
+		}
+		unchecked = ""
+		pending = 0
+		return nil
+	}
+	defer func() {
+		if err := checkpoint(storeCtx); retErr == nil {
+			retErr = err
+		}
+	}()
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%T.Recv(): %v", stream, err)
+		}
+
+		if !s.SkipEmpty || len(resp.GetBlock().GetTransactions()) > 0 {
+			msg, err := s.message(resp)
+			if err != nil {
+				return err
+			}
+			if err := s.Publisher.Publish(ctx, msg); err != nil {
+				return fmt.Errorf("%T.Publish(block %d): %w", s.Publisher, resp.GetBlock().GetNumber(), err)
+			}
+		}
+
+		unchecked = resp.Cursor
+		if pending++; pending >= s.checkpointEvery() {
+			if err := checkpoint(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// message returns the SinkMessage that carries the BlockResponse.
+func (s *Sink) message(resp *svcpb.BlockResponse) (*SinkMessage, error) {
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("proto.Marshal(%T): %v", resp, err)
+	}
+	b := resp.GetBlock()
+	return &SinkMessage{
+		Key:  s.Key,
+		Data: data,
+		Attributes: map[string]string{
+			SinkAttributeStream:      s.Key,
+			SinkAttributeBlockNumber: strconv.FormatUint(b.GetNumber(), 10),
+			SinkAttributeBlockHash:   fmt.Sprintf("%#x", b.GetHash().GetBytes()),
+			SinkAttributeCursor:      resp.Cursor,
+			SinkAttributeStep:        resp.FirehoseStep.String(),
+		},
+	}, nil
+}
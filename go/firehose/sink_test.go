@@ -0,0 +1,174 @@
+package firehose_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cxkoda/solgo/projects/indexing/firehose"
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+)
+
+// fakeHydrant is a HydrantServiceClient that streams its blocks, starting
+// after the block with the request's cursor, if any.
+type fakeHydrant struct {
+	svcpb.HydrantServiceClient
+	blocks []*svcpb.BlockResponse
+}
+
+func (h *fakeHydrant) Events(ctx context.Context, req *svcpb.EventsRequest, _ ...grpc.CallOption) (svcpb.HydrantService_EventsClient, error) {
+	s := &fakeEventsStream{ctx: ctx}
+	s.blocks = h.blocks
+	if req.Cursor == "" {
+		return s, nil
+	}
+	for i, b := range h.blocks {
+		if b.Cursor == req.Cursor {
+			s.blocks = h.blocks[i+1:]
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown cursor %q", req.Cursor)
+}
+
+type fakeEventsStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	blocks []*svcpb.BlockResponse
+}
+
+func (s *fakeEventsStream) Recv() (*svcpb.BlockResponse, error) {
+	if err := s.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(s.blocks) == 0 {
+		return nil, io.EOF
+	}
+	b := s.blocks[0]
+	s.blocks = s.blocks[1:]
+	return b, nil
+}
+
+// fakePublisher records the block numbers of published messages, failing if
+// failAt is published.
+type fakePublisher struct {
+	published []uint64
+	failAt    uint64
+}
+
+var errPublish = errors.New("broker unavailable")
+
+func (p *fakePublisher) Publish(_ context.Context, msg *firehose.SinkMessage) error {
+	num, err := strconv.ParseUint(msg.Attributes[firehose.SinkAttributeBlockNumber], 10, 64)
+	if err != nil {
+		return err
+	}
+	if num == p.failAt {
+		return errPublish
+	}
+
+	resp := new(svcpb.BlockResponse)
+	if err := proto.Unmarshal(msg.Data, resp); err != nil {
+		return err
+	}
+	if got := resp.GetBlock().GetNumber(); got != num {
+		return fmt.Errorf("SinkMessage.Data has block %d; attributes have %d", got, num)
+	}
+	p.published = append(p.published, num)
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	ctx := context.Background()
+
+	var blocks []*svcpb.BlockResponse
+	for i := uint64(1); i <= 6; i++ {
+		b := &svcpb.BlockResponse{
+			Block:  &ethpb.Block{Number: i},
+			Cursor: fmt.Sprintf("cursor-%d", i),
+		}
+		if i%2 == 0 {
+			b.Block.Transactions = []*ethpb.Transaction{{}}
+		}
+		blocks = append(blocks, b)
+	}
+	client := &fakeHydrant{blocks: blocks}
+
+	tests := []struct {
+		name          string
+		sink          firehose.Sink
+		wantPublished []uint64
+	}{
+		{
+			name:          "all blocks",
+			wantPublished: []uint64{1, 2, 3, 4, 5, 6},
+		},
+		{
+			name:          "skip empty",
+			sink:          firehose.Sink{SkipEmpty: true},
+			wantPublished: []uint64{2, 4, 6},
+		},
+		{
+			name:          "infrequent checkpoints",
+			sink:          firehose.Sink{CheckpointEvery: 4},
+			wantPublished: []uint64{1, 2, 3, 4, 5, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := new(fakePublisher)
+			cursors := new(firehose.MemCursorStore)
+
+			s := tt.sink
+			s.Client = client
+			s.Publisher = pub
+			s.Cursors = cursors
+			s.Key = "stream"
+
+			if err := s.Run(ctx, &svcpb.EventsRequest{}); err != nil {
+				t.Fatalf("%T.Run() error %v", s, err)
+			}
+			if diff := cmp.Diff(tt.wantPublished, pub.published); diff != "" {
+				t.Errorf("%T.Run() published blocks diff (-want +got):\n%s", s, diff)
+			}
+			if got, err := cursors.Load(ctx, s.Key); err != nil || got != "cursor-6" {
+				t.Errorf("%T.Load(%q) after %T.Run() got %q, err = %v; want %q, nil err", cursors, s.Key, s, got, err, "cursor-6")
+			}
+		})
+	}
+
+	t.Run("resume after publishing failure", func(t *testing.T) {
+		pub := &fakePublisher{failAt: 4}
+		cursors := new(firehose.MemCursorStore)
+		s := &firehose.Sink{
+			Client:    client,
+			Publisher: pub,
+			Cursors:   cursors,
+			Key:       "stream",
+		}
+
+		if err := s.Run(ctx, &svcpb.EventsRequest{}); !errors.Is(err, errPublish) {
+			t.Fatalf("%T.Run() with failing publisher got err %v; want %v", s, err, errPublish)
+		}
+		if got, _ := cursors.Load(ctx, s.Key); got != "cursor-3" {
+			t.Errorf("%T.Load() after publishing failure got %q; want cursor of last published block", cursors, got)
+		}
+
+		pub.failAt = 0
+		if err := s.Run(ctx, &svcpb.EventsRequest{}); err != nil {
+			t.Fatalf("%T.Run() after recovery error %v", s, err)
+		}
+		if diff := cmp.Diff([]uint64{1, 2, 3, 4, 5, 6}, pub.published); diff != "" {
+			t.Errorf("published blocks across both calls to %T.Run() diff (-want +got):\n%s", s, diff)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package firehose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+	"github.com/cxkoda/solgo/go/otelonce"
+)
+
+// InstrumentationName is the name of the OpenTelemetry Meter and Tracer used
+// by this package. Metrics and traces are recorded with the global providers,
+// which default to no-ops; binaries that wish to export them MUST install
+// providers with otel.SetMeterProvider() and otel.SetTracerProvider().
+//
+// Metrics, all of which have "method" and "client" attributes, are:
+//   - hydrant.blocks.sent
+//   - hydrant.transactions.sent
+//   - hydrant.extraction.duration (seconds)
+//   - hydrant.stream.duration (seconds)
+//
+// The "client" attribute is the ClientConfig.ID of the caller, as
+// authenticated by an Authenticator, or "anonymous" if there is none; the
+// user-agent is deliberately not used as it is unbounded. Metrics of
+// clients using the BufferBlocks option, all of which have a "policy"
+// attribute, are:
+//   - hydrant.client.buffer.dropped
+//   - hydrant.client.buffer.spilled
+const InstrumentationName = "github.com/cxkoda/solgo/go/firehose"
+
+// instruments are the OpenTelemetry metric instruments used by ethHandler and
+// BufferBlocks.
+type instruments struct {
+	blocksSent, txsSent               metric.Int64Counter
+	extractionLatency, streamDuration metric.Float64Histogram
+	bufferDropped, bufferSpilled      metric.Int64Counter
+}
+
+// metrics returns the package's metric instruments.
+var metrics = otelonce.Instruments(InstrumentationName, func(m metric.Meter) (*instruments, error) {
+	inst := new(instruments)
+	var errs [6]error
+	inst.blocksSent, errs[0] = m.Int64Counter(
+		"hydrant.blocks.sent",
+		metric.WithDescription("Number of blocks sent to clients."),
+	)
+	inst.txsSent, errs[1] = m.Int64Counter(
+		"hydrant.transactions.sent",
+		metric.WithDescription("Number of transactions sent to clients."),
+	)
+	inst.extractionLatency, errs[2] = m.Float64Histogram(
+		"hydrant.extraction.duration",
+		metric.WithDescription("Time taken to extract events from a single Firehose block."),
+		metric.WithUnit("s"),
+	)
+	inst.streamDuration, errs[3] = m.Float64Histogram(
+		"hydrant.stream.duration",
+		metric.WithDescription("Lifetime of block streams."),
+		metric.WithUnit("s"),
+	)
+	inst.bufferDropped, errs[4] = m.Int64Counter(
+		"hydrant.client.buffer.dropped",
+		metric.WithDescription("Number of blocks dropped by clients because their buffer was full."),
+	)
+	inst.bufferSpilled, errs[5] = m.Int64Counter(
+		"hydrant.client.buffer.spilled",
+		metric.WithDescription("Number of blocks spilled to disk by clients because their buffer was full."),
+	)
+	return inst, errors.Join(errs[:]...)
+})
+
+// A streamTelemetry records metrics and a trace span for a single call to
+// ethHandler.events().
+type streamTelemetry struct {
+	inst  *instruments
+	span  trace.Span
+	attrs metric.MeasurementOption
+	start time.Time
+}
+
+// startStreamTelemetry starts a span for the stream and returns a Context
+// carrying it. The returned streamTelemetry's end() method MUST be called.
+func startStreamTelemetry(ctx context.Context) (context.Context, *streamTelemetry) {
+	method, ok := grpc.Method(ctx)
+	if !ok {
+		method = "in-process"
+	}
+	client, ok := ClientIDFromContext(ctx)
+	if !ok {
+		client = "anonymous"
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("method", method),
+		attribute.String("client", client),
+	}
+
+	ctx, span := otel.Tracer(InstrumentationName).Start(ctx, "firehose.events", trace.WithAttributes(attrs...))
+	return ctx, &streamTelemetry{
+		inst:  metrics(),
+		span:  span,
+		attrs: metric.WithAttributes(attrs...),
+		start: time.Now(),
+	}
+}
+
+// debug bridges ethlog to OpenTelemetry by logging at Debug level and also
+// recording the message, with the fields as attributes, as an event on the
+// stream's span.
+func (t *streamTelemetry) debug(msg string, fields ...ethlog.Field) {
+	logger.Debug(msg, fields...)
+
+	attrs := make([]attribute.KeyValue, len(fields))
+	for i, f := range fields {
+		attrs[i] = attribute.String(f.Key, fmt.Sprint(f.Value))
+	}
+	t.span.AddEvent(msg, trace.WithAttributes(attrs...))
+}
+
+// blockSent records the sending of a block with the specified number of
+// transactions, which took extraction time to extract.
+func (t *streamTelemetry) blockSent(ctx context.Context, txs int, extraction time.Duration) {
+	t.inst.blocksSent.Add(ctx, 1, t.attrs)
+	t.inst.txsSent.Add(ctx, int64(txs), t.attrs)
+	t.inst.extractionLatency.Record(ctx, extraction.Seconds(), t.attrs)
+}
+
+// end records the stream duration and ends the span, recording err on it if
+// non-nil.
+func (t *streamTelemetry) end(ctx context.Context, blocks, txs int, err error) {
+	t.inst.streamDuration.Record(ctx, time.Since(t.start).Seconds(), t.attrs)
+
+	t.span.SetAttributes(
+		attribute.Int("blocks_sent", blocks),
+		attribute.Int("transactions_sent", txs),
+	)
+	if err != nil {
+		t.span.RecordError(err)
+		t.span.SetStatus(otelcodes.Error, err.Error())
+	}
+	t.span.End()
+}
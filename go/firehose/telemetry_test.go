@@ -0,0 +1,156 @@
+package firehose
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+)
+
+func TestStreamTelemetry(t *testing.T) {
+	// The instruments are created from the global providers, so this test MUST
+	// NOT be run in parallel with any other that installs them.
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+
+	ctx := context.Background()
+	errStream := errors.New("stream failed")
+
+	authCtx, tel := startStreamTelemetry(context.WithValue(ctx, clientIDKey{}, "alice"))
+	tel.debug("Sending block", ethlog.Uint("block", 42))
+	tel.blockSent(authCtx, 3, time.Millisecond)
+	tel.blockSent(authCtx, 4, time.Millisecond)
+	tel.end(authCtx, 2, 7, errStream)
+
+	anonCtx, tel := startStreamTelemetry(ctx)
+	tel.blockSent(anonCtx, 1, time.Millisecond)
+	tel.end(anonCtx, 1, 1, nil)
+
+	alice := attribute.NewSet(
+		attribute.String("method", "in-process"),
+		attribute.String("client", "alice"),
+	)
+	anon := attribute.NewSet(
+		attribute.String("method", "in-process"),
+		attribute.String("client", "anonymous"),
+	)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("%T.Collect() error %v", reader, err)
+	}
+	// got maps metric name -> attribute set -> sum or histogram count.
+	got := make(map[string]map[attribute.Distinct]int64)
+	for _, sm := range rm.ScopeMetrics {
+		if sm.Scope.Name != InstrumentationName {
+			continue
+		}
+		for _, m := range sm.Metrics {
+			got[m.Name] = make(map[attribute.Distinct]int64)
+			switch d := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range d.DataPoints {
+					got[m.Name][dp.Attributes.Equivalent()] = dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range d.DataPoints {
+					got[m.Name][dp.Attributes.Equivalent()] = int64(dp.Count)
+				}
+			default:
+				t.Errorf("metric %q has unexpected data type %T", m.Name, d)
+			}
+		}
+	}
+
+	want := map[string]map[attribute.Distinct]int64{
+		"hydrant.blocks.sent": {
+			alice.Equivalent(): 2,
+			anon.Equivalent():  1,
+		},
+		"hydrant.transactions.sent": {
+			alice.Equivalent(): 7,
+			anon.Equivalent():  1,
+		},
+		"hydrant.extraction.duration": {
+			alice.Equivalent(): 2,
+			anon.Equivalent():  1,
+		},
+		"hydrant.stream.duration": {
+			alice.Equivalent(): 1,
+			anon.Equivalent():  1,
+		},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(attribute.Distinct{})); diff != "" {
+		t.Errorf("Collected metrics diff (-want +got):\n%s", diff)
+	}
+
+	ended := spans.Ended()
+	if n := len(ended); n != 2 {
+		t.Fatalf("%T.Ended() got %d spans; want 2", spans, n)
+	}
+
+	type span struct {
+		Name        string
+		Attrs       map[attribute.Key]string
+		Events      []string
+		Code        otelcodes.Code
+		Description string
+	}
+	var gotSpans []span
+	for _, s := range ended {
+		sp := span{
+			Name:        s.Name(),
+			Attrs:       make(map[attribute.Key]string),
+			Code:        s.Status().Code,
+			Description: s.Status().Description,
+		}
+		for _, kv := range s.Attributes() {
+			sp.Attrs[kv.Key] = kv.Value.Emit()
+		}
+		for _, ev := range s.Events() {
+			sp.Events = append(sp.Events, ev.Name)
+		}
+		gotSpans = append(gotSpans, sp)
+	}
+
+	wantSpans := []span{
+		{
+			Name: "firehose.events",
+			Attrs: map[attribute.Key]string{
+				"method":            "in-process",
+				"client":            "alice",
+				"blocks_sent":       "2",
+				"transactions_sent": "7",
+			},
+			Events:      []string{"Sending block", "exception"},
+			Code:        otelcodes.Error,
+			Description: errStream.Error(),
+		},
+		{
+			Name: "firehose.events",
+			Attrs: map[attribute.Key]string{
+				"method":            "in-process",
+				"client":            "anonymous",
+				"blocks_sent":       "1",
+				"transactions_sent": "1",
+			},
+			Code: otelcodes.Unset,
+		},
+	}
+	if diff := cmp.Diff(wantSpans, gotSpans); diff != "" {
+		t.Errorf("Ended spans diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,248 @@
+package firehose
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+)
+
+// ValidateEventsRequest performs all of the validation that the Hydrant
+// service performs on an EventsRequest, without starting a stream, and also
+// returns warnings about valid requests that are unlikely to be intended; e.g.
+// those without any contracts, or that never end. All errors have code
+// InvalidArgument and, for violations of the proto's validation rules, a
+// message describing every invalid field as with ethpb.Validate().
+//
+// See Estimator for an estimate of the request's volume.
+func ValidateEventsRequest(req *svcpb.EventsRequest) (warnings []string, _ error) {
+	_, warnings, err := validateEventsRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// validateEventsRequest implements ValidateEventsRequest(), additionally
+// returning extractors for all of the request's signatures, with its topic
+// filters added.
+func validateEventsRequest(req *svcpb.EventsRequest) (ethEventExtractors, []string, error) {
+	if err := ethpb.Validate(req); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var warnings []string
+	warnf := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+
+	extractors := make(ethEventExtractors)
+	for i, sig := range req.Signatures {
+		x, err := newEthEventExtractor(sig)
+		if err != nil {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "signatures[%d] (%s): %v", i, sig.EVMString(), err)
+		}
+		if _, ok := extractors[x.hash]; ok {
+			warnf("duplicate signature %s; only the last is used to parse events", sig.EVMString())
+		}
+		extractors[x.hash] = x
+	}
+	if err := extractors.addTopicFilters(req.TopicFilters); err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[common.Address]bool)
+	for i, addr := range req.Contracts {
+		if n := len(addr.GetBytes()); n != common.AddressLength {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "contracts[%d] of %d bytes; MUST be %d", i, n, common.AddressLength)
+		}
+		a := common.BytesToAddress(addr.Bytes)
+		if seen[a] {
+			warnf("duplicate contract %v", a)
+		}
+		seen[a] = true
+	}
+
+	if req.StopBlockNum != 0 && req.StartBlockNum >= 0 && uint64(req.StartBlockNum) > req.StopBlockNum {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "start block %d after stop block %d", req.StartBlockNum, req.StopBlockNum)
+	}
+
+	if len(req.Signatures) == 0 {
+		warnf("no signatures so no events will be returned")
+	}
+	if len(req.Contracts) == 0 {
+		warnf("no contracts so matching events from all contracts will be returned, which MAY be very high volume")
+	}
+	if req.StopBlockNum == 0 {
+		warnf("zero stop block so the stream will never end")
+	}
+	if req.Cursor != "" && req.StartBlockNum != 0 {
+		warnf("start block %d ignored in favour of cursor", req.StartBlockNum)
+	}
+	return extractors, warnings, nil
+}
+
+// An EstimateBackend is the subset of an *ethclient.Client required by an
+// Estimator.
+type EstimateBackend interface {
+	BlockNumber(context.Context) (uint64, error)
+	FilterLogs(context.Context, ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// An Estimator estimates the volume of events that an EventsRequest will
+// return by sampling logs from an Ethereum node, allowing mistakes to be
+// caught before a long stream is started.
+type Estimator struct {
+	Backend EstimateBackend
+	// Samples is the number of evenly spaced ranges of blocks that are
+	// sampled; it defaults to 10.
+	Samples int
+	// SampleBlocks is the number of blocks in each sampled range; it defaults
+	// to 100. Some nodes limit the range of eth_getLogs requests, which MUST
+	// therefore be no smaller than SampleBlocks.
+	SampleBlocks uint64
+}
+
+func (e *Estimator) samples() int {
+	if e.Samples <= 0 {
+		return 10
+	}
+	return e.Samples
+}
+
+func (e *Estimator) sampleBlocks() uint64 {
+	if e.SampleBlocks == 0 {
+		return 100
+	}
+	return e.SampleBlocks
+}
+
+// An EventsEstimate is the result of Estimator.Estimate().
+type EventsEstimate struct {
+	// Warnings are those returned by ValidateEventsRequest(), along with any
+	// raised while estimating.
+	Warnings []string
+	// StartBlock and StopBlock are the resolved, inclusive range of blocks
+	// that the request spans. If the request never ends, StopBlock is the
+	// current head.
+	StartBlock, StopBlock uint64
+	// Blocks is the number of blocks in [StartBlock, StopBlock].
+	Blocks uint64
+	// SampledBlocks is the number of blocks that were sampled, in which
+	// SampledEvents matching events were found.
+	SampledBlocks uint64
+	SampledEvents uint64
+	// Events is the extrapolated number of events in all Blocks.
+	Events uint64
+}
+
+// Estimate validates the request with ValidateEventsRequest() and, if valid,
+// estimates the number of events that it will return. TopicFilters are
+// ignored when sampling so the estimate is an upper bound if the request has
+// any. If the request has a cursor, the estimate is of the entire request as
+// if it didn't.
+func (e *Estimator) Estimate(ctx context.Context, req *svcpb.EventsRequest) (*EventsEstimate, error) {
+	warnings, err := ValidateEventsRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	est := &EventsEstimate{Warnings: warnings}
+	warnf := func(format string, args ...interface{}) {
+		est.Warnings = append(est.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	head, err := e.Backend.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%T.BlockNumber(): %v", e.Backend, err)
+	}
+
+	// A negative start block is relative to the head.
+	if s := req.StartBlockNum; s >= 0 {
+		est.StartBlock = uint64(s)
+	} else if back := uint64(-s); back < head {
+		est.StartBlock = head - back
+	}
+	est.StopBlock = req.StopBlockNum
+	if est.StopBlock == 0 || est.StopBlock > head {
+		est.StopBlock = head
+	}
+	if est.StartBlock > head {
+		warnf("start block %d after current head %d so volume can't be estimated", est.StartBlock, head)
+		return est, nil
+	}
+	est.Blocks = est.StopBlock - est.StartBlock + 1
+
+	if len(req.Signatures) == 0 {
+		return est, nil
+	}
+	q := ethereum.FilterQuery{
+		Addresses: make([]common.Address, len(req.Contracts)),
+		Topics:    [][]common.Hash{make([]common.Hash, len(req.Signatures))},
+	}
+	for i, addr := range req.Contracts {
+		q.Addresses[i] = common.BytesToAddress(addr.Bytes)
+	}
+	for i, sig := range req.Signatures {
+		q.Topics[0][i] = sig.EVMHash()
+	}
+
+	for _, r := range e.sampleRanges(est.StartBlock, est.StopBlock) {
+		q.FromBlock = new(big.Int).SetUint64(r[0])
+		q.ToBlock = new(big.Int).SetUint64(r[1])
+		logs, err := e.Backend.FilterLogs(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("%T.FilterLogs([blocks %d to %d]): %v", e.Backend, r[0], r[1], err)
+		}
+		est.SampledBlocks += r[1] - r[0] + 1
+		est.SampledEvents += uint64(len(logs))
+	}
+
+	if est.SampledBlocks == est.Blocks {
+		est.Events = est.SampledEvents
+	} else {
+		est.Events = uint64(float64(est.SampledEvents) / float64(est.SampledBlocks) * float64(est.Blocks))
+	}
+	if len(req.TopicFilters) > 0 {
+		warnf("topic filters ignored when estimating so %d events is an upper bound", est.Events)
+	}
+	return est, nil
+}
+
+// sampleRanges returns inclusive ranges of blocks, within [start, stop], to be
+// sampled. If the entire range is no larger than the total number of blocks
+// to be sampled, it is returned in its entirety, split into ranges of at most
+// SampleBlocks.
+func (e *Estimator) sampleRanges(start, stop uint64) [][2]uint64 {
+	size := e.sampleBlocks()
+	n := uint64(e.samples())
+	span := stop - start + 1
+
+	var rs [][2]uint64
+	if span <= n*size {
+		for from := start; from <= stop; from += size {
+			to := from + size - 1
+			if to > stop {
+				to = stop
+			}
+			rs = append(rs, [2]uint64{from, to})
+		}
+		return rs
+	}
+
+	for i := uint64(0); i < n; i++ {
+		from := start
+		if n > 1 {
+			from += i * (span - size) / (n - 1)
+		}
+		rs = append(rs, [2]uint64{from, from + size - 1})
+	}
+	return rs
+}
@@ -0,0 +1,206 @@
+package firehose_test
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cxkoda/solgo/projects/indexing/firehose"
+	"github.com/cxkoda/solgo/projects/indexing/firehose/firehosetest"
+
+	svcpb "github.com/cxkoda/solgo/projects/indexing/firehose/proto/eth"
+	ethpb "github.com/cxkoda/solgo/proto/eth"
+)
+
+func TestValidateEventsRequest(t *testing.T) {
+	contract := &ethpb.Address{Bytes: common.HexToAddress("0x01").Bytes()}
+
+	tests := []struct {
+		name string
+		req  *svcpb.EventsRequest
+		// wantWarnings are substrings of the respective warnings.
+		wantWarnings []string
+		wantCode     codes.Code
+		// wantErrMsg are substrings of the error message, if any.
+		wantErrMsg []string
+	}{
+		{
+			name: "fully specified",
+			req: &svcpb.EventsRequest{
+				Signatures:   []*ethpb.Event{firehose.ERC721TransferEvent()},
+				Contracts:    []*ethpb.Address{contract},
+				StopBlockNum: 100,
+			},
+		},
+		{
+			name: "warnings",
+			req: &svcpb.EventsRequest{
+				Signatures:    []*ethpb.Event{firehose.ERC721TransferEvent(), firehose.ERC721TransferEvent()},
+				StartBlockNum: 42,
+				Cursor:        "cursor",
+			},
+			wantWarnings: []string{"duplicate signature", "no contracts", "never end", "start block 42 ignored"},
+		},
+		{
+			name: "duplicate contract without signatures",
+			req: &svcpb.EventsRequest{
+				Contracts:    []*ethpb.Address{contract, contract},
+				StopBlockNum: 1,
+			},
+			wantWarnings: []string{"duplicate contract", "no signatures"},
+		},
+		{
+			name: "short address",
+			req: &svcpb.EventsRequest{
+				Signatures: []*ethpb.Event{firehose.ERC721TransferEvent()},
+				Contracts:  []*ethpb.Address{{Bytes: []byte{1}}},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "long addresses",
+			req: &svcpb.EventsRequest{
+				Signatures: []*ethpb.Event{firehose.ERC721TransferEvent()},
+				Contracts:  []*ethpb.Address{contract, {Bytes: make([]byte, 21)}, {Bytes: make([]byte, 32)}},
+			},
+			wantCode:   codes.InvalidArgument,
+			wantErrMsg: []string{"2 invalid fields", "contracts[1].bytes", "contracts[2].bytes", "20-byte address"},
+		},
+		{
+			name: "start after stop",
+			req: &svcpb.EventsRequest{
+				Signatures:    []*ethpb.Event{firehose.ERC721TransferEvent()},
+				Contracts:     []*ethpb.Address{contract},
+				StartBlockNum: 10,
+				StopBlockNum:  9,
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "unmatched topic filter",
+			req: &svcpb.EventsRequest{
+				Signatures: []*ethpb.Event{firehose.ERC721TransferEvent()},
+				Contracts:  []*ethpb.Address{contract},
+				TopicFilters: []*svcpb.TopicFilter{{
+					Argument: "nonexistent",
+					Values:   []*ethpb.Value{{Payload: &ethpb.Value_Bool{Bool: true}}},
+				}},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := firehose.ValidateEventsRequest(tt.req)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("ValidateEventsRequest(%+v) got err %v; want code %v", tt.req, err, tt.wantCode)
+			}
+			for _, want := range tt.wantErrMsg {
+				if msg := status.Convert(err).Message(); !strings.Contains(msg, want) {
+					t.Errorf("ValidateEventsRequest(%+v) got err %q; want containing %q", tt.req, msg, want)
+				}
+			}
+			if len(warnings) != len(tt.wantWarnings) {
+				t.Fatalf("ValidateEventsRequest(%+v) got warnings %q; want %d containing %q", tt.req, warnings, len(tt.wantWarnings), tt.wantWarnings)
+			}
+			for i, w := range warnings {
+				if !strings.Contains(w, tt.wantWarnings[i]) {
+					t.Errorf("ValidateEventsRequest(%+v) warning[%d] = %q; want containing %q", tt.req, i, w, tt.wantWarnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEstimator(t *testing.T) {
+	ctx := context.Background()
+	fake := firehosetest.NewFake(ctx, t)
+
+	emitterAddr, _, emit, err := DeployEmitter(fake.TxOpts(), fake.Backend())
+	if err != nil {
+		t.Fatalf("DeployEmitter(…) error %v", err)
+	}
+	// Events from other contracts MUST NOT be counted.
+	_, _, emit2, err := DeployEmitter(fake.TxOpts(), fake.Backend())
+	if err != nil {
+		t.Fatalf("DeployEmitter(…) error %v", err)
+	}
+	fake.MineBlock(ctx, t)
+
+	const numBlocks = 10
+	var first, last uint64
+	for i := 0; i < numBlocks; i++ {
+		for _, e := range []*Emitter{emit, emit2} {
+			if _, err := e.Transfer(fake.TxOpts(), common.HexToAddress("0xc0ffee"), common.HexToAddress("0xdead"), big.NewInt(int64(i))); err != nil {
+				t.Fatalf("%T.Transfer(…) error %v", e, err)
+			}
+		}
+		n := fake.MineBlock(ctx, t).NumberU64()
+		if i == 0 {
+			first = n
+		}
+		last = n
+	}
+	// Blocks after the stop block MUST NOT be sampled.
+	if _, err := emit.Transfer(fake.TxOpts(), common.HexToAddress("0xc0ffee"), common.HexToAddress("0xdead"), big.NewInt(numBlocks)); err != nil {
+		t.Fatalf("%T.Transfer(…) error %v", emit, err)
+	}
+	fake.MineBlock(ctx, t)
+
+	req := &svcpb.EventsRequest{
+		Signatures:    []*ethpb.Event{firehose.ERC721TransferEvent()},
+		Contracts:     []*ethpb.Address{{Bytes: emitterAddr.Bytes()}},
+		StartBlockNum: int64(first),
+		StopBlockNum:  last,
+	}
+
+	tests := []struct {
+		name              string
+		estimator         firehose.Estimator
+		wantSampledBlocks uint64
+	}{
+		{
+			name:              "entire range",
+			wantSampledBlocks: numBlocks,
+		},
+		{
+			name: "sampled",
+			estimator: firehose.Estimator{
+				Samples:      2,
+				SampleBlocks: 3,
+			},
+			wantSampledBlocks: 6,
+		},
+	}
+
+	client := fake.RPCClient(ctx, t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.estimator
+			e.Backend = client
+
+			got, err := e.Estimate(ctx, req)
+			if err != nil {
+				t.Fatalf("%T.Estimate(%+v) error %v", e, req, err)
+			}
+			if len(got.Warnings) != 0 {
+				t.Errorf("%T.Estimate(%+v) got warnings %q; want none", e, req, got.Warnings)
+			}
+			if got.StartBlock != first || got.StopBlock != last || got.Blocks != numBlocks {
+				t.Errorf("%T.Estimate(%+v) got blocks [%d, %d] (%d); want [%d, %d] (%d)", e, req, got.StartBlock, got.StopBlock, got.Blocks, first, last, numBlocks)
+			}
+			if got.SampledBlocks != tt.wantSampledBlocks || got.SampledEvents != tt.wantSampledBlocks {
+				t.Errorf("%T.Estimate(%+v) got %d events sampled from %d blocks; want %d from %d (one per block)", e, req, got.SampledEvents, got.SampledBlocks, tt.wantSampledBlocks, tt.wantSampledBlocks)
+			}
+			if got.Events != numBlocks {
+				t.Errorf("%T.Estimate(%+v).Events got %d; want %d", e, req, got.Events, numBlocks)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package flagtype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cxkoda/solgo/go/secrets"
+)
+
+// DefaultURLSchemes are the schemes accepted by a URL with empty Schemes.
+var DefaultURLSchemes = []string{"https", "wss"}
+
+// A URL is an absolute URL, restricted to a set of schemes, that accepts either
+// a literal value or a secrets.Secret from which the URL is fetched; e.g.
+// https://ipfs.io or env://NODE_URL. Secret URLs can therefore carry API keys
+// without the flag's consumer having to treat the two cases differently.
+//
+// Literal values, including those of the secrets.Raw Source, are validated by
+// Set() whereas other secrets are only validated when Resolve()d.
+type URL struct {
+	// Schemes are the allowed, lower-case URL schemes. If empty,
+	// DefaultURLSchemes are used.
+	Schemes []string
+
+	literal *url.URL
+	secret  *secrets.Secret
+}
+
+// NewURL returns a URL with the specified allowed schemes, Set() to raw. It is
+// typically used to define a flag's default value.
+func NewURL(raw string, schemes ...string) (*URL, error) {
+	u := &URL{Schemes: schemes}
+	if err := u.Set(raw); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// MustNewURL is equivalent to NewURL() except that it panics on error.
+func MustNewURL(raw string, schemes ...string) *URL {
+	u, err := NewURL(raw, schemes...)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// Set parses raw as a secrets.Secret if it is prefixed by a known
+// secrets.Source, otherwise as a literal URL. If raw == "" then u becomes unset.
+func (u *URL) Set(raw string) error {
+	u.literal, u.secret = nil, nil
+	if raw == "" {
+		return nil
+	}
+
+	prefix, _, ok := strings.Cut(raw, "://")
+	if !ok || !isSecretSource(secrets.Source(prefix)) {
+		return u.setLiteral(raw)
+	}
+
+	s := new(secrets.Secret)
+	if err := s.Set(raw); err != nil {
+		return err
+	}
+	if s.Source == secrets.Raw {
+		return u.setLiteral(s.ID)
+	}
+	u.secret = s
+	return nil
+}
+
+func isSecretSource(s secrets.Source) bool {
+	switch s {
+	case secrets.Raw, secrets.GCP, secrets.Environment, secrets.Template:
+		return true
+	}
+	return false
+}
+
+func (u *URL) setLiteral(raw string) error {
+	parsed, err := u.parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %v", raw, err)
+	}
+	u.literal = parsed
+	return nil
+}
+
+// parse parses and validates raw. Returned errors never include raw as it may
+// have been fetched from a secret.
+func (u *URL) parse(raw string) (*url.URL, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		var uErr *url.Error
+		if errors.As(err, &uErr) {
+			err = uErr.Err
+		}
+		return nil, err
+	}
+
+	schemes := u.Schemes
+	if len(schemes) == 0 {
+		schemes = DefaultURLSchemes
+	}
+	allowed := false
+	for _, s := range schemes {
+		if parsed.Scheme == s {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("scheme %q not in %q", parsed.Scheme, schemes)
+	}
+	if parsed.Host == "" {
+		return nil, errors.New("empty host")
+	}
+	return parsed, nil
+}
+
+// String returns the literal URL or the secrets.Secret reference, or the empty
+// string if u is unset. Values fetched by Resolve() are never returned.
+func (u *URL) String() string {
+	switch {
+	case u == nil:
+		return ""
+	case u.secret != nil:
+		return u.secret.String()
+	case u.literal != nil:
+		return u.literal.String()
+	default:
+		return ""
+	}
+}
+
+// Type returns the fully qualified type of u.
+func (u *URL) Type() string {
+	return fmt.Sprintf("%T", u)
+}
+
+// IsSet reports whether u has a literal or secret value.
+func (u *URL) IsSet() bool {
+	return u != nil && (u.literal != nil || u.secret != nil)
+}
+
+// Resolve returns the URL, Fetch()ing it with the Options if it is a
+// secrets.Secret, in which case the URL is validated as if it were a literal
+// and errors don't include the fetched value. Resolve returns an error if u is
+// unset; see IsSet().
+func (u *URL) Resolve(ctx context.Context, opts ...secrets.Option) (*url.URL, error) {
+	switch {
+	case !u.IsSet():
+		return nil, fmt.Errorf("%T unset", u)
+	case u.literal != nil:
+		cp := *u.literal
+		return &cp, nil
+	}
+
+	buf, err := u.secret.Fetch(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%T.Fetch(%v): %v", u.secret, u.secret, err)
+	}
+	parsed, err := u.parse(string(buf))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL in secret %v: %v", u.secret, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,173 @@
+package flagtype
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		envVar    = "FLAGTYPE_TEST_URL"
+		keyEnvVar = "FLAGTYPE_TEST_KEY"
+		badEnvVar = "FLAGTYPE_TEST_BAD_URL"
+	)
+	t.Setenv(envVar, "wss://node.example/v3/s3cr3t-k3y")
+	t.Setenv(keyEnvVar, "s3cr3t-k3y")
+	t.Setenv(badEnvVar, "node.example/v3/s3cr3t-k3y")
+
+	tests := []struct {
+		name    string
+		schemes []string
+		input   string
+		// wantSetErr is a substring of the error returned by Set(); if
+		// non-empty, nothing else is checked.
+		wantSetErr string
+		wantString string
+		// wantResolveErr is a substring of the error returned by Resolve();
+		// if non-empty, wantURL isn't checked.
+		wantResolveErr string
+		wantURL        string
+	}{
+		{
+			name:       "literal https",
+			input:      "https://ipfs.io/path",
+			wantString: "https://ipfs.io/path",
+			wantURL:    "https://ipfs.io/path",
+		},
+		{
+			name:       "literal scheme case insensitive",
+			input:      "HTTPS://ipfs.io",
+			wantString: "https://ipfs.io",
+			wantURL:    "https://ipfs.io",
+		},
+		{
+			name:       "disallowed scheme",
+			input:      "http://ipfs.io",
+			wantSetErr: `scheme "http" not in ["https" "wss"]`,
+		},
+		{
+			name:       "explicitly allowed scheme",
+			schemes:    []string{"http"},
+			input:      "http://localhost:8080",
+			wantString: "http://localhost:8080",
+			wantURL:    "http://localhost:8080",
+		},
+		{
+			name:       "relative",
+			input:      "ipfs.io",
+			wantSetErr: `scheme "" not in`,
+		},
+		{
+			name:       "empty host",
+			input:      "https:///path",
+			wantSetErr: "empty host",
+		},
+		{
+			name:       "raw secret validated as literal",
+			input:      "not-secret://https://ipfs.io",
+			wantString: "https://ipfs.io",
+			wantURL:    "https://ipfs.io",
+		},
+		{
+			name:       "invalid raw secret",
+			input:      "not-secret://ftp://ipfs.io",
+			wantSetErr: `scheme "ftp"`,
+		},
+		{
+			name:       "environment secret",
+			input:      "env://" + envVar,
+			wantString: "env://" + envVar,
+			wantURL:    "wss://node.example/v3/s3cr3t-k3y",
+		},
+		{
+			name:           "environment secret with disallowed scheme",
+			schemes:        []string{"https"},
+			input:          "env://" + envVar,
+			wantString:     "env://" + envVar,
+			wantResolveErr: `scheme "wss" not in ["https"]`,
+		},
+		{
+			name:           "missing environment variable",
+			input:          "env://FLAGTYPE_TEST_UNSET",
+			wantString:     "env://FLAGTYPE_TEST_UNSET",
+			wantResolveErr: "not set",
+		},
+		{
+			name:       "invalid environment secret",
+			input:      "env://" + badEnvVar,
+			wantString: "env://" + badEnvVar,
+			// The fetched value MUST NOT be included in the error.
+			wantResolveErr: `invalid URL in secret env://` + badEnvVar + `: scheme ""`,
+		},
+		{
+			name:       "template secret",
+			input:      "template://https://node.example/v3/${env://" + keyEnvVar + "}",
+			wantString: "template://https://node.example/v3/${env://" + keyEnvVar + "}",
+			wantURL:    "https://node.example/v3/s3cr3t-k3y",
+		},
+		{
+			name:           "unset",
+			input:          "",
+			wantString:     "",
+			wantResolveErr: "unset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("testing", flag.ContinueOnError)
+			u := &URL{Schemes: tt.schemes}
+			fs.Var(u, "url", "")
+
+			err := fs.Parse([]string{"-url", tt.input})
+			if tt.wantSetErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantSetErr) {
+					t.Errorf("%T.Parse(-url %q) got err %v; want containing %q", fs, tt.input, err, tt.wantSetErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%T.Parse(-url %q) error %v", fs, tt.input, err)
+			}
+
+			if got := u.String(); got != tt.wantString {
+				t.Errorf("%T.String() got %q; want %q", u, got, tt.wantString)
+			}
+
+			got, err := u.Resolve(ctx)
+			if tt.wantResolveErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantResolveErr) {
+					t.Fatalf("%T.Resolve() got err %v; want containing %q", u, err, tt.wantResolveErr)
+				}
+				if strings.Contains(err.Error(), "s3cr3t") {
+					t.Errorf("%T.Resolve() error %q includes secret value", u, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%T.Resolve() error %v", u, err)
+			}
+			if got.String() != tt.wantURL {
+				t.Errorf("%T.Resolve() got %v; want %s", u, got, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestMustNewURL(t *testing.T) {
+	u := MustNewURL("https://ipfs.io")
+	if got, want := u.String(), "https://ipfs.io"; got != want {
+		t.Errorf("MustNewURL(%q).String() got %q; want %q", want, got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MustNewURL(http://…) with default schemes did not panic")
+		}
+	}()
+	MustNewURL("http://ipfs.io")
+}
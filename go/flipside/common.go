@@ -17,6 +17,9 @@ import (
 type Config struct {
 	APIKey string
 	APIURL string
+	// FilesURL is the base URL from which query-run result files are
+	// downloaded; see DownloadQueryRunFile. It is not set by NewFromSecret.
+	FilesURL string
 }
 
 const apiURL = "https://api-v2.flipsidecrypto.xyz/json-rpc"
@@ -70,7 +73,7 @@ func submitRequest[T any](ctx context.Context, cfg *Config, request request[T])
 			return nil, fmt.Errorf("HTTP %d: io.ReadAll([resp.Body]): %v", resp.StatusCode, err)
 		}
 
-		return nil, fmt.Errorf("HTTP %d: %v", resp.StatusCode, string(body))
+		return nil, newAPIError(resp.StatusCode, resp.Header, body)
 	}
 
 	return resp.Body, nil
@@ -82,14 +85,14 @@ func submitParams[T any](ctx context.Context, cfg *Config, method string, params
 }
 
 type response[U any] struct {
-	JSONRPC string      `json:"jsonrpc"`
-	ID      int         `json:"id"`
-	Result  U           `json:"result"`
-	Error   interface{} `json:"error"`
+	JSONRPC string    `json:"jsonrpc"`
+	ID      int       `json:"id"`
+	Result  U         `json:"result"`
+	Error   *rpcError `json:"error"`
 }
 
 // parseResults decodes a flipside API response and returns the result.
-// Any flipside API error is returned as error.
+// Any flipside API error is returned as an *APIError.
 func parseResults[U any](r io.Reader) (*U, error) {
 	var resp response[U]
 	if err := json.NewDecoder(r).Decode(&resp); err != nil {
@@ -97,7 +100,12 @@ func parseResults[U any](r io.Reader) (*U, error) {
 	}
 
 	if resp.Error != nil {
-		return nil, fmt.Errorf("flipside API error: %+v", resp.Error)
+		return nil, &APIError{
+			StatusCode: http.StatusOK,
+			Code:       resp.Error.Code,
+			Message:    resp.Error.Message,
+			Data:       resp.Error.Data,
+		}
 	}
 
 	return &resp.Result, nil
@@ -107,7 +115,7 @@ func parseResults[U any](r io.Reader) (*U, error) {
 func submitParamsAndParseResults[T any, U any](ctx context.Context, cfg *Config, method string, params []T) (*U, error) {
 	raw, err := submitParams(ctx, cfg, method, params)
 	if err != nil {
-		return nil, fmt.Errorf("submitParams(ctx, cfg, %q, %+v): %v", method, params, err)
+		return nil, fmt.Errorf("submitParams(ctx, cfg, %q, %+v): %w", method, params, err)
 	}
 
 	return parseResults[U](raw)
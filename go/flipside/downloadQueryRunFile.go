@@ -0,0 +1,101 @@
+package flipside
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Files returns the names of the result files of the query run, parsed from
+// FileNames.
+func (r *QueryRun) Files() []string {
+	var names []string
+	for _, n := range strings.Split(r.FileNames, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// ResultFile returns the name of the single result file of the query run with
+// the given extension; e.g. ".parquet" or ".csv".
+func (r *QueryRun) ResultFile(ext string) (string, error) {
+	var match []string
+	for _, n := range r.Files() {
+		if strings.HasSuffix(n, ext) {
+			match = append(match, n)
+		}
+	}
+	if len(match) != 1 {
+		return "", fmt.Errorf("query run %s has %d files with extension %q; want 1 from %q", r.ID, len(match), ext, r.FileNames)
+	}
+	return match[0], nil
+}
+
+// DownloadQueryRunFile streams the named result file of a completed query run
+// to w, returning the number of bytes written. This bypasses the paginated
+// getQueryRunResults endpoint, which is impractical for very large results.
+// The name is typically one of run.Files().
+func (cfg *Config) DownloadQueryRunFile(ctx context.Context, run *QueryRun, name string, w io.Writer) (int64, error) {
+	if cfg.FilesURL == "" {
+		return 0, fmt.Errorf("%T.FilesURL not set", cfg)
+	}
+	if run.State != "QUERY_STATE_SUCCESS" {
+		return 0, fmt.Errorf("query run %s in state %s; want QUERY_STATE_SUCCESS", run.ID, run.State)
+	}
+
+	u, err := url.JoinPath(cfg.FilesURL, run.Path, name)
+	if err != nil {
+		return 0, fmt.Errorf("url.JoinPath(%q, %q, %q): %v", cfg.FilesURL, run.Path, name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, fmt.Errorf(`http.NewRequestWithContext(ctx, "GET", %q, nil): %v`, u, err)
+	}
+	cfg.addHeaders(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("http.DefaultClient.Do(%+v): %v", req, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return 0, fmt.Errorf("HTTP %d: io.ReadAll([resp.Body]): %v", resp.StatusCode, err)
+		}
+
+		return 0, newAPIError(resp.StatusCode, resp.Header, body)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("io.Copy(%T, [resp.Body]) after %d bytes: %v", w, n, err)
+	}
+	return n, nil
+}
+
+// DownloadQueryRunResults is a convenience wrapper that waits until a given
+// query succeeds and streams its single result file with the given extension
+// (see QueryRun.ResultFile) to w.
+func (cfg *Config) DownloadQueryRunResults(ctx context.Context, queryRunId QueryRunID, ext string, w io.Writer) (int64, error) {
+	// TODO expose this to the user, as with FetchQueryResults
+	initialBackoff := 1 * time.Second
+	backoffFactor := 1.2
+	run, err := cfg.AwaitQueryRunExecution(ctx, queryRunId, initialBackoff, backoffFactor)
+	if err != nil {
+		return 0, err
+	}
+
+	name, err := run.ResultFile(ext)
+	if err != nil {
+		return 0, err
+	}
+	return cfg.DownloadQueryRunFile(ctx, run, name, w)
+}
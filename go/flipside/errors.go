@@ -0,0 +1,180 @@
+package flipside
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for branching on the kind of API failure with errors.Is().
+// The typed errors that match them, *APIError and *QueryFailedError, can be
+// extracted with errors.As() for details.
+var (
+	// ErrUnauthorized is matched by *APIErrors for HTTP 401 and 403
+	// responses, typically due to a missing or invalid API key. Retrying is
+	// futile.
+	ErrUnauthorized = errors.New("flipside: unauthorized")
+	// ErrRateLimited is matched by *APIErrors for HTTP 429 responses; see
+	// RetryAfter().
+	ErrRateLimited = errors.New("flipside: rate limited")
+	// ErrQueryFailed is matched by *QueryFailedErrors.
+	ErrQueryFailed = errors.New("flipside: query failed")
+)
+
+// An APIError is returned, possibly wrapped, if the flipside API responds
+// with either a non-200 HTTP status or a JSON-RPC error.
+type APIError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// Code, Message, and Data are from the JSON-RPC error object, if one was
+	// returned; otherwise Code is zero and Message is the raw response body.
+	Code    int
+	Message string
+	Data    json.RawMessage
+	// RetryAfter is parsed from the Retry-After header of the response, and is
+	// zero if absent or invalid.
+	RetryAfter time.Duration
+}
+
+// Error returns a description of the error, including the HTTP status if not
+// 200 OK.
+func (e *APIError) Error() string {
+	var s strings.Builder
+	s.WriteString("flipside API error")
+	if e.StatusCode != http.StatusOK {
+		fmt.Fprintf(&s, ": HTTP %d", e.StatusCode)
+	}
+	if e.Code != 0 {
+		fmt.Fprintf(&s, ": code %d", e.Code)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&s, ": %s", e.Message)
+	}
+	if e.RetryAfter > 0 {
+		fmt.Fprintf(&s, " (retry after %v)", e.RetryAfter)
+	}
+	return s.String()
+}
+
+// Is returns whether target is the sentinel error corresponding to e's HTTP
+// status.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// newAPIError returns an *APIError for a response with the HTTP status,
+// headers, and body. If the body is a JSON-RPC response with an error object,
+// it is used for the details, otherwise the body becomes the Message.
+func newAPIError(status int, header http.Header, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: status,
+		RetryAfter: parseRetryAfter(header.Get("Retry-After"), time.Now()),
+	}
+
+	var resp struct {
+		Error *rpcError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error != nil {
+		e.Code = resp.Error.Code
+		e.Message = resp.Error.Message
+		e.Data = resp.Error.Data
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	return e
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a number
+// of seconds or an HTTP date, returning zero if empty or invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// RetryAfter returns the duration that the API requested be waited before
+// retrying, and true, if err is or wraps an *APIError that matches
+// ErrRateLimited. A zero duration with true means that the API didn't
+// specify; callers SHOULD then fall back to their own backoff.
+func RetryAfter(err error) (time.Duration, bool) {
+	var e *APIError
+	if !errors.As(err, &e) || !e.Is(ErrRateLimited) {
+		return 0, false
+	}
+	return e.RetryAfter, true
+}
+
+// A QueryFailedError is returned, possibly wrapped, by AwaitQueryRunSuccess()
+// and its callers if a query run completes without success. It matches
+// ErrQueryFailed.
+type QueryFailedError struct {
+	ID    QueryRunID
+	State string
+	// ErrorName, Message, and Data are propagated from the QueryRun.
+	ErrorName string
+	Message   string
+	Data      interface{}
+}
+
+func newQueryFailedError(run *QueryRun) *QueryFailedError {
+	e := &QueryFailedError{
+		ID:        run.ID,
+		State:     run.State,
+		ErrorName: run.ErrorName,
+		Data:      run.ErrorData,
+	}
+	switch m := run.ErrorMessage.(type) {
+	case nil:
+	case string:
+		e.Message = m
+	default:
+		e.Message = fmt.Sprintf("%v", m)
+	}
+	return e
+}
+
+// Error returns a description of the failure, including the error name and
+// message if reported by the API.
+func (e *QueryFailedError) Error() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "query run %s unsuccessful: %s", e.ID, e.State)
+	if e.ErrorName != "" {
+		fmt.Fprintf(&s, ": %s", e.ErrorName)
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&s, ": %s", e.Message)
+	}
+	return s.String()
+}
+
+// Is returns true iff target is ErrQueryFailed.
+func (e *QueryFailedError) Is(target error) bool {
+	return target == ErrQueryFailed
+}
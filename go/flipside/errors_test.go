@@ -0,0 +1,193 @@
+package flipside
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		header         http.Header
+		body           string
+		wantIs         []error
+		wantNotIs      []error
+		want           *APIError
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:      "unauthorized",
+			status:    http.StatusUnauthorized,
+			body:      "Invalid API key\n",
+			wantIs:    []error{ErrUnauthorized},
+			wantNotIs: []error{ErrRateLimited, ErrQueryFailed},
+			want: &APIError{
+				StatusCode: http.StatusUnauthorized,
+				Message:    "Invalid API key",
+			},
+		},
+		{
+			name:      "forbidden",
+			status:    http.StatusForbidden,
+			body:      `{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"forbidden"}}`,
+			wantIs:    []error{ErrUnauthorized},
+			wantNotIs: []error{ErrRateLimited},
+			want: &APIError{
+				StatusCode: http.StatusForbidden,
+				Code:       -32001,
+				Message:    "forbidden",
+			},
+		},
+		{
+			name:      "rate limited",
+			status:    http.StatusTooManyRequests,
+			header:    http.Header{"Retry-After": {"30"}},
+			body:      `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"too many requests","data":{"limit":10}}}`,
+			wantIs:    []error{ErrRateLimited},
+			wantNotIs: []error{ErrUnauthorized},
+			want: &APIError{
+				StatusCode: http.StatusTooManyRequests,
+				Code:       -32000,
+				Message:    "too many requests",
+				Data:       json.RawMessage(`{"limit":10}`),
+				RetryAfter: 30 * time.Second,
+			},
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			name:      "rate limited without Retry-After",
+			status:    http.StatusTooManyRequests,
+			body:      "slow down",
+			wantIs:    []error{ErrRateLimited},
+			wantNotIs: []error{ErrUnauthorized},
+			want: &APIError{
+				StatusCode: http.StatusTooManyRequests,
+				Message:    "slow down",
+			},
+		},
+		{
+			name:      "JSON-RPC error with HTTP 200",
+			status:    http.StatusOK,
+			body:      `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"invalid params"}}`,
+			wantNotIs: []error{ErrUnauthorized, ErrRateLimited, ErrQueryFailed},
+			want: &APIError{
+				StatusCode: http.StatusOK,
+				Code:       -32602,
+				Message:    "invalid params",
+			},
+		},
+		{
+			name:      "server error",
+			status:    http.StatusInternalServerError,
+			body:      "oops",
+			wantNotIs: []error{ErrUnauthorized, ErrRateLimited, ErrQueryFailed},
+			want: &APIError{
+				StatusCode: http.StatusInternalServerError,
+				Message:    "oops",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, vs := range tt.header {
+					w.Header()[k] = vs
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(server.Close)
+
+			fs := &Config{APIKey: "test", APIURL: server.URL}
+			_, err := fs.GetQueryRun(context.Background(), "")
+
+			for _, target := range tt.wantIs {
+				if !errors.Is(err, target) {
+					t.Errorf("GetQueryRun() err: %v; want errors.Is(…, %v)", err, target)
+				}
+			}
+			for _, target := range tt.wantNotIs {
+				if errors.Is(err, target) {
+					t.Errorf("GetQueryRun() err: %v; want !errors.Is(…, %v)", err, target)
+				}
+			}
+
+			var got *APIError
+			if !errors.As(err, &got) {
+				t.Fatalf("GetQueryRun() err: %v; want errors.As(…, %T)", err, got)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("errors.As(GetQueryRun() err, %T) diff (-want +got):\n%s", got, diff)
+			}
+
+			gotWait, gotOK := RetryAfter(err)
+			if wantOK := errors.Is(err, ErrRateLimited); gotWait != tt.wantRetryAfter || gotOK != wantOK {
+				t.Errorf("RetryAfter(GetQueryRun() err) got (%v, %t); want (%v, %t)", gotWait, gotOK, tt.wantRetryAfter, wantOK)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 4, 5, 20, 14, 55, 0, time.UTC)
+
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"120", 2 * time.Minute},
+		{"-1", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q, %v) got %v; want %v", tt.header, now, got, tt.want)
+		}
+	}
+}
+
+func TestAwaitQueryRunSuccessFailed(t *testing.T) {
+	run := &QueryRun{
+		ID:           "failed",
+		State:        "QUERY_STATE_FAILED",
+		ErrorName:    "QueryRunExecutionError",
+		ErrorMessage: "SQL compilation error: invalid identifier 'FOO'",
+	}
+	resp := QueryRunResponses{responses: []*QueryRun{run}}
+	server := newFlipsideMockServer(t, resp.next)
+
+	fs := &Config{APIKey: "test", APIURL: server.URL}
+	err := fs.AwaitQueryRunSuccess(context.Background(), run.ID, time.Millisecond, 1)
+	if !errors.Is(err, ErrQueryFailed) {
+		t.Fatalf("AwaitQueryRunSuccess([failed run]) err: %v; want errors.Is(…, %v)", err, ErrQueryFailed)
+	}
+
+	var got *QueryFailedError
+	if !errors.As(err, &got) {
+		t.Fatalf("AwaitQueryRunSuccess([failed run]) err: %v; want errors.As(…, %T)", err, got)
+	}
+	want := &QueryFailedError{
+		ID:        run.ID,
+		State:     run.State,
+		ErrorName: "QueryRunExecutionError",
+		Message:   "SQL compilation error: invalid identifier 'FOO'",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("errors.As(AwaitQueryRunSuccess() err, %T) diff (-want +got):\n%s", got, diff)
+	}
+}
@@ -1,9 +1,11 @@
 package flipside
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -416,3 +418,98 @@ func TestGetQueryRunResults(t *testing.T) {
 		},
 	})
 }
+
+func TestDownloadQueryRunResults(t *testing.T) {
+	const (
+		apiKey  = "secret"
+		path    = "2023/04/05/20/clg44olzq00cbn60tasvob5l2"
+		parquet = "clg44olzq00cbn60tasvob5l2-consolidated-results.parquet"
+		csv     = "clg44olzq00cbn60tasvob5l2-consolidated-results.csv"
+		content = "a,b\n1,2\n"
+	)
+	getQueryRun := `{"jsonrpc":"2.0","id":1,"result":{"queryRun":{"id":"clg44olzq00cbn60tasvob5l2","state":"QUERY_STATE_SUCCESS","path":"` + path + `","fileCount":2,"fileNames":"` + parquet + `,` + csv + `"}}}`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(getQueryRun))
+	})
+	mux.HandleFunc("/files/"+path+"/"+csv, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("x-api-key"); got != apiKey {
+			http.Error(w, "bad key", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(content))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	fs := Config{APIKey: apiKey, APIURL: server.URL + "/rpc", FilesURL: server.URL + "/files"}
+
+	t.Run("ok", func(t *testing.T) {
+		var got bytes.Buffer
+		n, err := fs.DownloadQueryRunResults(ctx, "clg44olzq00cbn60tasvob5l2", ".csv", &got)
+		if err != nil {
+			t.Fatalf("DownloadQueryRunResults(…, .csv) error %v", err)
+		}
+		if got.String() != content || n != int64(len(content)) {
+			t.Errorf("DownloadQueryRunResults(…, .csv) wrote %d bytes %q; want %q", n, got.String(), content)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, ext := range []string{".parquet" /* not served */, ".json" /* no such file */} {
+			if _, err := fs.DownloadQueryRunResults(ctx, "clg44olzq00cbn60tasvob5l2", ext, io.Discard); err == nil {
+				t.Errorf("DownloadQueryRunResults(…, %q) got nil error; want non-nil", ext)
+			}
+		}
+
+		noFiles := fs
+		noFiles.FilesURL = ""
+		if _, err := noFiles.DownloadQueryRunResults(ctx, "clg44olzq00cbn60tasvob5l2", ".csv", io.Discard); err == nil {
+			t.Error("DownloadQueryRunResults() without FilesURL got nil error; want non-nil")
+		}
+
+		failed := &QueryRun{State: "QUERY_STATE_FAILED", Path: path, FileNames: csv}
+		if _, err := fs.DownloadQueryRunFile(ctx, failed, csv, io.Discard); err == nil {
+			t.Error("DownloadQueryRunFile([failed run]) got nil error; want non-nil")
+		}
+	})
+}
+
+func TestQueryRunFiles(t *testing.T) {
+	r := &QueryRun{FileNames: "a.parquet, b.csv,"}
+	if diff := cmp.Diff([]string{"a.parquet", "b.csv"}, r.Files()); diff != "" {
+		t.Errorf("%T{FileNames: %q}.Files() diff (-want +got):\n%s", r, r.FileNames, diff)
+	}
+	if got, err := r.ResultFile(".csv"); err != nil || got != "b.csv" {
+		t.Errorf("%T.ResultFile(.csv) got %q, err %v; want b.csv, nil", r, got, err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	pages := []*QueryRunResults[map[string]any]{
+		{
+			ColumnNames: []string{"holder", "balance", "label"},
+			Rows: []map[string]any{
+				{"holder": "0xa11ce", "balance": float64(42), "label": "alice"},
+				{"holder": "0xb0b", "balance": float64(7), "label": nil},
+			},
+		},
+		{
+			ColumnNames: []string{"holder", "balance", "label"},
+			Rows: []map[string]any{
+				{"holder": "0xc4a41e", "balance": float64(1)},
+			},
+		},
+	}
+
+	var got bytes.Buffer
+	if err := WriteCSV(&got, pages); err != nil {
+		t.Fatalf("WriteCSV() error %v", err)
+	}
+	want := "holder,balance,label\n0xa11ce,42,alice\n0xb0b,7,\n0xc4a41e,1,\n"
+	if diff := cmp.Diff(want, got.String()); diff != "" {
+		t.Errorf("WriteCSV() diff (-want +got):\n%s", diff)
+	}
+}
@@ -38,7 +38,7 @@ func (cfg *Config) GetQueryRun(ctx context.Context, id QueryRunID) (*QueryRun, e
 func (cfg *Config) AwaitQueryRunExecution(ctx context.Context, queryRunId QueryRunID, initialBackoff time.Duration, backoffFactor float64) (*QueryRun, error) {
 	run, err := cfg.GetQueryRun(ctx, queryRunId)
 	if err != nil {
-		return nil, fmt.Errorf("getQueryRun(ctx, %q): %v", queryRunId, err)
+		return nil, fmt.Errorf("getQueryRun(ctx, %q): %w", queryRunId, err)
 	}
 
 	glog.Infof("Query %s status: %v", queryRunId, run.State)
@@ -58,7 +58,7 @@ func (cfg *Config) AwaitQueryRunExecution(ctx context.Context, queryRunId QueryR
 }
 
 // AwaitQueryRunSuccess waits for a specific query run to complete successfully.
-// Unsuccessful queries will return an error.
+// Unsuccessful queries will return a *QueryFailedError.
 func (cfg *Config) AwaitQueryRunSuccess(ctx context.Context, queryRunId QueryRunID, initialBackoff time.Duration, backoffFactor float64) error {
 	run, err := cfg.AwaitQueryRunExecution(ctx, queryRunId, initialBackoff, backoffFactor)
 	if err != nil {
@@ -66,7 +66,7 @@ func (cfg *Config) AwaitQueryRunSuccess(ctx context.Context, queryRunId QueryRun
 	}
 
 	if run.State != "QUERY_STATE_SUCCESS" {
-		return fmt.Errorf("Query %s unsuccessful: %+v", queryRunId, run)
+		return newQueryFailedError(run)
 	}
 
 	return nil
@@ -3,7 +3,10 @@ package flipside
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
+
+	"github.com/cxkoda/solgo/go/eth/export"
 )
 
 type requestPage struct {
@@ -52,7 +55,7 @@ func GetQueryRunResults[T any](ctx context.Context, cfg *Config, queryRunId Quer
 			},
 		})
 	if err != nil {
-		return nil, fmt.Errorf("submitParamsAndDecode[GetQueryRunResultsRequestParams, GetQueryRunResultsResponse](ctx, cfg, \"createQueryRun\", []QueryRunResultsRequest{...}): %v", err)
+		return nil, fmt.Errorf("submitParamsAndDecode[GetQueryRunResultsRequestParams, GetQueryRunResultsResponse](ctx, cfg, \"createQueryRun\", []QueryRunResultsRequest{...}): %w", err)
 	}
 
 	return ret, nil
@@ -66,15 +69,20 @@ func FetchQueryResults[T any](ctx context.Context, cfg *Config, queryRunId Query
 	initialBackoff := 1 * time.Second
 	backoffFactor := 1.2
 	if err := cfg.AwaitQueryRunSuccess(ctx, queryRunId, initialBackoff, backoffFactor); err != nil {
-		return nil, fmt.Errorf("cfg.awaitQueryRun(ctx, %q, ..): %v", queryRunId, err)
+		return nil, fmt.Errorf("cfg.awaitQueryRun(ctx, %q, ..): %w", queryRunId, err)
 	}
+	return fetchAllPages[T](ctx, cfg, queryRunId)
+}
 
+// fetchAllPages fetches all pages of results of a successfully completed query
+// run.
+func fetchAllPages[T any](ctx context.Context, cfg *Config, queryRunId QueryRunID) ([]*QueryRunResults[T], error) {
 	var results []*QueryRunResults[T]
 	numPages := 1
 	for i := 1; i <= numPages; i++ {
 		res, err := GetQueryRunResults[T](ctx, cfg, queryRunId, i)
 		if err != nil {
-			return nil, fmt.Errorf("%T.GetQueryRunResults(ctx, %q, page=%d): %v", cfg, queryRunId, i, err)
+			return nil, fmt.Errorf("%T.GetQueryRunResults(ctx, %q, page=%d): %w", cfg, queryRunId, i, err)
 		}
 		results = append(results, res)
 		numPages = res.Page.TotalPages
@@ -82,3 +90,34 @@ func FetchQueryResults[T any](ctx context.Context, cfg *Config, queryRunId Query
 
 	return results, nil
 }
+
+// WriteCSV writes the rows of all pages as CSV, using the ColumnNames of the
+// first page as headers. Values are formatted with `export.FormatString`; in
+// particular, nulls and missing columns are empty.
+func WriteCSV(w io.Writer, pages []*QueryRunResults[map[string]any]) error {
+	c := export.NewCSV(w)
+	if len(pages) == 0 {
+		return c.Flush()
+	}
+
+	cols := pages[0].ColumnNames
+	if err := c.Header(cols); err != nil {
+		return fmt.Errorf("%T.Header([column names]): %v", c, err)
+	}
+	for _, p := range pages {
+		for _, row := range p.Rows {
+			vals := make([]any, len(cols))
+			for i, col := range cols {
+				vals[i] = row[col]
+			}
+			if err := c.Row(vals); err != nil {
+				return fmt.Errorf("%T.Row([data]): %v", c, err)
+			}
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		return fmt.Errorf("%T.Flush(): %v", c, err)
+	}
+	return nil
+}
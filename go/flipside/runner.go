@@ -0,0 +1,299 @@
+package flipside
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// A Node is a named query in a Runner's dependency graph. It is implemented by
+// *Query[T] for all T.
+type Node interface {
+	name() string
+	deps() []string
+	sql() string
+	// fetch fetches and stores all results of the successful query run.
+	fetch(context.Context, *Config, QueryRunID) error
+	// results returns the rows stored by fetch.
+	results() interface{}
+}
+
+// A Query is a Node that decodes result rows into values of type T.
+//
+// The SQL is a text/template that is executed with a map from the Name of
+// each dependency to its results (i.e. the []U of a *Query[U]), allowing the
+// output of one query to feed into another. The `quote` template function
+// returns its argument as an SQL string literal; e.g.
+//
+//	SELECT * FROM t WHERE address IN (
+//	{{- range $i, $h := .holders}}{{if $i}}, {{end}}{{quote $h.Address}}{{end -}}
+//	)
+type Query[T any] struct {
+	Name string
+	SQL  string
+	Deps []string
+
+	rows []T
+}
+
+var _ Node = (*Query[struct{}])(nil)
+
+// NewQuery returns a new Query.
+func NewQuery[T any](name, sql string, deps ...string) *Query[T] {
+	return &Query[T]{
+		Name: name,
+		SQL:  sql,
+		Deps: deps,
+	}
+}
+
+// Results returns the results of the Query after Runner.Run() returns nil.
+func (q *Query[T]) Results() []T {
+	return q.rows
+}
+
+func (q *Query[T]) name() string         { return q.Name }
+func (q *Query[T]) deps() []string       { return q.Deps }
+func (q *Query[T]) sql() string          { return q.SQL }
+func (q *Query[T]) results() interface{} { return q.rows }
+
+func (q *Query[T]) fetch(ctx context.Context, cfg *Config, id QueryRunID) error {
+	pages, err := fetchAllPages[T](ctx, cfg, id)
+	if err != nil {
+		return err
+	}
+	var rows []T
+	for _, p := range pages {
+		rows = append(rows, p.Rows...)
+	}
+	q.rows = rows
+	return nil
+}
+
+// A Runner runs a set of Nodes concurrently, respecting their dependencies.
+// Polling for the completion of query runs, and retrying of those that fail,
+// is handled centrally.
+type Runner struct {
+	Config *Config
+	// Concurrency is the maximum number of simultaneous query runs. If
+	// non-positive, 1 is used.
+	Concurrency int
+	// Retries is the number of times that a failed query run is re-created
+	// before Run() returns an error. Errors matching ErrUnauthorized are never
+	// retried, and retries after ErrRateLimited wait for at least the period
+	// requested by the API; see RetryAfter().
+	Retries int
+	// InitialBackoff and BackoffFactor are passed to
+	// Config.AwaitQueryRunExecution(). If zero, they default to 1s and 1.2
+	// respectively, as with FetchQueryResults().
+	InitialBackoff time.Duration
+	BackoffFactor  float64
+}
+
+// templateFuncs are available to all Query templates.
+var templateFuncs = template.FuncMap{
+	"quote": func(v interface{}) string {
+		return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+	},
+}
+
+// Run runs all nodes, each only once all of its dependencies have succeeded.
+// Results are available via each Query's Results() method. The first error
+// cancels all other query runs, and is returned. Run returns an error without
+// running any queries if names aren't unique, a dependency is missing, the
+// dependency graph has a cycle, or a template can't be parsed.
+func (r *Runner) Run(ctx context.Context, nodes ...Node) error {
+	byName := make(map[string]Node)
+	for _, n := range nodes {
+		if n.name() == "" {
+			return fmt.Errorf("%T with empty name", n)
+		}
+		if _, ok := byName[n.name()]; ok {
+			return fmt.Errorf("duplicate query name %q", n.name())
+		}
+		byName[n.name()] = n
+	}
+	if err := checkGraph(nodes, byName); err != nil {
+		return err
+	}
+
+	tmpls := make(map[string]*template.Template)
+	for _, n := range nodes {
+		t, err := template.New(n.name()).Funcs(templateFuncs).Option("missingkey=error").Parse(n.sql())
+		if err != nil {
+			return fmt.Errorf("parsing SQL template of query %q: %v", n.name(), err)
+		}
+		tmpls[n.name()] = t
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	done := make(map[string]chan struct{})
+	for _, n := range nodes {
+		done[n.name()] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n Node) {
+			defer wg.Done()
+
+			data := make(map[string]interface{})
+			for _, d := range n.deps() {
+				select {
+				case <-done[d]:
+					data[d] = byName[d].results()
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			if err := r.runNode(ctx, n, tmpls[n.name()], data); err != nil {
+				fail(err)
+				return
+			}
+			close(done[n.name()])
+		}(n)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	// The parent context may have been cancelled before any node failed.
+	return ctx.Err()
+}
+
+// runNode executes the template and runs the resulting SQL, retrying up to
+// r.Retries times. The returned error wraps that of the last attempt.
+func (r *Runner) runNode(ctx context.Context, n Node, tmpl *template.Template, data map[string]interface{}) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("executing SQL template of query %q: %v", n.name(), err)
+	}
+	sql := buf.String()
+
+	backoff, factor := r.InitialBackoff, r.BackoffFactor
+	if backoff == 0 {
+		backoff = time.Second
+	}
+	if factor == 0 {
+		factor = 1.2
+	}
+
+	var err error
+	for attempt := 0; attempt <= r.Retries; attempt++ {
+		if attempt > 0 {
+			glog.Warningf("Retrying query %q (attempt %d of %d) after error: %v", n.name(), attempt+1, r.Retries+1, err)
+		}
+		if err = r.runOnce(ctx, n, sql, backoff, factor); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || errors.Is(err, ErrUnauthorized) {
+			break
+		}
+		if wait, ok := RetryAfter(err); ok && wait > 0 && attempt < r.Retries {
+			glog.Warningf("Query %q rate limited; waiting %v before retrying", n.name(), wait)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("query %q: %w", n.name(), err)
+			case <-time.After(wait):
+			}
+		}
+	}
+	return fmt.Errorf("query %q: %w", n.name(), err)
+}
+
+// runOnce creates a single query run of the sql, awaits its completion, and
+// fetches its results into n.
+func (r *Runner) runOnce(ctx context.Context, n Node, sql string, backoff time.Duration, factor float64) error {
+	created, err := r.Config.CreateQueryRun(ctx, sql)
+	if err != nil {
+		return fmt.Errorf("%T.CreateQueryRun(): %w", r.Config, err)
+	}
+	id := created.QueryRun.ID
+	glog.Infof("Query %q created run %s", n.name(), id)
+
+	if err := r.Config.AwaitQueryRunSuccess(ctx, id, backoff, factor); err != nil {
+		return err
+	}
+	if err := n.fetch(ctx, r.Config, id); err != nil {
+		return fmt.Errorf("fetching results of run %s: %w", id, err)
+	}
+	return nil
+}
+
+// checkGraph returns an error if any dependency is missing or if there is a
+// cycle.
+func checkGraph(nodes []Node, byName map[string]Node) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+
+	var visit func(Node, []string) error
+	visit = func(n Node, path []string) error {
+		path = append(path, n.name())
+		switch state[n.name()] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("query dependency cycle: %s", strings.Join(path, " -> "))
+		}
+
+		state[n.name()] = visiting
+		for _, d := range n.deps() {
+			dep, ok := byName[d]
+			if !ok {
+				return fmt.Errorf("query %q depends on unknown query %q", n.name(), d)
+			}
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[n.name()] = visited
+		return nil
+	}
+
+	for _, n := range nodes {
+		if err := visit(n, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package flipside
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeRunnerAPI mocks the flipside API methods used by a Runner. Every SQL
+// statement is expected to be of the form `<key>[ <anything>]`, and the rows
+// returned for the respective query run are rows[key].
+type fakeRunnerAPI struct {
+	rows map[string][]map[string]interface{}
+	// failures is the number of times query runs with the given key fail
+	// before succeeding.
+	failures map[string]int
+
+	mu          sync.Mutex
+	sql         []string
+	runs        map[QueryRunID]string // query-run ID to key
+	failed      map[QueryRunID]bool
+	active      int
+	maxActive   int
+	numRequests int
+}
+
+func (f *fakeRunnerAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request[map[string]interface{}]
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := req.Params[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.numRequests++
+
+	var result interface{}
+	switch req.Method {
+	case "createQueryRun":
+		sql := params["sql"].(string)
+		f.sql = append(f.sql, sql)
+		key, _, _ := strings.Cut(sql, " ")
+
+		id := QueryRunID(fmt.Sprintf("run-%d", len(f.sql)))
+		f.runs[id] = key
+		if f.failures[key] > 0 {
+			f.failures[key]--
+			f.failed[id] = true
+		}
+
+		f.active++
+		if f.active > f.maxActive {
+			f.maxActive = f.active
+		}
+		result = CreateQueryRunResponse{QueryRun: QueryRun{ID: id}}
+
+	case "getQueryRun":
+		id := QueryRunID(params["queryRunId"].(string))
+		state := "QUERY_STATE_SUCCESS"
+		if f.failed[id] {
+			state = "QUERY_STATE_FAILED"
+			f.active--
+		}
+		result = getQueryRunResponse{QueryRun{ID: id, State: state}}
+
+	case "getQueryRunResults":
+		id := QueryRunID(params["queryRunId"].(string))
+		f.active--
+		result = QueryRunResults[map[string]interface{}]{
+			Rows: f.rows[f.runs[id]],
+			Page: ResultsPage{TotalPages: 1},
+		}
+
+	default:
+		http.Error(w, "unknown method "+req.Method, http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response[interface{}]{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+func newFakeRunner(t *testing.T, api *fakeRunnerAPI, concurrency, retries int) *Runner {
+	t.Helper()
+	api.runs = make(map[QueryRunID]string)
+	api.failed = make(map[QueryRunID]bool)
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	return &Runner{
+		Config:         &Config{APIKey: "test", APIURL: server.URL},
+		Concurrency:    concurrency,
+		Retries:        retries,
+		InitialBackoff: time.Millisecond,
+		BackoffFactor:  1,
+	}
+}
+
+func TestRunner(t *testing.T) {
+	type holder struct {
+		Address string `json:"address"`
+	}
+	type balance struct {
+		Address string `json:"address"`
+		Balance int    `json:"balance"`
+	}
+	type count struct {
+		N int `json:"n"`
+	}
+
+	api := &fakeRunnerAPI{
+		rows: map[string][]map[string]interface{}{
+			"holders": {
+				{"address": "0xa"},
+				{"address": "0xb'c"},
+			},
+			"balances": {
+				{"address": "0xa", "balance": 1},
+				{"address": "0xb'c", "balance": 2},
+			},
+			"count": {{"n": 42}},
+			"other": {{"n": 1}},
+		},
+		failures: map[string]int{"balances": 1},
+	}
+	runner := newFakeRunner(t, api, 2, 1)
+
+	holders := NewQuery[holder]("holders", "holders")
+	balances := NewQuery[balance](
+		"balances",
+		`balances WHERE address IN ({{range $i, $h := .holders}}{{if $i}}, {{end}}{{quote $h.Address}}{{end}}) AND n = {{(index .count 0).N}}`,
+		"holders", "count",
+	)
+	counts := NewQuery[count]("count", "count")
+	other := NewQuery[count]("other", "other")
+
+	if err := runner.Run(context.Background(), balances, holders, counts, other); err != nil {
+		t.Fatalf("%T.Run() error %v", runner, err)
+	}
+
+	wantBalances := []balance{{"0xa", 1}, {"0xb'c", 2}}
+	if diff := cmp.Diff(wantBalances, balances.Results()); diff != "" {
+		t.Errorf("%T.Results() diff (-want +got):\n%s", balances, diff)
+	}
+	if diff := cmp.Diff([]count{{1}}, other.Results()); diff != "" {
+		t.Errorf("%T.Results() of independent query diff (-want +got):\n%s", other, diff)
+	}
+
+	const wantSQL = `balances WHERE address IN ('0xa', '0xb''c') AND n = 42`
+	var gotSQL []string
+	for _, s := range api.sql {
+		if strings.HasPrefix(s, "balances") {
+			gotSQL = append(gotSQL, s)
+		}
+	}
+	// The first run fails, and is retried.
+	if diff := cmp.Diff([]string{wantSQL, wantSQL}, gotSQL); diff != "" {
+		t.Errorf("SQL of dependent query diff (-want +got):\n%s", diff)
+	}
+
+	if got, want := api.maxActive, runner.Concurrency; got > want {
+		t.Errorf("%T.Run() had %d concurrent query runs; want <= %d", runner, got, want)
+	}
+}
+
+func TestRunnerErrors(t *testing.T) {
+	type row struct {
+		N int `json:"n"`
+	}
+
+	tests := []struct {
+		name         string
+		nodes        []Node
+		failures     map[string]int
+		retries      int
+		wantRequests bool
+	}{
+		{
+			name: "duplicate name",
+			nodes: []Node{
+				NewQuery[row]("a", "a"),
+				NewQuery[row]("a", "a"),
+			},
+		},
+		{
+			name:  "empty name",
+			nodes: []Node{NewQuery[row]("", "a")},
+		},
+		{
+			name:  "unknown dependency",
+			nodes: []Node{NewQuery[row]("a", "a", "b")},
+		},
+		{
+			name: "cycle",
+			nodes: []Node{
+				NewQuery[row]("a", "a", "c"),
+				NewQuery[row]("b", "b", "a"),
+				NewQuery[row]("c", "c", "b"),
+			},
+		},
+		{
+			name:  "invalid template",
+			nodes: []Node{NewQuery[row]("a", "a {{")},
+		},
+		{
+			name: "missing template data",
+			nodes: []Node{
+				NewQuery[row]("a", "a"),
+				NewQuery[row]("b", "b {{.c}}", "a"),
+			},
+			wantRequests: true,
+		},
+		{
+			name: "retries exhausted",
+			nodes: []Node{
+				NewQuery[row]("a", "a"),
+				NewQuery[row]("b", "b", "a"),
+			},
+			failures:     map[string]int{"a": 2},
+			retries:      1,
+			wantRequests: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeRunnerAPI{
+				rows: map[string][]map[string]interface{}{
+					"a": {{"n": 1}},
+					"b": {{"n": 2}},
+				},
+				failures: tt.failures,
+			}
+			runner := newFakeRunner(t, api, 1, tt.retries)
+
+			if err := runner.Run(context.Background(), tt.nodes...); err == nil {
+				t.Errorf("%T.Run() got nil error; want non-nil", runner)
+			}
+			if got := api.numRequests > 0; got != tt.wantRequests {
+				t.Errorf("%T.Run() sent API requests = %t; want %t", runner, got, tt.wantRequests)
+			}
+			for _, s := range api.sql {
+				if strings.HasPrefix(s, "b") {
+					t.Errorf("%T.Run() ran query %q after failure of its dependency", runner, s)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerDoesNotRetryUnauthorized(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "Invalid API key", http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	runner := &Runner{
+		Config:         &Config{APIKey: "bad", APIURL: server.URL},
+		Retries:        3,
+		InitialBackoff: time.Millisecond,
+	}
+	type row struct{}
+	err := runner.Run(context.Background(), NewQuery[row]("a", "a"))
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("%T.Run() err: %v; want errors.Is(…, %v)", runner, err, ErrUnauthorized)
+	}
+	if requests != 1 {
+		t.Errorf("%T.Run() with %d retries sent %d requests after %v; want 1", runner, runner.Retries, requests, ErrUnauthorized)
+	}
+}
@@ -0,0 +1,147 @@
+package grpctest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A DeadlineChecker asserts that RPC handlers respect client deadlines, ending
+// with codes.DeadlineExceeded within a tolerance of the deadline. Clients
+// always observe the deadline being exceeded, regardless of the handler, so
+// the DeadlineChecker instead inspects calls from the server side, where a
+// handler that ignores its Context wastes resources. Its ServerOptions() must
+// be passed to the Tester (or any function that accepts grpc.ServerOptions,
+// e.g. NewClientConnTB()).
+//
+// Calls without deadlines are ignored. A DeadlineChecker is safe for
+// concurrent use.
+type DeadlineChecker struct {
+	tolerance time.Duration
+
+	mu         sync.Mutex
+	inFlight   map[*deadlineCall]struct{}
+	violations []error
+}
+
+// A deadlineCall is an RPC with a deadline, observed by a DeadlineChecker.
+type deadlineCall struct {
+	method   string
+	deadline time.Time
+}
+
+// NewDeadlineChecker returns a new DeadlineChecker that allows handlers to
+// return up to the tolerance after their deadlines.
+func NewDeadlineChecker(tolerance time.Duration) *DeadlineChecker {
+	return &DeadlineChecker{
+		tolerance: tolerance,
+		inFlight:  make(map[*deadlineCall]struct{}),
+	}
+}
+
+// ServerOptions returns the grpc.ServerOptions that install the
+// DeadlineChecker's interceptors. As with a Recorder, chained interceptors are
+// used so other interceptors can still be provided; the DeadlineChecker
+// observes errors as returned by interceptors that are installed after it.
+func (d *DeadlineChecker) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(d.unary),
+		grpc.ChainStreamInterceptor(d.stream),
+	}
+}
+
+// start records the beginning of a call if, and only if, its Context has a
+// deadline. The returned function MUST be called with the handler's error.
+func (d *DeadlineChecker) start(ctx context.Context, method string) func(error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return func(error) {}
+	}
+	c := &deadlineCall{
+		method:   method,
+		deadline: deadline,
+	}
+
+	d.mu.Lock()
+	d.inFlight[c] = struct{}{}
+	d.mu.Unlock()
+
+	return func(err error) {
+		end := time.Now()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.inFlight, c)
+		if err := c.check(end, err, d.tolerance); err != nil {
+			d.violations = append(d.violations, err)
+		}
+	}
+}
+
+// check returns an error if a handler that returned err at time end didn't
+// respect the deadline.
+func (c *deadlineCall) check(end time.Time, err error, tolerance time.Duration) error {
+	if end.Before(c.deadline) {
+		return nil
+	}
+	if late := end.Sub(c.deadline); late > tolerance {
+		return fmt.Errorf("%s returned %v after deadline; tolerance %v", c.method, late, tolerance)
+	}
+	if got := status.Code(err); got != codes.DeadlineExceeded {
+		return fmt.Errorf("%s returned after deadline with error %v and status code %v; want %v", c.method, err, got, codes.DeadlineExceeded)
+	}
+	return nil
+}
+
+func (d *DeadlineChecker) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	done := d.start(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	done(err)
+	return resp, err
+}
+
+func (d *DeadlineChecker) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	done := d.start(ss.Context(), info.FullMethod)
+	err := handler(srv, ss)
+	done(err)
+	return err
+}
+
+// Check returns an error describing all calls that violated their deadlines,
+// including those still in progress for longer than the tolerance after their
+// deadlines. A handler that returns after its deadline, but within the
+// tolerance, is only considered to be in violation if it returns an error
+// without codes.DeadlineExceeded; status.FromContextError() is typically used
+// to convert Context errors.
+//
+// Check only reflects calls that started before it was called, and SHOULD
+// therefore be called only after clients have observed the deadlines being
+// exceeded, plus the tolerance.
+func (d *DeadlineChecker) Check() error {
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	errs := append([]error(nil), d.violations...)
+	for c := range d.inFlight {
+		if late := now.Sub(c.deadline); late > d.tolerance {
+			errs = append(errs, fmt.Errorf("%s still running %v after deadline; tolerance %v", c.method, late, d.tolerance))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Assert calls tb.Errorf() if Check() returns an error.
+func (d *DeadlineChecker) Assert(tb testing.TB) {
+	tb.Helper()
+	if err := d.Check(); err != nil {
+		tb.Errorf("%T.Check(): %v", d, err)
+	}
+}
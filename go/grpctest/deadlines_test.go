@@ -0,0 +1,135 @@
+package grpctest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/cxkoda/solgo/go/grpctest/proto"
+)
+
+// deadlineEcho is an echo service whose Echo() waits before responding,
+// optionally ignoring its Context.
+type deadlineEcho struct {
+	echo
+	wait time.Duration
+	// ignoreCtx, if true, results in Echo() waiting for the full duration.
+	ignoreCtx bool
+	// errCode is the code returned if the Context is Done.
+	errCode codes.Code
+}
+
+func (e *deadlineEcho) Echo(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	t := time.NewTimer(e.wait)
+	defer t.Stop()
+
+	if e.ignoreCtx {
+		<-t.C
+		return &pb.Response{Msg: in.Msg}, nil
+	}
+	select {
+	case <-t.C:
+		return &pb.Response{Msg: in.Msg}, nil
+	case <-ctx.Done():
+		return nil, status.Error(e.errCode, ctx.Err().Error())
+	}
+}
+
+func TestDeadlineChecker(t *testing.T) {
+	const (
+		wait      = 200 * time.Millisecond
+		timeout   = 20 * time.Millisecond
+		tolerance = 50 * time.Millisecond
+	)
+
+	tests := []struct {
+		name        string
+		svc         *deadlineEcho
+		noDeadline  bool
+		wantViolate bool
+	}{
+		{
+			name: "respects deadline",
+			svc: &deadlineEcho{
+				wait:    wait,
+				errCode: codes.DeadlineExceeded,
+			},
+		},
+		{
+			name: "ignores Context",
+			svc: &deadlineEcho{
+				wait:      wait,
+				ignoreCtx: true,
+			},
+			wantViolate: true,
+		},
+		{
+			name: "wrong code",
+			svc: &deadlineEcho{
+				wait:    wait,
+				errCode: codes.Internal,
+			},
+			wantViolate: true,
+		},
+		{
+			name: "no deadline",
+			svc: &deadlineEcho{
+				wait:      timeout,
+				ignoreCtx: true,
+			},
+			noDeadline: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDeadlineChecker(tolerance)
+			client := pb.NewEchoServiceClient(NewClientConnTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, tt.svc, d.ServerOptions()...))
+
+			ctx := context.Background()
+			wantCode := codes.OK
+			if !tt.noDeadline {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+				wantCode = codes.DeadlineExceeded
+			}
+			_, err := client.Echo(ctx, &pb.Request{Msg: "hi"})
+			AssertCode(t, err, wantCode)
+
+			// Even a handler that respects its deadline may return after the
+			// client has observed it being exceeded.
+			time.Sleep(2 * tolerance)
+			if err := d.Check(); (err != nil) != tt.wantViolate {
+				t.Errorf("%T.Check() got err %v; want violation? %t", d, err, tt.wantViolate)
+			}
+		})
+	}
+}
+
+func TestDeadlineCheckerInFlight(t *testing.T) {
+	const (
+		timeout   = 10 * time.Millisecond
+		tolerance = 10 * time.Millisecond
+	)
+
+	d := NewDeadlineChecker(tolerance)
+	svc := &deadlineEcho{
+		wait:      200 * time.Millisecond,
+		ignoreCtx: true,
+	}
+	client := pb.NewEchoServiceClient(NewClientConnTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, svc, d.ServerOptions()...))
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, err := client.Echo(ctx, &pb.Request{Msg: "hi"})
+	AssertCode(t, err, codes.DeadlineExceeded)
+
+	time.Sleep(2 * tolerance)
+	if err := d.Check(); err == nil {
+		t.Errorf("%T.Check() while handler ignoring its deadline still running; got nil error", d)
+	}
+}
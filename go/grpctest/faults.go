@@ -0,0 +1,257 @@
+package grpctest
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A Latency returns the delay to inject before an RPC's handler is called,
+// using the source of randomness for non-constant distributions.
+type Latency func(*rand.Rand) time.Duration
+
+// FixedLatency returns a Latency that always returns d.
+func FixedLatency(d time.Duration) Latency {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// UniformLatency returns a Latency that is uniformly distributed in [min,max).
+func UniformLatency(min, max time.Duration) Latency {
+	return func(rng *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rng.Int63n(int64(max-min)))
+	}
+}
+
+// A Fault describes the failures injected into calls of an RPC method by a
+// FaultInjector. The zero value injects no faults.
+type Fault struct {
+	// Latency, if non-nil, delays every call before its handler is invoked. If
+	// the call's context is cancelled, or its deadline exceeded, during the
+	// delay then the respective status is returned without invoking the
+	// handler.
+	Latency Latency
+
+	// FailFirst is the number of calls that fail, with ErrorCode, before any
+	// are allowed to succeed. Subsequent calls fail with probability ErrorRate
+	// in [0,1]. Failed calls don't invoke the handler. If the Fault is set for
+	// AllMethods, calls to all methods without their own Fault are counted
+	// together.
+	FailFirst int
+	ErrorRate float64
+	// ErrorCode is the status code of injected errors and stream resets. It
+	// defaults to codes.Unavailable, which clients typically treat as
+	// retryable.
+	ErrorCode codes.Code
+
+	// If ResetAfter is positive then server-streaming calls are reset with
+	// probability ResetRate in [0,1], ending with ErrorCode immediately after
+	// ResetAfter messages have been sent to the client. The handler's context
+	// is cancelled and all of its subsequent sends fail.
+	ResetAfter int
+	ResetRate  float64
+}
+
+func (f *Fault) code() codes.Code {
+	if f.ErrorCode == codes.OK {
+		return codes.Unavailable
+	}
+	return f.ErrorCode
+}
+
+// AllMethods can be passed to FaultInjector.Set() to configure the Fault used
+// for all methods that don't have their own.
+const AllMethods = ""
+
+// A FaultInjector injects latency, errors, and mid-stream resets into RPCs
+// received by a grpc.Server, allowing client retry and timeout logic to be
+// tested. Its ServerOptions() must be passed to the Tester (or any function
+// that accepts grpc.ServerOptions, e.g. NewClientConnTB()).
+//
+// All randomness is derived from a single seeded source so, for calls that are
+// made sequentially, injected faults are deterministic. A FaultInjector is
+// safe for concurrent use.
+type FaultInjector struct {
+	mu     sync.Mutex
+	rng    *rand.Rand
+	faults map[string]*Fault
+	calls  map[string]int
+}
+
+// NewFaultInjector returns a new FaultInjector, which injects no faults until
+// Set() is called.
+func NewFaultInjector(seed int64) *FaultInjector {
+	return &FaultInjector{
+		rng:    rand.New(rand.NewSource(seed)),
+		faults: make(map[string]*Fault),
+		calls:  make(map[string]int),
+	}
+}
+
+// Set configures the Fault to inject into calls to the RPC method, which MUST
+// be of the form /package.service/method, or AllMethods. A nil Fault removes
+// the configuration. Changes only affect calls that start after Set() returns,
+// and the count of calls used for Fault.FailFirst is reset.
+func (fi *FaultInjector) Set(fullMethod string, f *Fault) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	if f == nil {
+		delete(fi.faults, fullMethod)
+	} else {
+		fi.faults[fullMethod] = f
+	}
+	delete(fi.calls, fullMethod)
+}
+
+// ServerOptions returns the grpc.ServerOptions that install the
+// FaultInjector's interceptors. As with a Recorder, chained interceptors are
+// used so other interceptors can still be provided; those installed before the
+// FaultInjector observe injected faults as if they were returned by the
+// handler.
+func (fi *FaultInjector) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(fi.unary),
+		grpc.ChainStreamInterceptor(fi.stream),
+	}
+}
+
+// A plannedFault is the concrete set of faults to inject into a single call.
+type plannedFault struct {
+	latency time.Duration
+	err     error
+	// resetAfter is the number of messages after which a stream is reset; zero
+	// implies no reset.
+	resetAfter int
+	resetErr   error
+}
+
+// plan determines the faults to inject into a call of the method.
+func (fi *FaultInjector) plan(method string) plannedFault {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	key := method
+	f, ok := fi.faults[key]
+	if !ok {
+		key = AllMethods
+		if f, ok = fi.faults[key]; !ok {
+			return plannedFault{}
+		}
+	}
+	n := fi.calls[key]
+	fi.calls[key]++
+
+	var p plannedFault
+	if f.Latency != nil {
+		p.latency = f.Latency(fi.rng)
+	}
+	if n < f.FailFirst || fi.rng.Float64() < f.ErrorRate {
+		p.err = status.Errorf(f.code(), "grpctest: injected error in call %d to %s", n, method)
+	}
+	if f.ResetAfter > 0 && fi.rng.Float64() < f.ResetRate {
+		p.resetAfter = f.ResetAfter
+		p.resetErr = status.Errorf(f.code(), "grpctest: injected stream reset after %d messages in call %d to %s", f.ResetAfter, n, method)
+	}
+	return p
+}
+
+// delay blocks for the planned latency, returning early with an error if ctx
+// is Done.
+func (p *plannedFault) delay(ctx context.Context) error {
+	if p.latency <= 0 {
+		return nil
+	}
+	t := time.NewTimer(p.latency)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (fi *FaultInjector) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	p := fi.plan(info.FullMethod)
+	if err := p.delay(ctx); err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return handler(ctx, req)
+}
+
+func (fi *FaultInjector) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	p := fi.plan(info.FullMethod)
+	if err := p.delay(ss.Context()); err != nil {
+		return err
+	}
+	if p.err != nil {
+		return p.err
+	}
+	if p.resetAfter == 0 || !info.IsServerStream {
+		return handler(srv, ss)
+	}
+
+	ctx, cancel := context.WithCancel(ss.Context())
+	defer cancel()
+	rs := &resettingStream{
+		ServerStream: ss,
+		ctx:          ctx,
+		cancel:       cancel,
+		remaining:    p.resetAfter,
+		err:          p.resetErr,
+	}
+	err := handler(srv, rs)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.reset {
+		return rs.err
+	}
+	return err
+}
+
+// A resettingStream is a grpc.ServerStream that is reset after sending a
+// specific number of messages.
+type resettingStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	remaining int
+	reset     bool
+	err       error
+}
+
+func (s *resettingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *resettingStream) SendMsg(m interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reset {
+		return s.err
+	}
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	s.remaining--
+	if s.remaining == 0 {
+		s.reset = true
+		s.cancel()
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package grpctest
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	pb "github.com/cxkoda/solgo/go/grpctest/proto"
+)
+
+const (
+	echoMethod       = "/EchoService/Echo"
+	echoStreamMethod = "/EchoService/EchoStream"
+)
+
+// newFaultyEchoClient returns a client of an echo server with fi's
+// interceptors installed.
+func newFaultyEchoClient(t *testing.T, fi *FaultInjector) pb.EchoServiceClient {
+	t.Helper()
+	return pb.NewEchoServiceClient(NewClientConnTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, &echo{}, fi.ServerOptions()...))
+}
+
+// echoCodes calls client.Echo() n times, returning the status codes.
+func echoCodes(ctx context.Context, client pb.EchoServiceClient, n int) []codes.Code {
+	var got []codes.Code
+	for i := 0; i < n; i++ {
+		_, err := client.Echo(ctx, &pb.Request{Msg: "hi"})
+		got = append(got, status.Code(err))
+	}
+	return got
+}
+
+func TestFaultInjectorErrors(t *testing.T) {
+	ctx := context.Background()
+	fi := NewFaultInjector(0)
+	client := newFaultyEchoClient(t, fi)
+	codesOf := func(n int) []codes.Code {
+		return echoCodes(ctx, client, n)
+	}
+
+	t.Run("no faults", func(t *testing.T) {
+		want := []codes.Code{codes.OK, codes.OK}
+		if diff := cmp.Diff(want, codesOf(2)); diff != "" {
+			t.Errorf("Echo() codes diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("fail first", func(t *testing.T) {
+		fi.Set(echoMethod, &Fault{FailFirst: 2})
+		want := []codes.Code{codes.Unavailable, codes.Unavailable, codes.OK, codes.OK}
+		if diff := cmp.Diff(want, codesOf(4)); diff != "" {
+			t.Errorf("Echo() codes diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("error code", func(t *testing.T) {
+		fi.Set(echoMethod, &Fault{ErrorRate: 1, ErrorCode: codes.ResourceExhausted})
+		want := []codes.Code{codes.ResourceExhausted, codes.ResourceExhausted}
+		if diff := cmp.Diff(want, codesOf(2)); diff != "" {
+			t.Errorf("Echo() codes diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("all methods", func(t *testing.T) {
+		fi.Set(echoMethod, nil)
+		fi.Set(AllMethods, &Fault{FailFirst: 1})
+		want := []codes.Code{codes.Unavailable, codes.OK}
+		if diff := cmp.Diff(want, codesOf(2)); diff != "" {
+			t.Errorf("Echo() codes diff (-want +got):\n%s", diff)
+		}
+		fi.Set(AllMethods, nil)
+	})
+
+	t.Run("error rate is deterministic", func(t *testing.T) {
+		const n = 50
+		var runs [2][]codes.Code
+		for i := range runs {
+			fi := NewFaultInjector(42)
+			fi.Set(echoMethod, &Fault{ErrorRate: 0.5})
+			runs[i] = echoCodes(ctx, newFaultyEchoClient(t, fi), n)
+		}
+		if diff := cmp.Diff(runs[0], runs[1]); diff != "" {
+			t.Errorf("Echo() codes with same seed diff (-first +second):\n%s", diff)
+		}
+
+		var failed int
+		for _, c := range runs[0] {
+			if c != codes.OK {
+				failed++
+			}
+		}
+		if failed == 0 || failed == n {
+			t.Errorf("Echo() with %T.ErrorRate = 0.5 failed %d of %d calls; want some but not all", Fault{}, failed, n)
+		}
+	})
+}
+
+func TestFaultInjectorLatency(t *testing.T) {
+	ctx := context.Background()
+	fi := NewFaultInjector(0)
+	client := newFaultyEchoClient(t, fi)
+
+	const latency = 50 * time.Millisecond
+	fi.Set(echoMethod, &Fault{Latency: FixedLatency(latency)})
+
+	t.Run("delayed", func(t *testing.T) {
+		start := time.Now()
+		if _, err := client.Echo(ctx, &pb.Request{Msg: "hi"}); err != nil {
+			t.Fatalf("Echo() error %v", err)
+		}
+		if got := time.Since(start); got < latency {
+			t.Errorf("Echo() with %v injected latency took %v", latency, got)
+		}
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, latency/5)
+		defer cancel()
+		if _, err := client.Echo(ctx, &pb.Request{Msg: "hi"}); status.Code(err) != codes.DeadlineExceeded {
+			t.Errorf("Echo() with deadline shorter than injected latency got err %v; want code %v", err, codes.DeadlineExceeded)
+		}
+	})
+}
+
+func TestUniformLatency(t *testing.T) {
+	const min, max = time.Second, 2 * time.Second
+	l := UniformLatency(min, max)
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 100; i++ {
+		if got := l(rng); got < min || got >= max {
+			t.Fatalf("UniformLatency(%v, %v) got %v; want in [%[1]v, %[2]v)", min, max, got)
+		}
+	}
+}
+
+func TestFaultInjectorStreamReset(t *testing.T) {
+	ctx := context.Background()
+	fi := NewFaultInjector(0)
+	client := newFaultyEchoClient(t, fi)
+
+	tests := []struct {
+		name     string
+		fault    *Fault
+		want     []*pb.Response
+		wantCode codes.Code
+	}{
+		{
+			name:  "no reset",
+			fault: &Fault{ResetAfter: 2, ResetRate: 0},
+			want:  responses("hi", "hi", "hi", "hi"),
+		},
+		{
+			name:     "reset",
+			fault:    &Fault{ResetAfter: 2, ResetRate: 1},
+			want:     responses("hi", "hi"),
+			wantCode: codes.Unavailable,
+		},
+		{
+			name:     "reset with code",
+			fault:    &Fault{ResetAfter: 1, ResetRate: 1, ErrorCode: codes.Internal},
+			want:     responses("hi"),
+			wantCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fi.Set(echoStreamMethod, tt.fault)
+
+			stream, err := client.EchoStream(ctx, &pb.Request{Msg: "hi", Repeat: 4})
+			if err != nil {
+				t.Fatalf("EchoStream() error %v", err)
+			}
+			got, err := RecvAll[*pb.Response](stream)
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("RecvAll(EchoStream()) got err %v; want code %v", err, tt.wantCode)
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("RecvAll(EchoStream()) diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package grpctest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// A MetadataChecker asserts that a service under test propagates specific
+// incoming metadata keys, either by echoing them back to the client in headers
+// or trailers, or by forwarding them to downstream services. Its
+// ServerOptions() must be passed to the Tester (or any function that accepts
+// grpc.ServerOptions, e.g. NewClientConnTB()) and, to check forwarding, its
+// DialOptions() must be used by the service when connecting to downstream
+// services.
+//
+// A MetadataChecker is safe for concurrent use.
+type MetadataChecker struct {
+	keys []string
+
+	mu       sync.Mutex
+	calls    []*metadataCall
+	orphaned []string
+}
+
+// A metadataCall is an RPC observed by a MetadataChecker.
+type metadataCall struct {
+	method            string
+	incoming          metadata.MD
+	headers, trailers metadata.MD
+	downstream        []downstreamCall
+}
+
+// A downstreamCall is an RPC made by a handler, observed by a MetadataChecker.
+type downstreamCall struct {
+	method   string
+	outgoing metadata.MD
+}
+
+// NewMetadataChecker returns a new MetadataChecker of the keys, which are
+// case-insensitive as with metadata.MD.
+func NewMetadataChecker(keys ...string) *MetadataChecker {
+	m := &MetadataChecker{}
+	for _, k := range keys {
+		m.keys = append(m.keys, strings.ToLower(k))
+	}
+	return m
+}
+
+// ServerOptions returns the grpc.ServerOptions that install the
+// MetadataChecker's interceptors. As with a Recorder, chained interceptors are
+// used so other interceptors can still be provided; the MetadataChecker
+// observes metadata set by interceptors that are installed after it.
+func (m *MetadataChecker) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(m.unary),
+		grpc.ChainStreamInterceptor(m.stream),
+	}
+}
+
+// DialOptions returns the grpc.DialOptions that install the MetadataChecker's
+// client interceptors. They MUST only be used for connections to downstream
+// services, by the service under test, as calls made with Contexts that
+// weren't derived from one received by the service's handlers are considered
+// failures to propagate metadata. Using a derived Context also propagates
+// deadlines and cancellation.
+func (m *MetadataChecker) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(m.unaryClient),
+		grpc.WithChainStreamInterceptor(m.streamClient),
+	}
+}
+
+// metadataCallKey is a Context key carrying the *metadataCall of the handler
+// that received the Context.
+type metadataCallKey struct{}
+
+// start records the beginning of a call and returns it along with a Context,
+// derived from ctx, that MUST be passed to the handler. The Context's
+// grpc.ServerTransportStream records headers and trailers set via
+// grpc.SetHeader() etc.
+func (m *MetadataChecker) start(ctx context.Context, method string) (*metadataCall, context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c := &metadataCall{
+		method:   method,
+		incoming: md.Copy(),
+		headers:  metadata.MD{},
+		trailers: metadata.MD{},
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, c)
+	m.mu.Unlock()
+
+	ctx = context.WithValue(ctx, metadataCallKey{}, c)
+	if ts := grpc.ServerTransportStreamFromContext(ctx); ts != nil {
+		ctx = grpc.NewContextWithServerTransportStream(ctx, &metadataTransportStream{ServerTransportStream: ts, checker: m, call: c})
+	}
+	return c, ctx
+}
+
+// record merges md into dst, which MUST be either c.headers or c.trailers.
+func (m *MetadataChecker) record(dst, md metadata.MD) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, vs := range md {
+		k = strings.ToLower(k)
+		dst[k] = append(dst[k], vs...)
+	}
+}
+
+func (m *MetadataChecker) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	_, ctx = m.start(ctx, info.FullMethod)
+	return handler(ctx, req)
+}
+
+func (m *MetadataChecker) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	c, ctx := m.start(ss.Context(), info.FullMethod)
+	return handler(srv, &metadataStream{ServerStream: ss, ctx: ctx, checker: m, call: c})
+}
+
+// A metadataTransportStream is a grpc.ServerTransportStream that records all
+// headers and trailers.
+type metadataTransportStream struct {
+	grpc.ServerTransportStream
+	checker *MetadataChecker
+	call    *metadataCall
+}
+
+func (s *metadataTransportStream) SetHeader(md metadata.MD) error {
+	if err := s.ServerTransportStream.SetHeader(md); err != nil {
+		return err
+	}
+	s.checker.record(s.call.headers, md)
+	return nil
+}
+
+func (s *metadataTransportStream) SendHeader(md metadata.MD) error {
+	if err := s.ServerTransportStream.SendHeader(md); err != nil {
+		return err
+	}
+	s.checker.record(s.call.headers, md)
+	return nil
+}
+
+func (s *metadataTransportStream) SetTrailer(md metadata.MD) error {
+	if err := s.ServerTransportStream.SetTrailer(md); err != nil {
+		return err
+	}
+	s.checker.record(s.call.trailers, md)
+	return nil
+}
+
+// A metadataStream is a grpc.ServerStream that records all headers and
+// trailers.
+type metadataStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	checker *MetadataChecker
+	call    *metadataCall
+}
+
+func (s *metadataStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *metadataStream) SetHeader(md metadata.MD) error {
+	if err := s.ServerStream.SetHeader(md); err != nil {
+		return err
+	}
+	s.checker.record(s.call.headers, md)
+	return nil
+}
+
+func (s *metadataStream) SendHeader(md metadata.MD) error {
+	if err := s.ServerStream.SendHeader(md); err != nil {
+		return err
+	}
+	s.checker.record(s.call.headers, md)
+	return nil
+}
+
+func (s *metadataStream) SetTrailer(md metadata.MD) {
+	s.ServerStream.SetTrailer(md)
+	s.checker.record(s.call.trailers, md)
+}
+
+// downstream records a call to a downstream service.
+func (m *MetadataChecker) downstream(ctx context.Context, method string) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	c, ok := ctx.Value(metadataCallKey{}).(*metadataCall)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !ok {
+		m.orphaned = append(m.orphaned, method)
+		return
+	}
+	c.downstream = append(c.downstream, downstreamCall{
+		method:   method,
+		outgoing: md.Copy(),
+	})
+}
+
+func (m *MetadataChecker) unaryClient(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	m.downstream(ctx, method)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func (m *MetadataChecker) streamClient(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	m.downstream(ctx, method)
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// CheckEchoed returns an error describing every call that received any of the
+// keys without echoing them, with identical values, in either its headers or
+// its trailers. It SHOULD only be called after all calls have ended.
+func (m *MetadataChecker) CheckEchoed() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for _, c := range m.calls {
+		for _, k := range m.keys {
+			want := c.incoming.Get(k)
+			if len(want) == 0 {
+				continue
+			}
+			if !equalValues(c.headers.Get(k), want) && !equalValues(c.trailers.Get(k), want) {
+				errs = append(errs, fmt.Errorf("%s received %q = %q but echoed headers %q and trailers %q", c.method, k, want, c.headers.Get(k), c.trailers.Get(k)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckPropagated returns an error describing every downstream call, made via
+// a connection with DialOptions(), that either (a) didn't use a Context
+// derived from one received by a handler; or (b) didn't forward, with
+// identical values, all of the keys received by the handler. Handlers that
+// make no downstream calls are ignored.
+func (m *MetadataChecker) CheckPropagated() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for _, method := range m.orphaned {
+		errs = append(errs, fmt.Errorf("downstream call to %s made with Context not derived from that of a handler", method))
+	}
+	for _, c := range m.calls {
+		for _, d := range c.downstream {
+			for _, k := range m.keys {
+				want := c.incoming.Get(k)
+				if len(want) == 0 {
+					continue
+				}
+				if got := d.outgoing.Get(k); !equalValues(got, want) {
+					errs = append(errs, fmt.Errorf("%s received %q = %q but forwarded %q to %s", c.method, k, want, got, d.method))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AssertEchoed calls tb.Errorf() if CheckEchoed() returns an error.
+func (m *MetadataChecker) AssertEchoed(tb testing.TB) {
+	tb.Helper()
+	if err := m.CheckEchoed(); err != nil {
+		tb.Errorf("%T.CheckEchoed(): %v", m, err)
+	}
+}
+
+// AssertPropagated calls tb.Errorf() if CheckPropagated() returns an error.
+func (m *MetadataChecker) AssertPropagated(tb testing.TB) {
+	tb.Helper()
+	if err := m.CheckPropagated(); err != nil {
+		tb.Errorf("%T.CheckPropagated(): %v", m, err)
+	}
+}
@@ -0,0 +1,141 @@
+package grpctest
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/cxkoda/solgo/go/grpctest/proto"
+)
+
+const requestIDKey = "x-request-id"
+
+// forwardingEcho is an echo service that forwards Echo() and EchoStream() calls
+// to a downstream service.
+type forwardingEcho struct {
+	echo
+	downstream pb.EchoServiceClient
+	// downstreamCtx returns the Context used for downstream calls, given the
+	// one received by the handler.
+	downstreamCtx func(context.Context) context.Context
+	// If echoID is true then the request ID is echoed in the headers of unary
+	// calls and the trailers of streaming calls.
+	echoID bool
+}
+
+func (f *forwardingEcho) requestID(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return metadata.MD{requestIDKey: md.Get(requestIDKey)}
+}
+
+func (f *forwardingEcho) Echo(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	if f.echoID {
+		if err := grpc.SetHeader(ctx, f.requestID(ctx)); err != nil {
+			return nil, err
+		}
+	}
+	return f.downstream.Echo(f.downstreamCtx(ctx), in)
+}
+
+func (f *forwardingEcho) EchoStream(req *pb.Request, srv pb.EchoService_EchoStreamServer) error {
+	ctx := srv.Context()
+	if f.echoID {
+		srv.SetTrailer(f.requestID(ctx))
+	}
+	stream, err := f.downstream.EchoStream(f.downstreamCtx(ctx), req)
+	if err != nil {
+		return err
+	}
+	resps, err := RecvAll[*pb.Response](stream)
+	if err != nil {
+		return err
+	}
+	for _, r := range resps {
+		if err := srv.Send(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMetadataChecker(t *testing.T) {
+	forward := func(ctx context.Context) context.Context {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return metadata.NewOutgoingContext(ctx, metadata.MD{requestIDKey: md.Get(requestIDKey)})
+	}
+
+	tests := []struct {
+		name                            string
+		echoID                          bool
+		downstreamCtx                   func(context.Context) context.Context
+		wantEchoErr, wantPropagationErr bool
+	}{
+		{
+			name:          "echoed and forwarded",
+			echoID:        true,
+			downstreamCtx: forward,
+		},
+		{
+			name:               "derived Context without forwarding",
+			echoID:             true,
+			downstreamCtx:      func(ctx context.Context) context.Context { return ctx },
+			wantPropagationErr: true,
+		},
+		{
+			name:   "forwarded via background Context",
+			echoID: true,
+			downstreamCtx: func(ctx context.Context) context.Context {
+				md, _ := metadata.FromIncomingContext(ctx)
+				return metadata.NewOutgoingContext(context.Background(), md)
+			},
+			wantPropagationErr: true,
+		},
+		{
+			name:          "not echoed",
+			downstreamCtx: forward,
+			wantEchoErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMetadataChecker(requestIDKey)
+
+			// The downstream connection MUST use the MetadataChecker's
+			// DialOptions.
+			conn, err := NewWithRegisteredTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, &echo{}).Dial(m.DialOptions()...)
+			if err != nil {
+				t.Fatalf("Dial(%T.DialOptions()...) error %v", m, err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			svc := &forwardingEcho{
+				downstream:    pb.NewEchoServiceClient(conn),
+				downstreamCtx: tt.downstreamCtx,
+				echoID:        tt.echoID,
+			}
+
+			client := pb.NewEchoServiceClient(NewClientConnTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, svc, m.ServerOptions()...))
+			ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDKey, "abc123")
+
+			if _, err := client.Echo(ctx, &pb.Request{Msg: "hi"}); err != nil {
+				t.Fatalf("Echo() error %v", err)
+			}
+			stream, err := client.EchoStream(ctx, &pb.Request{Msg: "hi", Repeat: 2})
+			if err != nil {
+				t.Fatalf("EchoStream() error %v", err)
+			}
+			if _, err := RecvAll[*pb.Response](stream); err != nil {
+				t.Fatalf("RecvAll(EchoStream()) error %v", err)
+			}
+
+			if err := m.CheckEchoed(); (err != nil) != tt.wantEchoErr {
+				t.Errorf("%T.CheckEchoed() got err %v; want err? %t", m, err, tt.wantEchoErr)
+			}
+			if err := m.CheckPropagated(); (err != nil) != tt.wantPropagationErr {
+				t.Errorf("%T.CheckPropagated() got err %v; want err? %t", m, err, tt.wantPropagationErr)
+			}
+		})
+	}
+}
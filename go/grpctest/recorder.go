@@ -0,0 +1,169 @@
+package grpctest
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// A Recorder captures all RPCs received by a grpc.Server, including their
+// incoming metadata, requests, responses and final status. Its ServerOptions()
+// must be passed to the Tester (or any function that accepts
+// grpc.ServerOptions, e.g. NewClientConnTB()).
+//
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []*Call
+}
+
+// A Call is a single RPC captured by a Recorder.
+type Call struct {
+	// FullMethod is the full RPC method string; i.e. /package.service/method.
+	FullMethod string
+	// Metadata is the incoming metadata, as sent by the client.
+	Metadata metadata.MD
+	// Requests and Responses are clones of all messages received from and sent
+	// to the client, respectively. Unary RPCs have at most one of each.
+	Requests, Responses []proto.Message
+	// Status is the status with which the RPC ended. It is nil if the RPC is
+	// still in progress.
+	Status *status.Status
+}
+
+// NewRecorder returns a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// ServerOptions returns the grpc.ServerOptions that install the Recorder's
+// interceptors. Chained interceptors are used so that other interceptors can
+// still be provided; the Recorder observes requests and responses as seen by
+// interceptors that are installed after it.
+func (r *Recorder) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(r.unary),
+		grpc.ChainStreamInterceptor(r.stream),
+	}
+}
+
+// start records the beginning of a new call and returns it. The returned Call
+// MUST only be modified via Recorder.update().
+func (r *Recorder) start(ctx context.Context, method string) *Call {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c := &Call{
+		FullMethod: method,
+		Metadata:   md.Copy(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, c)
+	return c
+}
+
+func (r *Recorder) update(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn()
+}
+
+func clone(m interface{}) proto.Message {
+	if p, ok := m.(proto.Message); ok {
+		return proto.Clone(p)
+	}
+	return nil
+}
+
+func (r *Recorder) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	c := r.start(ctx, info.FullMethod)
+	r.update(func() {
+		c.Requests = append(c.Requests, clone(req))
+	})
+
+	resp, err := handler(ctx, req)
+	r.update(func() {
+		if err == nil {
+			c.Responses = append(c.Responses, clone(resp))
+		}
+		c.Status = status.Convert(err)
+	})
+	return resp, err
+}
+
+func (r *Recorder) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	c := r.start(ss.Context(), info.FullMethod)
+	err := handler(srv, &recordingStream{ServerStream: ss, rec: r, call: c})
+	r.update(func() {
+		c.Status = status.Convert(err)
+	})
+	return err
+}
+
+// A recordingStream is a grpc.ServerStream that records all messages to and
+// from the client.
+type recordingStream struct {
+	grpc.ServerStream
+	rec  *Recorder
+	call *Call
+}
+
+func (s *recordingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	s.rec.update(func() {
+		s.call.Requests = append(s.call.Requests, clone(m))
+	})
+	return nil
+}
+
+func (s *recordingStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	s.rec.update(func() {
+		s.call.Responses = append(s.call.Responses, clone(m))
+	})
+	return nil
+}
+
+// Calls returns copies of all calls recorded so far, in the order in which
+// they started.
+func (r *Recorder) Calls() []Call {
+	return r.filter(func(*Call) bool { return true })
+}
+
+// CallsTo returns the subset of Calls() with the specified FullMethod.
+func (r *Recorder) CallsTo(fullMethod string) []Call {
+	return r.filter(func(c *Call) bool { return c.FullMethod == fullMethod })
+}
+
+func (r *Recorder) filter(keep func(*Call) bool) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var calls []Call
+	for _, c := range r.calls {
+		if !keep(c) {
+			continue
+		}
+		cp := *c
+		cp.Metadata = c.Metadata.Copy()
+		cp.Requests = append([]proto.Message(nil), c.Requests...)
+		cp.Responses = append([]proto.Message(nil), c.Responses...)
+		calls = append(calls, cp)
+	}
+	return calls
+}
+
+// Reset discards all recorded calls.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
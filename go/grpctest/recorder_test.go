@@ -0,0 +1,86 @@
+package grpctest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	pb "github.com/cxkoda/solgo/go/grpctest/proto"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+	conn := NewClientConnTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, &echo{}, rec.ServerOptions()...)
+	client := pb.NewEchoServiceClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+
+	if _, err := client.Echo(ctx, &pb.Request{Msg: "unary"}); err != nil {
+		t.Fatalf("Echo() error %v", err)
+	}
+
+	stream, err := client.EchoBidi(ctx)
+	if err != nil {
+		t.Fatalf("EchoBidi() error %v", err)
+	}
+	reqs := []*pb.Request{
+		{Msg: "a"},
+		{Msg: "b", FailWithCode: int32(codes.Unavailable)},
+	}
+	_, err = Exchange[*pb.Request, *pb.Response](stream, reqs)
+	if err := CheckCode(err, codes.Unavailable); err != nil {
+		t.Fatalf("Exchange(EchoBidi) %v", err)
+	}
+
+	type call struct {
+		FullMethod          string
+		Auth                []string
+		Requests, Responses []proto.Message
+		Code                codes.Code
+	}
+	var got []call
+	for _, c := range rec.Calls() {
+		got = append(got, call{
+			FullMethod: c.FullMethod,
+			Auth:       c.Metadata.Get("authorization"),
+			Requests:   c.Requests,
+			Responses:  c.Responses,
+			Code:       c.Status.Code(),
+		})
+	}
+
+	want := []call{
+		{
+			FullMethod: "/EchoService/Echo",
+			Auth:       []string{"Bearer secret"},
+			Requests:   []proto.Message{&pb.Request{Msg: "unary"}},
+			Responses:  []proto.Message{&pb.Response{Msg: "unary"}},
+			Code:       codes.OK,
+		},
+		{
+			FullMethod: "/EchoService/EchoBidi",
+			Auth:       []string{"Bearer secret"},
+			Requests:   []proto.Message{reqs[0], reqs[1]},
+			Responses:  []proto.Message{&pb.Response{Msg: "a"}, &pb.Response{Msg: "b"}},
+			Code:       codes.Unavailable,
+		},
+	}
+
+	if diff := cmp.Diff(want, got, protocmp.Transform(), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("%T.Calls() diff (-want +got):\n%s", rec, diff)
+	}
+
+	if got := len(rec.CallsTo("/EchoService/Echo")); got != 1 {
+		t.Errorf("%T.CallsTo(Echo) got %d calls; want 1", rec, got)
+	}
+	rec.Reset()
+	if got := len(rec.Calls()); got != 0 {
+		t.Errorf("%T.Calls() after Reset() got %d calls; want 0", rec, got)
+	}
+}
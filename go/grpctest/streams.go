@@ -0,0 +1,129 @@
+package grpctest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A Receiver is any gRPC stream from which messages of type M can be received;
+// e.g. the client of a server-streaming RPC.
+type Receiver[M any] interface {
+	Recv() (M, error)
+}
+
+// A ClientSender is any client-side gRPC stream to which messages of type M can
+// be sent; e.g. the client of a client-streaming or bidi-streaming RPC.
+type ClientSender[M any] interface {
+	Send(M) error
+	CloseSend() error
+}
+
+// A ClientStreamingClient is the client of a client-streaming RPC, sending
+// messages of type Req and receiving a single Resp.
+type ClientStreamingClient[Req, Resp any] interface {
+	Send(Req) error
+	CloseAndRecv() (Resp, error)
+}
+
+// A BidiStreamingClient is the client of a bidi-streaming RPC, sending messages
+// of type Req and receiving messages of type Resp.
+type BidiStreamingClient[Req, Resp any] interface {
+	ClientSender[Req]
+	Receiver[Resp]
+}
+
+// RecvAll receives from the stream until it ends, returning all messages in
+// the order in which they were received. A stream that ends with io.EOF
+// results in a nil error, otherwise the error that ended the stream is
+// returned, along with all messages received before it.
+func RecvAll[M any](s Receiver[M]) ([]M, error) {
+	var msgs []M
+	for {
+		m, err := s.Recv()
+		if errors.Is(err, io.EOF) {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, m)
+	}
+}
+
+// SendAll sends all messages to the stream, in order, and then closes the
+// sending direction of the stream.
+//
+// If the server ends the stream early, Send() returns io.EOF and the actual
+// status is only available from the receiving side. SendAll therefore stops
+// sending and returns a nil error in this case, and the status SHOULD be
+// checked when receiving.
+func SendAll[M any](s ClientSender[M], msgs []M) error {
+	for i, m := range msgs {
+		err := s.Send(m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("sending message %d: %w", i, err)
+		}
+	}
+	return s.CloseSend()
+}
+
+// SendAllAndRecv sends all messages to a client-streaming RPC and returns the
+// single response. See SendAll() re handling of streams that the server ends
+// early; the returned error is always that of CloseAndRecv() in this case.
+func SendAllAndRecv[Req, Resp any](s ClientStreamingClient[Req, Resp], msgs []Req) (Resp, error) {
+	for i, m := range msgs {
+		err := s.Send(m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var zero Resp
+			return zero, fmt.Errorf("sending message %d: %w", i, err)
+		}
+	}
+	return s.CloseAndRecv()
+}
+
+// Exchange concurrently sends all msgs to, and receives all responses from, a
+// bidi-streaming RPC. Sending is performed as with SendAll() and receiving as
+// with RecvAll(). Exchange only returns once the stream has ended.
+//
+// An error in sending takes precedence over one in receiving as the former is
+// likely to have caused the latter.
+func Exchange[Req, Resp any](s BidiStreamingClient[Req, Resp], msgs []Req) ([]Resp, error) {
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- SendAll[Req](s, msgs)
+	}()
+
+	got, recvErr := RecvAll[Resp](s)
+	if err := <-sendErr; err != nil {
+		return got, err
+	}
+	return got, recvErr
+}
+
+// CheckCode returns an error if the gRPC status code of err is not equal to
+// want. A nil error is considered to have codes.OK.
+func CheckCode(err error, want codes.Code) error {
+	if got := status.Code(err); got != want {
+		return fmt.Errorf("got error %v with status code %v; want %v", err, got, want)
+	}
+	return nil
+}
+
+// AssertCode calls tb.Errorf() if CheckCode(err, want) returns an error.
+func AssertCode(tb testing.TB, err error, want codes.Code) {
+	tb.Helper()
+	if err := CheckCode(err, want); err != nil {
+		tb.Error(err)
+	}
+}
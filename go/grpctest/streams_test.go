@@ -0,0 +1,186 @@
+package grpctest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	pb "github.com/cxkoda/solgo/go/grpctest/proto"
+)
+
+func failWith(c int32) error {
+	if c == 0 {
+		return nil
+	}
+	return status.Error(codes.Code(c), "requested failure")
+}
+
+func (echo) EchoStream(req *pb.Request, srv pb.EchoService_EchoStreamServer) error {
+	for i := uint32(0); i < req.Repeat; i++ {
+		if err := srv.Send(&pb.Response{Msg: req.Msg}); err != nil {
+			return err
+		}
+	}
+	return failWith(req.FailWithCode)
+}
+
+func (echo) Concat(srv pb.EchoService_ConcatServer) error {
+	var msgs []string
+	for {
+		req, err := srv.Recv()
+		if err != nil {
+			return srv.SendAndClose(&pb.Response{Msg: strings.Join(msgs, "")})
+		}
+		if err := failWith(req.FailWithCode); err != nil {
+			return err
+		}
+		msgs = append(msgs, req.Msg)
+	}
+}
+
+func (echo) EchoBidi(srv pb.EchoService_EchoBidiServer) error {
+	for {
+		req, err := srv.Recv()
+		if err != nil {
+			return nil
+		}
+		if err := srv.Send(&pb.Response{Msg: req.Msg}); err != nil {
+			return err
+		}
+		if err := failWith(req.FailWithCode); err != nil {
+			return err
+		}
+	}
+}
+
+func responses(msgs ...string) []*pb.Response {
+	var rs []*pb.Response
+	for _, m := range msgs {
+		rs = append(rs, &pb.Response{Msg: m})
+	}
+	return rs
+}
+
+func TestRecvAll(t *testing.T) {
+	ctx := context.Background()
+	client := pb.NewEchoServiceClient(NewClientConnTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, &echo{}))
+
+	tests := []struct {
+		req      *pb.Request
+		want     []*pb.Response
+		wantCode codes.Code
+	}{
+		{
+			req:      &pb.Request{Msg: "hi", Repeat: 3},
+			want:     responses("hi", "hi", "hi"),
+			wantCode: codes.OK,
+		},
+		{
+			req:      &pb.Request{Msg: "nothing"},
+			wantCode: codes.OK,
+		},
+		{
+			req:      &pb.Request{Msg: "partial", Repeat: 2, FailWithCode: int32(codes.ResourceExhausted)},
+			want:     responses("partial", "partial"),
+			wantCode: codes.ResourceExhausted,
+		},
+	}
+
+	for _, tt := range tests {
+		stream, err := client.EchoStream(ctx, tt.req)
+		if err != nil {
+			t.Fatalf("EchoStream(%+v) error %v", tt.req, err)
+		}
+
+		got, err := RecvAll[*pb.Response](stream)
+		AssertCode(t, err, tt.wantCode)
+		if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("RecvAll(EchoStream(%+v)) diff (-want +got):\n%s", tt.req, diff)
+		}
+	}
+}
+
+func TestSendAllAndRecv(t *testing.T) {
+	ctx := context.Background()
+	client := pb.NewEchoServiceClient(NewClientConnTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, &echo{}))
+
+	tests := []struct {
+		name     string
+		reqs     []*pb.Request
+		want     *pb.Response
+		wantCode codes.Code
+	}{
+		{
+			name: "happy path",
+			reqs: []*pb.Request{{Msg: "foo"}, {Msg: "bar"}, {Msg: "baz"}},
+			want: &pb.Response{Msg: "foobarbaz"},
+		},
+		{
+			name:     "server ends early",
+			reqs:     []*pb.Request{{Msg: "foo"}, {FailWithCode: int32(codes.InvalidArgument)}, {Msg: "never"}},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Concat(ctx)
+			if err != nil {
+				t.Fatalf("Concat() error %v", err)
+			}
+
+			got, err := SendAllAndRecv[*pb.Request, *pb.Response](stream, tt.reqs)
+			AssertCode(t, err, tt.wantCode)
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("SendAllAndRecv() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+	client := pb.NewEchoServiceClient(NewClientConnTB[pb.EchoServiceServer](t, pb.RegisterEchoServiceServer, &echo{}))
+
+	tests := []struct {
+		name     string
+		reqs     []*pb.Request
+		want     []*pb.Response
+		wantCode codes.Code
+	}{
+		{
+			name: "happy path",
+			reqs: []*pb.Request{{Msg: "a"}, {Msg: "b"}, {Msg: "c"}},
+			want: responses("a", "b", "c"),
+		},
+		{
+			name:     "server ends early",
+			reqs:     []*pb.Request{{Msg: "a"}, {Msg: "b", FailWithCode: int32(codes.Aborted)}, {Msg: "c"}},
+			want:     responses("a", "b"),
+			wantCode: codes.Aborted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.EchoBidi(ctx)
+			if err != nil {
+				t.Fatalf("EchoBidi() error %v", err)
+			}
+
+			got, err := Exchange[*pb.Request, *pb.Response](stream, tt.reqs)
+			AssertCode(t, err, tt.wantCode)
+			if diff := cmp.Diff(tt.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Exchange() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -4,8 +4,12 @@ package httperr
 
 import (
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/golang/glog"
 	"github.com/julienschmidt/httprouter"
@@ -20,9 +24,9 @@ func Formatf(code int, format string, a ...interface{}) error {
 	if code == 200 {
 		return nil
 	}
-	return &httpError{
-		code: code,
-		msg:  fmt.Sprintf(format, a...),
+	return &Error{
+		Status:  code,
+		Message: fmt.Sprintf(format, a...),
 	}
 }
 
@@ -32,55 +36,210 @@ func WithStatus(code int, err error) error {
 	if code == 200 {
 		return nil
 	}
-	return &httpError{code: code, msg: err.Error()}
+	return &Error{Status: code, Message: err.Error()}
 }
 
-// httpError is an error that carries an HTTP response code and a message.
-type httpError struct {
-	code int
-	msg  string
+// Errorf is equivalent to Formatf() except that the returned Error also carries
+// a machine-readable code, which is propagated to clients that receive JSON
+// responses (see WithJSON()).
+func Errorf(status int, code, format string, a ...interface{}) error {
+	if status == 200 {
+		return nil
+	}
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+// An Error is an error that carries an HTTP status code, a message, and an
+// optional machine-readable Code. Code SHOULD be stable across releases as it
+// is intended for programmatic handling by clients; if empty, a code is derived
+// from the Status.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// Error returns e.Message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// code returns e.Code if non-empty, otherwise a code derived from e.Status.
+func (e *Error) code() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return statusCode(e.Status)
+}
+
+// statusCode returns a snake-case code derived from the text of the HTTP status
+// code; e.g. http.StatusNotFound becomes "not_found".
+func statusCode(status int) string {
+	txt := http.StatusText(status)
+	if txt == "" {
+		return strconv.Itoa(status)
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r == ' ', r == '-':
+			return '_'
+		default:
+			return -1
+		}
+	}, txt)
 }
 
-func (e *httpError) Error() string {
-	return e.msg
+// An Option configures the behaviour of HandlerFunc() and RouterHandle().
+type Option func(*config)
+
+type config struct {
+	json bool
+}
+
+func newConfig(opts []Option) *config {
+	c := new(config)
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// WithJSON returns an Option that causes errors to be sent as a JSONError
+// instead of plain text, but only if the request's Accept header includes
+// application/json (or application/*). All other requests receive plain-text
+// errors, as is the default.
+func WithJSON() Option {
+	return func(c *config) {
+		c.json = true
+	}
+}
+
+// A JSONError is the body of error responses sent to clients that accept JSON;
+// see WithJSON().
+//
+// For 400-level errors, Message is that of the original error and ID is empty.
+// For all other errors, Message is the standard HTTP status text and ID is the
+// identifier logged alongside the original, obfuscated, error message.
+// RequestID is only populated if the request passed through the RequestID()
+// middleware.
+type JSONError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	ID        string `json:"id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // HandlerFunc allows http.HandlerFunc-like functions to return errors. If the
-// returned error is one returned by Formatf(), it is treated as described in
-// that function's documentation. All other errors are treated as 500.
-func HandlerFunc(fn func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+// returned error is, or wraps, one returned by Formatf(), it is treated as
+// described in that function's documentation. Errors matching a mapping added
+// with RegisterStatus() or RegisterStatusAs() use the registered status. All
+// other errors are treated as 500.
+func HandlerFunc(fn func(http.ResponseWriter, *http.Request) error, opts ...Option) http.HandlerFunc {
+	cfg := newConfig(opts)
 	return func(w http.ResponseWriter, r *http.Request) {
-		handleErr(w, fn(w, r))
+		cfg.handleErr(w, r, fn(w, r))
 	}
 }
 
 // RouterHandle is equivalent to HandlerFunc, but also supports propagation of
 // httprouter.Params.
-func RouterHandle(fn func(http.ResponseWriter, *http.Request, httprouter.Params) error) httprouter.Handle {
+func RouterHandle(fn func(http.ResponseWriter, *http.Request, httprouter.Params) error, opts ...Option) httprouter.Handle {
+	cfg := newConfig(opts)
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		handleErr(w, fn(w, r, p))
+		cfg.handleErr(w, r, fn(w, r, p))
 	}
 }
 
-func handleErr(w http.ResponseWriter, err error) {
-	writeObfuscated := func(code int, msg string) {
-		id, errMsg := obfuscate(msg)
-		glog.Errorf("%x: %s", id, msg)
-		http.Error(w, errMsg, code)
+func (c *config) handleErr(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
 	}
+	e := asError(err)
 
-	switch err := err.(type) {
-	case nil:
-	case *httpError:
-		// TODO(arran) revisit which codes are propagated.
-		if err.code/100 == 4 {
-			http.Error(w, err.Error(), err.code)
+	asJSON := c.json && acceptsJSON(r)
+
+	// TODO(arran) revisit which codes are propagated.
+	if e.Status/100 == 4 {
+		if asJSON {
+			reqID, _ := RequestIDFromContext(r.Context())
+			writeJSON(w, e.Status, &JSONError{
+				Code:      e.code(),
+				Message:   e.Message,
+				RequestID: reqID,
+			})
 		} else {
-			writeObfuscated(err.code, err.Error())
+			http.Error(w, e.Message, e.Status)
+		}
+		return
+	}
+
+	id, errMsg := obfuscate(e.Message)
+	reqID, hasReqID := RequestIDFromContext(r.Context())
+	if hasReqID {
+		glog.Errorf("request %s: %x: %s", reqID, id, e.Message)
+		errMsg = fmt.Sprintf("%s (request %s)", errMsg, reqID)
+	} else {
+		glog.Errorf("%x: %s", id, e.Message)
+	}
+
+	if asJSON {
+		writeJSON(w, e.Status, &JSONError{
+			Code:      e.code(),
+			Message:   http.StatusText(e.Status),
+			ID:        fmt.Sprintf("%x", id),
+			RequestID: reqID,
+		})
+	} else {
+		http.Error(w, errMsg, e.Status)
+	}
+}
+
+// writeJSON is the JSON equivalent of http.Error().
+func writeJSON(w http.ResponseWriter, status int, e *JSONError) {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		// This can't happen with a struct of strings, but fail loudly rather
+		// than sending an empty body.
+		glog.Errorf("json.Marshal(%T): %v", e, err)
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(append(buf, '\n'))
+}
+
+// acceptsJSON reports whether the request's Accept header includes a media
+// range, with non-zero quality, that matches application/json.
+func acceptsJSON(r *http.Request) bool {
+	for _, h := range r.Header.Values("Accept") {
+		for _, rng := range strings.Split(h, ",") {
+			mt, params, err := mime.ParseMediaType(strings.TrimSpace(rng))
+			if err != nil {
+				continue
+			}
+			if q, ok := params["q"]; ok {
+				if f, err := strconv.ParseFloat(q, 64); err != nil || f == 0 {
+					continue
+				}
+			}
+			if mt == "application/json" || mt == "application/*" {
+				return true
+			}
 		}
-	default:
-		writeObfuscated(500, err.Error())
 	}
+	return false
 }
 
 func obfuscate(msg string) ([]byte, string) {
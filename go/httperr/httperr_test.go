@@ -2,6 +2,7 @@ package httperr
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -166,3 +167,114 @@ func TestRouterHandle(t *testing.T) {
 		t.Fatalf("%T passed to RouterHandle() got {Code = %d, Body = %q}; want {Code = 200, Body = %q}", got, got.Code, got.Body.String(), body)
 	}
 }
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     string
+		err        error
+		wantStatus int
+		wantType   string
+		want       *JSONError // nil if plain text expected
+	}{
+		{
+			name:       "no Accept header",
+			err:        Formatf(404, "nothing here"),
+			wantStatus: 404,
+			wantType:   "text/plain; charset=utf-8",
+		},
+		{
+			name:       "text only",
+			accept:     "text/html, text/plain;q=0.9",
+			err:        Formatf(404, "nothing here"),
+			wantStatus: 404,
+			wantType:   "text/plain; charset=utf-8",
+		},
+		{
+			name:       "JSON explicitly refused",
+			accept:     "text/plain, application/json;q=0",
+			err:        Formatf(404, "nothing here"),
+			wantStatus: 404,
+			wantType:   "text/plain; charset=utf-8",
+		},
+		{
+			name:       "4xx with derived code",
+			accept:     "application/json",
+			err:        Formatf(404, "nothing here"),
+			wantStatus: 404,
+			wantType:   "application/json",
+			want: &JSONError{
+				Code:    "not_found",
+				Message: "nothing here",
+			},
+		},
+		{
+			name:       "4xx with explicit code",
+			accept:     "text/html, application/*;q=0.5",
+			err:        Errorf(403, "token_expired", "token expired at %d", 42),
+			wantStatus: 403,
+			wantType:   "application/json",
+			want: &JSONError{
+				Code:    "token_expired",
+				Message: "token expired at 42",
+			},
+		},
+		{
+			name:       "5xx obfuscated",
+			accept:     "application/json",
+			err:        Errorf(503, "node_unavailable", "dial node: secret.example.com refused"),
+			wantStatus: 503,
+			wantType:   "application/json",
+			want: &JSONError{
+				Code:    "node_unavailable",
+				Message: "Service Unavailable",
+				ID:      strings.TrimPrefix(errMsg("dial node: secret.example.com refused"), "see log: "),
+			},
+		},
+		{
+			name:       "vanilla error",
+			accept:     "application/json",
+			err:        errors.New("uh oh"),
+			wantStatus: 500,
+			wantType:   "application/json",
+			want: &JSONError{
+				Code:    "internal_server_error",
+				Message: "Internal Server Error",
+				ID:      strings.TrimPrefix(errMsg("uh oh"), "see log: "),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://target", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			h := HandlerFunc(func(http.ResponseWriter, *http.Request) error {
+				return tt.err
+			}, WithJSON())
+			got := httptest.NewRecorder()
+			h(got, req)
+
+			if got.Code != tt.wantStatus {
+				t.Errorf("got status %d; want %d", got.Code, tt.wantStatus)
+			}
+			if got, want := got.Header().Get("Content-Type"), tt.wantType; got != want {
+				t.Errorf("got Content-Type %q; want %q", got, want)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			gotJSON := new(JSONError)
+			if err := json.Unmarshal(got.Body.Bytes(), gotJSON); err != nil {
+				t.Fatalf("json.Unmarshal(%q, %T) error %v", got.Body.String(), gotJSON, err)
+			}
+			if diff := cmp.Diff(tt.want, gotJSON); diff != "" {
+				t.Errorf("JSON error response diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
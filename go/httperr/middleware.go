@@ -0,0 +1,162 @@
+package httperr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// A Middleware wraps an http.Handler to extend its behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Chain returns h wrapped in all of the Middleware. The first Middleware is the
+// outermost, and therefore the first to receive each request. A typical stack
+// is Chain(h, RequestID(), AccessLog(), Recover()) so that panics are recovered
+// before being logged, and both carry the request ID.
+func Chain(h http.Handler, mw ...Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// RequestIDHeader is the HTTP header from which RequestID() reads, and to
+// which it writes, request IDs.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// validRequestID matches request IDs that are accepted from clients; anything
+// else is replaced to avoid log injection.
+var validRequestID = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+// RequestID returns a Middleware that attaches an ID to each request, available
+// via RequestIDFromContext(). The ID is taken from the RequestIDHeader if the
+// client provided a valid one, otherwise it is randomly generated. It is
+// always echoed in the response's RequestIDHeader.
+//
+// Errors returned to HandlerFunc() and RouterHandle() that are logged (i.e.
+// non-400-level) include the request ID in both the log line and the response,
+// allowing client-reported errors to be correlated with server logs.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID.MatchString(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID attached by the RequestID()
+// Middleware, and a boolean indicating whether one was found.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+func newRequestID() string {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		// Not worth failing the request over; the timestamp is still useful
+		// for correlation.
+		return fmt.Sprintf("t%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// Recover returns a Middleware that recovers panics in downstream handlers and
+// treats them as 500 errors, as if returned to HandlerFunc(). The panic value
+// and stack trace are logged but never propagated to the client. The Options
+// are equivalent to those accepted by HandlerFunc().
+//
+// If the downstream handler has already written a response header, the status
+// can't be changed so the client will receive a truncated response.
+func Recover(opts ...Option) Middleware {
+	cfg := newConfig(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				glog.Errorf("recovered panic serving %s %s: %v\n%s", r.Method, r.URL.Path, rec, debug.Stack())
+				cfg.handleErr(w, r, fmt.Errorf("panic: %v", rec))
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AccessLog returns a Middleware that logs, at glog.Info, every request along
+// with the response status, number of body bytes written, duration, and
+// request ID if one was attached by RequestID().
+func AccessLog() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			defer func() {
+				id, ok := RequestIDFromContext(r.Context())
+				if !ok {
+					id = "-"
+				}
+				glog.Infof("%s %s %s %d %dB %v request=%s", r.RemoteAddr, r.Method, r.URL.RequestURI(), rec.status(), rec.bytes, time.Since(start), id)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// A statusRecorder is an http.ResponseWriter that records the status code and
+// number of bytes written.
+type statusRecorder struct {
+	http.ResponseWriter
+	code  int
+	bytes int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(buf []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(buf)
+	s.bytes += n
+	return n, err
+}
+
+// status returns the recorded status code, defaulting to 200 as net/http does
+// if nothing was written.
+func (s *statusRecorder) status() int {
+	if s.code == 0 {
+		return http.StatusOK
+	}
+	return s.code
+}
+
+// Unwrap allows http.ResponseController to access the underlying
+// ResponseWriter.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,161 @@
+package httperr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChainOrder(t *testing.T) {
+	var got []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = append(got, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		got = append(got, "handler")
+	}), mw("outer"), mw("middle"), mw("inner"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://target", nil))
+
+	want := []string{"outer", "middle", "inner", "handler"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Chain() call order diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{
+			name:     "none provided",
+			incoming: "",
+		},
+		{
+			name:     "valid provided",
+			incoming: "abc-123_DEF.4",
+			wantSame: true,
+		},
+		{
+			name:     "log injection",
+			incoming: "abc\nERROR fake log line",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inHandler string
+			h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, ok := RequestIDFromContext(r.Context())
+				if !ok {
+					t.Errorf("RequestIDFromContext() got ok = false; want true")
+				}
+				inHandler = id
+			}), RequestID())
+
+			req := httptest.NewRequest(http.MethodGet, "http://target", nil)
+			req.Header.Set(RequestIDHeader, tt.incoming)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if inHandler == "" {
+				t.Fatal("empty request ID")
+			}
+			if got := rec.Header().Get(RequestIDHeader); got != inHandler {
+				t.Errorf("response header %s = %q; want %q as seen by handler", RequestIDHeader, got, inHandler)
+			}
+			if got := inHandler == tt.incoming; got != tt.wantSame {
+				t.Errorf("request ID %q with incoming %q; got reused = %t; want %t", inHandler, tt.incoming, got, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	const reqID = "my-request"
+
+	tests := []struct {
+		name     string
+		accept   string
+		checkRec func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "plain text",
+			checkRec: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				body := strings.TrimSpace(rec.Body.String())
+				want := errMsg("panic: boom") + " (request " + reqID + ")"
+				if body != want {
+					t.Errorf("got body %q; want %q", body, want)
+				}
+			},
+		},
+		{
+			name:   "JSON",
+			accept: "application/json",
+			checkRec: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				got := new(JSONError)
+				if err := json.Unmarshal(rec.Body.Bytes(), got); err != nil {
+					t.Fatalf("json.Unmarshal(%q) error %v", rec.Body.String(), err)
+				}
+				want := &JSONError{
+					Code:      "internal_server_error",
+					Message:   "Internal Server Error",
+					ID:        strings.TrimPrefix(errMsg("panic: boom"), "see log: "),
+					RequestID: reqID,
+				}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("JSON body diff (-want +got):\n%s", diff)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Chain(
+				http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					panic("boom")
+				}),
+				RequestID(), AccessLog(), Recover(WithJSON()),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "http://target", nil)
+			req.Header.Set(RequestIDHeader, reqID)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got, want := rec.Code, 500; got != want {
+				t.Errorf("got status %d; want %d", got, want)
+			}
+			tt.checkRec(t, rec)
+		})
+	}
+}
+
+func TestAccessLogPreservesResponse(t *testing.T) {
+	h := Chain(HandlerFunc(func(http.ResponseWriter, *http.Request) error {
+		return Formatf(http.StatusTeapot, "short and stout")
+	}), AccessLog())
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://target", nil))
+
+	if got, want := rec.Code, http.StatusTeapot; got != want {
+		t.Errorf("got status %d; want %d", got, want)
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), "short and stout"; got != want {
+		t.Errorf("got body %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,291 @@
+package httperr
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/time/rate"
+
+	"github.com/cxkoda/solgo/go/otelonce"
+)
+
+// A ClientKey identifies the client making a request, for the purposes of
+// rate limiting. An empty string exempts the request from limits.
+type ClientKey func(*http.Request) string
+
+// RemoteIP is a ClientKey that returns the IP address from the request's
+// RemoteAddr. IPv6 addresses are truncated to their /64 prefix because a
+// single client typically controls all of them.
+//
+// Behind a proxy or load balancer, RemoteAddr is that of the proxy so
+// ForwardedIP() SHOULD be used instead.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return clientIP(host)
+}
+
+// clientIP returns the canonical form of the IP address, truncating IPv6
+// addresses to their /64 prefix. If ip can't be parsed it is returned
+// unchanged.
+func clientIP(ip string) string {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return ip
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.String()
+	}
+	return (&net.IPNet{
+		IP:   parsed.Mask(net.CIDRMask(64, 128)),
+		Mask: net.CIDRMask(64, 128),
+	}).String()
+}
+
+// ForwardedIP returns a ClientKey for servers behind the specified number of
+// trusted proxies, each of which appends the address of its peer to the
+// X-Forwarded-For header. The client is identified by the address appended by
+// the outermost trusted proxy; all earlier addresses are controlled by the
+// client and therefore ignored. If the header has too few addresses, or
+// trustedProxies is zero, RemoteIP() is used instead.
+func ForwardedIP(trustedProxies int) ClientKey {
+	return func(r *http.Request) string {
+		if trustedProxies <= 0 {
+			return RemoteIP(r)
+		}
+		var addrs []string
+		for _, h := range r.Header.Values("X-Forwarded-For") {
+			addrs = append(addrs, strings.Split(h, ",")...)
+		}
+		i := len(addrs) - trustedProxies
+		if i < 0 {
+			return RemoteIP(r)
+		}
+		return clientIP(strings.TrimSpace(addrs[i]))
+	}
+}
+
+// HeaderOr returns a ClientKey that identifies clients by the value of the
+// header (e.g. an API key), falling back to the other ClientKey if the header
+// is absent. Keys are prefixed so that header values can't collide with those
+// returned by fallback.
+//
+// Header values are accepted verbatim, allowing a client to evade limits by
+// varying them, so HeaderOr() MUST only be used if the header is authenticated
+// before the request reaches the RateLimit() Middleware.
+func HeaderOr(header string, fallback ClientKey) ClientKey {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(header); v != "" {
+			return header + ":" + v
+		}
+		if k := fallback(r); k != "" {
+			return "-:" + k
+		}
+		return ""
+	}
+}
+
+// ClientRateLimits configures the RateLimit() Middleware. The zero value is
+// ready to use.
+type ClientRateLimits struct {
+	// Rate is the sustained number of requests per second permitted for each
+	// client; it defaults to 10.
+	Rate rate.Limit
+	// Burst is the maximum number of requests that a client can make at once,
+	// after being idle, i.e. the size of its token bucket; it defaults to
+	// max(ceil(Rate), 1).
+	Burst int
+	// Client identifies the client making each request; it defaults to
+	// RemoteIP.
+	Client ClientKey
+	// IdleTimeout is the duration after a client's last request at which its
+	// limiter is forgotten, bounding memory usage; it defaults to 10 minutes.
+	// It SHOULD be no shorter than the time for a client's bucket to refill.
+	IdleTimeout time.Duration
+
+	// now is time.Now, swappable in tests.
+	now func() time.Time
+}
+
+func (c *ClientRateLimits) rate() rate.Limit {
+	if c.Rate <= 0 {
+		return 10
+	}
+	return c.Rate
+}
+
+func (c *ClientRateLimits) burst() int {
+	if c.Burst > 0 {
+		return c.Burst
+	}
+	if r := c.rate(); r > 1 && r != rate.Inf {
+		return int(math.Ceil(float64(r)))
+	}
+	return 1
+}
+
+func (c *ClientRateLimits) client() ClientKey {
+	if c.Client == nil {
+		return RemoteIP
+	}
+	return c.Client
+}
+
+func (c *ClientRateLimits) idleTimeout() time.Duration {
+	if c.IdleTimeout <= 0 {
+		return 10 * time.Minute
+	}
+	return c.IdleTimeout
+}
+
+func (c *ClientRateLimits) timeNow() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// RateLimitInstrumentationName is the name of the OpenTelemetry Meter used by
+// the RateLimit() Middleware. As with all OpenTelemetry metrics, binaries that
+// wish to export them MUST install a provider with otel.SetMeterProvider().
+//
+// Metrics are:
+//   - http.ratelimit.requests, with a (boolean) "limited" attribute
+//   - http.ratelimit.clients, the number of clients being tracked
+//
+// Clients are deliberately not included as attributes, to avoid unbounded
+// cardinality.
+const RateLimitInstrumentationName = "github.com/cxkoda/solgo/go/httperr/ratelimit"
+
+// rateLimitInstruments are the OpenTelemetry metric instruments used by the
+// RateLimit() Middleware.
+type rateLimitInstruments struct {
+	requests metric.Int64Counter
+	clients  metric.Int64UpDownCounter
+}
+
+// rateLimitMetrics returns the RateLimit() metric instruments.
+var rateLimitMetrics = otelonce.Instruments(RateLimitInstrumentationName, func(m metric.Meter) (*rateLimitInstruments, error) {
+	inst := new(rateLimitInstruments)
+	var errs [2]error
+	inst.requests, errs[0] = m.Int64Counter(
+		"http.ratelimit.requests",
+		metric.WithDescription("Number of requests checked against per-client rate limits."),
+	)
+	inst.clients, errs[1] = m.Int64UpDownCounter(
+		"http.ratelimit.clients",
+		metric.WithDescription("Number of clients with tracked rate limits."),
+	)
+	return inst, errors.Join(errs[:]...)
+})
+
+// RateLimit returns a Middleware that applies a token-bucket limit to each
+// client, as identified by cfg.Client. Requests in excess of the limit receive
+// a 429 (Too Many Requests) error, with a Retry-After header, and are not
+// passed to the next handler. The Options are equivalent to those accepted by
+// HandlerFunc().
+func RateLimit(cfg ClientRateLimits, opts ...Option) Middleware {
+	errCfg := newConfig(opts)
+	clients := &clientLimiters{
+		cfg:     cfg,
+		byKey:   make(map[string]*clientLimiter),
+		metrics: rateLimitMetrics(),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := clients.cfg.client()(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wait := clients.reserve(r.Context(), key)
+			clients.metrics.requests.Add(r.Context(), 1, metric.WithAttributes(attribute.Bool("limited", wait > 0)))
+			if wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				errCfg.handleErr(w, r, Errorf(http.StatusTooManyRequests, "rate_limited", "rate limit exceeded; retry in %v", wait.Round(time.Millisecond)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// A clientLimiter is the rate limiter of a single client.
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// clientLimiters tracks a clientLimiter for every recently seen client.
+type clientLimiters struct {
+	cfg     ClientRateLimits
+	metrics *rateLimitInstruments
+
+	mu        sync.Mutex
+	byKey     map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// reserve consumes a token from the client's bucket if one is available,
+// returning zero, otherwise it returns the duration until one will be.
+func (c *clientLimiters) reserve(ctx context.Context, key string) time.Duration {
+	now := c.cfg.timeNow()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep(ctx, now)
+
+	cl, ok := c.byKey[key]
+	if !ok {
+		cl = &clientLimiter{
+			limiter: rate.NewLimiter(c.cfg.rate(), c.cfg.burst()),
+		}
+		c.byKey[key] = cl
+		c.metrics.clients.Add(ctx, 1)
+	}
+	cl.lastSeen = now
+
+	res := cl.limiter.ReserveN(now, 1)
+	if d := res.DelayFrom(now); d > 0 {
+		// Rejected requests MUST NOT consume tokens, otherwise a client that
+		// continues to hammer the server would never be served.
+		res.CancelAt(now)
+		return d
+	}
+	return 0
+}
+
+// sweep forgets all clients that have been idle for longer than the
+// IdleTimeout, at most once per IdleTimeout. It MUST be called with c.mu held.
+func (c *clientLimiters) sweep(ctx context.Context, now time.Time) {
+	idle := c.cfg.idleTimeout()
+	if now.Sub(c.lastSweep) < idle {
+		return
+	}
+	c.lastSweep = now
+
+	var n int64
+	for k, cl := range c.byKey {
+		if now.Sub(cl.lastSeen) >= idle {
+			delete(c.byKey, k)
+			n++
+		}
+	}
+	if n > 0 {
+		c.metrics.clients.Add(ctx, -n)
+	}
+}
@@ -0,0 +1,188 @@
+package httperr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	cfg := ClientRateLimits{
+		Rate:        1,
+		Burst:       2,
+		IdleTimeout: time.Minute,
+		now:         func() time.Time { return now },
+	}
+	var handled int
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		handled++
+	}), RateLimit(cfg))
+
+	type result struct {
+		Code       int
+		RetryAfter string
+	}
+	request := func(remoteAddr string) result {
+		req := httptest.NewRequest(http.MethodGet, "http://target", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return result{rec.Code, rec.Header().Get("Retry-After")}
+	}
+
+	var (
+		ok      = result{Code: http.StatusOK}
+		limited = result{Code: http.StatusTooManyRequests, RetryAfter: "1"}
+	)
+
+	steps := []struct {
+		desc       string
+		advance    time.Duration
+		remoteAddr string
+		want       result
+	}{
+		{"burst", 0, "1.2.3.4:1000", ok},
+		{"burst from different port", 0, "1.2.3.4:2000", ok},
+		{"burst exhausted", 0, "1.2.3.4:1000", limited},
+		{"rejected requests don't consume tokens", 0, "1.2.3.4:1000", limited},
+		{"other client unaffected", 0, "5.6.7.8:1000", ok},
+		{"refilled", time.Second, "1.2.3.4:1000", ok},
+		{"only one token refilled", 0, "1.2.3.4:1000", limited},
+		{"IPv6 /64 shares bucket", 0, "[2001:db8::1]:1000", ok},
+		{"IPv6 /64 shares bucket", 0, "[2001:db8::2]:1000", ok},
+		{"IPv6 /64 exhausted", 0, "[2001:db8::3]:1000", limited},
+		{"other IPv6 /64 unaffected", 0, "[2001:db8:0:1::1]:1000", ok},
+	}
+
+	var wantHandled int
+	for _, s := range steps {
+		now = now.Add(s.advance)
+		got := request(s.remoteAddr)
+		if diff := cmp.Diff(s.want, got); diff != "" {
+			t.Errorf("%s: request from %q diff (-want +got):\n%s", s.desc, s.remoteAddr, diff)
+		}
+		if got.Code == http.StatusOK {
+			wantHandled++
+		}
+	}
+	if handled != wantHandled {
+		t.Errorf("next handler called %d times; want %d", handled, wantHandled)
+	}
+}
+
+func TestRateLimitSweep(t *testing.T) {
+	now := time.Unix(0, 0)
+	clients := &clientLimiters{
+		cfg: ClientRateLimits{
+			Rate:        1,
+			IdleTimeout: time.Minute,
+			now:         func() time.Time { return now },
+		},
+		byKey:   make(map[string]*clientLimiter),
+		metrics: rateLimitMetrics(),
+	}
+
+	ctx := context.Background()
+	for _, key := range []string{"a", "b"} {
+		if wait := clients.reserve(ctx, key); wait != 0 {
+			t.Fatalf("first reserve(%q) got wait %v; want 0", key, wait)
+		}
+	}
+	now = now.Add(30 * time.Second)
+	clients.reserve(ctx, "b")
+	now = now.Add(40 * time.Second)
+	clients.reserve(ctx, "c")
+
+	var got []string
+	for k := range clients.byKey {
+		got = append(got, k)
+	}
+	if diff := cmp.Diff([]string{"b", "c"}, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("after sweep; tracked clients diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestClientKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        ClientKey
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{
+			name:       "RemoteIP IPv4",
+			key:        RemoteIP,
+			remoteAddr: "1.2.3.4:5678",
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "RemoteIP IPv6",
+			key:        RemoteIP,
+			remoteAddr: "[2001:db8:1:2:3:4:5:6]:5678",
+			want:       "2001:db8:1:2::/64",
+		},
+		{
+			name:       "ForwardedIP without trusted proxies",
+			key:        ForwardedIP(0),
+			remoteAddr: "10.0.0.1:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1"}},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "ForwardedIP ignores spoofed addresses",
+			key:        ForwardedIP(1),
+			remoteAddr: "10.0.0.1:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4"}},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "ForwardedIP across multiple headers",
+			key:        ForwardedIP(2),
+			remoteAddr: "10.0.0.1:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4", "10.0.0.2"}},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "ForwardedIP with too few addresses",
+			key:        ForwardedIP(2),
+			remoteAddr: "10.0.0.1:80",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "HeaderOr with header",
+			key:        HeaderOr("X-Api-Key", RemoteIP),
+			remoteAddr: "1.2.3.4:5678",
+			headers:    map[string][]string{"X-Api-Key": {"secret"}},
+			want:       "X-Api-Key:secret",
+		},
+		{
+			name:       "HeaderOr fallback",
+			key:        HeaderOr("X-Api-Key", RemoteIP),
+			remoteAddr: "1.2.3.4:5678",
+			want:       "-:1.2.3.4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://target", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, vs := range tt.headers {
+				for _, v := range vs {
+					req.Header.Add(k, v)
+				}
+			}
+			if got := tt.key(req); got != tt.want {
+				t.Errorf("ClientKey(%+v) got %q; want %q", req, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package httperr
+
+import (
+	"errors"
+	"sync"
+)
+
+// A statusMapping converts errors into an *Error if they match the mapping.
+type statusMapping func(error) (*Error, bool)
+
+var registry struct {
+	sync.RWMutex
+	mappings []statusMapping
+}
+
+// RegisterStatus registers a mapping from all errors for which
+// errors.Is(err, target) holds to the HTTP status. This allows handlers to
+// return domain errors (e.g. sql.ErrNoRows) directly instead of wrapping them
+// with WithStatus() at every call site. The Error.Code is derived from the
+// status.
+//
+// Mappings are checked in the order they were registered, and the first to
+// match is used. Errors that already wrap an *Error (e.g. from Formatf()) are
+// never remapped. RegisterStatus is typically called from an init() function.
+func RegisterStatus(target error, status int) {
+	register(func(err error) (*Error, bool) {
+		if !errors.Is(err, target) {
+			return nil, false
+		}
+		return &Error{Status: status, Message: err.Error()}, true
+	})
+}
+
+// RegisterStatusAs is equivalent to RegisterStatus() except that it matches
+// all errors for which errors.As(err, new(E)) holds; i.e. those of, or
+// wrapping, type E.
+func RegisterStatusAs[E error](status int) {
+	register(func(err error) (*Error, bool) {
+		var target E
+		if !errors.As(err, &target) {
+			return nil, false
+		}
+		return &Error{Status: status, Message: err.Error()}, true
+	})
+}
+
+func register(m statusMapping) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.mappings = append(registry.mappings, m)
+}
+
+// asError converts a non-nil err into an *Error. If err wraps an *Error then
+// it is returned, otherwise registered mappings are checked before defaulting
+// to a 500.
+func asError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, m := range registry.mappings {
+		if e, ok := m(err); ok {
+			return e
+		}
+	}
+	return &Error{Status: 500, Message: err.Error()}
+}
@@ -0,0 +1,91 @@
+package httperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	errNotFoundForRegistry = errors.New("thing not found")
+	errUnmapped            = errors.New("unmapped")
+)
+
+type quotaError struct {
+	remaining int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("quota exceeded; %d remaining", e.remaining)
+}
+
+func init() {
+	RegisterStatus(errNotFoundForRegistry, http.StatusNotFound)
+	RegisterStatusAs[*quotaError](http.StatusTooManyRequests)
+}
+
+func TestRegisteredStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "sentinel",
+			err:        errNotFoundForRegistry,
+			wantStatus: 404,
+			wantBody:   "thing not found",
+		},
+		{
+			name:       "wrapped sentinel",
+			err:        fmt.Errorf("fetching thing: %w", errNotFoundForRegistry),
+			wantStatus: 404,
+			wantBody:   "fetching thing: thing not found",
+		},
+		{
+			name:       "type",
+			err:        fmt.Errorf("handler: %w", &quotaError{remaining: 0}),
+			wantStatus: 429,
+			wantBody:   "handler: quota exceeded; 0 remaining",
+		},
+		{
+			name:       "explicit status takes precedence",
+			err:        WithStatus(http.StatusGone, errNotFoundForRegistry),
+			wantStatus: 410,
+			wantBody:   "thing not found",
+		},
+		{
+			name:       "wrapped explicit status",
+			err:        fmt.Errorf("outer: %w", Formatf(http.StatusConflict, "inner")),
+			wantStatus: 409,
+			wantBody:   "inner",
+		},
+		{
+			name:       "unmapped",
+			err:        errUnmapped,
+			wantStatus: 500,
+			wantBody:   errMsg("unmapped"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := HandlerFunc(func(http.ResponseWriter, *http.Request) error {
+				return tt.err
+			})
+			rec := httptest.NewRecorder()
+			h(rec, httptest.NewRequest(http.MethodGet, "http://target", nil))
+
+			if got := rec.Code; got != tt.wantStatus {
+				t.Errorf("got status %d; want %d", got, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tt.wantBody {
+				t.Errorf("got body %q; want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package httperr
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// InvalidArgumentCode is the Error.Code of errors returned by JSONBody() and
+// QueryParams() when a request is well-formed but fails validation, mirroring
+// the gRPC code returned by the protovalid interceptors.
+const InvalidArgumentCode = "invalid_argument"
+
+// validate returns an error, with status 400 and InvalidArgumentCode, if v
+// implements ValidateAll() or Validate() and the respective method returns an
+// error. ValidateAll() is preferred, as generated by protoc-gen-validate, so
+// that all violations are reported at once.
+func validate(v interface{}) error {
+	var err error
+	switch v := v.(type) {
+	case interface{ ValidateAll() error }:
+		err = v.ValidateAll()
+	case interface{ Validate() error }:
+		err = v.Validate()
+	}
+	if err != nil {
+		return Errorf(http.StatusBadRequest, InvalidArgumentCode, "%v", err)
+	}
+	return nil
+}
+
+// JSONBody decodes the request's JSON body into a new T and validates it. If
+// *T is a proto.Message it is decoded with protojson, otherwise with
+// encoding/json, rejecting unknown fields in both cases.
+//
+// Errors are suitable for returning directly from a HandlerFunc() or
+// RouterHandle(): a non-JSON Content-Type results in a 415, malformed bodies in
+// a 400, and validation failures in a 400 with InvalidArgumentCode. T is
+// validated if *T has a ValidateAll() or Validate() method, which includes
+// all messages compiled with protoc-gen-validate.
+//
+// Bodies are read in full, so handlers SHOULD wrap r.Body with
+// http.MaxBytesReader() if they are exposed to untrusted clients.
+func JSONBody[T any](r *http.Request) (*T, error) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || mt != "application/json" {
+			return nil, Formatf(http.StatusUnsupportedMediaType, "Content-Type %q; want application/json", ct)
+		}
+	}
+	if r.Body == nil {
+		return nil, Formatf(http.StatusBadRequest, "empty request body")
+	}
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, Formatf(http.StatusBadRequest, "read request body: %v", err)
+	}
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return nil, Formatf(http.StatusBadRequest, "empty request body")
+	}
+
+	v := new(T)
+	if m, ok := interface{}(v).(proto.Message); ok {
+		if err := protojson.Unmarshal(buf, m); err != nil {
+			return nil, Formatf(http.StatusBadRequest, "decode JSON body: %v", err)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(buf))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return nil, Formatf(http.StatusBadRequest, "decode JSON body: %v", err)
+		}
+		if dec.More() {
+			return nil, Formatf(http.StatusBadRequest, "decode JSON body: unexpected data after top-level value")
+		}
+	}
+
+	if err := validate(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// QueryParams decodes the request's URL query parameters into a new T, which
+// MUST be a struct, and validates it. Only fields with a `query` tag are
+// populated, from the parameter named by the tag. A ",required" suffix on the
+// tag results in an error if the parameter is absent.
+//
+// Supported field types are strings, bools, integers, floats, types
+// implementing encoding.TextUnmarshaler (e.g. common.Address), pointers to any
+// of these (left nil if the parameter is absent), and slices of any of these,
+// which receive all values of a repeated parameter. Other fields only accept a
+// single value.
+//
+// Errors are as described for JSONBody(), except that an unsupported T is a
+// programming error and results in a 500.
+func QueryParams[T any](r *http.Request) (*T, error) {
+	v := new(T)
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("QueryParams[%T]() with non-struct type", *v)
+	}
+
+	query := r.URL.Query()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup("query")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if !field.IsExported() {
+			return nil, fmt.Errorf("QueryParams[%T]() with unexported field %s tagged %q", *v, field.Name, tag)
+		}
+
+		vals, ok := query[name]
+		if !ok || len(vals) == 0 {
+			if opts == "required" {
+				return nil, Errorf(http.StatusBadRequest, InvalidArgumentCode, "missing query parameter %q", name)
+			}
+			continue
+		}
+		if err := setQueryField(rv.Field(i), name, vals); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validate(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// setQueryField sets f from the values of the named query parameter.
+func setQueryField(f reflect.Value, name string, vals []string) error {
+	if f.Kind() == reflect.Slice && !reflect.PointerTo(f.Type()).Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(f.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setQueryValue(s.Index(i), name, val); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+		return nil
+	}
+
+	if len(vals) > 1 {
+		return Formatf(http.StatusBadRequest, "query parameter %q repeated %d times; want at most once", name, len(vals))
+	}
+	return setQueryValue(f, name, vals[0])
+}
+
+// setQueryValue parses val into f, allocating a new value if f is a pointer.
+func setQueryValue(f reflect.Value, name, val string) error {
+	if f.Kind() == reflect.Pointer {
+		p := reflect.New(f.Type().Elem())
+		if err := setQueryValue(p.Elem(), name, val); err != nil {
+			return err
+		}
+		f.Set(p)
+		return nil
+	}
+
+	if f.CanAddr() {
+		if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := u.UnmarshalText([]byte(val)); err != nil {
+				return Formatf(http.StatusBadRequest, "query parameter %q: %v", name, err)
+			}
+			return nil
+		}
+	}
+
+	var err error
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(val)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(val)
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(val, 10, f.Type().Bits())
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(val, 10, f.Type().Bits())
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var x float64
+		x, err = strconv.ParseFloat(val, f.Type().Bits())
+		f.SetFloat(x)
+	default:
+		return fmt.Errorf("query parameter %q: unsupported field type %v", name, f.Type())
+	}
+
+	if err != nil {
+		return Formatf(http.StatusBadRequest, "query parameter %q: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package httperr
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type order struct {
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+}
+
+func (o *order) Validate() error {
+	if o.Quantity <= 0 {
+		return fmt.Errorf("quantity %d; must be positive", o.Quantity)
+	}
+	return nil
+}
+
+// allValidator mimics messages compiled with protoc-gen-validate, which have
+// both Validate() and ValidateAll() methods.
+type allValidator struct {
+	X int `json:"x" query:"x"`
+}
+
+func (*allValidator) Validate() error {
+	return errors.New("Validate() called")
+}
+
+func (v *allValidator) ValidateAll() error {
+	if v.X < 0 {
+		return errors.New("ValidateAll() called")
+	}
+	return nil
+}
+
+// errStatus returns the HTTP status and code that HandlerFunc() would send for
+// the error.
+func errStatus(err error) (int, string) {
+	if err == nil {
+		return http.StatusOK, ""
+	}
+	e := asError(err)
+	return e.Status, e.code()
+}
+
+func TestJSONBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        *order
+		wantStatus  int
+		wantCode    string
+	}{
+		{
+			name:        "valid",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"item": "widget", "quantity": 3}`,
+			want:        &order{Item: "widget", Quantity: 3},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "valid without Content-Type",
+			body:       `{"item": "widget", "quantity": 1}`,
+			want:       &order{Item: "widget", Quantity: 1},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "wrong Content-Type",
+			contentType: "text/plain",
+			body:        `{"item": "widget", "quantity": 3}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    "unsupported_media_type",
+		},
+		{
+			name:       "empty body",
+			body:       "  \n",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			name:       "malformed",
+			body:       `{"item": `,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			name:       "unknown field",
+			body:       `{"item": "widget", "quantity": 3, "price": 1}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			name:       "trailing data",
+			body:       `{"item": "widget", "quantity": 3} {}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			name:       "invalid",
+			body:       `{"item": "widget", "quantity": 0}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   InvalidArgumentCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://target", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			got, err := JSONBody[order](req)
+			if gotStatus, gotCode := errStatus(err); gotStatus != tt.wantStatus || gotCode != tt.wantCode {
+				t.Fatalf("JSONBody[order](%q) got err %v with status %d and code %q; want status %d and code %q", tt.body, err, gotStatus, gotCode, tt.wantStatus, tt.wantCode)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("JSONBody[order](%q) diff (-want +got):\n%s", tt.body, diff)
+			}
+		})
+	}
+
+	t.Run("ValidateAll preferred", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "http://target", strings.NewReader(`{"x": -1}`))
+		_, err := JSONBody[allValidator](req)
+		if err == nil || err.Error() != "ValidateAll() called" {
+			t.Errorf("JSONBody[allValidator]() got err %v; want from ValidateAll()", err)
+		}
+	})
+
+	t.Run("proto", func(t *testing.T) {
+		ts := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+		req := httptest.NewRequest(http.MethodPost, "http://target", strings.NewReader(`"2023-04-05T06:07:08Z"`))
+
+		got, err := JSONBody[timestamppb.Timestamp](req)
+		if err != nil {
+			t.Fatalf("JSONBody[timestamppb.Timestamp]() error %v", err)
+		}
+		if diff := cmp.Diff(timestamppb.New(ts), got, protocmp.Transform()); diff != "" {
+			t.Errorf("JSONBody[timestamppb.Timestamp]() diff (-want +got):\n%s", diff)
+		}
+	})
+}
+
+type ipParam struct {
+	net.IP
+}
+
+func TestQueryParams(t *testing.T) {
+	type params struct {
+		Name    string    `query:"name,required"`
+		Limit   *uint8    `query:"limit"`
+		Verbose bool      `query:"v"`
+		Scores  []float64 `query:"score"`
+		Addr    ipParam   `query:"addr"`
+		Ignored string
+	}
+
+	tests := []struct {
+		query      string
+		want       *params
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			query:      "name=x",
+			want:       &params{Name: "x"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			query: "name=x&limit=42&v=true&score=1.5&score=-2&addr=10.0.0.1&Ignored=y",
+			want: &params{
+				Name:    "x",
+				Limit:   func() *uint8 { n := uint8(42); return &n }(),
+				Verbose: true,
+				Scores:  []float64{1.5, -2},
+				Addr:    ipParam{net.IPv4(10, 0, 0, 1)},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			query:      "limit=1",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   InvalidArgumentCode,
+		},
+		{
+			query:      "name=x&limit=256",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			query:      "name=x&v=maybe",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			query:      "name=x&name=y",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+		{
+			query:      "name=x&addr=localhost",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "bad_request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://target/?"+tt.query, nil)
+
+			got, err := QueryParams[params](req)
+			if gotStatus, gotCode := errStatus(err); gotStatus != tt.wantStatus || gotCode != tt.wantCode {
+				t.Fatalf("QueryParams[params](%q) got err %v with status %d and code %q; want status %d and code %q", tt.query, err, gotStatus, gotCode, tt.wantStatus, tt.wantCode)
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("QueryParams[params](%q) diff (-want +got):\n%s", tt.query, diff)
+			}
+		})
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://target/?x=-1", nil)
+		_, err := QueryParams[allValidator](req)
+		if gotStatus, gotCode := errStatus(err); gotStatus != http.StatusBadRequest || gotCode != InvalidArgumentCode {
+			t.Errorf("QueryParams[allValidator](%q) got err %v with status %d and code %q; want status %d and code %q", req.URL.RawQuery, err, gotStatus, gotCode, http.StatusBadRequest, InvalidArgumentCode)
+		}
+	})
+
+	t.Run("non-struct", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://target/?x=1", nil)
+		_, err := QueryParams[int](req)
+		if gotStatus, _ := errStatus(err); gotStatus != http.StatusInternalServerError {
+			t.Errorf("QueryParams[int]() got err %v with status %d; want status %d", err, gotStatus, http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package ipfs
+
+import (
+	"context"
+	"io/fs"
+
+	iface "github.com/ipfs/interface-go-ipfs-core"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+// AddOptions configure AddFSWithOptions(). The zero value is equivalent to
+// Kubo's defaults for the Unixfs API, which differ from the CLI's in that
+// content isn't pinned.
+type AddOptions struct {
+	// CIDVersion is the version of the CIDs of added nodes; 0 (the default) or
+	// 1.
+	CIDVersion int
+	// Chunker, if non-empty, determines how files are split into blocks; e.g.
+	// "size-1048576" or "rabin-min-avg-max". Kubo's default is "size-262144".
+	Chunker string
+	// RawLeaves, if non-nil, determines whether file data is stored in raw
+	// blocks instead of being wrapped in UnixFS nodes. If nil, Kubo enables
+	// raw leaves if and only if CIDVersion is 1.
+	RawLeaves *bool
+	// Pin, if true, recursively pins the root of the added content.
+	Pin bool
+	// Progress, if non-nil, is called after every Unixfs adder event that
+	// changes the AddProgress. Calls are made sequentially, from a goroutine
+	// other than the one calling AddFSWithOptions(), and all calls happen
+	// before it returns. Progress SHOULD NOT block as it stalls the adder.
+	Progress func(AddProgress)
+}
+
+// AddProgress describes the progress of AddFSWithOptions().
+type AddProgress struct {
+	// File is the path, within the added directory, of the file to which the
+	// most recent event pertained.
+	File string
+	// FilesAdded is the number of files that have been fully added, out of
+	// TotalFiles.
+	FilesAdded, TotalFiles int
+	// BytesHashed is the number of bytes read and hashed, summed over all
+	// files, including those that are only partially added.
+	BytesHashed int64
+}
+
+// unixfsOptions returns the UnixfsAddOptions equivalent to the AddOptions,
+// excluding those related to progress.
+func (o *AddOptions) unixfsOptions() []options.UnixfsAddOption {
+	opts := []options.UnixfsAddOption{
+		options.Unixfs.CidVersion(o.CIDVersion),
+		options.Unixfs.Pin(o.Pin),
+	}
+	if o.Chunker != "" {
+		opts = append(opts, options.Unixfs.Chunker(o.Chunker))
+	}
+	if o.RawLeaves != nil {
+		opts = append(opts, options.Unixfs.RawLeaves(*o.RawLeaves))
+	}
+	return opts
+}
+
+// AddFSWithOptions is equivalent to AddFS() except that the UnixfsAddOptions
+// are derived from opts, which MAY be nil to use the defaults.
+func (ipfs *IPFS) AddFSWithOptions(ctx context.Context, fsys fs.FS, root string, rh FSRootHandling, opts *AddOptions) (path.Resolved, error) {
+	if opts == nil {
+		opts = new(AddOptions)
+	}
+
+	dir, n, cleanup, err := fsDirectory(fsys, root, rh)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	addOpts := opts.unixfsOptions()
+	if opts.Progress == nil {
+		return ipfs.Unixfs().Add(ctx, dir, addOpts...)
+	}
+
+	// The adder sends events synchronously and doesn't close the channel, so
+	// it's safe to do so once Add() returns.
+	events := make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := newProgressTracker(n, opts.Progress)
+		for ev := range events {
+			if ev, ok := ev.(*iface.AddEvent); ok {
+				t.handle(ev)
+			}
+		}
+	}()
+
+	res, err := ipfs.Unixfs().Add(ctx, dir, append(addOpts, options.Unixfs.Events(events), options.Unixfs.Progress(true))...)
+	close(events)
+	<-done
+	return res, err
+}
+
+// A progressTracker converts Unixfs adder events into AddProgress.
+type progressTracker struct {
+	fn        func(AddProgress)
+	progress  AddProgress
+	fileBytes map[string]int64
+}
+
+func newProgressTracker(totalFiles int, fn func(AddProgress)) *progressTracker {
+	return &progressTracker{
+		fn:        fn,
+		progress:  AddProgress{TotalFiles: totalFiles},
+		fileBytes: make(map[string]int64),
+	}
+}
+
+// handle updates the AddProgress and propagates it to the callback. Events
+// without a Path report the cumulative bytes read from a single file, and are
+// only emitted for files, at least once each. Events with a Path signal that a
+// node was added, which is also the case for directories; these are therefore
+// identified by the absence of prior progress events.
+func (t *progressTracker) handle(ev *iface.AddEvent) {
+	if ev.Path == nil {
+		t.progress.BytesHashed += ev.Bytes - t.fileBytes[ev.Name]
+		t.fileBytes[ev.Name] = ev.Bytes
+	} else {
+		if _, ok := t.fileBytes[ev.Name]; !ok {
+			return
+		}
+		t.progress.FilesAdded++
+	}
+	t.progress.File = ev.Name
+	t.fn(t.progress)
+}
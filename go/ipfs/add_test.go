@@ -0,0 +1,53 @@
+package ipfs
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ipfs/go-cid"
+	iface "github.com/ipfs/interface-go-ipfs-core"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+)
+
+func TestProgressTracker(t *testing.T) {
+	const cidStr = "QmX9MfavkGfNUYAhGouW4wXoYPu3uuszG11NCD1mAC6VVB"
+	c, err := cid.Decode(cidStr)
+	if err != nil {
+		t.Fatalf("Bad test setup; cid.Decode(%q) error %v", cidStr, err)
+	}
+	added := path.IpfsPath(c)
+
+	events := []*iface.AddEvent{
+		{Name: "a", Bytes: 100},
+		{Name: "b", Bytes: 50},
+		{Name: "a", Bytes: 300},
+		{Name: "a", Path: added},
+		{Name: "empty", Bytes: 0},
+		{Name: "empty", Path: added},
+		{Name: "b", Bytes: 60},
+		{Name: "b", Path: added},
+		{Name: "", Path: added}, // root directory
+	}
+
+	var got []AddProgress
+	tr := newProgressTracker(3, func(p AddProgress) {
+		got = append(got, p)
+	})
+	for _, ev := range events {
+		tr.handle(ev)
+	}
+
+	want := []AddProgress{
+		{File: "a", TotalFiles: 3, BytesHashed: 100},
+		{File: "b", TotalFiles: 3, BytesHashed: 150},
+		{File: "a", TotalFiles: 3, BytesHashed: 350},
+		{File: "a", FilesAdded: 1, TotalFiles: 3, BytesHashed: 350},
+		{File: "empty", FilesAdded: 1, TotalFiles: 3, BytesHashed: 350},
+		{File: "empty", FilesAdded: 2, TotalFiles: 3, BytesHashed: 350},
+		{File: "b", FilesAdded: 2, TotalFiles: 3, BytesHashed: 360},
+		{File: "b", FilesAdded: 3, TotalFiles: 3, BytesHashed: 360},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%T.handle() progress diff (-want +got):\n%s", tr, diff)
+	}
+}
@@ -217,27 +217,43 @@ const (
 //
 // Unlike InitFS() and NewFS() that refer to a filesystem-based IPFS repository,
 // this method refers to the actual files being added to the system.
+//
+// See AddFSWithOptions() for a typed alternative to the UnixfsAddOptions, with
+// progress reporting.
 func (ipfs *IPFS) AddFS(ctx context.Context, fsys fs.FS, root string, rh FSRootHandling, opts ...options.UnixfsAddOption) (path.Resolved, error) {
+	dir, _, cleanup, err := fsDirectory(fsys, root, rh)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+	return ipfs.Unixfs().Add(ctx, dir, opts...)
+}
+
+// fsDirectory returns a files.Directory of all non-directory entries in fsys,
+// as described by AddFS(), along with the number of such files. The returned
+// function closes all opened files and MUST be called even if an error is
+// returned.
+func fsDirectory(fsys fs.FS, root string, rh FSRootHandling) (files.Directory, int, func(), error) {
 	var close []io.Closer
-	defer func() {
+	cleanup := func() {
 		for _, c := range close {
 			// It's safe to ignore errors returned here because we're only
 			// reading from files.
 			c.Close()
 		}
-	}()
+	}
 
 	if rh == StripFSRoot {
 		sub, err := fs.Sub(fsys, root)
 		if err != nil {
-			return nil, fmt.Errorf("stripping FS root: fs.Sub(%T, %q): %v", fsys, root, err)
+			return nil, 0, cleanup, fmt.Errorf("stripping FS root: fs.Sub(%T, %q): %v", fsys, root, err)
 		}
 		fsys = sub
 		root = "."
 	}
 
 	ipfsDir := make(map[string]files.Node)
-	fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("fs.WalkDirFunc received %w", err)
 		}
@@ -253,6 +269,8 @@ func (ipfs *IPFS) AddFS(ctx context.Context, fsys fs.FS, root string, rh FSRootH
 		ipfsDir[path] = files.NewReaderFile(f)
 		return nil
 	})
-
-	return ipfs.Unixfs().Add(ctx, files.NewMapDirectory(ipfsDir), opts...)
+	if err != nil {
+		return nil, 0, cleanup, fmt.Errorf("fs.WalkDir(%T, %q): %v", fsys, root, err)
+	}
+	return files.NewMapDirectory(ipfsDir), len(ipfsDir), cleanup, nil
 }
@@ -99,6 +99,38 @@ func TestLocalhostRoundtrip(t *testing.T) {
 		}
 	})
 
+	t.Run("AddFSWithOptions", func(t *testing.T) {
+		var got []AddProgress
+		opts := &AddOptions{
+			CIDVersion: 1,
+			Chunker:    "size-2",
+			Progress: func(p AddProgress) {
+				got = append(got, p)
+			},
+		}
+		res, err := ipfs.AddFSWithOptions(ctx, testdata, "testdata", StripFSRoot, opts)
+		if err != nil {
+			t.Fatalf("%T.AddFSWithOptions(ctx, %T{testdata/*}, %q, %+v) error %v", ipfs, testdata, "testdata", opts, err)
+		}
+		if v := res.Cid().Version(); v != 1 {
+			t.Errorf("%T.AddFSWithOptions(…, %+v) got CID %v with version %d; want 1", ipfs, opts, res.Cid(), v)
+		}
+
+		if len(got) == 0 {
+			t.Fatalf("%T.AddFSWithOptions(…, %+v) never called Progress()", ipfs, opts)
+		}
+		last := got[len(got)-1]
+		last.File = ""
+		want := AddProgress{
+			FilesAdded:  3,
+			TotalFiles:  3,
+			BytesHashed: 10, // foo + bar + {1,3,3,7}
+		}
+		if diff := cmp.Diff(want, last); diff != "" {
+			t.Errorf("%T.AddFSWithOptions(…, %+v) final AddProgress diff (-want +got) ignoring File:\n%s", ipfs, opts, diff)
+		}
+	})
+
 	t.Run("retrieve files through secondary node", func(t *testing.T) {
 		// Generally one would use DefaultPeers to connect to the actual libp2p
 		// network, but for testing we only want to connect to the fresh node
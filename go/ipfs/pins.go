@@ -0,0 +1,126 @@
+package ipfs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+	"github.com/ipfs/kubo/core/corerepo"
+)
+
+// OrphanHandling defines how AuditPins() treats recursive pins that aren't in
+// the allowlist.
+type OrphanHandling bool
+
+const (
+	// ReportOrphans only reports orphaned pins, leaving the node unchanged.
+	ReportOrphans OrphanHandling = false
+	// RemoveOrphans unpins orphans and then runs garbage collection to reclaim
+	// the storage that they used.
+	RemoveOrphans OrphanHandling = true
+)
+
+// A PinAudit reports the recursive pins on a node, cross-referenced with an
+// allowlist of root CIDs. All CIDs are sorted by their string representations.
+type PinAudit struct {
+	// Allowed are the recursively pinned CIDs that are in the allowlist.
+	Allowed []cid.Cid
+	// Orphaned are the recursively pinned CIDs that aren't in the allowlist.
+	// If orphans were removed, they are no longer pinned.
+	Orphaned []cid.Cid
+	// Missing are the allowlisted CIDs that aren't recursively pinned.
+	Missing []cid.Cid
+
+	// Removed is true iff Orphaned pins were removed and garbage collection
+	// was run.
+	Removed bool
+	// ReclaimedBytes is the reduction in repository storage usage across
+	// garbage collection. It is approximate as other operations may be
+	// concurrently modifying the repository, and it is always zero if Removed
+	// is false.
+	ReclaimedBytes uint64
+}
+
+// AuditPins lists all recursive pins on the node and cross-references them with
+// the allowlist of root CIDs, which are compared by their multihashes so are
+// agnostic to CID version. Content referenced only by direct or indirect pins
+// is neither reported nor removed.
+//
+// If oh is RemoveOrphans, all recursive pins not in the allowlist are unpinned
+// and a garbage collection is run, allowing long-running nodes to bound their
+// storage. Note that garbage collection also removes any other unpinned blocks,
+// such as those cached from retrieval.
+func (ipfs *IPFS) AuditPins(ctx context.Context, allowlist []cid.Cid, oh OrphanHandling) (*PinAudit, error) {
+	allowed := make(map[string]cid.Cid)
+	for _, c := range allowlist {
+		allowed[c.Hash().KeyString()] = c
+	}
+
+	pins, err := ipfs.Pin().Ls(ctx, options.Pin.Ls.Recursive())
+	if err != nil {
+		return nil, fmt.Errorf("%T.Pin().Ls(ctx, [recursive]): %v", ipfs, err)
+	}
+
+	audit := new(PinAudit)
+	pinned := make(map[string]bool)
+	for p := range pins {
+		if err := p.Err(); err != nil {
+			return nil, fmt.Errorf("%T.Pin().Ls(ctx, [recursive]) received %T with error %v", ipfs, p, err)
+		}
+
+		c := p.Path().Cid()
+		key := c.Hash().KeyString()
+		pinned[key] = true
+		if _, ok := allowed[key]; ok {
+			audit.Allowed = append(audit.Allowed, c)
+		} else {
+			audit.Orphaned = append(audit.Orphaned, c)
+		}
+	}
+	for key, c := range allowed {
+		if !pinned[key] {
+			audit.Missing = append(audit.Missing, c)
+		}
+	}
+
+	for _, cids := range [][]cid.Cid{audit.Allowed, audit.Orphaned, audit.Missing} {
+		sortCIDs(cids)
+	}
+
+	if oh == ReportOrphans {
+		return audit, nil
+	}
+
+	for _, c := range audit.Orphaned {
+		if err := ipfs.Pin().Rm(ctx, path.IpfsPath(c), options.Pin.RmRecursive(true)); err != nil {
+			return nil, fmt.Errorf("%T.Pin().Rm(ctx, %v): %v", ipfs, c, err)
+		}
+	}
+
+	before, err := ipfs.node.Repo.GetStorageUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%T.GetStorageUsage() before garbage collection: %v", ipfs.node.Repo, err)
+	}
+	if err := corerepo.GarbageCollect(ipfs.node, ctx); err != nil {
+		return nil, fmt.Errorf("corerepo.GarbageCollect(): %v", err)
+	}
+	after, err := ipfs.node.Repo.GetStorageUsage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%T.GetStorageUsage() after garbage collection: %v", ipfs.node.Repo, err)
+	}
+
+	audit.Removed = true
+	if after < before {
+		audit.ReclaimedBytes = before - after
+	}
+	return audit, nil
+}
+
+func sortCIDs(cids []cid.Cid) {
+	sort.Slice(cids, func(i, j int) bool {
+		return cids[i].String() < cids[j].String()
+	})
+}
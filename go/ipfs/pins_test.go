@@ -0,0 +1,103 @@
+package ipfs
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-libipfs/files"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+)
+
+func TestAuditPins(t *testing.T) {
+	ctx := context.Background()
+
+	plugins, err := LoadPlugins("")
+	if err != nil {
+		t.Fatalf("InitPlugins(%q) error %v", "", err)
+	}
+	t.Cleanup(func() {
+		if err := plugins.Close(); err != nil {
+			t.Errorf("%T.Close() error %v", plugins, err)
+		}
+	})
+
+	ipfs := fromCleanTmpDir(ctx, t)
+
+	add := func(t *testing.T, content string) cid.Cid {
+		t.Helper()
+		p, err := ipfs.Unixfs().Add(ctx, files.NewReaderFile(strings.NewReader(content)), options.Unixfs.Pin(true))
+		if err != nil {
+			t.Fatalf("%T.Unixfs().Add(ctx, %q, [pin]) error %v", ipfs, content, err)
+		}
+		return p.Cid()
+	}
+	keep := add(t, "keep me")
+	orphan := add(t, "delete me")
+
+	// From running `ipfs add -r .` in the `testdata` directory, which isn't
+	// added to the node.
+	const missingStr = "QmX9MfavkGfNUYAhGouW4wXoYPu3uuszG11NCD1mAC6VVB"
+	missing, err := cid.Decode(missingStr)
+	if err != nil {
+		t.Fatalf("Bad test setup; cid.Decode(%q) error %v", missingStr, err)
+	}
+
+	allowlist := []cid.Cid{keep, missing}
+	opts := []cmp.Option{
+		cmp.Comparer(func(a, b cid.Cid) bool { return a.Equals(b) }),
+		cmp.FilterPath(func(p cmp.Path) bool {
+			return p.Last().String() == ".ReclaimedBytes"
+		}, cmp.Ignore()),
+	}
+
+	t.Run("report", func(t *testing.T) {
+		got, err := ipfs.AuditPins(ctx, allowlist, ReportOrphans)
+		if err != nil {
+			t.Fatalf("%T.AuditPins(ctx, %v, ReportOrphans) error %v", ipfs, allowlist, err)
+		}
+		want := &PinAudit{
+			Allowed:  []cid.Cid{keep},
+			Orphaned: []cid.Cid{orphan},
+			Missing:  []cid.Cid{missing},
+		}
+		if diff := cmp.Diff(want, got, opts...); diff != "" {
+			t.Errorf("%T.AuditPins(ctx, %v, ReportOrphans) diff (-want +got):\n%s", ipfs, allowlist, diff)
+		}
+		if got.ReclaimedBytes != 0 {
+			t.Errorf("%T.AuditPins(ctx, %v, ReportOrphans).ReclaimedBytes got %d; want 0", ipfs, allowlist, got.ReclaimedBytes)
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		got, err := ipfs.AuditPins(ctx, allowlist, RemoveOrphans)
+		if err != nil {
+			t.Fatalf("%T.AuditPins(ctx, %v, RemoveOrphans) error %v", ipfs, allowlist, err)
+		}
+		want := &PinAudit{
+			Allowed:  []cid.Cid{keep},
+			Orphaned: []cid.Cid{orphan},
+			Missing:  []cid.Cid{missing},
+			Removed:  true,
+		}
+		if diff := cmp.Diff(want, got, opts...); diff != "" {
+			t.Errorf("%T.AuditPins(ctx, %v, RemoveOrphans) diff (-want +got):\n%s", ipfs, allowlist, diff)
+		}
+	})
+
+	t.Run("after removal", func(t *testing.T) {
+		got, err := ipfs.AuditPins(ctx, allowlist, ReportOrphans)
+		if err != nil {
+			t.Fatalf("%T.AuditPins(ctx, %v, ReportOrphans) error %v", ipfs, allowlist, err)
+		}
+		want := &PinAudit{
+			Allowed: []cid.Cid{keep},
+			Missing: []cid.Cid{missing},
+		}
+		if diff := cmp.Diff(want, got, opts...); diff != "" {
+			t.Errorf("%T.AuditPins(ctx, %v, ReportOrphans) after removing orphans diff (-want +got):\n%s", ipfs, allowlist, diff)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package memconv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/holiman/uint256"
+)
+
+// Unlike the rest of this package, the functions in this file perform
+// value-preserving conversions with explicit byte order and bounds checks. They
+// exist to provide a single, audited implementation of the conversions between
+// EVM words and Go numeric types that are otherwise easy to get subtly wrong.
+
+// A Word is a 256-bit EVM word, in big-endian byte order, as used by Solidity.
+// The constraint is satisfied by both [32]byte and go-ethereum's common.Hash.
+type Word interface {
+	~[32]byte
+}
+
+// Uint256FromWord returns w as a uint256.
+func Uint256FromWord[W Word](w W) *uint256.Int {
+	return new(uint256.Int).SetBytes32(w[:])
+}
+
+// WordFromUint256 returns u as a Word.
+func WordFromUint256[W Word](u *uint256.Int) W {
+	return W(u.Bytes32())
+}
+
+// Uint256FromBytes returns the big-endian bytes as a uint256, returning an
+// error if the value overflows. Unlike Uint256FromWord(), b MAY be of any
+// length; if shorter than 32 bytes it is treated as if left-padded with zeros,
+// and if longer then all excess leading bytes MUST be zero.
+func Uint256FromBytes(b []byte) (*uint256.Int, error) {
+	if n := len(b); n > 32 {
+		for _, x := range b[:n-32] {
+			if x != 0 {
+				return nil, fmt.Errorf("%#x overflows uint256", b)
+			}
+		}
+		b = b[n-32:]
+	}
+	return new(uint256.Int).SetBytes(b), nil
+}
+
+// BigFromWord returns w as a non-negative big.Int; i.e. as a Solidity uint256.
+func BigFromWord[W Word](w W) *big.Int {
+	return new(big.Int).SetBytes(w[:])
+}
+
+// WordFromBig returns b as a Word, returning an error if b is negative or
+// overflows 256 bits.
+func WordFromBig[W Word](b *big.Int) (W, error) {
+	var w W
+	if b.Sign() < 0 {
+		return w, fmt.Errorf("negative %T %v can't be converted to unsigned word", b, b)
+	}
+	if n := b.BitLen(); n > 256 {
+		return w, fmt.Errorf("%T of %d bits overflows 256-bit word", b, n)
+	}
+	b.FillBytes(w[:])
+	return w, nil
+}
+
+// Uint64FromWord returns w as a uint64, returning an error if it overflows.
+func Uint64FromWord[W Word](w W) (uint64, error) {
+	for _, b := range w[:24] {
+		if b != 0 {
+			return 0, fmt.Errorf("%#x overflows uint64", w[:])
+		}
+	}
+	return binary.BigEndian.Uint64(w[24:]), nil
+}
+
+// WordFromUint64 returns x as a Word.
+func WordFromUint64[W Word](x uint64) W {
+	var w W
+	binary.BigEndian.PutUint64(w[24:], x)
+	return w
+}
+
+// Int64FromWord returns w, interpreted as a two's-complement Solidity int256,
+// as an int64, returning an error if it overflows.
+func Int64FromWord[W Word](w W) (int64, error) {
+	x := int64(binary.BigEndian.Uint64(w[24:]))
+
+	// All higher bytes must be sign extensions of x.
+	var ext byte
+	if x < 0 {
+		ext = 0xff
+	}
+	for _, b := range w[:24] {
+		if b != ext {
+			return 0, fmt.Errorf("%#x overflows int64", w[:])
+		}
+	}
+	return x, nil
+}
+
+// WordFromInt64 returns x as a two's-complement Solidity int256.
+func WordFromInt64[W Word](x int64) W {
+	var w W
+	if x < 0 {
+		for i := range w[:24] {
+			w[i] = 0xff
+		}
+	}
+	binary.BigEndian.PutUint64(w[24:], uint64(x))
+	return w
+}
+
+// Uint64FromBytes returns the 8 bytes as a uint64 in the specified byte order.
+// Unlike Cast[[8]byte, uint64](), the result is independent of the native
+// byte order of the platform.
+func Uint64FromBytes(order binary.ByteOrder, b [8]byte) uint64 {
+	return order.Uint64(b[:])
+}
+
+// Int64FromBytes is the int64 equivalent of Uint64FromBytes(), using a
+// two's-complement representation.
+func Int64FromBytes(order binary.ByteOrder, b [8]byte) int64 {
+	return int64(order.Uint64(b[:]))
+}
+
+// BytesFromUint64 is the inverse of Uint64FromBytes().
+func BytesFromUint64(order binary.ByteOrder, x uint64) [8]byte {
+	var b [8]byte
+	order.PutUint64(b[:], x)
+	return b
+}
+
+// BytesFromInt64 is the inverse of Int64FromBytes().
+func BytesFromInt64(order binary.ByteOrder, x int64) [8]byte {
+	return BytesFromUint64(order, uint64(x))
+}
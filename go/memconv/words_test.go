@@ -0,0 +1,154 @@
+package memconv
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestUint64Words(t *testing.T) {
+	for _, x := range []uint64{0, 1, 42, math.MaxUint32, math.MaxUint64} {
+		w := WordFromUint64[common.Hash](x)
+		if got, want := w.Big(), new(big.Int).SetUint64(x); got.Cmp(want) != 0 {
+			t.Errorf("WordFromUint64(%d) = %v; want %v", x, w, common.BigToHash(want))
+		}
+		got, err := Uint64FromWord(w)
+		if err != nil || got != x {
+			t.Errorf("Uint64FromWord(WordFromUint64(%d)) got %d, err = %v; want %[1]d, nil err", x, got, err)
+		}
+	}
+
+	overflow := WordFromUint64[[32]byte](1)
+	overflow[23] = 1
+	if _, err := Uint64FromWord(overflow); err == nil {
+		t.Errorf("Uint64FromWord(%#x) got nil error; want overflow", overflow)
+	}
+}
+
+func TestInt64Words(t *testing.T) {
+	for _, x := range []int64{0, 1, -1, 42, -42, math.MaxInt64, math.MinInt64} {
+		w := WordFromInt64[common.Hash](x)
+
+		// Solidity int256 is two's complement over 256 bits.
+		want := new(big.Int).SetInt64(x)
+		if x < 0 {
+			want.Add(want, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		if got := w.Big(); got.Cmp(want) != 0 {
+			t.Errorf("WordFromInt64(%d) = %v; want %v", x, w, common.BigToHash(want))
+		}
+
+		got, err := Int64FromWord(w)
+		if err != nil || got != x {
+			t.Errorf("Int64FromWord(WordFromInt64(%d)) got %d, err = %v; want %[1]d, nil err", x, got, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		word [32]byte
+	}{
+		{
+			name: "positive overflow",
+			word: func() [32]byte {
+				w := WordFromInt64[[32]byte](1)
+				w[0] = 0x01
+				return w
+			}(),
+		},
+		{
+			name: "negative int64 without sign extension",
+			word: WordFromUint64[[32]byte](math.MaxUint64),
+		},
+		{
+			name: "negative overflow",
+			word: func() [32]byte {
+				w := WordFromInt64[[32]byte](-1)
+				w[0] = 0xfe
+				return w
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Int64FromWord(tt.word); err == nil {
+				t.Errorf("Int64FromWord(%#x) got %d, nil error; want overflow", tt.word, got)
+			}
+		})
+	}
+}
+
+func TestBigAndUint256Words(t *testing.T) {
+	maxU256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	for _, b := range []*big.Int{big.NewInt(0), big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), 200), maxU256} {
+		w, err := WordFromBig[common.Hash](b)
+		if err != nil {
+			t.Fatalf("WordFromBig(%v) error %v", b, err)
+		}
+		if w != common.BigToHash(b) {
+			t.Errorf("WordFromBig(%v) = %v; want %v", b, w, common.BigToHash(b))
+		}
+		if got := BigFromWord(w); got.Cmp(b) != 0 {
+			t.Errorf("BigFromWord(WordFromBig(%v)) = %v", b, got)
+		}
+
+		u := Uint256FromWord(w)
+		if got := u.ToBig(); got.Cmp(b) != 0 {
+			t.Errorf("Uint256FromWord(WordFromBig(%v)) = %v", b, got)
+		}
+		if got := WordFromUint256[common.Hash](u); got != w {
+			t.Errorf("WordFromUint256(Uint256FromWord(%v)) = %v", w, got)
+		}
+
+		fromBytes, err := Uint256FromBytes(b.Bytes())
+		if err != nil || !fromBytes.Eq(u) {
+			t.Errorf("Uint256FromBytes(%#x) got %v, err = %v; want %v, nil err", b.Bytes(), fromBytes, err, u)
+		}
+	}
+
+	for _, b := range []*big.Int{big.NewInt(-1), new(big.Int).Add(maxU256, big.NewInt(1))} {
+		if _, err := WordFromBig[[32]byte](b); err == nil {
+			t.Errorf("WordFromBig(%v) got nil error; want error", b)
+		}
+	}
+	padded := append(make([]byte, 8), maxU256.Bytes()...)
+	if got, err := Uint256FromBytes(padded); err != nil || got.ToBig().Cmp(maxU256) != 0 {
+		t.Errorf("Uint256FromBytes(%#x) got %v, err = %v; want %v, nil err", padded, got, err, maxU256)
+	}
+	overflow := append([]byte{1}, make([]byte, 32)...)
+	if _, err := Uint256FromBytes(overflow); err == nil {
+		t.Errorf("Uint256FromBytes(%#x) got nil error; want overflow", overflow)
+	}
+}
+
+func TestByteOrder(t *testing.T) {
+	b := [8]byte{1, 2, 3, 4, 5, 6, 7, 0x88}
+
+	tests := []struct {
+		order binary.ByteOrder
+		want  uint64
+	}{
+		{binary.BigEndian, 0x0102030405060788},
+		{binary.LittleEndian, 0x8807060504030201},
+	}
+	for _, tt := range tests {
+		if got := Uint64FromBytes(tt.order, b); got != tt.want {
+			t.Errorf("Uint64FromBytes(%v, %#x) = %#x; want %#x", tt.order, b, got, tt.want)
+		}
+		if got := BytesFromUint64(tt.order, tt.want); got != b {
+			t.Errorf("BytesFromUint64(%v, %#x) = %#x; want %#x", tt.order, tt.want, got, b)
+		}
+
+		x := Int64FromBytes(tt.order, b)
+		if got, want := uint64(x), tt.want; got != want {
+			t.Errorf("Int64FromBytes(%v, %#x) = %d; want bits %#x", tt.order, b, x, want)
+		}
+		if got := BytesFromInt64(tt.order, x); got != b {
+			t.Errorf("BytesFromInt64(%v, %d) = %#x; want %#x", tt.order, x, got, b)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package merkle builds Merkle trees compatible with OpenZeppelin's
+// StandardMerkleTree (https://github.com/OpenZeppelin/merkle-tree) and
+// therefore verifiable on-chain with MerkleProof.sol.
+//
+// Leaves are the double keccak256 hash of the ABI-encoded values, and pairs of
+// nodes are sorted before hashing so proofs don't need to encode direction.
+package merkle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// A Tree is a Merkle tree over ABI-encoded values. Its layout is identical to
+// that of an OpenZeppelin StandardMerkleTree constructed from the same values,
+// so the JSON produced by MarshalJSON() can be loaded by
+// StandardMerkleTree.load().
+type Tree struct {
+	encoding []string
+	// nodes is a complete binary tree in array form, with the root at index 0
+	// and the children of node i at 2i+1 and 2i+2.
+	nodes  []common.Hash
+	values []value
+}
+
+type value struct {
+	args      []interface{}
+	treeIndex int
+}
+
+// New returns a Tree over the values, each of which MUST be ABI-encodable as
+// the respective types in encoding; e.g. ["address", "uint256"].
+func New(encoding []string, values [][]interface{}) (*Tree, error) {
+	if len(values) == 0 {
+		return nil, errors.New("merkle tree requires at least one leaf")
+	}
+	args, err := arguments(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	type hashed struct {
+		index int
+		hash  common.Hash
+	}
+	leaves := make([]hashed, len(values))
+	for i, v := range values {
+		h, err := leafHash(args, v)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %v", i, err)
+		}
+		leaves[i] = hashed{index: i, hash: h}
+	}
+	sort.SliceStable(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i].hash[:], leaves[j].hash[:]) < 0
+	})
+
+	n := 2*len(leaves) - 1
+	t := &Tree{
+		encoding: append([]string{}, encoding...),
+		nodes:    make([]common.Hash, n),
+		values:   make([]value, len(values)),
+	}
+	for i, l := range leaves {
+		idx := n - 1 - i
+		t.nodes[idx] = l.hash
+		t.values[l.index] = value{
+			args:      values[l.index],
+			treeIndex: idx,
+		}
+	}
+	for i := n - 1 - len(leaves); i >= 0; i-- {
+		t.nodes[i] = hashPair(t.nodes[2*i+1], t.nodes[2*i+2])
+	}
+	return t, nil
+}
+
+// NewAddresses returns a Tree with leaf encoding ["address"].
+func NewAddresses(addrs []common.Address) (*Tree, error) {
+	values := make([][]interface{}, len(addrs))
+	for i, a := range addrs {
+		values[i] = []interface{}{a}
+	}
+	return New([]string{"address"}, values)
+}
+
+// An AddressAmount is a single leaf of a Tree returned by NewAddressAmounts().
+type AddressAmount struct {
+	Address common.Address
+	Amount  *big.Int
+}
+
+// NewAddressAmounts returns a Tree with leaf encoding ["address", "uint256"].
+func NewAddressAmounts(leaves []AddressAmount) (*Tree, error) {
+	values := make([][]interface{}, len(leaves))
+	for i, l := range leaves {
+		values[i] = []interface{}{l.Address, l.Amount}
+	}
+	return New([]string{"address", "uint256"}, values)
+}
+
+// arguments returns the abi.Arguments for encoding values of the given types.
+func arguments(encoding []string) (abi.Arguments, error) {
+	args := make(abi.Arguments, len(encoding))
+	for i, e := range encoding {
+		typ, err := abi.NewType(e, "", nil)
+		if err != nil {
+			return nil, fmt.Errorf("abi.NewType(%q): %v", e, err)
+		}
+		args[i] = abi.Argument{Type: typ}
+	}
+	return args, nil
+}
+
+// LeafHash returns the hash of the ABI-encoded values, as used for leaves of a
+// Tree with the same encoding.
+func LeafHash(encoding []string, values ...interface{}) (common.Hash, error) {
+	args, err := arguments(encoding)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return leafHash(args, values)
+}
+
+func leafHash(args abi.Arguments, values []interface{}) (common.Hash, error) {
+	buf, err := args.Pack(values...)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("abi.Arguments.Pack(%v): %v", values, err)
+	}
+	// Double hashing prevents second-preimage attacks in which an internal node
+	// is presented as a leaf.
+	return crypto.Keccak256Hash(crypto.Keccak256(buf)), nil
+}
+
+// hashPair returns the keccak256 hash of the concatenated, sorted pair.
+func hashPair(a, b common.Hash) common.Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a[:], b[:])
+}
+
+// Root returns the root of the tree.
+func (t *Tree) Root() common.Hash {
+	return t.nodes[0]
+}
+
+// Len returns the number of leaves in the tree.
+func (t *Tree) Len() int {
+	return len(t.values)
+}
+
+// Leaf returns the hash of the i'th value passed to the constructor.
+func (t *Tree) Leaf(i int) common.Hash {
+	return t.nodes[t.values[i].treeIndex]
+}
+
+// Proof returns the proof of inclusion of the i'th value passed to the
+// constructor.
+func (t *Tree) Proof(i int) ([]common.Hash, error) {
+	if i < 0 || i >= len(t.values) {
+		return nil, fmt.Errorf("index %d out of range for %d values", i, len(t.values))
+	}
+
+	var proof []common.Hash
+	for idx := t.values[i].treeIndex; idx > 0; idx = (idx - 1) / 2 {
+		sibling := idx - 1
+		if idx%2 == 1 {
+			sibling = idx + 1
+		}
+		proof = append(proof, t.nodes[sibling])
+	}
+	return proof, nil
+}
+
+// Verify returns whether the proof demonstrates inclusion of the leaf in a
+// tree with the root. It is equivalent to MerkleProof.verify() in Solidity.
+func Verify(root, leaf common.Hash, proof []common.Hash) bool {
+	h := leaf
+	for _, p := range proof {
+		h = hashPair(h, p)
+	}
+	return h == root
+}
+
+// dump is the JSON format of an OpenZeppelin StandardMerkleTree.
+type dump struct {
+	Format       string      `json:"format"`
+	Tree         []string    `json:"tree"`
+	Values       []dumpValue `json:"values"`
+	LeafEncoding []string    `json:"leafEncoding"`
+}
+
+type dumpValue struct {
+	Value     []interface{} `json:"value"`
+	TreeIndex int           `json:"treeIndex"`
+}
+
+// MarshalJSON returns the tree in the same format as
+// StandardMerkleTree.dump(). Addresses are checksummed and integers are
+// represented as decimal strings.
+func (t *Tree) MarshalJSON() ([]byte, error) {
+	d := dump{
+		Format:       "standard-v1",
+		Tree:         make([]string, len(t.nodes)),
+		Values:       make([]dumpValue, len(t.values)),
+		LeafEncoding: t.encoding,
+	}
+	for i, n := range t.nodes {
+		d.Tree[i] = n.Hex()
+	}
+	for i, v := range t.values {
+		vals := make([]interface{}, len(v.args))
+		for j, a := range v.args {
+			switch a := a.(type) {
+			case common.Address:
+				vals[j] = a.Hex()
+			case *big.Int:
+				vals[j] = a.String()
+			default:
+				vals[j] = a
+			}
+		}
+		d.Values[i] = dumpValue{Value: vals, TreeIndex: v.treeIndex}
+	}
+	return json.Marshal(d)
+}
@@ -0,0 +1,170 @@
+package merkle
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+)
+
+func mustBig(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("big.Int.SetString(%q, 10) failed", s)
+	}
+	return n
+}
+
+func TestOpenZeppelinCompatibility(t *testing.T) {
+	// Example from the OpenZeppelin merkle-tree README.
+	tree, err := NewAddressAmounts([]AddressAmount{
+		{
+			Address: common.HexToAddress("0x1111111111111111111111111111111111111111"),
+			Amount:  mustBig(t, "5000000000000000000"),
+		},
+		{
+			Address: common.HexToAddress("0x2222222222222222222222222222222222222222"),
+			Amount:  mustBig(t, "2500000000000000000"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAddressAmounts(…) error %v", err)
+	}
+
+	if got, want := tree.Root(), common.HexToHash("0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"); got != want {
+		t.Errorf("%T.Root() got %v; want %v", tree, got, want)
+	}
+}
+
+func TestProofs(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		t.Run(fmt.Sprintf("%d leaves", n), func(t *testing.T) {
+			addrs := make([]common.Address, n)
+			for i := range addrs {
+				addrs[i] = common.BigToAddress(big.NewInt(int64(i + 1)))
+			}
+			tree, err := NewAddresses(addrs)
+			if err != nil {
+				t.Fatalf("NewAddresses([%d addresses]) error %v", n, err)
+			}
+			if got := tree.Len(); got != n {
+				t.Errorf("%T.Len() got %d; want %d", tree, got, n)
+			}
+			root := tree.Root()
+
+			for i, a := range addrs {
+				leaf, err := LeafHash([]string{"address"}, a)
+				if err != nil {
+					t.Fatalf("LeafHash(address, %v) error %v", a, err)
+				}
+				if got := tree.Leaf(i); got != leaf {
+					t.Errorf("%T.Leaf(%d) got %v; want LeafHash() = %v", tree, i, got, leaf)
+				}
+
+				proof, err := tree.Proof(i)
+				if err != nil {
+					t.Fatalf("%T.Proof(%d) error %v", tree, i, err)
+				}
+				if !Verify(root, leaf, proof) {
+					t.Errorf("Verify(%T.Root(), %T.Leaf(%d), %T.Proof(%d)) got false; want true", tree, tree, i, tree, i)
+				}
+
+				other, err := LeafHash([]string{"address"}, common.HexToAddress("0xbad"))
+				if err != nil {
+					t.Fatalf("LeafHash(address, 0xbad) error %v", err)
+				}
+				if Verify(root, other, proof) {
+					t.Errorf("Verify(%T.Root(), [leaf not in tree], %T.Proof(%d)) got true; want false", tree, tree, i)
+				}
+			}
+
+			if _, err := tree.Proof(n); err == nil {
+				t.Errorf("%T.Proof(%d) [out of range] got nil error; want non-nil", tree, n)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding []string
+		values   [][]interface{}
+	}{
+		{
+			name:     "no values",
+			encoding: []string{"address"},
+		},
+		{
+			name:     "invalid type",
+			encoding: []string{"nope"},
+			values:   [][]interface{}{{"x"}},
+		},
+		{
+			name:     "type mismatch",
+			encoding: []string{"address"},
+			values:   [][]interface{}{{big.NewInt(1)}},
+		},
+		{
+			name:     "wrong number of values",
+			encoding: []string{"address", "uint256"},
+			values:   [][]interface{}{{common.Address{}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.encoding, tt.values); err == nil {
+				t.Errorf("New(%q, %v) got nil error; want non-nil", tt.encoding, tt.values)
+			}
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	a := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	b := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	tree, err := NewAddressAmounts([]AddressAmount{
+		{Address: a, Amount: big.NewInt(5)},
+		{Address: b, Amount: big.NewInt(25)},
+	})
+	if err != nil {
+		t.Fatalf("NewAddressAmounts(…) error %v", err)
+	}
+
+	buf, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("json.Marshal(%T) error %v", tree, err)
+	}
+	var got dump
+	if err := json.Unmarshal(buf, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s, %T) error %v", buf, &got, err)
+	}
+
+	want := dump{
+		Format: "standard-v1",
+		Tree: []string{
+			tree.Root().Hex(),
+			tree.nodes[1].Hex(),
+			tree.nodes[2].Hex(),
+		},
+		Values: []dumpValue{
+			{Value: []interface{}{a.Hex(), "5"}, TreeIndex: tree.values[0].treeIndex},
+			{Value: []interface{}{b.Hex(), "25"}, TreeIndex: tree.values[1].treeIndex},
+		},
+		LeafEncoding: []string{"address", "uint256"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("json.Marshal(%T) diff (-want +got):\n%s", tree, diff)
+	}
+
+	for i := range want.Values {
+		if idx := want.Values[i].TreeIndex; tree.nodes[idx] != tree.Leaf(i) {
+			t.Errorf("dumped value %d has treeIndex %d, which isn't its leaf", i, idx)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package otelonce lazily creates OpenTelemetry metric instruments that are
+// shared by all users of a package.
+package otelonce
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/cxkoda/solgo/go/eth/ethlog"
+)
+
+var logger = ethlog.Default()
+
+// Instruments returns a function that, on its first call, creates instruments
+// with the named Meter from the global MeterProvider, and thereafter returns
+// the same instruments. Instruments created before a provider is installed are
+// forwarded to it once it is.
+//
+// Errors returned by create are logged, not propagated, because they only
+// result from invalid names or options, and the instruments remain usable.
+func Instruments[T any](meterName string, create func(metric.Meter) (T, error)) func() T {
+	var (
+		once sync.Once
+		inst T
+	)
+	return func() T {
+		once.Do(func() {
+			var err error
+			inst, err = create(otel.Meter(meterName))
+			if err != nil {
+				logger.Error("Creating OpenTelemetry instruments", ethlog.String("meter", meterName), ethlog.Err(err))
+			}
+		})
+		return inst
+	}
+}
@@ -0,0 +1,30 @@
+package otelonce
+
+import (
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+func TestInstruments(t *testing.T) {
+	var calls int
+	get := Instruments("test", func(m metric.Meter) (metric.Int64Counter, error) {
+		calls++
+		c, err := m.Int64Counter("test.calls")
+		return c, errors.Join(err, errors.New("logged, not returned"))
+	})
+
+	first := get()
+	if first == nil {
+		t.Fatal("Instruments()() got nil instrument despite error from create")
+	}
+	for i := 0; i < 3; i++ {
+		if got := get(); got != first {
+			t.Errorf("Instruments()() call %d got different instrument to first call", i+2)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Instruments()() called create %d times; want 1", calls)
+	}
+}
@@ -0,0 +1,87 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FetchAll fetches all of the secrets concurrently, returning their payloads
+// keyed by the same names as the input. Names are arbitrary and only used for
+// identification, typically being the flags from which the Secrets were parsed.
+//
+// It is intended for use at startup so that a binary fails immediately,
+// reporting every missing or misconfigured Secret, instead of one at a time
+// when each is first used. If any Secret can't be fetched, the returned error
+// wraps an errors.Join() of the individual errors, ordered by name, and no
+// payloads are returned. As with Secret.Fetch(), Options that aren't relevant
+// to a particular Source are ignored.
+func FetchAll(ctx context.Context, secrets map[string]*Secret, opts ...Option) (map[string][]byte, error) {
+	return fetchAll(ctx, secrets, true, opts...)
+}
+
+// ValidateAll is equivalent to FetchAll() except that payloads are discarded.
+// It is intended for smoke checks, e.g. in CI, confirming that all Secrets are
+// well formed and accessible with the available credentials.
+func ValidateAll(ctx context.Context, secrets map[string]*Secret, opts ...Option) error {
+	_, err := fetchAll(ctx, secrets, false, opts...)
+	return err
+}
+
+// fetchAll implements FetchAll() and ValidateAll(), only retaining payloads if
+// keep is true.
+func fetchAll(ctx context.Context, secrets map[string]*Secret, keep bool, opts ...Option) (map[string][]byte, error) {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		vals = make(map[string][]byte)
+		errs = make(map[string]error)
+	)
+	for name, s := range secrets {
+		if s == nil || s.Source == "" {
+			mu.Lock()
+			errs[name] = status.Errorf(codes.InvalidArgument, "%s: secret not configured (%s)", name, s)
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, s *Secret) {
+			defer wg.Done()
+
+			val, err := s.Fetch(ctx, opts...)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[name] = status.Errorf(status.Code(err), "%s (%s): %v", name, s, err)
+				return
+			}
+			if keep {
+				vals[name] = val
+			}
+		}(name, s)
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return vals, nil
+	}
+
+	names := make([]string, 0, len(errs))
+	for n := range errs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	all := make([]error, len(names))
+	for i, n := range names {
+		all[i] = errs[n]
+	}
+	return nil, fmt.Errorf("%d of %d secrets unavailable: %w", len(errs), len(secrets), errors.Join(all...))
+}
@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFetchAll(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		setEnvVar   = "secrets-test-fetchall-set"
+		unsetEnvVar = "secrets-test-fetchall-unset"
+		envVarVal   = "fetched-together"
+	)
+	if err := os.Setenv(setEnvVar, envVarVal); err != nil {
+		t.Fatalf("os.Setenv(%q, %q) error %v", setEnvVar, envVarVal, err)
+	}
+	if err := os.Unsetenv(unsetEnvVar); err != nil {
+		t.Fatalf("os.Unsetenv(%q) error %v", unsetEnvVar, err)
+	}
+
+	available := map[string]*Secret{
+		"raw":      {Source: Raw, ID: "hello"},
+		"env":      {Source: Environment, ID: setEnvVar},
+		"template": {Source: Template, ID: "${" + Environment.flagValue(setEnvVar) + "}!"},
+	}
+
+	t.Run("all available", func(t *testing.T) {
+		got, err := FetchAll(ctx, available)
+		if err != nil {
+			t.Fatalf("FetchAll(…) error %v", err)
+		}
+		want := map[string][]byte{
+			"raw":      []byte("hello"),
+			"env":      []byte(envVarVal),
+			"template": []byte(envVarVal + "!"),
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("FetchAll(…) diff (-want +got):\n%s", diff)
+		}
+
+		if err := ValidateAll(ctx, available); err != nil {
+			t.Errorf("ValidateAll(…) error %v", err)
+		}
+	})
+
+	t.Run("errors aggregated", func(t *testing.T) {
+		secrets := map[string]*Secret{
+			"z-unset":     {Source: Environment, ID: unsetEnvVar},
+			"a-nil":       nil,
+			"m-zero":      {},
+			"b-template":  {Source: Template, ID: "${" + Environment.flagValue(unsetEnvVar) + "}"},
+			"unsupported": {Source: Source("foo"), ID: "bar"},
+		}
+		for n, s := range available {
+			secrets[n] = s
+		}
+
+		for _, fn := range []struct {
+			name string
+			call func() error
+		}{
+			{
+				name: "FetchAll",
+				call: func() error {
+					got, err := FetchAll(ctx, secrets)
+					if got != nil {
+						t.Errorf("FetchAll(…) with errors got non-nil payloads %q", got)
+					}
+					return err
+				},
+			},
+			{
+				name: "ValidateAll",
+				call: func() error { return ValidateAll(ctx, secrets) },
+			},
+		} {
+			t.Run(fn.name, func(t *testing.T) {
+				err := fn.call()
+				if err == nil {
+					t.Fatalf("%s(…) got nil error; want aggregated errors", fn.name)
+				}
+
+				var joined interface{ Unwrap() []error }
+				if !errors.As(err, &joined) {
+					t.Fatalf("%s(…) error %v does not wrap joined errors", fn.name, err)
+				}
+				var got []string
+				codesByName := make(map[string]codes.Code)
+				for _, e := range joined.Unwrap() {
+					name, _, _ := strings.Cut(status.Convert(e).Message(), " ")
+					name = strings.TrimSuffix(name, ":")
+					got = append(got, name)
+					codesByName[name] = status.Code(e)
+				}
+
+				wantNames := []string{"a-nil", "b-template", "m-zero", "unsupported", "z-unset"}
+				if diff := cmp.Diff(wantNames, got); diff != "" {
+					t.Errorf("%s(…) names in joined errors diff (-want +got):\n%s", fn.name, diff)
+				}
+				wantCodes := map[string]codes.Code{
+					"a-nil":       codes.InvalidArgument,
+					"b-template":  codes.NotFound,
+					"m-zero":      codes.InvalidArgument,
+					"unsupported": codes.Unimplemented,
+					"z-unset":     codes.NotFound,
+				}
+				if diff := cmp.Diff(wantCodes, codesByName); diff != "" {
+					t.Errorf("%s(…) status codes of joined errors diff (-want +got):\n%s", fn.name, diff)
+				}
+			})
+		}
+	})
+}
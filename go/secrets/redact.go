@@ -0,0 +1,189 @@
+package secrets
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secret values in the output of Redact() and
+// RedactingWriters.
+const Redacted = "[REDACTED]"
+
+// MinRedactLen is the minimum length of a value for it to be redacted. Shorter
+// values would result in excessive false positives and aren't secret in any
+// meaningful sense.
+const MinRedactLen = 6
+
+// A redactor holds secret values, and a Replacer to scrub them, which is only
+// rebuilt when the set of values changes.
+type redactor struct {
+	mu       sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+// globalRedactor is populated automatically by Secret.Fetch().
+var globalRedactor redactor
+
+// RegisterRedaction adds the value to the set of secrets scrubbed by Redact()
+// and RedactingWriters. Values fetched by Secret.Fetch(), other than from the
+// Raw Source, are registered automatically so this is only required for
+// secrets obtained by other means. Values shorter than MinRedactLen are
+// ignored.
+func RegisterRedaction(val string) {
+	globalRedactor.register(val)
+}
+
+func (r *redactor) register(val string) {
+	if len(val) < MinRedactLen {
+		return
+	}
+	// URLs with embedded keys are the most likely way for secrets to leak into
+	// logs, and they MAY be escaped.
+	vals := []string{val, url.QueryEscape(val), url.PathEscape(val)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.values == nil {
+		r.values = make(map[string]bool)
+	}
+	for _, v := range vals {
+		if !r.values[v] {
+			r.values[v] = true
+			r.replacer = nil
+		}
+	}
+}
+
+// Redact returns s with all registered secret values replaced by Redacted.
+func Redact(s string) string {
+	return globalRedactor.redact(s)
+}
+
+func (r *redactor) redact(s string) string {
+	rep := r.getReplacer()
+	if rep == nil {
+		return s
+	}
+	return rep.Replace(s)
+}
+
+// getReplacer returns a Replacer for all registered values, or nil if there
+// are none.
+func (r *redactor) getReplacer() *strings.Replacer {
+	r.mu.RLock()
+	rep, n := r.replacer, len(r.values)
+	r.mu.RUnlock()
+	if rep != nil || n == 0 {
+		return rep
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.replacer != nil {
+		return r.replacer
+	}
+	vals := make([]string, 0, len(r.values))
+	for v := range r.values {
+		vals = append(vals, v)
+	}
+	// The Replacer tries old strings in argument order so longer values MUST
+	// come first to avoid partial redaction of those that overlap.
+	sort.Slice(vals, func(i, j int) bool {
+		if len(vals[i]) != len(vals[j]) {
+			return len(vals[i]) > len(vals[j])
+		}
+		return vals[i] < vals[j]
+	})
+	oldnew := make([]string, 0, 2*len(vals))
+	for _, v := range vals {
+		oldnew = append(oldnew, v, Redacted)
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+	return r.replacer
+}
+
+// A RedactingWriter scrubs all registered secret values from data before
+// writing it to the underlying io.Writer. Each call to Write() is redacted
+// independently, so a secret split across calls will not be scrubbed; this is
+// not a concern for loggers, which write entire lines.
+type RedactingWriter struct {
+	w io.Writer
+}
+
+var _ io.Writer = (*RedactingWriter)(nil)
+
+// NewRedactingWriter returns a RedactingWriter that writes to w.
+func NewRedactingWriter(w io.Writer) *RedactingWriter {
+	return &RedactingWriter{w: w}
+}
+
+// Write writes the redacted form of p to the underlying io.Writer. On success,
+// it returns len(p), regardless of the number of bytes actually written, as
+// required by the io.Writer contract.
+func (w *RedactingWriter) Write(p []byte) (int, error) {
+	red := globalRedactor.redact(string(p))
+	if _, err := io.WriteString(w.w, red); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// RedactStderr replaces os.Stderr with a pipe from which each line is redacted
+// before being written to the original. It is intended to be called early in
+// main(), before any secrets are fetched, as glog writes to whichever file is
+// os.Stderr at the time of logging; it therefore scrubs glog output when using
+// -logtostderr or -alsologtostderr, but NOT log files.
+//
+// The returned function restores os.Stderr and MUST be called, typically with
+// defer, to flush any partial final line.
+func RedactStderr() (func() error, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("os.Pipe(): %v", err)
+	}
+	orig := os.Stderr
+	os.Stderr = w
+
+	done := make(chan error, 1)
+	go func() {
+		err := redactLines(orig, r)
+		// Logging MUST NOT block, even if the original is no longer
+		// writable.
+		io.Copy(io.Discard, r)
+		done <- err
+	}()
+
+	return func() error {
+		os.Stderr = orig
+		errs := []error{w.Close(), <-done, r.Close()}
+		return errors.Join(errs...)
+	}, nil
+}
+
+// redactLines copies r to w, redacting each line.
+func redactLines(w io.Writer, r io.Reader) error {
+	rw := NewRedactingWriter(w)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, err := rw.Write(line); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
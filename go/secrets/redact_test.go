@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	r := new(redactor)
+	const in = "https://rpc.example/v3/abcdefgh?key=k%2By%2Fz%3D12 short 12345 abcdefghij"
+
+	if got := r.redact(in); got != in {
+		t.Errorf("%T.redact(%q) with nothing registered got %q; want unchanged", r, in, got)
+	}
+
+	for _, v := range []string{"abcdefgh", "abcdefghij", "k+y/z=12", "12345"} {
+		r.register(v)
+	}
+	want := "https://rpc.example/v3/[REDACTED]?key=[REDACTED] short 12345 [REDACTED]"
+	if got := r.redact(in); got != want {
+		t.Errorf("%T.redact(%q) got %q; want %q", r, in, got, want)
+	}
+}
+
+func TestFetchRegistersRedaction(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		envVar = "secrets-test-redaction-env-var"
+		val    = "s3cr3t-api-key"
+	)
+	if err := os.Setenv(envVar, val); err != nil {
+		t.Fatalf("os.Setenv(%q, %q) error %v", envVar, val, err)
+	}
+	const raw = "not-secret-raw-value"
+
+	for _, s := range []*Secret{
+		{Source: Environment, ID: envVar},
+		{Source: Raw, ID: raw},
+	} {
+		if _, err := s.Fetch(ctx); err != nil {
+			t.Fatalf("%T(%v).Fetch() error %v", s, s, err)
+		}
+	}
+
+	in := fmt.Sprintf("url=https://x.example/%s raw=%s", val, raw)
+	want := fmt.Sprintf("url=https://x.example/%s raw=%s", Redacted, raw)
+	if got := Redact(in); got != want {
+		t.Errorf("Redact(%q) after Fetch() got %q; want %q", in, got, want)
+	}
+
+	var buf bytes.Buffer
+	if _, err := fmt.Fprint(NewRedactingWriter(&buf), in); err != nil {
+		t.Fatalf("fmt.Fprint(%T, …) error %v", &RedactingWriter{}, err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("%T wrote %q; want %q", &RedactingWriter{}, got, want)
+	}
+
+	t.Run("RedactStderr", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "stderr")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("os.Create(%q) error %v", path, err)
+		}
+		defer f.Close()
+
+		orig := os.Stderr
+		os.Stderr = f
+		defer func() { os.Stderr = orig }()
+
+		restore, err := RedactStderr()
+		if err != nil {
+			t.Fatalf("RedactStderr() error %v", err)
+		}
+		fmt.Fprintln(os.Stderr, in)
+		fmt.Fprint(os.Stderr, "partial ", val)
+		if err := restore(); err != nil {
+			t.Fatalf("RedactStderr() restore error %v", err)
+		}
+		if os.Stderr != f {
+			t.Errorf("os.Stderr not restored by RedactStderr()")
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("os.ReadFile(%q) error %v", path, err)
+		}
+		if want := want + "\npartial " + Redacted; string(got) != want {
+			t.Errorf("Redacted stderr got %q; want %q", got, want)
+		}
+	})
+}
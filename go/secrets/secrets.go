@@ -25,6 +25,11 @@ const (
 	GCP Source = "gcp"
 	// The Environment Source fetches secrets from an environment variable.
 	Environment Source = "env"
+	// The Template Source expands ${…} placeholders in its ID with the values
+	// of the referenced secrets, which MAY themselves be Templates; e.g.
+	// template://https://mainnet.infura.io/v3/${env://INFURA_KEY}. Use $$ for a
+	// literal $.
+	Template Source = "template"
 )
 
 // A Secret identifies a secret but doesn't carry the value itself.
@@ -53,6 +58,10 @@ func (s *Secret) Set(raw string) error {
 	s.Source = Source(parts[0])
 	switch s.Source {
 	case Raw, GCP, Environment:
+	case Template:
+		if _, err := parseTemplate(parts[1]); err != nil {
+			return err
+		}
 	default:
 		return status.Errorf(codes.InvalidArgument, "invalid %T %q from %q", s.Source, s.Source, raw)
 	}
@@ -75,7 +84,22 @@ type Option interface {
 // Fetch fetches and returns the Secret's payload. It ignores all Options that
 // aren't relevant to s.Source; for example, passing a GCPOption with an
 // environment variable is allowed.
+//
+// Payloads from all Sources other than Raw are registered for redaction; see
+// RegisterRedaction().
 func (s *Secret) Fetch(ctx context.Context, opts ...Option) ([]byte, error) {
+	val, err := s.fetch(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if s.Source != Raw {
+		RegisterRedaction(string(val))
+	}
+	return val, nil
+}
+
+// fetch implements Fetch(), without registering the payload for redaction.
+func (s *Secret) fetch(ctx context.Context, opts ...Option) ([]byte, error) {
 	switch s.Source {
 	case GCP:
 		return gcp(ctx, s.ID, filterOptions[gcpOption](opts)...)
@@ -90,6 +114,9 @@ func (s *Secret) Fetch(ctx context.Context, opts ...Option) ([]byte, error) {
 		}
 		return []byte(val), nil
 
+	case Template:
+		return template(ctx, s.ID, opts...)
+
 	default:
 		return nil, status.Errorf(codes.Unimplemented, "unsupported secret source %q", s.Source)
 	}
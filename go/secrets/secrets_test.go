@@ -82,6 +82,42 @@ func TestSecretEndToEnd(t *testing.T) {
 			fetchOpts:      []Option{gcpStubOption(t)},
 			errDiffAgainst: codes.NotFound,
 		},
+		{
+			name:      "template with env variable",
+			flagValue: Template.flagValue("https://mainnet.infura.io/v3/${" + Environment.flagValue(setEnvVar) + "}"),
+			want:      []byte("https://mainnet.infura.io/v3/" + envVarVal),
+		},
+		{
+			name:      "template with multiple and nested references",
+			flagValue: Template.flagValue("${not-secret://a}-${template://b${not-secret://c}d}-${gcp://" + gcpSecretName + "}"),
+			fetchOpts: []Option{gcpStubOption(t)},
+			want:      []byte("a-bcd-" + gcpSecretValue),
+		},
+		{
+			name:      "template with escaped $",
+			flagValue: Template.flagValue("$${env://NOT_A_REFERENCE}$$"),
+			want:      []byte("${env://NOT_A_REFERENCE}$"),
+		},
+		{
+			name:      "template without references",
+			flagValue: Template.flagValue("hello"),
+			want:      []byte("hello"),
+		},
+		{
+			name:               "template with unterminated reference",
+			flagValue:          Template.flagValue("https://${env://KEY"),
+			flagErrDiffAgainst: codes.InvalidArgument,
+		},
+		{
+			name:               "template with invalid reference",
+			flagValue:          Template.flagValue("https://${foo://KEY}"),
+			flagErrDiffAgainst: codes.InvalidArgument,
+		},
+		{
+			name:           "template with unset env variable",
+			flagValue:      Template.flagValue("https://${" + Environment.flagValue(unsetEnvVar) + "}"),
+			errDiffAgainst: codes.NotFound,
+		},
 	}
 
 	for _, tt := range tests {
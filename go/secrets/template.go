@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A templatePart is either a literal string or a reference to another Secret,
+// but never both.
+type templatePart struct {
+	literal string
+	ref     *Secret
+}
+
+// parseTemplate splits a Template Secret's ID into literal strings and the
+// Secrets referenced by ${…} placeholders. References MAY themselves be
+// Templates, in which case their braces MUST be balanced. The literal "$$" is
+// an escaped "$".
+func parseTemplate(tmpl string) ([]templatePart, error) {
+	var (
+		parts []templatePart
+		lit   strings.Builder
+	)
+	for i := 0; i < len(tmpl); {
+		switch {
+		case strings.HasPrefix(tmpl[i:], "$$"):
+			lit.WriteByte('$')
+			i += 2
+			continue
+		case !strings.HasPrefix(tmpl[i:], "${"):
+			lit.WriteByte(tmpl[i])
+			i++
+			continue
+		}
+
+		start := i + 2
+		end, depth := start, 1
+		for ; end < len(tmpl) && depth > 0; end++ {
+			switch tmpl[end] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+		}
+		if depth > 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "unterminated ${ at offset %d of template %q", i, tmpl)
+		}
+
+		ref := new(Secret)
+		if err := ref.Set(tmpl[start : end-1]); err != nil {
+			return nil, status.Errorf(status.Code(err), "template %q: %v", tmpl, err)
+		}
+		if lit.Len() > 0 {
+			parts = append(parts, templatePart{literal: lit.String()})
+			lit.Reset()
+		}
+		parts = append(parts, templatePart{ref: ref})
+		i = end
+	}
+
+	if lit.Len() > 0 {
+		parts = append(parts, templatePart{literal: lit.String()})
+	}
+	return parts, nil
+}
+
+// template expands all ${…} placeholders in tmpl with the values of the
+// referenced Secrets, which are fetched with the same Options.
+func template(ctx context.Context, tmpl string, opts ...Option) ([]byte, error) {
+	parts, err := parseTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, p := range parts {
+		if p.ref == nil {
+			buf.WriteString(p.literal)
+			continue
+		}
+
+		val, err := p.ref.Fetch(ctx, opts...)
+		if err != nil {
+			return nil, status.Errorf(status.Code(err), "fetching %s referenced in template: %v", p.ref, err)
+		}
+		buf.Write(val)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package shuffle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// An EntropyRand is a Rand that deterministically derives unbiased integers
+// from 32 bytes of entropy; e.g. that provided by the EntropyOracle contract.
+// It is intended for reproducible, publicly verifiable shuffles such as token
+// reveals.
+//
+// The i-th word (from zero) is keccak256(abi.encode(entropy, i)), of which the
+// most significant 8 bytes are interpreted as a big-endian uint64, x. Each call
+// to Intn(n) consumes words until one has x < 2^64 - (2^64 mod n), i.e. by
+// rejection sampling, and returns x mod n. This is equivalent to the Solidity:
+//
+//	uint64 x = uint64(uint256(keccak256(abi.encode(entropy, i))) >> 192);
+type EntropyRand struct {
+	entropy [32]byte
+	// words is the number of words consumed so far, i.e. i of the next one.
+	words uint64
+}
+
+var _ Rand = (*EntropyRand)(nil)
+
+// NewEntropyRand returns an EntropyRand seeded with the entropy.
+func NewEntropyRand(entropy [32]byte) *EntropyRand {
+	return &EntropyRand{entropy: entropy}
+}
+
+// next returns the most significant 8 bytes of the next word.
+func (r *EntropyRand) next() uint64 {
+	var buf [64]byte
+	copy(buf[:32], r.entropy[:])
+	binary.BigEndian.PutUint64(buf[56:], r.words)
+	r.words++
+	return binary.BigEndian.Uint64(crypto.Keccak256(buf[:])[:8])
+}
+
+// Intn returns a uniformly distributed integer in [0,n). It panics if n <= 0.
+func (r *EntropyRand) Intn(n int) int {
+	if n <= 0 {
+		panic(fmt.Sprintf("%T.Intn(%d) with non-positive n", r, n))
+	}
+	un := uint64(n)
+	// 2^64 mod n, computed without overflow. Values of x in the top `rem` of
+	// the range would bias the result towards smaller values.
+	rem := (math.MaxUint64%un + 1) % un
+	for {
+		if x := r.next(); x <= math.MaxUint64-rem {
+			return int(x % un)
+		}
+	}
+}
+
+// FromEntropy returns a permutation of [0,n) from a Fisher–Yates shuffle
+// driven by an EntropyRand seeded with the entropy. The same entropy and n
+// always result in the same permutation.
+func FromEntropy(entropy [32]byte, n uint32) []int {
+	return NewFisherYates(n).PermuteUpTo(n, NewEntropyRand(entropy))
+}
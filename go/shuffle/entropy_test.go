@@ -0,0 +1,131 @@
+package shuffle
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEntropyRandMatchesSolidity(t *testing.T) {
+	bytes32, err := abi.NewType("bytes32", "", nil)
+	if err != nil {
+		t.Fatalf(`abi.NewType("bytes32") error %v`, err)
+	}
+	uint256, err := abi.NewType("uint256", "", nil)
+	if err != nil {
+		t.Fatalf(`abi.NewType("uint256") error %v`, err)
+	}
+	args := abi.Arguments{{Type: bytes32}, {Type: uint256}}
+
+	entropy := [32]byte{0xde, 0xad, 31: 0xbe}
+	r := NewEntropyRand(entropy)
+	for i := int64(0); i < 5; i++ {
+		buf, err := args.Pack(entropy, big.NewInt(i))
+		if err != nil {
+			t.Fatalf("%T.Pack(…) error %v", args, err)
+		}
+		want := binary.BigEndian.Uint64(crypto.Keccak256(buf)[:8])
+
+		if got := r.next(); got != want {
+			t.Errorf("%T.next() word %d got %#x; want uint64(uint256(keccak256(abi.encode(entropy, %d))) >> 192) = %#x", r, i, got, i, want)
+		}
+	}
+}
+
+func TestEntropyRandRejectionSampling(t *testing.T) {
+	// With n = 2^63 + 1, 2^64 mod n = 2^63 - 1 so almost half of all words
+	// are rejected.
+	const n = math.MaxInt64/2 + 2
+	const draws = 1000
+
+	r := NewEntropyRand([32]byte{42})
+	for i := 0; i < draws; i++ {
+		if got := r.Intn(n); got < 0 || got >= n {
+			t.Fatalf("%T.Intn(%d) got %d; out of range", r, n, got)
+		}
+	}
+	if r.words == draws {
+		t.Errorf("%T.Intn(%d) %d times consumed %d words; want more (i.e. some rejected)", r, n, draws, r.words)
+	}
+
+	// Conversely, powers of two never reject.
+	r = NewEntropyRand([32]byte{42})
+	for i := 0; i < draws; i++ {
+		r.Intn(1 << 10)
+	}
+	if r.words != draws {
+		t.Errorf("%T.Intn(1<<10) %d times consumed %d words; want %[2]d", r, draws, r.words)
+	}
+}
+
+func TestEntropyRandUniform(t *testing.T) {
+	const (
+		n     = 7
+		draws = 70_000
+	)
+	r := NewEntropyRand([32]byte{1, 2, 3})
+	counts := make([]int, n)
+	for i := 0; i < draws; i++ {
+		counts[r.Intn(n)]++
+	}
+
+	// Chi-squared critical value for 6 degrees of freedom at p = 0.001.
+	const critical = 22.458
+	var chi2 float64
+	for _, c := range counts {
+		d := float64(c) - draws/n
+		chi2 += d * d / (draws / n)
+	}
+	if chi2 > critical {
+		t.Errorf("%T.Intn(%d) counts %v; chi-squared %.2f > %.2f", r, n, counts, chi2, critical)
+	}
+}
+
+func TestFromEntropy(t *testing.T) {
+	tests := []struct {
+		entropy [32]byte
+		n       uint32
+		want    []int
+	}{
+		{
+			entropy: [32]byte{},
+			n:       10,
+			want:    []int{1, 0, 8, 7, 5, 9, 3, 4, 2, 6},
+		},
+		{
+			entropy: [32]byte{31: 1},
+			n:       10,
+			want:    []int{8, 4, 6, 9, 3, 2, 7, 1, 5, 0},
+		},
+		{
+			entropy: [32]byte{31: 1},
+			n:       0,
+			want:    []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		// A change in the returned values means that past reveals can no
+		// longer be reproduced.
+		if diff := cmp.Diff(tt.want, FromEntropy(tt.entropy, tt.n)); diff != "" {
+			t.Errorf("FromEntropy(%#x, %d) diff (-want +got):\n%s", tt.entropy, tt.n, diff)
+		}
+	}
+
+	t.Run("permutation", func(t *testing.T) {
+		const n = 1000
+		got := FromEntropy([32]byte{7}, n)
+		sort.Ints(got)
+		for i, v := range got {
+			if i != v {
+				t.Fatalf("sorted FromEntropy(…, %d)[%d] = %d; want %[2]d", n, i, v)
+			}
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package spawner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ory/dockertest/v3"
+
+	pb "github.com/cxkoda/solgo/go/spawner/proto"
+)
+
+const (
+	// AnvilMnemonic is the mnemonic from which anvil's pre-funded accounts are
+	// derived. It is the same as anvil's default, but is passed explicitly so
+	// as to be independent of the version.
+	AnvilMnemonic = "test test test test test test test test test test test junk"
+
+	// anvilDefaultChainID is used if an AnvilRequest doesn't specify one.
+	anvilDefaultChainID = 31337
+)
+
+func (s *spawner) anvil(ctx context.Context, sReq *pb.SpawnRequest) (*dockertest.Resource, *pb.SpawnResponse, error) {
+	req := sReq.GetAnvil()
+
+	chainID := req.ChainId
+	if chainID == 0 {
+		chainID = anvilDefaultChainID
+	}
+
+	const port = "8545"
+	args := []string{
+		"--host", "0.0.0.0",
+		"--port", port,
+		"--chain-id", strconv.FormatUint(chainID, 10),
+		"--mnemonic", AnvilMnemonic,
+	}
+	if n := req.Accounts; n > 0 {
+		args = append(args, "--accounts", strconv.FormatUint(uint64(n), 10))
+	}
+	if bt := req.BlockTime.AsDuration(); bt > 0 {
+		// anvil only accepts whole seconds.
+		args = append(args, "--block-time", strconv.FormatInt(int64(math.Ceil(bt.Seconds())), 10))
+	}
+
+	opts := &dockertest.RunOptions{
+		Repository:   "ghcr.io/foundry-rs/foundry",
+		Tag:          req.DockerTag,
+		Entrypoint:   []string{"anvil"},
+		Cmd:          args,
+		ExposedPorts: []string{port + "/tcp"},
+	}
+	res, err := s.run(opts, req.Ttl.AsDuration())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostAndPort, host, hostPortNum, err := hostPort(res, port+"/tcp")
+	if err != nil {
+		return nil, nil, err
+	}
+	httpURL := fmt.Sprintf("http://%s", hostAndPort)
+
+	var (
+		gotChainID uint64
+		// pool.Retry doesn't propagate errors so we need to do it ourselves
+		lastConnErr error
+	)
+	tryConnect := func() (retErr error) {
+		defer func() { lastConnErr = retErr }()
+
+		client, err := ethclient.DialContext(ctx, httpURL)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		id, err := client.ChainID(ctx)
+		if err != nil {
+			return err
+		}
+		gotChainID = id.Uint64()
+		return nil
+	}
+	if err := s.pool.Retry(tryConnect); err != nil {
+		return nil, nil, fmt.Errorf("%T.Retry(ethclient.Dial(%q)) error %v; last retry error: %v", s.pool, httpURL, err, lastConnErr)
+	}
+	if gotChainID != chainID {
+		return nil, nil, fmt.Errorf("anvil node reports chain ID %d; requested %d", gotChainID, chainID)
+	}
+
+	return res, &pb.SpawnResponse{
+		Process: &pb.SpawnResponse_Anvil{Anvil: &pb.AnvilResponse{
+			HttpUrl:     httpURL,
+			WsUrl:       fmt.Sprintf("ws://%s", hostAndPort),
+			HostAndPort: hostAndPort,
+			Host:        host,
+			Port:        int64(hostPortNum),
+			ChainId:     chainID,
+			Mnemonic:    AnvilMnemonic,
+		}},
+	}, nil
+}
@@ -4,14 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"net"
 	"net/url"
 	"os"
-	"strconv"
-	"time"
 
 	"github.com/ory/dockertest/v3"
-	"github.com/ory/dockertest/v3/docker"
 
 	pb "github.com/cxkoda/solgo/go/spawner/proto"
 
@@ -45,26 +41,21 @@ func (s *spawner) postgres(ctx context.Context, sReq *pb.SpawnRequest) (*dockert
 			"listen_addresses = '*'",
 		},
 	}
-	res, err := s.pool.RunWithOptions(
-		opts,
-		func(hostCfg *docker.HostConfig) {
-			hostCfg.AutoRemove = true
-			hostCfg.RestartPolicy = docker.NeverRestart()
-		},
-	)
+	res, err := s.run(opts, req.Ttl.AsDuration())
 	if err != nil {
-		return nil, nil, fmt.Errorf("%T.RunWithOptions(%+v) error %v", s.pool, opts, err)
+		return nil, nil, err
 	}
-	ttl := req.Ttl.AsDuration()
-	res.Expire(uint(ttl / time.Second))
 
 	var (
 		db *sql.DB
 		// pool.Retry doesn't propagate errors so we need to do it ourselves
 		lastConnErr error
 	)
-	hostPort := res.GetHostPort("5432/tcp")
-	dsn := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", user, password, hostPort, url.PathEscape(req.DbName))
+	hostAndPort, host, port, err := hostPort(res, "5432/tcp")
+	if err != nil {
+		return nil, nil, err
+	}
+	dsn := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", user, password, hostAndPort, url.PathEscape(req.DbName))
 	tryConnect := func() (retErr error) {
 		defer func() { lastConnErr = retErr }()
 
@@ -82,21 +73,12 @@ func (s *spawner) postgres(ctx context.Context, sReq *pb.SpawnRequest) (*dockert
 		return nil, nil, fmt.Errorf("%T.Close(): %v", db, err)
 	}
 
-	host, portStr, err := net.SplitHostPort(hostPort)
-	if err != nil {
-		return nil, nil, fmt.Errorf("net.SplitHostPort(%q): %v", hostPort, err)
-	}
-	port, err := strconv.Atoi(portStr)
-	if err != nil {
-		return nil, nil, fmt.Errorf("parsing port from %q: strconv.Atoi(%q): %v", hostPort, portStr, err)
-	}
-
 	return res, &pb.SpawnResponse{
 		Process: &pb.SpawnResponse_Postgres{Postgres: &pb.PostgresResponse{
 			Dsn:         dsn,
 			User:        user,
 			Password:    password,
-			HostAndPort: hostPort,
+			HostAndPort: hostAndPort,
 			Host:        host,
 			Port:        int64(port),
 			DbName:      req.DbName,
@@ -0,0 +1,79 @@
+package spawner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/ory/dockertest/v3"
+
+	pb "github.com/cxkoda/solgo/go/spawner/proto"
+)
+
+func (s *spawner) redis(ctx context.Context, sReq *pb.SpawnRequest) (*dockertest.Resource, *pb.SpawnResponse, error) {
+	req := sReq.GetRedis()
+
+	opts := &dockertest.RunOptions{
+		Repository: "redis",
+		Tag:        req.DockerTag,
+	}
+	res, err := s.run(opts, req.Ttl.AsDuration())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostAndPort, host, port, err := hostPort(res, "6379/tcp")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// pool.Retry doesn't propagate errors so we need to do it ourselves
+	var lastPingErr error
+	tryPing := func() (retErr error) {
+		defer func() { lastPingErr = retErr }()
+		return redisPing(ctx, hostAndPort)
+	}
+	if err := s.pool.Retry(tryPing); err != nil {
+		return nil, nil, fmt.Errorf("%T.Retry(PING %q) error %v; last retry error: %v", s.pool, hostAndPort, err, lastPingErr)
+	}
+
+	return res, &pb.SpawnResponse{
+		Process: &pb.SpawnResponse_Redis{Redis: &pb.RedisResponse{
+			Url:         fmt.Sprintf("redis://%s", hostAndPort),
+			HostAndPort: hostAndPort,
+			Host:        host,
+			Port:        int64(port),
+		}},
+	}, nil
+}
+
+// redisPing sends a PING command, in RESP inline format, to the Redis server at
+// addr and returns an error if the reply isn't PONG. This avoids depending on a
+// full Redis client library just for a readiness check.
+func redisPing(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return fmt.Errorf("writing PING: %v", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("reading PING reply: %v", err)
+	}
+	if want := "+PONG\r\n"; reply != want {
+		return fmt.Errorf("PING reply %q; want %q", reply, want)
+	}
+	return nil
+}
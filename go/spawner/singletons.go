@@ -14,22 +14,14 @@ import (
 // Convenience functions for spawning one-off "processes" without an independent
 // SpawnerService.
 
-// NewPostgres spawns a new PostgreSQL instance. The returned cleanup function
-// MUST be called to kill the instance.
-func NewPostgres(ctx context.Context, dockerTag string, ttl time.Duration) (*pb.PostgresResponse, func(context.Context) error, error) {
+// spawn spawns the requested "process" with a new SpawnerService. The returned
+// cleanup function MUST be called to kill the process.
+func spawn(ctx context.Context, req *pb.SpawnRequest) (*pb.SpawnResponse, func(context.Context) error, error) {
 	s, err := New(30 * time.Second)
 	if err != nil {
 		return nil, nil, fmt.Errorf("spawner.New(): %v", err)
 	}
 
-	req := &pb.SpawnRequest{
-		Process: &pb.SpawnRequest_Postgres{
-			Postgres: &pb.PostgresRequest{
-				DockerTag: dockerTag,
-				Ttl:       durationpb.New(ttl),
-			},
-		},
-	}
 	resp, err := s.Spawn(ctx, req)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%T.Spawn(%T %+v) error %v", s, req, req, err)
@@ -38,24 +30,111 @@ func NewPostgres(ctx context.Context, dockerTag string, ttl time.Duration) (*pb.
 		_, err := s.Kill(ctx, resp.ToKill)
 		return err
 	}
-	return resp.GetPostgres(), cleanup, nil
+	return resp, cleanup, nil
 }
 
-// NewPostgresT is equivalent to NewPostGres() except that failures to spawn are
-// reported on tb.Fatal(), and Kill() is called in tb.Cleanup().
-func NewPostgresT(ctx context.Context, tb testing.TB, dockerTag string, ttl time.Duration) *pb.PostgresResponse {
+// spawnT is equivalent to spawn() except that failures to spawn are reported
+// on tb.Fatal(), and the cleanup function is called in tb.Cleanup(). The name
+// of the calling function is used in error messages.
+func spawnT(ctx context.Context, tb testing.TB, caller string, req *pb.SpawnRequest) *pb.SpawnResponse {
 	tb.Helper()
 
-	resp, cleanup, err := NewPostgres(ctx, dockerTag, ttl)
+	resp, cleanup, err := spawn(ctx, req)
 	if err != nil {
 		tb.Fatal(err)
 	}
 
 	tb.Cleanup(func() {
 		if err := cleanup(ctx); err != nil {
-			tb.Errorf("cleanup() as returned by NewPostgres(); error %v", err)
+			tb.Errorf("cleanup() as returned by %s(); error %v", caller, err)
 		}
 	})
 
 	return resp
 }
+
+// NewPostgres spawns a new PostgreSQL instance. The returned cleanup function
+// MUST be called to kill the instance.
+func NewPostgres(ctx context.Context, dockerTag string, ttl time.Duration) (*pb.PostgresResponse, func(context.Context) error, error) {
+	resp, cleanup, err := spawn(ctx, postgresRequest(dockerTag, ttl))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.GetPostgres(), cleanup, nil
+}
+
+// NewPostgresT is equivalent to NewPostGres() except that failures to spawn are
+// reported on tb.Fatal(), and Kill() is called in tb.Cleanup().
+func NewPostgresT(ctx context.Context, tb testing.TB, dockerTag string, ttl time.Duration) *pb.PostgresResponse {
+	tb.Helper()
+	return spawnT(ctx, tb, "NewPostgres", postgresRequest(dockerTag, ttl)).GetPostgres()
+}
+
+func postgresRequest(dockerTag string, ttl time.Duration) *pb.SpawnRequest {
+	return &pb.SpawnRequest{
+		Process: &pb.SpawnRequest_Postgres{
+			Postgres: &pb.PostgresRequest{
+				DockerTag: dockerTag,
+				Ttl:       durationpb.New(ttl),
+			},
+		},
+	}
+}
+
+// NewRedis spawns a new Redis instance. The returned cleanup function MUST be
+// called to kill the instance.
+func NewRedis(ctx context.Context, dockerTag string, ttl time.Duration) (*pb.RedisResponse, func(context.Context) error, error) {
+	resp, cleanup, err := spawn(ctx, redisRequest(dockerTag, ttl))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.GetRedis(), cleanup, nil
+}
+
+// NewRedisT is equivalent to NewRedis() except that failures to spawn are
+// reported on tb.Fatal(), and Kill() is called in tb.Cleanup().
+func NewRedisT(ctx context.Context, tb testing.TB, dockerTag string, ttl time.Duration) *pb.RedisResponse {
+	tb.Helper()
+	return spawnT(ctx, tb, "NewRedis", redisRequest(dockerTag, ttl)).GetRedis()
+}
+
+func redisRequest(dockerTag string, ttl time.Duration) *pb.SpawnRequest {
+	return &pb.SpawnRequest{
+		Process: &pb.SpawnRequest_Redis{
+			Redis: &pb.RedisRequest{
+				DockerTag: dockerTag,
+				Ttl:       durationpb.New(ttl),
+			},
+		},
+	}
+}
+
+// NewAnvil spawns a new foundry anvil node with default chain ID and accounts,
+// mining a block for every transaction. The returned cleanup function MUST be
+// called to kill the node. Use a SpawnerService directly for other
+// configurations.
+func NewAnvil(ctx context.Context, dockerTag string, ttl time.Duration) (*pb.AnvilResponse, func(context.Context) error, error) {
+	resp, cleanup, err := spawn(ctx, anvilRequest(dockerTag, ttl))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.GetAnvil(), cleanup, nil
+}
+
+// NewAnvilT is equivalent to NewAnvil() except that failures to spawn are
+// reported on tb.Fatal(), and Kill() is called in tb.Cleanup().
+func NewAnvilT(ctx context.Context, tb testing.TB, dockerTag string, ttl time.Duration) *pb.AnvilResponse {
+	tb.Helper()
+	return spawnT(ctx, tb, "NewAnvil", anvilRequest(dockerTag, ttl)).GetAnvil()
+}
+
+func anvilRequest(dockerTag string, ttl time.Duration) *pb.SpawnRequest {
+	return &pb.SpawnRequest{
+		Process: &pb.SpawnRequest_Anvil{
+			Anvil: &pb.AnvilRequest{
+				DockerTag: dockerTag,
+				Ttl:       durationpb.New(ttl),
+			},
+		},
+	}
+}
@@ -5,6 +5,8 @@ package spawner
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -12,6 +14,7 @@ import (
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/google/uuid"
 	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -55,12 +58,48 @@ type spawner struct {
 
 type launcher func(context.Context, *pb.SpawnRequest) (*dockertest.Resource, *pb.SpawnResponse, error)
 
+// run runs the Docker image described by opts, to be automatically removed
+// when stopped, and expired after the ttl.
+func (s *spawner) run(opts *dockertest.RunOptions, ttl time.Duration) (*dockertest.Resource, error) {
+	res, err := s.pool.RunWithOptions(
+		opts,
+		func(hostCfg *docker.HostConfig) {
+			hostCfg.AutoRemove = true
+			hostCfg.RestartPolicy = docker.NeverRestart()
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%T.RunWithOptions(%+v) error %v", s.pool, opts, err)
+	}
+	res.Expire(uint(ttl / time.Second))
+	return res, nil
+}
+
+// hostPort returns the host:port to which the container's port id (e.g.
+// 5432/tcp) is bound, as well as its constituent parts.
+func hostPort(res *dockertest.Resource, id string) (hostAndPort, host string, port int, _ error) {
+	hostAndPort = res.GetHostPort(id)
+	host, portStr, err := net.SplitHostPort(hostAndPort)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("net.SplitHostPort(%q): %v", hostAndPort, err)
+	}
+	port, err = strconv.Atoi(portStr)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("parsing port from %q: strconv.Atoi(%q): %v", hostAndPort, portStr, err)
+	}
+	return hostAndPort, host, port, nil
+}
+
 // Spawn spawns the requested process type.
 func (s *spawner) Spawn(ctx context.Context, req *pb.SpawnRequest) (_ *pb.SpawnResponse, retErr error) {
 	var launch launcher
 	switch req.Process.(type) {
 	case *pb.SpawnRequest_Postgres:
 		launch = s.postgres
+	case *pb.SpawnRequest_Redis:
+		launch = s.redis
+	case *pb.SpawnRequest_Anvil:
+		launch = s.anvil
 
 	default:
 		return nil, status.Errorf(codes.InvalidArgument, "invalid %T.process: %T", req, req.Process)
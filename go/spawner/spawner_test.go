@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/ethclient"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/durationpb"
 
@@ -104,3 +105,39 @@ func TestPostgres(t *testing.T) {
 		})
 	}
 }
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+
+	resp := NewRedisT(ctx, t, "7.2", time.Minute)
+	if err := redisPing(ctx, resp.HostAndPort); err != nil {
+		t.Errorf("redisPing(ctx, [%T.HostAndPort]) error %v", resp, err)
+	}
+	if got, want := resp.Url, "redis://"+resp.HostAndPort; got != want {
+		t.Errorf("%T.Url got %q; want %q", resp, got, want)
+	}
+}
+
+func TestAnvil(t *testing.T) {
+	ctx := context.Background()
+
+	resp := NewAnvilT(ctx, t, "latest", time.Minute)
+
+	client, err := ethclient.DialContext(ctx, resp.HttpUrl)
+	if err != nil {
+		t.Fatalf("ethclient.DialContext(ctx, %q) error %v", resp.HttpUrl, err)
+	}
+	t.Cleanup(client.Close)
+
+	id, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("%T.ChainID() error %v", client, err)
+	}
+	if got, want := id.Uint64(), resp.ChainId; got != want {
+		t.Errorf("%T.ChainID() got %d; want %d as returned in %T", client, got, want, resp)
+	}
+
+	if got, want := resp.Mnemonic, AnvilMnemonic; got != want {
+		t.Errorf("%T.Mnemonic got %q; want %q", resp, got, want)
+	}
+}
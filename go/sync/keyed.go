@@ -0,0 +1,236 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A KeyedMutex provides mutual exclusion on a per-key basis. Locking one key
+// has no effect on any other key so, for example, operations on different
+// accounts can proceed concurrently while those on the same account are
+// serialised.
+//
+// The zero value for a KeyedMutex is ready to use, with all keys unlocked. A
+// KeyedMutex MUST NOT be copied after first use.
+//
+// Internal state is only retained for keys that are either locked or have
+// Lock()ers waiting, so the set of keys may be unbounded.
+type KeyedMutex[K comparable] struct {
+	mu    sync.Mutex
+	locks map[K]*keyedLock
+}
+
+// A keyedLock is a single-item semaphore, held by whoever successfully sends
+// on ch. The refs count includes the holder and all waiters, and MUST only be
+// accessed while holding the parent KeyedMutex's mu.
+type keyedLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// acquire returns the keyedLock for the key, creating it if necessary, having
+// incremented its reference count.
+func (m *KeyedMutex[K]) acquire(key K) *keyedLock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.locks == nil {
+		m.locks = make(map[K]*keyedLock)
+	}
+	l, ok := m.locks[key]
+	if !ok {
+		l = &keyedLock{ch: make(chan struct{}, 1)}
+		m.locks[key] = l
+	}
+	l.refs++
+	return l
+}
+
+// releaseWhenAlreadyLocked decrements the reference count of l, removing it
+// from m if there are no more references.
+func (m *KeyedMutex[K]) releaseWhenAlreadyLocked(key K, l *keyedLock) {
+	l.refs--
+	if l.refs == 0 {
+		delete(m.locks, key)
+	}
+}
+
+// Lock blocks until the key is locked by the caller or the Context is done,
+// returning ctx.Err() in the latter case. If ctx is already done, Lock returns
+// immediately without locking.
+func (m *KeyedMutex[K]) Lock(ctx context.Context, key K) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l := m.acquire(key)
+	select {
+	case l.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.releaseWhenAlreadyLocked(key, l)
+		return ctx.Err()
+	}
+}
+
+// TryLock tries to lock the key and reports whether it succeeded.
+func (m *KeyedMutex[K]) TryLock(key K) bool {
+	l := m.acquire(key)
+	select {
+	case l.ch <- struct{}{}:
+		return true
+	default:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.releaseWhenAlreadyLocked(key, l)
+		return false
+	}
+}
+
+// Unlock unlocks the key. As with a sync.Mutex, a locked key is not associated
+// with a particular goroutine. It is a run-time error (panic) if the key is not
+// locked on entry to Unlock.
+func (m *KeyedMutex[K]) Unlock(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.locks[key]
+	if ok {
+		select {
+		case <-l.ch:
+		default:
+			ok = false
+		}
+	}
+	if !ok {
+		panic("sync: Unlock of unlocked KeyedMutex key")
+	}
+	m.releaseWhenAlreadyLocked(key, l)
+}
+
+// A OnceMap runs a function at most once per key, successfully. Concurrent
+// calls to Do() with the same key wait for the first call to complete and then
+// share its result.
+//
+// Unlike a sync.Once, errors are not retained: if the function returns an
+// error (or panics) then the key is reset and the next call to Do() will run
+// its own function. Callers waiting on a failed call retry in this manner, so a
+// call to Do() only ever returns an error from its own function or its own
+// Context.
+//
+// The zero value for a OnceMap is ready to use. A OnceMap MUST NOT be copied
+// after first use.
+type OnceMap[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*onceEntry[V]
+}
+
+// A onceEntry carries the result of a single call to a OnceMap function. The
+// val and err fields MUST NOT be read until done is closed.
+type onceEntry[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+// entry returns the entry for the key, creating it if necessary. The returned
+// boolean is true i.f.f. the entry was created, in which case the caller MUST
+// run() it.
+func (o *OnceMap[K, V]) entry(key K) (*onceEntry[V], bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.entries == nil {
+		o.entries = make(map[K]*onceEntry[V])
+	}
+	if e, ok := o.entries[key]; ok {
+		return e, false
+	}
+	e := &onceEntry[V]{done: make(chan struct{})}
+	o.entries[key] = e
+	return e, true
+}
+
+// errOncePanicked is stored in a onceEntry if its function panicked, to signal
+// to waiters that they must retry.
+var errOncePanicked = errors.New("sync: OnceMap function panicked")
+
+// Do returns the value stored for the key if one exists, otherwise it calls fn
+// and, if it returns a nil error, stores its value. The same Context passed to
+// Do is passed to fn. See the OnceMap documentation re concurrent calls and
+// error handling.
+func (o *OnceMap[K, V]) Do(ctx context.Context, key K, fn func(context.Context) (V, error)) (V, error) {
+	for {
+		e, first := o.entry(key)
+		if first {
+			return o.run(ctx, key, e, fn)
+		}
+
+		select {
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		case <-e.done:
+			if e.err == nil {
+				return e.val, nil
+			}
+			// The entry has already been removed so the next iteration will
+			// create a new one, unless another waiter beat us to it.
+		}
+	}
+}
+
+// run calls fn, storing its result in e before closing e.done. The entry is
+// removed from o if fn returns an error or panics.
+func (o *OnceMap[K, V]) run(ctx context.Context, key K, e *onceEntry[V], fn func(context.Context) (V, error)) (V, error) {
+	returned := false
+	defer func() {
+		if !returned {
+			e.err = errOncePanicked
+		}
+		if e.err != nil {
+			o.mu.Lock()
+			if o.entries[key] == e {
+				delete(o.entries, key)
+			}
+			o.mu.Unlock()
+		}
+		close(e.done)
+	}()
+
+	e.val, e.err = fn(ctx)
+	returned = true
+	return e.val, e.err
+}
+
+// Load returns the value stored for the key, and a boolean indicating whether
+// one exists. It does not block on an in-flight call to Do().
+func (o *OnceMap[K, V]) Load(key K) (V, bool) {
+	o.mu.Lock()
+	e, ok := o.entries[key]
+	o.mu.Unlock()
+
+	if ok {
+		select {
+		case <-e.done:
+			if e.err == nil {
+				return e.val, true
+			}
+		default:
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// Forget removes any value stored for the key, such that the next call to Do()
+// will call its function. Calls to Do() that are already waiting on an
+// in-flight function still receive its result.
+func (o *OnceMap[K, V]) Forget(key K) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.entries, key)
+}
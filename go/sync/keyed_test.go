@@ -0,0 +1,229 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	ctx := context.Background()
+	m := new(KeyedMutex[string])
+
+	// Counters are deliberately non-atomic so that the race detector will flag
+	// any failure to serialise access to a single key.
+	keys := []string{"a", "b", "c"}
+	counts := make(map[string]*int)
+	for _, k := range keys {
+		counts[k] = new(int)
+	}
+
+	const perKey = 100
+	var group errgroup.Group
+	for _, k := range keys {
+		k := k
+		n := counts[k]
+		for i := 0; i < perKey; i++ {
+			group.Go(func() error {
+				if err := m.Lock(ctx, k); err != nil {
+					return err
+				}
+				defer m.Unlock(k)
+				*n++
+				return nil
+			})
+		}
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatalf("%T.Lock() error %v", m, err)
+	}
+
+	for _, k := range keys {
+		if got, want := *counts[k], perKey; got != want {
+			t.Errorf("key %q incremented %d times; want %d", k, got, want)
+		}
+	}
+	if n := len(m.locks); n != 0 {
+		t.Errorf("%T retained state for %d keys after all were unlocked", m, n)
+	}
+}
+
+func TestKeyedMutexIndependentKeys(t *testing.T) {
+	ctx := context.Background()
+	m := new(KeyedMutex[int])
+
+	if err := m.Lock(ctx, 0); err != nil {
+		t.Fatalf("%T.Lock(ctx, 0) error %v", m, err)
+	}
+	if !m.TryLock(1) {
+		t.Errorf("%T.TryLock(1) while 0 locked got false; want true", m)
+	}
+	if m.TryLock(0) {
+		t.Errorf("%T.TryLock(0) while 0 locked got true; want false", m)
+	}
+	m.Unlock(1)
+
+	t.Run("context cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if got, want := m.Lock(ctx, 0), context.DeadlineExceeded; got != want {
+			t.Errorf("%T.Lock([ctx with deadline], 0) while locked got %v; want %v", m, got, want)
+		}
+	})
+
+	t.Run("unblocks on Unlock", func(t *testing.T) {
+		locked := make(chan error)
+		go func() {
+			locked <- m.Lock(ctx, 0)
+		}()
+
+		select {
+		case err := <-locked:
+			t.Fatalf("%T.Lock(ctx, 0) returned %v before Unlock(0)", m, err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		m.Unlock(0)
+		if err := <-locked; err != nil {
+			t.Errorf("%T.Lock(ctx, 0) error %v", m, err)
+		}
+		m.Unlock(0)
+	})
+
+	if n := len(m.locks); n != 0 {
+		t.Errorf("%T retained state for %d keys after all were unlocked", m, n)
+	}
+}
+
+func TestKeyedMutexUnlockOfUnlocked(t *testing.T) {
+	m := new(KeyedMutex[int])
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%T.Unlock() of unlocked key did not panic", m)
+		}
+	}()
+	m.Unlock(42)
+}
+
+func TestOnceMap(t *testing.T) {
+	ctx := context.Background()
+	o := new(OnceMap[string, int])
+
+	var calls int64
+	fn := func(context.Context) (int, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return 42, nil
+	}
+
+	var group errgroup.Group
+	for i := 0; i < 50; i++ {
+		group.Go(func() error {
+			got, err := o.Do(ctx, "k", fn)
+			if err != nil {
+				return err
+			}
+			if got != 42 {
+				t.Errorf("%T.Do() got %d; want 42", o, got)
+			}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		t.Fatalf("%T.Do() error %v", o, err)
+	}
+	if got := atomic.LoadInt64(&calls); got != 1 {
+		t.Errorf("function called %d times; want 1", got)
+	}
+
+	if got, ok := o.Load("k"); !ok || got != 42 {
+		t.Errorf("%T.Load(k) got (%d, %t); want (42, true)", o, got, ok)
+	}
+	if _, ok := o.Load("other"); ok {
+		t.Errorf("%T.Load(other) got true; want false", o)
+	}
+
+	o.Forget("k")
+	if _, ok := o.Load("k"); ok {
+		t.Errorf("%T.Load(k) after Forget(k) got true; want false", o)
+	}
+	if _, err := o.Do(ctx, "k", fn); err != nil {
+		t.Fatalf("%T.Do() after Forget() error %v", o, err)
+	}
+	if got := atomic.LoadInt64(&calls); got != 2 {
+		t.Errorf("after Forget(); function called %d times; want 2", got)
+	}
+}
+
+func TestOnceMapErrorsNotRetained(t *testing.T) {
+	ctx := context.Background()
+	o := new(OnceMap[int, string])
+
+	errFailed := errors.New("failed")
+	var calls int64
+	fail := func(context.Context) (string, error) {
+		atomic.AddInt64(&calls, 1)
+		return "", errFailed
+	}
+
+	const n = 10
+	var group errgroup.Group
+	for i := 0; i < n; i++ {
+		group.Go(func() error {
+			if _, err := o.Do(ctx, 0, fail); err != errFailed {
+				t.Errorf("%T.Do([failing fn]) got err %v; want %v", o, err, errFailed)
+			}
+			return nil
+		})
+	}
+	group.Wait()
+
+	// Every call MUST have received the error from its own function.
+	if got := atomic.LoadInt64(&calls); got != n {
+		t.Errorf("failing function called %d times; want %d", got, n)
+	}
+
+	t.Run("panic", func(t *testing.T) {
+		func() {
+			defer func() { recover() }()
+			o.Do(ctx, 0, func(context.Context) (string, error) {
+				panic("oops")
+			})
+		}()
+		got, err := o.Do(ctx, 0, func(context.Context) (string, error) {
+			return "ok", nil
+		})
+		if err != nil || got != "ok" {
+			t.Errorf("%T.Do() after panic got (%q, %v); want (%q, nil)", o, got, err, "ok")
+		}
+	})
+}
+
+func TestOnceMapWaiterContext(t *testing.T) {
+	ctx := context.Background()
+	o := new(OnceMap[int, int])
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go o.Do(ctx, 0, func(context.Context) (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := o.Do(waitCtx, 0, nil); err != context.DeadlineExceeded {
+		t.Errorf("%T.Do([ctx with deadline]) while in flight got err %v; want %v", o, err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if got, err := o.Do(ctx, 0, nil); err != nil || got != 1 {
+		t.Errorf("%T.Do() after in-flight call completed got (%d, %v); want (1, nil)", o, got, err)
+	}
+}
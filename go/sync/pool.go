@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// A Pool is an errgroup.Group with optional progress reporting. As with a Group
+// derived via errgroup.WithContext(), the first error returned by a function
+// cancels the Context passed to all others, and is returned by Wait().
+//
+// A Pool MUST be constructed with NewPool().
+type Pool struct {
+	ctx   context.Context
+	group *errgroup.Group
+
+	mu          sync.Mutex
+	done, total uint64
+	progress    func(done, total uint64)
+}
+
+// NewPool returns a new Pool, with no limit on concurrency, that derives a
+// Context from ctx for use by all of its functions.
+func NewPool(ctx context.Context) *Pool {
+	g, ctx := errgroup.WithContext(ctx)
+	return &Pool{
+		ctx:   ctx,
+		group: g,
+	}
+}
+
+// SetLimit limits the number of concurrently running functions to at most n; a
+// negative value indicates no limit. As with errgroup.Group.SetLimit(), the
+// limit MUST NOT be modified while any functions are running.
+func (p *Pool) SetLimit(n int) {
+	p.group.SetLimit(n)
+}
+
+// OnProgress sets a function to be called after each function passed to Go()
+// returns, regardless of error. The arguments are the number of functions that
+// have returned and the number passed to Go() so far; if functions are still
+// being added then total is therefore a lower bound. Calls to fn are
+// serialised, with monotonically increasing values of done, and all are
+// complete before Wait() returns.
+func (p *Pool) OnProgress(fn func(done, total uint64)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress = fn
+}
+
+// Progress returns the values that would be passed to an OnProgress() function
+// if one were to be called now.
+func (p *Pool) Progress() (done, total uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done, p.total
+}
+
+// Go calls fn in a new goroutine, blocking until doing so would not exceed any
+// limit set with SetLimit(). The Context passed to fn is cancelled if any other
+// function returns an error, or if the Context passed to NewPool() is
+// cancelled; in either case, functions that have yet to start are not called.
+func (p *Pool) Go(fn func(context.Context) error) {
+	p.mu.Lock()
+	p.total++
+	p.mu.Unlock()
+
+	p.group.Go(func() error {
+		defer p.returned()
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+		return fn(p.ctx)
+	})
+}
+
+// returned records the return of a function passed to Go() and reports
+// progress.
+func (p *Pool) returned() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	if p.progress != nil {
+		p.progress(p.done, p.total)
+	}
+}
+
+// Wait blocks until all functions passed to Go() have returned, and then
+// returns the first non-nil error (if any) from them.
+func (p *Pool) Wait() error {
+	return p.group.Wait()
+}
@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPoolLimitAndProgress(t *testing.T) {
+	ctx := context.Background()
+
+	const (
+		n     = 50
+		limit = 4
+	)
+	p := NewPool(ctx)
+	p.SetLimit(limit)
+
+	var gotDone []uint64
+	p.OnProgress(func(done, total uint64) {
+		gotDone = append(gotDone, done) // serialised by the Pool
+		if done > total {
+			t.Errorf("OnProgress(done = %d, total = %d); done > total", done, total)
+		}
+	})
+
+	var running, maxRunning int64
+	for i := 0; i < n; i++ {
+		p.Go(func(context.Context) error {
+			now := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+
+			for {
+				max := atomic.LoadInt64(&maxRunning)
+				if now <= max || atomic.CompareAndSwapInt64(&maxRunning, max, now) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("%T.Wait() error %v", p, err)
+	}
+
+	if got := atomic.LoadInt64(&maxRunning); got > limit {
+		t.Errorf("%d functions ran concurrently; limit %d", got, limit)
+	}
+
+	var want []uint64
+	for i := uint64(1); i <= n; i++ {
+		want = append(want, i)
+	}
+	if diff := cmp.Diff(want, gotDone); diff != "" {
+		t.Errorf("OnProgress() `done` values diff (-want +got):\n%s", diff)
+	}
+
+	if done, total := p.Progress(); done != n || total != n {
+		t.Errorf("%T.Progress() got (%d, %d); want (%d, %d)", p, done, total, n, n)
+	}
+}
+
+func TestPoolCancellation(t *testing.T) {
+	ctx := context.Background()
+	p := NewPool(ctx)
+	p.SetLimit(1)
+
+	errFailed := errors.New("failed")
+	p.Go(func(context.Context) error {
+		return errFailed
+	})
+
+	var called int64
+	for i := 0; i < 10; i++ {
+		p.Go(func(ctx context.Context) error {
+			atomic.AddInt64(&called, 1)
+			return nil
+		})
+	}
+
+	if got, want := p.Wait(), errFailed; got != want {
+		t.Errorf("%T.Wait() got %v; want %v", p, got, want)
+	}
+	if got := atomic.LoadInt64(&called); got != 0 {
+		t.Errorf("%d functions called after an earlier one returned an error; want 0", got)
+	}
+	if done, total := p.Progress(); done != 11 || total != 11 {
+		t.Errorf("%T.Progress() got (%d, %d); want (11, 11)", p, done, total)
+	}
+}
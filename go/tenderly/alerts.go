@@ -0,0 +1,200 @@
+package tenderly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// An AlertExpression is a single condition of an Alert, all of which must be
+// met for the Alert to trigger. The NetworkExpression(), AddressExpression(),
+// EventEmittedExpression(), and FailedTxExpression() functions return common
+// expressions.
+type AlertExpression struct {
+	Type       string                 `json:"type"`
+	Expression map[string]interface{} `json:"expression"`
+}
+
+// NetworkExpression limits an alert to transactions on the chain.
+func NetworkExpression(chainID uint64) AlertExpression {
+	return AlertExpression{
+		Type:       "network",
+		Expression: map[string]interface{}{"network_id": strconv.FormatUint(chainID, 10)},
+	}
+}
+
+// AddressExpression limits an alert to transactions involving the address.
+func AddressExpression(addr common.Address) AlertExpression {
+	return AlertExpression{
+		Type:       "contract_address",
+		Expression: map[string]interface{}{"address": addr},
+	}
+}
+
+// EventEmittedExpression limits an alert to transactions in which the contract
+// emits the event identified by its topic (e.g. abi.Event.ID).
+func EventEmittedExpression(contract common.Address, topic common.Hash) AlertExpression {
+	return AlertExpression{
+		Type: "emitted_log",
+		Expression: map[string]interface{}{
+			"contract_address": contract,
+			"id":               topic,
+		},
+	}
+}
+
+// FailedTxExpression limits an alert to failed transactions.
+func FailedTxExpression() AlertExpression {
+	return AlertExpression{
+		Type:       "tx_status",
+		Expression: map[string]interface{}{"transaction_success": false},
+	}
+}
+
+// A DeliveryChannel is a destination for triggered alerts, such as a webhook,
+// that has been configured in the Tenderly dashboard.
+type DeliveryChannel struct {
+	ID      string `json:"id"`
+	Enabled bool   `json:"enabled"`
+}
+
+// AlertParams are the parameters to create a new Tenderly alert using
+// `Config.NewAlert`.
+type AlertParams struct {
+	// AccountSlug and ProjectSlug identify the project in which the alert is
+	// created.
+	AccountSlug string `json:"-"`
+	ProjectSlug string `json:"-"`
+
+	Name             string            `json:"name"`
+	Description      string            `json:"description"`
+	Enabled          bool              `json:"enabled"`
+	Expressions      []AlertExpression `json:"expressions"`
+	DeliveryChannels []DeliveryChannel `json:"delivery_channels"`
+}
+
+// AddressWatchAlert returns parameters for an alert triggered by any
+// transaction involving the address on the chain.
+func AddressWatchAlert(name string, chainID uint64, addr common.Address, channels ...DeliveryChannel) AlertParams {
+	return AlertParams{
+		Name:             name,
+		Enabled:          true,
+		Expressions:      []AlertExpression{NetworkExpression(chainID), AddressExpression(addr)},
+		DeliveryChannels: channels,
+	}
+}
+
+// EventEmittedAlert returns parameters for an alert triggered when the contract
+// on the chain emits the event identified by its topic.
+func EventEmittedAlert(name string, chainID uint64, contract common.Address, topic common.Hash, channels ...DeliveryChannel) AlertParams {
+	return AlertParams{
+		Name:             name,
+		Enabled:          true,
+		Expressions:      []AlertExpression{NetworkExpression(chainID), EventEmittedExpression(contract, topic)},
+		DeliveryChannels: channels,
+	}
+}
+
+// FailedTxAlert returns parameters for an alert triggered by any failed
+// transaction involving the address on the chain.
+func FailedTxAlert(name string, chainID uint64, addr common.Address, channels ...DeliveryChannel) AlertParams {
+	return AlertParams{
+		Name:             name,
+		Enabled:          true,
+		Expressions:      []AlertExpression{NetworkExpression(chainID), AddressExpression(addr), FailedTxExpression()},
+		DeliveryChannels: channels,
+	}
+}
+
+// Alert is an alert created by the Tenderly API.
+type Alert struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Enabled     bool              `json:"enabled"`
+	Expressions []AlertExpression `json:"expressions"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// NewAlertResponse is the response from the Tenderly API when a new alert is
+// created.
+type NewAlertResponse struct {
+	Alert Alert `json:"alert"`
+}
+
+// alertsPath returns the URL of the project's alert endpoints, with extra path
+// elements appended.
+func (cfg *Config) alertsPath(accountSlug, projectSlug string, elem ...string) (string, error) {
+	ps := append([]string{"/api/v1/account", accountSlug, "project", projectSlug}, elem...)
+	path, err := url.JoinPath(cfg.APIURL, ps...)
+	if err != nil {
+		return "", fmt.Errorf("url.JoinPath(%q, %v): %v", cfg.APIURL, ps, err)
+	}
+	return path, nil
+}
+
+// NewAlert creates a new alert on Tenderly.
+func (cfg *Config) NewAlert(ctx context.Context, params AlertParams) (*Alert, error) {
+	path, err := cfg.alertsPath(params.AccountSlug, params.ProjectSlug, "alerts")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := sendRequest[AlertParams, NewAlertResponse](ctx, cfg, http.MethodPost, path, params)
+	if err != nil {
+		return nil, fmt.Errorf("sendRequest(..., %s, %s, %+v): %v", path, http.MethodPost, params, err)
+	}
+	return &resp.Alert, nil
+}
+
+// DeleteAlert deletes an alert on Tenderly.
+func (cfg *Config) DeleteAlert(ctx context.Context, accountSlug, projectSlug, alertID string) error {
+	path, err := cfg.alertsPath(accountSlug, projectSlug, "alert", alertID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := sendRequest[struct{}, struct{}](ctx, cfg, http.MethodDelete, path, struct{}{}); err != nil {
+		return fmt.Errorf("sendRequest(..., %s, %s): %v", path, http.MethodDelete, err)
+	}
+	return nil
+}
+
+// AlertHistoryEntry is a single triggering of an alert.
+type AlertHistoryEntry struct {
+	ID              string    `json:"id"`
+	AlertID         string    `json:"alert_id"`
+	NetworkID       string    `json:"network_id"`
+	TransactionHash string    `json:"tx_hash"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// AlertHistoryResponse is the response from the Tenderly API when listing the
+// alert history of a project.
+type AlertHistoryResponse struct {
+	AlertHistory []AlertHistoryEntry `json:"alert_history"`
+}
+
+// AlertHistory lists the alerts triggered in the project, most recent first,
+// returning the requested page (starting at 1) of perPage entries.
+func (cfg *Config) AlertHistory(ctx context.Context, accountSlug, projectSlug string, page, perPage int) ([]AlertHistoryEntry, error) {
+	path, err := cfg.alertsPath(accountSlug, projectSlug, "alert-history")
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("page", strconv.Itoa(page))
+	q.Set("perPage", strconv.Itoa(perPage))
+	path += "?" + q.Encode()
+
+	resp, err := sendRequest[struct{}, AlertHistoryResponse](ctx, cfg, http.MethodGet, path, struct{}{})
+	if err != nil {
+		return nil, fmt.Errorf("sendRequest(..., %s, %s): %v", path, http.MethodGet, err)
+	}
+	return resp.AlertHistory, nil
+}
@@ -0,0 +1,159 @@
+package tenderly
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAlerts(t *testing.T) {
+	ctx := context.Background()
+	contract := common.HexToAddress("0xc0ffee")
+	topic := common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	webhook := DeliveryChannel{ID: "webhook-id", Enabled: true}
+
+	var (
+		gotCreate map[string]interface{}
+		deleted   []string
+		gotQuery  string
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/account/acc/project/proj/alerts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "bad method", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotCreate); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"alert":{"id":"alert-id","name":"deployed","enabled":true}}`))
+	})
+	mux.HandleFunc("/api/v1/account/acc/project/proj/alert/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "bad method", http.StatusMethodNotAllowed)
+			return
+		}
+		deleted = append(deleted, r.URL.Path)
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("/api/v1/account/acc/project/proj/alert-history", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Write([]byte(`{"alert_history":[{"id":"h1","alert_id":"alert-id","network_id":"1","tx_hash":"0xabc","created_at":"2023-08-25T12:00:00Z"}]}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	cfg := &Config{APIKey: "key", APIURL: srv.URL}
+
+	t.Run("create", func(t *testing.T) {
+		params := EventEmittedAlert("deployed", 1, contract, topic, webhook)
+		params.AccountSlug = "acc"
+		params.ProjectSlug = "proj"
+
+		got, err := cfg.NewAlert(ctx, params)
+		if err != nil {
+			t.Fatalf("%T.NewAlert(%+v) error %v", cfg, params, err)
+		}
+		if diff := cmp.Diff(&Alert{ID: "alert-id", Name: "deployed", Enabled: true}, got); diff != "" {
+			t.Errorf("%T.NewAlert(%+v) diff (-want +got):\n%s", cfg, params, diff)
+		}
+
+		want := map[string]interface{}{
+			"name":        "deployed",
+			"description": "",
+			"enabled":     true,
+			"expressions": []interface{}{
+				map[string]interface{}{
+					"type":       "network",
+					"expression": map[string]interface{}{"network_id": "1"},
+				},
+				map[string]interface{}{
+					"type": "emitted_log",
+					"expression": map[string]interface{}{
+						"contract_address": strings.ToLower(contract.Hex()),
+						"id":               topic.Hex(),
+					},
+				},
+			},
+			"delivery_channels": []interface{}{
+				map[string]interface{}{"id": "webhook-id", "enabled": true},
+			},
+		}
+		if diff := cmp.Diff(want, gotCreate); diff != "" {
+			t.Errorf("%T.NewAlert(%+v) request diff (-want +got):\n%s", cfg, params, diff)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := cfg.DeleteAlert(ctx, "acc", "proj", "alert-id"); err != nil {
+			t.Fatalf("%T.DeleteAlert() error %v", cfg, err)
+		}
+		if diff := cmp.Diff([]string{"/api/v1/account/acc/project/proj/alert/alert-id"}, deleted); diff != "" {
+			t.Errorf("%T.DeleteAlert() paths diff (-want +got):\n%s", cfg, diff)
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		got, err := cfg.AlertHistory(ctx, "acc", "proj", 2, 10)
+		if err != nil {
+			t.Fatalf("%T.AlertHistory() error %v", cfg, err)
+		}
+		want := []AlertHistoryEntry{{
+			ID:              "h1",
+			AlertID:         "alert-id",
+			NetworkID:       "1",
+			TransactionHash: "0xabc",
+			CreatedAt:       time.Date(2023, 8, 25, 12, 0, 0, 0, time.UTC),
+		}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%T.AlertHistory() diff (-want +got):\n%s", cfg, diff)
+		}
+		if want := "page=2&perPage=10"; gotQuery != want {
+			t.Errorf("%T.AlertHistory(…, 2, 10) sent query %q; want %q", cfg, gotQuery, want)
+		}
+	})
+}
+
+func TestAlertConstructors(t *testing.T) {
+	addr := common.HexToAddress("0xc0ffee")
+
+	tests := []struct {
+		name      string
+		params    AlertParams
+		wantTypes []string
+	}{
+		{
+			name:      "address watch",
+			params:    AddressWatchAlert("watch", 5, addr),
+			wantTypes: []string{"network", "contract_address"},
+		},
+		{
+			name:      "failed tx",
+			params:    FailedTxAlert("fail", 5, addr),
+			wantTypes: []string{"network", "contract_address", "tx_status"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range tt.params.Expressions {
+				got = append(got, e.Type)
+			}
+			if diff := cmp.Diff(tt.wantTypes, got); diff != "" {
+				t.Errorf("expression types diff (-want +got):\n%s", diff)
+			}
+			if !tt.params.Enabled {
+				t.Errorf("%T.Enabled got false; want true", tt.params)
+			}
+		})
+	}
+}
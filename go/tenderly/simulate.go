@@ -0,0 +1,130 @@
+package tenderly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+// SimulateParams are the parameters of a single transaction simulation with
+// `Config.Simulate`. See Simulator for simulating *types.Transactions.
+type SimulateParams struct {
+	// AccountSlug and ProjectSlug identify the project in which the simulation
+	// is run.
+	AccountSlug string `json:"-"`
+	ProjectSlug string `json:"-"`
+
+	NetworkID string          `json:"network_id"`
+	From      common.Address  `json:"from"`
+	To        *common.Address `json:"to,omitempty"`
+	Input     hexutil.Bytes   `json:"input"`
+	Gas       uint64          `json:"gas"`
+	GasPrice  string          `json:"gas_price"`
+	Value     string          `json:"value"`
+	// Save determines whether the simulation is stored in the Tenderly
+	// dashboard.
+	Save           bool   `json:"save"`
+	SaveIfFails    bool   `json:"save_if_fails"`
+	SimulationType string `json:"simulation_type"`
+}
+
+// SimulateResponse is the response from the Tenderly API when a transaction is
+// simulated. Only the fields required to determine the outcome are included.
+type SimulateResponse struct {
+	Transaction SimulatedTransaction `json:"transaction"`
+}
+
+// SimulatedTransaction is the transaction simulated by the Tenderly API.
+type SimulatedTransaction struct {
+	// Status is true if the transaction succeeded.
+	Status          bool            `json:"status"`
+	ErrorMessage    string          `json:"error_message"`
+	TransactionInfo TransactionInfo `json:"transaction_info"`
+}
+
+// TransactionInfo is the execution information of a simulated transaction.
+type TransactionInfo struct {
+	CallTrace CallTrace `json:"call_trace"`
+}
+
+// CallTrace is the top-level call of a simulated transaction.
+type CallTrace struct {
+	// Output is the return data or, if the transaction reverted, the revert
+	// data.
+	Output hexutil.Bytes `json:"output"`
+	Error  string        `json:"error"`
+}
+
+// Simulate simulates a transaction on Tenderly.
+func (cfg *Config) Simulate(ctx context.Context, params SimulateParams) (*SimulatedTransaction, error) {
+	ps := []string{"/api/v1/account", params.AccountSlug, "project", params.ProjectSlug, "simulate"}
+	path, err := url.JoinPath(cfg.APIURL, ps...)
+	if err != nil {
+		return nil, fmt.Errorf("url.JoinPath(%q, %v): %v", cfg.APIURL, ps, err)
+	}
+
+	resp, err := sendRequest[SimulateParams, SimulateResponse](ctx, cfg, http.MethodPost, path, params)
+	if err != nil {
+		return nil, fmt.Errorf("sendRequest(..., %s, %s, [params]): %v", path, http.MethodPost, err)
+	}
+
+	return &resp.Transaction, nil
+}
+
+// A Simulator is an eth.Simulator that uses Tenderly's simulation API, which
+// provides traces of failures in the Tenderly dashboard if SaveIfFails is
+// true.
+type Simulator struct {
+	Config                   *Config
+	AccountSlug, ProjectSlug string
+	ChainID                  uint64
+	SaveIfFails              bool
+}
+
+var _ eth.Simulator = (*Simulator)(nil)
+
+// Simulate implements the eth.Simulator interface.
+func (s *Simulator) Simulate(ctx context.Context, from common.Address, tx *types.Transaction) ([]byte, error) {
+	gasPrice := tx.GasPrice()
+	if tx.Type() == types.DynamicFeeTxType {
+		gasPrice = tx.GasFeeCap()
+	}
+
+	sim, err := s.Config.Simulate(ctx, SimulateParams{
+		AccountSlug:    s.AccountSlug,
+		ProjectSlug:    s.ProjectSlug,
+		NetworkID:      strconv.FormatUint(s.ChainID, 10),
+		From:           from,
+		To:             tx.To(),
+		Input:          tx.Data(),
+		Gas:            tx.Gas(),
+		GasPrice:       gasPrice.String(),
+		Value:          tx.Value().String(),
+		SaveIfFails:    s.SaveIfFails,
+		SimulationType: "quick",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%T.Simulate(ctx, [tx %v]): %v", s.Config, tx.Hash(), err)
+	}
+
+	out := sim.TransactionInfo.CallTrace.Output
+	if sim.Status {
+		return out, nil
+	}
+	rev := &eth.RevertError{
+		Data:   out,
+		Reason: eth.DecodeRevert(out).String(),
+	}
+	if rev.Reason == "" {
+		rev.Reason = sim.ErrorMessage
+	}
+	return nil, rev
+}
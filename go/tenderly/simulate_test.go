@@ -0,0 +1,122 @@
+package tenderly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cxkoda/solgo/go/eth"
+)
+
+func TestSimulator(t *testing.T) {
+	ctx := context.Background()
+
+	from := common.HexToAddress("0xa11ce")
+	to := common.HexToAddress("0xb0b")
+	revertData, err := eth.PackCall("Error(string)", "nope")
+	if err != nil {
+		t.Fatalf("eth.PackCall(Error(string)) error %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		response   string
+		wantOutput []byte
+		wantReason string // empty implies success
+	}{
+		{
+			name:       "success",
+			response:   `{"transaction":{"status":true,"transaction_info":{"call_trace":{"output":"0x2a"}}}}`,
+			wantOutput: []byte{42},
+		},
+		{
+			name:       "revert with data",
+			response:   `{"transaction":{"status":false,"error_message":"execution reverted","transaction_info":{"call_trace":{"output":"` + hexutil.Encode(revertData) + `","error":"execution reverted"}}}}`,
+			wantReason: "nope",
+		},
+		{
+			name:       "revert without data",
+			response:   `{"transaction":{"status":false,"error_message":"out of gas","transaction_info":{"call_trace":{"output":"0x"}}}}`,
+			wantReason: "out of gas",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got SimulateParams
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if p, want := r.URL.Path, "/api/v1/account/acc/project/proj/simulate"; p != want {
+					http.Error(w, "bad path "+p, http.StatusNotFound)
+					return
+				}
+				if k := r.Header.Get("X-Access-Key"); k != "key" {
+					http.Error(w, "bad key", http.StatusUnauthorized)
+					return
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				w.Write([]byte(tt.response))
+			}))
+			t.Cleanup(srv.Close)
+
+			sim := &Simulator{
+				Config:      &Config{APIKey: "key", APIURL: srv.URL},
+				AccountSlug: "acc",
+				ProjectSlug: "proj",
+				ChainID:     1,
+			}
+			tx := types.NewTx(&types.LegacyTx{
+				To:       &to,
+				Gas:      21_000,
+				GasPrice: big.NewInt(7),
+				Value:    big.NewInt(42),
+				Data:     []byte{1, 2, 3},
+			})
+
+			out, err := sim.Simulate(ctx, from, tx)
+
+			want := SimulateParams{
+				NetworkID:      "1",
+				From:           from,
+				To:             &to,
+				Input:          []byte{1, 2, 3},
+				Gas:            21_000,
+				GasPrice:       "7",
+				Value:          "42",
+				SimulationType: "quick",
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("%T.Simulate() request diff (-want +got):\n%s", sim, diff)
+			}
+
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("%T.Simulate() error %v", sim, err)
+				}
+				if diff := cmp.Diff(tt.wantOutput, out); diff != "" {
+					t.Errorf("%T.Simulate() output diff (-want +got):\n%s", sim, diff)
+				}
+				return
+			}
+
+			var rev *eth.RevertError
+			if !errors.As(err, &rev) {
+				t.Fatalf("%T.Simulate() got err %v; want %T", sim, err, rev)
+			}
+			if rev.Reason != tt.wantReason {
+				t.Errorf("%T.Simulate() got revert reason %q; want %q", sim, rev.Reason, tt.wantReason)
+			}
+		})
+	}
+}
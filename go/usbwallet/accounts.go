@@ -1,9 +1,13 @@
 package usbwallet
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -22,26 +26,50 @@ var zeroAddr common.Address
 // Although a SignerFn accepts a types.Transaction, which itself contains a
 // chain ID, the returned function is bound to a pre-specified chain for added
 // security.
-func (w *Wallet) SignerFn(index uint32, expectedAddr *common.Address, chainID *big.Int) (bind.SignerFn, common.Address, error) {
+//
+// By default, the returned function blocks until the user confirms or rejects
+// the transaction on the device; see the SignerOptions to limit this.
+func (w *Wallet) SignerFn(index uint32, expectedAddr *common.Address, chainID *big.Int, opts ...SignerOption) (bind.SignerFn, common.Address, error) {
 	ww, acc, err := w.derive(index, expectedAddr)
 	if err != nil {
 		return nil, zeroAddr, err
 	}
 
+	cfg := &signerConfig{ctx: context.Background()}
+	for _, o := range opts {
+		o(cfg)
+	}
+
 	// Clone to avoid the pointer being changed.
 	chainID = new(big.Int).Set(chainID)
 
 	return func(signAddr common.Address, tx *types.Transaction) (*types.Transaction, error) {
-		// Don't allow the eventloop to modify the wallets.
-		x := <-w.wallets
-		defer func() {
-			w.wallets <- x
-		}()
-
 		if signAddr != acc.Address {
 			return nil, fmt.Errorf("signing for %v with account %v", signAddr, acc.Address)
 		}
+		// Neither Ledger nor Trezor support blob transactions, and go-ethereum's
+		// drivers would otherwise fail after prompting the user.
+		if tx.Type() == types.BlobTxType {
+			return nil, fmt.Errorf("%w: blob transaction %#x", ErrUnsupportedTxType, tx.Hash())
+		}
+
+		ctx := cfg.ctx
+		if cfg.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+			defer cancel()
+		}
+
+		// Don't allow the eventloop to modify the wallets.
+		var x map[accounts.URL]*walletAndStatus
+		select {
+		case <-ctx.Done():
+			return nil, confirmationErr(ctx)
+		case x = <-w.wallets:
+		}
+
 		if !ww.open() {
+			w.wallets <- x
 			return nil, fmt.Errorf("%T closed since account %v pinned", ww.Wallet, acc.Address)
 		}
 
@@ -49,12 +77,45 @@ func (w *Wallet) SignerFn(index uint32, expectedAddr *common.Address, chainID *b
 			"[%v][%v] signing tx=%#x to=%v nonce=%d value=%d data=%#x",
 			ww.url, acc.Address, tx.Hash(), tx.To(), tx.Nonce(), tx.Value(), tx.Data(),
 		)
-		tx, err := ww.SignTx(acc, tx, chainID)
-		if err != nil {
-			return nil, fmt.Errorf("%T.SignTx(%+v, %+v, %d): %v", ww.Wallet, acc, tx, chainID, err)
+		if cfg.prompt != nil {
+			cfg.prompt(Prompt{
+				Device:  ww.url,
+				Account: acc.Address,
+				Tx:      tx,
+			})
+		}
+
+		type result struct {
+			tx  *types.Transaction
+			err error
 		}
-		glog.Infof("[%v] signed tx %#x as %v", ww.url, tx.Hash(), acc.Address)
-		return tx, nil
+		// Buffered so the goroutine never blocks if we've stopped waiting.
+		res := make(chan result, 1)
+		go func() {
+			// The device can't be interrupted so the wallets are only released
+			// once it responds, even if we've stopped waiting.
+			defer func() {
+				w.wallets <- x
+			}()
+			signed, err := ww.SignTx(acc, tx, chainID)
+			res <- result{signed, err}
+		}()
+
+		var r result
+		select {
+		case <-ctx.Done():
+			return nil, confirmationErr(ctx)
+		case r = <-res:
+		}
+
+		if r.err != nil {
+			if isRejection(r.err) {
+				return nil, fmt.Errorf("%w: %v", ErrUserRejected, r.err)
+			}
+			return nil, fmt.Errorf("%T.SignTx(%+v, %+v, %d): %v", ww.Wallet, acc, tx, chainID, r.err)
+		}
+		glog.Infof("[%v] signed tx %#x as %v", ww.url, r.tx.Hash(), acc.Address)
+		return r.tx, nil
 	}, acc.Address, nil
 }
 
@@ -68,6 +129,86 @@ var (
 	ErrNoWalletsOpen = errors.New("no wallets open")
 )
 
+var (
+	// ErrUserRejected is returned by a SignerFn if the user rejects the
+	// transaction on the device.
+	ErrUserRejected = errors.New("rejected on device")
+	// ErrConfirmationTimeout is returned by a SignerFn if the user doesn't
+	// respond on the device before the timeout set with
+	// WithConfirmationTimeout().
+	ErrConfirmationTimeout = errors.New("timed out waiting for confirmation on device")
+	// ErrUnsupportedTxType is returned by a SignerFn if hardware wallets can't
+	// sign the type of transaction; e.g. EIP-4844 blob transactions.
+	ErrUnsupportedTxType = errors.New("transaction type unsupported by device")
+)
+
+// A SignerOption configures the behaviour of the function returned by
+// Wallet.SignerFn().
+type SignerOption func(*signerConfig)
+
+type signerConfig struct {
+	ctx     context.Context
+	timeout time.Duration
+	prompt  func(Prompt)
+}
+
+// WithConfirmationTimeout limits the time spent waiting for the user to
+// respond on the device, after which ErrConfirmationTimeout is returned. The
+// device itself isn't interrupted, so signing with the same Wallet blocks
+// until the user responds to the pending request (or disconnects the device).
+func WithConfirmationTimeout(d time.Duration) SignerOption {
+	return func(c *signerConfig) {
+		c.timeout = d
+	}
+}
+
+// WithSigningContext stops waiting for the user to respond on the device once
+// ctx is done, returning ctx.Err(). As with WithConfirmationTimeout(), the
+// device isn't interrupted.
+func WithSigningContext(ctx context.Context) SignerOption {
+	return func(c *signerConfig) {
+		c.ctx = ctx
+	}
+}
+
+// WithPrompt registers a callback that is called immediately before every
+// transaction is sent to the device, allowing users to be told to confirm it;
+// e.g. by printing the transaction hash for comparison with the device screen.
+// The callback MUST NOT block.
+func WithPrompt(fn func(Prompt)) SignerOption {
+	return func(c *signerConfig) {
+		c.prompt = fn
+	}
+}
+
+// A Prompt describes a transaction awaiting confirmation on a device.
+type Prompt struct {
+	Device  accounts.URL
+	Account common.Address
+	Tx      *types.Transaction
+}
+
+// confirmationErr returns the error to report when ctx is done while waiting
+// for a device.
+func confirmationErr(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrConfirmationTimeout, ctx.Err())
+	}
+	return ctx.Err()
+}
+
+// isRejection reports whether an error returned by a device's SignTx() method
+// indicates that the user rejected the transaction. go-ethereum doesn't expose
+// typed errors so this relies on the messages returned by the Ledger (a reply
+// without a signature, which is what a denial produces) and Trezor (a cancelled
+// action) drivers.
+func isRejection(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "reply lacks signature") ||
+		strings.Contains(msg, "cancelled") ||
+		strings.Contains(msg, "denied")
+}
+
 // derive checks all open() wallets for an account at the specified index that
 // matches the expected address. See SignerFn() re behaviour of nil/zero
 // expectedAddr to avoid ambiguity.
@@ -76,7 +217,6 @@ func (w *Wallet) derive(index uint32, expectedAddr *common.Address) (*walletAndS
 	if expectedAddr != nil {
 		addr = *expectedAddr
 	}
-	path := w.derivationPath(index)
 
 	wallets := <-w.wallets
 	defer func() {
@@ -98,6 +238,7 @@ func (w *Wallet) derive(index uint32, expectedAddr *common.Address) (*walletAndS
 			continue
 		}
 
+		path := derivationPath(w.basePath, ww.typ, index)
 		acc, err := ww.Wallet.Derive(path, true)
 		if err != nil {
 			return nil, accounts.Account{}, fmt.Errorf("%T.Derive(%v, true): %v", ww.Wallet, path, err)
@@ -113,14 +254,73 @@ func (w *Wallet) derive(index uint32, expectedAddr *common.Address) (*walletAndS
 	return nil, accounts.Account{}, fmt.Errorf("no account %d with address %v found", index, addr)
 }
 
+// DeviceAccounts are the accounts derived from a single device.
+type DeviceAccounts struct {
+	URL      accounts.URL
+	Type     Type
+	Accounts []accounts.Account
+}
+
+// Accounts derives the first n accounts of every open device, sorted by device
+// URL. The i-th account of each device is the one used by SignerFn(i, …),
+// which allows users to choose an index by address.
+//
+// Derived accounts are not pinned, so their derivation has no side effects.
+func (w *Wallet) Accounts(ctx context.Context, n uint32) ([]DeviceAccounts, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var wallets map[accounts.URL]*walletAndStatus
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case wallets = <-w.wallets:
+	}
+	defer func() {
+		w.wallets <- wallets
+	}()
+
+	var all []DeviceAccounts
+	for url, ww := range wallets {
+		if !ww.open() {
+			continue
+		}
+
+		dev := DeviceAccounts{
+			URL:  url,
+			Type: ww.typ,
+		}
+		for i := uint32(0); i < n; i++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			path := derivationPath(w.basePath, ww.typ, i)
+			acc, err := ww.Wallet.Derive(path, false)
+			if err != nil {
+				return nil, fmt.Errorf("%T.Derive(%v, false): %v", ww.Wallet, path, err)
+			}
+			dev.Accounts = append(dev.Accounts, acc)
+		}
+		all = append(all, dev)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].URL.Cmp(all[j].URL) < 0
+	})
+	return all, nil
+}
+
 // derivationPath returns the DerivationPath for the 0-indexed account,
-// accounting (pun intended) for the Type passed to New() when creating the
-// Wallet.
-func (w *Wallet) derivationPath(index uint32) accounts.DerivationPath {
-	path := make(accounts.DerivationPath, len(w.basePath))
-	copy(path, w.basePath)
+// accounting (pun intended) for the Type of device. Ledger devices increment
+// the account component of the path whereas all others increment the final
+// (address index) component.
+func derivationPath(basePath accounts.DerivationPath, typ Type, index uint32) accounts.DerivationPath {
+	path := make(accounts.DerivationPath, len(basePath))
+	copy(path, basePath)
 
-	switch w.typ {
+	switch typ {
 	case Ledger:
 		path[2] += index
 	default:
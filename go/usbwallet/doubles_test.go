@@ -85,20 +85,34 @@ type fakeDevice struct {
 
 	hub   *fakeHub
 	label string
+	// scheme and status, if non-empty, override the default URL scheme and
+	// the Ledger-specific value returned by Status().
+	scheme, status string
 
 	open       sync.Toggle
 	passphrase string
+	// locks are returned, in order, by calls to Open() before the device opens,
+	// simulating a Trezor waiting for PIN and/or passphrase entry.
+	locks []error
 
 	// Unlike `open`, which encodes a state, this channel signals the closure
 	// of the device via Close(). It is constructed by Open().
 	activelyClosed chan struct{}
 
 	pins map[common.Address]*ecdsa.PrivateKey
+
+	// beforeSign, if non-nil, is called by SignTx(), which returns any error
+	// instead of signing. It can block to simulate waiting for the user.
+	beforeSign func() error
 }
 
 func (d *fakeDevice) URL() accounts.URL {
+	scheme := d.scheme
+	if scheme == "" {
+		scheme = "test-double"
+	}
 	return accounts.URL{
-		Scheme: "test-double",
+		Scheme: scheme,
 		Path:   d.label,
 	}
 }
@@ -109,6 +123,11 @@ func (d *fakeDevice) Open(passphrase string) error {
 	}
 
 	d.passphrase = passphrase
+	if len(d.locks) > 0 {
+		err := d.locks[0]
+		d.locks = d.locks[1:]
+		return err
+	}
 	d.activelyClosed = make(chan struct{})
 
 	go func() {
@@ -124,6 +143,9 @@ func (d *fakeDevice) Open(passphrase string) error {
 }
 
 func (d *fakeDevice) Status() (string, error) {
+	if d.status != "" {
+		return d.status, nil
+	}
 	return "Ethereum app v0.0.0 online", nil
 }
 
@@ -162,10 +184,15 @@ func (d *fakeDevice) Derive(dPath accounts.DerivationPath, pin bool) (_ accounts
 		}
 	}
 
-	curve := crypto.S256()
-	key, err := ecdsa.GenerateKey(curve, state)
+	// ecdsa.GenerateKey() isn't deterministic, even with a deterministic
+	// source of randomness, as it calls randutil.MaybeReadByte().
+	seed := make([]byte, 32)
+	if _, err := state.Read(seed); err != nil {
+		return accounts.Account{}, fmt.Errorf("%T.Read(): %v", state, err)
+	}
+	key, err := crypto.ToECDSA(seed)
 	if err != nil {
-		return accounts.Account{}, fmt.Errorf("ecdsa.GenerateKey(%T, %T): %v", curve, state, err)
+		return accounts.Account{}, fmt.Errorf("crypto.ToECDSA([seed]): %v", err)
 	}
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 
@@ -186,6 +213,11 @@ func (d *fakeDevice) Derive(dPath accounts.DerivationPath, pin bool) (_ accounts
 }
 
 func (d *fakeDevice) SignTx(acc accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if d.beforeSign != nil {
+		if err := d.beforeSign(); err != nil {
+			return nil, err
+		}
+	}
 	if d.pins == nil || d.pins[acc.Address] == nil {
 		return nil, fmt.Errorf("%T requested to sign for unpinned address %v", d, acc.Address)
 	}
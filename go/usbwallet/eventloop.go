@@ -71,6 +71,36 @@ func (w *Wallet) trackExistingWallets(ch chan<- accounts.WalletEvent, done chan
 // Ethereum app, not the "home" screen.
 var ledgerAppOnlineRE = regexp.MustCompile(`^Ethereum app v\d+\.\d+\.\d+ online$`)
 
+// trezorOnlineRE and trezorPINWaitRE match the statuses returned by a Trezor
+// that is unlocked, or waiting for PIN entry, respectively.
+var (
+	trezorOnlineRE  = regexp.MustCompile(`^Trezor v\d+\.\d+\.\d+ '.*' online$`)
+	trezorPINWaitRE = regexp.MustCompile(`^Trezor v\d+\.\d+\.\d+ '.*' waiting for PIN$`)
+)
+
+// parseStatus parses the status string returned by an opened device of the
+// specified Type, returning whether the device is ready to sign. A device of
+// UnknownWalletType has its status checked against all known formats.
+func parseStatus(t Type, status string) (online bool, _ error) {
+	ledger := t == Ledger || t == UnknownWalletType
+	trezor := t == Trezor || t == UnknownWalletType
+	virtual := t == Virtual || t == UnknownWalletType
+
+	switch {
+	case ledger && status == "Ethereum app offline":
+		return false, nil
+	case ledger && ledgerAppOnlineRE.MatchString(status):
+		return true, nil
+	case trezor && trezorPINWaitRE.MatchString(status):
+		return false, nil
+	case trezor && trezorOnlineRE.MatchString(status):
+		return true, nil
+	case virtual && status == virtualStatus:
+		return true, nil
+	}
+	return false, fmt.Errorf("unrecognised %v status %q", t, status)
+}
+
 // eventLoop handles a single iteration of the event loop. The channel MUST be
 // the same one used to create the Subscription. An loop iteration may be any
 // one of:
@@ -102,6 +132,12 @@ func (w *Wallet) eventLoop(sub event.Subscription, ch <-chan accounts.WalletEven
 			glog.InfoDepth(1, fmt.Sprintf("[%v] %s", url, msg))
 		}
 
+		typ := w.deviceType(url)
+		if !w.accepts(typ) {
+			log(fmt.Sprintf("ignoring %v device; want %v", typ, w.typ))
+			return nil
+		}
+
 		var wallets map[accounts.URL]*walletAndStatus
 		select {
 		case wallets = <-w.wallets:
@@ -126,9 +162,15 @@ func (w *Wallet) eventLoop(sub event.Subscription, ch <-chan accounts.WalletEven
 		switch ev.Kind {
 		case accounts.WalletArrived:
 			log("arrived")
-			wallets[url] = &walletAndStatus{Wallet: ev.Wallet, url: url}
+			ww := &walletAndStatus{Wallet: ev.Wallet, url: url, typ: typ}
+			wallets[url] = ww
 
 			if err := ev.Wallet.Open("" /*passphrase*/); err != nil {
+				if isLocked(err) {
+					log(fmt.Sprintf("locked: %v", err))
+					ww.locked = err
+					return &LockedError{URL: url, Err: err}
+				}
 				return fmt.Errorf("%T{%v}.Open(%q): %w", ev.Wallet, url, "", err)
 			}
 
@@ -137,7 +179,7 @@ func (w *Wallet) eventLoop(sub event.Subscription, ch <-chan accounts.WalletEven
 			ww, ok := wallets[url]
 			if !ok {
 				log("previously unseen")
-				ww = &walletAndStatus{Wallet: ev.Wallet, url: url}
+				ww = &walletAndStatus{Wallet: ev.Wallet, url: url, typ: typ}
 				wallets[url] = ww
 			}
 
@@ -147,15 +189,22 @@ func (w *Wallet) eventLoop(sub event.Subscription, ch <-chan accounts.WalletEven
 			if err != nil {
 				return fmt.Errorf("%T.Status(): %v", ev.Wallet, err)
 			}
-			switch {
-			case status == "Ethereum app offline":
-				log("app offline")
-			case ledgerAppOnlineRE.MatchString(status):
+			online, err := parseStatus(ww.typ, status)
+			if err != nil {
+				return fmt.Errorf("%v: %w", url, err)
+			}
+			// A Trezor waiting for its passphrase reports itself as online, so
+			// the status alone is insufficient.
+			if ww.locked != nil {
+				online = false
+				status = ww.locked.Error()
+			}
+			if online {
 				log("app online")
-				ww.appOpen = true
-			default:
-				return fmt.Errorf("unrecognised status %q of %q", status, url)
+			} else {
+				log(fmt.Sprintf("not ready: %s", status))
 			}
+			ww.appOpen = online
 
 		case accounts.WalletDropped:
 			log("dropped")
@@ -0,0 +1,146 @@
+package usbwallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// NewTrezor is equivalent to calling New() with parameters specific to Trezor
+// devices, connected over WebUSB (i.e. all models and firmware released since
+// 2019). Use NewTrezorHID() for older firmware.
+//
+// Trezor devices that require PIN or passphrase entry on the host (rather than
+// on the device itself) aren't opened when they arrive. Instead, a *LockedError
+// wrapping usbwallet.ErrTrezorPINNeeded or usbwallet.ErrTrezorPassphraseNeeded
+// respectively is reported on the Err() channel, and the device remains
+// unavailable until the secret is provided to Wallet.Unlock().
+func NewTrezor() (*Wallet, error) {
+	hub, err := usbwallet.NewTrezorHubWithWebUSB()
+	if err != nil {
+		return nil, fmt.Errorf("go-ethereum/accounts/usbwallet.NewTrezorHubWithWebUSB(): %w", err)
+	}
+	return New(hub, Trezor, accounts.DefaultBaseDerivationPath), nil
+}
+
+// NewTrezorHID is equivalent to NewTrezor() except that it connects to devices
+// over HID.
+func NewTrezorHID() (*Wallet, error) {
+	hub, err := usbwallet.NewTrezorHubWithHID()
+	if err != nil {
+		return nil, fmt.Errorf("go-ethereum/accounts/usbwallet.NewTrezorHubWithHID(): %w", err)
+	}
+	return New(hub, Trezor, accounts.DefaultBaseDerivationPath), nil
+}
+
+// A LockedError is reported on a Wallet's Err() channel when a device is
+// waiting for PIN or passphrase entry on the host. The device is not available
+// for signing until unlocked with Wallet.Unlock().
+type LockedError struct {
+	URL accounts.URL
+	// Err is usbwallet.ErrTrezorPINNeeded or usbwallet.ErrTrezorPassphraseNeeded.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("device %v locked: %v", e.URL, e.Err)
+}
+
+// Unwrap returns e.Err.
+func (e *LockedError) Unwrap() error {
+	return e.Err
+}
+
+// isLocked reports whether err, returned by a device's Open() method, indicates
+// that it is waiting for PIN or passphrase entry.
+func isLocked(err error) bool {
+	return errors.Is(err, usbwallet.ErrTrezorPINNeeded) || errors.Is(err, usbwallet.ErrTrezorPassphraseNeeded)
+}
+
+// Unlock provides the secret requested by the device with the URL, as reported
+// by a LockedError. If the device is waiting for its PIN, the secret is the
+// sequence of positions entered on the scrambled matrix shown by the device,
+// numbered as on a numeric keypad (i.e. 7, 8, 9 along the top row). If the
+// device then requires a passphrase, another *LockedError is returned and
+// Unlock() must be called again with the passphrase.
+//
+// Any other error leaves the device in a failed state, and it must be
+// reconnected before another attempt.
+func (w *Wallet) Unlock(ctx context.Context, url accounts.URL, secret string) error {
+	var wallets map[accounts.URL]*walletAndStatus
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case wallets = <-w.wallets:
+	}
+	defer func() {
+		w.wallets <- wallets
+	}()
+
+	ww, ok := wallets[url]
+	if !ok {
+		return fmt.Errorf("no device with URL %v", url)
+	}
+	if ww.locked == nil {
+		return fmt.Errorf("device %v not waiting for PIN or passphrase", url)
+	}
+
+	switch err := ww.Wallet.Open(secret); {
+	case err == nil:
+		ww.locked = nil
+		return nil
+	case isLocked(err):
+		ww.locked = err
+		return &LockedError{URL: url, Err: err}
+	default:
+		ww.locked = nil
+		return fmt.Errorf("%T{%v}.Open([secret]): %w", ww.Wallet, url, err)
+	}
+}
+
+// NewAny returns a Wallet that manages all supported hardware devices, of mixed
+// Types. Derivation paths are adjusted per device Type (see SignerFn()).
+func NewAny() (*Wallet, error) {
+	var hubs multiHub
+	for _, h := range []struct {
+		name string
+		fn   func() (*usbwallet.Hub, error)
+	}{
+		{"NewLedgerHub", usbwallet.NewLedgerHub},
+		{"NewTrezorHubWithWebUSB", usbwallet.NewTrezorHubWithWebUSB},
+		{"NewTrezorHubWithHID", usbwallet.NewTrezorHubWithHID},
+	} {
+		hub, err := h.fn()
+		if err != nil {
+			return nil, fmt.Errorf("go-ethereum/accounts/usbwallet.%s(): %w", h.name, err)
+		}
+		hubs = append(hubs, hub)
+	}
+	return construct(hubs, UnknownWalletType, accounts.DefaultBaseDerivationPath), nil
+}
+
+// A multiHub merges multiple hubs into one.
+type multiHub []hub
+
+// Subscribe subscribes ch to all hubs.
+func (m multiHub) Subscribe(ch chan<- accounts.WalletEvent) event.Subscription {
+	subs := make([]event.Subscription, len(m))
+	for i, h := range m {
+		subs[i] = h.Subscribe(ch)
+	}
+	return event.JoinSubscriptions(subs...)
+}
+
+// Wallets returns the concatenation of all hubs' Wallets().
+func (m multiHub) Wallets() []accounts.Wallet {
+	var all []accounts.Wallet
+	for _, h := range m {
+		all = append(all, h.Wallets()...)
+	}
+	return all
+}
@@ -0,0 +1,317 @@
+package usbwallet
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// VirtualScheme is the URL scheme of devices attached to a VirtualHub.
+const VirtualScheme = "virtual"
+
+// virtualStatus is the Status() of every open virtual device.
+const virtualStatus = "Virtual signer online"
+
+// A RemoteSigner signs transactions on behalf of a single address without
+// exposing its private key; e.g. an *ethkms.GCP, or a client of a remote
+// signing service. A RemoteSigner is typically bound to a chain, so
+// transactions for any other chain will fail verification by the virtual
+// device.
+type RemoteSigner interface {
+	Address() common.Address
+	SignTx(context.Context, *types.Transaction) (*types.Transaction, error)
+}
+
+// A TextSigner is a RemoteSigner that also supports EIP-191 personal
+// signatures, in which case virtual devices implement SignText(). The returned
+// signature MUST have V in {27,28}, as returned by *ethkms.GCP.
+type TextSigner interface {
+	RemoteSigner
+	PersonalSign(context.Context, []byte) ([]byte, error)
+}
+
+// A VirtualHub is a stand-in for a *usbwallet.Hub, managing "devices" that are
+// backed by RemoteSigners instead of hardware. A Wallet created with
+// NewVirtual() behaves identically to one managing physical devices, which
+// allows code written against a Wallet to be used in environments without
+// them; e.g. CI or remote operations.
+//
+// The i-th RemoteSigner of a device is the i-th account of SignerFn() and
+// Accounts().
+type VirtualHub struct {
+	feed event.Feed
+
+	// mu also serialises all events sent on feed, which guarantees that they
+	// are received in the same order as the changes that they announce.
+	mu       sync.Mutex
+	basePath accounts.DerivationPath
+	devices  map[string]*virtualDevice
+	order    []string
+}
+
+// NewVirtualHub returns an empty VirtualHub; see Connect().
+func NewVirtualHub() *VirtualHub {
+	return &VirtualHub{
+		basePath: accounts.DefaultBaseDerivationPath,
+		devices:  make(map[string]*virtualDevice),
+	}
+}
+
+// NewVirtual returns a Wallet that manages all devices attached to the hub.
+func NewVirtual(hub *VirtualHub) *Wallet {
+	return construct(hub, Virtual, hub.basePath)
+}
+
+// Connect attaches a new device, with the label and backed by the signers, to
+// the hub, as if it were plugged in. Labels MUST be unique amongst connected
+// devices.
+func (h *VirtualHub) Connect(label string, signers ...RemoteSigner) error {
+	if len(signers) == 0 {
+		return fmt.Errorf("%T.Connect(%q) without signers", h, label)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.devices[label]; ok {
+		return fmt.Errorf("%T.Connect(%q): label already connected", h, label)
+	}
+	d := &virtualDevice{
+		hub:      h,
+		label:    label,
+		basePath: h.basePath,
+		signers:  signers,
+	}
+	h.devices[label] = d
+	h.order = append(h.order, label)
+
+	h.feed.Send(accounts.WalletEvent{Wallet: d, Kind: accounts.WalletArrived})
+	return nil
+}
+
+// Disconnect detaches the device with the label, as if it were unplugged.
+func (h *VirtualHub) Disconnect(label string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	d, ok := h.devices[label]
+	if !ok {
+		return fmt.Errorf("%T.Disconnect(%q): no such device", h, label)
+	}
+	delete(h.devices, label)
+	for i, l := range h.order {
+		if l == label {
+			h.order = append(h.order[:i], h.order[i+1:]...)
+			break
+		}
+	}
+
+	h.feed.Send(accounts.WalletEvent{Wallet: d, Kind: accounts.WalletDropped})
+	return nil
+}
+
+// announceOpened sends a WalletOpened event for the device unless it has since
+// been disconnected, in which case the WalletDropped event has already been
+// sent and the device MUST NOT be announced after it.
+func (h *VirtualHub) announceOpened(d *virtualDevice) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.devices[d.label] != d {
+		return
+	}
+	h.feed.Send(accounts.WalletEvent{Wallet: d, Kind: accounts.WalletOpened})
+}
+
+// Subscribe implements the hub interface.
+func (h *VirtualHub) Subscribe(ch chan<- accounts.WalletEvent) event.Subscription {
+	return h.feed.Subscribe(ch)
+}
+
+// Wallets returns all connected devices, in the order that they were
+// connected.
+func (h *VirtualHub) Wallets() []accounts.Wallet {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ws := make([]accounts.Wallet, len(h.order))
+	for i, l := range h.order {
+		ws[i] = h.devices[l]
+	}
+	return ws
+}
+
+// virtualDevice implements accounts.Wallet with RemoteSigners.
+type virtualDevice struct {
+	hub      *VirtualHub
+	label    string
+	basePath accounts.DerivationPath
+	signers  []RemoteSigner
+
+	mu   sync.Mutex
+	open bool
+}
+
+var _ accounts.Wallet = (*virtualDevice)(nil)
+
+func (d *virtualDevice) URL() accounts.URL {
+	return accounts.URL{
+		Scheme: VirtualScheme,
+		Path:   d.label,
+	}
+}
+
+func (d *virtualDevice) Status() (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.open {
+		return "Virtual signer closed", nil
+	}
+	return virtualStatus, nil
+}
+
+// Open marks the device as open and, as with a physical device, asynchronously
+// announces it with a WalletOpened event. Unlike a physical device, there is
+// no passphrase so it is ignored.
+func (d *virtualDevice) Open(passphrase string) error {
+	d.mu.Lock()
+	d.open = true
+	d.mu.Unlock()
+
+	// Open() is called by the Wallet's event loop, which is also the receiver
+	// of the event, so sending synchronously would deadlock.
+	go d.hub.announceOpened(d)
+	return nil
+}
+
+func (d *virtualDevice) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.open {
+		return fmt.Errorf("%T{%q}.Close() when not open", d, d.label)
+	}
+	d.open = false
+	return nil
+}
+
+// Accounts returns an account per signer, in the order passed to Connect().
+func (d *virtualDevice) Accounts() []accounts.Account {
+	accs := make([]accounts.Account, len(d.signers))
+	for i := range d.signers {
+		accs[i] = d.account(uint32(i))
+	}
+	return accs
+}
+
+func (d *virtualDevice) Contains(acc accounts.Account) bool {
+	_, err := d.signer(acc)
+	return err == nil
+}
+
+// account returns the Account of the signer at the index, which MUST be in
+// range.
+func (d *virtualDevice) account(index uint32) accounts.Account {
+	path := derivationPath(d.basePath, Virtual, index)
+	return accounts.Account{
+		Address: d.signers[index].Address(),
+		URL: accounts.URL{
+			Scheme: VirtualScheme,
+			Path:   d.label + "/" + path.String(),
+		},
+	}
+}
+
+// Derive returns the account of the signer whose index is the offset of the
+// final component of path from that of the base derivation path; all other
+// components MUST be equal. Pinning is a no-op as all accounts are always
+// available for signing.
+func (d *virtualDevice) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	base := d.basePath
+	n := len(base)
+	if len(path) != n {
+		return accounts.Account{}, fmt.Errorf("%T{%q}: derivation path %v not relative to %v", d, d.label, path, base)
+	}
+	for i := 0; i < n-1; i++ {
+		if path[i] != base[i] {
+			return accounts.Account{}, fmt.Errorf("%T{%q}: derivation path %v not relative to %v", d, d.label, path, base)
+		}
+	}
+	if path[n-1] < base[n-1] || uint64(path[n-1]-base[n-1]) >= uint64(len(d.signers)) {
+		return accounts.Account{}, fmt.Errorf("%T{%q}: derivation path %v out of range of %d signer(s)", d, d.label, path, len(d.signers))
+	}
+	return d.account(path[n-1] - base[n-1]), nil
+}
+
+func (d *virtualDevice) SelfDerive([]accounts.DerivationPath, ethereum.ChainStateReader) {}
+
+// signer returns the signer for the account, which MUST have been returned by
+// d.
+func (d *virtualDevice) signer(acc accounts.Account) (RemoteSigner, error) {
+	for i, s := range d.signers {
+		if want := d.account(uint32(i)); acc.Address == want.Address && (acc.URL == accounts.URL{} || acc.URL == want.URL) {
+			return s, nil
+		}
+	}
+	return nil, accounts.ErrUnknownAccount
+}
+
+// SignTx signs the transaction with the account's RemoteSigner, confirming
+// that the signature is valid for the chain ID; i.e. that the signer is bound
+// to the same chain as the SignerFn.
+func (d *virtualDevice) SignTx(acc accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	s, err := d.signer(acc)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Protected() && tx.ChainId().Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("%T{%q}: tx for chain %d; signing for %d", d, d.label, tx.ChainId(), chainID)
+	}
+
+	signed, err := s.SignTx(context.Background(), tx)
+	if err != nil {
+		return nil, fmt.Errorf("%T.SignTx(%#x): %v", s, tx.Hash(), err)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("%T.SignTx(%#x) returned tx with invalid signature for chain %d: %v", s, tx.Hash(), chainID, err)
+	}
+	if sender != acc.Address {
+		return nil, fmt.Errorf("%T.SignTx(%#x) returned tx signed by %v for chain %d; want %v", s, tx.Hash(), sender, chainID, acc.Address)
+	}
+	return signed, nil
+}
+
+func (d *virtualDevice) SignTxWithPassphrase(acc accounts.Account, _ string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return d.SignTx(acc, tx, chainID)
+}
+
+// SignText returns an EIP-191 personal signature if the account's signer is a
+// TextSigner, otherwise accounts.ErrNotSupported.
+func (d *virtualDevice) SignText(acc accounts.Account, text []byte) ([]byte, error) {
+	s, err := d.signer(acc)
+	if err != nil {
+		return nil, err
+	}
+	ts, ok := s.(TextSigner)
+	if !ok {
+		return nil, accounts.ErrNotSupported
+	}
+	return ts.PersonalSign(context.Background(), text)
+}
+
+func (d *virtualDevice) SignTextWithPassphrase(acc accounts.Account, _ string, text []byte) ([]byte, error) {
+	return d.SignText(acc, text)
+}
+
+func (d *virtualDevice) SignData(accounts.Account, string, []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+func (d *virtualDevice) SignDataWithPassphrase(accounts.Account, string, string, []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
@@ -0,0 +1,235 @@
+package usbwallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/cxkoda/solgo/go/ethkms"
+)
+
+var _ TextSigner = (*ethkms.GCP)(nil)
+
+// keySigner implements TextSigner with a local private key, bound to a chain
+// in the same manner as an *ethkms.GCP.
+type keySigner struct {
+	key    *ecdsa.PrivateKey
+	signer types.Signer
+}
+
+func newKeySigner(t *testing.T, seed string, chainID int64) *keySigner {
+	t.Helper()
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte(seed)))
+	if err != nil {
+		t.Fatalf("crypto.ToECDSA(keccak(%q)) error %v", seed, err)
+	}
+	return &keySigner{
+		key:    key,
+		signer: types.LatestSignerForChainID(big.NewInt(chainID)),
+	}
+}
+
+func (s *keySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s *keySigner) SignTx(_ context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	return types.SignTx(tx, s.signer, s.key)
+}
+
+func (s *keySigner) PersonalSign(_ context.Context, buf []byte) ([]byte, error) {
+	sig, err := crypto.Sign(accounts.TextHash(buf), s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+// txOnlySigner hides the PersonalSign() method of a keySigner.
+type txOnlySigner struct {
+	RemoteSigner
+}
+
+func TestVirtualWallet(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const chainID = 1
+	signers := []*keySigner{
+		newKeySigner(t, "zero", chainID),
+		newKeySigner(t, "one", chainID),
+	}
+
+	hub := NewVirtualHub()
+	if err := hub.Connect("ci", signers[0], txOnlySigner{signers[1]}); err != nil {
+		t.Fatalf("%T.Connect() error %v", hub, err)
+	}
+	if err := hub.Connect("ci", signers[0]); err == nil {
+		t.Errorf("%T.Connect() with duplicate label; got nil error", hub)
+	}
+
+	w := NewVirtual(hub)
+	defer w.Close()
+	if err := w.Wait(ctx); err != nil {
+		t.Fatalf("%T.Wait() with pre-connected virtual device: %v", w, err)
+	}
+
+	t.Run("Accounts", func(t *testing.T) {
+		got, err := w.Accounts(ctx, uint32(len(signers)))
+		if err != nil {
+			t.Fatalf("%T.Accounts(ctx, %d) error %v", w, len(signers), err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("%T.Accounts() got %d devices; want 1", w, len(got))
+		}
+		if got[0].Type != Virtual {
+			t.Errorf("%T.Accounts() got device of %T %v; want %v", w, got[0].Type, got[0].Type, Virtual)
+		}
+		for i, acc := range got[0].Accounts {
+			if want := signers[i].Address(); acc.Address != want {
+				t.Errorf("%T.Accounts()[0].Accounts[%d] got %v; want %v", w, i, acc.Address, want)
+			}
+		}
+
+		if _, err := w.Accounts(ctx, uint32(len(signers)+1)); err == nil {
+			t.Errorf("%T.Accounts(ctx, %d) with only %d signers; got nil error", w, len(signers)+1, len(signers))
+		}
+	})
+
+	t.Run("SignerFn", func(t *testing.T) {
+		for i, s := range signers {
+			fn, addr, err := w.SignerFn(uint32(i), nil, big.NewInt(chainID))
+			if err != nil {
+				t.Fatalf("%T.SignerFn(%d, nil, %d) error %v", w, i, chainID, err)
+			}
+			if want := s.Address(); addr != want {
+				t.Errorf("%T.SignerFn(%d, …) got address %v; want %v", w, i, addr, want)
+			}
+
+			tx := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(chainID), Nonce: uint64(i)})
+			signed, err := fn(addr, tx)
+			if err != nil {
+				t.Fatalf("SignerFn(%d)(%v, [tx]) error %v", i, addr, err)
+			}
+			if got, err := types.Sender(signer(big.NewInt(chainID)), signed); err != nil || got != addr {
+				t.Errorf("types.Sender([tx signed by SignerFn(%d)]) got %v, err = %v; want %v, nil", i, got, err, addr)
+			}
+		}
+	})
+
+	t.Run("signer bound to other chain", func(t *testing.T) {
+		const other = 5
+		fn, addr, err := w.SignerFn(0, nil, big.NewInt(other))
+		if err != nil {
+			t.Fatalf("%T.SignerFn(0, nil, %d) error %v", w, other, err)
+		}
+		// Legacy transactions carry no chain ID so the device can't reject
+		// them before signing, but the signature is for the wrong chain.
+		for _, tx := range []*types.Transaction{
+			types.NewTx(&types.LegacyTx{}),
+			types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(other)}),
+		} {
+			if _, err := fn(addr, tx); err == nil {
+				t.Errorf("SignerFn(0, nil, %d)(…, [tx type %d]) with RemoteSigner bound to chain %d; got nil error", other, tx.Type(), chainID)
+			}
+		}
+	})
+
+	t.Run("SignText", func(t *testing.T) {
+		dev := hub.Wallets()[0]
+		accs := dev.Accounts()
+
+		msg := []byte("hello")
+		sig, err := dev.SignText(accs[0], msg)
+		if err != nil {
+			t.Fatalf("%T.SignText(%v, %q) error %v", dev, accs[0].Address, msg, err)
+		}
+		sig[64] -= 27
+		pub, err := crypto.SigToPub(accounts.TextHash(msg), sig)
+		if err != nil || crypto.PubkeyToAddress(*pub) != accs[0].Address {
+			t.Errorf("%T.SignText(%v, %q) recovers to %v, err = %v", dev, accs[0].Address, msg, pub, err)
+		}
+
+		if _, err := dev.SignText(accs[1], msg); !errors.Is(err, accounts.ErrNotSupported) {
+			t.Errorf("%T.SignText() with non-%T signer; err = %v; want %v", dev, (*TextSigner)(nil), err, accounts.ErrNotSupported)
+		}
+	})
+
+	t.Run("connect and disconnect", func(t *testing.T) {
+		late := newKeySigner(t, "late", chainID)
+		if err := hub.Connect("late", late); err != nil {
+			t.Fatalf("%T.Connect(%q) error %v", hub, "late", err)
+		}
+
+		addr := late.Address()
+		waitFor(ctx, t, func() bool {
+			_, _, err := w.SignerFn(0, &addr, big.NewInt(chainID))
+			return err == nil
+		})
+
+		for _, label := range []string{"ci", "late"} {
+			if err := hub.Disconnect(label); err != nil {
+				t.Fatalf("%T.Disconnect(%q) error %v", hub, label, err)
+			}
+		}
+		if err := hub.Disconnect("ci"); err == nil {
+			t.Errorf("%T.Disconnect() of already-disconnected device; got nil error", hub)
+		}
+
+		waitFor(ctx, t, func() bool {
+			_, _, err := w.SignerFn(0, nil, big.NewInt(chainID))
+			return errors.Is(err, ErrNoWalletsOpen)
+		})
+	})
+}
+
+func TestVirtualHubEventOrder(t *testing.T) {
+	hub := NewVirtualHub()
+	ch := make(chan accounts.WalletEvent, 4)
+	sub := hub.Subscribe(ch)
+	defer sub.Unsubscribe()
+
+	if err := hub.Connect("dev", newKeySigner(t, "dev", 1)); err != nil {
+		t.Fatalf("%T.Connect() error %v", hub, err)
+	}
+	d := hub.Wallets()[0].(*virtualDevice)
+	if err := hub.Disconnect("dev"); err != nil {
+		t.Fatalf("%T.Disconnect() error %v", hub, err)
+	}
+	// Equivalent to the goroutine started by Open() only being scheduled after
+	// the device was disconnected.
+	hub.announceOpened(d)
+
+	close(ch)
+	var got []accounts.WalletEventType
+	for ev := range ch {
+		got = append(got, ev.Kind)
+	}
+	want := []accounts.WalletEventType{accounts.WalletArrived, accounts.WalletDropped}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%T events after Open() of disconnected device; diff (-want +got):\n%s", hub, diff)
+	}
+}
+
+// waitFor polls cond until it returns true, failing the test if ctx is done
+// first.
+func waitFor(ctx context.Context, t *testing.T, cond func() bool) {
+	t.Helper()
+	for !cond() {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("condition not met: %v", ctx.Err())
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -21,8 +21,41 @@ type Type int
 const (
 	UnknownWalletType Type = iota
 	Ledger
+	Trezor
+	// Virtual devices are backed by RemoteSigners; see VirtualHub.
+	Virtual
 )
 
+// String returns a human-readable name for the Type.
+func (t Type) String() string {
+	switch t {
+	case Ledger:
+		return "Ledger"
+	case Trezor:
+		return "Trezor"
+	case Virtual:
+		return "virtual"
+	default:
+		return "unknown"
+	}
+}
+
+// typeFromURL returns the Type of device with the URL, based on the scheme
+// used by go-ethereum's usbwallet hubs and VirtualHub. It returns UnknownWalletType for
+// unrecognised schemes.
+func typeFromURL(u accounts.URL) Type {
+	switch u.Scheme {
+	case usbwallet.LedgerScheme:
+		return Ledger
+	case usbwallet.TrezorScheme:
+		return Trezor
+	case VirtualScheme:
+		return Virtual
+	default:
+		return UnknownWalletType
+	}
+}
+
 // A Wallet manages hardware wallets connected over USB. As it manages device
 // events concurrently, any errors are reported on a channel accessible via the
 // Err() method.
@@ -58,11 +91,15 @@ type hub interface {
 type walletAndStatus struct {
 	accounts.Wallet
 	url accounts.URL
+	typ Type
 	// Although the physical hardware device may be connected and "open", the
 	// Ethereum-specific app may not be. This is definitely the case on Ledgers;
 	// if extending to support hardware for which this isn't the case, simply
 	// couple the two values.
 	deviceOpen, appOpen bool
+	// locked is non-nil iff the device is waiting for PIN or passphrase entry
+	// via Wallet.Unlock(); see LockedError.
+	locked error
 }
 
 // open returns true iff the device is connected and the Ethereum app is opened.
@@ -74,11 +111,26 @@ func (w walletAndStatus) open() bool {
 const errChanBuffer = 16
 
 // New creates a new Wallet backed by the Hub, connected to the specific Type of
-// hardware.
+// hardware. Devices reported by the Hub as being of a different, known Type are
+// ignored. If t is UnknownWalletType, devices of all Types are accepted.
 func New(hub *usbwallet.Hub, t Type, basePath accounts.DerivationPath) *Wallet {
 	return construct(hub, t, basePath)
 }
 
+// accepts reports whether a device of Type t is managed by the Wallet.
+func (w *Wallet) accepts(t Type) bool {
+	return w.typ == UnknownWalletType || t == UnknownWalletType || t == w.typ
+}
+
+// deviceType returns the Type of the device with the URL, defaulting to the
+// Type passed to New() if it can't be determined from the URL.
+func (w *Wallet) deviceType(u accounts.URL) Type {
+	if t := typeFromURL(u); t != UnknownWalletType {
+		return t
+	}
+	return w.typ
+}
+
 // construct abstracts New() to allow for testing with a test-double
 // implementation of usbwallet.Hub. Without this we'd need to expose the
 // arbitrary hub interface, obscuring any documentation coupling it to
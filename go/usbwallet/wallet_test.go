@@ -6,11 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/usbwallet"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
@@ -173,7 +175,7 @@ func TestWallet(t *testing.T) {
 			index := uint32(i)
 			// As we have multiple devices connected, an expected address is
 			// required so all devices can be checked.
-			expected := dev.deriveAddrT(t, w.derivationPath(index))
+			expected := dev.deriveAddrT(t, derivationPath(w.basePath, Ledger, index))
 
 			wg.Add(1)
 			go func(dev *fakeDevice) {
@@ -205,7 +207,7 @@ func testSignerFn(t *testing.T, w *Wallet, dev *fakeDevice, index uint32, expect
 
 	chainID := big.NewInt(1337)
 
-	wantAddr := dev.deriveAddrT(t, w.derivationPath(index))
+	wantAddr := dev.deriveAddrT(t, derivationPath(w.basePath, Ledger, index))
 	fn, gotAddr, err := w.SignerFn(index, expectedAddr, chainID)
 	if err != nil || gotAddr != wantAddr {
 		t.Fatalf("%T.SignerFn(%d, nil, %d) got %v, err = %v; want %v, nil err", w, index, chainID, gotAddr, err, wantAddr)
@@ -240,6 +242,13 @@ func testSignerFn(t *testing.T, w *Wallet, dev *fakeDevice, index uint32, expect
 			t.Errorf("%T.SignerFn()() returned tx; got types.Sender() = %v; want %v", w, got, wantAddr)
 		}
 	})
+
+	t.Run("blob tx", func(t *testing.T) {
+		blob := types.NewTx(&types.BlobTx{Nonce: 43})
+		if _, err := fn(gotAddr, blob); !errors.Is(err, ErrUnsupportedTxType) {
+			t.Errorf("%T.SignerFn()([blob tx]) got err %v; want %v", w, err, ErrUnsupportedTxType)
+		}
+	})
 }
 
 func TestWalletErrorPropagation(t *testing.T) {
@@ -262,3 +271,309 @@ func TestWalletErrorPropagation(t *testing.T) {
 		}
 	})
 }
+
+func TestTrezorUnlock(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dev := &fakeDevice{
+		label:  "trezor",
+		scheme: usbwallet.TrezorScheme,
+		status: "Trezor v2.6.0 'trezor' online",
+		locks:  []error{usbwallet.ErrTrezorPINNeeded, usbwallet.ErrTrezorPassphraseNeeded},
+	}
+	hub := newFakeHub(t, dev)
+
+	w := construct(hub, Trezor, accounts.DefaultBaseDerivationPath)
+	defer w.Close()
+
+	var locked *LockedError
+	if err := <-w.Err(); !errors.As(err, &locked) || !errors.Is(err, usbwallet.ErrTrezorPINNeeded) {
+		t.Fatalf("%T.Err() received %v; want %T wrapping %v", w, err, locked, usbwallet.ErrTrezorPINNeeded)
+	}
+	url := dev.URL()
+	if locked.URL != url {
+		t.Errorf("%T.URL = %v; want %v", locked, locked.URL, url)
+	}
+
+	if err := w.Unlock(ctx, accounts.URL{Scheme: "test-double", Path: "unknown"}, "1234"); err == nil {
+		t.Errorf("%T.Unlock([unknown URL]) got nil error", w)
+	}
+
+	const pin, passphrase = "1234", "hunter2"
+	if err := w.Unlock(ctx, url, pin); !errors.As(err, &locked) || !errors.Is(err, usbwallet.ErrTrezorPassphraseNeeded) {
+		t.Fatalf("%T.Unlock(%v, [PIN]) got err %v; want %T wrapping %v", w, url, err, locked, usbwallet.ErrTrezorPassphraseNeeded)
+	}
+	if got, err := w.Accounts(ctx, 1); err != nil || len(got) != 0 {
+		t.Errorf("%T.Accounts() while waiting for passphrase got %d devices, err %v; want none, nil error", w, len(got), err)
+	}
+
+	if err := w.Unlock(ctx, url, passphrase); err != nil {
+		t.Fatalf("%T.Unlock(%v, [passphrase]) error %v", w, url, err)
+	}
+	if err := w.Wait(ctx); err != nil {
+		t.Fatalf("%T.Wait() after unlocking error %v", w, err)
+	}
+	if dev.passphrase != passphrase {
+		t.Errorf("%T.Unlock() opened device with passphrase %q; want %q", w, dev.passphrase, passphrase)
+	}
+
+	if err := w.Unlock(ctx, url, passphrase); err == nil {
+		t.Errorf("%T.Unlock() on unlocked device got nil error", w)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		typ        Type
+		status     string
+		wantOnline bool
+		wantErr    bool
+	}{
+		{Ledger, "Ethereum app v1.10.3 online", true, false},
+		{Ledger, "Ethereum app offline", false, false},
+		{Ledger, "Trezor v2.6.0 'My Trezor' online", false, true},
+		{Trezor, "Trezor v2.6.0 'My Trezor' online", true, false},
+		{Trezor, "Trezor v1.12.1 '' waiting for PIN", false, false},
+		{Trezor, "Ethereum app v1.10.3 online", false, true},
+		{UnknownWalletType, "Ethereum app v1.10.3 online", true, false},
+		{UnknownWalletType, "Trezor v2.6.0 'My Trezor' online", true, false},
+		{UnknownWalletType, "Ethereum app in browser mode", false, true},
+		{Virtual, "Virtual signer online", true, false},
+		{Virtual, "Ethereum app v1.10.3 online", false, true},
+		{Ledger, "Virtual signer online", false, true},
+		{UnknownWalletType, "Virtual signer online", true, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseStatus(tt.typ, tt.status)
+		if got != tt.wantOnline || (err != nil) != tt.wantErr {
+			t.Errorf("parseStatus(%v, %q) got %t, err = %v; want %t, err? %t", tt.typ, tt.status, got, err, tt.wantOnline, tt.wantErr)
+		}
+	}
+}
+
+func TestDerivationPath(t *testing.T) {
+	base := accounts.DefaultBaseDerivationPath
+
+	tests := []struct {
+		typ   Type
+		index uint32
+		want  string
+	}{
+		{Ledger, 0, "m/44'/60'/0'/0/0"},
+		{Ledger, 3, "m/44'/60'/3'/0/0"},
+		{Trezor, 0, "m/44'/60'/0'/0/0"},
+		{Trezor, 3, "m/44'/60'/0'/0/3"},
+	}
+
+	for _, tt := range tests {
+		if got := derivationPath(base, tt.typ, tt.index).String(); got != tt.want {
+			t.Errorf("derivationPath(%v, %v, %d) got %q; want %q", base, tt.typ, tt.index, got, tt.want)
+		}
+	}
+
+	if got, want := base.String(), "m/44'/60'/0'/0/0"; got != want {
+		t.Errorf("derivationPath() modified base path; got %q; want %q", got, want)
+	}
+}
+
+func TestTypeFiltering(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ledger := &fakeDevice{label: "ledger", scheme: usbwallet.LedgerScheme}
+	trezor := &fakeDevice{
+		label:  "trezor",
+		scheme: usbwallet.TrezorScheme,
+		status: "Trezor v2.6.0 'trezor' online",
+	}
+	hub := newFakeHub(t, ledger, trezor)
+
+	w := construct(hub, Trezor, accounts.DefaultBaseDerivationPath)
+	defer w.Close()
+	if err := w.Wait(ctx); err != nil {
+		t.Fatalf("%T.Wait() error %v", w, err)
+	}
+
+	// As the Ledger is ignored, derivation is unambiguous even without an
+	// expected address.
+	if _, _, err := w.SignerFn(2, nil, big.NewInt(1)); err != nil {
+		t.Errorf("%T.SignerFn(2, nil, 1) with only one device of the requested type; error %v", w, err)
+	}
+
+	if ledger.open.State() {
+		t.Errorf("%T constructed for %v opened %v device", w, Trezor, Ledger)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	devs := []*fakeDevice{
+		{label: "b"},
+		{label: "a"},
+	}
+	hub := newFakeHub(t, devs...)
+
+	w := construct(hub, Ledger, accounts.DefaultBaseDerivationPath)
+	defer w.Close()
+	if err := w.Wait(ctx); err != nil {
+		t.Fatalf("%T.Wait() error %v", w, err)
+	}
+	for _, d := range devs {
+		if err := d.open.Wait(ctx); err != nil {
+			t.Fatalf("%T{%q}.open.Wait() error %v", d, d.label, err)
+		}
+	}
+	// Wait() only guarantees that at least one device is available.
+	for {
+		got, err := w.Accounts(ctx, 0)
+		if err != nil {
+			t.Fatalf("%T.Accounts(ctx, 0) error %v", w, err)
+		}
+		if len(got) == len(devs) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	const n = 3
+	got, err := w.Accounts(ctx, n)
+	if err != nil {
+		t.Fatalf("%T.Accounts(ctx, %d) error %v", w, n, err)
+	}
+
+	var gotLabels []string
+	for _, dev := range got {
+		gotLabels = append(gotLabels, dev.URL.Path)
+		if dev.Type != Ledger {
+			t.Errorf("%T.Accounts() got device %v with %T %v; want %v", w, dev.URL, dev.Type, dev.Type, Ledger)
+		}
+		if len(dev.Accounts) != n {
+			t.Errorf("%T.Accounts(ctx, %d) got %d accounts for device %v; want %d", w, n, len(dev.Accounts), dev.URL, n)
+			continue
+		}
+		for i, acc := range dev.Accounts {
+			want := derivationPath(w.basePath, Ledger, uint32(i)).String()
+			if !strings.HasSuffix(acc.URL.Path, want) {
+				t.Errorf("%T.Accounts() device %v account %d has URL %v; want derived from path %s", w, dev.URL, i, acc.URL, want)
+			}
+		}
+	}
+	if got, want := strings.Join(gotLabels, ","), "a,b"; got != want {
+		t.Errorf("%T.Accounts() got devices in order %q; want %q", w, got, want)
+	}
+
+	for _, d := range devs {
+		if len(d.pins) != 0 {
+			t.Errorf("%T.Accounts() pinned accounts on device %q", w, d.label)
+		}
+	}
+
+	cancelled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	if _, err := w.Accounts(cancelled, n); !errors.Is(err, context.Canceled) {
+		t.Errorf("%T.Accounts([cancelled context]) got err %v; want %v", w, err, context.Canceled)
+	}
+}
+
+func TestSignerFnConfirmation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dev := &fakeDevice{label: "dev"}
+	hub := newFakeHub(t, dev)
+	w := construct(hub, Ledger, accounts.DefaultBaseDerivationPath)
+	defer w.Close()
+	if err := w.Wait(ctx); err != nil {
+		t.Fatalf("%T.Wait() error %v", w, err)
+	}
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21_000, big.NewInt(0), nil)
+	chainID := big.NewInt(1)
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		dev.beforeSign = func() error {
+			<-release
+			return nil
+		}
+		defer func() {
+			close(release)
+			// The wallets are only released once the device responds, which
+			// guarantees that the hook is no longer in use.
+			w.wallets <- <-w.wallets
+			dev.beforeSign = nil
+		}()
+
+		var prompts []Prompt
+		fn, addr, err := w.SignerFn(0, nil, chainID, WithConfirmationTimeout(10*time.Millisecond), WithPrompt(func(p Prompt) {
+			prompts = append(prompts, p)
+		}))
+		if err != nil {
+			t.Fatalf("%T.SignerFn() error %v", w, err)
+		}
+
+		if _, err := fn(addr, tx); !errors.Is(err, ErrConfirmationTimeout) {
+			t.Errorf("SignerFn()(…) while device blocks; got err %v; want %v", err, ErrConfirmationTimeout)
+		}
+		if len(prompts) != 1 || prompts[0].Account != addr || prompts[0].Tx.Hash() != tx.Hash() || prompts[0].Device != dev.URL() {
+			t.Errorf("WithPrompt() callback got %+v; want single Prompt{Device: %v, Account: %v, Tx: %#x}", prompts, dev.URL(), addr, tx.Hash())
+		}
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		release := make(chan struct{})
+		dev.beforeSign = func() error {
+			<-release
+			return nil
+		}
+		defer func() {
+			close(release)
+			// The wallets are only released once the device responds, which
+			// guarantees that the hook is no longer in use.
+			w.wallets <- <-w.wallets
+			dev.beforeSign = nil
+		}()
+
+		sigCtx, sigCancel := context.WithCancel(ctx)
+		fn, addr, err := w.SignerFn(0, nil, chainID, WithSigningContext(sigCtx))
+		if err != nil {
+			t.Fatalf("%T.SignerFn() error %v", w, err)
+		}
+		time.AfterFunc(10*time.Millisecond, sigCancel)
+
+		if _, err := fn(addr, tx); !errors.Is(err, context.Canceled) {
+			t.Errorf("SignerFn()(…) with cancelled context; got err %v; want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		dev.beforeSign = func() error {
+			return errors.New("trezor: Action cancelled by user")
+		}
+		defer func() {
+			dev.beforeSign = nil
+		}()
+
+		fn, addr, err := w.SignerFn(0, nil, chainID, WithConfirmationTimeout(time.Second))
+		if err != nil {
+			t.Fatalf("%T.SignerFn() error %v", w, err)
+		}
+		if _, err := fn(addr, tx); !errors.Is(err, ErrUserRejected) {
+			t.Errorf("SignerFn()(…) when rejected on device; got err %v; want %v", err, ErrUserRejected)
+		}
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		fn, addr, err := w.SignerFn(0, nil, chainID, WithConfirmationTimeout(time.Second))
+		if err != nil {
+			t.Fatalf("%T.SignerFn() error %v", w, err)
+		}
+		if _, err := fn(addr, tx); err != nil {
+			t.Errorf("SignerFn()(…) error %v", err)
+		}
+	})
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/holiman/uint256"
+
+	"github.com/cxkoda/solgo/go/memconv"
 )
 
 // Convert returns conv(v.Payload) i.f.f. v.Payload is of type P.
@@ -50,15 +52,7 @@ func (a *Argument) AsUint256() (*uint256.Int, error) {
 // AsUint256 returns v as a *uint256.Int i.f.f. v.Payload is a *Value_Uint256.
 func (v *Value) AsUint256() (*uint256.Int, error) {
 	return Convert(v, func(p *Value_Uint256) (*uint256.Int, error) {
-		b, err := v.AsBig256()
-		if err != nil {
-			return nil, err
-		}
-		u, overflow := uint256.FromBig(b)
-		if overflow {
-			return nil, fmt.Errorf("0x%s overflows uint256", b.Text(16))
-		}
-		return u, nil
+		return memconv.Uint256FromBytes(p.Uint256)
 	})
 }
 
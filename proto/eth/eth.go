@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"reflect"
 	"strings"
 	"time"
 
@@ -11,7 +12,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/holiman/uint256"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/cxkoda/solgo/go/memconv"
 )
 
 // The type support in this file is likely incomplete and will be extended on an
@@ -50,8 +54,8 @@ func (v *Value) MustParseWithoutValidation() interface{} {
 		return b
 
 	case *Value_Uint256:
-		u, overflow := uint256.FromBig(new(big.Int).SetBytes(p.Uint256))
-		if overflow {
+		u, err := memconv.Uint256FromBytes(p.Uint256)
+		if err != nil {
 			panic(fmt.Sprintf("%T %#x overflowed when converting to %T", p, p.Uint256, u))
 		}
 		return u
@@ -61,6 +65,26 @@ func (v *Value) MustParseWithoutValidation() interface{} {
 	}
 }
 
+// SameType reports whether v and w have payloads of the same type, regardless
+// of their values.
+func (v *Value) SameType(w *Value) bool {
+	return reflect.TypeOf(v.GetPayload()) == reflect.TypeOf(w.GetPayload())
+}
+
+// Equal reports whether v and w have the same payload type and value. Unlike
+// proto.Equal(), integer values are compared numerically so, for example,
+// uint256 values with differing leading zero bytes are considered equal.
+func (v *Value) Equal(w *Value) bool {
+	if !v.SameType(w) {
+		return false
+	}
+	if p, ok := v.GetPayload().(*Value_Uint256); ok {
+		q := w.GetPayload().(*Value_Uint256)
+		return new(big.Int).SetBytes(p.Uint256).Cmp(new(big.Int).SetBytes(q.Uint256)) == 0
+	}
+	return proto.Equal(v, w)
+}
+
 // AsValue returns p as a Value only if the mapping to Payload type is
 // unambigous.
 func AsValue(p interface{}) (*Value, error) {
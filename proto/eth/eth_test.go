@@ -395,3 +395,66 @@ func TestSignatureIdentifier(t *testing.T) {
 		})
 	}
 }
+
+func TestValueEqual(t *testing.T) {
+	addr := func(a string) *Value {
+		return value(&Value_Address{Address: &Address{Bytes: common.HexToAddress(a).Bytes()}})
+	}
+	u256 := func(b ...byte) *Value {
+		return value(&Value_Uint256{Uint256: b})
+	}
+
+	tests := []struct {
+		name string
+		a, b *Value
+		want bool
+	}{
+		{
+			name: "same address",
+			a:    addr("0x01"),
+			b:    addr("0x01"),
+			want: true,
+		},
+		{
+			name: "different address",
+			a:    addr("0x01"),
+			b:    addr("0x02"),
+			want: false,
+		},
+		{
+			name: "uint256 with leading zeros",
+			a:    u256(0, 0, 42),
+			b:    u256(42),
+			want: true,
+		},
+		{
+			name: "zero uint256",
+			a:    u256(),
+			b:    u256(0),
+			want: true,
+		},
+		{
+			name: "different uint256",
+			a:    u256(1),
+			b:    u256(2),
+			want: false,
+		},
+		{
+			name: "different types",
+			a:    value(&Value_Bool{Bool: false}),
+			b:    u256(),
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Equal(tt.b); got != tt.want {
+				t.Errorf("%v.Equal(%v) got %t; want %t", tt.a, tt.b, got, tt.want)
+			}
+			if got := tt.b.Equal(tt.a); got != tt.want {
+				t.Errorf("%v.Equal(%v) got %t; want %t", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
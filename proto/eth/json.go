@@ -0,0 +1,301 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The "hex JSON" format is identical to protojson except that bytes fields are
+// represented as 0x-prefixed hex strings instead of base64, and Address and Hash
+// messages are represented as the hex string of their bytes instead of as an
+// object. For example, a Transaction is represented as
+//
+//	{"hash": "0x…", "from": "0x…", "value": "0x0de0b6b3a7640000", …}
+//
+// which is both human-readable and the convention of Ethereum web clients.
+//
+// Well-known types (google.protobuf.*) are left unchanged, including the
+// contents of Any messages.
+
+// MarshalHexJSON is equivalent to HexMarshalOptions{}.Marshal(m).
+func MarshalHexJSON(m proto.Message) ([]byte, error) {
+	return HexMarshalOptions{}.Marshal(m)
+}
+
+// UnmarshalHexJSON is equivalent to HexUnmarshalOptions{}.Unmarshal(b, m).
+func UnmarshalHexJSON(b []byte, m proto.Message) error {
+	return HexUnmarshalOptions{}.Unmarshal(b, m)
+}
+
+// HexMarshalOptions configures marshalling of messages to hex JSON. All
+// protojson options are respected.
+type HexMarshalOptions struct {
+	protojson.MarshalOptions
+}
+
+// Marshal returns m in the hex JSON format.
+func (o HexMarshalOptions) Marshal(m proto.Message) ([]byte, error) {
+	pj := o.MarshalOptions
+	pj.Multiline = false
+	pj.Indent = ""
+	buf, err := pj.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := decodeJSON(buf)
+	if err != nil {
+		return nil, err
+	}
+	tree, err = toHex.message(m.ProtoReflect().Descriptor(), tree)
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(bytes.Buffer)
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false) // consistent with protojson
+	if o.Multiline || o.Indent != "" {
+		indent := o.Indent
+		if indent == "" {
+			indent = "  "
+		}
+		enc.SetIndent("", indent)
+	}
+	if err := enc.Encode(tree); err != nil {
+		return nil, fmt.Errorf("%T.Encode(…): %v", enc, err)
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte{'\n'}), nil
+}
+
+// HexUnmarshalOptions configures unmarshalling of messages from hex JSON. All
+// protojson options are respected.
+type HexUnmarshalOptions struct {
+	protojson.UnmarshalOptions
+}
+
+// Unmarshal parses the hex JSON in b into m. Address and Hash messages MAY also
+// be in their regular protojson (object) form, but bytes fields MUST be hex.
+func (o HexUnmarshalOptions) Unmarshal(b []byte, m proto.Message) error {
+	tree, err := decodeJSON(b)
+	if err != nil {
+		return err
+	}
+	tree, err = fromHex.message(m.ProtoReflect().Descriptor(), tree)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(tree)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(…): %v", err)
+	}
+	return o.UnmarshalOptions.Unmarshal(buf, m)
+}
+
+// decodeJSON decodes b into a generic tree, retaining numbers as json.Number to
+// avoid loss of precision.
+func decodeJSON(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("%T.Decode(…): %v", dec, err)
+	}
+	return tree, nil
+}
+
+// hexMessages are those represented in hex JSON as the hex string of their
+// sole, bytes field.
+var hexMessages = map[protoreflect.FullName]bool{
+	(*Address)(nil).ProtoReflect().Descriptor().FullName(): true,
+	(*Hash)(nil).ProtoReflect().Descriptor().FullName():    true,
+}
+
+// A hexTranscoder converts a generic JSON tree between protojson and hex JSON,
+// in the direction determined by the conversion functions. Values that don't
+// have the type expected by the descriptor are left unchanged for protojson to
+// report.
+type hexTranscoder struct {
+	bytes      func(string) (string, error)
+	hexMessage func(any) (any, error)
+}
+
+var (
+	toHex = hexTranscoder{
+		bytes: func(s string) (string, error) {
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return "", fmt.Errorf("base64 decoding %q: %v", s, err)
+			}
+			return hexutil.Encode(b), nil
+		},
+		hexMessage: func(v any) (any, error) {
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return v, nil
+			}
+			s, _ := obj["bytes"].(string) // absent if empty
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("base64 decoding %q: %v", s, err)
+			}
+			return hexutil.Encode(b), nil
+		},
+	}
+
+	fromHex = hexTranscoder{
+		bytes: func(s string) (string, error) {
+			b, err := hexutil.Decode(s)
+			if err != nil {
+				return "", fmt.Errorf("hex decoding %q: %v", s, err)
+			}
+			return base64.StdEncoding.EncodeToString(b), nil
+		},
+		hexMessage: func(v any) (any, error) {
+			s, ok := v.(string)
+			if !ok {
+				return v, nil
+			}
+			b, err := hexutil.Decode(s)
+			if err != nil {
+				return nil, fmt.Errorf("hex decoding %q: %v", s, err)
+			}
+			return map[string]any{"bytes": base64.StdEncoding.EncodeToString(b)}, nil
+		},
+	}
+)
+
+func (t hexTranscoder) message(md protoreflect.MessageDescriptor, v any) (any, error) {
+	if hexMessages[md.FullName()] {
+		out, err := t.hexMessage(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", md.FullName(), err)
+		}
+		return out, nil
+	}
+	if strings.HasPrefix(string(md.FullName()), "google.protobuf.") {
+		return v, nil
+	}
+
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return v, nil
+	}
+	fields := md.Fields()
+	for k, fv := range obj {
+		// protojson accepts either name when unmarshalling, and the JSON names
+		// are only used when marshalling if UseProtoNames is false.
+		fd := fields.ByJSONName(k)
+		if fd == nil {
+			fd = fields.ByTextName(k)
+		}
+		if fd == nil { // unknown field or extension
+			continue
+		}
+
+		out, err := t.field(fd, fv)
+		if err != nil {
+			return nil, err
+		}
+		obj[k] = out
+	}
+	return obj, nil
+}
+
+func (t hexTranscoder) field(fd protoreflect.FieldDescriptor, v any) (any, error) {
+	switch {
+	case fd.IsMap():
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return v, nil
+		}
+		for k, e := range obj {
+			out, err := t.singular(fd.MapValue(), e)
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = out
+		}
+		return obj, nil
+
+	case fd.IsList():
+		list, ok := v.([]any)
+		if !ok {
+			return v, nil
+		}
+		for i, e := range list {
+			out, err := t.singular(fd, e)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = out
+		}
+		return list, nil
+
+	default:
+		return t.singular(fd, v)
+	}
+}
+
+func (t hexTranscoder) singular(fd protoreflect.FieldDescriptor, v any) (any, error) {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		s, ok := v.(string)
+		if !ok {
+			return v, nil
+		}
+		out, err := t.bytes(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fd.FullName(), err)
+		}
+		return out, nil
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return t.message(fd.Message(), v)
+
+	default:
+		return v, nil
+	}
+}
+
+// MarshalText returns the 0x-prefixed hex representation of a.Bytes. As it
+// implements encoding.TextMarshaler, it is also used by encoding/json.
+func (a *Address) MarshalText() ([]byte, error) {
+	return []byte(hexutil.Encode(a.GetBytes())), nil
+}
+
+// UnmarshalText is the inverse of MarshalText(), returning an error if the
+// result is invalid.
+func (a *Address) UnmarshalText(txt []byte) error {
+	b, err := hexutil.Decode(string(txt))
+	if err != nil {
+		return fmt.Errorf("%T.UnmarshalText(%q): %v", a, txt, err)
+	}
+	a.Bytes = b
+	return a.Validate()
+}
+
+// MarshalText returns the 0x-prefixed hex representation of h.Bytes. As it
+// implements encoding.TextMarshaler, it is also used by encoding/json.
+func (h *Hash) MarshalText() ([]byte, error) {
+	return []byte(hexutil.Encode(h.GetBytes())), nil
+}
+
+// UnmarshalText is the inverse of MarshalText(), returning an error if the
+// result is invalid.
+func (h *Hash) UnmarshalText(txt []byte) error {
+	b, err := hexutil.Decode(string(txt))
+	if err != nil {
+		return fmt.Errorf("%T.UnmarshalText(%q): %v", h, txt, err)
+	}
+	h.Bytes = b
+	return h.Validate()
+}
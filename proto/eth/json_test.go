@@ -0,0 +1,175 @@
+package eth
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestHexJSON(t *testing.T) {
+	addr := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	hash := common.HexToHash("0x0102030405060708091011121314151617181920212223242526272829303132")
+
+	block := &Block{
+		Number:    42,
+		TimeStamp: timestamppb.New(time.Unix(1e9, 0)),
+		Hash:      &Hash{Bytes: hash.Bytes()},
+		Transactions: []*Transaction{
+			{
+				Hash:  &Hash{Bytes: hash.Bytes()},
+				From:  &Address{Bytes: addr.Bytes()},
+				Value: []byte{0x0d, 0xe0, 0xb6, 0xb3, 0xa7, 0x64, 0x00, 0x00},
+				Logs: []*Event{
+					NewEvent("Transfer", addr,
+						&Argument{Name: "to", Value: &Value{Payload: &Value_Address{Address: &Address{}}}},
+						&Argument{Name: "amount", Value: &Value{Payload: &Value_Uint256{Uint256: []byte{1, 0}}}},
+						&Argument{Name: "data", Value: &Value{Payload: &Value_Bytes{Bytes: []byte("hi")}}},
+					),
+				},
+				Status: Transaction_STATUS_SUCCEEDED,
+			},
+		},
+	}
+
+	const wantJSON = `{
+		"number": "42",
+		"timeStamp": "2001-09-09T01:46:40Z",
+		"hash": "0x0102030405060708091011121314151617181920212223242526272829303132",
+		"transactions": [{
+			"hash": "0x0102030405060708091011121314151617181920212223242526272829303132",
+			"from": "0x00000000000000000000000000000000deadbeef",
+			"value": "0x0de0b6b3a7640000",
+			"status": "STATUS_SUCCEEDED",
+			"logs": [{
+				"name": "Transfer",
+				"emitter": "0x00000000000000000000000000000000deadbeef",
+				"arguments": [
+					{"name": "to", "value": {"address": "0x"}},
+					{"name": "amount", "value": {"uint256": "0x0100"}},
+					{"name": "data", "value": {"bytes": "0x6869"}}
+				],
+				"argumentsByName": {
+					"to": {"name": "to", "value": {"address": "0x"}},
+					"amount": {"name": "amount", "value": {"uint256": "0x0100"}},
+					"data": {"name": "data", "value": {"bytes": "0x6869"}}
+				}
+			}]
+		}]
+	}`
+
+	// jsonDiff compares JSON semantically, ignoring whitespace and field order.
+	jsonDiff := func(t *testing.T, want, got []byte) string {
+		t.Helper()
+		var w, g any
+		if err := json.Unmarshal(want, &w); err != nil {
+			t.Fatalf("json.Unmarshal(%s) error %v", want, err)
+		}
+		if err := json.Unmarshal(got, &g); err != nil {
+			t.Fatalf("json.Unmarshal(%s) error %v", got, err)
+		}
+		return cmp.Diff(w, g)
+	}
+
+	got, err := MarshalHexJSON(block)
+	if err != nil {
+		t.Fatalf("MarshalHexJSON(%T) error %v", block, err)
+	}
+	if diff := jsonDiff(t, []byte(wantJSON), got); diff != "" {
+		t.Errorf("MarshalHexJSON(%T) diff (-want +got):\n%s", block, diff)
+	}
+
+	t.Run("proto names", func(t *testing.T) {
+		opts := HexMarshalOptions{protojson.MarshalOptions{UseProtoNames: true}}
+		got, err := opts.Marshal(block.Transactions[0].Logs[0])
+		if err != nil {
+			t.Fatalf("%T.Marshal(%T) error %v", opts, block.Transactions[0].Logs[0], err)
+		}
+		var ev struct {
+			ArgumentsByName map[string]struct {
+				Value struct{ Uint256 string }
+			} `json:"arguments_by_name"`
+		}
+		if err := json.Unmarshal(got, &ev); err != nil {
+			t.Fatalf("json.Unmarshal(%s) error %v", got, err)
+		}
+		if got, want := ev.ArgumentsByName["amount"].Value.Uint256, "0x0100"; got != want {
+			t.Errorf("%T.Marshal() with proto names; got uint256 %q; want %q", opts, got, want)
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		got := new(Block)
+		if err := UnmarshalHexJSON([]byte(wantJSON), got); err != nil {
+			t.Fatalf("UnmarshalHexJSON(…, %T) error %v", got, err)
+		}
+		if diff := cmp.Diff(block, got, protocmp.Transform()); diff != "" {
+			t.Errorf("UnmarshalHexJSON(MarshalHexJSON(%T)) diff (-want +got):\n%s", block, diff)
+		}
+	})
+
+	t.Run("protojson objects accepted", func(t *testing.T) {
+		got := new(Transaction)
+		if err := UnmarshalHexJSON([]byte(`{"from": {"bytes": "3q2+7w=="}}`), got); err != nil {
+			t.Fatalf("UnmarshalHexJSON(…, %T) error %v", got, err)
+		}
+		if diff := cmp.Diff([]byte{0xde, 0xad, 0xbe, 0xef}, got.GetFrom().GetBytes()); diff != "" {
+			t.Errorf("UnmarshalHexJSON() with protojson Address; From.Bytes diff (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("base64 bytes rejected", func(t *testing.T) {
+		for _, in := range []string{
+			`{"value": "3q2+7w=="}`,
+			`{"from": "3q2+7w=="}`,
+		} {
+			if err := UnmarshalHexJSON([]byte(in), new(Transaction)); err == nil {
+				t.Errorf("UnmarshalHexJSON(%s) got nil error; want non-nil", in)
+			}
+		}
+	})
+}
+
+func TestTextMarshaling(t *testing.T) {
+	tx := &Transaction{
+		From: &Address{Bytes: common.HexToAddress("0xdeadbeef").Bytes()},
+		Hash: &Hash{Bytes: make([]byte, 32)},
+	}
+	// encoding/json, unlike protojson, uses the MarshalText() methods.
+	got, err := json.Marshal(struct {
+		From *Address
+		Hash *Hash
+	}{tx.From, tx.Hash})
+	if err != nil {
+		t.Fatalf("json.Marshal() error %v", err)
+	}
+	const want = `{"From":"0x00000000000000000000000000000000deadbeef","Hash":"0x0000000000000000000000000000000000000000000000000000000000000000"}`
+	if string(got) != want {
+		t.Errorf("json.Marshal() got %s; want %s", got, want)
+	}
+
+	for _, tt := range []struct {
+		txt     string
+		wantErr bool
+	}{
+		{txt: "0xdeadbeef"},
+		{txt: "deadbeef", wantErr: true},
+		{txt: "0x" + strings.Repeat("00", 21), wantErr: true},
+	} {
+		a := new(Address)
+		if err := a.UnmarshalText([]byte(tt.txt)); (err != nil) != tt.wantErr {
+			t.Errorf("%T.UnmarshalText(%q) got err %v; want err %t", a, tt.txt, err, tt.wantErr)
+		}
+	}
+
+	h := new(Hash)
+	if err := h.UnmarshalText([]byte("0x01")); err == nil {
+		t.Errorf("%T.UnmarshalText(<1 byte>) got nil error; want non-nil", h)
+	}
+}
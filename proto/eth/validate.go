@@ -0,0 +1,262 @@
+package eth
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A FieldViolation is a single validation rule, as defined in a proto with
+// protoc-gen-validate, that a message failed. Unlike the generated errors, it
+// describes the failure in terms that a user of the API can act upon, without
+// knowledge of the rules or of the generated Go types.
+type FieldViolation struct {
+	// Path is the path to the field from the validated message, using proto
+	// field names; e.g. "signatures[0].arguments[1].value.uint8".
+	Path string
+	// Reason is the rule that was violated; e.g. "value must be less than or
+	// equal to 255".
+	Reason string
+	// Expected and Example describe a valid value of the field, in the hex
+	// JSON format (see MarshalHexJSON()), if known; they are otherwise empty.
+	Expected, Example string
+}
+
+// String returns a single-line description of the violation, including the
+// expected format and an example if known.
+func (v *FieldViolation) String() string {
+	var s strings.Builder
+	s.WriteString(v.Path)
+	s.WriteString(": ")
+	s.WriteString(v.Reason)
+	switch {
+	case v.Expected != "" && v.Example != "":
+		fmt.Fprintf(&s, " (expected %s, e.g. %s)", v.Expected, v.Example)
+	case v.Expected != "":
+		fmt.Fprintf(&s, " (expected %s)", v.Expected)
+	case v.Example != "":
+		fmt.Fprintf(&s, " (e.g. %s)", v.Example)
+	}
+	return s.String()
+}
+
+// A ValidationError is returned by Validate() and FriendlyValidationError().
+type ValidationError struct {
+	// Violations are in the order reported by the generated validation code,
+	// which follows the order of fields in the proto.
+	Violations []*FieldViolation
+	// Cause is the original error returned by the generated code.
+	Cause error
+}
+
+// Error returns all violations, separated by semicolons if there are more
+// than one.
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	if len(parts) == 1 {
+		return "invalid " + parts[0]
+	}
+	return fmt.Sprintf("%d invalid fields: %s", len(parts), strings.Join(parts, "; "))
+}
+
+// Unwrap returns e.Cause.
+func (e *ValidationError) Unwrap() error {
+	return e.Cause
+}
+
+// Validate calls m.ValidateAll(), which is generated by protoc-gen-validate,
+// returning the result of FriendlyValidationError(). It is intended for
+// client-side pre-checks so that requests can be rejected, with actionable
+// messages, before being sent.
+func Validate(m interface{ ValidateAll() error }) error {
+	return FriendlyValidationError(m.ValidateAll())
+}
+
+// FriendlyValidationError converts an error returned by a Validate() or
+// ValidateAll() method generated by protoc-gen-validate into a
+// *ValidationError. A nil error results in nil and other errors are returned
+// unchanged. Messages need not be from this package; all generated errors
+// share the same methods.
+func FriendlyValidationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	vs := violations(err, "")
+	if len(vs) == 0 {
+		return err
+	}
+	return &ValidationError{
+		Violations: vs,
+		Cause:      err,
+	}
+}
+
+// pgvError is implemented by the <Message>ValidationError types generated by
+// protoc-gen-validate.
+type pgvError interface {
+	error
+	Field() string
+	Reason() string
+	Cause() error
+	ErrorName() string
+}
+
+// pgvMultiError is implemented by the <Message>MultiError types generated by
+// protoc-gen-validate, and returned by ValidateAll().
+type pgvMultiError interface {
+	error
+	AllErrors() []error
+}
+
+// violations returns the leaf violations of err, with their paths prefixed by
+// that of the message that returned err.
+func violations(err error, prefix string) []*FieldViolation {
+	switch err := err.(type) {
+	case pgvMultiError:
+		var vs []*FieldViolation
+		for _, e := range err.AllErrors() {
+			vs = append(vs, violations(e, prefix)...)
+		}
+		return vs
+
+	case pgvError:
+		path := fieldPath(err.Field())
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		// Embedded messages that fail validation have their own error as the
+		// cause; only the innermost one describes the actual violation.
+		if vs := violations(err.Cause(), path); len(vs) > 0 {
+			return vs
+		}
+
+		v := &FieldViolation{
+			Path:   path,
+			Reason: err.Reason(),
+		}
+		msg := strings.TrimSuffix(err.ErrorName(), "ValidationError")
+		v.Expected, v.Example = fieldHint(msg, baseField(err.Field()))
+		return []*FieldViolation{v}
+	}
+	return nil
+}
+
+// fieldPath converts a field, as reported by a generated error, to its proto
+// name, retaining any index or key; e.g. "TopicFilters[2]" becomes
+// "topic_filters[2]".
+func fieldPath(field string) string {
+	name, index, _ := strings.Cut(field, "[")
+	if index != "" {
+		index = "[" + index
+	}
+	return snakeCase(name) + index
+}
+
+// baseField returns the field without any index or key.
+func baseField(field string) string {
+	name, _, _ := strings.Cut(field, "[")
+	return name
+}
+
+// snakeCase converts a generated Go field name to the proto field name from
+// which it was derived. Digits are treated as lower case, so "Uint256" becomes
+// "uint256".
+func snakeCase(s string) string {
+	var out strings.Builder
+	rs := []rune(s)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && !unicode.IsUpper(rs[i-1]) {
+				out.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// Examples of common values, in hex JSON.
+const (
+	exampleAddress = `"0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045"`
+	exampleHash    = `"0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060"`
+)
+
+// fieldHint returns the expected format of, and an example value for, a field
+// of a message in this package, keyed by their generated Go names. Empty
+// strings are returned for unknown fields.
+func fieldHint(msg, field string) (expected, example string) {
+	switch msg {
+	case "Address":
+		if field == "Bytes" {
+			return "a 20-byte address as a hex string", exampleAddress
+		}
+	case "Hash":
+		if field == "Bytes" {
+			return "a 32-byte hash as a hex string", exampleHash
+		}
+	case "Transaction":
+		if field == "Value" {
+			return "a big-endian wei amount of at most 32 bytes as a hex string", `"0x0de0b6b3a7640000"`
+		}
+	case "Value":
+		return valueHint(field)
+	}
+	return "", ""
+}
+
+// valueHint returns the hint for a field of the Value.payload oneof.
+func valueHint(field string) (expected, example string) {
+	if field == "Payload" {
+		return "exactly one typed payload, which MAY be empty for signatures", `{"uint256": ""}`
+	}
+
+	for _, t := range []struct {
+		prefix   string
+		describe func(bits int) (string, string)
+	}{
+		{"Uint", uintHint},
+		{"Int", intHint},
+		{"Bytes", bytesHint},
+	} {
+		if !strings.HasPrefix(field, t.prefix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(field, t.prefix))
+		if err != nil {
+			continue
+		}
+		if t.prefix != "Bytes" && (n < 8 || n > 256 || n%8 != 0) {
+			continue
+		}
+		return t.describe(n)
+	}
+	return "", ""
+}
+
+// uintHint describes a uint<bits> Value payload, which is a number if it fits
+// in a uint64 and big-endian bytes otherwise.
+func uintHint(bits int) (string, string) {
+	if bits <= 64 {
+		return fmt.Sprintf("an unsigned integer less than 2^%d", bits), "42"
+	}
+	return fmt.Sprintf("a big-endian unsigned integer of at most %d bytes as a hex string", bits/8), `"0x2a"`
+}
+
+// intHint describes an int<bits> Value payload, which is a number if it fits
+// in an int64 and big-endian two's complement bytes otherwise.
+func intHint(bits int) (string, string) {
+	if bits <= 64 {
+		return fmt.Sprintf("a signed integer in the range [-2^%d, 2^%[1]d)", bits-1), "-42"
+	}
+	return fmt.Sprintf("a big-endian two's complement integer of exactly %d bytes as a hex string", bits/8), `"0x` + strings.Repeat("ff", bits/8-1) + `d6"`
+}
+
+// bytesHint describes a bytes<n> Value payload.
+func bytesHint(n int) (string, string) {
+	return fmt.Sprintf("exactly %d bytes as a hex string", n), `"0x` + strings.Repeat("ab", n) + `"`
+}
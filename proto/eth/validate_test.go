@@ -1,12 +1,15 @@
 package eth
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/go-cmp/cmp"
 	"github.com/h-fam/errdiff"
 	"google.golang.org/protobuf/proto"
 )
@@ -109,3 +112,90 @@ func TestValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestFriendlyValidationError(t *testing.T) {
+	addressHint := &FieldViolation{
+		Reason:   "value length must be at most 20 bytes",
+		Expected: "a 20-byte address as a hex string",
+		Example:  `"0xd8dA6BF26964aF9D7eEd9e03E53415D37aA96045"`,
+	}
+	withPath := func(v *FieldViolation, path string) *FieldViolation {
+		v2 := *v
+		v2.Path = path
+		return &v2
+	}
+
+	tests := []struct {
+		name string
+		msg  interface {
+			proto.Message
+			ValidateAll() error
+		}
+		want []*FieldViolation
+	}{
+		{
+			name: "valid",
+			msg:  &Address{Bytes: make([]byte, 20)},
+		},
+		{
+			name: "top-level field",
+			msg:  &Address{Bytes: make([]byte, 21)},
+			want: []*FieldViolation{withPath(addressHint, "bytes")},
+		},
+		{
+			name: "missing oneof",
+			msg:  &Value{},
+			want: []*FieldViolation{{
+				Path:     "payload",
+				Reason:   "value is required",
+				Expected: "exactly one typed payload, which MAY be empty for signatures",
+				Example:  `{"uint256": ""}`,
+			}},
+		},
+		{
+			name: "nested and repeated",
+			msg: &Event{
+				Name: "Transfer",
+				Arguments: []*Argument{
+					{Name: "from", Value: &Value{Payload: &Value_Address{&Address{Bytes: make([]byte, 21)}}}},
+					{Name: "to", Value: &Value{Payload: &Value_Address{&Address{}}}},
+					{Name: "id", Value: &Value{Payload: &Value_Uint8{Uint8: 256}}},
+				},
+			},
+			want: []*FieldViolation{
+				withPath(addressHint, "arguments[0].value.address.bytes"),
+				{
+					Path:     "arguments[2].value.uint8",
+					Reason:   "value must be less than or equal to 255",
+					Expected: "an unsigned integer less than 2^8",
+					Example:  "42",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.msg)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Validate(%T) error %v", tt.msg, err)
+				}
+				return
+			}
+
+			var got *ValidationError
+			if !errors.As(err, &got) {
+				t.Fatalf("Validate(%T) got err %v; want %T", tt.msg, err, got)
+			}
+			if diff := cmp.Diff(tt.want, got.Violations); diff != "" {
+				t.Errorf("Validate(%T) violations diff (-want +got):\n%s", tt.msg, diff)
+			}
+			for _, v := range tt.want {
+				if msg := err.Error(); !strings.Contains(msg, v.String()) {
+					t.Errorf("Validate(%T) got err %q; want containing %q", tt.msg, msg, v.String())
+				}
+			}
+		})
+	}
+}
@@ -54,3 +54,128 @@ export class ClientReadableStream<T> {
     return this.stream.getPeer();
   }
 }
+
+/**
+ * A StatusError is thrown by server handlers to end a call with a specific
+ * gRPC status code. Any other thrown value results in an INTERNAL status, with
+ * details withheld from the client.
+ */
+export class StatusError extends Error {
+  public readonly code: grpc.status;
+  public readonly metadata?: grpc.Metadata;
+
+  constructor(code: grpc.status, details: string, metadata?: grpc.Metadata) {
+    super(details);
+    this.name = "StatusError";
+    this.code = code;
+    this.metadata = metadata;
+  }
+}
+
+/**
+ * Convenience constructors for StatusErrors with common codes.
+ */
+export const errors = {
+  invalidArgument: (details: string) =>
+    new StatusError(grpc.status.INVALID_ARGUMENT, details),
+  notFound: (details: string) => new StatusError(grpc.status.NOT_FOUND, details),
+  alreadyExists: (details: string) =>
+    new StatusError(grpc.status.ALREADY_EXISTS, details),
+  permissionDenied: (details: string) =>
+    new StatusError(grpc.status.PERMISSION_DENIED, details),
+  unauthenticated: (details: string) =>
+    new StatusError(grpc.status.UNAUTHENTICATED, details),
+  failedPrecondition: (details: string) =>
+    new StatusError(grpc.status.FAILED_PRECONDITION, details),
+  unimplemented: (details: string) =>
+    new StatusError(grpc.status.UNIMPLEMENTED, details),
+  unavailable: (details: string) =>
+    new StatusError(grpc.status.UNAVAILABLE, details),
+};
+
+/**
+ * Maps an error thrown by a server handler to a gRPC status. StatusErrors are
+ * propagated as-is while all others are logged and converted to INTERNAL.
+ *
+ * @param err The thrown value.
+ * @returns A grpc.ServiceError suitable for returning to the client.
+ */
+export function toServiceError(err: unknown): Partial<grpc.ServiceError> {
+  if (err instanceof StatusError) {
+    return {
+      name: err.name,
+      message: err.message,
+      code: err.code,
+      details: err.message,
+      metadata: err.metadata,
+    };
+  }
+  console.error("gRPC handler error:", err);
+  return {
+    name: "StatusError",
+    message: "internal error",
+    code: grpc.status.INTERNAL,
+    details: "internal error",
+  };
+}
+
+/**
+ * Converts an async unary handler into a grpc.handleUnaryCall.
+ */
+export function handleUnary<Req, Resp>(
+  fn: (req: Req, call: grpc.ServerUnaryCall<Req, Resp>) => Promise<Resp>
+): grpc.handleUnaryCall<Req, Resp> {
+  return (call, callback) => {
+    fn(call.request, call).then(
+      (resp) => callback(null, resp),
+      (err) => callback(toServiceError(err))
+    );
+  };
+}
+
+/**
+ * Converts an async server-streaming handler into a
+ * grpc.handleServerStreamingCall. The call is ended when the returned Promise
+ * resolves.
+ */
+export function handleServerStreaming<Req, Resp>(
+  fn: (req: Req, call: grpc.ServerWritableStream<Req, Resp>) => Promise<void>
+): grpc.handleServerStreamingCall<Req, Resp> {
+  return (call) => {
+    fn(call.request, call).then(
+      () => call.end(),
+      (err) => call.emit("error", toServiceError(err))
+    );
+  };
+}
+
+/**
+ * Converts an async client-streaming handler into a
+ * grpc.handleClientStreamingCall.
+ */
+export function handleClientStreaming<Req, Resp>(
+  fn: (call: grpc.ServerReadableStream<Req, Resp>) => Promise<Resp>
+): grpc.handleClientStreamingCall<Req, Resp> {
+  return (call, callback) => {
+    fn(call).then(
+      (resp) => callback(null, resp),
+      (err) => callback(toServiceError(err))
+    );
+  };
+}
+
+/**
+ * Converts an async bidirectional-streaming handler into a
+ * grpc.handleBidiStreamingCall. The call is ended when the returned Promise
+ * resolves.
+ */
+export function handleBidiStreaming<Req, Resp>(
+  fn: (call: grpc.ServerDuplexStream<Req, Resp>) => Promise<void>
+): grpc.handleBidiStreamingCall<Req, Resp> {
+  return (call) => {
+    fn(call).then(
+      () => call.end(),
+      (err) => call.emit("error", toServiceError(err))
+    );
+  };
+}